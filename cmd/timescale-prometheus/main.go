@@ -13,13 +13,14 @@ import (
 	"net/http"
 	pprof "net/http/pprof"
 	"os"
+	"os/signal"
 	"regexp"
 	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/prometheus/common/route"
 
-	"github.com/jackc/pgx/v4/pgxpool"
 	_ "github.com/jackc/pgx/v4/stdlib"
 	"github.com/jamiealquiza/envy"
 	"github.com/prometheus/client_golang/prometheus"
@@ -43,6 +44,9 @@ type config struct {
 	electionInterval  time.Duration
 	migrate           bool
 	corsOrigin        *regexp.Regexp
+	maxResponseBytes  int64
+	truncateResponses bool
+	shutdownTimeout   time.Duration
 }
 
 const (
@@ -248,29 +252,33 @@ func main() {
 		CachedMetricNames:   cachedMetricNames,
 		CachedLabels:        cachedLabels,
 	}
-	writeHandler := timeHandler(httpRequestDuration, "write", api.Write(client, elector, &promMetrics))
+	writeHandler := timeHandler(httpRequestDuration, "write", tenantHandler(cfg, api.Write(client, elector, &promMetrics)))
 	router.Post("/write", writeHandler)
 
-	readHandler := timeHandler(httpRequestDuration, "read", api.Read(client, &promMetrics))
+	readHandler := timeHandler(httpRequestDuration, "read", tenantHandler(cfg, api.Read(client, &promMetrics)))
 	router.Get("/read", readHandler)
 	router.Post("/read", readHandler)
 
-	apiConf := &api.Config{AllowedOrigin: cfg.corsOrigin}
+	apiConf := &api.Config{
+		AllowedOrigin:     cfg.corsOrigin,
+		MaxResponseBytes:  cfg.maxResponseBytes,
+		TruncateResponses: cfg.truncateResponses,
+	}
 	queryable := client.GetQueryable()
 	queryEngine := query.NewEngine(log.GetLogger(), time.Minute)
-	queryHandler := timeHandler(httpRequestDuration, "query", api.Query(apiConf, queryEngine, queryable))
+	queryHandler := timeHandler(httpRequestDuration, "query", tenantHandler(cfg, api.Query(apiConf, queryEngine, queryable)))
 	router.Get("/api/v1/query", queryHandler)
 	router.Post("/api/v1/query", queryHandler)
 
-	queryRangeHandler := timeHandler(httpRequestDuration, "query_range", api.QueryRange(apiConf, queryEngine, queryable))
+	queryRangeHandler := timeHandler(httpRequestDuration, "query_range", tenantHandler(cfg, api.QueryRange(apiConf, queryEngine, queryable)))
 	router.Get("/api/v1/query_range", queryRangeHandler)
 	router.Post("/api/v1/query_range", queryRangeHandler)
 
-	labelsHandler := timeHandler(httpRequestDuration, "labels", api.Labels(apiConf, queryable))
+	labelsHandler := timeHandler(httpRequestDuration, "labels", tenantHandler(cfg, api.Labels(apiConf, queryable)))
 	router.Get("/api/v1/labels", labelsHandler)
 	router.Post("/api/v1/labels", labelsHandler)
 
-	labelValuesHandler := timeHandler(httpRequestDuration, "label/:name/values", api.LabelValues(apiConf, queryable))
+	labelValuesHandler := timeHandler(httpRequestDuration, "label/:name/values", tenantHandler(cfg, api.LabelValues(apiConf, queryable)))
 	router.Get("/api/v1/label/:name/values", labelValuesHandler)
 
 	router.Get("/healthz", api.Health(client))
@@ -286,11 +294,33 @@ func main() {
 	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
 	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
 	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
-	err = http.ListenAndServe(cfg.listenAddr, mux)
 
-	if err != nil {
+	server := &http.Server{Addr: cfg.listenAddr, Handler: mux}
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case err = <-serverErr:
 		log.Error("msg", "Listen failure", "err", err)
 		os.Exit(1)
+	case sig := <-stop:
+		log.Info("msg", "Shutting down", "signal", sig.String())
+	}
+
+	// no new writes are received once the server is shut down, so all
+	// data is buffered by then
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.shutdownTimeout)
+	defer cancel()
+	if err = server.Shutdown(ctx); err != nil {
+		log.Error("msg", "error shutting down the web server", "err", err)
+	}
+	if err = client.Shutdown(ctx); err != nil {
+		log.Error("msg", "error inserting pending data on shutdown", "err", err)
+		os.Exit(1)
 	}
 }
 
@@ -311,6 +341,8 @@ func parseFlags() (*config, error) {
 		return nil, err
 	}
 	cfg.corsOrigin = corsOriginRegex
+	flag.Int64Var(&cfg.maxResponseBytes, "web-max-response-bytes", 0, "maximum size in bytes of a query response, 0 means no limit")
+	flag.BoolVar(&cfg.truncateResponses, "web-truncate-responses", false, "truncate query responses exceeding web-max-response-bytes with a warning instead of failing the query")
 	flag.StringVar(&cfg.logLevel, "log-level", "debug", "The log level to use [ \"error\", \"warn\", \"info\", \"debug\" ].")
 	flag.IntVar(&cfg.haGroupLockID, "leader-election-pg-advisory-lock-id", 0, "Unique advisory lock id per adapter high-availability group. Set it if you want to use leader election implementation based on PostgreSQL advisory lock.")
 	flag.DurationVar(&cfg.prometheusTimeout, "leader-election-pg-advisory-lock-prometheus-timeout", -1, "Adapter will resign if there are no requests from Prometheus within a given timeout (0 means no timeout). "+
@@ -318,6 +350,7 @@ func parseFlags() (*config, error) {
 	flag.BoolVar(&cfg.restElection, "leader-election-rest", false, "Enable REST interface for the leader election")
 	flag.DurationVar(&cfg.electionInterval, "scheduled-election-interval", 5*time.Second, "Interval at which scheduled election runs. This is used to select a leader and confirm that we still holding the advisory lock.")
 	flag.BoolVar(&cfg.migrate, "migrate", true, "Update the Prometheus SQL to the latest version")
+	flag.DurationVar(&cfg.shutdownTimeout, "shutdown-timeout", 30*time.Second, "time to wait on SIGINT or SIGTERM for the requests being served to complete and the data received to be inserted before exiting")
 	envy.Parse("TS_PROM")
 	flag.Parse()
 
@@ -368,7 +401,7 @@ func migrate(cfg *pgclient.Config) error {
 	}
 
 	leaderGauge.Set(1)
-	db, err := pgxpool.Connect(context.Background(), cfg.GetConnectionStr())
+	db, err := cfg.Connect(cfg.GetConnectionStr())
 	if err != nil {
 		return fmt.Errorf("Error while trying to open DB connection: %w", err)
 	}
@@ -401,6 +434,15 @@ func timeHandler(histogramVec prometheus.ObserverVec, path string, handler http.
 	}
 }
 
+// tenantHandler sets the tenant of the requests of handler from the tenant
+// header if tenancy is enabled.
+func tenantHandler(cfg *config, handler http.Handler) http.Handler {
+	if cfg.pgmodelCfg.TenantLabel == "" {
+		return handler
+	}
+	return api.Tenant(cfg.pgmodelCfg.TenantHeader, handler)
+}
+
 func compileAnchoredRegexString(s string) (*regexp.Regexp, error) {
 	r, err := regexp.Compile("^(?:" + s + ")$")
 	if err != nil {
@@ -20,6 +20,6 @@ var (
 	// since an app version must uniquely determine the state o f the schema.
 	// It is customary to bump the version by incrementing the numeral after
 	// the `dev` tag. The SQL migration script name must correspond to the /new/ version.
-	Version    = "0.1.0-beta.2.dev.0"
+	Version    = "0.1.0-beta.2.dev.1"
 	CommitHash = ""
 )
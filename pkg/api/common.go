@@ -28,6 +28,12 @@ var (
 
 type Config struct {
 	AllowedOrigin *regexp.Regexp
+	// MaxResponseBytes limits the serialized size of query responses, zero
+	// means no limit.
+	MaxResponseBytes int64
+	// TruncateResponses makes responses over MaxResponseBytes drop series
+	// and return a warning instead of failing.
+	TruncateResponses bool
 }
 
 func corsWrapper(conf *Config, f http.HandlerFunc) http.HandlerFunc {
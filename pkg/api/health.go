@@ -1,17 +1,26 @@
 package api
 
 import (
+	"errors"
 	"github.com/timescale/timescale-prometheus/pkg/log"
 	"github.com/timescale/timescale-prometheus/pkg/pgmodel"
 	"net/http"
 )
 
+// Health returns a handler for liveness and readiness probes. Failures for a
+// known reason, the database being unreachable or its schema missing, are
+// reported as 503 Service Unavailable, any other failure as 500.
 func Health(hc pgmodel.HealthChecker) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		err := hc.HealthCheck()
 		if err != nil {
 			log.Warn("msg", "Healthcheck failed", err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			status := http.StatusInternalServerError
+			var healthErr *pgmodel.HealthError
+			if errors.As(err, &healthErr) {
+				status = http.StatusServiceUnavailable
+			}
+			http.Error(w, err.Error(), status)
 			return
 		}
 		w.Header().Set("Content-Length", "0")
@@ -3,6 +3,7 @@ package api
 import (
 	"fmt"
 	"github.com/timescale/timescale-prometheus/pkg/log"
+	"github.com/timescale/timescale-prometheus/pkg/pgmodel"
 	"net/http"
 	"reflect"
 	"strings"
@@ -40,6 +41,16 @@ func TestHealth(t *testing.T) {
 			httpStatus:             http.StatusInternalServerError,
 			healthCheckerReturnErr: fmt.Errorf("some error"),
 		},
+		{
+			name:                   "database unreachable",
+			httpStatus:             http.StatusServiceUnavailable,
+			healthCheckerReturnErr: &pgmodel.HealthError{Status: pgmodel.DBUnreachable, Err: fmt.Errorf("connection refused")},
+		},
+		{
+			name:                   "schema missing",
+			httpStatus:             http.StatusServiceUnavailable,
+			healthCheckerReturnErr: &pgmodel.HealthError{Status: pgmodel.SchemaMissing, Err: fmt.Errorf("missing tables")},
+		},
 	}
 
 	for _, c := range testCases {
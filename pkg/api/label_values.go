@@ -1,7 +1,6 @@
 package api
 
 import (
-	"context"
 	"fmt"
 	"math"
 	"net/http"
@@ -9,6 +8,8 @@ import (
 	"github.com/NYTimes/gziphandler"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/common/route"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/storage"
 	"github.com/timescale/timescale-prometheus/pkg/promql"
 	"github.com/timescale/timescale-prometheus/pkg/query"
 )
@@ -26,13 +27,20 @@ func labelValues(queryable *query.Queryable) http.HandlerFunc {
 			respondError(w, http.StatusBadRequest, fmt.Errorf("invalid label name: %s", name), "bad_data")
 			return
 		}
-		querier, err := queryable.Querier(context.Background(), math.MinInt64, math.MaxInt64)
+		matcherSets, err := parseMatcherSets(r)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, err, "bad_data")
+			return
+		}
+		querier, err := queryable.Querier(ctx, math.MinInt64, math.MaxInt64)
 		if err != nil {
 			respondError(w, http.StatusInternalServerError, err, "internal")
 			return
 		}
 		var values labelsValue
-		values, warnings, err := querier.LabelValues(name)
+		values, warnings, err := mergeLabels(matcherSets, func(matchers ...*labels.Matcher) ([]string, storage.Warnings, error) {
+			return querier.LabelValues(name, matchers...)
+		})
 		if err != nil {
 			respondError(w, http.StatusInternalServerError, err, "internal")
 			return
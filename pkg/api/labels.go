@@ -1,13 +1,14 @@
 package api
 
 import (
-	"context"
 	"encoding/json"
 	"math"
 	"net/http"
+	"sort"
 	"strings"
 
 	"github.com/NYTimes/gziphandler"
+	"github.com/prometheus/prometheus/pkg/labels"
 	"github.com/prometheus/prometheus/promql/parser"
 	"github.com/prometheus/prometheus/storage"
 	"github.com/timescale/timescale-prometheus/pkg/promql"
@@ -31,13 +32,18 @@ func Labels(conf *Config, queryable *query.Queryable) http.Handler {
 
 func labelsHandler(queryable *query.Queryable) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		querier, err := queryable.Querier(context.Background(), math.MinInt64, math.MaxInt64)
+		matcherSets, err := parseMatcherSets(r)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, err, "bad_data")
+			return
+		}
+		querier, err := queryable.Querier(r.Context(), math.MinInt64, math.MaxInt64)
 		if err != nil {
 			respondError(w, http.StatusInternalServerError, err, "internal")
 			return
 		}
 		var names labelsValue
-		names, warnings, err := querier.LabelNames()
+		names, warnings, err := mergeLabels(matcherSets, querier.LabelNames)
 		if err != nil {
 			respondError(w, http.StatusInternalServerError, err, "internal")
 			return
@@ -48,6 +54,50 @@ func labelsHandler(queryable *query.Queryable) http.HandlerFunc {
 	}
 }
 
+// parseMatcherSets parses the optional match[] selectors of a request.
+func parseMatcherSets(r *http.Request) ([][]*labels.Matcher, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, err
+	}
+	var matcherSets [][]*labels.Matcher
+	for _, s := range r.Form["match[]"] {
+		matchers, err := parser.ParseMetricSelector(s)
+		if err != nil {
+			return nil, err
+		}
+		matcherSets = append(matcherSets, matchers)
+	}
+	return matcherSets, nil
+}
+
+// mergeLabels calls get for every matcher set, or once without matchers if
+// there are none, and returns the sorted union of the results.
+func mergeLabels(matcherSets [][]*labels.Matcher, get func(...*labels.Matcher) ([]string, storage.Warnings, error)) ([]string, storage.Warnings, error) {
+	if len(matcherSets) == 0 {
+		return get()
+	}
+
+	var warnings storage.Warnings
+	seen := make(map[string]bool)
+	for _, matchers := range matcherSets {
+		res, wrn, err := get(matchers...)
+		warnings = append(warnings, wrn...)
+		if err != nil {
+			return nil, warnings, err
+		}
+		for _, s := range res {
+			seen[s] = true
+		}
+	}
+
+	merged := make([]string, 0, len(seen))
+	for s := range seen {
+		merged = append(merged, s)
+	}
+	sort.Strings(merged)
+	return merged, warnings, nil
+}
+
 func respondLabels(w http.ResponseWriter, res *promql.Result, warnings storage.Warnings) {
 	setResponseHeaders(w, res, warnings)
 	resp := &response{
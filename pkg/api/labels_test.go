@@ -18,6 +18,8 @@ func TestLabels(t *testing.T) {
 	testCases := []struct {
 		name        string
 		querier     *mockQuerier
+		params      string
+		expected    []string
 		expectCode  int
 		expectError string
 		canceled    bool
@@ -31,12 +33,28 @@ func TestLabels(t *testing.T) {
 			name:       "All good",
 			expectCode: http.StatusOK,
 			querier:    &mockQuerier{labelNames: []string{"a"}},
+			expected:   []string{"a"},
+		}, {
+			name:        "Invalid matcher",
+			params:      "match[]=up{",
+			expectCode:  http.StatusBadRequest,
+			expectError: "bad_data",
+			querier:     &mockQuerier{},
+		}, {
+			name:       "Label names of the matched series are merged",
+			params:     "match[]=a&match[]=b",
+			expectCode: http.StatusOK,
+			querier: &mockQuerier{labelNamesByMetric: map[string][]string{
+				"a": {"__name__", "job"},
+				"b": {"__name__", "instance"},
+			}},
+			expected: []string{"__name__", "instance", "job"},
 		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			handler := labelsHandler(query.NewQueryable(tc.querier))
-			w := doLabels(t, handler)
+			w := doLabels(t, handler, tc.params)
 
 			if w.Code != tc.expectCode {
 				t.Errorf("Unexpected HTTP status code received: got %d wanted %d", w.Code, tc.expectCode)
@@ -59,8 +77,8 @@ func TestLabels(t *testing.T) {
 			for _, s := range res.Data.([]interface{}) {
 				resStr = append(resStr, s.(string))
 			}
-			if !reflect.DeepEqual(resStr, tc.querier.labelNames) {
-				t.Errorf("expected: %v, got: %v", tc.querier.labelNames, res.Data)
+			if !reflect.DeepEqual(resStr, tc.expected) {
+				t.Errorf("expected: %v, got: %v", tc.expected, res.Data)
 			}
 		})
 
@@ -68,8 +86,8 @@ func TestLabels(t *testing.T) {
 
 }
 
-func doLabels(t *testing.T, queryHandler http.Handler) *httptest.ResponseRecorder {
-	req, err := http.NewRequestWithContext(context.Background(), "GET", "http://localhost:9090/labels", nil)
+func doLabels(t *testing.T, queryHandler http.Handler, params string) *httptest.ResponseRecorder {
+	req, err := http.NewRequestWithContext(context.Background(), "GET", "http://localhost:9090/labels?"+params, nil)
 	if err != nil {
 		t.Errorf("%v", err)
 	}
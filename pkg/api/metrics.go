@@ -21,3 +21,18 @@ type Metrics struct {
 	CachedMetricNames   prometheus.CounterFunc
 	CachedLabels        prometheus.CounterFunc
 }
+
+// droppedRemoteWriteV2 counts the fields of remote write 2.0 requests that
+// cannot be stored, by kind.
+var droppedRemoteWriteV2 = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: util.PromNamespace,
+		Name:      "remote_write_v2_dropped_total",
+		Help:      "Total number of metadata, created timestamps, histograms and exemplars of remote write 2.0 requests dropped because they cannot be stored",
+	},
+	[]string{"kind"},
+)
+
+func init() {
+	prometheus.MustRegister(droppedRemoteWriteV2)
+}
@@ -12,11 +12,11 @@ import (
 )
 
 func Query(conf *Config, queryEngine *promql.Engine, queryable *query.Queryable) http.Handler {
-	hf := corsWrapper(conf, queryHandler(queryEngine, queryable))
+	hf := corsWrapper(conf, queryHandler(conf, queryEngine, queryable))
 	return gziphandler.GzipHandler(hf)
 }
 
-func queryHandler(queryEngine *promql.Engine, queryable *query.Queryable) http.HandlerFunc {
+func queryHandler(conf *Config, queryEngine *promql.Engine, queryable *query.Queryable) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var ts time.Time
 		var err error
@@ -66,6 +66,12 @@ func queryHandler(queryEngine *promql.Engine, queryable *query.Queryable) http.H
 			return
 		}
 
+		if err := limitResponseSize(conf, res); err != nil {
+			log.Error("msg", err, "endpoint", "query")
+			respondError(w, http.StatusUnprocessableEntity, err, "execution")
+			return
+		}
+
 		respondQuery(w, res, res.Warnings)
 	}
 }
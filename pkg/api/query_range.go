@@ -12,11 +12,11 @@ import (
 )
 
 func QueryRange(conf *Config, queryEngine *promql.Engine, queriable *query.Queryable) http.Handler {
-	hf := corsWrapper(conf, queryRange(queryEngine, queriable))
+	hf := corsWrapper(conf, queryRange(conf, queryEngine, queriable))
 	return gziphandler.GzipHandler(hf)
 }
 
-func queryRange(queryEngine *promql.Engine, queriable *query.Queryable) http.HandlerFunc {
+func queryRange(conf *Config, queryEngine *promql.Engine, queriable *query.Queryable) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		start, err := parseTime(r.FormValue("start"))
 		if err != nil {
@@ -105,6 +105,12 @@ func queryRange(queryEngine *promql.Engine, queriable *query.Queryable) http.Han
 			return
 		}
 
+		if err := limitResponseSize(conf, res); err != nil {
+			log.Error("msg", err, "endpoint", "query_range")
+			respondError(w, http.StatusUnprocessableEntity, err, "execution")
+			return
+		}
+
 		respondQuery(w, res, res.Warnings)
 	}
 }
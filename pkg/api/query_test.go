@@ -41,23 +41,31 @@ type mockQuerier struct {
 	selectErr           error
 	labelNames          []string
 	labelNamesErr       error
+	// labelNamesByMetric, if set, holds the label names returned for
+	// matchers selecting a metric
+	labelNamesByMetric map[string][]string
 }
 
 var _ pgmodel.Querier = (*mockQuerier)(nil)
 
-func (m mockQuerier) LabelNames() ([]string, error) {
+func (m mockQuerier) LabelNames(_ context.Context, matchers ...*labels.Matcher) ([]string, error) {
+	for _, matcher := range matchers {
+		if matcher.Name == labels.MetricName && m.labelNamesByMetric != nil {
+			return m.labelNamesByMetric[matcher.Value], m.labelNamesErr
+		}
+	}
 	return m.labelNames, m.labelNamesErr
 }
 
-func (m mockQuerier) LabelValues(string) ([]string, error) {
+func (m mockQuerier) LabelValues(context.Context, string, ...*labels.Matcher) ([]string, error) {
 	return nil, nil
 }
 
-func (m mockQuerier) Query(*prompb.Query) ([]*prompb.TimeSeries, error) {
+func (m mockQuerier) Query(context.Context, *prompb.Query) ([]*prompb.TimeSeries, error) {
 	panic("implement me")
 }
 
-func (m mockQuerier) Select(int64, int64, bool, *storage.SelectHints, []parser.Node, ...*labels.Matcher) (storage.SeriesSet, parser.Node, storage.Warnings, error) {
+func (m mockQuerier) Select(context.Context, int64, int64, bool, *storage.SelectHints, []parser.Node, ...*labels.Matcher) (storage.SeriesSet, parser.Node, storage.Warnings, error) {
 	time.Sleep(m.timeToSleepOnSelect)
 	return &mockSeriesSet{}, nil, nil, m.selectErr
 }
@@ -190,7 +198,7 @@ func TestQuery(t *testing.T) {
 					Timeout:    timeout,
 				},
 			)
-			handler := queryHandler(engine, query.NewQueryable(tc.querier))
+			handler := queryHandler(&Config{}, engine, query.NewQueryable(tc.querier))
 			queryURL := constructQuery(tc.metric, tc.time, tc.timeout)
 			w := doQuery(t, handler, queryURL, tc.canceled)
 
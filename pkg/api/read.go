@@ -1,16 +1,26 @@
 package api
 
 import (
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
 	"github.com/gogo/protobuf/proto"
 	"github.com/golang/snappy"
 	"github.com/timescale/timescale-prometheus/pkg/log"
 	"github.com/timescale/timescale-prometheus/pkg/pgmodel"
 	"github.com/timescale/timescale-prometheus/pkg/prompb"
-	"io/ioutil"
-	"net/http"
-	"time"
 )
 
+// chunkedReadContentType is the content type of STREAMED_XOR_CHUNKS
+// responses.
+const chunkedReadContentType = "application/x-streamed-protobuf; proto=prometheus.ChunkedReadResponse"
+
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
 func Read(reader pgmodel.Reader, metrics *Metrics) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		compressed, err := ioutil.ReadAll(r.Body)
@@ -38,8 +48,14 @@ func Read(reader pgmodel.Reader, metrics *Metrics) http.Handler {
 		metrics.ReceivedQueries.Add(queryCount)
 		begin := time.Now()
 
+		if cr, ok := reader.(pgmodel.ChunkedReader); ok && acceptsChunked(&req) {
+			readChunked(w, r, cr, &req, metrics)
+			metrics.QueryBatchDuration.Observe(time.Since(begin).Seconds())
+			return
+		}
+
 		var resp *prompb.ReadResponse
-		resp, err = reader.Read(&req)
+		resp, err = reader.Read(r.Context(), &req)
 		if err != nil {
 			log.Warn("msg", "Error executing query", "query", req, "storage", "PostgreSQL", "err", err)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -68,3 +84,54 @@ func Read(reader pgmodel.Reader, metrics *Metrics) http.Handler {
 		}
 	})
 }
+
+// acceptsChunked returns true if STREAMED_XOR_CHUNKS is the first response
+// type accepted by the request. Requests accepting none accept SAMPLES.
+func acceptsChunked(req *prompb.ReadRequest) bool {
+	types := req.GetAcceptedResponseTypes()
+	return len(types) > 0 && types[0] == prompb.ReadRequest_STREAMED_XOR_CHUNKS
+}
+
+// readChunked streams the response to the request as frames of XOR chunks,
+// flushing every frame. Errors after the first frame was written can only be
+// logged, the response ends early.
+func readChunked(w http.ResponseWriter, r *http.Request, reader pgmodel.ChunkedReader, req *prompb.ReadRequest, metrics *Metrics) {
+	w.Header().Set("Content-Type", chunkedReadContentType)
+	flusher, _ := w.(http.Flusher)
+
+	written := false
+	err := reader.ReadChunked(r.Context(), req, func(frame *prompb.ChunkedReadResponse) error {
+		data, err := proto.Marshal(frame)
+		if err != nil {
+			return err
+		}
+		if err := writeChunkedFrame(w, data); err != nil {
+			return err
+		}
+		written = true
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		log.Warn("msg", "Error executing query", "query", req, "storage", "PostgreSQL", "err", err)
+		metrics.FailedQueries.Add(float64(len(req.Queries)))
+		if !written {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
+
+// writeChunkedFrame writes a frame of a streamed response: the size of the
+// message as uvarint, its CRC32 Castagnoli checksum and the message itself.
+func writeChunkedFrame(w io.Writer, data []byte) error {
+	var header [binary.MaxVarintLen64 + 4]byte
+	n := binary.PutUvarint(header[:], uint64(len(data)))
+	binary.BigEndian.PutUint32(header[n:], crc32.Checksum(data, castagnoliTable))
+	if _, err := w.Write(header[:n+4]); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
@@ -1,12 +1,18 @@
 package api
 
 import (
+	"bufio"
+	"context"
+	"encoding/binary"
 	"fmt"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"testing"
+
 	"github.com/gogo/protobuf/proto"
 	"github.com/golang/snappy"
 	"github.com/timescale/timescale-prometheus/pkg/prompb"
-	"net/http"
-	"testing"
 )
 
 func TestRead(t *testing.T) {
@@ -96,6 +102,118 @@ func TestRead(t *testing.T) {
 	}
 }
 
+func TestReadChunked(t *testing.T) {
+	frames := []*prompb.ChunkedReadResponse{
+		{
+			ChunkedSeries: []*prompb.ChunkedSeries{{
+				Labels: []prompb.Label{{Name: "__name__", Value: "foo"}},
+				Chunks: []prompb.Chunk{{MinTimeMs: 1, MaxTimeMs: 2, Type: prompb.Chunk_XOR, Data: []byte{1, 2, 3}}},
+			}},
+		},
+		{
+			ChunkedSeries: []*prompb.ChunkedSeries{{
+				Labels: []prompb.Label{{Name: "__name__", Value: "bar"}},
+				Chunks: []prompb.Chunk{{MinTimeMs: 3, MaxTimeMs: 4, Type: prompb.Chunk_XOR, Data: []byte{4}}},
+			}},
+			QueryIndex: 1,
+		},
+	}
+	testCases := []struct {
+		name          string
+		acceptedTypes []prompb.ReadRequest_ResponseType
+		readerErr     error
+		responseCode  int
+		contentType   string
+		frames        []*prompb.ChunkedReadResponse
+	}{
+		{
+			name:          "chunked",
+			acceptedTypes: []prompb.ReadRequest_ResponseType{prompb.ReadRequest_STREAMED_XOR_CHUNKS, prompb.ReadRequest_SAMPLES},
+			responseCode:  http.StatusOK,
+			contentType:   chunkedReadContentType,
+			frames:        frames,
+		},
+		{
+			name:          "samples preferred",
+			acceptedTypes: []prompb.ReadRequest_ResponseType{prompb.ReadRequest_SAMPLES, prompb.ReadRequest_STREAMED_XOR_CHUNKS},
+			responseCode:  http.StatusOK,
+			contentType:   "application/x-protobuf",
+		},
+		{
+			name:          "chunked reader error",
+			acceptedTypes: []prompb.ReadRequest_ResponseType{prompb.ReadRequest_STREAMED_XOR_CHUNKS},
+			readerErr:     fmt.Errorf("some error"),
+			responseCode:  http.StatusInternalServerError,
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			reader := &mockChunkedReader{
+				mockReader: mockReader{response: &prompb.ReadResponse{}},
+				frames:     frames,
+				err:        c.readerErr,
+			}
+			metrics := &Metrics{
+				QueryBatchDuration: &mockMetric{},
+				FailedQueries:      &mockMetric{},
+				ReceivedQueries:    &mockMetric{},
+			}
+			test := GenerateHandleTester(t, Read(reader, metrics))
+			w := test("POST", getReader(readRequestToString(&prompb.ReadRequest{
+				Queries:               []*prompb.Query{{}, {}},
+				AcceptedResponseTypes: c.acceptedTypes,
+			})))
+
+			if w.Code != c.responseCode {
+				t.Fatalf("Unexpected HTTP status code received: got %d wanted %d", w.Code, c.responseCode)
+			}
+			if c.contentType != "" && w.Header().Get("Content-Type") != c.contentType {
+				t.Errorf("unexpected content type: got %s, wanted %s", w.Header().Get("Content-Type"), c.contentType)
+			}
+			if c.frames == nil {
+				return
+			}
+
+			body := bufio.NewReader(w.Body)
+			var got []*prompb.ChunkedReadResponse
+			for {
+				size, err := binary.ReadUvarint(body)
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				var checksum uint32
+				if err := binary.Read(body, binary.BigEndian, &checksum); err != nil {
+					t.Fatal(err)
+				}
+				data := make([]byte, size)
+				if _, err := io.ReadFull(body, data); err != nil {
+					t.Fatal(err)
+				}
+				if crc32.Checksum(data, crc32.MakeTable(crc32.Castagnoli)) != checksum {
+					t.Fatalf("checksum mismatch of frame %d", len(got))
+				}
+				frame := &prompb.ChunkedReadResponse{}
+				if err := proto.Unmarshal(data, frame); err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, frame)
+			}
+			if len(got) != len(c.frames) {
+				t.Fatalf("unexpected number of frames: got %d, wanted %d", len(got), len(c.frames))
+			}
+			for i := range got {
+				if !proto.Equal(got[i], c.frames[i]) {
+					t.Errorf("unexpected frame %d: got %v, wanted %v", i, got[i], c.frames[i])
+				}
+			}
+		})
+	}
+}
+
 func readRequestToString(r *prompb.ReadRequest) string {
 	data, _ := proto.Marshal(r)
 	return string(snappy.Encode(nil, data))
@@ -107,7 +225,26 @@ type mockReader struct {
 	err      error
 }
 
-func (m *mockReader) Read(r *prompb.ReadRequest) (*prompb.ReadResponse, error) {
+func (m *mockReader) Read(ctx context.Context, r *prompb.ReadRequest) (*prompb.ReadResponse, error) {
 	m.request = r
 	return m.response, m.err
 }
+
+type mockChunkedReader struct {
+	mockReader
+	frames []*prompb.ChunkedReadResponse
+	err    error
+}
+
+func (m *mockChunkedReader) ReadChunked(ctx context.Context, r *prompb.ReadRequest, send func(*prompb.ChunkedReadResponse) error) error {
+	m.request = r
+	if m.err != nil {
+		return m.err
+	}
+	for _, frame := range m.frames {
+		if err := send(frame); err != nil {
+			return err
+		}
+	}
+	return nil
+}
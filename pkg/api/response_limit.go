@@ -0,0 +1,108 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/timescale/timescale-prometheus/pkg/promql"
+)
+
+// countingWriter discards everything written to it, only counting the bytes.
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
+
+func vectorResponseSize(v promql.Vector, warnings []string) int64 {
+	cw := &countingWriter{}
+	_ = marshalVectorResponse(cw, v, warnings)
+	return cw.n
+}
+
+func matrixResponseSize(m promql.Matrix, warnings []string) int64 {
+	cw := &countingWriter{}
+	_ = marshalMatrixResponse(cw, m, warnings)
+	return cw.n
+}
+
+// limitResponseSize checks that the serialized response for res fits in
+// conf.MaxResponseBytes. Oversized responses either fail with an error or,
+// if conf.TruncateResponses is set, have their trailing series dropped and a
+// warning added. Only vector and matrix results are limited, other result
+// types have a fixed small size.
+//
+// Series are sized one at a time in a single pass, which stops at the first
+// series over the limit, so oversized responses are never serialized whole.
+func limitResponseSize(conf *Config, res *promql.Result) error {
+	if conf.MaxResponseBytes <= 0 {
+		return nil
+	}
+
+	warnings := make([]string, 0, len(res.Warnings)+1)
+	for _, warn := range res.Warnings {
+		warnings = append(warnings, warn.Error())
+	}
+
+	var (
+		numSeries  int
+		seriesSize func(i int) int64
+		emptySize  func(warnings []string) int64
+	)
+	switch v := res.Value.(type) {
+	case promql.Vector:
+		numSeries = len(v)
+		seriesSize = func(i int) int64 { return vectorResponseSize(v[i:i+1], nil) }
+		emptySize = func(warnings []string) int64 { return vectorResponseSize(promql.Vector{}, warnings) }
+	case promql.Matrix:
+		numSeries = len(v)
+		seriesSize = func(i int) int64 { return matrixResponseSize(v[i:i+1], nil) }
+		emptySize = func(warnings []string) int64 { return matrixResponseSize(promql.Matrix{}, warnings) }
+	default:
+		return nil
+	}
+
+	// The warning is sized using the total number of series, which is never
+	// shorter than the warning for the series actually kept.
+	empty := emptySize(nil)
+	size := emptySize(warnings)
+	warningSize := emptySize(append(warnings, truncationWarning(numSeries, numSeries, conf.MaxResponseBytes))) - size
+	// kept counts the series fitting together with the truncation warning
+	kept := 0
+	for i := 0; i < numSeries; i++ {
+		size += seriesSize(i) - empty
+		if i > 0 {
+			// separating comma
+			size++
+		}
+		if size > conf.MaxResponseBytes {
+			break
+		}
+		if size+warningSize <= conf.MaxResponseBytes {
+			kept = i + 1
+		}
+	}
+	if size <= conf.MaxResponseBytes {
+		return nil
+	}
+
+	if !conf.TruncateResponses {
+		return fmt.Errorf("response size exceeds the maximum of %d bytes", conf.MaxResponseBytes)
+	}
+
+	switch v := res.Value.(type) {
+	case promql.Vector:
+		res.Value = v[:kept]
+	case promql.Matrix:
+		res.Value = v[:kept]
+	}
+	res.Warnings = append(res.Warnings, errors.New(truncationWarning(kept, numSeries, conf.MaxResponseBytes)))
+	return nil
+}
+
+func truncationWarning(kept, total int, maxBytes int64) string {
+	return fmt.Sprintf("response truncated to %d of %d series to fit the maximum response size of %d bytes", kept, total, maxBytes)
+}
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/timescale/timescale-prometheus/pkg/promql"
+)
+
+func largeMatrix(numSeries, numPoints int) promql.Matrix {
+	m := make(promql.Matrix, 0, numSeries)
+	for i := 0; i < numSeries; i++ {
+		points := make([]promql.Point, 0, numPoints)
+		for j := 0; j < numPoints; j++ {
+			points = append(points, promql.Point{T: int64(j * 1000), V: float64(i*j) / 3})
+		}
+		m = append(m, promql.Series{
+			Metric: labels.Labels{
+				{Name: "__name__", Value: "foo"},
+				{Name: "instance", Value: fmt.Sprintf("host-%d", i)},
+			},
+			Points: points,
+		})
+	}
+	return m
+}
+
+func largeVector(numSeries int) promql.Vector {
+	v := make(promql.Vector, 0, numSeries)
+	for i := 0; i < numSeries; i++ {
+		v = append(v, promql.Sample{
+			Metric: labels.Labels{
+				{Name: "__name__", Value: "foo"},
+				{Name: "instance", Value: fmt.Sprintf("host-%d", i)},
+			},
+			Point: promql.Point{T: 1000, V: float64(i) / 3},
+		})
+	}
+	return v
+}
+
+func TestLimitResponseSize(t *testing.T) {
+	testCases := []struct {
+		name      string
+		value     parser.Value
+		maxBytes  int64
+		truncate  bool
+		expectErr bool
+		// kept is the expected number of series left, -1 for truncated
+		// responses where it is only checked that as many series as fit
+		// are kept.
+		kept int
+	}{
+		{
+			name:  "No limit",
+			value: largeMatrix(100, 100),
+			kept:  100,
+		},
+		{
+			name:     "Matrix within limit",
+			value:    largeMatrix(10, 10),
+			maxBytes: 1 << 20,
+			kept:     10,
+		},
+		{
+			name:      "Matrix over limit",
+			value:     largeMatrix(100, 100),
+			maxBytes:  10000,
+			expectErr: true,
+		},
+		{
+			name:     "Matrix over limit, truncated",
+			value:    largeMatrix(100, 100),
+			maxBytes: 10000,
+			truncate: true,
+			kept:     -1,
+		},
+		{
+			name:      "Vector over limit",
+			value:     largeVector(1000),
+			maxBytes:  1000,
+			expectErr: true,
+		},
+		{
+			name:     "Vector over limit, truncated",
+			value:    largeVector(1000),
+			maxBytes: 1000,
+			truncate: true,
+			kept:     -1,
+		},
+		{
+			name:     "Limit too small for any series",
+			value:    largeVector(10),
+			maxBytes: 10,
+			truncate: true,
+			kept:     0,
+		},
+		{
+			name:     "Empty vector within limit",
+			value:    promql.Vector{},
+			maxBytes: 100,
+			kept:     0,
+		},
+		{
+			name:     "Scalar is not limited",
+			value:    promql.Scalar{T: 1000, V: 1},
+			maxBytes: 1,
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			conf := &Config{MaxResponseBytes: c.maxBytes, TruncateResponses: c.truncate}
+			res := &promql.Result{Value: c.value}
+
+			err := limitResponseSize(conf, res)
+			if c.expectErr {
+				if err == nil {
+					t.Fatal("expected an error for an oversized response")
+				}
+				if !strings.Contains(err.Error(), fmt.Sprintf("maximum of %d bytes", c.maxBytes)) {
+					t.Errorf("unexpected error message: %s", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			var kept, total int
+			switch v := res.Value.(type) {
+			case promql.Matrix:
+				kept, total = len(v), len(c.value.(promql.Matrix))
+			case promql.Vector:
+				kept, total = len(v), len(c.value.(promql.Vector))
+			default:
+				return
+			}
+
+			truncated := c.truncate && c.maxBytes > 0
+			if truncated != (len(res.Warnings) == 1) {
+				t.Fatalf("unexpected warnings: %v", res.Warnings)
+			}
+			if !truncated {
+				if kept != c.kept {
+					t.Errorf("unexpected number of series kept: got %d, wanted %d", kept, c.kept)
+				}
+				return
+			}
+			warning := truncationWarning(kept, total, c.maxBytes)
+			if res.Warnings[0].Error() != warning {
+				t.Errorf("unexpected warning: got %q, wanted %q", res.Warnings[0], warning)
+			}
+			if c.kept >= 0 {
+				if kept != c.kept {
+					t.Errorf("unexpected number of series kept: got %d, wanted %d", kept, c.kept)
+				}
+				return
+			}
+			if kept == 0 || kept >= total {
+				t.Fatalf("expected the response to be truncated, kept %d of %d series", kept, total)
+			}
+
+			w := httptest.NewRecorder()
+			respondQuery(w, res, res.Warnings)
+			if int64(w.Body.Len()) > c.maxBytes {
+				t.Errorf("truncated response is too large: got %d bytes, max %d", w.Body.Len(), c.maxBytes)
+			}
+
+			// One more series must not have fit.
+			var next int64
+			switch v := c.value.(type) {
+			case promql.Matrix:
+				next = matrixResponseSize(v[:kept+1], []string{warning})
+			case promql.Vector:
+				next = vectorResponseSize(v[:kept+1], []string{warning})
+			}
+			if next <= c.maxBytes {
+				t.Errorf("response truncated too much: %d series fit in %d bytes, kept %d", kept+1, c.maxBytes, kept)
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/timescale/timescale-prometheus/pkg/pgmodel"
+)
+
+// Tenant returns a handler serving the requests with the header for the
+// tenant named by its value, see pgmodel.Tenancy. Requests without the header
+// are served as they are.
+func Tenant(header string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if tenant := r.Header.Get(header); tenant != "" {
+			r = r.WithContext(pgmodel.WithTenant(r.Context(), tenant))
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/timescale/timescale-prometheus/pkg/pgmodel"
+)
+
+func TestTenant(t *testing.T) {
+	testCases := []struct {
+		name   string
+		header string
+		tenant string
+	}{
+		{
+			name:   "tenant header",
+			header: "a",
+			tenant: "a",
+		},
+		{
+			name: "no tenant header",
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			tenant := "unset"
+			handler := Tenant("X-Scope-OrgID", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tenant = pgmodel.TenantFromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest("GET", "http://localhost/api/v1/labels", nil)
+			if c.header != "" {
+				req.Header.Set("X-Scope-OrgID", c.header)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if tenant != c.tenant {
+				t.Errorf("unexpected tenant: got %q, wanted %q", tenant, c.tenant)
+			}
+		})
+	}
+}
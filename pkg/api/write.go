@@ -1,9 +1,12 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"net/http"
+	"strconv"
 	"sync/atomic"
 	"time"
 
@@ -32,6 +35,22 @@ func Write(writer pgmodel.DBInserter, elector *util.Elector, metrics *Metrics) h
 
 		metrics.LeaderGauge.Set(1)
 
+		if o, ok := writer.(pgmodel.OverloadReporter); ok {
+			if retryAfter, err := o.Overloaded(); err != nil {
+				log.Warn("msg", "Rejecting write request", "err", err)
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+		}
+
+		protoMsg, err := remoteWriteProto(r.Header.Get("Content-Type"))
+		if err != nil {
+			log.Error("msg", "Content type error", "err", err.Error())
+			http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+			return
+		}
+
 		compressed, err := ioutil.ReadAll(r.Body)
 		if err != nil {
 			log.Error("msg", "Read error", "err", err.Error())
@@ -49,11 +68,22 @@ func Write(writer pgmodel.DBInserter, elector *util.Elector, metrics *Metrics) h
 		}
 
 		req := pgmodel.NewWriteRequest()
-		if err := proto.Unmarshal(reqBuf, req); err != nil {
+		var dropped droppedV2
+		if protoMsg == remoteWriteV2Proto {
+			dropped, err = unmarshalWriteV2(reqBuf, req)
+		} else {
+			err = proto.Unmarshal(reqBuf, req)
+		}
+		if err != nil {
 			log.Error("msg", "Unmarshal error", "err", err.Error())
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		if !dropped.empty() {
+			warning := dropped.report()
+			log.Debug("msg", "Fields of write request dropped", "warning", warning)
+			w.Header().Add("Warning", fmt.Sprintf("199 - %q", warning))
+		}
 
 		ts := req.GetTimeseries()
 		receivedBatchCount := 0
@@ -65,14 +95,34 @@ func Write(writer pgmodel.DBInserter, elector *util.Elector, metrics *Metrics) h
 		metrics.ReceivedSamples.Add(float64(receivedBatchCount))
 		begin := time.Now()
 
-		numSamples, err := writer.Ingest(req.GetTimeseries(), req)
+		numSamples, err := writer.Ingest(r.Context(), req.GetTimeseries(), req)
+		var partial *pgmodel.PartialWriteError
+		if errors.As(err, &partial) {
+			// the other series were written, so the request must not be
+			// retried
+			log.Warn("msg", "Series of write request rejected", "err", err, "fingerprints", fmt.Sprintf("%016x", partial.FailedFingerprints))
+			w.Header().Add("Warning", fmt.Sprintf("199 - %q", partial.Error()))
+			metrics.FailedSamples.Add(float64(partial.FailedSamples))
+			err = nil
+		}
 		if err != nil {
 			log.Warn("msg", "Error sending samples to remote storage", "err", err, "num_samples", numSamples)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			status := http.StatusInternalServerError
+			if isBadWriteRequest(err) {
+				// retrying the request cannot succeed
+				status = http.StatusBadRequest
+			}
+			http.Error(w, err.Error(), status)
 			metrics.FailedSamples.Add(float64(receivedBatchCount))
 			return
 		}
 
+		if protoMsg == remoteWriteV2Proto {
+			w.Header().Set(remoteWriteSamplesHeader, strconv.FormatUint(numSamples, 10))
+			w.Header().Set(remoteWriteHistogramsHeader, "0")
+			w.Header().Set(remoteWriteExemplarsHeader, "0")
+		}
+
 		duration := time.Since(begin).Seconds()
 
 		metrics.SentSamples.Add(float64(numSamples))
@@ -89,6 +139,26 @@ func Write(writer pgmodel.DBInserter, elector *util.Elector, metrics *Metrics) h
 	})
 }
 
+// badWriteRequestErrors are the ingest errors caused by the data of a write
+// request. Prometheus retries requests failing with a 5xx status forever, so
+// these are reported with 400 for the request to be dropped.
+var badWriteRequestErrors = []error{
+	pgmodel.ErrMissingTenant,
+	pgmodel.ErrInvalidLabel,
+	pgmodel.ErrSeriesTooLarge,
+	pgmodel.ErrTimestampOutOfRange,
+	pgmodel.ErrDuplicateSample,
+}
+
+func isBadWriteRequest(err error) bool {
+	for _, e := range badWriteRequestErrors {
+		if errors.Is(err, e) {
+			return true
+		}
+	}
+	return false
+}
+
 func isWriter(elector *util.Elector) (bool, error) {
 	if elector != nil {
 		shouldWrite, err := elector.IsLeader()
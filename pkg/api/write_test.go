@@ -1,18 +1,24 @@
 package api
 
 import (
+	"context"
+	"encoding/binary"
 	"fmt"
 	"github.com/gogo/protobuf/proto"
 	"github.com/golang/snappy"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	dto "github.com/prometheus/client_model/go"
 	io_prometheus_client "github.com/prometheus/client_model/go"
 	"github.com/timescale/timescale-prometheus/pkg/log"
+	"github.com/timescale/timescale-prometheus/pkg/pgmodel"
 	"github.com/timescale/timescale-prometheus/pkg/prompb"
 	"github.com/timescale/timescale-prometheus/pkg/util"
 	"io"
+	"math"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
 	"testing/iotest"
@@ -56,6 +62,51 @@ func TestWrite(t *testing.T) {
 				&prompb.WriteRequest{},
 			),
 		},
+		{
+			name:         "missing tenant",
+			isLeader:     true,
+			responseCode: http.StatusBadRequest,
+			inserterErr:  fmt.Errorf("%w: series without a tenant label", pgmodel.ErrMissingTenant),
+			requestBody: writeRequestToString(
+				&prompb.WriteRequest{},
+			),
+		},
+		{
+			name:         "invalid label",
+			isLeader:     true,
+			responseCode: http.StatusBadRequest,
+			inserterErr:  fmt.Errorf("%w: value of job is not valid UTF-8", pgmodel.ErrInvalidLabel),
+			requestBody: writeRequestToString(
+				&prompb.WriteRequest{},
+			),
+		},
+		{
+			name:         "series too large",
+			isLeader:     true,
+			responseCode: http.StatusBadRequest,
+			inserterErr:  fmt.Errorf("%w: 70000 bytes in 3 labels, maximum 65536 bytes", pgmodel.ErrSeriesTooLarge),
+			requestBody: writeRequestToString(
+				&prompb.WriteRequest{},
+			),
+		},
+		{
+			name:         "timestamp out of range",
+			isLeader:     true,
+			responseCode: http.StatusBadRequest,
+			inserterErr:  fmt.Errorf("%w: 1 samples of metric foo", pgmodel.ErrTimestampOutOfRange),
+			requestBody: writeRequestToString(
+				&prompb.WriteRequest{},
+			),
+		},
+		{
+			name:         "duplicate sample rejected",
+			isLeader:     true,
+			responseCode: http.StatusBadRequest,
+			inserterErr:  fmt.Errorf("%w for metric foo at timestamp 1", pgmodel.ErrDuplicateSample),
+			requestBody: writeRequestToString(
+				&prompb.WriteRequest{},
+			),
+		},
 		{
 			name:         "elector error",
 			electionErr:  fmt.Errorf("some error"),
@@ -137,6 +188,65 @@ func TestWrite(t *testing.T) {
 	}
 }
 
+type mockOverloadedInserter struct {
+	mockInserter
+	retryAfter  time.Duration
+	overloadErr error
+}
+
+func (m *mockOverloadedInserter) Overloaded() (time.Duration, error) {
+	return m.retryAfter, m.overloadErr
+}
+
+func TestWriteOverloaded(t *testing.T) {
+	testCases := []struct {
+		name         string
+		overloadErr  error
+		retryAfter   time.Duration
+		responseCode int
+		header       string
+	}{
+		{
+			name:         "not overloaded",
+			responseCode: http.StatusOK,
+		},
+		{
+			name:         "overloaded",
+			overloadErr:  fmt.Errorf("%w: 10 requests queued, more than 5", pgmodel.ErrOverloaded),
+			retryAfter:   1500 * time.Millisecond,
+			responseCode: http.StatusServiceUnavailable,
+			header:       "2",
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			mock := &mockOverloadedInserter{retryAfter: c.retryAfter, overloadErr: c.overloadErr}
+			handler := Write(mock, nil, &Metrics{
+				LeaderGauge:       &mockMetric{},
+				ReceivedSamples:   &mockMetric{},
+				FailedSamples:     &mockMetric{},
+				SentSamples:       &mockMetric{},
+				SentBatchDuration: &mockMetric{},
+				WriteThroughput:   util.NewThroughputCalc(time.Second),
+			})
+
+			w := GenerateHandleTester(t, handler)("POST", getReader(writeRequestToString(
+				&prompb.WriteRequest{Timeseries: []prompb.TimeSeries{{}}},
+			)))
+
+			if w.Code != c.responseCode {
+				t.Errorf("Unexpected HTTP status code received: got %d wanted %d", w.Code, c.responseCode)
+			}
+			if got := w.Header().Get("Retry-After"); got != c.header {
+				t.Errorf("unexpected Retry-After header: got %q, wanted %q", got, c.header)
+			}
+			if c.overloadErr != nil && mock.ts != nil {
+				t.Errorf("overloaded write was ingested")
+			}
+		})
+	}
+}
+
 func writeRequestToString(r *prompb.WriteRequest) string {
 	data, _ := proto.Marshal(r)
 	return string(snappy.Encode(nil, data))
@@ -187,7 +297,7 @@ type mockInserter struct {
 	err    error
 }
 
-func (m *mockInserter) Ingest(series []prompb.TimeSeries, request *prompb.WriteRequest) (uint64, error) {
+func (m *mockInserter) Ingest(ctx context.Context, series []prompb.TimeSeries, request *prompb.WriteRequest) (uint64, error) {
 	m.ts = series
 	return m.result, m.err
 }
@@ -250,3 +360,214 @@ func (m *mockMetric) Sub(f float64) {
 func (m *mockMetric) SetToCurrentTime() {
 	panic("implement me")
 }
+
+// protoField appends a length-delimited field to a protobuf message.
+func protoField(b []byte, num int, value []byte) []byte {
+	b = protoVarint(b, uint64(num<<3|2))
+	b = protoVarint(b, uint64(len(value)))
+	return append(b, value...)
+}
+
+func protoVarint(b []byte, v uint64) []byte {
+	buf := make([]byte, binary.MaxVarintLen64)
+	return append(b, buf[:binary.PutUvarint(buf, v)]...)
+}
+
+// writeRequestV2 encodes a remote write 2.0 request with a single series of
+// a counter, along with the fields of the series that are not stored.
+func writeRequestV2(refs []uint64, samples []prompb.Sample) string {
+	var packed []byte
+	for _, ref := range refs {
+		packed = protoVarint(packed, ref)
+	}
+	series := protoField(nil, 1, packed)
+	for _, s := range samples {
+		sample := protoVarint(nil, 1<<3|1)
+		sample = append(sample, make([]byte, 8)...)
+		binary.LittleEndian.PutUint64(sample[len(sample)-8:], math.Float64bits(s.Value))
+		sample = protoVarint(sample, 2<<3)
+		sample = protoVarint(sample, uint64(s.Timestamp))
+		series = protoField(series, 2, sample)
+	}
+	// a histogram, an exemplar, metadata of type counter with help text, a
+	// created timestamp
+	series = protoField(series, 3, protoVarint(protoVarint(nil, 1<<3), 7))
+	series = protoField(series, 4, protoVarint(protoVarint(nil, 3<<3), 1000))
+	series = protoField(series, 5, protoVarint(protoVarint(protoVarint(protoVarint(nil, 1<<3), 1), 3<<3), 5))
+	series = protoVarint(protoVarint(series, 6<<3), 500)
+
+	var req []byte
+	// the series first, the symbols may come in any order
+	req = protoField(req, 5, series)
+	for _, symbol := range []string{"", pgmodel.MetricNameLabelName, "foo", "job", "api", "help"} {
+		req = protoField(req, 4, []byte(symbol))
+	}
+	return string(snappy.Encode(nil, req))
+}
+
+func TestWriteRemoteWriteVersions(t *testing.T) {
+	samples := []prompb.Sample{{Timestamp: 1000, Value: 1}, {Timestamp: 2000, Value: 2.5}}
+	expected := []prompb.TimeSeries{{
+		Labels: []prompb.Label{
+			{Name: pgmodel.MetricNameLabelName, Value: "foo"},
+			{Name: "job", Value: "api"},
+		},
+		Samples: samples,
+	}}
+	v1Body := writeRequestToString(&prompb.WriteRequest{Timeseries: expected})
+
+	testCases := []struct {
+		name         string
+		contentType  string
+		body         string
+		responseCode int
+		v2           bool
+	}{
+		{
+			name:         "v1 without content type",
+			body:         v1Body,
+			responseCode: http.StatusOK,
+		},
+		{
+			name:         "v1 without proto",
+			contentType:  "application/x-protobuf",
+			body:         v1Body,
+			responseCode: http.StatusOK,
+		},
+		{
+			name:         "v1",
+			contentType:  "application/x-protobuf;proto=prometheus.WriteRequest",
+			body:         v1Body,
+			responseCode: http.StatusOK,
+		},
+		{
+			name:         "v2",
+			contentType:  "application/x-protobuf;proto=io.prometheus.write.v2.Request",
+			body:         writeRequestV2([]uint64{1, 2, 3, 4}, samples),
+			responseCode: http.StatusOK,
+			v2:           true,
+		},
+		{
+			name:         "v2 label out of the symbol table",
+			contentType:  "application/x-protobuf;proto=io.prometheus.write.v2.Request",
+			body:         writeRequestV2([]uint64{1, 2, 3, 9}, samples),
+			responseCode: http.StatusBadRequest,
+		},
+		{
+			name:         "v2 truncated",
+			contentType:  "application/x-protobuf;proto=io.prometheus.write.v2.Request",
+			body:         string(snappy.Encode(nil, []byte{5<<3 | 2, 10, 1})),
+			responseCode: http.StatusBadRequest,
+		},
+		{
+			name:         "unsupported version",
+			contentType:  "application/x-protobuf;proto=io.prometheus.write.v3.Request",
+			body:         v1Body,
+			responseCode: http.StatusUnsupportedMediaType,
+		},
+	}
+	droppedKinds := []string{"metadata", "created_timestamp", "histogram", "exemplar"}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			droppedBefore := make(map[string]float64)
+			for _, kind := range droppedKinds {
+				droppedBefore[kind] = testutil.ToFloat64(droppedRemoteWriteV2.WithLabelValues(kind))
+			}
+			mock := &mockInserter{result: uint64(len(samples))}
+			handler := Write(mock, nil, &Metrics{
+				LeaderGauge:       &mockMetric{},
+				ReceivedSamples:   &mockMetric{},
+				FailedSamples:     &mockMetric{},
+				SentSamples:       &mockMetric{},
+				SentBatchDuration: &mockMetric{},
+				WriteThroughput:   util.NewThroughputCalc(time.Second),
+			})
+
+			req, err := http.NewRequest("POST", "", strings.NewReader(c.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.contentType != "" {
+				req.Header.Set("Content-Type", c.contentType)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != c.responseCode {
+				t.Fatalf("Unexpected HTTP status code received: got %d wanted %d: %s", w.Code, c.responseCode, w.Body)
+			}
+			if c.responseCode != http.StatusOK {
+				if mock.ts != nil {
+					t.Errorf("rejected write was ingested")
+				}
+				return
+			}
+			if !reflect.DeepEqual(mock.ts, expected) {
+				t.Errorf("unexpected series ingested:\ngot\n%+v\nwanted\n%+v", mock.ts, expected)
+			}
+
+			headers := map[string]string{
+				remoteWriteSamplesHeader:    "",
+				remoteWriteHistogramsHeader: "",
+				remoteWriteExemplarsHeader:  "",
+				"Warning":                   "",
+			}
+			dropped := 0.0
+			if c.v2 {
+				headers = map[string]string{
+					remoteWriteSamplesHeader:    "2",
+					remoteWriteHistogramsHeader: "0",
+					remoteWriteExemplarsHeader:  "0",
+					"Warning":                   `199 - "dropped 1 metadata, 1 created timestamps, 1 histograms and 1 exemplars that cannot be stored"`,
+				}
+				dropped = 1
+			}
+			for name, value := range headers {
+				if got := w.Header().Get(name); got != value {
+					t.Errorf("unexpected %s header: got %q, wanted %q", name, got, value)
+				}
+			}
+			for _, kind := range droppedKinds {
+				if got := testutil.ToFloat64(droppedRemoteWriteV2.WithLabelValues(kind)) - droppedBefore[kind]; got != dropped {
+					t.Errorf("unexpected number of dropped %s: got %v, wanted %v", kind, got, dropped)
+				}
+			}
+		})
+	}
+}
+
+func TestWritePartial(t *testing.T) {
+	mock := &mockInserter{
+		result: 2,
+		err: &pgmodel.PartialWriteError{
+			Inserted:           2,
+			FailedSamples:      3,
+			FailedFingerprints: []uint64{0xabc},
+			Err:                fmt.Errorf("%w: value of job contains a NUL byte", pgmodel.ErrInvalidLabel),
+		},
+	}
+	failed := &mockMetric{}
+	sent := &mockMetric{}
+	handler := Write(mock, nil, &Metrics{
+		LeaderGauge:       &mockMetric{},
+		ReceivedSamples:   &mockMetric{},
+		FailedSamples:     failed,
+		SentSamples:       sent,
+		SentBatchDuration: &mockMetric{},
+		WriteThroughput:   util.NewThroughputCalc(time.Second),
+	})
+
+	w := GenerateHandleTester(t, handler)("POST", getReader(writeRequestToString(
+		&prompb.WriteRequest{Timeseries: []prompb.TimeSeries{{}}},
+	)))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Unexpected HTTP status code received: got %d wanted %d", w.Code, http.StatusOK)
+	}
+	if warning := w.Header().Get("Warning"); !strings.HasPrefix(warning, "199 - ") || !strings.Contains(warning, "1 series with 3 samples rejected") {
+		t.Errorf("unexpected Warning header: %q", warning)
+	}
+	if failed.value != 3 || sent.value != 2 {
+		t.Errorf("unexpected samples counted: %v failed, %v sent", failed.value, sent.value)
+	}
+}
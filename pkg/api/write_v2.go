@@ -0,0 +1,297 @@
+package api
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"mime"
+
+	"github.com/timescale/timescale-prometheus/pkg/prompb"
+)
+
+const (
+	// remoteWriteV1Proto and remoteWriteV2Proto are the proto parameters
+	// of the content type of the remote write protocol versions.
+	remoteWriteV1Proto = "prometheus.WriteRequest"
+	remoteWriteV2Proto = "io.prometheus.write.v2.Request"
+
+	remoteWriteSamplesHeader    = "X-Prometheus-Remote-Write-Samples-Written"
+	remoteWriteHistogramsHeader = "X-Prometheus-Remote-Write-Histograms-Written"
+	remoteWriteExemplarsHeader  = "X-Prometheus-Remote-Write-Exemplars-Written"
+)
+
+var errInvalidProto = fmt.Errorf("invalid protobuf message")
+
+// remoteWriteProto returns the message a write request is encoded as, given
+// its content type. Requests that are not explicitly of another version, e.g.
+// of Prometheus versions sending no content type, are read as version 1.
+func remoteWriteProto(contentType string) (string, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "application/x-protobuf" {
+		return remoteWriteV1Proto, nil
+	}
+	switch proto := params["proto"]; proto {
+	case "", remoteWriteV1Proto:
+		return remoteWriteV1Proto, nil
+	case remoteWriteV2Proto:
+		return remoteWriteV2Proto, nil
+	default:
+		return "", fmt.Errorf("unsupported remote write protobuf message %q", proto)
+	}
+}
+
+// droppedV2 counts the fields of a version 2 write request that cannot be
+// stored and are dropped.
+type droppedV2 struct {
+	metadata          int
+	createdTimestamps int
+	histograms        int
+	exemplars         int
+}
+
+func (d droppedV2) empty() bool {
+	return d == droppedV2{}
+}
+
+// report counts the dropped fields in droppedRemoteWriteV2 and returns a
+// warning for the response to the request.
+func (d droppedV2) report() string {
+	droppedRemoteWriteV2.WithLabelValues("metadata").Add(float64(d.metadata))
+	droppedRemoteWriteV2.WithLabelValues("created_timestamp").Add(float64(d.createdTimestamps))
+	droppedRemoteWriteV2.WithLabelValues("histogram").Add(float64(d.histograms))
+	droppedRemoteWriteV2.WithLabelValues("exemplar").Add(float64(d.exemplars))
+	return fmt.Sprintf("dropped %d metadata, %d created timestamps, %d histograms and %d exemplars that cannot be stored",
+		d.metadata, d.createdTimestamps, d.histograms, d.exemplars)
+}
+
+// unmarshalWriteV2 decodes a version 2 write request into req. The labels of
+// the series are resolved from the symbol table. There is no storage for
+// metadata, created timestamps, histograms or exemplars, so they are dropped
+// and returned as counts for the handler to report.
+func unmarshalWriteV2(buf []byte, req *prompb.WriteRequest) (droppedV2, error) {
+	var (
+		symbols []string
+		series  [][]byte
+		dropped droppedV2
+	)
+	r := protoReader{buf: buf}
+	for !r.done() {
+		num, wireType, err := r.key()
+		if err != nil {
+			return dropped, err
+		}
+		switch {
+		case num == 4 && wireType == 2:
+			symbol, err := r.bytes()
+			if err != nil {
+				return dropped, err
+			}
+			symbols = append(symbols, string(symbol))
+		case num == 5 && wireType == 2:
+			// the symbols may follow the series
+			ts, err := r.bytes()
+			if err != nil {
+				return dropped, err
+			}
+			series = append(series, ts)
+		default:
+			if err = r.skip(wireType); err != nil {
+				return dropped, err
+			}
+		}
+	}
+
+	req.Timeseries = req.Timeseries[:0]
+	for _, buf := range series {
+		ts, err := unmarshalTimeSeriesV2(buf, symbols, &dropped)
+		if err != nil {
+			return dropped, err
+		}
+		req.Timeseries = append(req.Timeseries, ts)
+	}
+	return dropped, nil
+}
+
+func unmarshalTimeSeriesV2(buf []byte, symbols []string, dropped *droppedV2) (prompb.TimeSeries, error) {
+	var (
+		ts   prompb.TimeSeries
+		refs []uint64
+	)
+	r := protoReader{buf: buf}
+	for !r.done() {
+		num, wireType, err := r.key()
+		if err != nil {
+			return ts, err
+		}
+		switch {
+		case num == 1 && wireType == 2:
+			packed, err := r.bytes()
+			if err != nil {
+				return ts, err
+			}
+			pr := protoReader{buf: packed}
+			for !pr.done() {
+				ref, err := pr.varint()
+				if err != nil {
+					return ts, err
+				}
+				refs = append(refs, ref)
+			}
+		case num == 1 && wireType == 0:
+			ref, err := r.varint()
+			if err != nil {
+				return ts, err
+			}
+			refs = append(refs, ref)
+		case num == 2 && wireType == 2:
+			sample, err := r.bytes()
+			if err != nil {
+				return ts, err
+			}
+			s, err := unmarshalSampleV2(sample)
+			if err != nil {
+				return ts, err
+			}
+			ts.Samples = append(ts.Samples, s)
+		case num == 3 && wireType == 2:
+			if err = r.skip(wireType); err != nil {
+				return ts, err
+			}
+			dropped.histograms++
+		case num == 4 && wireType == 2:
+			if err = r.skip(wireType); err != nil {
+				return ts, err
+			}
+			dropped.exemplars++
+		case num == 5 && wireType == 2:
+			// the metadata is always sent, empty if there is none
+			metadata, err := r.bytes()
+			if err != nil {
+				return ts, err
+			}
+			if len(metadata) > 0 {
+				dropped.metadata++
+			}
+		case num == 6 && wireType == 0:
+			created, err := r.varint()
+			if err != nil {
+				return ts, err
+			}
+			if created != 0 {
+				dropped.createdTimestamps++
+			}
+		default:
+			if err = r.skip(wireType); err != nil {
+				return ts, err
+			}
+		}
+	}
+
+	if len(refs)%2 != 0 {
+		return ts, fmt.Errorf("%w: odd number of label references", errInvalidProto)
+	}
+	ts.Labels = make([]prompb.Label, 0, len(refs)/2)
+	for i := 0; i < len(refs); i += 2 {
+		if refs[i] >= uint64(len(symbols)) || refs[i+1] >= uint64(len(symbols)) {
+			return ts, fmt.Errorf("%w: label reference out of the symbol table", errInvalidProto)
+		}
+		ts.Labels = append(ts.Labels, prompb.Label{Name: symbols[refs[i]], Value: symbols[refs[i+1]]})
+	}
+	return ts, nil
+}
+
+func unmarshalSampleV2(buf []byte) (prompb.Sample, error) {
+	var s prompb.Sample
+	r := protoReader{buf: buf}
+	for !r.done() {
+		num, wireType, err := r.key()
+		if err != nil {
+			return s, err
+		}
+		switch {
+		case num == 1 && wireType == 1:
+			v, err := r.fixed64()
+			if err != nil {
+				return s, err
+			}
+			s.Value = math.Float64frombits(v)
+		case num == 2 && wireType == 0:
+			v, err := r.varint()
+			if err != nil {
+				return s, err
+			}
+			s.Timestamp = int64(v)
+		default:
+			if err = r.skip(wireType); err != nil {
+				return s, err
+			}
+		}
+	}
+	return s, nil
+}
+
+// protoReader reads the fields of an encoded protobuf message.
+type protoReader struct {
+	buf []byte
+}
+
+func (r *protoReader) done() bool {
+	return len(r.buf) == 0
+}
+
+// key returns the field number and wire type of the next field.
+func (r *protoReader) key() (int, int, error) {
+	key, err := r.varint()
+	return int(key >> 3), int(key & 7), err
+}
+
+func (r *protoReader) varint() (uint64, error) {
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		return 0, errInvalidProto
+	}
+	r.buf = r.buf[n:]
+	return v, nil
+}
+
+func (r *protoReader) fixed64() (uint64, error) {
+	if len(r.buf) < 8 {
+		return 0, errInvalidProto
+	}
+	v := binary.LittleEndian.Uint64(r.buf)
+	r.buf = r.buf[8:]
+	return v, nil
+}
+
+func (r *protoReader) bytes() ([]byte, error) {
+	n, err := r.varint()
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(len(r.buf)) {
+		return nil, errInvalidProto
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b, nil
+}
+
+func (r *protoReader) skip(wireType int) error {
+	var err error
+	switch wireType {
+	case 0:
+		_, err = r.varint()
+	case 1:
+		_, err = r.fixed64()
+	case 2:
+		_, err = r.bytes()
+	case 5:
+		if len(r.buf) < 4 {
+			return errInvalidProto
+		}
+		r.buf = r.buf[4:]
+	default:
+		return fmt.Errorf("%w: unsupported wire type %d", errInvalidProto, wireType)
+	}
+	return err
+}
@@ -5,6 +5,9 @@ import (
 	"flag"
 	"fmt"
 	"runtime"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 
@@ -21,18 +24,82 @@ import (
 
 // Config for the database
 type Config struct {
-	host             string
-	port             int
-	user             string
-	password         string
-	database         string
-	sslMode          string
-	dbConnectRetries int
-	AsyncAcks        bool
-	ReportInterval   int
-	LabelsCacheSize  uint64
-	MetricsCacheSize uint64
-	SeriesCacheSize  uint64
+	host                    string
+	port                    int
+	user                    string
+	password                string
+	database                string
+	sslMode                 string
+	dbConnectRetries        int
+	dbConnectBackoff        time.Duration
+	AsyncAcks               bool
+	ReportInterval          int
+	LabelsCacheSize         uint64
+	MetricsCacheSize        uint64
+	SeriesCacheSize         uint64
+	AlignToStep             bool
+	AllowUnbounded          bool
+	ConflictTarget          string
+	SeriesInsertConcurrency int
+	WarnOnRetention         bool
+	InsertMaxRetries        int
+	ValuePrecision          string
+	DuplicatePolicy         string
+	EnrichmentTable         string
+	EnrichmentKeyColumn     string
+	EnrichmentKeyLabel      string
+	EnrichmentColumns       string
+	InterpolationMaxGap     time.Duration
+	EmptyLabelsPolicy       string
+	MaxMetricsPerQuery      int
+	MaxMetricsWarnOnly      bool
+	QueryFormat             string
+	InsertFormat            string
+	ReadMetricNamePrefix    string
+	ReadRetryDelay          time.Duration
+	ReadWaitForMigrations   bool
+	InsertBatchSize         int
+	InsertBatchMaxAge       time.Duration
+	ReadValueFilter         string
+	ReadStaleGap            time.Duration
+	ReadPropagateCancel     bool
+	InsertTimeBucket        time.Duration
+	ReadCaseInsensitive     string
+	ReadFailOnDecodeErrors  bool
+	ReadMaxQueryDuration    time.Duration
+	EmptyInsertPolicy       string
+	DBMaxConnections        int
+	DisabledMetrics         string
+	ReadMonotonicCounters   string
+	NullValues              string
+	DBIngestRole            string
+	DBReadRole              string
+	MaxLabelsSize           int
+	TimestampRangePolicy    string
+	ReadLabelRewrites       string
+	ReadMergeRewritten      bool
+	ChunkIntervals          string
+	TenantLabel             string
+	TenantHeader            string
+	DefaultTenant           string
+	TenantHonorSeriesLabels bool
+	ReadIgnoreExtraColumns  bool
+	ReadLabelQueryRetries   int
+	OverloadQueueDepth      int
+	OverloadPoolSaturation  float64
+	OverloadRetryAfter      time.Duration
+	ReadInfiniteTimestamps  string
+	MaxLabelValueLength     int
+	InsertBufferDir         string
+	InsertBufferMaxBytes    int64
+	InsertBufferReplay      time.Duration
+	InsertBufferConcurrency int
+	ReadCheckLabelNames     bool
+	PartialWrites           bool
+	InsertSortRows          bool
+	ReadContinuousAggs      string
+	ReadUnionMetricTables   bool
+	InsertTarget            pgmodel.InsertTarget
 }
 
 // ParseFlags parses the configuration flags specific to PostgreSQL and TimescaleDB
@@ -43,11 +110,79 @@ func ParseFlags(cfg *Config) *Config {
 	flag.StringVar(&cfg.password, "db-password", "", "The TimescaleDB password")
 	flag.StringVar(&cfg.database, "db-name", "timescale", "The TimescaleDB database")
 	flag.StringVar(&cfg.sslMode, "db-ssl-mode", "disable", "The TimescaleDB connection ssl mode")
-	flag.IntVar(&cfg.dbConnectRetries, "db-connect-retries", 0, "How many times to retry connecting to the database")
+	flag.IntVar(&cfg.DBMaxConnections, "db-max-connections", 0, fmt.Sprintf("maximum number of connections to the database. One connection per CPU is kept for reads, the rest insert samples in parallel. Defaults to %d per CPU if 0", pgmodel.ConnectionsPerProc))
+	flag.StringVar(&cfg.DBIngestRole, "db-ingest-role", "", "role the connections inserting samples switch to with SET ROLE after connecting, e.g. prom_writer. Migrations run as the connecting user. No switch if empty")
+	flag.StringVar(&cfg.DBReadRole, "db-read-role", "", "role the connections of reads switch to with SET ROLE after connecting, e.g. prom_reader. If it differs from the ingest role, reads use a pool of their own with one connection per CPU. No switch if empty")
+	flag.IntVar(&cfg.dbConnectRetries, "db-connect-retries", 0, "how many times to retry connecting to the database")
+	flag.DurationVar(&cfg.dbConnectBackoff, "db-connect-retry-backoff", time.Second, "delay before the first retry of connecting to the database, doubled for every further retry up to 30s")
+	flag.StringVar(&cfg.QueryFormat, "db-query-format", "binary", "wire format query results are read in [ \"binary\", \"text\" ]. Text reads staleness markers as plain NaN")
+	flag.StringVar(&cfg.InsertFormat, "db-insert-format", "binary", "wire format of the statements inserting samples [ \"binary\", \"text\" ]. Samples holding staleness markers are always inserted in binary")
 	flag.BoolVar(&cfg.AsyncAcks, "async-acks", false, "Ack before data is written to DB")
 	flag.IntVar(&cfg.ReportInterval, "tput-report", 0, "interval in seconds at which throughput should be reported")
 	flag.Uint64Var(&cfg.LabelsCacheSize, "labels-cache-size", 10000, "maximum number of labels to cache")
 	flag.Uint64Var(&cfg.MetricsCacheSize, "metrics-cache-size", pgmodel.DefaultMetricCacheSize, "maximum number of metric names to cache")
+	flag.Uint64Var(&cfg.SeriesCacheSize, "series-cache-size", pgmodel.DefaultSeriesCacheSize, "maximum number of series ids to cache on the write path, so that the series of every scrape are not looked up in the database again")
+	flag.BoolVar(&cfg.AlignToStep, "align-to-step", false, "snap samples returned for step queries onto the step grid")
+	flag.DurationVar(&cfg.ReadStaleGap, "read-stale-gap", 0, "mark series stale between two samples further apart than this, so PromQL does not connect samples across outages. Disabled if 0")
+	flag.DurationVar(&cfg.InterpolationMaxGap, "interpolation-max-gap", 0, "linearly interpolate the steps missing between samples at most this far apart, requires -align-to-step. Disabled if 0")
+	flag.StringVar(&cfg.ReadMetricNamePrefix, "read-metric-name-prefix", "", "prefix added to the metric names of all series read, e.g. to namespace metrics federated into another Prometheus. Queries still use the stored names")
+	flag.DurationVar(&cfg.ReadRetryDelay, "read-schema-change-retry-delay", pgmodel.DefaultSchemaChangeRetryDelay, "delay before a read failing because of a concurrent schema change is retried once. Not retried if 0")
+	flag.StringVar(&cfg.ReadCaseInsensitive, "read-case-insensitive-labels", "", "comma-separated names of the labels whose values are matched case-insensitively, e.g. 'host,instance'. The metric name is always matched exactly")
+	flag.BoolVar(&cfg.ReadFailOnDecodeErrors, "read-fail-on-decode-errors", true, "fail reads of series with samples that could not be decoded. If false, such samples are skipped")
+	flag.StringVar(&cfg.ReadLabelRewrites, "read-label-rewrites", "", "semicolon-separated rewrites of label values on read of the form <label>=~<regex>-><replacement>, e.g. 'pod=~(.+)-[a-z0-9]{5}->$1'. Queries still match the stored values")
+	flag.DurationVar(&cfg.ReadMaxQueryDuration, "read-max-query-duration", 0, "maximum time the database queries of a read may take, including reading their results. Slower reads are canceled. No limit if 0")
+	flag.BoolVar(&cfg.ReadIgnoreExtraColumns, "read-ignore-extra-columns", false, "skip the columns of series rows after the label ids, times and values, e.g. columns added by a newer schema, instead of failing the read")
+	flag.IntVar(&cfg.ReadLabelQueryRetries, "read-label-query-retries", 0, "number of times a failed query of the labels of the series read is retried before the read fails")
+	flag.StringVar(&cfg.ReadInfiniteTimestamps, "read-infinite-timestamps", "keep", "what happens to samples stored at an infinite time, which only queries without a start or end read [ \"keep\", \"warn\", \"drop\" ]. \"warn\" logs the series read with such samples, \"drop\" skips them")
+	flag.StringVar(&cfg.ReadContinuousAggs, "read-continuous-aggregates", "", "comma-separated continuous aggregates of the form <metric>:<resolution>=[<schema>.]<view>, e.g. 'cpu_usage:1h=cpu_usage_1h'. Reads of the metric with a step and a range, or lookback delta, of at least the resolution are answered from the aggregate, the coarsest one matching first. The view needs the time, value and series_id columns of the metric's data table")
+	flag.BoolVar(&cfg.ReadCheckLabelNames, "read-check-label-names", false, "look up the label names the matchers of a read require first, returning no series without querying the data tables if one does not exist. Costs an extra query for reads matching on labels other than the metric name")
+	flag.BoolVar(&cfg.ReadMergeRewritten, "read-merge-rewritten-series", false, "merge the series read that have the same labels after -read-label-rewrites. Of samples at the same time, the one of the series read first is kept")
+	flag.StringVar(&cfg.ReadMonotonicCounters, "read-monotonic-counters", "", "comma-separated names of counter metrics whose resets are merged on read, offsetting the values after a reset so the series never decreases")
+	flag.BoolVar(&cfg.ReadPropagateCancel, "read-propagate-cancel", true, "cancel the database queries of reads whose request was canceled, e.g. by a timeout. The connections of canceled queries are closed")
+	flag.BoolVar(&cfg.ReadWaitForMigrations, "read-wait-for-migrations", false, "make reads wait for running schema migrations to finish")
+	flag.StringVar(&cfg.ReadValueFilter, "read-value-filter", "", "only return series whose values over the query range pass this filter, e.g. 'last>0.9'. Aggregates are last, max, min and avg. All series are returned if empty")
+	flag.StringVar(&cfg.DisabledMetrics, "disabled-metrics", "", "comma-separated names of metrics whose samples are dropped rather than written. Writes can be enabled again at runtime")
+	flag.StringVar(&cfg.EmptyInsertPolicy, "empty-insert-policy", "ignore", "what happens when no sample of a non-empty batch is inserted, usually because of a trigger or constraint of the data table [ \"ignore\", \"warn\", \"error\" ]. Ignored samples are counted as duplicates")
+	flag.IntVar(&cfg.MaxLabelsSize, "max-labels-size", 0, "maximum combined size in bytes of the label names and values of a series. Write requests with larger series are rejected. No limit if 0")
+	flag.BoolVar(&cfg.PartialWrites, "partial-writes", false, "reject only the series of a write request that can not be stored, e.g. with invalid labels, and insert the others. The response has a Warning header counting the rejected series. If false, such requests fail as a whole")
+	flag.BoolVar(&cfg.InsertSortRows, "insert-sort-rows", false, "sort the samples of every insert by series and time. Samples of a series at the same time keep the order they were received in")
+	flag.StringVar(&cfg.InsertTarget.Schema, "insert-schema", "", "schema of the data tables samples are inserted into. Defaults to the schema of the standard data tables if empty")
+	flag.StringVar(&cfg.InsertTarget.TimeColumn, "insert-time-column", "", "column of the data tables samples are inserted into storing their time. Defaults to 'time' if empty")
+	flag.StringVar(&cfg.InsertTarget.ValueColumn, "insert-value-column", "", "column of the data tables samples are inserted into storing their value. Defaults to 'value' if empty")
+	flag.StringVar(&cfg.InsertTarget.SeriesIDColumn, "insert-series-id-column", "", "column of the data tables samples are inserted into storing their series id. Defaults to 'series_id' if empty")
+	flag.IntVar(&cfg.MaxLabelValueLength, "max-label-value-length", 0, "maximum length in bytes of a label value. Write requests with longer values are rejected. No limit if 0")
+	flag.StringVar(&cfg.ChunkIntervals, "chunk-intervals", "", "comma-separated metric=interval pairs setting the chunk interval of the data tables of metrics when the connector creates them, e.g. 'node_cpu_seconds_total=2h'. Other metrics use the default chunk interval of the database, 8 hours unless changed")
+	flag.StringVar(&cfg.TenantLabel, "tenant-label", "", "label storing the tenant of every series, which isolates the data of tenants sharing the connector. Reads only return the series of their tenant. Disabled if empty")
+	flag.StringVar(&cfg.TenantHeader, "tenant-header", "X-Scope-OrgID", "HTTP header naming the tenant of a request, used if tenant-label is set. Requests without it are requests of the default tenant")
+	flag.BoolVar(&cfg.TenantHonorSeriesLabels, "tenant-honor-series-labels", false, "keep the tenant label of the series written without the tenant header, instead of replacing it with the default tenant. Only enable for clients trusted to write the data of any tenant")
+	flag.StringVar(&cfg.DefaultTenant, "default-tenant", "", "tenant of the requests and series without one, used if tenant-label is set. If empty, such requests are rejected")
+	flag.IntVar(&cfg.OverloadQueueDepth, "overload-queue-depth", 0, "number of write requests queued for insertion above which writes are rejected with 503 Service Unavailable, which Prometheus retries after Retry-After. Not checked if 0")
+	flag.Float64Var(&cfg.OverloadPoolSaturation, "overload-pool-saturation", 0, "fraction of the connections inserting samples that are busy above which writes are rejected with 503 Service Unavailable, e.g. 0.9. Not checked if 0")
+	flag.DurationVar(&cfg.OverloadRetryAfter, "overload-retry-after", time.Second, "how long clients of rejected writes are asked to wait before retrying, sent as the Retry-After header")
+	flag.StringVar(&cfg.NullValues, "null-values", "", "comma-separated sample values stored as NULL to mark explicit gaps of sparse series, e.g. '-1,NaN'. Reads skip them like missing samples. Stale markers are always stored as they are")
+	flag.StringVar(&cfg.TimestampRangePolicy, "timestamp-range-policy", "error", "what happens to samples with timestamps PostgreSQL can not store [ \"error\", \"drop\", \"clamp\" ]. \"error\" fails the write request, \"clamp\" moves the samples to the nearest time that can be stored")
+	flag.StringVar(&cfg.EmptyLabelsPolicy, "empty-labels-policy", "keep", "how series read without any labels are returned [ \"keep\", \"drop\", \"label\" ], \"label\" adds the label unlabeled_series=\"true\"")
+	flag.IntVar(&cfg.MaxMetricsPerQuery, "max-metrics-per-query", 0, "maximum number of metrics a single query may match, e.g. through a regex on __name__. Unlimited if 0")
+	flag.BoolVar(&cfg.ReadUnionMetricTables, "read-union-metric-tables", false, "read the series of queries matching several metrics with a single UNION ALL query over their tables rather than with a query per table. The number of tables is bounded by -max-metrics-per-query")
+	flag.BoolVar(&cfg.MaxMetricsWarnOnly, "max-metrics-warn-only", false, "only log a warning for queries over -max-metrics-per-query instead of failing them")
+	flag.BoolVar(&cfg.AllowUnbounded, "allow-unbounded-queries", false, "allow queries whose label matchers select every series, e.g. {__name__=~\".+\"}. Such queries fail unless set")
+	flag.StringVar(&cfg.ConflictTarget, "conflict-target", "", "comma-separated columns of the unique constraint samples are deduplicated on, e.g. 'series_id,time'. Conflicts on any constraint are ignored if empty")
+	flag.IntVar(&cfg.SeriesInsertConcurrency, "series-insert-concurrency", 1, "maximum number of concurrent batches used to create new series of a metric")
+	flag.IntVar(&cfg.InsertBatchSize, "insert-batch-size", 2000, "maximum number of series of a metric inserted in one batch")
+	flag.DurationVar(&cfg.InsertTimeBucket, "insert-time-bucket", 0, "split every batch of samples into one insert per time range of this width, e.g. the chunk interval, so each insert writes into a single chunk. Disabled if 0")
+	flag.DurationVar(&cfg.InsertBatchMaxAge, "insert-batch-max-age", 0, "how long an incomplete batch waits for more samples of its metric before it is inserted. Sent as soon as no more samples are queued if 0")
+	flag.StringVar(&cfg.InsertBufferDir, "insert-buffer-dir", "", "directory write requests are buffered in while the database is unavailable, acking them to Prometheus. They are inserted once the database is back, while new write requests are inserted directly, so the samples of a series may be inserted out of order. Disabled if empty")
+	flag.Int64Var(&cfg.InsertBufferMaxBytes, "insert-buffer-max-bytes", 1<<30, "maximum size in bytes of the insert buffer. The oldest buffered write requests are dropped once it is full. No limit if 0")
+	flag.DurationVar(&cfg.InsertBufferReplay, "insert-buffer-replay-interval", pgmodel.DefaultInsertBufferReplayInterval, "how often the insert buffer is replayed while the database is unavailable")
+	flag.IntVar(&cfg.InsertBufferConcurrency, "insert-buffer-replay-concurrency", pgmodel.DefaultInsertBufferReplayConcurrency, "number of routines replaying the insert buffer in parallel. The buffered samples of a series are always replayed in order")
+	flag.BoolVar(&cfg.WarnOnRetention, "warn-on-retention", false, "warn when a query's time range ends before the retention boundary of the queried metric")
+	flag.IntVar(&cfg.InsertMaxRetries, "insert-max-retries", 3, "how many times to retry inserting samples after a transient database error")
+	flag.StringVar(&cfg.DuplicatePolicy, "duplicate-policy", "keep-all", "how samples of a series sharing a timestamp within one write are handled [ \"keep-all\", \"keep-last\", \"keep-first\", \"error\" ]")
+	flag.StringVar(&cfg.EnrichmentTable, "label-enrichment-table", "", "table holding extra labels added to the series returned by queries, disabled if empty")
+	flag.StringVar(&cfg.EnrichmentKeyColumn, "label-enrichment-key-column", "", "column of the label enrichment table matched against the key label")
+	flag.StringVar(&cfg.EnrichmentKeyLabel, "label-enrichment-key-label", "", "label whose value selects the row of the label enrichment table")
+	flag.StringVar(&cfg.EnrichmentColumns, "label-enrichment-columns", "", "comma-separated columns of the label enrichment table added as labels")
+	flag.StringVar(&cfg.ValuePrecision, "value-precision", "", "comma-separated metric=figures pairs rounding the values read for a metric to the given number of significant figures, e.g. 'cpu_usage=3'. Values are exact if empty")
 	return cfg
 }
 
@@ -60,10 +195,94 @@ type Client struct {
 	cfg           *Config
 	ConnectionStr string
 	metricCache   *pgmodel.MetricNameCache
+	metricSwitch  *pgmodel.MetricSwitch
+	// readPool is the pool of the reads, Connection unless they run under
+	// a role of their own
+	readPool *pgxpool.Pool
 }
 
 // NewClient creates a new PostgreSQL client
 func NewClient(cfg *Config, readHist prometheus.ObserverVec) (*Client, error) {
+	valuePrecision, err := parseValuePrecision(cfg.ValuePrecision)
+	if err != nil {
+		log.Error("err parsing value precision", err)
+		return nil, err
+	}
+	duplicatePolicy, err := pgmodel.ParseDuplicatePolicy(cfg.DuplicatePolicy)
+	if err != nil {
+		log.Error("err parsing duplicate policy", err)
+		return nil, err
+	}
+	emptyLabelsPolicy, err := pgmodel.ParseEmptyLabelsPolicy(cfg.EmptyLabelsPolicy)
+	if err != nil {
+		log.Error("err parsing empty labels policy", err)
+		return nil, err
+	}
+	infiniteTimestampPolicy, err := pgmodel.ParseInfiniteTimestampPolicy(cfg.ReadInfiniteTimestamps)
+	if err != nil {
+		log.Error("err parsing infinite timestamp policy", err)
+		return nil, err
+	}
+	emptyInsertPolicy, err := pgmodel.ParseEmptyInsertPolicy(cfg.EmptyInsertPolicy)
+	if err != nil {
+		log.Error("err parsing empty insert policy", err)
+		return nil, err
+	}
+	timestampRangePolicy, err := pgmodel.ParseTimestampRangePolicy(cfg.TimestampRangePolicy)
+	if err != nil {
+		log.Error("err parsing timestamp range policy", err)
+		return nil, err
+	}
+	nullValues, err := parseNullValues(cfg.NullValues)
+	if err != nil {
+		log.Error("err parsing null values", err)
+		return nil, err
+	}
+	chunkIntervals, err := parseChunkIntervals(cfg.ChunkIntervals)
+	if err != nil {
+		log.Error("err parsing chunk intervals", err)
+		return nil, err
+	}
+	queryFormat, err := pgmodel.ParseWireFormat(cfg.QueryFormat)
+	if err != nil {
+		log.Error("err parsing query format", err)
+		return nil, err
+	}
+	insertFormat, err := pgmodel.ParseWireFormat(cfg.InsertFormat)
+	if err != nil {
+		log.Error("err parsing insert format", err)
+		return nil, err
+	}
+	valueFilter, err := pgmodel.ParseValueFilter(cfg.ReadValueFilter)
+	if err != nil {
+		log.Error("err parsing value filter", err)
+		return nil, err
+	}
+	labelRewrites, err := pgmodel.ParseLabelRewrites(cfg.ReadLabelRewrites)
+	if err != nil {
+		log.Error("err parsing label rewrites", err)
+		return nil, err
+	}
+	continuousAggs, err := pgmodel.ParseContinuousAggregates(cfg.ReadContinuousAggs)
+	if err != nil {
+		log.Error("err parsing continuous aggregates", err)
+		return nil, err
+	}
+	enrichment, err := cfg.labelEnrichment()
+	if err != nil {
+		log.Error("err parsing label enrichment", err)
+		return nil, err
+	}
+
+	var insertBuffer *pgmodel.InsertBuffer
+	if cfg.InsertBufferDir != "" {
+		insertBuffer, err = pgmodel.OpenInsertBuffer(cfg.InsertBufferDir, cfg.InsertBufferMaxBytes)
+		if err != nil {
+			log.Error("err opening insert buffer", err)
+			return nil, err
+		}
+	}
+
 	connectionStr := cfg.GetConnectionStr()
 
 	maxProcs := runtime.GOMAXPROCS(-1)
@@ -73,7 +292,20 @@ func NewClient(cfg *Config, readHist prometheus.ObserverVec) (*Client, error) {
 	if maxProcs <= 0 {
 		maxProcs = 1
 	}
-	connectionPool, err := pgxpool.Connect(context.Background(), connectionStr+fmt.Sprintf(" pool_max_conns=%d pool_min_conns=%d", maxProcs*pgmodel.ConnectionsPerProc, maxProcs))
+	maxConns := cfg.DBMaxConnections
+	if maxConns <= 0 {
+		maxConns = maxProcs * pgmodel.ConnectionsPerProc
+	}
+	minConns := maxProcs
+	if minConns > maxConns {
+		minConns = maxConns
+	}
+	// the copiers share the pool with reads if it is that small
+	numCopiers := maxConns - maxProcs
+	if numCopiers < 1 {
+		numCopiers = 1
+	}
+	connectionPool, err := cfg.ConnectAs(connectionStr+fmt.Sprintf(" pool_max_conns=%d pool_min_conns=%d", maxConns, minConns), cfg.DBIngestRole)
 
 	log.Info("msg", util.MaskPassword(connectionStr))
 
@@ -81,33 +313,247 @@ func NewClient(cfg *Config, readHist prometheus.ObserverVec) (*Client, error) {
 		log.Error("err creating connection pool for new client", util.MaskPassword(err.Error()))
 		return nil, err
 	}
+	// connections switch roles for good, so reads under another role need
+	// connections of their own
+	readPool := connectionPool
+	if cfg.DBReadRole != cfg.DBIngestRole {
+		readPool, err = cfg.ConnectAs(connectionStr+fmt.Sprintf(" pool_max_conns=%d pool_min_conns=%d", maxProcs, minConns), cfg.DBReadRole)
+		if err != nil {
+			log.Error("err creating read connection pool for new client", util.MaskPassword(err.Error()))
+			connectionPool.Close()
+			return nil, err
+		}
+	}
 
 	cache := &pgmodel.MetricNameCache{Metrics: clockcache.WithMax(cfg.MetricsCacheSize)}
 
 	c := pgmodel.Cfg{
-		AsyncAcks:       cfg.AsyncAcks,
-		ReportInterval:  cfg.ReportInterval,
-		SeriesCacheSize: cfg.SeriesCacheSize,
+		AsyncAcks:               cfg.AsyncAcks,
+		ReportInterval:          cfg.ReportInterval,
+		SeriesCacheSize:         cfg.SeriesCacheSize,
+		SeriesInsertConcurrency: cfg.SeriesInsertConcurrency,
+		InsertRetryPolicy:       pgmodel.DefaultRetryPolicy(cfg.InsertMaxRetries),
+		DuplicatePolicy:         duplicatePolicy,
+		InsertFormat:            insertFormat,
+		MaxBatchSize:            cfg.InsertBatchSize,
+		MaxBatchAge:             cfg.InsertBatchMaxAge,
+		InsertTimeBucket:        cfg.InsertTimeBucket,
+		EmptyInsertPolicy:       emptyInsertPolicy,
+		NumCopiers:              numCopiers,
+		NullValues:              nullValues,
+		MaxLabelsSize:           cfg.MaxLabelsSize,
+		MaxLabelValueLength:     cfg.MaxLabelValueLength,
+		TimestampRangePolicy:    timestampRangePolicy,
+		MetricChunkIntervals:    chunkIntervals,
+		Tenancy:                 cfg.tenancy(),
+		OverloadPolicy: pgmodel.OverloadPolicy{
+			MaxQueueDepth:     cfg.OverloadQueueDepth,
+			MaxPoolSaturation: cfg.OverloadPoolSaturation,
+			RetryAfter:        cfg.OverloadRetryAfter,
+		},
+		InsertBuffer:                  insertBuffer,
+		InsertBufferReplayInterval:    cfg.InsertBufferReplay,
+		InsertBufferReplayConcurrency: cfg.InsertBufferConcurrency,
+		PartialWrites:                 cfg.PartialWrites,
+		SortRows:                      cfg.InsertSortRows,
+		InsertTarget:                  cfg.InsertTarget,
+	}
+	if cfg.ConflictTarget != "" {
+		for _, col := range strings.Split(cfg.ConflictTarget, ",") {
+			c.ConflictTarget = append(c.ConflictTarget, strings.TrimSpace(col))
+		}
+	}
+	metricSwitch := pgmodel.NewMetricSwitch()
+	for _, metric := range strings.Split(cfg.DisabledMetrics, ",") {
+		if metric = strings.TrimSpace(metric); metric != "" {
+			metricSwitch.Disable(metric)
+		}
 	}
+	c.IngestStages = append(c.IngestStages, metricSwitch.Stage())
 	ingestor, err := pgmodel.NewPgxIngestorWithMetricCache(connectionPool, cache, &c)
 	if err != nil {
 		log.Error("err starting ingestor", err)
+		connectionPool.Close()
+		if readPool != connectionPool {
+			readPool.Close()
+		}
 		return nil, err
 	}
-	reader := pgmodel.NewPgxReaderWithMetricCache(connectionPool, cache, cfg.LabelsCacheSize)
+	readRetryDelay := cfg.ReadRetryDelay
+	if readRetryDelay == 0 {
+		// not retried, the reader uses its default for 0
+		readRetryDelay = -1
+	}
+	readerCfg := pgmodel.ReaderCfg{
+		LabelsCacheSize: cfg.LabelsCacheSize,
+		AlignToStep:     cfg.AlignToStep,
+		AllowUnbounded:  cfg.AllowUnbounded,
+		WarnOnRetention: cfg.WarnOnRetention,
+		ValuePrecision:  valuePrecision,
+		LabelEnrichment: enrichment,
+		// gaps are only interpolated for samples aligned to the step grid
+		InterpolationMaxGap: cfg.InterpolationMaxGap,
+		EmptyLabelsPolicy:   emptyLabelsPolicy,
+		MaxMetricsPerQuery:  cfg.MaxMetricsPerQuery,
+		MaxMetricsWarnOnly:  cfg.MaxMetricsWarnOnly,
+		QueryFormat:         queryFormat,
+		MetricNamePrefix:    cfg.ReadMetricNamePrefix,
+		// coordination with migrations run by other connectors
+		SchemaChangeRetryDelay:  readRetryDelay,
+		WaitForMigrations:       cfg.ReadWaitForMigrations,
+		ValueFilter:             valueFilter,
+		StaleGap:                cfg.ReadStaleGap,
+		IgnoreCancel:            !cfg.ReadPropagateCancel,
+		SkipDecodeErrors:        !cfg.ReadFailOnDecodeErrors,
+		MaxQueryDuration:        cfg.ReadMaxQueryDuration,
+		LabelRewrites:           labelRewrites,
+		MergeRewrittenSeries:    cfg.ReadMergeRewritten,
+		Tenancy:                 cfg.tenancy(),
+		IgnoreExtraColumns:      cfg.ReadIgnoreExtraColumns,
+		LabelQueryRetries:       cfg.ReadLabelQueryRetries,
+		InfiniteTimestampPolicy: infiniteTimestampPolicy,
+		CheckLabelNames:         cfg.ReadCheckLabelNames,
+		ContinuousAggregates:    continuousAggs,
+		UnionMetricTables:       cfg.ReadUnionMetricTables,
+	}
+	for _, name := range strings.Split(cfg.ReadCaseInsensitive, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			readerCfg.CaseInsensitiveLabels = append(readerCfg.CaseInsensitiveLabels, name)
+		}
+	}
+	for _, name := range strings.Split(cfg.ReadMonotonicCounters, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			readerCfg.MonotonicCounters = append(readerCfg.MonotonicCounters, name)
+		}
+	}
+	reader := pgmodel.NewPgxReaderWithCfg(readPool, cache, &readerCfg)
 
 	queryable := query.NewQueryable(reader.GetQuerier())
 
 	return &Client{
-		Connection:  connectionPool,
-		ingestor:    ingestor,
-		reader:      reader,
-		queryable:   queryable,
-		cfg:         cfg,
-		metricCache: cache,
+		Connection:   connectionPool,
+		ingestor:     ingestor,
+		reader:       reader,
+		queryable:    queryable,
+		cfg:          cfg,
+		metricCache:  cache,
+		metricSwitch: metricSwitch,
+		readPool:     readPool,
 	}, nil
 }
 
+// parseValuePrecision parses a comma-separated list of metric=figures pairs.
+func parseValuePrecision(s string) (map[string]int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	precision := make(map[string]int)
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid value precision %q, expected metric=figures", pair)
+		}
+		figs, err := strconv.Atoi(kv[1])
+		if err != nil || figs <= 0 {
+			return nil, fmt.Errorf("invalid number of significant figures for metric %q: %q", kv[0], kv[1])
+		}
+		precision[kv[0]] = figs
+	}
+	return precision, nil
+}
+
+// parseChunkIntervals parses a comma-separated list of metric=interval pairs.
+func parseChunkIntervals(s string) (map[string]time.Duration, error) {
+	if s == "" {
+		return nil, nil
+	}
+	intervals := make(map[string]time.Duration)
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid chunk interval %q, expected metric=interval", pair)
+		}
+		interval, err := time.ParseDuration(kv[1])
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("invalid chunk interval for metric %q: %q", kv[0], kv[1])
+		}
+		intervals[kv[0]] = interval
+	}
+	return intervals, nil
+}
+
+// parseNullValues parses a comma-separated list of sample values.
+func parseNullValues(s string) ([]float64, error) {
+	var values []float64
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid null value %q", v)
+		}
+		values = append(values, f)
+	}
+	return values, nil
+}
+
+// tenancy returns the configured tenancy, or nil if it is disabled.
+func (cfg *Config) tenancy() *pgmodel.Tenancy {
+	if cfg.TenantLabel == "" {
+		return nil
+	}
+	return &pgmodel.Tenancy{Label: cfg.TenantLabel, DefaultTenant: cfg.DefaultTenant, HonorSeriesLabels: cfg.TenantHonorSeriesLabels}
+}
+
+// labelEnrichment returns the configured label enrichment, or nil if it is
+// disabled.
+func (cfg *Config) labelEnrichment() (*pgmodel.LabelEnrichment, error) {
+	if cfg.EnrichmentTable == "" {
+		return nil, nil
+	}
+	e := &pgmodel.LabelEnrichment{
+		Table:     cfg.EnrichmentTable,
+		KeyColumn: cfg.EnrichmentKeyColumn,
+		KeyLabel:  cfg.EnrichmentKeyLabel,
+	}
+	for _, col := range strings.Split(cfg.EnrichmentColumns, ",") {
+		if col = strings.TrimSpace(col); col != "" {
+			e.LabelColumns = append(e.LabelColumns, col)
+		}
+	}
+	if e.KeyColumn == "" || e.KeyLabel == "" || len(e.LabelColumns) == 0 {
+		return nil, fmt.Errorf("label enrichment table %s requires a key column, a key label and label columns", e.Table)
+	}
+	return e, nil
+}
+
+// Connect opens a connection pool with the connection string, retrying with
+// backoff while the database can not be reached, e.g. because it is still
+// starting up.
+func (cfg *Config) Connect(connStr string) (*pgxpool.Pool, error) {
+	return cfg.ConnectAs(connStr, "")
+}
+
+// ConnectAs opens a connection pool like Connect, whose connections switch to
+// the role after connecting. They keep the connecting user's role if role is
+// empty.
+func (cfg *Config) ConnectAs(connStr string, role string) (*pgxpool.Pool, error) {
+	poolCfg, err := pgxpool.ParseConfig(connStr)
+	if err != nil {
+		return nil, err
+	}
+	if role != "" {
+		poolCfg.AfterConnect = pgmodel.SetRoleAfterConnect(role)
+	}
+	var pool *pgxpool.Pool
+	err = util.RetryWithBackoff("connecting to the database", cfg.dbConnectRetries, cfg.dbConnectBackoff, func() (err error) {
+		pool, err = pgxpool.ConnectConfig(context.Background(), poolCfg)
+		return err
+	})
+	return pool, err
+}
+
 // GetConnectionStr returns a Postgres connection string
 func (cfg *Config) GetConnectionStr() string {
 	return fmt.Sprintf("host=%v port=%v user=%v dbname=%v password='%v' sslmode=%v connect_timeout=10",
@@ -117,16 +563,40 @@ func (cfg *Config) GetConnectionStr() string {
 // Close closes the client and performs cleanup
 func (c *Client) Close() {
 	c.ingestor.Close()
+	if c.readPool != c.Connection {
+		c.readPool.Close()
+	}
+}
+
+// Shutdown inserts the data the client buffered and closes its connections,
+// see pgmodel.Shutdowner. Reads sharing the connections fail afterwards.
+func (c *Client) Shutdown(ctx context.Context) error {
+	err := c.ingestor.Shutdown(ctx)
+	if c.readPool != c.Connection {
+		c.readPool.Close()
+	}
+	return err
 }
 
 // Ingest writes the timeseries object into the DB
-func (c *Client) Ingest(tts []prompb.TimeSeries, req *prompb.WriteRequest) (uint64, error) {
-	return c.ingestor.Ingest(tts, req)
+func (c *Client) Ingest(ctx context.Context, tts []prompb.TimeSeries, req *prompb.WriteRequest) (uint64, error) {
+	return c.ingestor.Ingest(ctx, tts, req)
+}
+
+// Overloaded reports whether writes should be retried later, see
+// pgmodel.OverloadReporter.
+func (c *Client) Overloaded() (time.Duration, error) {
+	return c.ingestor.Overloaded()
 }
 
 // Read returns the promQL query results
-func (c *Client) Read(req *prompb.ReadRequest) (*prompb.ReadResponse, error) {
-	return c.reader.Read(req)
+func (c *Client) Read(ctx context.Context, req *prompb.ReadRequest) (*prompb.ReadResponse, error) {
+	return c.reader.Read(ctx, req)
+}
+
+// ReadChunked reads the request, sending its response as frames of XOR chunks.
+func (c *Client) ReadChunked(ctx context.Context, req *prompb.ReadRequest, send func(*prompb.ChunkedReadResponse) error) error {
+	return c.reader.ReadChunked(ctx, req, send)
 }
 
 func (c *Client) NumCachedMetricNames() int {
@@ -145,6 +615,16 @@ func (c *Client) LabelsCacheCapacity() int {
 	return c.reader.GetQuerier().LabelsCacheCapacity()
 }
 
+// DisableMetric drops the samples of the metric from all further writes.
+func (c *Client) DisableMetric(metric string) {
+	c.metricSwitch.Disable(metric)
+}
+
+// EnableMetric resumes the writes of a disabled metric.
+func (c *Client) EnableMetric(metric string) {
+	c.metricSwitch.Enable(metric)
+}
+
 // HealthCheck checks that the client is properly connected
 func (c *Client) HealthCheck() error {
 	return c.reader.HealthCheck()
@@ -0,0 +1,118 @@
+// This file and its contents are licensed under the Apache License 2.0.
+// Please see the included NOTICE for copyright information and
+// LICENSE for a copy of the license.
+
+package pgmodel
+
+import (
+	"regexp"
+
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/timescale/timescale-prometheus/pkg/prompb"
+)
+
+const (
+	subQueryEQCaseInsensitive            = "labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $%d and lower(l.value) = lower($%d))"
+	subQueryEQMatchEmptyCaseInsensitive  = "NOT labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $%d and lower(l.value) != lower($%d))"
+	subQueryNEQCaseInsensitive           = "labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $%d and lower(l.value) != lower($%d))"
+	subQueryNEQMatchEmptyCaseInsensitive = "NOT labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $%d and lower(l.value) = lower($%d))"
+	subQueryRECaseInsensitive            = "labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $%d and l.value ~* $%d)"
+	subQueryREMatchEmptyCaseInsensitive  = "NOT labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $%d and l.value !~* $%d)"
+	subQueryNRECaseInsensitive           = "labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $%d and l.value !~* $%d)"
+	subQueryNREMatchEmptyCaseInsensitive = "NOT labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $%d and l.value ~* $%d)"
+)
+
+// caseInsensitiveSubQueries maps the sub queries of the matchers to their
+// case-insensitive variants.
+var caseInsensitiveSubQueries = map[string]string{
+	subQueryEQ:            subQueryEQCaseInsensitive,
+	subQueryEQMatchEmpty:  subQueryEQMatchEmptyCaseInsensitive,
+	subQueryNEQ:           subQueryNEQCaseInsensitive,
+	subQueryNEQMatchEmpty: subQueryNEQMatchEmptyCaseInsensitive,
+	subQueryRE:            subQueryRECaseInsensitive,
+	subQueryREMatchEmpty:  subQueryREMatchEmptyCaseInsensitive,
+	subQueryNRE:           subQueryNRECaseInsensitive,
+	subQueryNREMatchEmpty: subQueryNREMatchEmptyCaseInsensitive,
+}
+
+// caseInsensitiveLabels are the names of the labels whose values are matched
+// case-insensitively. The metric name is always matched exactly, since it
+// selects the metric's table.
+type caseInsensitiveLabels map[string]bool
+
+func newCaseInsensitiveLabels(names []string) caseInsensitiveLabels {
+	if len(names) == 0 {
+		return nil
+	}
+	c := make(caseInsensitiveLabels, len(names))
+	for _, name := range names {
+		if name != MetricNameLabelName {
+			c[name] = true
+		}
+	}
+	return c
+}
+
+// subQuery returns the sub query selecting the series matching m.
+func (c caseInsensitiveLabels) subQuery(m *labels.Matcher, sq string) string {
+	if !c[m.Name] {
+		return sq
+	}
+	return caseInsensitiveSubQueries[sq]
+}
+
+// filter returns case-insensitive Prometheus matchers equivalent to the
+// matchers on case-insensitive labels. The database lowercases values by its
+// own collation rules and evaluates regexps in its own dialect, so the series
+// it returns are filtered again with these. Nil is returned if no matcher is
+// case-insensitive.
+func (c caseInsensitiveLabels) filter(matchers []*labels.Matcher) ([]*labels.Matcher, error) {
+	var filter []*labels.Matcher
+	for _, m := range matchers {
+		if !c[m.Name] {
+			continue
+		}
+		var (
+			mtype labels.MatchType
+			value string
+		)
+		switch m.Type {
+		case labels.MatchEqual:
+			mtype, value = labels.MatchRegexp, regexp.QuoteMeta(m.Value)
+		case labels.MatchNotEqual:
+			mtype, value = labels.MatchNotRegexp, regexp.QuoteMeta(m.Value)
+		case labels.MatchRegexp, labels.MatchNotRegexp:
+			mtype, value = m.Type, "(?:"+m.Value+")"
+		default:
+			continue
+		}
+		f, err := labels.NewMatcher(mtype, m.Name, "(?i)"+value)
+		if err != nil {
+			return nil, err
+		}
+		filter = append(filter, f)
+	}
+	return filter, nil
+}
+
+// matchesLabels reports whether the labels, given as a lookup of their
+// values, pass all matchers of filter.
+func matchesLabels(filter []*labels.Matcher, value func(name string) string) bool {
+	for _, m := range filter {
+		if !m.Matches(value(m.Name)) {
+			return false
+		}
+	}
+	return true
+}
+
+// prompbLabelValue returns the value of the label with the given name, or the
+// empty string if there is no such label.
+func prompbLabelValue(lls []prompb.Label, name string) string {
+	for _, l := range lls {
+		if l.Name == name {
+			return l.Value
+		}
+	}
+	return ""
+}
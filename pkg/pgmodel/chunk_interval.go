@@ -0,0 +1,70 @@
+// This file and its contents are licensed under the Apache License 2.0.
+// Please see the included NOTICE for copyright information and
+// LICENSE for a copy of the license.
+
+package pgmodel
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const (
+	setDefaultChunkIntervalSQL  = "SELECT " + promSchema + ".set_default_chunk_interval($1::interval)"
+	setMetricChunkIntervalSQL   = "SELECT " + promSchema + ".set_metric_chunk_interval($1, $2::interval)"
+	resetMetricChunkIntervalSQL = "SELECT " + promSchema + ".reset_metric_chunk_interval($1)"
+)
+
+// ChunkIntervals manages the chunk intervals of the data tables of metrics,
+// the time range covered by each of their chunks. A new interval only
+// applies to the chunks created after it is set. Metrics without an interval
+// of their own use the default one, 8 hours unless changed.
+type ChunkIntervals struct {
+	conn pgxConn
+}
+
+// NewChunkIntervals returns a ChunkIntervals managing the intervals in the
+// database.
+func NewChunkIntervals(db *pgxpool.Pool) *ChunkIntervals {
+	return &ChunkIntervals{conn: &pgxConnImpl{conn: db}}
+}
+
+// SetDefault sets the chunk interval of the metrics without one of their own,
+// existing and new.
+func (c *ChunkIntervals) SetDefault(ctx context.Context, interval time.Duration) error {
+	if err := validateChunkInterval(interval); err != nil {
+		return err
+	}
+	_, err := c.conn.Exec(ctx, setDefaultChunkIntervalSQL, interval)
+	return err
+}
+
+// Set sets the chunk interval of the metric, overriding the default. The
+// metric is created if it does not exist yet.
+func (c *ChunkIntervals) Set(ctx context.Context, metric string, interval time.Duration) error {
+	return setMetricChunkInterval(ctx, c.conn, metric, interval)
+}
+
+// Reset makes the metric use the default chunk interval again.
+func (c *ChunkIntervals) Reset(ctx context.Context, metric string) error {
+	_, err := c.conn.Exec(ctx, resetMetricChunkIntervalSQL, metric)
+	return err
+}
+
+func setMetricChunkInterval(ctx context.Context, conn pgxConn, metric string, interval time.Duration) error {
+	if err := validateChunkInterval(interval); err != nil {
+		return err
+	}
+	_, err := conn.Exec(ctx, setMetricChunkIntervalSQL, metric, interval)
+	return err
+}
+
+func validateChunkInterval(interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid chunk interval %v, it must be positive", interval)
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+// This file and its contents are licensed under the Apache License 2.0.
+// Please see the included NOTICE for copyright information and
+// LICENSE for a copy of the license.
+
+package pgmodel
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/timescale/timescale-prometheus/pkg/prompb"
+)
+
+const (
+	// maxChunkSamples is the number of samples of the chunks of streamed
+	// reads, like the chunks of the Prometheus TSDB.
+	maxChunkSamples = 120
+	// maxChunkedFrameBytes is the size of the chunks of a frame of a
+	// streamed read above which the frame is sent, 1MiB like Prometheus.
+	maxChunkedFrameBytes = 1 << 20
+)
+
+// ChunkedReader reads remote read requests as STREAMED_XOR_CHUNKS responses,
+// sending every frame of the response as it is complete rather than
+// buffering the whole response.
+type ChunkedReader interface {
+	ReadChunked(ctx context.Context, req *prompb.ReadRequest, send func(*prompb.ChunkedReadResponse) error) error
+}
+
+// ChunkedQuerier queries the data of a remote read query as XOR chunks.
+type ChunkedQuerier interface {
+	QueryChunked(ctx context.Context, query *prompb.Query, queryIndex int64, send func(*prompb.ChunkedReadResponse) error) error
+}
+
+// ReadChunked reads the queries of the request in order, sending the frames
+// of their series. Series are sent in the order they are read, which is not
+// sorted by labels.
+func (r *DBReader) ReadChunked(ctx context.Context, req *prompb.ReadRequest, send func(*prompb.ChunkedReadResponse) error) error {
+	q, ok := r.db.(ChunkedQuerier)
+	if !ok {
+		return fmt.Errorf("querier does not support streamed reads")
+	}
+	if req == nil {
+		return nil
+	}
+	for i, query := range req.Queries {
+		if err := q.QueryChunked(ctx, query, int64(i), send); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// QueryChunked implements ChunkedQuerier. The series are read in batches, so
+// only a batch of series and a frame of chunks are held in memory at any
+// time.
+func (q *pgxQuerier) QueryChunked(ctx context.Context, query *prompb.Query, queryIndex int64, send func(*prompb.ChunkedReadResponse) error) error {
+	if query == nil {
+		return nil
+	}
+	matchers, err := FromLabelMatchers(query.Matchers)
+	if err != nil {
+		return err
+	}
+
+	// Samples are downsampled in the database if the hints ask for it.
+	rows, _, err := q.getResultRows(ctx, query.StartTimestampMs, query.EndTimestampMs, nil, nil, matchers, newReadHintsBucket(query.Hints))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, r := range rows {
+			r.Close()
+		}
+	}()
+
+	ss, _, err := buildSeriesSet(rows, false, nil, matchers, q)
+	if err != nil {
+		return err
+	}
+	return streamChunkedSeries(ss, queryIndex, maxChunkedFrameBytes, send)
+}
+
+// streamChunkedSeries sends the series of the set as frames of XOR chunks.
+// A frame is sent once its chunks take maxFrameBytes, splitting the chunks of
+// a series over several frames if needed. Series without samples are not
+// sent.
+func streamChunkedSeries(ss storage.SeriesSet, queryIndex int64, maxFrameBytes int, send func(*prompb.ChunkedReadResponse) error) error {
+	frame := &prompb.ChunkedReadResponse{QueryIndex: queryIndex}
+	frameBytes := 0
+	flush := func() error {
+		if len(frame.ChunkedSeries) == 0 {
+			return nil
+		}
+		err := send(frame)
+		frame, frameBytes = &prompb.ChunkedReadResponse{QueryIndex: queryIndex}, 0
+		return err
+	}
+
+	for ss.Next() {
+		s := ss.At()
+		if s == nil {
+			// invalid series are reported by Err
+			break
+		}
+		lls := s.Labels()
+		pls := make([]prompb.Label, 0, len(lls))
+		for _, l := range lls {
+			pls = append(pls, prompb.Label{Name: l.Name, Value: l.Value})
+		}
+
+		series := &prompb.ChunkedSeries{Labels: pls}
+		err := encodeChunks(s.Iterator(), func(c prompb.Chunk) error {
+			if len(series.Chunks) == 0 {
+				frame.ChunkedSeries = append(frame.ChunkedSeries, series)
+			}
+			series.Chunks = append(series.Chunks, c)
+			if frameBytes += len(c.Data); frameBytes < maxFrameBytes {
+				return nil
+			}
+			// the following chunks of the series go into the next frame
+			series = &prompb.ChunkedSeries{Labels: pls}
+			return flush()
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if err := ss.Err(); err != nil {
+		return err
+	}
+	return flush()
+}
+
+// encodeChunks encodes the samples of the iterator into XOR chunks of at most
+// maxChunkSamples samples, emitting every chunk once it is complete.
+func encodeChunks(it chunkenc.Iterator, emit func(prompb.Chunk) error) error {
+	var (
+		chk        *chunkenc.XORChunk
+		app        chunkenc.Appender
+		mint, maxt int64
+	)
+	cut := func() error {
+		c := prompb.Chunk{MinTimeMs: mint, MaxTimeMs: maxt, Type: prompb.Chunk_XOR, Data: chk.Bytes()}
+		chk = nil
+		return emit(c)
+	}
+
+	for it.Next() {
+		t, v := it.At()
+		if chk == nil {
+			chk = chunkenc.NewXORChunk()
+			var err error
+			if app, err = chk.Appender(); err != nil {
+				return err
+			}
+			mint = t
+		}
+		app.Append(t, v)
+		maxt = t
+		if chk.NumSamples() >= maxChunkSamples {
+			if err := cut(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	if chk != nil {
+		return cut()
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+// This file and its contents are licensed under the Apache License 2.0.
+// Please see the included NOTICE for copyright information and
+// LICENSE for a copy of the license.
+
+package pgmodel
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const (
+	compressionEnabledSQL      = "SELECT compressed_hypertable_id IS NOT NULL FROM _timescaledb_catalog.hypertable WHERE schema_name = $1 AND table_name = $2"
+	enableCompressionSQL       = "ALTER TABLE %s SET (timescaledb.compress, timescaledb.compress_segmentby = 'series_id', timescaledb.compress_orderby = 'time, value')"
+	removeCompressionPolicySQL = "SELECT remove_compress_chunks_policy($1::regclass, if_exists => true)"
+	addCompressionPolicySQL    = "SELECT add_compress_chunks_policy($1::regclass, $2::interval)"
+)
+
+// SetCompressionPolicy enables native compression of the data table of the
+// metric, with the samples segmented by series and ordered by time as for the
+// tables the connector creates, and compresses its chunks once they are older
+// than compressAfter. Running it again is safe: compression is only enabled
+// if it is not yet, and the policy replaces the previous one. A compressAfter
+// of zero removes the policy, the chunks already compressed stay compressed.
+func SetCompressionPolicy(db *pgxpool.Pool, metric string, compressAfter time.Duration) error {
+	return setCompressionPolicy(&pgxConnImpl{conn: db}, metric, compressAfter)
+}
+
+func setCompressionPolicy(conn pgxConn, metric string, compressAfter time.Duration) error {
+	if compressAfter < 0 {
+		return fmt.Errorf("invalid compress-after interval %v for metric %s", compressAfter, metric)
+	}
+	ctx := context.Background()
+
+	tableName, err := queryMetricTableName(ctx, conn, metric)
+	if err != nil {
+		return err
+	}
+	table := pgx.Identifier{dataSchema, tableName}.Sanitize()
+
+	enabled, err := compressionEnabled(ctx, conn, tableName)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		if _, err := conn.Exec(ctx, fmt.Sprintf(enableCompressionSQL, table)); err != nil {
+			return err
+		}
+	}
+
+	if _, err := conn.Exec(ctx, removeCompressionPolicySQL, table); err != nil {
+		return err
+	}
+	if compressAfter == 0 {
+		return nil
+	}
+	_, err = conn.Exec(ctx, addCompressionPolicySQL, table, compressAfter)
+	return err
+}
+
+func queryMetricTableName(ctx context.Context, conn pgxConn, metric string) (string, error) {
+	rows, err := conn.Query(ctx, getMetricsTableSQL, metric)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return "", errMissingTableName
+	}
+	var tableName string
+	if err := rows.Scan(&tableName); err != nil {
+		return "", err
+	}
+	return tableName, rows.Err()
+}
+
+func compressionEnabled(ctx context.Context, conn pgxConn, tableName string) (bool, error) {
+	rows, err := conn.Query(ctx, compressionEnabledSQL, dataSchema, tableName)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return false, fmt.Errorf("data table %s of metric is not a hypertable", tableName)
+	}
+	var enabled bool
+	if err := rows.Scan(&enabled); err != nil {
+		return false, err
+	}
+	return enabled, rows.Err()
+}
@@ -0,0 +1,132 @@
+// This file and its contents are licensed under the Apache License 2.0.
+// Please see the included NOTICE for copyright information and
+// LICENSE for a copy of the license.
+
+package pgmodel
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/storage"
+)
+
+// ContinuousAggregate is a TimescaleDB continuous aggregate downsampling the
+// samples of a metric to one per Resolution. Reads with a step and a range of
+// at least Resolution are answered from it rather than from the metric's data
+// table, so the series read have the times of its buckets.
+//
+// The aggregate must have the columns of a data table: time, the start of a
+// bucket, value and series_id.
+type ContinuousAggregate struct {
+	Metric     string
+	Resolution time.Duration
+	Schema     string
+	View       string
+}
+
+// ParseContinuousAggregates parses comma-separated aggregates of the form
+// <metric>:<resolution>=[<schema>.]<view>, e.g. "cpu_usage:1h=cpu_usage_1h".
+// The schema defaults to the schema of the data tables. An empty expression
+// returns no aggregates.
+func ParseContinuousAggregates(expr string) ([]ContinuousAggregate, error) {
+	var aggs []ContinuousAggregate
+	for _, part := range strings.Split(expr, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		eq := strings.Index(part, "=")
+		colon := strings.LastIndex(part[:eq+1], ":")
+		if eq < 0 || colon <= 0 || eq == len(part)-1 {
+			return nil, fmt.Errorf("invalid continuous aggregate %q, expected <metric>:<resolution>=[<schema>.]<view>", part)
+		}
+		resolution, err := model.ParseDuration(part[colon+1 : eq])
+		if err != nil || resolution <= 0 {
+			return nil, fmt.Errorf("invalid continuous aggregate resolution %q", part[colon+1:eq])
+		}
+		agg := ContinuousAggregate{
+			Metric:     part[:colon],
+			Resolution: time.Duration(resolution),
+			Schema:     dataSchema,
+			View:       part[eq+1:],
+		}
+		if dot := strings.Index(agg.View, "."); dot >= 0 {
+			agg.Schema, agg.View = agg.View[:dot], agg.View[dot+1:]
+		}
+		if agg.Schema == "" || agg.View == "" {
+			return nil, fmt.Errorf("invalid continuous aggregate view %q", part[eq+1:])
+		}
+		aggs = append(aggs, agg)
+	}
+	return aggs, nil
+}
+
+// queryLookbackDelta is how far back the PromQL engine looks for the samples
+// of selectors without a range, the engine's default.
+const queryLookbackDelta = 5 * time.Minute
+
+// continuousAggregates maps metric names to their aggregates, coarsest first.
+type continuousAggregates map[string][]ContinuousAggregate
+
+func newContinuousAggregates(aggs []ContinuousAggregate) continuousAggregates {
+	if len(aggs) == 0 {
+		return nil
+	}
+	c := make(continuousAggregates, len(aggs))
+	for _, agg := range aggs {
+		c[agg.Metric] = append(c[agg.Metric], agg)
+	}
+	for _, metricAggs := range c {
+		sort.SliceStable(metricAggs, func(i, j int) bool {
+			return metricAggs[i].Resolution > metricAggs[j].Resolution
+		})
+	}
+	return c
+}
+
+// forStep returns the coarsest aggregate of the metric that is coarser than
+// neither the step nor the window of the read in milliseconds, or nil if the
+// metric has none, in which case the samples are read from its data table.
+// A window finer than the resolution could hold no bucket of the aggregate.
+func (c continuousAggregates) forStep(metric string, step, window int64) *ContinuousAggregate {
+	if step <= 0 {
+		return nil
+	}
+	for i, agg := range c[metric] {
+		if res := agg.Resolution.Milliseconds(); res <= step && res <= window {
+			return &c[metric][i]
+		}
+	}
+	return nil
+}
+
+// queryStep returns the step of a read in milliseconds, from its hints or,
+// for remote reads, from the bucket its samples are downsampled to.
+func queryStep(hints *storage.SelectHints, bucket *timeBucket) int64 {
+	if hints != nil {
+		return hints.Step
+	}
+	if bucket != nil {
+		return bucket.width
+	}
+	return 0
+}
+
+// queryWindow returns the time range in milliseconds every output sample of a
+// read is computed from: the range of its range selector, the lookback delta
+// without one or, for remote reads, the bucket its samples are downsampled to.
+func queryWindow(hints *storage.SelectHints, bucket *timeBucket) int64 {
+	if hints != nil {
+		if hints.Range > 0 {
+			return hints.Range
+		}
+		return queryLookbackDelta.Milliseconds()
+	}
+	if bucket != nil {
+		return bucket.width
+	}
+	return 0
+}
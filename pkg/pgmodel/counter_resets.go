@@ -0,0 +1,60 @@
+// This file and its contents are licensed under the Apache License 2.0.
+// Please see the included NOTICE for copyright information and
+// LICENSE for a copy of the license.
+
+package pgmodel
+
+import (
+	"math"
+
+	"github.com/jackc/pgtype"
+	"github.com/timescale/timescale-prometheus/pkg/prompb"
+)
+
+// counterResets merges the resets of a counter, e.g. after a restart of the
+// process exporting it. Every value lower than the one before it starts a new
+// run of the counter, and the last value before the reset is added to all
+// values from there on, so the series read never decreases.
+type counterResets struct {
+	offset  float64
+	prev    float64
+	hasPrev bool
+}
+
+// adjust returns v, the next value of the counter, offset by the resets seen
+// so far. NaN values, including stale markers, are returned unchanged and do
+// not count as the previous value.
+func (c *counterResets) adjust(v float64) float64 {
+	if math.IsNaN(v) {
+		return v
+	}
+	if c.hasPrev && v < c.prev {
+		c.offset += c.prev
+	}
+	c.prev, c.hasPrev = v, true
+	return v + c.offset
+}
+
+// counterOffsets returns the offset of every element of values that merges
+// the counter resets before it. Elements without a value get the offset of
+// the preceding ones.
+func counterOffsets(values pgtype.Float8Array) []float64 {
+	offsets := make([]float64, len(values.Elements))
+	var c counterResets
+	for i, v := range values.Elements {
+		if v.Status == pgtype.Present {
+			c.adjust(v.Float)
+		}
+		offsets[i] = c.offset
+	}
+	return offsets
+}
+
+// mergeCounterResets offsets the samples in place so that they never
+// decrease.
+func mergeCounterResets(samples []prompb.Sample) {
+	var c counterResets
+	for i := range samples {
+		samples[i].Value = c.adjust(samples[i].Value)
+	}
+}
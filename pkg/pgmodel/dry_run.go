@@ -0,0 +1,116 @@
+// This file and its contents are licensed under the Apache License 2.0.
+// Please see the included NOTICE for copyright information and
+// LICENSE for a copy of the license.
+
+package pgmodel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// errDryRunQuery is returned by the connection of a dry run to every statement
+// other than the inserts of the samples, which a dry run never sends.
+var errDryRunQuery = errors.New("no statements are sent to the database in a dry run")
+
+// DryRunInsert is an insert statement of samples built by a dry run, with the
+// arguments it would have been executed with.
+type DryRunInsert struct {
+	SQL  string
+	Args []interface{}
+	// Rows is the number of samples of the insert.
+	Rows int
+}
+
+// DryRunReporter returns the inserts built by a dry run.
+type DryRunReporter interface {
+	// DryRunInserts returns the inserts built since the last call, in the
+	// order they were built.
+	DryRunInserts() []DryRunInsert
+}
+
+// dryRun collects the inserts of an inserter that batches the samples like
+// it does for the database, but sends nothing to the database. Metrics not
+// in the metric cache are assumed to have a table named after them, and
+// series not in the series cache keep the id -1; neither is created.
+type dryRun struct {
+	mtx     sync.Mutex
+	inserts []DryRunInsert
+}
+
+// dryRunConn is the connection of an inserter running dry. It is not
+// connected to the database: its Exec records the inserts of the samples
+// rather than executing them, and all other statements fail.
+type dryRunConn struct {
+	dryRun *dryRun
+}
+
+func (c dryRunConn) Close() {}
+
+func (c dryRunConn) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	return nil, errDryRunQuery
+}
+
+func (c dryRunConn) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	return 0, errDryRunQuery
+}
+
+func (c dryRunConn) CopyFromRows(rows [][]interface{}) pgx.CopyFromSource {
+	return pgx.CopyFromRows(rows)
+}
+
+func (c dryRunConn) NewBatch() pgxBatch {
+	return &pgx.Batch{}
+}
+
+func (c dryRunConn) SendBatch(ctx context.Context, b pgxBatch) (pgx.BatchResults, error) {
+	return nil, errDryRunQuery
+}
+
+func (c dryRunConn) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Exec records the insert of samples and reports all of its rows as
+// inserted. Other statements fail.
+func (c dryRunConn) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
+	if len(arguments) == 0 {
+		return nil, errDryRunQuery
+	}
+	times, ok := arguments[0].([]time.Time)
+	if !ok {
+		return nil, errDryRunQuery
+	}
+	rows := len(times)
+	c.dryRun.mtx.Lock()
+	c.dryRun.inserts = append(c.dryRun.inserts, DryRunInsert{SQL: sql, Args: arguments, Rows: rows})
+	c.dryRun.mtx.Unlock()
+	return pgconn.CommandTag(fmt.Sprintf("INSERT 0 %d", rows)), nil
+}
+
+// DryRunInserts implements DryRunReporter. Inserters not running dry return
+// no inserts.
+func (i *DBIngestor) DryRunInserts() []DryRunInsert {
+	if r, ok := i.db.(DryRunReporter); ok {
+		return r.DryRunInserts()
+	}
+	return nil
+}
+
+// DryRunInserts implements DryRunReporter.
+func (p *pgxInserter) DryRunInserts() []DryRunInsert {
+	if p.dryRun == nil {
+		return nil
+	}
+	p.dryRun.mtx.Lock()
+	defer p.dryRun.mtx.Unlock()
+	inserts := p.dryRun.inserts
+	p.dryRun.inserts = nil
+	return inserts
+}
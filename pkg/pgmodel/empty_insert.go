@@ -0,0 +1,69 @@
+// This file and its contents are licensed under the Apache License 2.0.
+// Please see the included NOTICE for copyright information and
+// LICENSE for a copy of the license.
+
+package pgmodel
+
+import (
+	"fmt"
+
+	"github.com/timescale/timescale-prometheus/pkg/log"
+)
+
+// ErrNothingInserted is returned under FailEmptyInserts for inserts of
+// samples of which not a single row was stored.
+var ErrNothingInserted = fmt.Errorf("no rows inserted")
+
+// EmptyInsertPolicy decides what happens when the database reports no rows
+// inserted for a non-empty batch of samples. All samples conflicting with
+// samples already stored is possible, but more often a trigger or constraint
+// of the data table silently drops them.
+type EmptyInsertPolicy int
+
+const (
+	// IgnoreEmptyInserts counts the samples as duplicates.
+	IgnoreEmptyInserts EmptyInsertPolicy = iota
+	// WarnEmptyInserts also logs a warning.
+	WarnEmptyInserts
+	// FailEmptyInserts fails the insert with ErrNothingInserted.
+	FailEmptyInserts
+)
+
+var emptyInsertPolicies = map[string]EmptyInsertPolicy{
+	"ignore": IgnoreEmptyInserts,
+	"warn":   WarnEmptyInserts,
+	"error":  FailEmptyInserts,
+}
+
+// ParseEmptyInsertPolicy returns the policy with the given name, one of
+// ignore, warn or error.
+func ParseEmptyInsertPolicy(name string) (EmptyInsertPolicy, error) {
+	policy, ok := emptyInsertPolicies[name]
+	if !ok {
+		return IgnoreEmptyInserts, fmt.Errorf("invalid empty insert policy %q", name)
+	}
+	return policy, nil
+}
+
+func (p EmptyInsertPolicy) String() string {
+	for name, policy := range emptyInsertPolicies {
+		if policy == p {
+			return name
+		}
+	}
+	return fmt.Sprintf("EmptyInsertPolicy(%d)", int(p))
+}
+
+// check applies the policy to an insert of numRows rows into table of which
+// inserted were stored.
+func (p EmptyInsertPolicy) check(table string, numRows, inserted int64) error {
+	if numRows == 0 || inserted > 0 || p == IgnoreEmptyInserts {
+		return nil
+	}
+	emptyInserts.Inc()
+	if p == FailEmptyInserts {
+		return fmt.Errorf("%w into %s: %d rows sent", ErrNothingInserted, table, numRows)
+	}
+	log.Warn("msg", "no rows inserted, check the triggers and constraints of the table", "table", table, "row_count", numRows)
+	return nil
+}
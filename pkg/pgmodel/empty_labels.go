@@ -0,0 +1,53 @@
+// This file and its contents are licensed under the Apache License 2.0.
+// Please see the included NOTICE for copyright information and
+// LICENSE for a copy of the license.
+
+package pgmodel
+
+import (
+	"fmt"
+
+	"github.com/prometheus/prometheus/pkg/labels"
+)
+
+// EmptyLabelsPolicy decides what happens to series read without any labels,
+// not even a metric name. Prometheus considers such series invalid.
+type EmptyLabelsPolicy int
+
+const (
+	// KeepEmptyLabels returns the series without labels.
+	KeepEmptyLabels EmptyLabelsPolicy = iota
+	// DropEmptyLabels leaves the series out of the result.
+	DropEmptyLabels
+	// LabelEmptyLabels returns the series with the UnlabeledSeriesLabel.
+	LabelEmptyLabels
+)
+
+// UnlabeledSeriesLabel is the label attached to series without labels under
+// LabelEmptyLabels.
+var UnlabeledSeriesLabel = labels.Label{Name: "unlabeled_series", Value: "true"}
+
+var emptyLabelsPolicies = map[string]EmptyLabelsPolicy{
+	"keep":  KeepEmptyLabels,
+	"drop":  DropEmptyLabels,
+	"label": LabelEmptyLabels,
+}
+
+// ParseEmptyLabelsPolicy returns the policy with the given name, one of keep,
+// drop or label.
+func ParseEmptyLabelsPolicy(name string) (EmptyLabelsPolicy, error) {
+	policy, ok := emptyLabelsPolicies[name]
+	if !ok {
+		return KeepEmptyLabels, fmt.Errorf("invalid empty labels policy %q", name)
+	}
+	return policy, nil
+}
+
+func (p EmptyLabelsPolicy) String() string {
+	for name, policy := range emptyLabelsPolicies {
+		if policy == p {
+			return name
+		}
+	}
+	return fmt.Sprintf("EmptyLabelsPolicy(%d)", int(p))
+}
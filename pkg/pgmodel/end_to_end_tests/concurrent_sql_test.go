@@ -225,7 +225,7 @@ func testConcurrentInsertSimple(t testing.TB, db *pgxpool.Pool, metric string) {
 		t.Fatal(err)
 	}
 	defer ingestor.Close()
-	_, err = ingestor.Ingest(copyMetrics(metrics), NewWriteRequest())
+	_, err = ingestor.Ingest(context.Background(), copyMetrics(metrics), NewWriteRequest())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -285,7 +285,7 @@ func testConcurrentInsertAdvanced(t testing.TB, db *pgxpool.Pool) {
 	}
 
 	defer ingestor.Close()
-	_, err = ingestor.Ingest(copyMetrics(metrics), NewWriteRequest())
+	_, err = ingestor.Ingest(context.Background(), copyMetrics(metrics), NewWriteRequest())
 	if err != nil {
 		t.Fatal(err)
 	}
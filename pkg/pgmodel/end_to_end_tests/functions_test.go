@@ -7,6 +7,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"reflect"
 	"strings"
 	"testing"
@@ -16,6 +17,7 @@ import (
 
 	_ "github.com/jackc/pgx/v4/stdlib"
 	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/value"
 	"github.com/timescale/timescale-prometheus/pkg/prompb"
 )
 
@@ -527,3 +529,65 @@ func TestExtensionGapfillIncrease(t *testing.T) {
 		}
 	})
 }
+
+func TestExtensionGapfillRate(t *testing.T) {
+	if !*useExtension || testing.Short() {
+		t.Skip("skipping extension test; testing without extension")
+	}
+	startTime, err := time.Parse(time.RFC3339, "2000-01-02T15:00:00Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	times := make([]time.Time, 7)
+	for i := range times {
+		times[i] = startTime.Add(time.Duration(i) * 5 * time.Minute)
+	}
+	// the counter resets after 3, for an increase of 7 over the window
+	vals := []float64{0, 1, 2, 3, 2, 3, 4}
+	window := int64(30 * 60 * 1000)
+
+	withDB(t, *testDatabase, func(db *pgxpool.Pool, t testing.TB) {
+		_, err := db.Exec(context.Background(), "CREATE TABLE gfr_test_table(t TIMESTAMPTZ, v DOUBLE PRECISION);")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = db.Exec(context.Background(),
+			"INSERT INTO gfr_test_table SELECT unnest($1::TIMESTAMPTZ[]) t, unnest($2::DOUBLE PRECISION[]) v;",
+			times, vals)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var res []float64
+		err = db.QueryRow(context.Background(),
+			"SELECT prom_rate($1::TIMESTAMPTZ, $2::TIMESTAMPTZ, $3, $3, t, v order by t) FROM gfr_test_table;",
+			startTime, startTime.Add(time.Duration(window)*time.Millisecond), window,
+		).Scan(&res)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := 7 / (float64(window) / 1000)
+		if len(res) != 1 || math.Abs(res[0]-expected) > 1e-9 {
+			t.Errorf("wrong result. Expected\n\t[%v]\nfound\n\t%v\n", expected, res)
+		}
+
+		// staleness markers are excluded like the query pushdown does
+		_, err = db.Exec(context.Background(),
+			"INSERT INTO gfr_test_table VALUES ($1, $2);",
+			startTime.Add(27*time.Minute), math.Float64frombits(value.StaleNaN))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = db.QueryRow(context.Background(),
+			"SELECT prom_rate($1::TIMESTAMPTZ, $2::TIMESTAMPTZ, $3, $3, t, v order by t) FILTER (WHERE NOT prom_api.is_stale_marker(v)) FROM gfr_test_table;",
+			startTime, startTime.Add(time.Duration(window)*time.Millisecond), window,
+		).Scan(&res)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res) != 1 || math.Abs(res[0]-expected) > 1e-9 {
+			t.Errorf("wrong result with a staleness marker. Expected\n\t[%v]\nfound\n\t%v\n", expected, res)
+		}
+	})
+}
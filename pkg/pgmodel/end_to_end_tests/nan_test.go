@@ -73,7 +73,7 @@ func TestSQLStaleNaN(t *testing.T) {
 			t.Fatal(err)
 		}
 		defer ingestor.Close()
-		_, err = ingestor.Ingest(copyMetrics(metrics), NewWriteRequest())
+		_, err = ingestor.Ingest(context.Background(), copyMetrics(metrics), NewWriteRequest())
 
 		if err != nil {
 			t.Fatalf("unexpected error while ingesting test dataset: %s", err)
@@ -131,7 +131,7 @@ func TestSQLStaleNaN(t *testing.T) {
 
 		for _, c := range query {
 			r := NewPgxReader(db, nil, 100)
-			resp, err := r.Read(&c.rrq)
+			resp, err := r.Read(context.Background(), &c.rrq)
 			startMs := c.rrq.Queries[0].StartTimestampMs
 			endMs := c.rrq.Queries[0].EndTimestampMs
 			timeClause := "time >= 'epoch'::timestamptz + $1 AND time <= 'epoch'::timestamptz + $2"
@@ -1,6 +1,7 @@
 package end_to_end_tests
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -560,7 +561,7 @@ func TestPromQLLabelEndpoints(t *testing.T) {
 		testMethod := testRequest(req, router, client, labelsResultComparator)
 		tester.Run("get label names", testMethod)
 
-		labelNames, err := r.GetQuerier().LabelNames()
+		labelNames, err := r.GetQuerier().LabelNames(context.Background())
 		if err != nil {
 			t.Fatalf("could not get label names from querier")
 		}
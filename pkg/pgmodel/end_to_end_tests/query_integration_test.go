@@ -19,8 +19,10 @@ import (
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/pkg/labels"
 	"github.com/prometheus/prometheus/pkg/timestamp"
+	"github.com/prometheus/prometheus/storage"
 	"github.com/prometheus/prometheus/tsdb"
 	"github.com/prometheus/prometheus/util/testutil"
+	"github.com/timescale/timescale-prometheus/pkg/clockcache"
 	"github.com/timescale/timescale-prometheus/pkg/internal/testhelpers"
 	"github.com/timescale/timescale-prometheus/pkg/log"
 	"github.com/timescale/timescale-prometheus/pkg/prompb"
@@ -598,7 +600,7 @@ func TestSQLQuery(t *testing.T) {
 		r := NewPgxReader(readOnly, nil, 100)
 		for _, c := range testCases {
 			tester.Run(c.name, func(t *testing.T) {
-				resp, err := r.Read(&c.readRequest)
+				resp, err := r.Read(context.Background(), &c.readRequest)
 
 				if err != nil && err != c.expectErr {
 					t.Fatalf("unexpected error returned:\ngot\n%s\nwanted\n%s", err, c.expectErr)
@@ -626,7 +628,7 @@ func ingestQueryTestDataset(db *pgxpool.Pool, t testing.TB, metrics []prompb.Tim
 	if err != nil {
 		t.Fatal(err)
 	}
-	cnt, err := ingestor.Ingest(copyMetrics(metrics), NewWriteRequest())
+	cnt, err := ingestor.Ingest(context.Background(), copyMetrics(metrics), NewWriteRequest())
 
 	if err != nil {
 		t.Fatalf("unexpected error while ingesting test dataset: %s", err)
@@ -979,7 +981,7 @@ func TestPromQL(t *testing.T) {
 		r := NewPgxReader(readOnly, nil, 100)
 		for _, c := range testCases {
 			tester.Run(c.name, func(t *testing.T) {
-				connResp, connErr := r.Read(c.readRequest)
+				connResp, connErr := r.Read(context.Background(), c.readRequest)
 				promResp, promErr := promClient.Read(c.readRequest)
 
 				// If a query returns an error on both sides, its considered an
@@ -1271,3 +1273,113 @@ func TestPushdown(t *testing.T) {
 		}
 	})
 }
+
+func TestAlignToStep(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	withDB(t, *testDatabase, func(db *pgxpool.Pool, t testing.TB) {
+		// Ingest test dataset.
+		ingestQueryTestDataset(db, t, generateLargeTimeseries())
+		// Getting a read-only connection to ensure read path is idempotent.
+		readOnly := testhelpers.GetReadOnlyConnection(t, *testDatabase)
+		defer readOnly.Close()
+
+		cache := &MetricNameCache{Metrics: clockcache.WithMax(DefaultMetricCacheSize)}
+		r := NewPgxReaderWithCfg(readOnly, cache, &ReaderCfg{LabelsCacheSize: 100, AlignToStep: true})
+		q := r.GetQuerier()
+
+		// The step grid is deliberately offset from the 30s sampling interval.
+		hints := &storage.SelectHints{
+			Start: startTime + 7000,
+			End:   startTime + 600000,
+			Step:  60000,
+		}
+		matcher := labels.MustNewMatcher(labels.MatchEqual, MetricNameLabelName, "metric_1")
+
+		ss, _, _, err := q.Select(context.Background(), hints.Start, hints.End, false, hints, nil, matcher)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var expected []int64
+		seriesCount := 0
+		for ss.Next() {
+			seriesCount++
+			var got []int64
+			it := ss.At().Iterator()
+			for it.Next() {
+				ts, _ := it.At()
+				if (ts-hints.Start)%hints.Step != 0 {
+					t.Errorf("timestamp %d is not aligned to step %d starting at %d", ts, hints.Step, hints.Start)
+				}
+				got = append(got, ts)
+			}
+			if expected == nil {
+				expected = got
+				continue
+			}
+			if !reflect.DeepEqual(got, expected) {
+				t.Errorf("series do not share time points:\ngot\n%v\nwanted\n%v", got, expected)
+			}
+		}
+		if ss.Err() != nil {
+			t.Fatal(ss.Err())
+		}
+		if seriesCount != 3 {
+			t.Fatalf("unexpected series count: got %d, wanted 3", seriesCount)
+		}
+		if len(expected) == 0 {
+			t.Fatal("no samples returned")
+		}
+	})
+}
+
+func TestAlignToStepNearest(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	withDB(t, *testDatabase, func(db *pgxpool.Pool, t testing.TB) {
+		step := int64(60000)
+		ingestQueryTestDataset(db, t, []prompb.TimeSeries{
+			{
+				Labels: []prompb.Label{{Name: MetricNameLabelName, Value: "snapped"}},
+				Samples: []prompb.Sample{
+					// just before half a step past the grid point
+					{Timestamp: startTime + step/2 - 1, Value: 1},
+					// exactly half a step past the next grid point
+					{Timestamp: startTime + step + step/2, Value: 2},
+				},
+			},
+		})
+
+		cache := &MetricNameCache{Metrics: clockcache.WithMax(DefaultMetricCacheSize)}
+		r := NewPgxReaderWithCfg(db, cache, &ReaderCfg{LabelsCacheSize: 100, AlignToStep: true})
+		q := r.GetQuerier()
+
+		hints := &storage.SelectHints{Start: startTime, End: startTime + 3*step, Step: step}
+		matcher := labels.MustNewMatcher(labels.MatchEqual, MetricNameLabelName, "snapped")
+		ss, _, _, err := q.Select(context.Background(), hints.Start, hints.End, false, hints, nil, matcher)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := []int64{startTime, startTime + 2*step}
+		var got []int64
+		for ss.Next() {
+			it := ss.At().Iterator()
+			for it.Next() {
+				ts, _ := it.At()
+				got = append(got, ts)
+			}
+		}
+		if ss.Err() != nil {
+			t.Fatal(ss.Err())
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("samples not snapped to the nearest step:\ngot\n%v\nwanted\n%v", got, expected)
+		}
+	})
+}
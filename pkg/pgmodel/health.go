@@ -0,0 +1,74 @@
+// This file and its contents are licensed under the Apache License 2.0.
+// Please see the included NOTICE for copyright information and
+// LICENSE for a copy of the license.
+
+package pgmodel
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// HealthStatus is the outcome of a failed health check.
+type HealthStatus int
+
+const (
+	// DBUnreachable means no connection to the database could be made.
+	DBUnreachable HealthStatus = iota + 1
+	// SchemaMissing means the database is reachable but the schema is not
+	// initialized, e.g. because the migrations did not run yet.
+	SchemaMissing
+)
+
+func (s HealthStatus) String() string {
+	switch s {
+	case DBUnreachable:
+		return "database unreachable"
+	case SchemaMissing:
+		return "schema not initialized"
+	default:
+		return fmt.Sprintf("HealthStatus(%d)", int(s))
+	}
+}
+
+// HealthError is returned by health checks failing for a known reason,
+// which Status tells apart.
+type HealthError struct {
+	Status HealthStatus
+	Err    error
+}
+
+func (e *HealthError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Status, e.Err)
+}
+
+func (e *HealthError) Unwrap() error {
+	return e.Err
+}
+
+// schemaTables are the tables that must exist for the connector to work.
+var schemaTables = []string{
+	catalogSchema + ".metric",
+	catalogSchema + ".label",
+	catalogSchema + ".series",
+}
+
+const missingSchemaTablesSQL = "SELECT t FROM unnest($1::TEXT[]) t WHERE to_regclass(t) IS NULL"
+
+// HealthCheck verifies that the database is reachable and that its schema is
+// initialized. It returns a *HealthError for either failure.
+func (q *pgxQuerier) HealthCheck() error {
+	if err := q.conn.Ping(context.Background()); err != nil {
+		return &HealthError{Status: DBUnreachable, Err: err}
+	}
+
+	missing, err := q.queryStrings(context.Background(), missingSchemaTablesSQL, schemaTables)
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return &HealthError{Status: SchemaMissing, Err: fmt.Errorf("missing tables %s", strings.Join(missing, ", "))}
+	}
+	return nil
+}
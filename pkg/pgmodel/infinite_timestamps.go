@@ -0,0 +1,65 @@
+// This file and its contents are licensed under the Apache License 2.0.
+// Please see the included NOTICE for copyright information and
+// LICENSE for a copy of the license.
+
+package pgmodel
+
+import (
+	"fmt"
+
+	"github.com/jackc/pgtype"
+)
+
+// InfiniteTimestampPolicy decides what happens to samples read at an infinite
+// timestamp. Such samples are returned at math.MinInt64 or math.MaxInt64
+// milliseconds, which PromQL evaluates at times no query asks for. Queries
+// bounded in time never read them, the database only returns them to queries
+// without a start or end.
+type InfiniteTimestampPolicy int
+
+const (
+	// KeepInfiniteTimestamps returns the samples as they are.
+	KeepInfiniteTimestamps InfiniteTimestampPolicy = iota
+	// WarnInfiniteTimestamps returns the samples, logging a warning for
+	// every series read with such samples.
+	WarnInfiniteTimestamps
+	// DropInfiniteTimestamps skips the samples. Series without any other
+	// sample are left out of the result.
+	DropInfiniteTimestamps
+)
+
+var infiniteTimestampPolicies = map[string]InfiniteTimestampPolicy{
+	"keep": KeepInfiniteTimestamps,
+	"warn": WarnInfiniteTimestamps,
+	"drop": DropInfiniteTimestamps,
+}
+
+// ParseInfiniteTimestampPolicy returns the policy with the given name, one of
+// keep, warn or drop.
+func ParseInfiniteTimestampPolicy(name string) (InfiniteTimestampPolicy, error) {
+	policy, ok := infiniteTimestampPolicies[name]
+	if !ok {
+		return KeepInfiniteTimestamps, fmt.Errorf("invalid infinite timestamp policy %q", name)
+	}
+	return policy, nil
+}
+
+func (p InfiniteTimestampPolicy) String() string {
+	for name, policy := range infiniteTimestampPolicies {
+		if policy == p {
+			return name
+		}
+	}
+	return fmt.Sprintf("InfiniteTimestampPolicy(%d)", int(p))
+}
+
+// countInfinite returns the number of infinite times.
+func countInfinite(times pgtype.TimestamptzArray) int {
+	n := 0
+	for _, t := range times.Elements {
+		if t.Status == pgtype.Present && t.InfinityModifier != pgtype.None {
+			n++
+		}
+	}
+	return n
+}
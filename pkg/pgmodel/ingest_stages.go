@@ -0,0 +1,187 @@
+// This file and its contents are licensed under the Apache License 2.0.
+// Please see the included NOTICE for copyright information and
+// LICENSE for a copy of the license.
+
+package pgmodel
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/relabel"
+	"github.com/timescale/timescale-prometheus/pkg/prompb"
+)
+
+// IngestStage transforms a batch of timeseries before it is written to the
+// database. Stages may modify the timeseries in place, drop them or return an
+// error to reject the whole batch.
+type IngestStage func(tts []prompb.TimeSeries) ([]prompb.TimeSeries, error)
+
+// applyStages runs the stages over the batch in order.
+func applyStages(tts []prompb.TimeSeries, stages []IngestStage) ([]prompb.TimeSeries, error) {
+	var err error
+	for _, stage := range stages {
+		tts, err = stage(tts)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return tts, nil
+}
+
+// ValidateStage rejects batches containing series with invalid label names
+// or values.
+func ValidateStage() IngestStage {
+	return func(tts []prompb.TimeSeries) ([]prompb.TimeSeries, error) {
+		for i := range tts {
+			for _, l := range tts[i].Labels {
+				if !model.LabelName(l.Name).IsValid() {
+					return nil, fmt.Errorf("invalid label name %q", l.Name)
+				}
+				if !utf8.ValidString(l.Value) {
+					return nil, fmt.Errorf("invalid value for label %q: not valid UTF-8", l.Name)
+				}
+			}
+		}
+		return tts, nil
+	}
+}
+
+// RelabelStage applies Prometheus relabeling rules to the series labels.
+// Series whose labels are dropped by the rules are removed from the batch.
+func RelabelStage(cfgs ...*relabel.Config) IngestStage {
+	return func(tts []prompb.TimeSeries) ([]prompb.TimeSeries, error) {
+		kept := tts[:0]
+		for _, t := range tts {
+			ls := make(labels.Labels, 0, len(t.Labels))
+			for _, l := range t.Labels {
+				ls = append(ls, labels.Label{Name: l.Name, Value: l.Value})
+			}
+			sort.Sort(ls)
+
+			ls = relabel.Process(ls, cfgs...)
+			if ls == nil {
+				continue
+			}
+
+			t.Labels = make([]prompb.Label, 0, len(ls))
+			for _, l := range ls {
+				t.Labels = append(t.Labels, prompb.Label{Name: l.Name, Value: l.Value})
+			}
+			kept = append(kept, t)
+		}
+		return kept, nil
+	}
+}
+
+// RoundTimestampsStage rounds sample timestamps down to a multiple of the
+// resolution.
+func RoundTimestampsStage(resolution time.Duration) IngestStage {
+	res := resolution.Milliseconds()
+	return func(tts []prompb.TimeSeries) ([]prompb.TimeSeries, error) {
+		if res <= 1 {
+			return tts, nil
+		}
+		for i := range tts {
+			for j := range tts[i].Samples {
+				ts := tts[i].Samples[j].Timestamp
+				rounded := ts - ts%res
+				if ts < 0 && ts%res != 0 {
+					rounded -= res
+				}
+				tts[i].Samples[j].Timestamp = rounded
+			}
+		}
+		return tts, nil
+	}
+}
+
+// DedupStage sorts the samples of each series by time and removes samples
+// sharing a timestamp, keeping the last one received.
+func DedupStage() IngestStage {
+	return func(tts []prompb.TimeSeries) ([]prompb.TimeSeries, error) {
+		for i := range tts {
+			samples := tts[i].Samples
+			if len(samples) < 2 {
+				continue
+			}
+			sort.SliceStable(samples, func(a, b int) bool {
+				return samples[a].Timestamp < samples[b].Timestamp
+			})
+			deduped := samples[:1]
+			for _, s := range samples[1:] {
+				if s.Timestamp == deduped[len(deduped)-1].Timestamp {
+					deduped[len(deduped)-1] = s
+					continue
+				}
+				deduped = append(deduped, s)
+			}
+			tts[i].Samples = deduped
+		}
+		return tts, nil
+	}
+}
+
+// MetricSwitch is a set of metrics whose writes are disabled, e.g. to stop
+// ingesting a misbehaving metric during an incident. Metrics can be disabled
+// and enabled again at any time, the change applies to the next write.
+type MetricSwitch struct {
+	lock     sync.RWMutex
+	disabled map[string]bool
+}
+
+// NewMetricSwitch returns a switch with the given metrics disabled.
+func NewMetricSwitch(disabled ...string) *MetricSwitch {
+	s := &MetricSwitch{disabled: make(map[string]bool, len(disabled))}
+	for _, metric := range disabled {
+		s.disabled[metric] = true
+	}
+	return s
+}
+
+// Disable stops the writes of the metric.
+func (s *MetricSwitch) Disable(metric string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.disabled[metric] = true
+}
+
+// Enable resumes the writes of the metric.
+func (s *MetricSwitch) Enable(metric string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.disabled, metric)
+}
+
+// IsDisabled returns true if the writes of the metric are disabled.
+func (s *MetricSwitch) IsDisabled(metric string) bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.disabled[metric]
+}
+
+// Stage returns a stage dropping the series of disabled metrics from the
+// batch. Their samples are counted.
+func (s *MetricSwitch) Stage() IngestStage {
+	return func(tts []prompb.TimeSeries) ([]prompb.TimeSeries, error) {
+		s.lock.RLock()
+		defer s.lock.RUnlock()
+		if len(s.disabled) == 0 {
+			return tts, nil
+		}
+		kept := tts[:0]
+		for _, t := range tts {
+			if s.disabled[prompbLabelValue(t.Labels, MetricNameLabelName)] {
+				disabledMetricSamples.Add(float64(len(t.Samples)))
+				continue
+			}
+			kept = append(kept, t)
+		}
+		return kept, nil
+	}
+}
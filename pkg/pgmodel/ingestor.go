@@ -5,8 +5,16 @@
 package pgmodel
 
 import (
+	"context"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
 
+	"github.com/prometheus/common/model"
+	"github.com/timescale/timescale-prometheus/pkg/log"
 	"github.com/timescale/timescale-prometheus/pkg/prompb"
 )
 
@@ -16,6 +24,12 @@ const (
 
 var (
 	ErrNoMetricName = fmt.Errorf("metric name missing")
+	// ErrSeriesTooLarge is returned for write requests with a series whose
+	// labels exceed the maximum size.
+	ErrSeriesTooLarge = fmt.Errorf("series labels too large")
+	// ErrInvalidLabel is returned for write requests with a series whose
+	// labels can not be stored.
+	ErrInvalidLabel = fmt.Errorf("invalid label")
 )
 
 // SeriesID represents a globally unique id for the series. This should be equivalent
@@ -24,7 +38,7 @@ type SeriesID int64
 
 // inserter is responsible for inserting label, series and data into the storage.
 type inserter interface {
-	InsertNewData(rows map[string][]samplesInfo) (uint64, error)
+	InsertNewData(ctx context.Context, rows map[string][]samplesInfo) (uint64, DroppedSamples, error)
 	CompleteMetricCreation() error
 	Close()
 }
@@ -50,20 +64,75 @@ type samplesInfo struct {
 
 // DBIngestor ingest the TimeSeries data into Timescale database.
 type DBIngestor struct {
-	db inserter
+	db     inserter
+	stages []IngestStage
+	// maxLabelsSize is zero if the size of labels is not limited
+	maxLabelsSize int
+	// maxLabelValueLength is zero if the length of values is not limited
+	maxLabelValueLength int
+	// tenancy is nil if tenancy is disabled
+	tenancy *Tenancy
+	// partialWrites rejects the series that can not be stored rather than
+	// the whole request
+	partialWrites bool
+	// buffer is nil if writes are not buffered while the database is
+	// unavailable
+	buffer *InsertBuffer
+	// replayConcurrency is the number of routines replaying the buffer
+	replayConcurrency int
+	// unavailable is set while the database is known to be unavailable,
+	// until a replay of the buffer reaches it
+	unavailable int32
+	stopReplay  chan struct{}
+	replayDone  chan struct{}
+	// stopOnce closes stopReplay once, for Close and Shutdown alike
+	stopOnce sync.Once
 }
 
 // Ingest transforms and ingests the timeseries data into Timescale database.
-func (i *DBIngestor) Ingest(tts []prompb.TimeSeries, req *prompb.WriteRequest) (uint64, error) {
-	data, totalRows, err := i.parseData(tts, req)
+func (i *DBIngestor) Ingest(ctx context.Context, tts []prompb.TimeSeries, req *prompb.WriteRequest) (uint64, error) {
+	tts, err := applyStages(tts, i.stages)
+	if err != nil {
+		return 0, err
+	}
+	// after the stages, so that they cannot change the tenant
+	if err = i.tenancy.labelSeries(ctx, tts); err != nil {
+		return 0, err
+	}
+
+	data, totalRows, rejected, err := i.parseData(tts, req)
 
 	if err != nil {
 		return 0, err
 	}
+	rowsInserted, err := i.insert(ctx, data, totalRows)
+	if err == nil && rejected != nil {
+		rejected.Inserted = rowsInserted
+		return rowsInserted, rejected
+	}
+	return rowsInserted, err
+}
 
-	rowsInserted, err := i.db.InsertNewData(data)
-	if err == nil && int(rowsInserted) != totalRows {
-		return rowsInserted, fmt.Errorf("Failed to insert all the data! Expected: %d, Got: %d", totalRows, rowsInserted)
+// insert inserts the parsed samples, or buffers them while the database is
+// unavailable.
+func (i *DBIngestor) insert(ctx context.Context, data map[string][]samplesInfo, totalRows int) (uint64, error) {
+	// while the database is known to be unavailable, requests are
+	// buffered without trying it; once a replay reaches it again, they
+	// are inserted directly while the buffer is replayed
+	if i.buffer != nil && atomic.LoadInt32(&i.unavailable) != 0 {
+		return i.bufferData(data, totalRows)
+	}
+
+	rowsInserted, dropped, err := i.db.InsertNewData(ctx, data)
+	if i.buffer != nil && isUnavailableError(err) {
+		log.Warn("msg", "database unavailable, buffering write request", "err", err)
+		atomic.StoreInt32(&i.unavailable, 1)
+		return i.bufferData(data, totalRows)
+	}
+	// the samples dropped by the inserter's checks are not inserted, but
+	// are accounted for
+	if err == nil && int(rowsInserted+dropped.Total()) != totalRows {
+		return rowsInserted, fmt.Errorf("Failed to insert all the data! Expected: %d, Got: %d inserted and %d dropped", totalRows, rowsInserted, dropped.Total())
 	}
 	return rowsInserted, err
 }
@@ -72,22 +141,28 @@ func (i *DBIngestor) CompleteMetricCreation() error {
 	return i.db.CompleteMetricCreation()
 }
 
-func (i *DBIngestor) parseData(tts []prompb.TimeSeries, req *prompb.WriteRequest) (map[string][]samplesInfo, int, error) {
+// parseData groups the samples of the series by metric. In partial write
+// mode, series that can not be stored are left out and returned as rejected
+// rather than failing the whole request.
+func (i *DBIngestor) parseData(tts []prompb.TimeSeries, req *prompb.WriteRequest) (data map[string][]samplesInfo, rows int, rejected *PartialWriteError, err error) {
 	dataSamples := make(map[string][]samplesInfo)
-	rows := 0
 
-	for i := range tts {
-		t := &tts[i]
+	for j := range tts {
+		t := &tts[j]
 		if len(t.Samples) == 0 {
 			continue
 		}
 
-		seriesLabels, metricName, err := labelProtosToLabels(t.Labels)
+		seriesLabels, metricName, err := i.parseSeries(t.Labels)
 		if err != nil {
-			return nil, rows, err
-		}
-		if metricName == "" {
-			return nil, rows, ErrNoMetricName
+			if !i.partialWrites {
+				return nil, rows, nil, err
+			}
+			if rejected == nil {
+				rejected = &PartialWriteError{Err: err}
+			}
+			rejected.reject(t)
+			continue
 		}
 		sample := samplesInfo{
 			seriesLabels,
@@ -104,10 +179,93 @@ func (i *DBIngestor) parseData(tts []prompb.TimeSeries, req *prompb.WriteRequest
 
 	FinishWriteRequest(req)
 
-	return dataSamples, rows, nil
+	return dataSamples, rows, rejected, nil
+}
+
+// parseSeries returns the labels and the metric name of a series, or an
+// error if the series can not be stored.
+func (i *DBIngestor) parseSeries(lls []prompb.Label) (*Labels, string, error) {
+	if err := i.checkLabelsSize(lls); err != nil {
+		return nil, "", err
+	}
+	if err := i.checkLabels(lls); err != nil {
+		return nil, "", err
+	}
+	seriesLabels, metricName, err := labelProtosToLabels(lls)
+	if err != nil {
+		return nil, "", err
+	}
+	if metricName == "" {
+		return nil, "", ErrNoMetricName
+	}
+	return seriesLabels, metricName, nil
+}
+
+// checkLabelsSize returns ErrSeriesTooLarge if the combined size of the names
+// and values of the labels exceeds the maximum.
+func (i *DBIngestor) checkLabelsSize(lls []prompb.Label) error {
+	if i.maxLabelsSize <= 0 {
+		return nil
+	}
+	size := 0
+	for _, l := range lls {
+		size += len(l.Name) + len(l.Value)
+	}
+	if size <= i.maxLabelsSize {
+		return nil
+	}
+	oversizedSeries.Inc()
+	return fmt.Errorf("%w: %d bytes in %d labels, maximum %d bytes", ErrSeriesTooLarge, size, len(lls), i.maxLabelsSize)
+}
+
+// checkLabels returns ErrInvalidLabel if a label name does not follow the
+// naming rules of Prometheus or occurs more than once, or a value is not
+// valid UTF-8, contains a NUL byte or is longer than the maximum. PostgreSQL
+// can not store such values as text, so they would fail the whole insert
+// they are batched in.
+func (i *DBIngestor) checkLabels(lls []prompb.Label) error {
+	if name, ok := duplicateLabelName(lls); ok {
+		invalidSeries.Inc()
+		return fmt.Errorf("%w: duplicate name %q", ErrInvalidLabel, name)
+	}
+	for _, l := range lls {
+		var err error
+		switch {
+		case !model.LabelName(l.Name).IsValid():
+			err = fmt.Errorf("%w: name %q", ErrInvalidLabel, l.Name)
+		case !utf8.ValidString(l.Value):
+			err = fmt.Errorf("%w: value of %s is not valid UTF-8", ErrInvalidLabel, l.Name)
+		case strings.IndexByte(l.Value, 0) >= 0:
+			err = fmt.Errorf("%w: value of %s contains a NUL byte", ErrInvalidLabel, l.Name)
+		case i.maxLabelValueLength > 0 && len(l.Value) > i.maxLabelValueLength:
+			err = fmt.Errorf("%w: value of %s is %d bytes, maximum %d bytes", ErrInvalidLabel, l.Name, len(l.Value), i.maxLabelValueLength)
+		}
+		if err != nil {
+			invalidSeries.Inc()
+			return err
+		}
+	}
+	return nil
+}
+
+// duplicateLabelName returns a label name that occurs more than once.
+func duplicateLabelName(lls []prompb.Label) (string, bool) {
+	less := func(i, j int) bool { return lls[i].Name < lls[j].Name }
+	if !sort.SliceIsSorted(lls, less) {
+		// the labels of the request are sorted later, when they are parsed
+		lls = append([]prompb.Label(nil), lls...)
+		sort.Slice(lls, less)
+	}
+	for i := 1; i < len(lls); i++ {
+		if lls[i].Name == lls[i-1].Name {
+			return lls[i].Name, true
+		}
+	}
+	return "", false
 }
 
 // Close closes the ingestor
 func (i *DBIngestor) Close() {
+	i.stopReplaying()
 	i.db.Close()
 }
@@ -4,12 +4,17 @@
 
 package pgmodel
 
-import "github.com/timescale/timescale-prometheus/pkg/prompb"
+import (
+	"context"
+
+	"github.com/timescale/timescale-prometheus/pkg/prompb"
+)
 
 // DBInserter is responsible for ingesting the TimeSeries protobuf structs and
 // storing them in the database.
 type DBInserter interface {
 	// Ingest takes an array of TimeSeries and attepts to store it into the database.
 	// Returns the number of metrics ingested and any error encountered before finishing.
-	Ingest([]prompb.TimeSeries, *prompb.WriteRequest) (uint64, error)
+	// If ctx is canceled before the data is stored, Ingest returns ctx.Err().
+	Ingest(context.Context, []prompb.TimeSeries, *prompb.WriteRequest) (uint64, error)
 }
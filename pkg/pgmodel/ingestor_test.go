@@ -4,9 +4,27 @@
 package pgmodel
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"math"
+	"net"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgerrcode"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/relabel"
+	"github.com/prometheus/prometheus/pkg/value"
 	"github.com/timescale/timescale-prometheus/pkg/prompb"
 )
 
@@ -49,14 +67,25 @@ type mockInserter struct {
 	insertedData    []map[string][]samplesInfo
 	insertSeriesErr error
 	insertDataErr   error
+	// dropped are reported as dropped by every insert, out of the samples
+	// received
+	dropped DroppedSamples
+	// mtx serializes the inserts of the insert buffer's replay routines
+	mtx sync.Mutex
 }
 
 func (m *mockInserter) Close() {
 
 }
 
-func (m *mockInserter) InsertNewData(rows map[string][]samplesInfo) (uint64, error) {
-	return m.InsertData(rows)
+func (m *mockInserter) InsertNewData(ctx context.Context, rows map[string][]samplesInfo) (uint64, DroppedSamples, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	n, err := m.InsertData(rows)
+	if err != nil {
+		return n, DroppedSamples{}, err
+	}
+	return n - m.dropped.Total(), m.dropped, nil
 }
 
 func (m *mockInserter) CompleteMetricCreation() error {
@@ -198,6 +227,22 @@ func TestDBIngestorIngest(t *testing.T) {
 			count:       2,
 			countSeries: 1,
 		},
+		{
+			name: "NaN values",
+			metrics: []prompb.TimeSeries{
+				{
+					Labels: []prompb.Label{
+						{Name: MetricNameLabelName, Value: "test"},
+					},
+					Samples: []prompb.Sample{
+						{Timestamp: 1, Value: math.NaN()},
+						{Timestamp: 2, Value: math.Float64frombits(value.StaleNaN)},
+					},
+				},
+			},
+			count:       2,
+			countSeries: 1,
+		},
 		{
 			name: "Insert series error",
 			metrics: []prompb.TimeSeries{
@@ -258,7 +303,7 @@ func TestDBIngestorIngest(t *testing.T) {
 				db: &inserter,
 			}
 
-			count, err := i.Ingest(c.metrics, NewWriteRequest())
+			count, err := i.Ingest(context.Background(), c.metrics, NewWriteRequest())
 
 			if err != nil {
 				if c.insertSeriesErr != nil && err != c.insertSeriesErr {
@@ -298,3 +343,989 @@ func TestDBIngestorIngest(t *testing.T) {
 		})
 	}
 }
+
+func TestDBIngestorIngestStages(t *testing.T) {
+	dropDev := &relabel.Config{
+		SourceLabels: model.LabelNames{"env"},
+		Separator:    ";",
+		Regex:        relabel.MustNewRegexp("dev"),
+		Action:       relabel.Drop,
+	}
+	errStage := fmt.Errorf("stage error")
+
+	testCases := []struct {
+		name    string
+		stages  []IngestStage
+		metrics []prompb.TimeSeries
+		count   uint64
+		samples map[string][]prompb.Sample
+		err     error
+	}{
+		{
+			name: "No stages",
+			metrics: []prompb.TimeSeries{
+				{
+					Labels: []prompb.Label{
+						{Name: MetricNameLabelName, Value: "test"},
+					},
+					Samples: []prompb.Sample{
+						{Timestamp: 12, Value: 0.1},
+						{Timestamp: 12, Value: 0.2},
+					},
+				},
+			},
+			count: 2,
+			samples: map[string][]prompb.Sample{
+				"__name__=test": {
+					{Timestamp: 12, Value: 0.1},
+					{Timestamp: 12, Value: 0.2},
+				},
+			},
+		},
+		{
+			name:   "Relabel, round and dedup",
+			stages: []IngestStage{ValidateStage(), RelabelStage(dropDev), RoundTimestampsStage(10 * time.Millisecond), DedupStage()},
+			metrics: []prompb.TimeSeries{
+				{
+					Labels: []prompb.Label{
+						{Name: MetricNameLabelName, Value: "test"},
+						{Name: "env", Value: "prod"},
+					},
+					Samples: []prompb.Sample{
+						{Timestamp: 25, Value: 0.3},
+						{Timestamp: 12, Value: 0.1},
+						{Timestamp: 17, Value: 0.2},
+					},
+				},
+				{
+					Labels: []prompb.Label{
+						{Name: MetricNameLabelName, Value: "test"},
+						{Name: "env", Value: "dev"},
+					},
+					Samples: []prompb.Sample{
+						{Timestamp: 1, Value: 0.1},
+					},
+				},
+			},
+			count: 2,
+			samples: map[string][]prompb.Sample{
+				"__name__=test,env=prod": {
+					{Timestamp: 10, Value: 0.2},
+					{Timestamp: 20, Value: 0.3},
+				},
+			},
+		},
+		{
+			name: "Custom stage",
+			stages: []IngestStage{
+				func(tts []prompb.TimeSeries) ([]prompb.TimeSeries, error) {
+					for i := range tts {
+						for j := range tts[i].Samples {
+							tts[i].Samples[j].Value *= 2
+						}
+					}
+					return tts, nil
+				},
+			},
+			metrics: []prompb.TimeSeries{
+				{
+					Labels: []prompb.Label{
+						{Name: MetricNameLabelName, Value: "test"},
+					},
+					Samples: []prompb.Sample{
+						{Timestamp: 1, Value: 0.1},
+					},
+				},
+			},
+			count: 1,
+			samples: map[string][]prompb.Sample{
+				"__name__=test": {
+					{Timestamp: 1, Value: 0.2},
+				},
+			},
+		},
+		{
+			name:   "Invalid label name",
+			stages: []IngestStage{ValidateStage()},
+			metrics: []prompb.TimeSeries{
+				{
+					Labels: []prompb.Label{
+						{Name: MetricNameLabelName, Value: "test"},
+						{Name: "no-dashes", Value: "test"},
+					},
+					Samples: []prompb.Sample{
+						{Timestamp: 1, Value: 0.1},
+					},
+				},
+			},
+			err: fmt.Errorf("invalid label name %q", "no-dashes"),
+		},
+		{
+			name: "Stage error stops the chain",
+			stages: []IngestStage{
+				func(tts []prompb.TimeSeries) ([]prompb.TimeSeries, error) {
+					return nil, errStage
+				},
+				func(tts []prompb.TimeSeries) ([]prompb.TimeSeries, error) {
+					t.Fatal("stage after error should not run")
+					return tts, nil
+				},
+			},
+			metrics: []prompb.TimeSeries{
+				{
+					Labels: []prompb.Label{
+						{Name: MetricNameLabelName, Value: "test"},
+					},
+					Samples: []prompb.Sample{
+						{Timestamp: 1, Value: 0.1},
+					},
+				},
+			},
+			err: errStage,
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			inserter := mockInserter{
+				insertedSeries: make(map[string]SeriesID),
+			}
+
+			i := DBIngestor{
+				db:     &inserter,
+				stages: c.stages,
+			}
+
+			count, err := i.Ingest(context.Background(), c.metrics, NewWriteRequest())
+
+			if c.err != nil {
+				if err == nil || err.Error() != c.err.Error() {
+					t.Fatalf("wrong error returned: got\n%v\nwant\n%s\n", err, c.err)
+				}
+				if len(inserter.insertedData) != 0 {
+					t.Errorf("data inserted despite stage error: %+v", inserter.insertedData)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if count != c.count {
+				t.Errorf("invalid number of metrics inserted: got %d, want %d\n", count, c.count)
+			}
+
+			samples := make(map[string][]prompb.Sample)
+			for _, rows := range inserter.insertedData {
+				for _, data := range rows {
+					for _, si := range data {
+						pairs := make([]string, len(si.labels.names))
+						for j := range pairs {
+							pairs[j] = si.labels.names[j] + "=" + si.labels.values[j]
+						}
+						key := strings.Join(pairs, ",")
+						samples[key] = append(samples[key], si.samples...)
+					}
+				}
+			}
+			if !reflect.DeepEqual(samples, c.samples) {
+				t.Errorf("unexpected samples inserted:\ngot\n%+v\nwanted\n%+v", samples, c.samples)
+			}
+		})
+	}
+}
+
+func TestMetricSwitch(t *testing.T) {
+	metrics := func() []prompb.TimeSeries {
+		return []prompb.TimeSeries{
+			{
+				Labels:  []prompb.Label{{Name: MetricNameLabelName, Value: "foo"}},
+				Samples: []prompb.Sample{{Timestamp: 1, Value: 0.1}, {Timestamp: 2, Value: 0.2}},
+			},
+			{
+				Labels:  []prompb.Label{{Name: MetricNameLabelName, Value: "bar"}},
+				Samples: []prompb.Sample{{Timestamp: 1, Value: 0.3}},
+			},
+		}
+	}
+
+	sw := NewMetricSwitch()
+	inserter := mockInserter{
+		insertedSeries: make(map[string]SeriesID),
+	}
+	i := DBIngestor{
+		db:     &inserter,
+		stages: []IngestStage{sw.Stage()},
+	}
+
+	steps := []struct {
+		name     string
+		toggle   func()
+		disabled bool
+		count    uint64
+		dropped  float64
+		inserted []string
+	}{
+		{
+			name:     "enabled",
+			toggle:   func() {},
+			count:    3,
+			inserted: []string{"bar", "foo"},
+		},
+		{
+			name:     "disabled",
+			toggle:   func() { sw.Disable("foo") },
+			disabled: true,
+			count:    1,
+			dropped:  2,
+			inserted: []string{"bar"},
+		},
+		{
+			name:     "enabled again",
+			toggle:   func() { sw.Enable("foo") },
+			count:    3,
+			inserted: []string{"bar", "foo"},
+		},
+	}
+
+	for _, step := range steps {
+		step.toggle()
+		if sw.IsDisabled("foo") != step.disabled {
+			t.Fatalf("%s: unexpected state of foo: disabled %v", step.name, sw.IsDisabled("foo"))
+		}
+		inserter.insertedData = nil
+		droppedBefore := testutil.ToFloat64(disabledMetricSamples)
+
+		count, err := i.Ingest(context.Background(), metrics(), NewWriteRequest())
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", step.name, err)
+		}
+		if count != step.count {
+			t.Errorf("%s: unexpected number of samples inserted: got %d, wanted %d", step.name, count, step.count)
+		}
+		if got := testutil.ToFloat64(disabledMetricSamples) - droppedBefore; got != step.dropped {
+			t.Errorf("%s: unexpected number of dropped samples counted: got %v, wanted %v", step.name, got, step.dropped)
+		}
+		inserted := make([]string, 0)
+		for _, rows := range inserter.insertedData {
+			for metric := range rows {
+				inserted = append(inserted, metric)
+			}
+		}
+		sort.Strings(inserted)
+		if !reflect.DeepEqual(inserted, step.inserted) {
+			t.Errorf("%s: unexpected metrics inserted: got %v, wanted %v", step.name, inserted, step.inserted)
+		}
+	}
+}
+
+func TestDBIngestorMaxLabelsSize(t *testing.T) {
+	metrics := func(value string) []prompb.TimeSeries {
+		return []prompb.TimeSeries{
+			{
+				Labels:  []prompb.Label{{Name: MetricNameLabelName, Value: "foo"}},
+				Samples: []prompb.Sample{{Timestamp: 1, Value: 0.1}},
+			},
+			{
+				// 11 bytes of names and 3 of the metric name besides the value
+				Labels:  []prompb.Label{{Name: MetricNameLabelName, Value: "foo"}, {Name: "big", Value: value}},
+				Samples: []prompb.Sample{{Timestamp: 1, Value: 0.2}},
+			},
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		maxSize  int
+		value    string
+		err      error
+		rejected float64
+	}{
+		{
+			name:  "no limit",
+			value: strings.Repeat("x", 1000),
+		},
+		{
+			name:    "at limit",
+			maxSize: 114,
+			value:   strings.Repeat("x", 100),
+		},
+		{
+			name:     "over limit",
+			maxSize:  114,
+			value:    strings.Repeat("x", 101),
+			err:      ErrSeriesTooLarge,
+			rejected: 1,
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			inserter := mockInserter{
+				insertedSeries: make(map[string]SeriesID),
+			}
+			i := DBIngestor{
+				db:            &inserter,
+				maxLabelsSize: c.maxSize,
+			}
+
+			rejectedBefore := testutil.ToFloat64(oversizedSeries)
+			_, err := i.Ingest(context.Background(), metrics(c.value), NewWriteRequest())
+			if !errors.Is(err, c.err) {
+				t.Fatalf("unexpected error: got %v, wanted %v", err, c.err)
+			}
+			if got := testutil.ToFloat64(oversizedSeries) - rejectedBefore; got != c.rejected {
+				t.Errorf("unexpected number of rejected series: got %v, wanted %v", got, c.rejected)
+			}
+			if c.err != nil && len(inserter.insertedData) != 0 {
+				t.Errorf("samples inserted despite rejected series: %v", inserter.insertedData)
+			}
+		})
+	}
+}
+
+func TestDBIngestorDroppedSamples(t *testing.T) {
+	inserter := mockInserter{
+		insertedSeries: make(map[string]SeriesID),
+		dropped:        DroppedSamples{Duplicates: 1, OutOfOrder: 1},
+	}
+	i := DBIngestor{db: &inserter}
+
+	metrics := []prompb.TimeSeries{
+		{
+			Labels:  []prompb.Label{{Name: MetricNameLabelName, Value: "foo"}},
+			Samples: []prompb.Sample{{Timestamp: 2, Value: 0.1}, {Timestamp: 1, Value: 0.2}, {Timestamp: 2, Value: 0.3}},
+		},
+	}
+	count, err := i.Ingest(context.Background(), metrics, NewWriteRequest())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// the dropped samples are accounted for, but not counted as inserted
+	if count != 1 {
+		t.Errorf("unexpected number of samples inserted: got %d, wanted 1", count)
+	}
+}
+
+func TestDBIngestorInvalidLabels(t *testing.T) {
+	testCases := []struct {
+		name      string
+		label     prompb.Label
+		maxLength int
+		err       error
+	}{
+		{
+			name:  "valid label",
+			label: prompb.Label{Name: "job", Value: "it's a \"quoted\"\nvalue"},
+		},
+		{
+			name:  "quote in name",
+			label: prompb.Label{Name: `job") OR 1=1 --`, Value: "api"},
+			err:   ErrInvalidLabel,
+		},
+		{
+			name:  "newline in name",
+			label: prompb.Label{Name: "job\n", Value: "api"},
+			err:   ErrInvalidLabel,
+		},
+		{
+			name:  "non-UTF-8 value",
+			label: prompb.Label{Name: "job", Value: "api\xff\xfe"},
+			err:   ErrInvalidLabel,
+		},
+		{
+			name:  "NUL byte in value",
+			label: prompb.Label{Name: "job", Value: "api\x00"},
+			err:   ErrInvalidLabel,
+		},
+		{
+			name:  "duplicate name",
+			label: prompb.Label{Name: MetricNameLabelName, Value: "bar"},
+			err:   ErrInvalidLabel,
+		},
+		{
+			name:      "value at maximum length",
+			label:     prompb.Label{Name: "job", Value: strings.Repeat("x", 10)},
+			maxLength: 10,
+		},
+		{
+			name:      "value over maximum length",
+			label:     prompb.Label{Name: "job", Value: strings.Repeat("x", 11)},
+			maxLength: 10,
+			err:       ErrInvalidLabel,
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			inserter := mockInserter{
+				insertedSeries: make(map[string]SeriesID),
+			}
+			i := DBIngestor{
+				db:                  &inserter,
+				maxLabelValueLength: c.maxLength,
+			}
+			tts := []prompb.TimeSeries{
+				{
+					Labels:  []prompb.Label{{Name: MetricNameLabelName, Value: "foo"}},
+					Samples: []prompb.Sample{{Timestamp: 1, Value: 0.1}},
+				},
+				{
+					Labels:  []prompb.Label{{Name: MetricNameLabelName, Value: "foo"}, c.label},
+					Samples: []prompb.Sample{{Timestamp: 1, Value: 0.2}},
+				},
+			}
+
+			rejectedBefore := testutil.ToFloat64(invalidSeries)
+			_, err := i.Ingest(context.Background(), tts, NewWriteRequest())
+			if !errors.Is(err, c.err) {
+				t.Fatalf("unexpected error: got %v, wanted %v", err, c.err)
+			}
+			if c.err == nil {
+				return
+			}
+			if got := testutil.ToFloat64(invalidSeries) - rejectedBefore; got != 1 {
+				t.Errorf("unexpected number of rejected series: got %v, wanted 1", got)
+			}
+			if len(inserter.insertedData) != 0 {
+				t.Errorf("samples inserted despite rejected series: %v", inserter.insertedData)
+			}
+		})
+	}
+}
+
+func TestDBIngestorTenancy(t *testing.T) {
+	series := func(lls ...prompb.Label) []prompb.TimeSeries {
+		return []prompb.TimeSeries{
+			{
+				Labels:  append([]prompb.Label{{Name: MetricNameLabelName, Value: "foo"}}, lls...),
+				Samples: []prompb.Sample{{Timestamp: 1, Value: 0.1}},
+			},
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		ctxTenant     string
+		defaultTenant string
+		honorLabels   bool
+		labels        []prompb.Label
+		expected      []prompb.Label
+		err           error
+	}{
+		{
+			name:      "tenant of request",
+			ctxTenant: "a",
+			labels:    []prompb.Label{{Name: "job", Value: "j"}},
+			expected:  []prompb.Label{{Name: MetricNameLabelName, Value: "foo"}, {Name: "job", Value: "j"}, {Name: "tenant", Value: "a"}},
+		},
+		{
+			name:      "tenant of request replaces label",
+			ctxTenant: "a",
+			labels:    []prompb.Label{{Name: "tenant", Value: "b"}},
+			expected:  []prompb.Label{{Name: MetricNameLabelName, Value: "foo"}, {Name: "tenant", Value: "a"}},
+		},
+		{
+			name:      "tenant of request replaces label of unsorted series",
+			ctxTenant: "a",
+			labels:    []prompb.Label{{Name: "zz", Value: "1"}, {Name: "tenant", Value: "evil"}},
+			expected:  []prompb.Label{{Name: MetricNameLabelName, Value: "foo"}, {Name: "tenant", Value: "a"}, {Name: "zz", Value: "1"}},
+		},
+		{
+			name:      "tenant of request replaces every label",
+			ctxTenant: "a",
+			labels:    []prompb.Label{{Name: "tenant", Value: "b"}, {Name: "tenant", Value: "c"}},
+			expected:  []prompb.Label{{Name: MetricNameLabelName, Value: "foo"}, {Name: "tenant", Value: "a"}},
+		},
+		{
+			name:          "default tenant replaces label",
+			defaultTenant: "d",
+			labels:        []prompb.Label{{Name: "tenant", Value: "b"}},
+			expected:      []prompb.Label{{Name: MetricNameLabelName, Value: "foo"}, {Name: "tenant", Value: "d"}},
+		},
+		{
+			name:   "tenant label without a tenant",
+			labels: []prompb.Label{{Name: "tenant", Value: "b"}},
+			err:    ErrMissingTenant,
+		},
+		{
+			name:          "honored tenant label",
+			defaultTenant: "d",
+			honorLabels:   true,
+			labels:        []prompb.Label{{Name: "tenant", Value: "b"}},
+			expected:      []prompb.Label{{Name: MetricNameLabelName, Value: "foo"}, {Name: "tenant", Value: "b"}},
+		},
+		{
+			name:        "tenant of request replaces honored label",
+			ctxTenant:   "a",
+			honorLabels: true,
+			labels:      []prompb.Label{{Name: "tenant", Value: "b"}},
+			expected:    []prompb.Label{{Name: MetricNameLabelName, Value: "foo"}, {Name: "tenant", Value: "a"}},
+		},
+		{
+			name:          "default tenant",
+			defaultTenant: "d",
+			labels:        []prompb.Label{{Name: "a", Value: "1"}},
+			expected:      []prompb.Label{{Name: MetricNameLabelName, Value: "foo"}, {Name: "a", Value: "1"}, {Name: "tenant", Value: "d"}},
+		},
+		{
+			name: "missing tenant",
+			err:  ErrMissingTenant,
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			inserter := mockInserter{
+				insertedSeries: make(map[string]SeriesID),
+			}
+			i := DBIngestor{
+				db:      &inserter,
+				tenancy: &Tenancy{Label: "tenant", DefaultTenant: c.defaultTenant, HonorSeriesLabels: c.honorLabels},
+			}
+
+			ctx := context.Background()
+			if c.ctxTenant != "" {
+				ctx = WithTenant(ctx, c.ctxTenant)
+			}
+			_, err := i.Ingest(ctx, series(c.labels...), NewWriteRequest())
+			if !errors.Is(err, c.err) {
+				t.Fatalf("unexpected error: got %v, wanted %v", err, c.err)
+			}
+			if c.err != nil {
+				if len(inserter.insertedData) != 0 {
+					t.Errorf("samples inserted without a tenant: %v", inserter.insertedData)
+				}
+				return
+			}
+
+			expected, _, err := labelProtosToLabels(c.expected)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := inserter.insertedSeries[expected.String()]; !ok || len(inserter.insertedSeries) != 1 {
+				t.Errorf("unexpected series inserted: got %v, wanted %v", inserter.insertedSeries, c.expected)
+			}
+		})
+	}
+}
+
+func TestInsertBuffer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "insert_buffer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	request := func(ts int64) []prompb.TimeSeries {
+		return []prompb.TimeSeries{
+			{
+				Labels:  []prompb.Label{{Name: MetricNameLabelName, Value: "foo"}},
+				Samples: []prompb.Sample{{Timestamp: ts, Value: 0.1}},
+			},
+		}
+	}
+	oldestTimestamp := func(b *InsertBuffer) int64 {
+		reqs := b.oldest(1)
+		if len(reqs) != 1 || reqs[0].err != nil {
+			t.Fatalf("no oldest request: %v", reqs)
+		}
+		return reqs[0].tts[0].Samples[0].Timestamp
+	}
+
+	b, err := OpenInsertBuffer(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !b.empty() {
+		t.Fatal("new buffer not empty")
+	}
+	for ts := int64(1); ts <= 3; ts++ {
+		if err = b.append(request(ts)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	size := b.bytes / 3
+	if testutil.ToFloat64(insertBufferBytes) != float64(3*size) {
+		t.Errorf("unexpected buffered bytes: got %v, wanted %v", testutil.ToFloat64(insertBufferBytes), 3*size)
+	}
+	if ts := oldestTimestamp(b); ts != 1 {
+		t.Errorf("unexpected oldest request: got %d, wanted 1", ts)
+	}
+	if reqs := b.oldest(5); len(reqs) != 3 || reqs[2].tts[0].Samples[0].Timestamp != 3 {
+		t.Errorf("unexpected oldest requests: %v", reqs)
+	}
+	b.remove(b.oldest(1)[0].seq)
+
+	// the requests outlive the buffer
+	b, err = OpenInsertBuffer(dir, 3*size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.entries) != 2 || b.bytes != 2*size {
+		t.Fatalf("unexpected reopened buffer: %d requests of %d bytes", len(b.entries), b.bytes)
+	}
+	if ts := oldestTimestamp(b); ts != 2 {
+		t.Errorf("unexpected oldest request: got %d, wanted 2", ts)
+	}
+
+	// a full buffer drops its oldest requests
+	dropped := testutil.ToFloat64(insertBufferDroppedBytes)
+	for ts := int64(4); ts <= 5; ts++ {
+		if err = b.append(request(ts)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(b.entries) != 3 || b.bytes != 3*size {
+		t.Fatalf("unexpected full buffer: %d requests of %d bytes", len(b.entries), b.bytes)
+	}
+	if ts := oldestTimestamp(b); ts != 3 {
+		t.Errorf("unexpected oldest request: got %d, wanted 3", ts)
+	}
+	if delta := testutil.ToFloat64(insertBufferDroppedBytes) - dropped; delta != float64(size) {
+		t.Errorf("unexpected dropped bytes: got %v, wanted %v", delta, size)
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Errorf("unexpected files in buffer directory: got %d, wanted 3", len(files))
+	}
+
+	// a partly replayed request keeps the series left
+	seq := b.oldest(1)[0].seq
+	if err = b.replace(seq, request(7)); err != nil {
+		t.Fatal(err)
+	}
+	if ts := oldestTimestamp(b); ts != 7 || b.entries[0].seq != seq {
+		t.Errorf("unexpected replaced request: got timestamp %d", ts)
+	}
+
+	// a request larger than the whole buffer fails
+	b.maxBytes = size - 1
+	if err = b.append(request(6)); err == nil {
+		t.Error("request larger than the buffer buffered")
+	}
+}
+
+func TestInsertBufferPartialFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "insert_buffer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	b, err := OpenInsertBuffer(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tts := []prompb.TimeSeries{
+		{
+			Labels:  []prompb.Label{{Name: MetricNameLabelName, Value: "foo"}},
+			Samples: []prompb.Sample{{Timestamp: 1, Value: 0.1}},
+		},
+	}
+	if err = b.append(tts); err != nil {
+		t.Fatal(err)
+	}
+	size := b.bytes
+	// a request whose write was interrupted by a crash
+	partial := b.path(1) + insertBufferTmpExt
+	if err = ioutil.WriteFile(partial, []byte("partial"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err = OpenInsertBuffer(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(partial); !os.IsNotExist(err) {
+		t.Errorf("partial request not deleted: %v", err)
+	}
+	if len(b.entries) != 1 || b.bytes != size || b.nextSeq != 1 {
+		t.Errorf("unexpected reopened buffer: %d requests of %d bytes, next sequence number %d", len(b.entries), b.bytes, b.nextSeq)
+	}
+}
+
+func TestDBIngestorInsertBuffer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "insert_buffer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	b, err := OpenInsertBuffer(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(ts int64) []prompb.TimeSeries {
+		return []prompb.TimeSeries{
+			{
+				Labels:  []prompb.Label{{Name: MetricNameLabelName, Value: "foo"}},
+				Samples: []prompb.Sample{{Timestamp: ts, Value: 0.1}},
+			},
+		}
+	}
+	unavailable := &net.OpError{Op: "dial", Net: "tcp", Err: fmt.Errorf("connection refused")}
+	inserter := mockInserter{
+		insertedSeries: make(map[string]SeriesID),
+		insertDataErr:  unavailable,
+	}
+	i := DBIngestor{
+		db:     &inserter,
+		buffer: b,
+	}
+
+	// the failed request is buffered and acked
+	count, err := i.Ingest(context.Background(), request(1), NewWriteRequest())
+	if err != nil || count != 1 {
+		t.Fatalf("unexpected result of buffered write: count %d, err %v", count, err)
+	}
+	if b.empty() {
+		t.Fatal("failed write request not buffered")
+	}
+	inserter.insertedData = nil
+
+	// newer requests are buffered without trying the database, until a
+	// replay reaches it
+	inserter.insertDataErr = nil
+	if count, err = i.Ingest(context.Background(), request(2), NewWriteRequest()); err != nil || count != 1 {
+		t.Fatalf("unexpected result of buffered write: count %d, err %v", count, err)
+	}
+	if len(inserter.insertedData) != 0 {
+		t.Fatalf("write request inserted while the database is unavailable: %v", inserter.insertedData)
+	}
+	inserter.insertDataErr = &pgconn.PgError{Code: pgerrcode.CannotConnectNow}
+	if i.replayOldest() {
+		t.Fatal("replayed while the database is unavailable")
+	}
+	inserter.insertedData = nil
+
+	inserter.insertDataErr = nil
+	for i.replayOldest() {
+	}
+	if !b.empty() {
+		t.Fatalf("buffer not empty after replay: %v", b.entries)
+	}
+	var timestamps []int64
+	for _, data := range inserter.insertedData {
+		for _, si := range data["foo"] {
+			timestamps = append(timestamps, si.samples[0].Timestamp)
+		}
+	}
+	if !reflect.DeepEqual(timestamps, []int64{1, 2}) {
+		t.Errorf("unexpected replay order: got %v, wanted [1 2]", timestamps)
+	}
+
+	// once the database is back, requests are inserted directly
+	inserter.insertedData = nil
+	if _, err = i.Ingest(context.Background(), request(3), NewWriteRequest()); err != nil {
+		t.Fatal(err)
+	}
+	if len(inserter.insertedData) != 1 || !b.empty() {
+		t.Fatalf("write request not inserted directly: inserted %v, buffered %v", inserter.insertedData, b.entries)
+	}
+
+	// requests failing for other reasons are dropped rather than retried
+	if err = b.append(request(4)); err != nil {
+		t.Fatal(err)
+	}
+	inserter.insertDataErr = fmt.Errorf("check constraint violated")
+	for i.replayOldest() {
+	}
+	if !b.empty() {
+		t.Fatalf("failing request not dropped: %v", b.entries)
+	}
+}
+
+func TestDBIngestorCloseAfterShutdown(t *testing.T) {
+	dir, err := ioutil.TempDir("", "insert_buffer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	b, err := OpenInsertBuffer(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i := &DBIngestor{
+		db:         &mockInserter{insertedSeries: make(map[string]SeriesID)},
+		buffer:     b,
+		stopReplay: make(chan struct{}),
+		replayDone: make(chan struct{}),
+	}
+	go i.replayBuffer(time.Hour)
+
+	// the replay is stopped once, whatever closes the ingestor first
+	i.Close()
+	if err = i.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	i.Close()
+	select {
+	case <-i.replayDone:
+	default:
+		t.Error("replay not stopped")
+	}
+}
+
+// unavailableMetricInserter fails the inserts of one metric as if the
+// database was unavailable.
+type unavailableMetricInserter struct {
+	*mockInserter
+	metric string
+}
+
+func (m *unavailableMetricInserter) InsertNewData(ctx context.Context, rows map[string][]samplesInfo) (uint64, DroppedSamples, error) {
+	if _, ok := rows[m.metric]; ok {
+		return 0, DroppedSamples{}, &pgconn.PgError{Code: pgerrcode.CannotConnectNow}
+	}
+	return m.mockInserter.InsertNewData(ctx, rows)
+}
+
+func TestDBIngestorInsertBufferParallelReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "insert_buffer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	b, err := OpenInsertBuffer(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metrics := []string{"foo", "bar", "baz", "qux", "quux", "corge"}
+	series := func(metric string) []prompb.Label {
+		return []prompb.Label{{Name: MetricNameLabelName, Value: metric}}
+	}
+	const requests = 50
+	for ts := int64(0); ts < requests; ts++ {
+		tts := make([]prompb.TimeSeries, 0, len(metrics))
+		for _, m := range metrics {
+			tts = append(tts, prompb.TimeSeries{Labels: series(m), Samples: []prompb.Sample{{Timestamp: ts, Value: 0.1}}})
+		}
+		if err = b.append(tts); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	inserter := &mockInserter{insertedSeries: make(map[string]SeriesID)}
+	i := DBIngestor{db: inserter, buffer: b, replayConcurrency: 4}
+	for i.replayOldest() {
+	}
+	if !b.empty() {
+		t.Fatalf("buffer not empty after replay: %v", b.entries)
+	}
+	timestamps := make(map[string][]int64)
+	for _, data := range inserter.insertedData {
+		for metric, sis := range data {
+			for _, si := range sis {
+				timestamps[metric] = append(timestamps[metric], si.samples[0].Timestamp)
+			}
+		}
+	}
+	for _, m := range metrics {
+		got := timestamps[m]
+		if len(got) != requests || !sort.SliceIsSorted(got, func(i, j int) bool { return got[i] < got[j] }) {
+			t.Errorf("unexpected replay of %s: got %v", m, got)
+		}
+	}
+
+	// a replay interrupted by the database keeps the series not replayed
+	var unavailable string
+	for _, m := range metrics[1:] {
+		if seriesShard(series(m), 2) != seriesShard(series("foo"), 2) {
+			unavailable = m
+			break
+		}
+	}
+	if unavailable == "" {
+		t.Fatal("no series replayed by another routine than foo")
+	}
+	if err = b.append([]prompb.TimeSeries{
+		{Labels: series("foo"), Samples: []prompb.Sample{{Timestamp: requests, Value: 0.1}}},
+		{Labels: series(unavailable), Samples: []prompb.Sample{{Timestamp: requests, Value: 0.1}}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	inserter.insertedData = nil
+	i = DBIngestor{db: &unavailableMetricInserter{inserter, unavailable}, buffer: b, replayConcurrency: 2}
+	if i.replayOldest() {
+		t.Fatal("replayed while the database is unavailable")
+	}
+	if len(inserter.insertedData) != 1 || inserter.insertedData[0]["foo"] == nil {
+		t.Fatalf("unexpected series replayed: %v", inserter.insertedData)
+	}
+	reqs := b.oldest(1)
+	if len(reqs) != 1 || len(reqs[0].tts) != 1 || reqs[0].tts[0].Labels[0].Value != unavailable {
+		t.Fatalf("unexpected series kept in the buffer: %v", reqs)
+	}
+	if kept := reqs[0].tts[0].Samples; !reflect.DeepEqual(kept, []prompb.Sample{{Timestamp: requests, Value: 0.1}}) {
+		t.Errorf("unexpected samples kept in the buffer: %v", kept)
+	}
+}
+
+func TestDBIngestorPartialWrites(t *testing.T) {
+	valid := []prompb.Label{{Name: MetricNameLabelName, Value: "foo"}, {Name: "job", Value: "api"}}
+	invalid := []prompb.Label{{Name: MetricNameLabelName, Value: "foo"}, {Name: "job", Value: "a\x00pi"}}
+	unnamed := []prompb.Label{{Name: "job", Value: "api"}}
+	series := func() []prompb.TimeSeries {
+		return []prompb.TimeSeries{
+			{Labels: valid, Samples: []prompb.Sample{{Timestamp: 1, Value: 0.1}, {Timestamp: 2, Value: 0.2}}},
+			{Labels: invalid, Samples: []prompb.Sample{{Timestamp: 1, Value: 0.1}}},
+			{Labels: unnamed, Samples: []prompb.Sample{{Timestamp: 1, Value: 0.1}, {Timestamp: 2, Value: 0.2}}},
+		}
+	}
+	fingerprint := func(lls []prompb.Label) uint64 {
+		ls := make(labels.Labels, len(lls))
+		for i, l := range lls {
+			ls[i] = labels.Label{Name: l.Name, Value: l.Value}
+		}
+		return FingerprintFor(ls)
+	}
+
+	t.Run("whole request fails", func(t *testing.T) {
+		inserter := mockInserter{insertedSeries: make(map[string]SeriesID)}
+		i := DBIngestor{db: &inserter}
+		if _, err := i.Ingest(context.Background(), series(), NewWriteRequest()); !errors.Is(err, ErrInvalidLabel) {
+			t.Fatalf("unexpected error: got %v, wanted %v", err, ErrInvalidLabel)
+		}
+		if len(inserter.insertedData) != 0 {
+			t.Errorf("samples of a failed request inserted: %v", inserter.insertedData)
+		}
+	})
+
+	t.Run("partial write", func(t *testing.T) {
+		inserter := mockInserter{insertedSeries: make(map[string]SeriesID)}
+		i := DBIngestor{db: &inserter, partialWrites: true}
+		count, err := i.Ingest(context.Background(), series(), NewWriteRequest())
+		if count != 2 {
+			t.Errorf("unexpected number of samples inserted: got %d, wanted 2", count)
+		}
+		var partial *PartialWriteError
+		if !errors.As(err, &partial) {
+			t.Fatalf("unexpected error: got %v, wanted a partial write", err)
+		}
+		expected := &PartialWriteError{
+			Inserted:           2,
+			FailedSamples:      3,
+			FailedFingerprints: []uint64{fingerprint(invalid), fingerprint(unnamed)},
+			Err:                partial.Err,
+		}
+		if !reflect.DeepEqual(partial, expected) {
+			t.Errorf("unexpected partial write:\ngot\n%+v\nwanted\n%+v", partial, expected)
+		}
+		if !errors.Is(err, ErrInvalidLabel) {
+			t.Errorf("unexpected first error: %v", partial.Err)
+		}
+		expectedSeries, _, err := labelProtosToLabels(valid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := inserter.insertedSeries[expectedSeries.String()]; !ok || len(inserter.insertedSeries) != 1 {
+			t.Errorf("unexpected series inserted: %v", inserter.insertedSeries)
+		}
+	})
+}
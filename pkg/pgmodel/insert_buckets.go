@@ -0,0 +1,65 @@
+// This file and its contents are licensed under the Apache License 2.0.
+// Please see the included NOTICE for copyright information and
+// LICENSE for a copy of the license.
+
+package pgmodel
+
+import (
+	"sort"
+	"time"
+
+	"github.com/prometheus/prometheus/pkg/timestamp"
+)
+
+// sampleRows are the columns of the rows of one sample insert.
+type sampleRows struct {
+	times  []time.Time
+	vals   []float64
+	series []int64
+}
+
+// groupByTimeBucket splits rows into groups of samples falling into the same
+// time range of the given width, aligned to the Unix epoch, ordered by time
+// range. With a width matching the chunk interval of the data table, each
+// group is written into a single chunk. Rows keep their order within a group.
+// If width is not positive all rows are returned as a single group. Times are
+// bucketed in milliseconds, as in nanoseconds the times a TIMESTAMPTZ holds
+// after 2262 would overflow.
+func groupByTimeBucket(rows sampleRows, width time.Duration) []sampleRows {
+	if width <= 0 || len(rows.times) == 0 {
+		return []sampleRows{rows}
+	}
+
+	// samples have a millisecond resolution, so narrower buckets hold the
+	// same samples as buckets of a millisecond
+	widthMs := width.Milliseconds()
+	if widthMs < 1 {
+		widthMs = 1
+	}
+	buckets := make(map[int64]*sampleRows)
+	starts := make([]int64, 0)
+	for i, t := range rows.times {
+		ms := timestamp.FromTime(t)
+		start := ms / widthMs
+		if ms%widthMs < 0 {
+			// round towards negative infinity
+			start--
+		}
+		bucket, ok := buckets[start]
+		if !ok {
+			bucket = &sampleRows{}
+			buckets[start] = bucket
+			starts = append(starts, start)
+		}
+		bucket.times = append(bucket.times, t)
+		bucket.vals = append(bucket.vals, rows.vals[i])
+		bucket.series = append(bucket.series, rows.series[i])
+	}
+
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+	groups := make([]sampleRows, 0, len(starts))
+	for _, start := range starts {
+		groups = append(groups, *buckets[start])
+	}
+	return groups
+}
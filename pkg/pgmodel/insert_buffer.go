@@ -0,0 +1,476 @@
+// This file and its contents are licensed under the Apache License 2.0.
+// Please see the included NOTICE for copyright information and
+// LICENSE for a copy of the license.
+
+package pgmodel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgerrcode"
+	"github.com/timescale/timescale-prometheus/pkg/log"
+	"github.com/timescale/timescale-prometheus/pkg/prompb"
+)
+
+const (
+	// DefaultInsertBufferReplayInterval is how often the insert buffer is
+	// replayed while the database is unavailable.
+	DefaultInsertBufferReplayInterval = 5 * time.Second
+	// DefaultInsertBufferReplayConcurrency is the default number of
+	// routines replaying the insert buffer.
+	DefaultInsertBufferReplayConcurrency = 4
+
+	insertBufferFileExt = ".buf"
+	// insertBufferTmpExt is appended to the name of a request while it is
+	// written.
+	insertBufferTmpExt = ".tmp"
+	// insertBufferReplayBatch is the number of buffered requests replayed
+	// at once per replay routine.
+	insertBufferReplayBatch = 16
+)
+
+// InsertBuffer is an on-disk buffer of the write requests that could not be
+// inserted because the database was unavailable. Every request is appended
+// as a file of its own, named by its sequence number, so that the buffer
+// survives restarts and is replayed in the order it was written. Once the
+// files exceed the maximum size, the oldest are deleted.
+type InsertBuffer struct {
+	dir      string
+	maxBytes int64
+
+	mtx     sync.Mutex
+	entries []insertBufferEntry // written requests, oldest first
+	// bytes includes the requests still being written
+	bytes   int64
+	nextSeq uint64
+}
+
+type insertBufferEntry struct {
+	seq  uint64
+	size int64
+}
+
+// OpenInsertBuffer opens the insert buffer in dir, creating the directory if
+// it does not exist. Requests buffered by an earlier run are kept for
+// replay, requests it did not finish writing are deleted. The size of the
+// buffer is not limited if maxBytes is 0.
+func OpenInsertBuffer(dir string, maxBytes int64) (*InsertBuffer, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("creating insert buffer: %w", err)
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("opening insert buffer: %w", err)
+	}
+	b := &InsertBuffer{dir: dir, maxBytes: maxBytes}
+	// the names are zero-padded, so they are sorted by sequence number
+	for _, f := range files {
+		name := f.Name()
+		if !f.IsDir() && strings.HasSuffix(name, insertBufferFileExt+insertBufferTmpExt) {
+			// left by a crash before the request was renamed
+			if err = os.Remove(filepath.Join(dir, name)); err != nil {
+				log.Warn("msg", "error deleting partial insert buffer file", "file", name, "err", err)
+			}
+			continue
+		}
+		if f.IsDir() || !strings.HasSuffix(name, insertBufferFileExt) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, insertBufferFileExt), 16, 64)
+		if err != nil {
+			continue
+		}
+		b.entries = append(b.entries, insertBufferEntry{seq: seq, size: f.Size()})
+		b.bytes += f.Size()
+		b.nextSeq = seq + 1
+	}
+	insertBufferBytes.Set(float64(b.bytes))
+	return b, nil
+}
+
+func (b *InsertBuffer) path(seq uint64) string {
+	return filepath.Join(b.dir, fmt.Sprintf("%016x%s", seq, insertBufferFileExt))
+}
+
+// empty returns true if no request is waiting for replay.
+func (b *InsertBuffer) empty() bool {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return len(b.entries) == 0
+}
+
+// append writes the series to the end of the buffer, deleting the oldest
+// requests buffered if the buffer would grow larger than its maximum. The
+// space and the sequence number of the request are reserved under the lock,
+// the file is written and synced outside of it, so that concurrent appends
+// do not wait for each other's disk writes.
+func (b *InsertBuffer) append(tts []prompb.TimeSeries) error {
+	data, err := (&prompb.WriteRequest{Timeseries: tts}).Marshal()
+	if err != nil {
+		return err
+	}
+	size := int64(len(data))
+
+	b.mtx.Lock()
+	if b.maxBytes > 0 && size > b.maxBytes {
+		b.mtx.Unlock()
+		insertBufferDroppedBytes.Add(float64(size))
+		return fmt.Errorf("write request of %d bytes does not fit into the insert buffer of %d bytes", size, b.maxBytes)
+	}
+	for b.maxBytes > 0 && len(b.entries) > 0 && b.bytes+size > b.maxBytes {
+		oldest := b.entries[0]
+		log.Warn("msg", "insert buffer full, dropping the oldest write request", "bytes", oldest.size)
+		insertBufferDroppedBytes.Add(float64(oldest.size))
+		b.removeLocked(0)
+	}
+	seq := b.nextSeq
+	b.nextSeq++
+	b.bytes += size
+	b.mtx.Unlock()
+
+	// written under another name first, so that a crash never leaves a
+	// partial request to replay
+	tmp := b.path(seq) + insertBufferTmpExt
+	if err = writeFileSync(tmp, data); err == nil {
+		err = os.Rename(tmp, b.path(seq))
+	}
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if err != nil {
+		os.Remove(tmp)
+		b.bytes -= size
+		return fmt.Errorf("writing to insert buffer: %w", err)
+	}
+	// appends finishing out of order are kept sorted by sequence number
+	i := sort.Search(len(b.entries), func(i int) bool { return b.entries[i].seq > seq })
+	b.entries = append(b.entries, insertBufferEntry{})
+	copy(b.entries[i+1:], b.entries[i:])
+	b.entries[i] = insertBufferEntry{seq: seq, size: size}
+	insertBufferBytes.Set(float64(b.bytes))
+	return nil
+}
+
+func writeFileSync(name string, data []byte) error {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// bufferedRequest is a request read from the insert buffer. err is set if
+// its file could not be read.
+type bufferedRequest struct {
+	seq uint64
+	tts []prompb.TimeSeries
+	err error
+}
+
+// oldest returns up to n of the oldest requests of the buffer, oldest first.
+// The files are read outside the lock, requests dropped from the buffer in
+// the meantime are left out.
+func (b *InsertBuffer) oldest(n int) []bufferedRequest {
+	b.mtx.Lock()
+	if n > len(b.entries) {
+		n = len(b.entries)
+	}
+	seqs := make([]uint64, n)
+	for i := range seqs {
+		seqs[i] = b.entries[i].seq
+	}
+	b.mtx.Unlock()
+
+	reqs := make([]bufferedRequest, 0, n)
+	for _, seq := range seqs {
+		data, err := ioutil.ReadFile(b.path(seq))
+		if os.IsNotExist(err) {
+			continue
+		}
+		req := bufferedRequest{seq: seq, err: err}
+		if err == nil {
+			var wr prompb.WriteRequest
+			if err = wr.Unmarshal(data); err != nil {
+				req.err = fmt.Errorf("decoding insert buffer file %s: %w", b.path(seq), err)
+			}
+			req.tts = wr.Timeseries
+		}
+		reqs = append(reqs, req)
+	}
+	return reqs
+}
+
+// find returns the index of the entry of the request, or -1 if it is not
+// buffered.
+func (b *InsertBuffer) find(seq uint64) int {
+	i := sort.Search(len(b.entries), func(i int) bool { return b.entries[i].seq >= seq })
+	if i < len(b.entries) && b.entries[i].seq == seq {
+		return i
+	}
+	return -1
+}
+
+// remove deletes the request with the given sequence number once it has
+// been replayed. Requests already dropped from the buffer are ignored.
+func (b *InsertBuffer) remove(seq uint64) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if i := b.find(seq); i >= 0 {
+		b.removeLocked(i)
+	}
+}
+
+// replace keeps only the given series of a request, once the others have
+// been replayed. It runs under the lock, so that a request dropped from a
+// full buffer is not written again; it is only used by replays interrupted
+// by the database becoming unavailable.
+func (b *InsertBuffer) replace(seq uint64, tts []prompb.TimeSeries) error {
+	data, err := (&prompb.WriteRequest{Timeseries: tts}).Marshal()
+	if err != nil {
+		return err
+	}
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	i := b.find(seq)
+	if i < 0 {
+		return nil
+	}
+	tmp := b.path(seq) + insertBufferTmpExt
+	if err = writeFileSync(tmp, data); err == nil {
+		err = os.Rename(tmp, b.path(seq))
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("writing to insert buffer: %w", err)
+	}
+	b.bytes += int64(len(data)) - b.entries[i].size
+	b.entries[i].size = int64(len(data))
+	insertBufferBytes.Set(float64(b.bytes))
+	return nil
+}
+
+func (b *InsertBuffer) removeLocked(i int) {
+	if err := os.Remove(b.path(b.entries[i].seq)); err != nil && !os.IsNotExist(err) {
+		log.Error("msg", "error deleting insert buffer file", "err", err)
+	}
+	b.bytes -= b.entries[i].size
+	b.entries = append(b.entries[:i], b.entries[i+1:]...)
+	insertBufferBytes.Set(float64(b.bytes))
+}
+
+// isUnavailableError returns true for errors of inserts that failed because
+// the database could not be reached, as opposed to errors of the data.
+func isUnavailableError(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case pgerrcode.ConnectionException,
+			pgerrcode.ConnectionDoesNotExist,
+			pgerrcode.ConnectionFailure,
+			pgerrcode.SQLClientUnableToEstablishSQLConnection,
+			pgerrcode.SQLServerRejectedEstablishmentOfSQLConnection,
+			pgerrcode.AdminShutdown,
+			pgerrcode.CrashShutdown,
+			pgerrcode.CannotConnectNow:
+			return true
+		}
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// samplesToTimeSeries returns the series of parsed samples, to be buffered.
+func samplesToTimeSeries(data map[string][]samplesInfo) []prompb.TimeSeries {
+	tts := make([]prompb.TimeSeries, 0, len(data))
+	for _, sis := range data {
+		for _, si := range sis {
+			ts := prompb.TimeSeries{
+				Labels:  make([]prompb.Label, len(si.labels.names)),
+				Samples: si.samples,
+			}
+			for i := range si.labels.names {
+				ts.Labels[i] = prompb.Label{Name: si.labels.names[i], Value: si.labels.values[i]}
+			}
+			tts = append(tts, ts)
+		}
+	}
+	return tts
+}
+
+// bufferData appends the parsed samples to the insert buffer, to be
+// inserted by the replay once the database is available.
+func (i *DBIngestor) bufferData(data map[string][]samplesInfo, rows int) (uint64, error) {
+	if err := i.buffer.append(samplesToTimeSeries(data)); err != nil {
+		return 0, err
+	}
+	return uint64(rows), nil
+}
+
+// replayBuffer inserts the requests of the insert buffer until the ingestor
+// is closed. While the database is unavailable, it tries again every
+// interval.
+func (i *DBIngestor) replayBuffer(interval time.Duration) {
+	defer close(i.replayDone)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-i.stopReplay:
+			return
+		case <-ticker.C:
+		}
+		for i.replayOldest() {
+			select {
+			case <-i.stopReplay:
+				return
+			default:
+			}
+		}
+	}
+}
+
+// stopReplaying stops the replay of the insert buffer and waits for it to
+// return. It may be called any number of times.
+func (i *DBIngestor) stopReplaying() {
+	if i.stopReplay == nil {
+		return
+	}
+	i.stopOnce.Do(func() {
+		close(i.stopReplay)
+		<-i.replayDone
+	})
+}
+
+// replayOldest inserts the oldest requests of the insert buffer, up to
+// insertBufferReplayBatch per replay routine. The series of the requests are
+// spread over the routines by their labels, and every routine inserts its
+// share of the requests oldest first: the samples of a series are inserted
+// in the order they were buffered, while different series are inserted in
+// parallel. A routine stops at the first insert failing because the
+// database is unavailable, and the series not inserted yet are kept in the
+// buffer for the next replay. Series failing for any other reason are
+// dropped, since inserting them again would fail the same way.
+//
+// It returns false if the buffer is empty or the database is still
+// unavailable. While it is, new requests are buffered right away; once a
+// replay reaches it, they are inserted directly again.
+func (i *DBIngestor) replayOldest() bool {
+	routines := i.replayConcurrency
+	if routines < 1 {
+		routines = 1
+	}
+	reqs := i.buffer.oldest(routines * insertBufferReplayBatch)
+	if len(reqs) == 0 {
+		atomic.StoreInt32(&i.unavailable, 0)
+		return false
+	}
+
+	// shares[r][w] are the series of request r inserted by routine w, done
+	// once they are inserted or dropped
+	shares := make([][][]prompb.TimeSeries, len(reqs))
+	done := make([][]bool, len(reqs))
+	for r, req := range reqs {
+		shares[r] = make([][]prompb.TimeSeries, routines)
+		done[r] = make([]bool, routines)
+		if req.err != nil {
+			log.Error("msg", "dropping buffered write request", "err", req.err)
+			continue
+		}
+		for _, ts := range req.tts {
+			w := seriesShard(ts.Labels, routines)
+			shares[r][w] = append(shares[r][w], ts)
+		}
+	}
+
+	var (
+		wg          sync.WaitGroup
+		unavailable int32
+	)
+	for w := 0; w < routines; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for r := range reqs {
+				if len(shares[r][w]) > 0 {
+					err := i.replaySeries(shares[r][w])
+					if isUnavailableError(err) {
+						atomic.StoreInt32(&unavailable, 1)
+						return
+					}
+					if err != nil {
+						log.Error("msg", "dropping buffered series", "count", len(shares[r][w]), "err", err)
+					}
+				}
+				done[r][w] = true
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	for r, req := range reqs {
+		var left []prompb.TimeSeries
+		for w := range shares[r] {
+			if !done[r][w] {
+				left = append(left, shares[r][w]...)
+			}
+		}
+		switch {
+		case len(left) == 0:
+			i.buffer.remove(req.seq)
+		case len(left) < len(req.tts):
+			if err := i.buffer.replace(req.seq, left); err != nil {
+				log.Error("msg", "error keeping the buffered series not replayed, they will be replayed again", "err", err)
+			}
+		}
+	}
+	atomic.StoreInt32(&i.unavailable, unavailable)
+	return unavailable == 0
+}
+
+// replaySeries inserts buffered series. tts is left untouched, so that the
+// series can be kept in the buffer if the database is unavailable.
+func (i *DBIngestor) replaySeries(tts []prompb.TimeSeries) error {
+	// parseData takes the samples out of the series it is given
+	tts = append([]prompb.TimeSeries(nil), tts...)
+	// the series already passed the stages when they were buffered
+	data, _, _, err := i.parseData(tts, NewWriteRequest())
+	if err != nil {
+		return err
+	}
+	_, _, err = i.db.InsertNewData(context.Background(), data)
+	return err
+}
+
+// seriesShard returns the replay routine of a series, out of n.
+func seriesShard(lls []prompb.Label, n int) int {
+	h := fnv.New32a()
+	for _, l := range lls {
+		h.Write([]byte(l.Name))
+		h.Write([]byte{0})
+		h.Write([]byte(l.Value))
+		h.Write([]byte{0})
+	}
+	return int(h.Sum32() % uint32(n))
+}
@@ -0,0 +1,101 @@
+// This file and its contents are licensed under the Apache License 2.0.
+// Please see the included NOTICE for copyright information and
+// LICENSE for a copy of the license.
+
+package pgmodel
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DuplicatePolicy decides what happens to samples of the same series sharing
+// a timestamp within a single InsertData call.
+type DuplicatePolicy int
+
+const (
+	// KeepDuplicates sends all samples to the database, leaving duplicates
+	// to the ON CONFLICT clause of the insert.
+	KeepDuplicates DuplicatePolicy = iota
+	// KeepLastDuplicate keeps the last sample received for a timestamp.
+	KeepLastDuplicate
+	// KeepFirstDuplicate keeps the first sample received for a timestamp.
+	KeepFirstDuplicate
+	// RejectDuplicates fails the whole insert if it contains duplicates.
+	RejectDuplicates
+)
+
+// ErrDuplicateSample is returned by inserts rejected by RejectDuplicates.
+var ErrDuplicateSample = fmt.Errorf("duplicate sample")
+
+var duplicatePolicies = map[string]DuplicatePolicy{
+	"keep-all":   KeepDuplicates,
+	"keep-last":  KeepLastDuplicate,
+	"keep-first": KeepFirstDuplicate,
+	"error":      RejectDuplicates,
+}
+
+// ParseDuplicatePolicy returns the policy with the given name, one of
+// keep-all, keep-last, keep-first or error.
+func ParseDuplicatePolicy(name string) (DuplicatePolicy, error) {
+	policy, ok := duplicatePolicies[name]
+	if !ok {
+		return KeepDuplicates, fmt.Errorf("invalid duplicate policy %q", name)
+	}
+	return policy, nil
+}
+
+// dedupSamples merges the samplesInfos of each series of a metric and
+// collapses samples sharing a timestamp according to the policy. It returns
+// the number of samples dropped.
+func dedupSamples(data []samplesInfo, policy DuplicatePolicy) ([]samplesInfo, int, error) {
+	if policy == KeepDuplicates {
+		return data, 0, nil
+	}
+
+	deduped := data[:0]
+	seriesIdx := make(map[string]int, len(data))
+	for _, si := range data {
+		key := si.labels.String()
+		if i, ok := seriesIdx[key]; ok {
+			deduped[i].samples = append(deduped[i].samples, si.samples...)
+			continue
+		}
+		seriesIdx[key] = len(deduped)
+		deduped = append(deduped, si)
+	}
+	// nil the merged entries to prevent memory leaks
+	for i := len(deduped); i < len(data); i++ {
+		data[i] = samplesInfo{}
+	}
+
+	dropped := 0
+	for i := range deduped {
+		samples := deduped[i].samples
+		if len(samples) < 2 {
+			continue
+		}
+		// the sort is stable so samples of a timestamp stay in arrival order
+		sort.SliceStable(samples, func(a, b int) bool {
+			return samples[a].Timestamp < samples[b].Timestamp
+		})
+
+		kept := samples[:1]
+		for _, s := range samples[1:] {
+			last := &kept[len(kept)-1]
+			if s.Timestamp != last.Timestamp {
+				kept = append(kept, s)
+				continue
+			}
+			switch policy {
+			case RejectDuplicates:
+				return nil, 0, fmt.Errorf("%w for metric %s at timestamp %d", ErrDuplicateSample, deduped[i].labels.metricName, s.Timestamp)
+			case KeepLastDuplicate:
+				*last = s
+			}
+			dropped++
+		}
+		deduped[i].samples = kept
+	}
+	return deduped, dropped, nil
+}
@@ -0,0 +1,64 @@
+// This file and its contents are licensed under the Apache License 2.0.
+// Please see the included NOTICE for copyright information and
+// LICENSE for a copy of the license.
+
+package pgmodel
+
+import "sort"
+
+// checkOutOfOrder counts the samples of a metric with a timestamp earlier than
+// one already seen for their series within the same insert, in the order
+// they were received. If reject is set the out-of-order samples are removed.
+func checkOutOfOrder(data []samplesInfo, reject bool) int {
+	outOfOrder := 0
+	maxSeen := make(map[string]int64, len(data))
+	for i := range data {
+		key := data[i].labels.String()
+		max, seen := maxSeen[key]
+		kept := data[i].samples[:0]
+		for _, s := range data[i].samples {
+			if seen && s.Timestamp < max {
+				outOfOrder++
+				if reject {
+					continue
+				}
+			} else {
+				max = s.Timestamp
+				seen = true
+			}
+			kept = append(kept, s)
+		}
+		data[i].samples = kept
+		if seen {
+			maxSeen[key] = max
+		}
+	}
+	return outOfOrder
+}
+
+// bySeriesAndTime orders sample rows by series id, then time.
+type bySeriesAndTime sampleRows
+
+func (r bySeriesAndTime) Len() int {
+	return len(r.times)
+}
+
+func (r bySeriesAndTime) Less(i, j int) bool {
+	if r.series[i] != r.series[j] {
+		return r.series[i] < r.series[j]
+	}
+	return r.times[i].Before(r.times[j])
+}
+
+func (r bySeriesAndTime) Swap(i, j int) {
+	r.times[i], r.times[j] = r.times[j], r.times[i]
+	r.vals[i], r.vals[j] = r.vals[j], r.vals[i]
+	r.series[i], r.series[j] = r.series[j], r.series[i]
+}
+
+// sortBySeriesAndTime sorts the rows in place by series id, then time. The
+// sort is stable, so samples of a series at the same time keep their order,
+// which the duplicate policy relies on.
+func sortBySeriesAndTime(rows sampleRows) {
+	sort.Stable(bySeriesAndTime(rows))
+}
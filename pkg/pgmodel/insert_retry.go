@@ -0,0 +1,89 @@
+// This file and its contents are licensed under the Apache License 2.0.
+// Please see the included NOTICE for copyright information and
+// LICENSE for a copy of the license.
+
+package pgmodel
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgerrcode"
+)
+
+// RetryPolicy describes how sample inserts failing with transient errors are
+// retried. Inserts are only retried for errors with one of the retryable
+// SQLSTATE codes; any other error fails the insert immediately.
+type RetryPolicy struct {
+	// MaxRetries is the number of times an insert is retried before its
+	// error is returned.
+	MaxRetries int
+	// InitialBackoff is the delay before the first retry. The delay doubles
+	// with every further retry, up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// RetryableCodes is the set of SQLSTATE codes considered transient.
+	RetryableCodes map[string]bool
+}
+
+// DefaultRetryPolicy returns a policy retrying serialization failures,
+// deadlocks and connection errors up to maxRetries times.
+func DefaultRetryPolicy(maxRetries int) *RetryPolicy {
+	return &RetryPolicy{
+		MaxRetries:     maxRetries,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		RetryableCodes: map[string]bool{
+			pgerrcode.SerializationFailure:   true,
+			pgerrcode.DeadlockDetected:       true,
+			pgerrcode.ConnectionException:    true,
+			pgerrcode.ConnectionDoesNotExist: true,
+			pgerrcode.ConnectionFailure:      true,
+			pgerrcode.AdminShutdown:          true,
+			pgerrcode.CannotConnectNow:       true,
+		},
+	}
+}
+
+// shouldRetry returns true if an insert that failed with err on the given
+// (zero-based) attempt should be retried.
+func (p *RetryPolicy) shouldRetry(err error, attempt int) bool {
+	if p == nil || attempt >= p.MaxRetries {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return p.RetryableCodes[pgErr.Code]
+}
+
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.InitialBackoff
+	for i := 0; i < attempt && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d
+}
+
+// insertWithRetry inserts the rows of a single insert statement, retrying
+// transient failures according to the policy. It returns the number of rows
+// inserted by all attempts. A failed statement leaves no rows behind, so
+// retrying does not duplicate samples. Callers splitting a batch into several
+// statements retry each of them on its own, as the statements that succeeded
+// are already committed.
+func insertWithRetry(conn pgxConn, table string, queryString string, rows sampleRows, policy *RetryPolicy, nulls nullValues, format WireFormat) (int64, error) {
+	inserted, err := insertRows(conn, table, queryString, rows, nulls, format)
+	for attempt := 0; err != nil && policy.shouldRetry(err, attempt); attempt++ {
+		insertRetries.Inc()
+		time.Sleep(policy.backoff(attempt))
+		var n int64
+		n, err = insertRows(conn, table, queryString, rows, nulls, format)
+		inserted += n
+	}
+	return inserted, err
+}
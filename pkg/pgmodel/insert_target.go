@@ -0,0 +1,71 @@
+// This file and its contents are licensed under the Apache License 2.0.
+// Please see the included NOTICE for copyright information and
+// LICENSE for a copy of the license.
+
+package pgmodel
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// InsertTarget is where samples are inserted: the schema of the data tables
+// and the names of their time, value and series id columns, e.g. for schemas
+// whose data tables have columns of their own, filled by defaults. Empty
+// fields default to the standard schema, so the zero value inserts into the
+// standard data tables.
+type InsertTarget struct {
+	Schema         string
+	TimeColumn     string
+	ValueColumn    string
+	SeriesIDColumn string
+}
+
+// columnNameRegexp matches the column names that need no quoting.
+var columnNameRegexp = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// insertStatement builds the statements inserting samples into the data table
+// of a metric.
+type insertStatement struct {
+	schema string
+	// columns are the time, value and series id columns, comma-separated
+	columns    string
+	onConflict string
+}
+
+// newInsertStatement returns the insert statement of the target. The conflict
+// target names the columns by their standard names.
+func newInsertStatement(target InsertTarget, conflictTarget []string) (insertStatement, error) {
+	columns := map[string]string{
+		"time":      target.TimeColumn,
+		"value":     target.ValueColumn,
+		"series_id": target.SeriesIDColumn,
+	}
+	for std, name := range columns {
+		if name == "" {
+			columns[std] = std
+		} else if !columnNameRegexp.MatchString(name) {
+			return insertStatement{}, fmt.Errorf("invalid %s column name %q", std, name)
+		}
+	}
+	onConflict, err := buildOnConflictClause(conflictTarget, columns)
+	if err != nil {
+		return insertStatement{}, err
+	}
+	s := insertStatement{
+		schema:     target.Schema,
+		columns:    fmt.Sprintf("%s, %s, %s", columns["time"], columns["value"], columns["series_id"]),
+		onConflict: onConflict,
+	}
+	if s.schema == "" {
+		s.schema = dataSchema
+	}
+	return s, nil
+}
+
+// sql returns the statement inserting the samples into the data table.
+func (s insertStatement) sql(table string) string {
+	return fmt.Sprintf("INSERT INTO %s(%s) SELECT * FROM unnest($1::TIMESTAMPTZ[], $2::DOUBLE PRECISION[], $3::BIGINT[]) a %s", pgx.Identifier{s.schema, table}.Sanitize(), s.columns, s.onConflict)
+}
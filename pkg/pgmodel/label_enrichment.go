@@ -0,0 +1,155 @@
+// This file and its contents are licensed under the Apache License 2.0.
+// Please see the included NOTICE for copyright information and
+// LICENSE for a copy of the license.
+
+package pgmodel
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/timescale/timescale-prometheus/pkg/log"
+	"github.com/timescale/timescale-prometheus/pkg/prompb"
+)
+
+// LabelEnrichment configures adding labels from an external table to the
+// series returned by queries. For a series with KeyLabel set, the row of Table
+// whose KeyColumn equals the label's value is looked up, and each of
+// LabelColumns is added as a label of the same name. Labels the series
+// already has are never overwritten.
+type LabelEnrichment struct {
+	// Table is the name of the table, optionally schema qualified.
+	Table        string
+	KeyColumn    string
+	KeyLabel     string
+	LabelColumns []string
+}
+
+// sql returns the query fetching the enrichment labels for a key. It fetches
+// up to two rows so that ambiguous keys can be detected.
+func (e *LabelEnrichment) sql() string {
+	cols := make([]string, len(e.LabelColumns))
+	for i, c := range e.LabelColumns {
+		cols[i] = fmt.Sprintf("coalesce(%s::text, '')", pgx.Identifier{c}.Sanitize())
+	}
+	return fmt.Sprintf("SELECT ARRAY[%s] FROM %s WHERE %s::text = $1 LIMIT 2",
+		strings.Join(cols, ", "),
+		pgx.Identifier(strings.Split(e.Table, ".")).Sanitize(),
+		pgx.Identifier{e.KeyColumn}.Sanitize())
+}
+
+// labelEnricher looks up enrichment labels for the series of a single query,
+// caching the labels of each key value.
+type labelEnricher struct {
+	conn  pgxConn
+	cfg   *LabelEnrichment
+	sql   string
+	cache map[string][]labels.Label
+}
+
+// newLabelEnricher returns an enricher for a query, or nil if enrichment is
+// not configured.
+func (q *pgxQuerier) newLabelEnricher() *labelEnricher {
+	if q.labelEnrichment == nil {
+		return nil
+	}
+	return &labelEnricher{
+		conn:  q.conn,
+		cfg:   q.labelEnrichment,
+		sql:   q.labelEnrichment.sql(),
+		cache: make(map[string][]labels.Label),
+	}
+}
+
+// extraLabels returns the labels to add for the given key value. Keys
+// matching no row or more than one row get no extra labels.
+func (e *labelEnricher) extraLabels(key string) ([]labels.Label, error) {
+	if extra, ok := e.cache[key]; ok {
+		return extra, nil
+	}
+
+	rows, err := e.conn.Query(context.Background(), e.sql, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		values  []string
+		numRows int
+	)
+	for rows.Next() {
+		numRows++
+		if err := rows.Scan(&values); err != nil {
+			return nil, err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var extra []labels.Label
+	switch {
+	case numRows > 1:
+		log.Warn("msg", "multiple rows match label enrichment key, not enriching", "table", e.cfg.Table, "key", key)
+	case numRows == 1:
+		for i, name := range e.cfg.LabelColumns {
+			if i < len(values) && values[i] != "" {
+				extra = append(extra, labels.Label{Name: name, Value: values[i]})
+			}
+		}
+	}
+	e.cache[key] = extra
+	return extra, nil
+}
+
+// enrich adds the enrichment labels to a sorted label set. It is a no-op on a
+// nil enricher.
+func (e *labelEnricher) enrich(lls labels.Labels) (labels.Labels, error) {
+	if e == nil {
+		return lls, nil
+	}
+	key := lls.Get(e.cfg.KeyLabel)
+	if key == "" {
+		return lls, nil
+	}
+	extra, err := e.extraLabels(key)
+	if err != nil || len(extra) == 0 {
+		return lls, err
+	}
+
+	for _, l := range extra {
+		if lls.Get(l.Name) == "" {
+			lls = append(lls, l)
+		}
+	}
+	sort.Sort(lls)
+	return lls, nil
+}
+
+// enrichPrompb adds the enrichment labels to a prompb label set. It is a
+// no-op on a nil enricher.
+func (e *labelEnricher) enrichPrompb(pls []prompb.Label) ([]prompb.Label, error) {
+	if e == nil {
+		return pls, nil
+	}
+	lls := make(labels.Labels, 0, len(pls))
+	for _, l := range pls {
+		lls = append(lls, labels.Label{Name: l.Name, Value: l.Value})
+	}
+	sort.Sort(lls)
+	enriched, err := e.enrich(lls)
+	if err != nil || len(enriched) == len(pls) {
+		return pls, err
+	}
+
+	pls = pls[:0]
+	for _, l := range enriched {
+		pls = append(pls, prompb.Label{Name: l.Name, Value: l.Value})
+	}
+	return pls, nil
+}
@@ -0,0 +1,35 @@
+// This file and its contents are licensed under the Apache License 2.0.
+// Please see the included NOTICE for copyright information and
+// LICENSE for a copy of the license.
+
+package pgmodel
+
+import "github.com/timescale/timescale-prometheus/pkg/clockcache"
+
+// stringInterner returns a single copy of equal strings. The names and values
+// of the labels fetched from the database are interned, so that the labels
+// read share their strings rather than each holding a copy, e.g. the name of
+// every label with the key instance, or a host name that is the value of
+// several keys. It holds at most max strings in a clockcache, like the labels
+// cache, so strings no longer read are evicted for new ones. A nil interner
+// interns nothing.
+type stringInterner struct {
+	strings *clockcache.Cache
+}
+
+func newStringInterner(max int) *stringInterner {
+	return &stringInterner{strings: clockcache.WithMax(uint64(max))}
+}
+
+// intern returns the interned copy of s, which compares equal to s.
+func (i *stringInterner) intern(s string) string {
+	if i == nil {
+		return s
+	}
+	if v, ok := i.strings.Get(s); ok {
+		return v.(string)
+	}
+	// returns s if the cache could not evict a string for it
+	v, _ := i.strings.Insert(s, s)
+	return v.(string)
+}
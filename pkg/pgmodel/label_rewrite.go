@@ -0,0 +1,278 @@
+// This file and its contents are licensed under the Apache License 2.0.
+// Please see the included NOTICE for copyright information and
+// LICENSE for a copy of the license.
+
+package pgmodel
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgtype"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/timescale/timescale-prometheus/pkg/prompb"
+)
+
+// LabelRewrite replaces the values of a label on read, e.g. to collapse the
+// per-pod suffixes of a label. Values matching Regex, which is anchored at
+// both ends like the regexps of matchers, are replaced by Replacement, which
+// can refer to the groups of Regex as $1 or ${name}. Other values are left as
+// they are, and a label whose value is rewritten to "" is dropped.
+//
+// Rewrites only change the series returned: queries still match the stored
+// values.
+type LabelRewrite struct {
+	Label       string
+	Regex       *regexp.Regexp
+	Replacement string
+}
+
+// ParseLabelRewrites parses semicolon-separated rewrites of the form
+// <label>=~<regex>-><replacement>, e.g. "pod=~(.+)-[a-z0-9]{5}->$1". The
+// metric name cannot be rewritten. An empty expression returns no rewrites.
+func ParseLabelRewrites(expr string) ([]LabelRewrite, error) {
+	var rewrites []LabelRewrite
+	for _, part := range strings.Split(expr, ";") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		sep := strings.Index(part, "=~")
+		arrow := strings.LastIndex(part, "->")
+		if sep <= 0 || arrow < sep {
+			return nil, fmt.Errorf("invalid label rewrite %q, expected <label>=~<regex>-><replacement>", part)
+		}
+		name := part[:sep]
+		if name == MetricNameLabelName {
+			return nil, fmt.Errorf("invalid label rewrite %q, the metric name cannot be rewritten", part)
+		}
+		regex, err := regexp.Compile("^(?:" + part[sep+2:arrow] + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid label rewrite regex %q: %w", part[sep+2:arrow], err)
+		}
+		rewrites = append(rewrites, LabelRewrite{Label: name, Regex: regex, Replacement: part[arrow+2:]})
+	}
+	return rewrites, nil
+}
+
+// labelRewrites are applied in order, so a later rewrite of a label sees the
+// value rewritten by the earlier ones.
+type labelRewrites []LabelRewrite
+
+// rewrite returns the rewritten value of the label.
+func (r labelRewrites) rewrite(name, value string) string {
+	for _, rw := range r {
+		if rw.Label == name && rw.Regex.MatchString(value) {
+			value = rw.Regex.ReplaceAllString(value, rw.Replacement)
+		}
+	}
+	return value
+}
+
+// apply rewrites the values of ls in place, returning ls without the labels
+// rewritten to empty values.
+func (r labelRewrites) apply(ls labels.Labels) labels.Labels {
+	if len(r) == 0 {
+		return ls
+	}
+	kept := ls[:0]
+	for _, l := range ls {
+		if l.Value = r.rewrite(l.Name, l.Value); l.Value != "" {
+			kept = append(kept, l)
+		}
+	}
+	return kept
+}
+
+// applyPrompb is apply for the labels of remote reads.
+func (r labelRewrites) applyPrompb(ls []prompb.Label) []prompb.Label {
+	if len(r) == 0 {
+		return ls
+	}
+	kept := ls[:0]
+	for _, l := range ls {
+		if l.Value = r.rewrite(l.Name, l.Value); l.Value != "" {
+			kept = append(kept, l)
+		}
+	}
+	return kept
+}
+
+// mergeSamples sorts the samples by time and keeps the first of the samples
+// sharing a timestamp.
+func mergeSamples(samples []prompb.Sample) []prompb.Sample {
+	sort.SliceStable(samples, func(i, j int) bool {
+		return samples[i].Timestamp < samples[j].Timestamp
+	})
+	merged := samples[:0]
+	for i, s := range samples {
+		if i > 0 && s.Timestamp == samples[i-1].Timestamp {
+			continue
+		}
+		merged = append(merged, s)
+	}
+	return merged
+}
+
+// mergeTimeSeries merges the series of a remote read that have the same
+// labels, keeping the order in which the series were first read.
+func mergeTimeSeries(series []*prompb.TimeSeries) []*prompb.TimeSeries {
+	merged := series[:0]
+	byLabels := make(map[string]*prompb.TimeSeries, len(series))
+	for _, ts := range series {
+		key := prompbLabelsKey(ts.Labels)
+		if first, ok := byLabels[key]; ok {
+			first.Samples = append(first.Samples, ts.Samples...)
+			continue
+		}
+		byLabels[key] = ts
+		merged = append(merged, ts)
+	}
+	for _, ts := range merged {
+		ts.Samples = mergeSamples(ts.Samples)
+	}
+	return merged
+}
+
+// prompbLabelsKey returns a string identifying the sorted labels.
+func prompbLabelsKey(ls []prompb.Label) string {
+	var b strings.Builder
+	for _, l := range ls {
+		b.WriteString(l.Name)
+		b.WriteByte('\xff')
+		b.WriteString(l.Value)
+		b.WriteByte('\xff')
+	}
+	return b.String()
+}
+
+// mergedSeriesSet merges the series of a set that have the same labels, as
+// after their values were rewritten. Such series can be anywhere in the set,
+// so the whole set is read on the first call of Next.
+type mergedSeriesSet struct {
+	set    storage.SeriesSet
+	series []storage.Series
+	idx    int
+	loaded bool
+	err    error
+}
+
+func newMergedSeriesSet(set storage.SeriesSet) *mergedSeriesSet {
+	return &mergedSeriesSet{set: set, idx: -1}
+}
+
+// Next implements storage.SeriesSet.
+func (m *mergedSeriesSet) Next() bool {
+	if !m.loaded {
+		m.load()
+	}
+	if m.err != nil || m.idx+1 >= len(m.series) {
+		return false
+	}
+	m.idx++
+	return true
+}
+
+// At implements storage.SeriesSet.
+func (m *mergedSeriesSet) At() storage.Series {
+	if m.idx < 0 || m.idx >= len(m.series) {
+		return nil
+	}
+	return m.series[m.idx]
+}
+
+// Err implements storage.SeriesSet.
+func (m *mergedSeriesSet) Err() error {
+	return m.err
+}
+
+func (m *mergedSeriesSet) load() {
+	m.loaded = true
+
+	var groups [][]storage.Series
+	byLabels := make(map[string]int)
+	for m.set.Next() {
+		s := m.set.At()
+		if s == nil {
+			// invalid series are reported by Err
+			break
+		}
+		key := s.Labels().String()
+		i, ok := byLabels[key]
+		if !ok {
+			i = len(groups)
+			byLabels[key] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], s)
+	}
+	if m.err = m.set.Err(); m.err != nil {
+		return
+	}
+
+	m.series = make([]storage.Series, 0, len(groups))
+	for _, group := range groups {
+		if len(group) == 1 {
+			m.series = append(m.series, group[0])
+			continue
+		}
+		s, err := mergeSeries(group)
+		if err != nil {
+			m.err = fmt.Errorf("Error retrieving series set: %w", err)
+			return
+		}
+		m.series = append(m.series, s)
+	}
+}
+
+// mergeSeries returns a series with the samples returned by the iterators of
+// the series, which share their labels. Of the samples sharing a timestamp,
+// the one of the series read first is kept.
+func mergeSeries(group []storage.Series) (*pgxSeries, error) {
+	var samples []prompb.Sample
+	for _, s := range group {
+		it := s.Iterator()
+		for it.Next() {
+			t, v := it.At()
+			samples = append(samples, prompb.Sample{Timestamp: t, Value: v})
+		}
+		if err := it.Err(); err != nil {
+			return nil, err
+		}
+	}
+	samples = mergeSamples(samples)
+
+	// the samples are final, so the merged series neither rounds nor
+	// fills them again
+	merged := &pgxSeries{
+		labels: group[0].Labels(),
+		times: pgtype.TimestamptzArray{
+			Elements: make([]pgtype.Timestamptz, len(samples)),
+			Status:   pgtype.Present,
+		},
+		values: pgtype.Float8Array{
+			Elements: make([]pgtype.Float8, len(samples)),
+			Status:   pgtype.Present,
+		},
+	}
+	for i, s := range samples {
+		merged.times.Elements[i] = milisToTimestamptz(s.Timestamp)
+		merged.values.Elements[i] = pgtype.Float8{Float: s.Value, Status: pgtype.Present}
+	}
+	return merged, nil
+}
+
+// milisToTimestamptz is the inverse of pgxSeriesIterator.tsAt.
+func milisToTimestamptz(ts int64) pgtype.Timestamptz {
+	switch ts {
+	case math.MinInt64:
+		return pgtype.Timestamptz{Status: pgtype.Present, InfinityModifier: pgtype.NegativeInfinity}
+	case math.MaxInt64:
+		return pgtype.Timestamptz{Status: pgtype.Present, InfinityModifier: pgtype.Infinity}
+	}
+	return pgtype.Timestamptz{Time: time.Unix(0, ts*int64(time.Millisecond)), Status: pgtype.Present}
+}
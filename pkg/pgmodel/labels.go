@@ -150,6 +150,29 @@ func (l *Labels) Equal(b *Labels) bool {
 	return l.str == b.str
 }
 
+// Fingerprint returns the fingerprint Prometheus identifies the series with,
+// see FingerprintFor.
+func (l *Labels) Fingerprint() uint64 {
+	ls := make(labels.Labels, len(l.names))
+	for i := range l.names {
+		ls[i] = labels.Label{Name: l.names[i], Value: l.values[i]}
+	}
+	return FingerprintFor(ls)
+}
+
+// FingerprintFor returns the fingerprint Prometheus identifies the series with
+// the labels by, as computed by labels.Labels.Hash: the 64-bit xxHash, seed 0,
+// of the names and values of the labels sorted by name, each followed by a
+// 0xff byte. Unsorted labels are sorted first, so that every label set has a
+// single fingerprint.
+func FingerprintFor(ls labels.Labels) uint64 {
+	if !sort.IsSorted(ls) {
+		ls = append(labels.Labels(nil), ls...)
+		sort.Sort(ls)
+	}
+	return ls.Hash()
+}
+
 // Labels implements sort.Interface
 
 func (l *Labels) Len() int {
@@ -13,6 +13,13 @@ var (
 			Help:      "Total number of processed samples which where duplicates",
 		},
 	)
+	outOfOrderSamples = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: util.PromNamespace,
+			Name:      "out_of_order_samples_total",
+			Help:      "Total number of processed samples older than a sample of the same series earlier in the same write",
+		},
+	)
 	duplicateWrites = prometheus.NewCounter(
 		prometheus.CounterOpts{
 			Namespace: util.PromNamespace,
@@ -27,6 +34,141 @@ var (
 			Help:      "Total number of calls to decompress_chunks_after",
 		},
 	)
+	insertRetries = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: util.PromNamespace,
+			Name:      "insert_retries_total",
+			Help:      "Total number of sample inserts retried after a transient error",
+		},
+	)
+	emptyInserts = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: util.PromNamespace,
+			Name:      "empty_inserts_total",
+			Help:      "Total number of non-empty sample batches of which no row was inserted",
+		},
+	)
+	disabledMetricSamples = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: util.PromNamespace,
+			Name:      "disabled_metric_samples_total",
+			Help:      "Total number of samples dropped because writes of their metric are disabled",
+		},
+	)
+	outOfRangeSamples = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: util.PromNamespace,
+			Name:      "out_of_range_samples_total",
+			Help:      "Total number of samples received with a timestamp PostgreSQL can not store",
+		},
+	)
+	oversizedSeries = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: util.PromNamespace,
+			Name:      "oversized_series_total",
+			Help:      "Total number of series rejected because their labels exceed the maximum size",
+		},
+	)
+	invalidSeries = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: util.PromNamespace,
+			Name:      "invalid_series_total",
+			Help:      "Total number of series rejected because of a label that can not be stored",
+		},
+	)
+	insertBufferBytes = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: util.PromNamespace,
+			Name:      "insert_buffer_bytes",
+			Help:      "Size of the write requests buffered on disk while the database is unavailable",
+		},
+	)
+	insertBufferDroppedBytes = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: util.PromNamespace,
+			Name:      "insert_buffer_dropped_bytes_total",
+			Help:      "Total size of the buffered write requests dropped because the insert buffer was full",
+		},
+	)
+	readRetries = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: util.PromNamespace,
+			Name:      "read_retries_total",
+			Help:      "Total number of reads retried after a concurrent schema change",
+		},
+	)
+	labelsCacheHits = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: util.PromNamespace,
+			Name:      "labels_cache_hits_total",
+			Help:      "Total number of label ids of query results found in the labels cache",
+		},
+	)
+	labelsCacheMisses = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: util.PromNamespace,
+			Name:      "labels_cache_misses_total",
+			Help:      "Total number of label ids of query results fetched from the database",
+		},
+	)
+	seriesCacheHits = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: util.PromNamespace,
+			Name:      "series_cache_hits_total",
+			Help:      "Total number of series written whose id was found in the series cache",
+		},
+	)
+	seriesCacheMisses = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: util.PromNamespace,
+			Name:      "series_cache_misses_total",
+			Help:      "Total number of series written whose id was looked up in the database",
+		},
+	)
+	seriesCacheElements = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: util.PromNamespace,
+			Name:      "series_cache_elements",
+			Help:      "Number of series ids in the series cache of the insert path",
+		},
+	)
+	readSeries = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: util.PromNamespace,
+			Name:      "read_series_total",
+			Help:      "Total number of series returned by queries",
+		},
+	)
+	labelLookups = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: util.PromNamespace,
+			Name:      "label_lookups_total",
+			Help:      "Total number of times the labels of query results were resolved from their ids",
+		},
+	)
+	labelQueries = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: util.PromNamespace,
+			Name:      "label_queries_total",
+			Help:      "Total number of database queries fetching labels missing from the labels cache",
+		},
+	)
+	readDuration = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: util.PromNamespace,
+			Name:      "read_duration_seconds",
+			Help:      "Duration of remote read queries",
+			Buckets:   prometheus.DefBuckets,
+		},
+	)
+	readRowsPerQuery = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: util.PromNamespace,
+			Name:      "read_rows_per_query",
+			Help:      "Number of rows scanned from a single data query",
+			Buckets:   prometheus.ExponentialBuckets(1, 4, 10),
+		},
+	)
 	decompressEarliest = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: util.PromNamespace,
@@ -38,6 +180,34 @@ var (
 func init() {
 	prometheus.MustRegister(duplicateSamples)
 	prometheus.MustRegister(duplicateWrites)
+	prometheus.MustRegister(outOfOrderSamples)
 	prometheus.MustRegister(decompressCalls)
 	prometheus.MustRegister(decompressEarliest)
+	prometheus.MustRegister(insertRetries)
+	prometheus.MustRegister(emptyInserts)
+	prometheus.MustRegister(disabledMetricSamples)
+	prometheus.MustRegister(oversizedSeries)
+	prometheus.MustRegister(invalidSeries)
+	prometheus.MustRegister(insertBufferBytes)
+	prometheus.MustRegister(insertBufferDroppedBytes)
+	prometheus.MustRegister(outOfRangeSamples)
+	prometheus.MustRegister(readRetries)
+	prometheus.MustRegister(labelsCacheHits)
+	prometheus.MustRegister(labelsCacheMisses)
+	prometheus.MustRegister(seriesCacheHits)
+	prometheus.MustRegister(seriesCacheMisses)
+	prometheus.MustRegister(seriesCacheElements)
+	prometheus.MustRegister(QueryMetrics()...)
+}
+
+// QueryMetrics returns the collectors of the query path metrics, e.g. to
+// export them from another registry.
+func QueryMetrics() []prometheus.Collector {
+	return []prometheus.Collector{
+		readSeries,
+		labelLookups,
+		labelQueries,
+		readDuration,
+		readRowsPerQuery,
+	}
 }
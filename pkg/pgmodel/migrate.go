@@ -33,6 +33,7 @@ const (
 	getVersion                  = "SELECT version FROM prom_schema_migrations LIMIT 1"
 	setVersion                  = "INSERT INTO prom_schema_migrations (version) VALUES ($1)"
 	truncateMigrationsTable     = "TRUNCATE prom_schema_migrations"
+	lockMigrationSQL            = "SELECT pg_advisory_xact_lock($1)"
 
 	preinstallScripts = "preinstall"
 	versionScripts    = "versions/dev"
@@ -50,6 +51,10 @@ var (
 	}
 	migrateMutex = &sync.Mutex{}
 
+	// migrationLockID is the advisory lock held by migrations, reads can wait
+	// for it to be released.
+	migrationLockID int64 = 0x70726f6d5f6d6967
+
 	//Format of migration files. e.g. 6-foo.sql
 	migrationFileNameRegexp = regexp.MustCompile(`([[:digit:]]+)-[[:word:]]+.sql`)
 )
@@ -159,6 +164,9 @@ func (t *Migrator) Migrate(appVersion semver.Version) error {
 			defer func() {
 				_ = tx.Rollback(context.Background())
 			}()
+			if err = lockMigration(tx); err != nil {
+				return err
+			}
 			if err = t.execMigrationDir(tx, idempotentScripts); err != nil {
 				return err
 			}
@@ -183,6 +191,10 @@ func (t *Migrator) Migrate(appVersion semver.Version) error {
 		_ = tx.Rollback(context.Background())
 	}()
 
+	if err = lockMigration(tx); err != nil {
+		return err
+	}
+
 	_, err = tx.Exec(context.Background(), timescaleInstall)
 	if err != nil {
 		return fmt.Errorf("timescaledb failed to install due to %w", err)
@@ -210,6 +222,14 @@ func (t *Migrator) Migrate(appVersion semver.Version) error {
 	return nil
 }
 
+// lockMigration takes the migration lock until the end of the transaction.
+func lockMigration(tx pgx.Tx) error {
+	if _, err := tx.Exec(context.Background(), lockMigrationSQL, migrationLockID); err != nil {
+		return fmt.Errorf("unable to take the migration lock: %w", err)
+	}
+	return nil
+}
+
 func ensureVersionTable(db *pgxpool.Pool) error {
 	_, err := db.Exec(context.Background(), createMigrationsTable)
 	if err != nil {
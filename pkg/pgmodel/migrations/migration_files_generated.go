@@ -28,9 +28,9 @@ var MigrationFiles = func() http.FileSystem {
 		"/idempotent/base.sql": &vfsgen۰CompressedFileInfo{
 			name:             "base.sql",
 			modTime:          time.Time{},
-			uncompressedSize: 46310,
+			uncompressedSize: 46301,
 
-			compressedContent: []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xec\x7d\x6b\x77\x22\x47\x92\xe8\x67\xf3\x2b\xe2\xee\x55\xaf\x28\x0f\xe0\x96\x7d\x67\x66\xaf\x7a\xd4\xe7\x60\xa9\xd4\x66\x47\x0d\x3d\x80\xfc\xb8\xbe\x3e\x6c\x52\x95\x40\x5a\x45\x15\xae\x2c\xa4\x66\xce\xfe\xf8\x3d\x19\xf9\xae\x07\x0f\x75\xcb\x3b\x7b\x76\xf5\x61\xc6\x5d\xe4\x33\x32\xde\x19\x11\xd9\xed\x0e\x47\xd3\x70\xd2\xea\x76\xa7\x2b\xc6\x21\xca\x62\x0a\x84\xf3\xed\x9a\x72\x28\x56\xa4\x80\x82\xcc\x13\x0a\x29\x11\x1f\x22\x92\x42\x96\x26\x3b\x98\x53\xf8\xd3\x37\x10\xad\x48\xce\x21\xc9\xd2\x65\xab\xd5\xba\x1e\x87\xfd\x69\x08\xa3\x31\x8c\xc3\x0f\x77\xfd\xeb\x10\x6e\xef\x87\xd7\xd3\xc1\x68\x08\x93\xeb\xef\xc2\xf7\xfd\xd9\x75\x7f\xda\xbf\x1b\xbd\xeb\x2d\x69\x31\x8b\xe9\x82\x6c\x93\x62\x16\xad\xb6\xe9\xc3\x8c\xa5\x05\xcd\x1f\x49\xd2\x0e\x5a\x00\x00\xe3\x70\x7a\x3f\x1e\x4e\x60\x30\x9c\x86\xe3\xef\xfb\x77\xad\xfe\x04\xce\x16\xdb\x34\x3a\xc3\x9f\x27\xe1\x5d\x78\x3d\x85\x47\x92\x6c\xe9\xe5\xa5\x6e\x04\xb7\xe3\xd1\xfb\xf2\x54\x6a\x1a\xf8\xe1\xbb\x70\x1c\xc2\x03\xdd\x5d\x9d\xfb\x33\x9e\xbf\x69\xa9\x91\xef\xfa\xc3\x77\xf7\xfd\x77\x21\x4c\xfe\x76\x07\x93\x69\xff\xdb\xbb\x10\x3e\xf4\xc7\xfd\xbb\xbb\xf0\x0e\x26\xfd\xdb\xf0\x4d\xeb\xdd\xb8\x3f\x9c\x42\xf8\x63\x78\x7d\x2f\x76\x3a\x7c\xd6\x0e\x61\x3a\x82\x4d\x9e\xad\x67\x39\x25\x31\xcd\xdf\x3c\x17\x72\x39\x2d\x68\x5a\xb0\x2c\x9d\x6d\x68\xce\xb2\xf8\xf7\x80\x5d\x79\xce\x97\x87\x5e\x75\x97\x55\xf8\xb5\xba\xdd\x6b\x92\x66\x29\x8b\x48\x02\x49\x16\x3d\x40\x96\xc7\x34\x67\xe9\xf2\xb2\xd5\xed\xae\x69\x91\xb3\x88\xb7\xba\xdd\x98\x14\x44\xa2\x73\xab\xdb\x4d\xc8\x9c\x26\xe2\x2b\xa7\x39\xa3\x1c\x36\x24\xa7\x69\xe1\xfd\xbb\x60\x62\x62\x31\x7c\x94\xa5\xbc\xc8\x09\x4b\x0b\x2e\x86\xec\xc2\x74\x45\xe5\x22\xe4\xe8\xf0\xc8\xe8\x13\x14\xe4\x81\x72\x5c\x00\x07\x96\x42\xb1\xa2\x72\x21\x97\x60\x67\xee\x40\x79\xfc\x1e\x6e\x00\x89\x6f\x93\x67\x11\x8d\xb7\x39\x85\x05\x4b\x49\xc2\xfe\x8e\x34\x48\x21\xca\x29\x11\x4d\x21\x5b\x00\x01\x39\x65\x0f\xd7\xb0\x60\x39\x2f\x70\x2c\xc8\x16\x66\xb3\xb6\xc3\x8a\x6c\x36\x34\xc5\xe5\xac\xc9\x03\x55\xcb\x9d\x49\x9a\x26\x69\x8c\xc3\xe3\x64\x72\x10\xdd\x7e\x45\x73\xda\x6b\x75\xbb\x3f\x50\xe0\x9b\x84\x15\x50\x1e\x98\xa5\x45\x06\xc5\x53\x86\xdd\x38\x14\x19\xac\x59\xca\xd6\xec\xef\x14\x12\x52\xd0\x34\xda\x41\xbc\x15\x47\x00\x2c\xe5\x34\x47\x40\x76\xbb\xed\xa7\x15\x8b\x56\xee\xaa\xc4\xfc\xd5\x95\x6d\x48\xb1\x0a\x7a\x10\xf2\x0d\x8d\x18\x49\x92\x1d\xa4\x59\x41\x9f\xb2\xbc\x58\xed\x80\x49\xc6\xd4\xea\x76\x49\x51\x90\x68\x25\x26\x11\xc3\x18\x88\x8a\xd5\x88\x0f\x0a\xd2\x72\x48\x77\x67\x30\xa7\x11\xd9\x72\x0a\xac\x80\x9c\xfe\xb6\x65\x39\x15\x98\x40\x52\xa0\x1f\xa3\x64\xcb\xd9\x23\xc5\x63\xec\x80\x5c\x2f\xe3\x40\x60\xc5\x96\xab\xae\xde\x5b\xb6\xa1\x39\x02\x42\x1e\x43\x56\xac\x68\x0e\x24\x12\x5f\xc4\xea\x98\x18\x4e\x10\x06\x2e\x27\xce\x28\x07\x92\x53\xb1\x09\x20\x1c\xa2\x9c\x15\x12\x57\xe5\x68\xdd\x27\xc6\x29\xcc\xb7\x05\x36\x22\x09\xcf\xb0\x65\x4a\x23\xca\x39\xc9\x77\xad\x6e\xb7\xc8\x60\x43\xf3\x45\x96\xaf\x05\xd0\x10\xab\xc4\x2e\x25\x6c\x25\x7a\xc9\xd3\xdc\xca\x99\x36\xdb\xc2\x9c\x61\xab\xdb\x1d\x66\x05\xbd\x94\xec\x9c\x80\x40\x66\xfa\xdb\x96\xa6\x11\x15\x08\x25\x56\x0b\x31\xe5\x6c\x99\x6a\xd0\xba\xd0\xb3\x50\x15\x50\x40\x80\xd3\x58\xae\xc8\x6f\x45\xd3\x02\xc8\xa2\xa0\xb9\x3c\x56\xc6\x81\x17\x74\x23\xe0\x23\xd6\xa4\x11\x68\xcd\x96\xab\x02\xb7\x37\x17\x9d\xa9\xc0\x24\xe0\xd9\x5a\x90\x64\x94\x67\x9c\x6b\x14\xfe\x6d\x2b\x47\xce\xb1\x03\x79\x22\x3b\x31\x54\xc6\xa9\xf9\x45\x4c\x79\x5e\x40\x94\xad\xd7\x02\xd3\xb3\x27\xfa\x28\x0e\x41\x21\x75\x4c\x13\x22\x20\xc7\x04\x9a\x89\xcd\xb1\x05\x8b\x48\x5a\x88\xf9\x36\xb9\x38\xaa\x48\x43\x47\x1c\x75\x57\x51\xaa\x9a\x5d\xd1\xaa\x00\xec\xac\x42\xb7\x34\x2d\xaa\x64\x5c\xc3\xc5\x3f\x8c\x47\xd7\xe1\xcd\xfd\x38\x2c\x33\x38\x4d\xdd\x1a\xe9\x35\x55\xb5\x03\xe4\xd6\x82\x0d\x9c\xb5\x6e\xc2\xeb\xbb\xfe\x38\x44\xae\x9d\xc3\x38\xbc\x1e\x8d\x6f\xde\xe0\xbf\xb0\x39\x8d\x61\x9e\x65\x09\x25\xe9\x9b\xd6\xb7\xe1\xbb\xc1\x10\x7f\xba\x1d\x8d\x21\x07\xf5\x0f\x87\xdf\x7f\x69\x3e\xd4\x71\x7a\xb9\x0c\xd3\x44\x32\xfc\xe1\x68\x6a\xc8\x7d\x16\x65\xeb\x4d\x42\x0b\x1a\x9b\x46\xa3\xf1\x4d\x38\x86\x6f\x7f\x82\x9c\xa4\x71\xb6\x56\xd2\xe7\x6e\x34\xfa\x50\x9e\x7b\xcf\x20\x83\xe1\x74\xa4\xb7\x73\xc4\x0a\x61\x5d\x5a\xe3\xba\xc7\x62\xb8\x82\xbc\xc7\x9c\xee\xa3\x31\xdc\x7f\xb8\xe9\x4f\xc3\x37\x2d\x3b\xd1\xad\x81\xda\xf4\xbb\xd0\x82\x47\xfc\x75\xbb\x39\x4d\x28\xe1\x14\xf2\xec\x09\xe9\xde\xfb\xf9\x7a\xf4\xfe\xfd\x60\xfa\xa6\xf4\x6d\x38\x1d\x0c\xef\x43\xfb\x35\x1c\xde\xc0\xe0\xd6\x99\x51\xcb\x39\x41\xb3\xa4\x68\x9f\x9d\x79\x03\xf4\xef\xa6\xe1\x18\xa4\x98\x54\x5b\xbd\xe9\x4f\xfb\xbd\x57\x03\x98\x84\x53\x68\x7b\x8d\xc5\x5f\xc1\xd6\x94\x47\x24\xa1\xf1\xbc\x27\xe0\x98\x53\xce\x3b\x47\xb5\x9a\x71\xba\x5c\xd3\xb4\x98\xef\xe0\x0a\xce\x25\xda\xce\x58\x7c\x7e\x64\x6f\xa4\x0b\xd9\x57\xfc\xde\x91\xaa\xc3\xb9\xd7\x39\x78\x03\x67\x67\x1d\xc8\x7b\x48\x2a\x33\xa1\x2b\x06\x0e\x28\xba\x5d\xd4\x81\x38\x3c\x21\xf3\x15\xd4\x49\x05\x95\xb2\x35\x15\x4c\x65\x4e\x17\x19\x32\xc7\xa7\x76\xd0\xbd\x80\x55\xb6\xcd\xe1\x89\x25\x89\xe0\x10\x7a\x19\x0e\x7a\x7c\x08\xc7\xb7\xa3\xf1\x7b\x20\x71\x3c\x33\xab\x94\x13\xcc\x36\x59\xc2\xa2\x5d\x5b\xc1\xfc\xdc\x87\xec\x79\x69\x85\x1d\xa3\x23\xc1\xb9\x9c\xf6\xdc\x5f\x75\x9c\x49\x3e\xa6\x16\x58\x90\x07\x21\x6a\x7c\x11\xe1\x49\xbe\xa7\x2c\x7f\x50\x3c\x50\x35\xf6\xb0\x49\x62\x65\x03\x6a\x8b\x63\xaf\x52\x0a\x5c\xc1\x74\x7c\x1f\x2a\x74\x37\xc8\xee\x2d\xf3\x89\x4a\x70\xa5\x94\xc6\x72\xc1\xb8\xb0\x45\x96\x43\x93\x80\xe4\x19\x2a\x2b\x42\xfe\xa5\xd9\x93\x33\x56\x91\x01\x79\xcc\x58\x2c\x87\xd8\x6e\x96\x39\x89\x69\x0f\x06\x85\x23\xb5\x2a\x3b\x8e\xb3\x94\x0a\x49\x99\x50\x29\xfa\xec\x70\x38\x8a\x10\x2a\xe4\x81\xa6\x3d\xf3\xc3\xdd\xe8\xfa\xaf\x0a\xf9\x47\xc3\xbb\x9f\xca\x10\x51\xac\x75\x30\x84\xfe\xf5\x75\x38\x99\x40\xf8\xe3\xf5\xdd\xfd\x64\xf0\x7d\x08\xeb\x2c\xa6\xc7\x12\x59\x0d\x8d\x95\x66\xe8\x4f\xa7\xfd\xeb\xef\x84\x9e\x3a\x1d\xb8\x8a\xa8\x40\x98\xd9\x24\x1c\x0f\xc2\x49\xef\xd5\xc5\xd9\x00\x79\xca\xf7\xfd\xbb\xfb\x50\xe8\xd5\xd0\x7e\xf5\xf5\xd9\x5d\x60\xa6\x2a\xa3\x7e\x07\x8f\x28\xb0\xbc\xc1\xe5\x20\x82\x4f\x08\x26\xf9\xa6\x15\x0e\x6f\xde\xb4\x24\xaf\x07\xa3\x3d\x7f\xb8\xfb\xf0\x6e\xf2\xb7\xbb\x37\x2d\xd1\x27\x1c\x4e\x85\x9a\xfc\x1c\x31\x32\x98\xc0\xf9\xad\xd1\x21\x4b\xca\x9b\x10\xa4\x9e\xb6\xc9\x57\xd9\x36\x89\x05\xbd\xe5\xdb\x14\xe6\x3b\xa9\x74\x66\x69\x4a\xa3\x42\x60\xd1\xb6\xc8\xd6\x04\x0f\x3f\xd9\x9d\xd7\x28\xf2\xcf\x58\xa1\xd1\xe2\x9f\x72\x56\xa0\x16\xaf\x74\x60\xa3\x35\x09\x63\x94\x24\x89\x90\x73\x3b\x20\x50\xe4\x6c\xb9\xa4\xb9\xe0\x21\x29\x10\x48\xe9\x93\xde\x96\x68\x28\x79\xbb\x40\x54\xd4\xe0\x0b\x0e\xdb\x8d\xd4\x2d\x65\x9b\x5f\xb7\xbc\x00\x9a\x66\xdb\xe5\xaa\xac\x37\xa1\x26\xcb\x8a\x1e\xbc\xf7\xa1\x24\x75\x07\x4b\x89\x2c\x85\x3d\xdb\x21\xf3\xec\x91\xf6\x60\x42\xa9\x02\xde\x5a\xf0\x5c\xa1\x06\x0a\x7d\x54\xe8\x54\x66\x63\x82\x30\x45\x9b\x9c\x12\x9e\xa5\x82\x38\xe5\x17\xa1\x31\xa1\xae\x2d\x95\x31\x4f\x75\xd3\x9a\x22\xa7\x29\x67\x85\x60\x3e\x7a\xb8\x1e\x4c\xe4\xe9\xa1\x5d\x1e\x65\x69\x41\x58\xea\xed\x37\xc9\x96\x2c\x92\x1a\x1b\xdf\x6e\x36\x59\x5e\xa8\xfd\x73\xb3\x14\x65\x52\x94\x74\x21\xd7\x6a\x91\xe6\x52\x9d\xf5\x72\xbc\xf5\x5a\xd1\xf3\x4b\x26\xab\x3a\x62\xfc\x66\xad\x56\xab\x07\xe1\x1a\x66\x2c\x16\x7c\xdb\x51\x7a\x4a\x4c\xe0\x5c\x2d\xa8\x4e\xb8\xb6\x51\xe6\x4c\x07\xef\xc3\xc9\xb4\xff\xfe\xc3\xf4\xff\xa1\x96\x33\xbc\xbf\xbb\x53\x72\x0d\x6e\x46\xf7\x68\xba\x8e\xc3\xeb\xc1\x44\xec\xc1\x36\x30\xa2\x13\xbe\x1d\xbc\x1b\x0c\xa7\xe6\xa7\xa0\x46\x96\x8a\xbf\x61\xf8\x83\x2f\x11\x9b\x17\x7b\x3f\x1c\xfc\xed\x3e\x84\xc1\xf0\x26\xfc\x51\x6a\x9d\x66\xb6\x99\x58\xf3\xec\x15\x07\x9f\x3f\x09\x55\xa1\x6d\x1a\x75\x50\x9a\x06\x30\x18\x5e\xdf\xdd\xdf\x84\xd0\xc6\xdd\xec\x5b\x98\xe8\x53\xb7\x40\x2d\x5e\x25\x49\xcd\x56\xbb\x0d\xcd\xe5\x61\x35\x4a\xd5\xd2\x30\x1d\xa9\x29\x34\xcc\x6d\xfe\xa4\x5f\x04\x37\xa7\x9d\x23\x57\x6f\x4f\xf0\xa3\x1c\x1a\x5e\xae\x5f\xf7\x66\x69\x4c\x3f\x52\x7e\xf5\x76\x41\x12\x6e\xb4\x13\xa5\xa1\xd6\xcc\x9a\xe5\x33\x35\x82\xc6\xba\xf6\xf9\x0c\xf7\x37\x9b\xa9\x2d\x2b\x4c\xc6\x3d\xe3\x68\xa8\xcd\x4e\xa6\xe3\xc1\xf5\xd4\xe0\xaa\xe4\xfa\xdd\xae\xb0\x5f\x25\x1f\xd0\xb6\xa7\xa4\xa8\x9f\x2f\x7e\x11\x0c\x6c\x9b\xb2\xdf\xb6\x14\x08\x9a\x40\x96\xba\x38\x9a\x33\x12\x39\xda\xb2\x43\x80\xe6\x4c\xec\x48\x73\x4d\x9c\x68\xf8\x2d\xb7\x24\x27\x69\x21\x54\x81\x65\x92\xcd\xd1\x76\x96\x83\xb7\xf6\x0b\xcc\x26\xaa\xf1\xe4\xa0\xaf\x97\xb2\x18\xe6\x6c\xc9\xd2\xc2\x12\x89\xf7\xbb\x02\x10\x13\xbc\xb3\xa9\x8d\x5a\xba\x9a\xf0\xc3\x78\xf4\xbe\x27\x41\x47\xf2\x9c\xec\x1a\x3a\x5d\x7f\x17\x5e\xff\xb5\x6d\x01\x78\x05\x42\x22\x43\x7f\x78\xe3\x40\x75\x30\xb1\x04\x5a\xd7\xdd\xae\xee\x0a\x5e\x7d\x73\x56\x69\x34\x1a\x4e\xa6\xe3\xbe\xa0\x73\x45\x65\x72\x68\x41\x91\xaf\xbe\x39\xe3\xe5\x53\x31\x94\xc7\xe2\x83\x23\x6d\x1e\xe8\x4e\x0e\xf2\x61\x3c\x78\xdf\x1f\xff\x04\x7f\x0d\x7f\x12\x1d\x4d\x3f\xf9\x5f\x42\xb3\xf0\x89\xab\x63\x10\xab\xa3\xa8\x58\xa1\xb2\xe4\xa1\xe2\x1b\x6a\x16\x15\xb7\x9c\x52\x2c\xe0\xfb\xd1\x5d\x7f\x3a\xb8\x3b\xc5\x1d\x57\xc3\xb6\xab\xd2\xfb\x66\x3c\xfa\x00\xd3\xf1\xe0\xdd\xbb\x70\x2c\xac\xad\xf0\xc7\xc1\x64\x3a\xa9\xba\x76\x66\x5a\x8e\xd7\xcc\x23\xe5\xef\x75\x7f\x72\xdd\xbf\x09\xdf\x68\xc1\xa2\x07\x6d\x1c\x4a\xca\x8b\x5b\xa1\xec\x0d\x86\x93\x70\x3c\x6d\x1c\xdb\x98\xc8\xa1\x50\xfb\xc6\xa3\x1f\x3c\x9a\x68\xd4\x62\x6a\x00\x20\xbd\x8e\xf5\x7f\xad\x6e\x17\x06\x82\x4d\xa5\x24\x31\x62\x9a\x03\xfe\xd0\xd0\x43\x74\x19\xd3\x62\x9b\x0b\xd5\xc6\x3a\xdc\x61\xbe\x65\x49\x01\x8b\x3c\x5b\x03\x81\xc5\x36\x49\x10\x09\x90\x29\x10\xe0\xdb\xc5\x82\x7d\x14\x42\x5b\xba\x02\xb7\xc2\x06\x20\xd2\xa6\x2a\xf2\x6d\x1a\xa1\xb9\x2b\x54\x7d\xe3\xd2\xc1\x1e\x10\xa1\xbe\xb0\x60\xe8\x0b\x11\xdd\x70\x0c\xec\xca\x51\xa9\x17\xd6\x04\x49\x9e\xc8\x4e\xd8\x3e\x40\x3f\x92\xa8\x48\x76\xf0\xa7\xaf\xa5\xc3\xff\x14\x91\xbf\x59\x4a\x9e\xf9\xc4\x8a\xd5\x4c\x4e\x6f\x79\x88\xdd\x50\x41\x3f\x16\x1d\xbd\x3c\xf1\x0f\x5f\x31\x10\x6d\xea\xfd\xd8\x6d\xbe\x9d\xf3\x22\x67\xe9\xb2\x6d\x47\x13\x5a\xcd\x9f\xbe\xee\xb6\xc5\x6a\x67\x09\x4d\x97\xc5\xaa\x2d\xc7\x0e\xfe\x70\x11\x04\xf0\xef\xff\x0e\xe7\xb3\x73\xf1\x7f\xea\xeb\xe5\x25\xce\x50\xe7\xca\x1e\xbc\x7f\x7f\xff\x69\xde\xec\x3a\x10\xc8\xfd\xe2\x46\xab\xee\x6c\x17\x17\x84\x9a\xab\x64\x83\xdc\x9a\x44\x05\x83\x05\x2c\x56\xe7\x8f\x67\x8e\xde\xce\x0c\x84\x74\x29\x14\x46\x48\x88\xe8\x73\x86\x6f\xb7\x05\xb0\x85\xf8\x55\x74\xb3\x28\x13\x67\x94\xa7\xe7\x85\x40\x8a\x0e\x2c\x69\x4a\x73\x52\x50\x5e\x5d\x00\xce\x36\x34\xb2\xac\x40\x83\x3e\x22\xa9\x72\xe8\x11\x88\xb2\x24\x61\x5c\xa8\xb9\x73\x5a\x3c\x51\x8a\xca\xfa\x96\xd3\x5c\x74\x8c\xe9\x82\xa5\x34\x06\x07\x89\xf1\x3f\x05\x68\x0c\x42\x1b\x01\x59\xd7\x8b\x43\xb6\x00\x79\xa4\x02\x1f\x15\x92\x2e\x69\x61\xbb\x93\x34\x16\xbd\xa2\x2c\x7d\xa4\x39\xa7\xc9\xae\x03\x44\x6d\x93\x97\x66\x12\x02\xd3\x0c\xd6\x43\xc8\xff\x80\xf3\x02\x81\x35\xf9\x28\x17\xa7\x1a\x64\x0b\x31\xa1\xd8\xe7\x9f\xbe\x31\x4b\x94\xa4\x6a\x9c\xe2\x52\x63\x10\x42\x5b\x0c\x25\x25\x58\xb1\xdb\x48\xd0\xc5\xf0\x6f\x92\x7b\x88\x7f\xfc\x5b\x4f\xcc\x24\x2d\xf6\x0c\x68\xca\xb7\xb9\x01\x29\xe3\x9a\x8c\xc5\x28\x5a\x33\xe0\xf0\x44\x93\xa4\x23\xe8\x79\x45\x1e\xa9\xe8\x96\x53\x4e\xf3\x47\xb1\x58\xbe\x21\x11\x35\xda\xfc\x36\x8d\x69\xce\xa3\x2c\xa7\xcf\x21\x55\x39\x61\x0d\x95\xce\x48\xbe\x7c\x3e\xa5\x5e\xf7\x27\xa1\xeb\xbd\x1b\x82\x4b\x9e\xde\x24\x01\xfc\x45\xc0\xba\xe2\xa8\xf3\x1a\x29\x9a\xd5\xbf\x85\x77\xce\xf0\x38\xed\x09\x8c\xa8\x76\x02\xbd\x4b\xdf\xd3\xe5\x3a\xfc\x5e\x98\x61\xa8\x83\x38\xc0\x2b\xae\x35\xce\x21\xa9\x4a\x84\x44\xd7\x0f\x2c\xd9\x23\x4d\xb5\x01\xac\x89\x17\x39\xc5\x96\x53\x34\x90\x79\x26\x58\xbe\xba\x0b\xe0\x02\xb5\xb8\x63\x4b\xce\xa9\x32\xc0\x5b\xdd\xee\x00\x79\x86\x1a\x1e\xef\x33\x04\x25\xec\x68\x01\xf4\x23\xe3\x85\x1c\x99\x3a\xc6\xbb\x32\x30\xe5\x35\x91\xb5\xc3\x23\x52\x90\x24\x5b\x2a\xab\x52\xe0\xb7\xba\x62\x41\x7a\xe2\x0d\xd7\x41\x5a\x67\x28\x32\x58\xb0\xdc\xeb\x47\xa2\x62\x8b\x4a\xae\xa6\x3d\xb3\x4c\xd1\x08\xef\xe2\xb4\x53\xbf\x53\x1d\xf9\xe7\x63\x4c\xdc\x5f\x4e\x20\x22\x65\x33\x78\xca\x42\xab\xa4\x0f\x97\x68\x69\x74\x3f\x05\xa9\x22\xcb\xff\xb6\xca\x1e\x48\xd3\xa2\xce\x14\x4e\xe9\x93\xd2\xab\xb5\x21\xac\xbe\x5c\x41\x4a\x3f\x16\xc2\x40\xda\x2c\x67\xc2\x90\x11\xbb\x21\xc9\x4c\x9f\xb2\x31\xdf\x7c\xcd\xe8\xbc\x73\xce\xe2\xf3\x20\xb8\xbc\xc4\x21\x8d\x1b\x5f\x29\x54\xd2\xb2\xa9\x55\xd7\xda\x42\x15\x75\x76\xd6\x71\x36\x10\x94\xaf\x02\xd4\xba\xab\xa6\x5b\x09\x34\xd5\x06\xfb\x69\xa4\xdc\x5d\xcd\x73\x79\x69\x39\xd4\x68\x28\x14\xf1\xdb\x3b\x61\x9c\xdd\x8c\x84\x69\xf0\xdd\x60\xf8\xce\x61\x5e\x83\xe1\xbb\xfa\x2d\xa2\xa9\x5c\xff\x8b\xdd\xaa\x35\x00\xd1\x18\x37\xdf\xb5\xfd\x27\x99\x32\x5e\x22\x0a\xd1\x14\x6d\xf3\x1c\x2f\x12\x05\xba\x2a\x6f\xd4\x9a\xe0\x35\x27\xe4\x4a\xf8\xa7\xbb\x62\xc5\xd2\x25\xb2\xfc\x22\xdf\x09\x36\x4f\x13\x1a\x15\x28\x39\x93\x2c\xdb\xe8\xa1\x57\x45\xb1\xe1\x97\x5f\x7d\xc5\x0b\x12\x3d\x64\x8f\x34\x5f\x24\xd9\x53\x2f\xca\xd6\x5f\x91\xaf\x2e\xfe\xf8\x7f\xff\xf8\xfa\x9b\xaf\xff\x8f\xd2\x74\x07\x53\xc9\x7b\x6f\x47\xf7\xc3\x1b\xdf\x08\x5e\xe3\x3e\xd7\x47\xec\x49\xaa\xd1\x87\x6e\x69\xd4\x0d\x8d\x3d\x19\xb8\x2a\x1f\xb3\x5a\x40\x65\x59\x9e\x93\xf4\xa0\x29\x03\x27\xf0\xd6\x3a\xfa\xf4\x59\xab\xe3\x8f\xf4\x59\xab\x34\xbc\x1e\xe8\x0e\xaf\x89\x5c\x16\xfb\x40\x77\x2f\xc9\x5a\x4f\xe6\x3e\x66\xa5\x96\xf5\x08\x7a\x10\x4b\x9f\x86\x3f\x4e\x0d\xcb\x19\x0c\xd5\x7f\xa3\xb3\x68\x16\x65\xc9\x76\x9d\xca\xa3\x1a\xf6\xdf\x87\xba\x5d\xe5\x87\xd6\x4b\xf3\x24\xb3\x81\x67\xb0\x25\x7b\x4c\xc8\x99\x1e\xe8\xae\x53\xdd\x5f\xa7\xb4\xad\xe3\x19\x95\x02\xe4\xa9\x0c\x4a\x77\xf3\x19\xd3\x33\x47\x91\x06\x0c\x8b\xcf\x3b\xc6\x8d\xf8\x4a\x5e\xe9\xa9\xe1\x83\xe7\xb3\x3c\x03\xbe\x3a\xae\x67\x7f\xac\x81\xe8\x9e\x81\xdc\x86\x3e\x53\x39\x78\x32\xff\x75\xf8\x67\xf2\x80\x20\x4b\x1e\xea\x80\x83\x3f\x7e\x02\x18\x1a\x59\xae\x45\xf7\xe4\xc1\x61\xbb\xe2\xc3\x95\x46\xd6\xcf\xc3\x66\x4f\xe7\xb2\x96\x0f\x09\xb6\x53\xcb\x62\xdf\xa1\xe5\x86\x0d\x41\xb3\x56\xb6\x80\x2c\xb5\x26\xe9\xb3\x38\x61\x9d\x0b\xd7\x63\x88\x9f\x8d\x19\x06\xbe\xb9\xa3\x90\xe1\xe8\x43\x3d\xe6\x4c\xe5\x91\x26\x0f\x3d\x79\xaa\x0d\x7b\x13\xbf\x8a\xd6\xf7\x43\x01\x8f\xfe\xdd\x5d\xab\x14\xfe\x51\x37\x55\x05\x40\x7b\x06\x47\xa6\x72\x37\x78\x3f\x98\xc2\x45\xad\xe9\xf3\x0c\x4c\x69\x3a\x27\x89\x30\x45\x56\x41\x18\x90\x18\x63\x04\xb2\xb2\xb2\x37\x19\x67\xe6\x72\xcd\x41\xa8\x1e\xdc\x8a\x0f\xe9\x4e\xdb\x00\x62\x88\x27\x0a\x4f\x24\x95\x2e\x31\xdd\x11\x1d\x27\x73\xb4\xb3\xa3\x6c\xbd\x21\x11\x46\x6a\x6d\x32\xce\xd9\x3c\xa1\xd6\xc9\x82\xf2\x1d\x85\xfb\x26\xa7\x45\xb1\x83\x15\x25\x8f\x3b\x15\xf4\xc7\xa5\xef\x85\x6f\x48\xce\xd2\x65\x82\x5a\x81\xb6\x41\xcc\xde\x66\x7a\xca\xce\xde\xb0\x40\x68\xb3\x54\x86\x15\x6a\xf7\x42\xd0\x39\x91\x00\x04\xf9\x6f\x32\x3e\x5b\x64\xb9\x8f\xfc\xae\x52\x26\x8d\x10\xb1\x2e\xf3\x4f\xdf\xa4\x67\x69\x51\x2b\xee\xc1\x02\x1d\x85\xb3\x94\x8e\x1f\x8b\x59\xf5\xb3\x67\xcc\x09\xa2\x71\x43\x96\xba\x5d\x01\xb3\x38\xdb\xa2\x2b\x65\x45\xa3\x07\x04\x19\x4b\x97\x18\xc1\xa6\xda\x2c\x18\x2f\x20\xdb\x14\x6c\xcd\x78\x21\x0c\x49\xd1\xf0\xd2\xe1\xbf\x66\x73\x9b\x8c\x1b\x6e\xd9\x6a\x90\xab\xd5\xc3\x80\xe4\x61\x63\xf9\xa7\xe9\x97\x3c\x6c\x7a\xbe\x0a\x5b\x03\x58\xb7\x85\xe9\x89\x97\x11\x0f\x1b\x87\x66\xcb\xbd\x34\xcc\xad\x28\xd0\x8b\x51\x0c\x7b\x70\x2b\x39\xb5\xef\x09\x51\x9e\x7e\xdb\x16\xca\xe1\x47\x8a\xe8\x8f\x50\xd8\x7d\xf2\xf3\x9c\xeb\xa2\x5f\xfb\xc0\x66\x9d\x6b\x2f\xb7\xaf\x96\xd9\x18\xfd\x41\xe4\x2d\xb3\x1b\x8c\xa1\xbd\x67\x4f\x14\x3d\x70\x2c\x05\xba\x58\x08\xc1\x1c\xad\x48\xba\xd4\xd1\x2a\x3c\x5a\xd1\x35\x71\x71\x00\x23\x23\xd7\x18\x64\xab\xfc\x65\xb4\x84\x71\x73\x9a\x08\x01\x22\x68\x38\xcf\xc5\x88\x2c\x85\x82\xe6\x6b\x74\x1b\x3a\x6a\x43\xdd\x5d\xd8\xb9\x13\x95\x52\xba\x67\x1d\x0c\x61\xf2\x5d\x7f\x1c\xea\x08\x1e\x1b\x8f\xf2\x7e\x74\x13\x9e\x77\xbc\xdd\x07\x7a\xfb\x9c\x46\x59\x1a\x2b\x94\x96\x51\x41\x26\x1c\xe8\xbf\x02\xce\xee\x45\xda\xcf\x8a\xb0\x83\x5b\xcb\x80\xae\xc0\xde\xb3\x7a\xe3\xf8\x27\x7d\x79\x05\x17\x6f\x84\xf2\x76\xd1\x95\x57\xbb\xb1\x94\x04\xbc\x03\xba\x3b\xa2\x1e\xc6\x47\xd3\x84\xae\x69\x5a\xc8\x89\x5d\x47\x61\xe9\x18\x90\x57\x91\x8f\xed\x4d\xc6\x03\xf8\x03\x5c\x98\x1f\xbc\x73\x39\xed\x6c\xaa\xe7\xf3\xac\x33\x92\xf0\xf6\x60\xe0\x87\x37\xfa\xe0\x19\x4c\xf0\xe2\xb3\xea\x43\xad\x40\xf1\x6b\x84\xa2\x82\x10\x5c\x68\xa7\x72\x8c\x54\xab\x41\xe9\x7a\x3d\xf1\xd8\x2a\x47\xa8\xc3\x06\x8e\x94\xef\xfa\xb8\xf5\x45\xfc\x31\x06\x9d\x59\xb6\x59\x8d\x0a\xc9\x6a\x7b\xee\x27\x3d\x74\xc7\xdf\x6b\xc5\x24\x32\xa3\x34\x99\x46\x2e\x75\x36\xa1\xfb\x70\x34\xad\x45\xf9\xfe\x60\x12\xc2\xf9\x35\x5a\xfc\xc2\x26\x59\x30\x79\xdb\x41\x9f\xcc\x20\xe7\xc7\x43\x51\x81\x4f\xdd\x1e\x0b\xa5\xc0\xdd\xb2\x62\x38\xfb\xfb\xaa\xf6\x35\x7d\x5b\xb5\x34\xfa\x99\x2d\x82\x3a\x75\xa4\xce\xb1\xed\x68\x7a\xb5\xfe\x12\xc5\x47\x89\xe2\xaa\xea\xc6\x44\x5d\x6f\x4a\xad\x4f\xdb\x0d\x68\x33\x3c\x43\x63\x32\xf1\x1e\x9e\x4e\xa4\xd5\x79\xe7\x83\x35\x1c\x5c\x1b\x40\x2a\x36\x75\x9e\x8a\xbd\x8c\xbd\x6d\x1d\x15\x12\x53\x25\x6e\x9b\x3e\x66\x35\x1d\xbb\x8e\x4f\xb4\xf2\x75\xd0\xb4\xb2\x42\x9b\xac\xc4\x3a\x79\x55\xee\xbb\xdf\x3c\x85\xa4\x46\x4a\x49\x19\x63\x60\xdc\x1f\xde\x98\x9f\x64\x24\xd6\x95\x03\xf1\xdf\xdd\x82\xad\x20\x83\x8b\xac\x35\x66\xc9\x53\x4e\x36\x1b\x81\x98\x79\xb6\x4d\x63\xf8\x95\x67\xe9\x7c\x46\x49\xb4\x9a\x89\x2e\xa2\xc7\x92\xe1\x4d\xdd\x9c\x16\x02\x81\xf3\xec\x69\x46\x79\xc1\xd6\xa4\xa0\xad\x6e\x57\xf0\x5a\x15\x09\xd3\xbe\x78\x8d\x1c\xe3\xe2\xf5\xeb\xe0\x04\xec\x95\x0b\x2d\xcd\xdb\xfe\x95\xcb\xa5\x48\x64\x15\x20\xb7\xa8\x2b\xa1\xac\xf4\x7d\xad\xec\x4f\xc2\xe9\xe8\x16\x72\x1a\x65\x79\xdc\x02\xd7\xba\x6b\x35\xdd\x6c\xe9\x88\xa7\xf1\xe8\x87\x09\x5c\xbc\x36\xa4\x20\xf8\xc8\x99\xb9\xa7\xaf\xae\x2c\x08\x7a\x5f\x3a\x2d\x4f\x38\x9c\xa6\xbd\x66\xe9\xdc\x1e\x8e\x73\x45\x56\x3a\x9c\x6d\x9a\x52\x6e\xcf\xc4\x9e\x08\xe8\x13\xf9\xb4\x43\x90\xe3\xb7\xdd\x30\x26\x92\xee\xf0\x3f\x2a\x90\x26\xe9\xce\x28\x27\x9f\x0f\xda\xd5\x15\x04\x9f\x02\x69\x35\x9c\xd9\x44\x15\xc6\x8d\x91\x2d\x7b\xfe\xea\xfa\xc0\x87\xed\x3c\x61\x11\xf4\x3f\x0c\x38\x1c\xd9\xe7\xe0\x3c\x27\xca\x80\x8a\x15\x34\x63\x8b\x19\x0a\x13\xde\x6c\x41\xfb\x26\xb3\x3c\xb7\xb6\xbe\xd5\xdb\x73\xa3\xe7\x7b\x8c\x6c\x43\x7b\xbb\x7d\xe8\x9e\x45\x27\xc2\x54\xb5\xc9\x3d\x1b\xf1\xb4\xff\x17\xca\xe0\xdc\x07\x47\x9f\x8f\xba\x91\x2f\x0a\x01\xcc\x45\xb2\xa0\x52\x2a\xc5\x3b\x6e\x2d\x73\x6f\x4b\xaa\xf7\xdc\xc6\x4f\x83\x31\x4c\x52\xf7\x71\xef\x9f\x65\x3f\xb6\x00\x56\x7c\xe2\x5d\xcb\x21\xd3\x79\x8f\xb3\xe5\xc0\x8d\xaf\xfc\xa8\x5c\x4f\x3b\x21\x86\xe0\xdb\xd1\xe8\x2e\xec\x0f\x8f\xc7\x9c\x0e\x60\x9c\xeb\xa7\x21\xd0\x9e\xed\x95\xcd\xc7\x5a\xa7\x63\x07\x8a\x7c\xdb\xb8\x86\xba\xab\xb8\x13\x66\x7d\x79\x6f\x64\xf5\x4c\x1b\xc5\xff\xa6\x19\x6b\xf7\xfb\x27\x3f\xdd\xa5\x2d\x54\xea\x03\x9e\xbd\x1a\x0e\x35\x18\x4e\x11\x97\xce\x94\xaf\x02\xb5\x6c\xfa\x91\x46\x5b\x1d\x43\xb1\xce\x72\x0a\xf4\xe3\x86\xa6\x98\xeb\xa4\x75\x29\x1b\xe1\xb1\x68\xd4\xb9\x6b\x14\xc6\xdf\xc1\xc1\xd1\x00\x9b\x23\x9d\x73\x4d\xbd\x95\x53\xdd\x47\xf0\xf2\xee\x8e\x30\x76\x8e\x5c\x61\xe7\xd0\x62\x54\x9a\xa5\xc6\xfb\x17\xf3\xc0\x23\x5a\x1d\x50\x7a\x9f\x8d\xf5\x8a\x01\x4b\x61\xd0\x42\x07\xf0\x62\x9b\x00\x4b\x21\x22\x2a\xf0\x8f\x2b\x17\x7d\x06\xcb\x3c\xdb\x6e\x64\x0e\x10\xa6\x83\x2f\x58\x74\x12\xfd\x38\x31\xe5\x2e\x6e\x7d\x2a\xcd\xfc\xbe\x08\x5e\xed\x7a\x04\x5e\xd7\x74\xd2\xe8\xdc\x84\x40\xcf\x94\xfb\x4d\x30\xae\x43\x20\x47\xda\xbf\xa3\x8e\xe1\x3e\x63\xb1\xc2\x13\x6b\x0a\xc3\x86\x30\x8c\x01\x4d\x33\x19\x2c\xa9\x24\x3e\x75\xd2\xa9\x90\x75\x11\xae\xb1\x06\x33\x21\x65\x34\xea\x96\x63\xfd\x81\x98\x43\xcc\x72\x1a\x15\xc9\xa7\xb2\x5b\x16\x7b\x17\x88\x7b\xbc\x03\xfb\xb9\xad\xf4\x4a\xaa\xdb\x68\x84\x0b\x7d\xa4\x69\x61\xe2\x45\x64\x98\xef\x9c\x8a\xe5\x6f\x39\x8d\x61\xab\xef\xaa\x85\x2a\x2e\xab\x02\xb0\x64\x57\x87\x87\x87\x6c\xf1\x4f\xb5\xc4\x9f\xcd\x0c\x2b\x6e\x15\x17\x66\xbf\x0b\x57\x3b\x6c\xc5\xa3\xe6\xe8\x46\x3f\xdb\x8b\x05\xc2\xb5\x87\x59\x1e\x8e\x40\x5b\xb4\x38\x5b\xdd\xee\x6b\x0e\x39\xdd\xe4\x94\x8b\x33\x7c\xa0\x3b\x55\x44\x41\x57\x7c\xe0\xb4\x80\xf6\x13\x85\x38\x13\x22\x72\xcb\x29\xba\x66\x5b\xdd\x2e\x67\xe2\xac\x59\x5a\xc8\x71\x8d\x3e\x6a\xb2\xf4\x8a\xc0\xc4\x15\x31\xf3\x13\xcd\x75\x29\x08\x22\xba\x9b\xb4\x5c\x39\x9a\xaa\x3d\xc1\xb8\xa4\x0b\xc4\x9e\x2c\x75\xc3\x24\xa2\x84\x61\xa9\x86\x34\x96\x29\x96\xd1\x0a\xd3\x86\x55\xb1\x88\x31\x25\xb1\xa9\xb0\x10\x91\xd4\x04\x93\xd3\xdf\x1c\x92\xcb\x65\xc5\x0b\x15\x4f\x6d\x60\x21\x03\x34\xd3\x18\xe8\x6f\x5b\x92\xb0\xe2\x53\xe9\x0d\xe1\x62\x9c\x18\x41\xcb\xd8\xce\x0d\xd9\x42\x96\xc6\x7e\x18\x4c\xbf\x03\x16\x7f\x9c\x3d\x92\x44\x7c\x6e\xef\x73\xf9\x77\xbb\x2a\x79\x92\x24\x89\x0a\x9c\xd7\x49\x1b\x45\xa6\x8d\x08\xa1\x86\x0b\x4a\x31\x17\xc6\xe5\x21\x30\xaa\x04\xe5\x92\xe0\x38\x52\x3d\xda\xa9\x43\x47\x49\x07\x6d\xc1\xbd\xdc\xb3\x93\x19\xa0\x3c\xf0\x86\x8a\x32\x92\x50\x1e\xd1\xb6\x60\xe4\x9b\xac\x92\xe5\x7f\x82\xfc\xfe\x95\x77\xdf\xbe\x75\xd3\xd6\x28\xaa\x10\x81\x80\x4c\xa7\x61\xd2\x5e\x35\xea\xe9\x38\xda\xc2\xb1\xc5\x14\xd2\x89\x19\x08\xf2\xf6\x2e\x50\xa0\xc9\x6f\x13\x00\xf5\x67\xbc\x0b\x6f\xa7\xf0\xaf\xa3\x41\xbd\x3f\x02\x92\xd2\xfa\x04\x23\x68\x27\x4a\xe8\xe1\x32\xa4\x20\xec\x69\xf6\xa5\xd7\xd4\x3a\x7e\x92\xe6\xcb\x1c\x33\x67\xf9\x4b\x35\x9e\xbc\x4e\x92\x97\xce\xc4\x63\xb7\x7e\x3f\x67\x3f\xe5\x16\x76\x27\xdd\xae\x90\x8b\x88\xa8\xb2\x2a\xcc\x7c\x27\xd5\x17\x2b\x55\x62\x4a\x62\x55\x94\x68\x51\xab\x5e\xb2\xd8\xe4\x50\x63\xcd\x02\x59\x19\xc9\x6c\x54\x17\xfa\x48\xcc\x4a\x02\x87\xef\x43\x7f\x3c\xee\xff\x54\xa6\x2f\x8b\x50\x8a\x08\xc5\x09\x74\xe0\x75\xd0\x7c\xa5\xa6\xf9\xae\xba\xe6\xa8\x83\x26\xc0\x45\x7d\xd6\x67\x5b\xc7\xb0\x92\x8f\x62\xc2\x40\xe2\x9b\x9a\xda\x3f\xf6\x00\x96\x0d\x68\xa0\xd9\x85\xc0\x26\xbd\x6a\x16\x7f\x14\x06\x82\x1c\x22\xb8\xbc\x6c\xe0\x3c\x7b\x44\x96\x93\xc8\xff\x1c\xd6\x87\x7c\x6f\x30\x81\x73\x99\xcf\x52\x08\xa9\x64\x78\x2d\x71\x63\x60\xea\x12\xf3\x9f\x3b\x63\xf5\x3e\xe6\x73\x30\x72\x97\x10\x64\xec\x95\x21\x35\x8e\x42\xf9\xe7\x5f\xf4\x27\xa4\x57\xfd\xf1\x7f\x18\xbf\xdc\xc0\xf1\x8c\xbf\xf1\x0c\x7c\x5b\xf3\xe1\xf1\x05\xe5\x81\x1c\x1c\x27\x69\x94\x08\xe8\xc5\x16\xff\xd5\xf6\x5c\xd6\x02\x21\x82\x0e\xdc\x0f\x87\xe1\x64\xda\x76\x31\x22\x08\xc4\xa1\x3e\x3c\x56\xae\xcb\xaa\xe4\x7c\xba\xe8\x90\x2b\x2e\xc9\x0e\xb3\xfc\x7f\x04\xe1\x71\xd4\xb9\x1e\x14\x29\x72\x9f\xcd\x32\xc5\x70\x7c\xa7\xe1\xff\xb0\xfc\xdf\x89\xe5\x5b\x13\x45\x70\x43\xcd\x00\x4b\x12\xc0\x49\xea\xea\x28\xab\x24\x5b\xa0\xe9\xd1\x91\x79\x95\xfa\x93\xe6\xa3\x2f\x22\x2b\x24\x0f\x2f\x2d\xb5\xee\x2a\x5f\xe5\xb3\x72\xc1\x20\x3b\xd2\x42\x12\xab\x54\x8b\x73\x9c\x43\x0a\xb4\xdd\xae\x2d\x9e\x68\xc2\xba\xe7\xd2\x0e\xe1\x98\x3b\xca\x55\x03\xb6\xc4\xda\x85\xa6\x98\x23\xf8\x35\x64\x8c\x6e\x34\xa7\x2a\x78\xf0\xef\xca\x89\xe0\x70\xe3\x93\x7c\x48\x7c\xc6\xd2\x45\xd6\x1e\x0c\x47\xf7\x53\x7d\x65\x3b\x18\x4e\x05\x00\xd4\x15\xab\x15\x65\xea\x96\xd5\x8a\xb1\x46\xef\x11\x6e\x7b\x46\x96\x4b\xe4\xb7\x41\xc7\xfb\x20\x58\xb4\xff\xc5\x61\x48\x0e\x4d\x55\x6f\x1f\x79\x60\x5c\x2b\xaa\xcd\x60\x38\x0c\xc7\xfb\xf8\xa3\x62\x88\x98\xb5\xa1\xfb\x06\x47\xba\x89\xf6\xe0\x7d\x0d\x00\xa7\x55\xbc\x4e\x2d\xe2\x5a\x81\x8a\x09\x84\x39\x55\x3e\x45\x7e\x89\xc1\xe1\xe2\x08\x11\x99\xf4\x3f\x0c\x52\x91\x54\x16\x63\x14\x1f\x25\x82\x1d\x83\xec\x0d\xeb\x7b\x4e\xad\x56\x1c\x4a\x08\x00\xe9\xde\x90\xa4\xb2\xbf\xcc\xc5\x73\x70\x47\x51\x3b\xb6\x71\xbd\x3d\x95\x9d\x28\x4c\xf8\x4c\x67\x58\xde\x58\xc3\x8e\x2a\x1c\xcb\x94\x28\xc1\x9c\x68\x59\x3a\xb4\x7c\xa0\x9f\xe3\x0c\x8f\x5d\x5f\x5d\x2a\xad\xe6\x52\xd6\x9f\x21\x59\x93\x0a\xcd\xd6\x79\xe8\xe8\x8a\x76\xd9\xd5\x91\x38\x81\x43\x1e\xc0\x04\xab\xea\xca\x05\x34\x72\x0c\x69\x45\x67\xf3\x5f\x69\x54\xb4\x2d\x2a\x54\x98\xc2\x61\xa4\xfc\x5c\x98\x71\xdc\xf6\x0e\xa0\x05\x81\x7f\x9d\x8c\x86\xdf\x82\xdc\xd8\xd1\xa7\x2e\xe7\x3e\xe5\xac\x6f\x64\x8d\x55\xd4\xfc\x55\x7d\x3f\x0c\x29\x93\xd1\xc7\x7e\x09\xd4\xea\x45\xf1\xe9\x39\x79\x65\x0d\xc7\xab\x8d\xd3\x29\x7f\x76\x6e\x84\x31\xf9\xc4\xde\x0c\x38\x46\x4f\x13\x33\xb1\x7a\xdc\xfd\xb4\x52\xb4\xaa\x3e\x75\x0f\x33\x85\x6c\x53\x59\xd5\xc7\x06\xe9\xfb\xbf\xda\x74\xbe\x72\xde\x9e\x2d\x2e\x19\x38\xc9\x7a\x7e\x9c\x35\xb8\x45\x87\x6a\xc2\x3e\xbd\x9a\x43\x03\x37\xbb\x18\xb3\xab\x14\xca\x96\x54\xcf\xb3\x8b\x0e\x9c\x7d\xdd\x81\xb3\x6f\xec\xe6\x9b\xc3\xe2\xc0\x0b\x8d\x53\xb2\xf1\xec\xac\x53\x85\xbe\x13\xe0\x6e\xf6\x26\x3d\xe0\x13\xd1\xd5\x83\x4b\x75\x9d\xf2\x3c\x2a\xb1\x6b\x16\x92\xca\xa9\x9b\x6e\x93\xc4\xb4\x6a\xaa\xcf\x64\x2e\xfb\x7d\x0b\xaa\x16\x6a\xa6\x89\xca\x1c\x92\x44\x76\x05\x67\x17\xcf\xde\xea\x33\x36\xf4\xd2\xe9\x65\x8a\xa4\x30\x8c\xc2\x4b\x41\x6c\x66\x00\xae\x4e\x3a\xc5\xda\x25\x32\xca\x54\x98\xea\x58\xbe\x44\x57\xe4\xc0\x12\x1b\x44\xd8\xe9\x2a\x02\xf5\x91\xe6\x5c\x55\xe0\xc6\x0a\x1d\x58\x8f\xe3\x3c\x49\x60\xcb\xa9\xd6\x57\xe9\x6f\xc6\x33\x0e\x6b\x52\x44\x28\xc2\x5c\x8f\x02\xfa\xc6\x15\x53\x11\xaa\x4d\xc2\x1e\xa4\xc7\xbe\x07\xdf\xc9\xf2\xc5\x1d\x35\x56\x2e\x53\x23\x74\xfe\x11\x7a\xe1\x23\xc2\x75\x4d\x7e\x55\xea\x93\xda\xc1\xcc\x15\x57\x45\xbb\xc5\x01\x49\x01\x4f\x54\x15\x5f\xd6\x17\xab\x9c\x62\x24\xcc\x93\xad\x43\xa2\x2e\x1e\x3a\xc0\x52\x53\x96\x81\x53\x20\x62\x8c\x2a\x28\xe4\x68\x78\xcf\xa6\xaf\x6f\x17\xdb\x62\x5b\x5f\x75\xe4\x48\xf3\xc2\xa0\x92\x14\x24\x65\xc7\xbf\x2a\xbc\x27\xa5\xa2\x65\x5f\x55\xce\x05\xe5\xa0\x1a\xf1\xc9\xe3\xb9\x4e\x0a\x52\xb7\x3b\xa1\x14\x1a\x16\x22\x6f\xf0\x1f\x15\xcf\x15\xc0\x4d\x33\xbc\x1c\x9a\x67\xdb\x42\x67\x2a\xb5\x9c\x34\xfa\x22\x95\x89\xf4\x45\xea\xa4\xd2\x3f\x2b\xfb\x06\x41\xe0\x79\x8b\x03\x31\x6c\xab\x94\x73\x53\x2e\x38\xd0\x3a\xba\xc2\x2a\x4b\x75\x85\x55\x99\xde\x62\xab\xab\x96\xf9\xd0\x6f\x5b\x9a\xef\x1c\x0f\xdb\xf5\x34\xac\xf3\xae\x1d\x6f\x3b\x9e\x5d\x04\x55\xbf\x42\xcd\xe5\x65\xa5\x04\x1d\xe1\xea\xb8\x5b\x35\x0c\x4e\x3b\x05\xbe\x94\x63\x44\x85\x62\x6b\x75\x17\x96\xfb\xb9\xca\xab\xaf\xcf\xee\x3a\xf0\xea\x42\xfc\x6f\xcd\xa8\xfe\x85\xa5\xe0\xa9\x12\x42\xee\x49\x38\x02\x01\x9b\x3b\x8c\xb4\x55\x61\xb5\x5e\x15\x37\xe7\x2b\xb2\xce\xbd\x6c\xf3\x64\x6f\x83\xa5\x31\xc7\xbd\x9c\x3b\xba\xaf\x65\x2a\xc8\x38\x74\xfd\x2f\xc9\xd2\xb8\xd5\xd1\x94\x95\x76\x94\x72\x7e\xdc\x52\x3e\x9f\xdf\xb9\x9e\x7e\x9f\xed\x82\xae\x04\xea\xd9\x9c\xe0\xe3\x34\xac\x66\xde\xa3\x99\xef\x9a\x3c\x50\x9b\xd7\x56\x49\x07\x55\xc1\x32\x2f\xc2\x68\xdc\xb0\xba\x63\x39\x4c\xb7\x8b\x55\xf6\x4d\x06\xab\xaa\x2a\x37\x97\x65\xb1\x69\xac\x9f\x7f\xb0\x41\x3f\xa6\xb0\xae\x4c\xe6\x4b\x63\x58\x6f\x79\xe1\xf4\xd0\x75\xb6\xab\xcf\x0a\x44\x51\x96\xc7\xa8\x9a\x67\xfe\x03\x1f\x07\x78\x15\x34\xb3\x42\x99\xdb\x27\x38\x8a\xad\x33\x2d\xb9\xe0\xfb\xd1\x8d\x0c\x8e\xa8\xa1\xe9\xe0\x77\x63\x8f\x5a\xa1\xfd\x6f\xca\x26\xbd\x7b\x0a\x4b\xa3\x3e\x71\xee\x67\xa3\x2f\x92\x14\x72\x90\xc3\x1c\xe7\x14\x95\x55\x22\x3f\x90\x9c\xac\x69\x81\xea\x62\xca\x36\xdb\x44\x96\x91\x33\x3a\x63\xeb\xb4\xe8\x75\x4e\xcb\x55\x72\x67\x59\xea\x07\xd8\x56\xf9\x1f\x56\x0c\x51\xcd\x4d\x41\x7c\xab\x71\x3d\x66\x2c\x2e\x15\x74\xc3\x02\xdb\x60\xfa\xc8\xc2\xef\x24\x46\xfa\xbc\x78\x25\xc4\x82\x7c\x12\x22\xa5\x9c\xeb\xa7\x58\x4c\x6b\x5d\x8e\x52\xbd\x08\x60\x5e\x45\x49\xd8\x32\xb5\xd5\x2a\xd5\x3c\x4e\x23\x5e\x90\xe5\x92\xe6\xca\x7b\xab\xeb\xfe\x0b\x68\xfd\x9a\xcd\x79\xcf\x45\x58\x0b\x06\xaf\xb8\xb0\x53\xd1\xae\xa1\x90\x71\xbb\x92\x38\xfd\xc9\xdc\x34\x08\x2e\x2f\x73\xba\x8c\x12\xe2\x3e\xd7\xe0\xc1\xfc\x4b\x68\x5f\xf4\x5e\xff\xa1\xdd\xd6\x0f\x69\x7c\xf9\xba\xf7\xfa\x22\xe8\xbe\xee\xbd\x7e\xfd\xc7\x20\x08\xea\x9f\x58\xb2\xe8\x7b\xac\xfb\x89\x37\xd7\x52\xf6\xff\x59\x83\x05\x2a\xfe\xdc\x71\x42\xee\x49\xd8\x54\x93\xb8\x57\x74\x2a\x9b\xad\x5d\x7e\x7c\xab\x03\xfe\x87\xa6\x82\x5d\x62\xac\x40\x98\xf1\xfa\x09\x85\x70\x6a\x62\xd3\x30\x19\xfb\x26\xbc\x91\x5e\xf1\xbd\x85\x9d\x4f\x23\x90\xf2\xe2\x82\x0a\xd7\xad\xa9\x2e\x2b\x39\x6d\x3d\x9c\x4b\xe9\xf9\xb9\x58\xed\xb3\x6e\x91\x0e\x9d\xa7\x3d\x40\xa1\xda\x71\x15\x65\x89\x8d\x2c\x25\x2e\xbc\xc2\x18\x1c\xda\xa8\x61\x08\x22\x16\xa2\x39\xa5\x4f\x01\x46\x77\x09\x2b\x07\x1f\x46\xda\x24\x2c\x62\x05\x64\x8f\x34\xcf\x59\x4c\xcf\x4f\xc3\x3c\x5d\x31\xdf\x5f\x68\x95\x1d\x9d\x84\x8a\x2e\x4f\x12\x16\xf2\x01\xca\x74\x0b\x0f\x68\x23\x58\x58\xfd\x58\x62\x30\xc3\x38\xbd\xaf\xa4\x0e\xf2\x15\x42\x46\x3e\x0f\x20\xcc\xa4\x25\xe5\xfa\x85\x1a\x27\xe0\x06\x1f\x4b\x90\x3a\xcb\x76\x13\x93\x82\x0a\xf6\x85\x79\x64\xa8\xab\xf9\xbf\xf5\xf6\xe0\xe5\x21\x8e\xd2\x08\xc0\x5e\x4d\x2a\xef\xc1\x57\x46\xea\x91\x06\xae\x6c\xca\x88\x7d\x6e\x64\x30\x34\x62\x9d\xc5\x8d\xdc\x70\x5f\xaa\xcf\x71\x6b\xdf\x5f\x90\xfd\x13\xe9\xb6\x96\xee\xf6\xf2\xd4\x63\x68\xaf\x1e\xa3\x25\x16\x57\x09\x90\xd4\x92\x9f\x8d\x68\xd7\xd5\x14\xd1\xb9\xa4\x69\x8c\xab\x97\xac\xf0\xbc\x82\x13\x28\x2e\xa7\xc7\xd3\xdc\x21\xd2\x7a\x3e\x3e\xe9\xec\x1f\xf7\xf5\x9a\x4f\xc4\xa6\x17\xc3\x19\xab\xa9\x57\x17\xd4\xf4\x02\xc1\x0b\x20\xd6\xbe\x83\x93\x87\x25\x8d\x75\x7c\xe2\xa4\x89\xa9\x57\xb0\x0a\x6b\x42\xeb\xba\x2a\x6a\x37\xc7\x61\x53\xcd\xb9\x54\x5e\x64\x6c\x46\x28\xef\x0d\x4a\xbf\x1a\xf0\xa8\x7f\x17\x4e\xae\xc3\xf6\xba\x57\x1e\xaf\x52\x49\x6e\xff\x73\x90\x87\xa4\xb2\x57\x8d\xf2\xb3\x70\xb4\x3d\xb0\xf0\x79\xda\xd1\x36\xd5\x11\xcf\x7a\x7e\xe6\x84\x8d\x63\xce\xd5\x2f\xda\x76\xea\x0d\x38\xdf\xb7\xa7\xf2\x87\x97\x54\x39\x2b\x6f\x96\x76\xa0\xfc\xe9\x73\xa8\x9d\x2f\xa4\xd9\x55\x40\x57\xaf\xdb\x99\x66\xa0\x00\xfa\x9f\xa2\xdd\x1d\x64\x0d\xd2\xdc\x3c\xf1\xf4\xff\x1b\x6a\x79\x7b\xf9\xca\xb1\x7a\x5e\x05\xcc\x57\xb5\xd0\x7f\x41\x85\x6f\x3f\x7b\x7c\x51\xb5\xac\x96\x9b\xd5\x2b\x66\xf5\xb4\xf3\xbb\xa8\x66\x27\xc8\xd2\x67\x2a\x67\x35\x48\x80\xd9\x47\x2f\xaa\x96\xbd\xa4\x52\x54\x2f\xa6\xca\x6a\xd1\x91\x67\xda\xa4\x18\x75\xbb\x71\x9e\x6d\xb4\x93\x0a\x13\xbe\x34\x23\xc5\xfd\xcb\x30\xa5\x98\x26\x54\x95\x17\x20\x9b\x4d\x9e\x6d\x72\x86\xec\x01\x1d\x83\xa7\xd4\x12\x10\x93\x79\x4a\x1f\xaf\xe1\x9c\x59\x12\xd3\x7c\x56\xac\x48\xea\x3e\x60\xe6\x27\x1a\x6a\x24\x81\x86\x17\xd4\x6a\x4b\x31\x02\x3e\xc6\x45\x23\xe9\x2f\x73\x07\x97\xbf\xa1\x17\x2d\x66\x6b\x9a\x72\x01\x76\xfd\xfe\x1a\x94\xc2\x51\x58\x5a\xfc\xfc\x8b\x5b\xd8\xb1\xbe\x0c\xa1\xfb\x48\x96\xbb\x98\x46\x35\xee\x14\x77\x9b\xcf\x52\x1c\x88\xfd\xa1\xfa\xd6\xa7\x5d\x8d\xdd\xbc\xea\x6f\x33\x53\x11\x22\x66\xef\xa0\x52\x54\xab\xbf\xb8\xd3\xc6\x5e\x71\x24\xb5\xd5\x0a\x10\xed\x7e\x67\xce\xbb\xab\x33\xf5\x40\x42\xcf\xbe\xbb\x06\x2b\x35\x98\x89\xdc\xac\xed\x60\x17\x19\x63\x0c\x67\xec\x0c\x21\xa3\x76\x56\x3d\xfd\xc2\x94\x24\xfc\x55\x4f\x96\x58\x34\x75\xf7\x1c\x7f\x28\xe6\x25\xc1\xca\xbd\x58\xba\xaa\x1e\x97\x09\x14\x17\x5b\x86\xfe\xe4\xda\x55\x55\x3d\x58\x12\x28\x30\x5a\xc0\x39\x92\xb6\xa9\xa2\x18\xd4\x89\xec\x87\x34\x7b\xc2\x77\x6a\xe4\x20\x78\x09\x09\xd1\xb6\xe8\x66\x8b\x85\x79\x48\x96\xa5\x4b\x6e\xde\x8a\x15\x54\xb4\x51\xf2\x5b\x1d\x85\x07\x29\xa6\xde\x64\xea\x15\x99\xfc\x5e\x90\xf5\xa6\x9d\x93\x74\x49\x67\x34\x8d\x9d\x00\x18\xbb\xca\x03\xa7\x24\xad\xaf\xe8\xa8\x03\x92\xd6\x9c\x7d\x49\x1e\xa2\x08\x0f\x2a\x92\xc1\xb6\x51\xa4\x5a\x30\xb3\x92\x23\x0f\x7c\xc6\x13\x16\x51\x88\xb9\x3c\x77\x6e\xc6\x2b\xb5\x30\x23\x77\xbb\x66\xd3\x42\xf1\xb1\x0f\xda\x72\xf5\xe0\xad\xf8\xf8\x48\x73\x59\x3f\x1b\xfe\xe2\x9d\x9a\x7d\x11\x3d\xe1\x99\xed\xeb\x22\x56\xcc\x7b\x1e\xbb\xb8\xaa\x61\x21\x02\xbd\x62\xde\xb3\x0b\xf9\xcb\x55\xf3\x69\x6d\x53\xf6\x71\xb6\x66\x51\x9e\xc9\x9a\x9a\xbc\x6d\x57\x14\xf8\x98\x68\x07\xbc\x09\x6b\xf1\x71\x70\xeb\x6e\xa7\xb6\x4e\xa2\xba\x85\x47\x77\x58\x4d\x91\xbe\x6e\x37\x5a\x11\xac\xea\x4f\xec\xa3\x3d\x54\xbe\x5b\xac\x1e\xff\x5d\x49\x6c\x84\x4d\x26\x0e\x1a\x11\x54\x3e\xda\x83\x85\x35\x78\x01\x9c\xad\x59\x42\x72\x73\x9f\xa2\x9f\x6e\x7a\x12\xa3\x31\xae\x71\x19\x6b\x97\xcb\x6c\xf1\x05\x4b\x0a\x99\xde\x47\x92\xc4\x3c\x6d\x2f\x9a\xe3\xc8\x73\x4a\x53\x8f\x02\xba\xdd\xf9\xb6\x30\x89\xc8\xe9\xb9\xac\x85\x8a\x0f\x91\xe2\x78\x72\xb9\xf2\xb1\xf4\xd4\xbf\x7c\xde\x79\x3d\xe4\x25\x2f\xa7\x45\x5d\x80\x9a\x7b\x4f\x6a\xa3\xce\x06\xd3\xef\x60\x93\xa1\x04\x26\x49\xb2\x9b\xa1\x7c\xd3\x6f\xee\x4e\x4a\xb9\x2e\x9a\x6b\xa2\x79\x12\x15\xa5\xe8\x33\xfd\x57\xbe\xec\xc4\x5b\x4e\xaf\x85\xc4\x3d\x64\xcb\x7f\xc1\xf7\x00\xbd\x5f\xc3\x1f\xaf\xc3\x0f\xd3\x97\x9e\xf8\xad\xf3\x12\xa1\x5e\xc9\x37\xce\x4a\x82\x0e\x44\x59\xba\x60\xf9\x9a\xc6\x47\x41\x65\xcf\x9a\x1a\x00\x5c\xb3\xb4\xe1\x68\xaa\xdf\xe3\xab\x49\xb1\x51\x33\x5d\x54\x7f\xc1\x69\xaa\xf7\xe5\xf2\x7d\x52\x1b\x8f\xe0\xff\x29\x16\x60\x9b\xf4\xdc\xc8\xd1\x86\x45\x3b\x6d\x0c\xe8\xde\x5e\xf9\xb0\x33\x7f\xd2\x0a\x94\xac\x97\x6c\x36\x82\xd6\xff\x20\xcb\xd5\x24\xec\x81\x26\x3b\x1b\x69\x97\xad\xa9\x64\x61\xbc\x20\xb9\x34\x7e\x0b\xa0\x24\x4f\x18\x16\x80\x63\x6b\x5a\x1d\xdd\x70\x12\x5c\x84\x96\x69\xde\x9f\x73\x9f\x6d\xfe\x02\xf7\x8c\xa5\x62\x18\x37\x1c\xee\x4d\x78\x17\x0a\x0a\x12\x5a\x65\xc3\xf5\x7d\xab\x0c\x4f\xdf\x32\xb3\xd0\x92\x37\xed\x75\x28\xe5\xa6\x40\xb9\xc1\xae\x9d\x72\x7e\x6f\x25\x98\x56\x66\x77\xa9\x7f\xdc\x0c\x26\xd3\xc1\xb0\x54\x53\x8e\x07\x40\x78\x39\xb5\x44\xe1\x8b\xbf\xf7\xc0\x0f\xa4\x70\x35\x08\x57\xa3\xed\x38\x3a\x58\x20\x65\x70\x35\xe0\xd4\xb1\xc9\x31\x56\x93\xd3\x0d\xc9\x85\xc2\x8d\xa3\x4b\xbf\x46\x26\x54\x8d\xeb\x69\xe8\x54\x43\xb0\x49\x40\xff\xc4\x29\xfd\x27\x35\x94\x13\x64\x92\x67\x4f\x5c\x2f\x5b\xbe\xe3\x2c\x76\xa7\x3e\xf4\xea\xb8\x5e\x25\xde\xa3\x74\x02\x2a\xd0\xa2\x89\xa8\x2b\x80\x33\xc0\x53\x40\x3e\xbb\xb0\x00\xe6\x6d\x9b\x86\x53\xa5\xb3\xcf\x46\xda\x5e\xf0\x88\xc2\xaf\xfd\x24\xee\x35\x52\x19\x27\xf0\xcf\xff\x2c\xd1\xe7\x67\xf9\xef\x9e\x5e\xfb\x2f\x27\x53\x91\xf9\x2f\x45\x2e\xfb\x2b\x9a\x40\x03\xa5\x7c\x59\x4b\x21\x0a\x89\xdf\x34\x23\x67\xd0\x14\xf6\xac\xab\xec\xe2\x38\xca\x56\xb3\x4a\xf2\xd5\x5b\x1f\xc3\x1d\x05\xfb\xea\xad\xaf\x60\xbb\xe8\x7f\xf5\xd6\xd1\x67\xde\xd8\x08\x16\x65\x3d\x1f\x17\xc6\xd2\xea\x76\x47\xba\x02\x80\x0c\x25\x90\xd5\x39\xb8\xb4\x22\xd6\x24\xc7\xe4\x86\x25\x7b\xa4\x1c\xb6\x5c\x26\xd2\xc9\x14\x3b\x96\x0a\x72\x2a\x48\x21\x8b\x40\xa3\x6d\xcb\x16\x0b\x9a\xd3\xb4\x68\x75\xbb\x26\xda\x0a\x23\x46\xcd\x2f\xb6\x07\x7f\xd6\x4d\x02\x17\xfb\x2d\x66\x82\x3c\x67\x16\x9c\x6d\x27\xdb\x1b\x4b\x64\xd6\xdf\xb5\x5b\xb7\x30\xb8\x4f\x58\x7d\xe9\x33\xa3\x7d\x37\x02\x16\x65\x6a\x09\x48\xd3\x4e\x35\x6b\x95\xaf\xb2\x27\x7d\xf4\xd6\xc6\xba\x7a\x6b\xde\xe2\x19\xc8\x67\xb1\x4b\xc7\xbd\xf6\xde\xc8\xae\xd2\x83\xfe\x73\xd1\x62\x38\xfa\xa1\x1d\x40\xf7\xa4\xdb\x18\xdf\xc9\xe6\x56\x8a\x50\x58\x21\xcf\x1c\xd5\x77\xb7\xf6\x50\x41\xf2\x47\xe2\x95\xd7\x76\x95\x6a\x8c\x4c\x69\xb8\x7d\x78\xd6\x7d\x43\xd3\xe9\xd7\x25\xeb\xa8\x42\x63\x9b\x3c\x8b\x68\x8c\x4a\x6a\xe6\x94\x84\x9e\xef\x20\xca\xb3\x54\xe3\x60\xe5\x19\x5f\xf4\x21\xb9\xf4\xaa\x93\xe9\x5c\xbf\x48\x0e\xe3\xf0\x7a\x34\xbe\xf1\x5f\xa6\x88\x33\x4c\x4e\x4b\xb2\x6c\xa3\x88\xe9\x81\x6d\x74\xb8\xa2\x51\x9e\x45\x13\xf9\xb4\xb7\x2c\xb3\xd1\x0c\xd5\xdb\xd1\x18\x72\x18\x0c\xcb\x88\xbb\x1f\x6d\x8f\x20\x19\x64\xa8\x3a\xe1\x06\xec\xeb\x0b\x6a\x1d\xdc\x3e\x7e\x50\xb8\xac\x0b\xb2\x54\xf2\x01\x6b\xd3\x83\xc3\xe4\x9e\x49\x4f\x6b\x69\x81\xe6\x3d\x57\x37\x18\x8d\x61\x38\x82\xbf\x86\x3f\x19\x9f\xe5\x5f\x07\x1f\x30\x38\x33\xbc\x71\x2a\xd9\x5f\x8f\x86\xd3\xc1\xf0\x3e\x94\x99\x25\xa6\xbc\xba\xd3\xa2\xa1\xce\x79\x8d\x0f\x2d\xf7\xab\x1d\x3c\x83\x98\xf2\xb2\xc7\xda\x2e\xf3\xfd\xfb\xc1\xd4\x1a\x87\x32\xcf\xe5\x77\x3e\xe3\x7f\x60\x48\x84\xe2\xc8\xce\xce\xa0\x2c\xaf\x3c\x67\xef\x31\x94\x3a\x98\xc0\xb9\xf8\xc2\xe5\x3d\x8d\x17\x09\x6c\x02\x90\x1d\x6f\x6f\x96\xb0\x68\xd7\x93\x69\xe0\x96\x5f\xa8\x5a\xf2\x73\x8a\xb7\x39\x39\x5d\x6e\x13\x92\x27\x3b\x29\xf8\x04\xf3\x80\x5f\xb3\xf9\x09\x4e\x7c\xc6\x67\xbc\x20\x09\x9d\x09\xa9\x4a\xf3\xb6\xbc\x14\x54\x6f\xd8\x6c\x72\x1a\xe1\x13\xc8\x87\x9c\xf7\x0a\x31\x16\x49\x46\x8a\x7f\xe1\x34\x8d\xdb\xea\xea\xf2\x0a\xce\xff\xff\xc7\x3f\x2f\x16\xaf\x9d\xbf\xaf\xcf\x4f\x7b\x6e\xf6\x90\x53\xbd\xbc\x85\xea\xe2\xbd\xb4\x80\x7c\x4b\x75\xc9\x4f\xb9\x59\xc6\x81\xc0\x87\x1c\x4d\x2c\x2a\xd4\x09\x31\x18\xc8\xc1\x8e\xce\xdb\x3c\xb8\x88\x67\x5f\x3f\x33\x3e\x4b\x85\x3c\x4e\x66\x29\x49\x5f\xea\x7c\xfe\xc5\x39\x9f\x8b\xcf\x7f\x3e\xce\x06\x9e\x75\x3a\x43\x32\x3c\xe5\x24\xf6\x4d\xf7\xec\x73\xf0\x02\x6f\x4d\xb9\x29\x4c\x93\x00\xb7\x62\x76\xf8\xe3\x9e\xe2\x9a\xb8\xa7\xc6\x0b\x82\xa6\xba\x86\x5e\x0d\x83\xcf\x94\xe4\xac\xa2\x2b\xab\x39\x33\x32\xcd\x41\x02\x5f\x3d\xd2\xae\x4b\x18\x1c\x7d\x06\x7a\xf0\xe7\x00\xdb\xe5\xe1\xb6\x06\x8d\xf3\xd8\x1c\x46\xa9\xe3\xf3\x1f\xe6\x67\x55\xba\x92\xc5\xb6\xbe\x34\xaa\x42\x6a\x5b\xf8\xd4\x67\xdd\x2d\x12\xe3\x33\xf3\x4a\xcc\x3c\xcb\x12\x4a\x52\xab\x36\x79\x36\xae\xac\x37\xd3\x1f\xfe\xd4\x96\x56\xe1\xb9\x30\xf0\x85\x6a\x8c\x80\x12\xff\x61\x53\x86\x3b\x70\xae\xd2\x89\x7e\x11\xeb\x70\x2f\x4f\x9c\x09\x51\xc8\x0e\x6e\xbd\x35\x18\xe7\xad\x9d\xf4\xf2\x4a\x8d\x26\xdf\xf9\x37\x3f\x08\x39\xe5\x38\x73\xc5\x40\x4e\x7f\x65\x75\xb5\x8f\xab\xb4\x64\x01\x19\x04\xbd\xea\xab\x95\xe6\xa1\xa1\x4f\x18\xb5\xf2\x84\xa0\xbb\xfe\x17\xc8\x6e\x38\x80\x39\x12\x5f\x34\xb2\x7c\x4a\xb2\x56\xcd\x8b\x36\x86\x6e\xcb\xf5\x78\xeb\xaf\x38\x6b\xaf\x37\x9d\xe4\x10\xb1\x03\x99\xbd\x85\xba\x89\x98\xc2\x0e\x89\x9f\x9a\x52\xb2\xca\xdc\xe7\xbc\x83\x38\xc4\x0b\x61\x02\x63\x05\x16\xcf\x78\xd3\x69\xed\xe7\x65\x52\x56\xae\x4d\x89\xd4\x3f\xbf\xe2\xbf\x60\xed\x2a\x61\x1a\x6e\x32\x8e\x2f\xda\xd6\x86\xba\x1d\x38\x03\x0c\x71\xc2\xab\x09\xc7\xb6\xeb\x80\x20\x1f\x6b\xb0\x6d\x32\x1e\x54\xae\x20\xcb\xc0\xd9\xcf\x50\xf7\xd4\x29\xae\x29\x4c\x55\x3d\x4f\xaf\x81\x30\x95\x04\x59\xfe\x2f\xf7\x75\xaf\xf2\xf3\xd3\x5e\x4a\x50\xdd\x1d\xaa\x39\x43\x27\x65\xa8\x71\x13\x35\xd1\x7f\xe5\x1a\xf7\xfb\x17\x5d\xca\xc8\x17\x9a\x76\x7f\xea\x66\xe4\x57\xb1\xfd\xfb\x41\xf8\x83\x5e\x87\xeb\x4f\xeb\x4f\x4a\xc6\x80\x87\x40\x78\x75\x6a\x7d\xba\xbe\x6b\xa0\xe4\xac\x15\x7f\xaf\xbe\x3e\xe3\x9e\x2d\xe1\xfb\x2d\x1a\x7c\x7a\x66\x0a\xe9\xf4\x3a\xeb\xb8\xe0\x2c\xa3\x86\xd2\xef\x9f\xe1\x85\x7a\x5e\x12\xbf\x65\x2f\x9f\x83\xab\xa8\x43\xfc\x1d\xb8\x8a\x73\x3d\xfe\x62\x6c\xa5\xc2\x46\x3e\x1b\x17\x11\xe7\xfa\x0f\xc8\x44\x9c\xe3\x7b\x01\x26\x52\x9b\x78\xf8\x19\xb8\x48\xc3\xaa\x3f\x91\x8b\xbc\x0f\xc5\xaa\x8f\xe1\x22\xc2\x10\xee\xe1\x9d\x15\xe1\x78\x77\xd5\xa9\xfe\x2c\xd5\x53\xc2\xa5\x9e\x5a\xd3\xc0\xb9\x86\x6b\xe4\x48\x1e\x3e\x3e\x8f\x31\x19\x8e\x24\x26\xf5\x1a\x55\xea\x2a\x36\xf3\x31\x8c\x76\x50\x8b\x41\x55\xdf\xdf\x41\x60\xf8\x9c\x7b\xe2\xff\x79\x8c\xce\x65\x4a\x0d\x8c\xee\xf0\xeb\x46\xf0\x3d\xa3\x4f\xb5\x2f\x23\x1d\x7c\xf2\xc8\xc5\xaa\xc1\xf0\x76\xa4\x51\x5b\x62\x95\x8b\x50\x6b\x5b\xf2\xd3\xc3\x75\xf3\xcd\x01\x68\xcb\x3d\xda\xd3\xdd\xd3\x02\x17\x2b\xf1\xff\x38\x66\x25\x75\xba\x31\x38\xc4\xe4\x47\x98\x3c\x8f\x84\xa6\xcb\x62\xe5\xd7\x9d\x3c\x18\x34\x55\xf2\x61\xd6\x87\x4f\x55\x5d\xe3\x5e\xe4\x13\x94\xae\xb5\x70\x7f\x7e\x16\x87\x82\x58\x7d\x05\x4d\xb7\x2a\xe7\x89\x3c\x15\xf6\xf0\xd5\x75\xed\x5b\x11\xb6\xca\x27\xdd\x05\x6e\x8a\x35\xb6\x58\xb1\x5e\x91\x15\x24\x99\x71\xf6\x77\x64\x19\xe2\xff\xd5\xd1\x5c\xf4\x5e\x43\x17\xda\x9b\x25\xfe\x38\x9b\xef\x0a\xca\xdb\xd1\x8a\xf7\x74\x5a\x2e\x8d\x67\xb2\x33\xfe\x14\x5c\x5e\xa6\xdb\x35\x15\xc8\xf6\x15\x54\x3b\x6d\xd3\x43\xdd\x82\x00\xbe\x84\x8b\xd7\xaf\xf5\xe3\xdc\x2a\xf3\x77\x96\x93\x82\x65\x72\x4d\x62\x20\xd9\x57\x7a\x0e\xed\xd7\x74\xbb\x9e\xd3\x7c\xe6\xcc\xa1\xb3\x8a\xed\x60\xe6\x63\xeb\x08\x31\x60\x59\x94\x8f\x91\x52\x60\xb3\x2c\xf5\x62\xf7\x58\xb5\xd6\x6b\x5b\xc0\x16\xf1\x4a\x3d\x71\x5c\x1b\x7a\xc7\x4a\xb1\x77\xbd\x52\xa9\xa6\xc3\xcb\x70\x76\xe7\xe0\x32\x2f\x48\x21\x90\x92\xd7\x2c\x4c\xc0\xcb\x69\xaa\xa6\x3e\xe5\x5e\xcb\x66\x3b\x97\x16\x79\x30\xc8\xb1\x0e\x4e\x27\x07\x28\xf6\xfc\x4a\x0d\x47\x70\x41\xe9\xa4\x41\x26\xe8\xf0\x40\xf9\xd6\x7e\x47\xff\x77\xf5\x25\x7f\xfd\x8b\x6f\xf6\xcb\xcf\x5e\xfc\x84\x14\xb9\x7b\x9e\xc6\xd4\x54\xab\xdf\x6c\x91\xaf\xfc\x1b\xe2\xf4\x6a\x0f\xf7\x27\x60\x0a\x1f\xec\xe5\x0f\x90\x3c\x48\x79\x82\x01\x10\xca\xfb\x8d\x15\x53\xd3\x64\x27\xe3\x26\xd1\x13\xce\x52\xce\x62\x19\x96\x56\xe4\x24\xe5\x04\xeb\x0e\xf4\x60\x50\x9c\x73\x60\xeb\x4d\x96\x17\xfa\xa1\xfc\xe2\x63\x0a\x34\x8d\xb9\x0a\x71\x8b\x48\x92\xc8\xc0\x69\xa7\xde\xaa\x98\x30\x83\x9c\x26\x94\x70\x59\xd5\xe3\x94\x9b\xe1\x98\x26\x64\x37\x73\x09\xfc\xd7\x6c\xde\x5e\x15\x2a\x58\x39\x55\x6f\xfa\x3e\xcd\x30\x9a\x07\x4c\x08\x67\xf1\xf7\xc0\xe8\xf1\xdf\x8f\x06\x37\x95\xdb\xbd\xf9\xf2\x49\x0c\x55\xa3\x92\x3b\xbc\x57\x35\x40\x55\xd1\x76\x68\x7d\xf1\x45\x8d\xf4\xc8\xd2\x05\x5b\xf6\x44\x2b\x79\xdf\x60\x16\xad\x79\xcb\xa6\xf5\xc5\x17\x87\xe2\x37\x4b\xc8\x2f\xd0\x5d\x86\x48\xf9\x92\x27\x68\x7d\xf1\xc5\x31\xf1\xba\x42\x3b\x2e\x91\x83\x06\x9d\x73\x79\xd6\xed\x12\x0c\x23\xfc\x35\x9b\x63\x70\x42\xbc\x4d\xa8\xae\xda\xa6\xee\x07\x93\x9d\xfb\x52\x7a\x97\x93\x05\x85\xb6\xb3\x01\x60\x9c\x6f\x29\xfc\xef\xaf\x2f\xfe\xf4\xc7\xa0\x72\x17\xb5\x59\xce\x48\xfc\xc8\x78\x96\xef\x66\x1f\x49\x54\xcc\x04\x16\xb4\x2f\xbe\xfe\xe6\xcf\x7f\xee\x38\x70\x15\xd4\xf9\xc5\x17\xba\x13\xae\x09\x7f\xd1\x6b\x6a\xdb\xa6\xea\x1d\x67\x3c\xf4\xab\xb7\xef\x10\x9d\x26\xd3\xb6\x41\x04\x5b\x78\xc4\xb6\x93\xd4\xd1\xc4\x18\xd5\xa1\x49\x4e\x28\x61\xab\x4e\xff\xca\x5d\x62\x10\x34\x96\x5f\x92\x25\x24\xa9\x5b\x86\x42\x13\x19\x79\xa0\xb0\x49\x08\x3e\xec\x02\xc4\xb9\x83\x72\x62\x9c\x65\x6d\x78\x8c\x74\x96\x37\xc6\x2b\x9a\xc4\x40\xa2\x3c\xe3\xbc\xd5\xed\xc6\xd4\xc7\x27\x24\x36\x6e\x42\x9a\x88\xf3\xf8\x51\x21\x5f\xc3\xcf\xd6\x14\x56\x94\x3c\x32\xf5\xc6\x3c\x07\x15\x94\x4a\xd3\xb8\x2e\x11\xa1\x72\xf3\x66\x69\xb0\x84\xca\x33\x24\xf7\x76\x25\x6d\xa0\x03\x6b\x96\x56\x12\x06\xea\x6e\xd6\xa5\xfe\x93\x67\x4f\x7b\xc2\xa2\xa5\x16\x6e\x03\x84\x1b\x5b\x9b\x26\xb2\x87\x43\x29\x8d\x5d\x6c\x1b\xd9\x47\xaf\xdb\x28\x91\x95\x72\x97\x86\x27\xac\x7a\x5f\x7a\xb6\x63\x69\xba\x13\xa2\xf7\x55\x8c\x68\x53\x34\x3d\x0a\xb3\x3d\xd4\xed\x5b\xb9\x71\x69\x59\x3e\xdc\x8e\xd2\x76\x75\x0a\x40\x59\xc7\xf5\x36\x28\xf8\x91\x11\x44\x2c\x2e\x05\x52\xef\xd7\xcb\x05\x80\xb5\x72\x9e\x92\xa4\xad\xa1\x1e\xf8\x09\x20\xe5\xb3\x70\xee\xd0\x2d\xde\x54\x99\x75\x54\x0e\x4c\x38\x32\x42\xde\x74\x7a\x66\xb0\x7e\x6d\x50\xbd\x30\x3d\xb9\x6b\x1c\x1c\x35\x38\xe8\x01\x75\xd4\x3f\x5c\x41\xe4\x8e\xa2\xec\x10\x3f\x90\xde\x3b\x6a\xb7\xb5\x2a\x41\x29\xc3\xdf\x91\x07\xca\xf0\x74\x96\xaa\x00\x7d\xd3\x52\xa0\x5a\x95\x06\xde\x5e\xd9\x70\x7c\xec\xee\xb5\x8f\x7a\x65\x55\x19\x65\xe5\x04\x03\x34\x4c\x3e\x99\x67\x55\xd4\x8c\x86\xc1\x0b\xce\x8a\x31\x42\xc5\x26\xb1\x9b\x5c\x82\x9e\x69\x53\x8a\xbb\x3c\xc7\x5a\x5e\xa8\x87\xbe\x31\xb5\x63\x04\x20\x1c\xd2\x71\x3f\x7b\xda\xa0\x9d\x57\xdd\x6e\xca\x27\xe4\x99\x50\x75\x78\x81\xcf\xc5\x9a\x3d\xf6\x2a\x72\xed\x38\x8c\x73\xf3\x41\xec\x19\xea\x0c\x0f\xb3\x2e\x15\xe6\x7c\x10\xaa\x8e\xe0\x1e\xdc\xca\x40\x18\x3f\x53\x41\xfc\x8d\xfb\x83\x09\x06\x82\x0e\xae\x43\x38\x9f\xea\x55\x75\x9d\xbb\x79\xc6\xc1\x32\x76\xa1\xb5\xe1\x64\x97\xf0\xaa\xf7\xea\x34\x30\xbe\xf1\x66\x36\xb1\x90\x25\xa1\xd1\x2e\x5b\x0c\xa7\x1c\x94\x63\x41\xd8\xd9\xbc\xfc\x0b\x78\x46\xd4\xc9\x7f\x04\x00\x00\xff\xff\xd4\x52\x86\x31\xe6\xb4\x00\x00"),
+			compressedContent: []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\x03\xed\x3d\x69\x77\xdb\x56\x76\x9f\x87\xbf\xe2\xb5\x95\x4b\xc2\x21\x19\xcb\xe9\x64\x5a\x79\xe4\x73\x18\x89\x72\xd8\x91\x49\x57\xa2\xe2\xa4\x69\x0e\x07\x22\x41\x11\x31\x08\x70\x00\xd0\xb2\xe6\xf4\xc7\xf7\x2e\x6f\xc5\xc2\x4d\x52\x66\x7a\x5a\x9d\x99\x58\x02\xf0\xb6\xfb\xee\xfe\xee\xbd\xaf\xd3\x19\x8e\xc6\xfd\xeb\x46\xa7\x33\x5e\x84\x99\x98\x26\xb3\x40\xf8\x59\xb6\x5e\x06\x99\xc8\x17\x7e\x2e\x72\xff\x36\x0a\x44\xec\xe3\x83\xa9\x1f\x8b\x24\x8e\x1e\xc4\x6d\x20\xbe\xfd\x46\x4c\x17\x7e\x9a\x89\x28\x89\xef\x1a\x8d\xc6\xd9\x55\xbf\x37\xee\x8b\xd1\x95\xb8\xea\x7f\xb8\xec\x9d\xf5\xc5\xc5\xcd\xf0\x6c\x3c\x18\x0d\xc5\xf5\xd9\xf7\xfd\xf7\xbd\xc9\x59\x6f\xdc\xbb\x1c\xbd\xeb\xde\x05\xf9\x64\x16\xcc\xfd\x75\x94\x4f\xa6\x8b\x75\xfc\x69\x12\xc6\x79\x90\x7e\xf6\xa3\x96\xd7\x10\xf0\x73\xd5\x1f\xdf\x5c\x0d\xaf\xc5\x60\x38\xee\x5f\xfd\xd0\xbb\x6c\xf4\xae\xc5\xd1\x7c\x1d\x4f\x8f\xe8\xf5\x75\xff\xb2\x7f\x36\x16\xf0\xfd\x3a\x38\x39\x51\x1f\x89\x8b\xab\xd1\xfb\xe2\x50\x72\x18\xf1\xf1\xfb\xfe\x55\x5f\x7c\x0a\x1e\x4e\x9b\xee\x88\xcd\x37\x0d\xd9\xf3\x65\x6f\xf8\xee\xa6\xf7\xae\x2f\xae\xff\xe3\x52\x5c\x8f\x7b\xdf\x5d\xf6\xc5\x87\xde\x55\xef\xf2\xb2\x0f\x7f\xf7\x2e\xfa\x6f\x1a\xef\xae\x7a\xc3\xb1\xe8\xff\xd8\x3f\xbb\xc1\x95\x0e\x0f\x5a\xa1\x18\x8f\xc4\x2a\x4d\x96\x93\x34\xf0\x67\x41\xfa\xe6\x50\xc8\xa5\x41\x1e\xc4\x79\x98\xc4\x93\x55\x90\x86\xc9\xec\xb7\x80\x5d\x71\xcc\xe7\x87\x5e\x79\x95\x65\xf8\x01\xee\x9e\xf9\x71\x12\x87\x53\x3f\x02\x6c\x9c\x7e\x12\x49\x0a\x6f\xc2\xf8\xee\x04\x5e\x2d\x83\x3c\x0d\xa7\x19\xfc\x36\xf3\x73\x9f\xd1\x19\xfe\x88\xfc\xdb\x20\xc2\xa7\x19\x7c\x09\xa8\xbd\xf2\x53\x18\xc8\xf9\x3b\x0f\x71\x60\xec\x7e\x9a\xc4\x59\x9e\xfa\xb0\x89\x19\x76\xd9\x11\xe3\x45\xc0\x93\xe0\xde\xc5\xe7\x30\xb8\x87\xae\x3f\x05\x19\x4d\x20\x13\x61\x0c\xd4\x13\xf0\x44\x4e\x84\x19\xb9\x2d\x8a\xfd\x77\x69\x01\x44\x7c\xd0\xe3\x34\x98\xad\xd3\x40\xcc\xc3\xd8\x8f\xc2\xbf\x12\x0d\x06\x62\x0a\x4b\xc5\x4f\x45\x32\x17\xbe\xe0\x21\xbb\x34\x87\x79\x98\x66\x39\xf5\x05\xef\xf4\x62\x4d\x83\x85\xbf\x5a\x05\x31\x4d\x67\x09\xb3\x93\xd3\x9d\x30\x4d\xfb\xf1\x8c\xba\xa7\xc1\xb8\x13\xf5\xfd\x22\x48\x83\x2e\xf4\xf7\x31\x10\xd9\x2a\x0a\x73\x51\xec\x18\x40\x91\x88\xfc\x3e\xa1\x66\x30\xcb\x44\x2c\xc3\x38\x5c\xc2\x94\x45\xe4\xc3\x96\x4d\x1f\x04\x2c\x04\xb6\x00\xbe\x84\x15\x13\x20\x3b\x9d\xd6\xfd\x22\x9c\x2e\xec\x59\xe1\xf8\xe5\x99\xad\xfc\x7c\xe1\x75\x45\x3f\x5b\x05\xd3\xd0\x8f\x80\xe1\xc4\x49\x1e\xdc\x27\x69\xbe\x78\x10\x21\x33\x26\xe8\xce\xcf\x73\x7f\xba\xc0\x41\xb0\x1b\x0d\x51\x9c\x0d\x3e\x90\x90\xe6\x2e\xed\x95\x01\xfb\x9a\xfa\xeb\x2c\x10\xb0\xb0\x34\xf8\xcb\x3a\x4c\x03\xc4\x04\x60\x6e\xc1\x97\x69\xb4\xce\xc2\xcf\x01\x6d\x63\x5b\xf0\x7c\x61\x44\x5f\x2c\xc2\xbb\x45\x47\xad\x2d\x01\x6c\x24\x40\xf0\x36\x24\x30\x5c\x2a\xfc\x29\x3e\xc1\xd9\x85\xd8\x1d\x12\x06\x4d\x67\x96\xc0\x2c\x00\xbd\x70\x11\xc0\x5b\x01\x88\x30\x4d\xc2\x55\xee\xad\x73\x1f\xc2\x5c\x6e\xd7\x39\x7d\xe4\x47\x59\x42\x5f\xc6\xc1\x34\xc8\x32\x3f\x7d\x80\xbe\x60\x45\x30\xe2\x3c\x49\x97\x08\x34\xc2\x2a\x5c\x25\xc3\x96\xd1\x8b\x77\x73\xcd\x23\xad\xa0\x33\xb5\x87\xf0\xbf\x21\x40\xef\x84\xd9\xb9\x2f\x10\x99\x61\xd1\x30\x72\x80\x08\x85\xb3\x15\xb3\x20\x0b\xef\x62\x05\x5a\x1b\x7a\x06\xaa\x08\x05\x02\x78\x30\xe3\x19\xb9\x5f\x01\xf5\x08\x7f\x0e\x5c\x8e\xb7\x15\xbe\xce\xf2\x60\x85\xf0\xc1\x39\x29\x04\x5a\x02\x14\x73\x5a\xde\x2d\x36\x0e\x10\x93\x44\x96\x2c\x91\x24\xa7\x69\x92\x65\x0a\x85\x61\x82\xd4\x73\x4a\x0d\xfc\x7b\xff\x01\xbb\x4a\x00\x50\xea\x0d\x0e\xd9\xcc\x61\x39\xcb\x25\x62\x7a\x72\x1f\x7c\xc6\x4d\x90\x48\x3d\x0b\x22\x1f\x21\x17\x22\x9a\xe1\xe2\xc2\x39\xc0\x1c\xe6\x08\xe3\xad\x52\xdc\xaa\xa9\x82\x0e\x6e\x75\x47\x52\xaa\x1c\x5d\xd2\x2a\x02\x76\x52\xa2\x5b\x58\x69\x99\x8c\x2b\xb8\xf8\x87\xab\xd1\x59\xff\xfc\x06\x98\x67\x81\xc1\x29\xea\x56\x48\xaf\xa8\x0a\x58\x38\x72\x6b\x64\x03\x47\x8d\xf3\xfe\xd9\x65\xef\xaa\x4f\x5c\x3b\x85\x3e\xcf\x46\x57\xe7\x6f\xe8\x2f\xfa\x3c\x98\x89\xdb\x24\x89\x02\x3f\x7e\xd3\xf8\xae\xff\x6e\x30\xa4\x57\x17\x30\x7c\x2a\xe4\x1f\x16\xbf\x7f\xa9\x1f\x54\x71\x7a\x9e\x86\xfe\x84\x19\x3e\xa8\x05\x9a\xdc\x27\x00\xe4\x55\x04\xac\x78\xa6\x3f\x82\xd9\xf4\xaf\xc4\x77\x3f\x89\x14\x20\x9e\x2c\xa5\xf4\xb9\x1c\x8d\x3e\x14\xc7\xde\xd0\x09\xc8\x9f\x91\x5a\xce\x0e\x33\x14\xcb\xc2\x1c\x97\xdd\x70\x26\x4e\x45\x0a\xff\x98\xe6\x00\x82\x9b\x0f\xe7\xb0\x17\x20\x1a\xf4\x40\x17\x1a\x6a\xe3\xef\xfb\x06\x3c\xf8\xd3\xe9\xa4\x01\xc0\x11\x30\x2b\x4d\xee\x89\xee\x9d\xd7\x67\xa3\xf7\xef\x07\xe3\x37\x85\x67\xc3\xf1\x60\x78\xd3\x37\x4f\xfb\xc3\x73\x18\xc4\x1a\x51\xc9\x39\xa4\x59\x3f\x6f\x1d\x1d\x39\x1d\xf4\x2e\x41\xf0\x0a\x16\x93\x72\xa9\x30\xe3\x5e\xf7\xc5\x00\xa0\x36\x16\x2d\xe7\x63\xfc\xc9\x43\xd0\xbf\x80\x67\x04\xb3\xdb\x2e\xc2\x11\x38\x56\xd6\xde\xe9\xab\x49\x16\xdc\x2d\x01\x65\x6f\x1f\x00\x52\x4d\x46\xdb\x49\x38\x6b\xee\xd8\x9a\xe8\x82\xdb\xe2\xfb\x36\xab\x0e\x4d\xa7\xb1\xf7\x46\x1c\x1d\xb5\x61\x1b\x88\x54\x26\xa8\x2b\x7a\x16\x28\x80\xb2\x51\x07\xca\x80\x9b\x22\xf3\x45\xea\x0c\x90\x4a\xa1\x3b\x64\x2a\xb7\x01\xc0\x08\x99\xe3\x7d\xcb\xeb\x1c\x03\x21\xaf\x53\x71\x1f\x46\x11\x72\x08\x35\x0d\x0b\x3d\x3e\xf4\xaf\x60\x8b\xdf\x0b\x7f\x36\x9b\xe8\x59\xf2\x00\x93\x55\x12\x85\xd3\x87\x96\x84\x79\xd3\x85\x6c\xb3\x30\xc3\xb6\xd6\x91\x44\x93\x87\x6d\xba\xb3\x9e\x25\xcc\xc7\xe4\x04\x41\xbc\xa3\xa8\x71\x45\x84\x23\xf9\x40\x40\x7d\x92\x3c\x50\x7e\xec\x60\x13\x63\x65\x0d\x6a\xe3\xb6\x97\x29\x05\xc0\x3e\xbe\xba\xe9\x4b\x74\xd7\xc8\xee\x4c\xf3\x3e\x60\x70\xc5\x41\x30\xe3\x09\xd3\xc4\x60\xce\xa2\x4e\x40\x82\x84\x41\x65\x05\xe5\x1f\x80\xdd\xea\x0b\x56\xe3\x7f\x4e\x60\x1c\xea\x62\xbd\xba\x4b\x41\xc7\xea\x8a\x41\x6e\x49\xad\xd2\x8a\x67\x49\x1c\xa0\xa4\x8c\x02\x16\x7d\xa6\x3b\xea\x05\x85\x0a\x0c\x06\x8a\x8e\x7a\x71\x39\x3a\xfb\x93\x44\xfe\xd1\xf0\xf2\xa7\x22\x44\x24\x6b\x1d\x0c\x45\xef\xec\xac\x7f\x7d\x0d\xb4\x74\x76\x79\x73\x3d\xf8\x01\x08\x1e\xcc\x93\x5d\x89\xac\x82\xc6\x0a\x23\xf4\xc6\xe3\xde\xd9\xf7\xa8\xa7\x8e\x07\xb6\x22\x8a\x08\x33\xb9\xee\x5f\x0d\xfa\xd7\xdd\x17\xc7\x47\x03\xe2\x29\x80\x27\x37\x7d\xd4\xab\x45\xeb\xc5\xeb\xa3\x4b\x4f\x0f\x55\x44\xfd\x36\x6d\x91\x67\x78\x83\xcd\x41\x90\x4f\x20\x93\x7c\xd3\x80\xdf\x40\x7b\x26\x5e\x2f\xb4\xf6\xfc\xe1\xf2\xc3\x3b\xd0\xa0\xdf\x34\xb0\x4d\x1f\xd4\x65\x98\xd4\x21\x62\x64\x70\x2d\x9a\x17\x5a\x87\x2c\x28\x6f\x28\x48\x1d\x6d\x33\x03\xe4\x8f\x66\x48\x6f\xe9\x3a\x16\x40\xec\xa4\x74\x26\x31\x68\x20\x39\x62\xd1\x3a\x4f\x00\xbc\xb8\xf9\xd1\x43\xb3\x42\x91\x3f\x60\x86\x5a\x8b\xbf\x07\xb4\x22\x2d\x5e\xea\xc0\x5a\x6b\x42\x63\x14\x06\x44\x39\xf7\x00\xfa\x03\xb4\xbf\xbb\x03\xc2\x02\x1e\x12\xc3\x9f\x31\xe8\xdb\x72\x59\xf8\x21\xf3\x76\x44\x54\xd2\xe0\x01\x5d\xd7\x2b\xd6\x2d\xf9\x9b\x5f\xd7\xa0\x26\x07\x71\xb2\xbe\x5b\x14\xf5\x26\xd2\x64\xc3\xbc\x2b\xde\xbb\x50\x62\xdd\xc1\x50\x22\xe8\x0d\x1b\x96\xe3\xdf\x26\x9f\x81\x50\xae\x83\x40\x02\x6f\x89\x3c\x17\xd5\x40\xd4\x47\x51\xa7\xd2\x0b\x43\xc2\xc4\x6f\xa0\x71\x06\x46\x0b\x10\x27\x3f\x41\x8d\x89\x74\x6d\x56\xc6\x1c\xd5\x4d\x69\x8a\x19\xa8\xb0\x40\x86\xc0\x7c\x54\x77\x30\x26\xef\x1e\xd9\xe5\xb0\x69\x39\x98\x29\xce\x7a\xa3\xe4\x0e\x84\x3b\xd1\x76\xb6\x5e\xad\x40\x89\x96\xeb\xcf\xf4\x54\xa4\x49\x51\xd0\x85\x6c\xab\x85\xcd\xa5\x2a\xeb\x65\x77\xeb\xb5\xa4\xe7\x17\x4c\x56\xb9\xc5\xf4\xcc\x58\xad\x46\x0f\xa2\x39\x80\xe8\x42\xbe\x6d\x29\x3d\x05\x26\xd0\x94\x13\xaa\x12\xae\x2d\x92\x39\xe3\xc1\xfb\x3e\x98\xa8\xef\x3f\x8c\xff\x93\xb4\x9c\xe1\xcd\xe5\xa5\x94\x6b\xe2\x7c\x74\x43\xa6\x2b\xe8\x5b\x83\x6b\x58\x83\x5a\x31\x0e\xfb\xdd\x00\x46\x1c\xeb\x26\x5e\x85\x08\xc5\x9f\x61\xff\xa3\x2b\x08\xeb\xe7\x78\x33\x1c\xfc\x07\x70\xf7\xc1\xf0\xbc\xff\x23\x2b\x9b\x7a\xb4\x09\x4e\x75\xf2\x22\x13\x2e\x5b\x42\x0d\xa1\xa5\x3f\x6a\x93\x10\xf5\xa0\x03\x60\x90\xe7\x7d\xd1\xa2\x45\x6c\x9a\x18\xb6\xa9\x9a\xa0\x92\xaa\x4c\x49\x93\xc5\x03\x98\x1b\xbc\x47\xb5\xc2\xb4\xd0\x4d\x9b\x15\x84\x9a\xb1\xf5\x0f\xbb\x43\x68\x71\xca\x27\x72\xfa\x76\x0f\xf7\xc9\xb6\xee\x79\xfe\xaa\x75\x18\xcf\x82\x2f\x41\x76\xfa\x76\x0e\x66\x95\x56\x4a\xa4\x62\x5a\x31\x6a\x92\x4e\x64\x0f\x0a\xd9\x5a\xcd\x09\xad\x6f\x32\x91\x4b\x96\x08\x4c\x6b\x6e\x68\x25\xf6\x7a\x7c\x35\x80\x2e\x55\x2b\x66\xf6\x9d\x0e\x9a\xad\x4c\xfe\xca\xe4\x64\x42\xfa\xf9\xf8\x17\xe4\x5b\xeb\x38\x04\x6b\x06\x0c\x47\xb4\x7c\x0c\x51\x65\x64\xc5\x30\x72\xb4\xb8\x81\x47\x56\xcc\xcc\x12\xe2\x8a\x26\xc9\xde\xbb\x5b\xfb\xa0\x87\xe7\xa8\x01\xdc\x45\xc9\x2d\x99\xcc\xdc\x79\x63\xb3\x9c\xac\x23\x16\x47\xfc\xb9\xea\x28\x50\xc2\x6d\x78\x07\x3b\x62\x88\xc7\x79\x2f\x01\x14\x22\xcb\xac\xfb\x46\x4e\x5d\x0e\x08\xf2\xe3\x7d\x97\x41\xe7\xa7\xa9\xff\x50\xd3\x08\x3e\x3e\xfb\x53\xcb\x00\xf0\x54\xa0\x20\x16\x3d\x10\xa9\xe6\x21\x48\x3e\x4d\xa0\x55\xcd\xcd\xec\xa0\xf9\x37\x47\xa5\x8f\x46\x43\xd8\xca\x1e\xd2\xb9\xa4\x32\xee\x1a\x29\x12\x3e\xcf\x8a\xbb\xa2\x29\x0f\x24\xfe\xb6\x9e\x56\x9f\x82\x07\xee\xe4\xc3\xd5\xe0\x7d\xef\xea\x27\xf1\xa7\xfe\x4f\xd8\x50\xb7\xe3\xdf\x50\xa1\x70\x89\xab\xad\x11\xab\x2d\xa9\x58\xa2\x32\xb3\x4e\x7c\x46\x0a\x45\xc9\x1b\x27\xf5\x09\xf1\xc3\xe8\xb2\x37\x1e\x5c\xee\xe3\x85\xab\xe0\xd6\x65\xa1\x7d\x7e\x35\xfa\x00\x1a\xea\xe0\xdd\x3b\x50\xb5\xc0\xc8\xea\xff\x38\xb8\x1e\x5f\x97\x3d\x3a\x13\x25\xbe\x2b\xc6\x61\xb1\x7b\xd6\xbb\x3e\xeb\x9d\xc3\x04\x15\x46\xca\x4e\x6b\xbb\x62\x31\x71\x81\x3a\xde\x60\x08\xd8\x3a\xae\xed\x5b\x5b\xc6\x7d\xd4\xf6\xae\x46\x1f\x1d\x9a\xa8\x55\x5e\x2a\x00\xc0\xce\xc6\xea\x1f\x78\x21\x06\xc8\xa6\xd0\x99\xa6\xa4\x73\x26\xe8\x45\x4d\x0b\x6c\x72\x15\xe4\xeb\x14\x35\x1a\xe3\x67\x17\xb7\xeb\x30\x02\x85\x01\x20\x0d\xcf\xe7\xeb\x28\x22\x24\x20\xa6\xe0\x83\x0c\x9f\xcf\xc3\x2f\x28\xab\xd9\x03\xb8\x46\xd5\xdf\x67\x53\x2a\x07\xfd\x6d\x4a\x56\x2e\x6a\xf8\xda\x93\x43\x2d\x40\x37\x40\x35\x61\x1e\x92\x0b\x04\x9b\x51\x1f\xd4\x34\x23\x5d\x1e\x8d\x08\x3f\xba\xf7\x1f\xd0\xe4\x01\x3b\xc7\x9f\xe6\xc0\x46\xbe\x7d\xcd\x7e\xfe\x7d\x24\xfd\xea\x8e\x79\xe6\x7d\x98\x2f\x26\x3c\xbc\xe1\x21\x66\x41\x79\xf0\x05\x3d\x29\x3c\x3d\xfc\xc3\xd5\x07\xf0\x9b\x6a\xf7\x75\x2b\x5b\xdf\x66\x39\xba\x17\x5b\xa6\x37\x54\x66\xbe\x7d\xdd\x69\xe1\x6c\x27\x51\x10\xdf\xe5\x8b\x16\xf7\xed\x7d\x75\xec\x79\xe2\xbf\xff\x5b\x34\x27\x4d\xfc\x47\x3e\x3d\x39\xa1\x11\xaa\x3c\xd8\x83\xf7\xef\x6f\x1e\xe7\xc4\xae\x02\x01\xaf\x97\x16\x5a\xf6\x62\xdb\xb8\x80\xda\xad\x94\x0d\xbc\x34\x46\x05\x8d\x05\xe1\x4c\xee\x3f\xed\x39\x39\x39\x13\x81\xd2\x25\x97\x18\xc1\x10\x51\xfb\x2c\xbe\x5b\xc3\xa6\xa3\xab\x0f\xdd\x6c\x16\xca\xa0\x67\x12\x7d\x68\x80\x14\x6d\x71\x17\xc4\xe8\xd4\x44\xcf\x5a\x71\x02\x34\xda\x50\xcb\xb2\x9c\xec\xf8\xa9\x1f\x4b\x3f\x1e\xfa\x14\xa3\x28\xcc\x50\xbb\xbd\x0d\xf2\xfb\x20\x20\x1d\x1d\x26\x94\x62\x43\x10\xc6\x61\x0c\x38\x69\x21\x31\xfd\x8a\xa0\xd1\x08\xad\x05\x64\x55\xab\x0c\x1d\x95\xbc\xa5\x88\x8f\x12\x49\x41\x60\x9b\xe6\xd0\x2f\xb6\x02\x05\xf8\x73\x90\x66\x41\xf4\xd0\x86\xcf\x22\xd9\xda\x1d\x09\x05\xa6\xee\xac\x4b\x90\xff\x48\xe3\xa2\x9b\xd2\xff\xc2\x93\x93\x1f\xc0\xb8\x30\x20\xae\xf3\xdb\x6f\xf4\x14\x99\x54\xb5\x2f\x9c\x35\x06\x14\xda\xd8\x15\x4b\xb0\x1c\x14\x28\xea\x68\x26\xfe\xcc\xdc\x03\xff\xf8\x73\x17\x47\x62\x43\x3d\x01\xfd\x3c\x5b\xa7\x1a\xa4\xb0\x95\x92\x8c\xb1\x17\xa5\x19\xc0\xdc\x83\x28\x6a\x23\x3d\x2f\x7c\x50\xfb\xa1\x59\x1a\x00\x84\x3e\xe3\x64\xb3\x95\x3f\x0d\xb4\x12\xbf\x06\x5d\x27\xcd\xa6\x09\x7a\x7d\xf7\x27\x55\x1e\xb0\x82\x4a\x41\x24\xdf\x1d\x4e\xa9\xc0\xd6\xfb\xb6\xd3\x6e\x28\x6c\xf2\x74\x06\xf1\xc4\x1f\x11\xd6\x25\xff\x9c\xf3\x91\xa4\x59\xed\x00\xb8\xb4\xba\xa7\x61\xf7\x60\x44\x95\x03\xa8\x55\xba\x0e\x2e\xdb\xcf\xf7\xcc\x0c\x43\x6e\xc4\x16\x5e\x71\xa6\x70\x8e\x48\x95\x11\x92\x3c\x3e\xe2\x0e\xac\xc3\x58\xd9\xbd\x8a\x78\x89\x53\x00\xea\x92\x5d\x8c\xee\x76\xa1\x8e\x00\x32\x44\xad\xcc\x32\x21\xd1\xeb\x46\x76\x37\x34\x1b\x10\xcf\x90\xdd\xd3\x31\x06\x52\xc2\x03\xd0\x5d\xf0\x25\xcc\x72\xee\x39\xb0\x6c\x76\x69\x57\xf2\xe9\x90\x31\xbf\x41\x26\xf9\x60\x86\x4a\x63\x12\xf1\x5b\x9e\xac\x10\x3d\x65\x35\xa7\x40\x4a\x67\x80\xbe\xe6\x61\xea\xb4\x03\xd1\xb4\x26\x25\x57\xd1\x9e\x9e\x26\x7e\x44\x47\x70\xca\x97\xdf\x2e\xf7\xfc\xf3\x2e\x96\xed\x2f\x7b\x10\x91\xb4\x19\x1c\x65\xa1\x51\xd0\x87\x0b\xb4\x34\xba\x19\x0b\x56\x91\xf9\x77\xa3\xec\x09\x36\x2d\xaa\x2c\x60\xd8\x6b\xa9\x57\x2b\xfb\x57\x3e\x39\x85\x57\x5f\x72\x34\x90\x00\x8d\xd0\x90\xc1\xd5\xf8\xd1\x44\xed\xb2\x36\xdf\x5c\xcd\xa8\xd9\x6e\x86\xb3\xa6\x07\x92\x90\xba\xd4\xde\x7b\xa9\x50\xb1\x65\x53\xa9\xae\xb5\x50\x15\xb5\x56\xd6\xb6\x16\xe0\x15\x4f\x00\xe4\xbc\xcb\xa6\x5b\x01\x34\xe5\x0f\x36\xd3\x48\xb1\xb9\x1c\xe7\xe4\xc4\x70\x28\xd8\x2b\x50\xc4\x2f\x2e\xd1\x38\x3b\x1f\xa1\x69\xf0\xfd\x60\xf8\xce\x62\x5e\xf0\x57\xf5\x12\xc9\x54\xae\x7e\x63\x96\x6a\x0c\x40\x32\xc6\xf5\x73\x65\xff\x31\x53\xa6\xb3\x43\x14\x4d\xd3\x75\x9a\xd2\xf9\x21\xa2\xab\x74\x42\x2d\x7d\x3a\xdd\x04\xb6\xce\xc2\x3f\x7e\xc8\xd1\x63\x4b\x2c\x3f\x4f\xd1\xf5\x05\xd2\x2c\x98\xe6\x24\x39\xa3\x24\x59\xa9\xae\x17\x79\xbe\xca\x4e\xbe\xfe\x3a\xcb\xfd\xe9\xa7\x04\xa4\xde\x3c\x4a\xee\xd1\x71\xff\xb5\xff\xf5\xf1\xef\xff\xed\xf7\xaf\xbe\x79\xfd\x2f\x52\xd3\x1d\x8c\x99\xf7\x5e\x8c\x6e\xd0\xeb\x68\x33\xe8\x25\xad\x73\xb9\xc3\x9a\x1a\x3b\x1d\xce\xc8\x83\x19\xb3\x33\x80\x9b\x85\x7d\x92\x13\x28\x4d\xcb\xf1\x8d\x6e\x35\x65\xc4\x1e\xbc\xb5\x8a\x3e\x5d\xd6\x6a\xb9\x21\x5d\xd6\xca\x86\x17\x58\x6e\x74\x3a\x64\xb3\x58\x78\xf6\x9c\xac\x75\x6f\xee\xa3\x67\x6a\x58\x0f\xd2\x03\x4e\x7d\xdc\xff\x71\xac\x59\x0e\x6c\x2d\xff\x4e\xce\xa2\x09\xa8\x6e\xeb\x65\xcc\x5b\x35\xec\xbd\xef\xab\xef\x4a\x2f\x1a\xcf\xcd\x93\xf4\x02\x0e\x60\x4b\x66\x9b\x88\x33\xc1\x2f\xed\xf2\xfa\xda\x85\x65\xed\xce\xa8\x24\x20\xf7\x65\x50\xaa\x99\xcb\x98\x0e\xec\x85\x0d\x18\x3c\xb9\xd3\x6e\xc4\x17\x7c\x92\x27\xbb\xf7\x0e\x67\x79\x1a\x7c\x55\x5c\xcf\xbc\xac\x80\xe8\x86\x8e\xec\x0f\x5d\xa6\xb2\x75\x67\xfe\xf7\xf0\xcf\xe8\x13\x81\x0c\xfe\xa9\x58\x14\xbd\x7c\x04\x18\x6a\x59\xae\x41\xf7\xe8\x93\xc5\x76\xf1\xc1\xa9\x42\xd6\xa7\x61\xb3\xfb\x73\x59\xc3\x87\x90\xed\x54\xb2\xd8\x77\x64\xb9\xd1\x87\x42\xb1\x56\xb0\x4f\xf1\x1c\x51\x99\xa4\x07\x71\xc2\x2a\x17\xae\xc3\x10\x9f\x8c\x19\x7a\xae\xb9\x23\x91\x61\xe7\x4d\xdd\x65\x4f\x79\x4b\x01\x85\x78\x57\x6b\xd6\x86\x6f\xf1\xeb\x9b\x21\xc2\x03\x2c\x8f\x46\x21\xea\xa3\x6a\xa8\x12\x80\x36\x74\x4e\x4c\xe5\x72\xf0\x1e\xb0\xe8\xb8\xd2\xf4\x39\x00\x53\xea\xf6\x89\x11\x06\x83\x9d\x0a\x08\x23\x18\x63\xb4\x40\x96\x56\xf6\x2a\xc9\x42\x7d\xa6\x66\x21\x54\x57\x5c\xe0\x83\xf8\x41\xd9\x00\xd8\x05\x9e\x93\x63\x00\x10\x1d\x85\xcb\x86\xe4\x38\xb9\x25\x3b\x1b\x4f\xfa\xfc\x29\x05\x68\xc1\xdb\x2c\x04\xb9\x6c\x9c\x2c\x24\xdf\x49\xb8\xaf\x80\xcf\xe4\x0f\x62\x11\xf8\x9f\x1f\x64\xac\x5f\xc6\xbe\x17\xb0\xc6\xd1\x23\x15\x91\x56\xa0\x6c\x10\xbd\xb6\x89\x1a\xb2\xbd\x31\x1a\x10\xc4\x57\xcc\xd1\x84\xca\xbd\x00\xe2\x62\x3f\x02\x40\xf2\x87\xc1\x26\x00\x13\x17\xf9\x6d\xa5\x8c\x8d\x10\x9c\x97\xfe\xd3\x35\xe9\x41\xf2\x56\x8a\x7b\x61\x80\x4e\xc2\x99\xa5\xe3\x97\x7c\x52\x7e\xec\x18\x73\x48\x34\x76\xa4\x52\xa7\x83\x30\x9b\x25\x6b\x72\xa5\x2c\x82\xe9\x27\x02\x19\x1e\x87\xa2\x77\x49\x7e\x33\x07\x06\x20\x92\x55\x1e\x2e\xe1\x17\x34\x24\xf1\xc3\x13\x8b\xff\xea\xc5\xc1\xf0\x9a\x5b\x36\x6a\xe4\x6a\x79\x33\x80\xbc\x56\x86\x7f\xea\x76\xf0\xb4\xeb\xaa\xb0\x15\x80\xb5\xbf\xd0\x2d\xe9\x30\x02\x5a\x1b\x9a\x2d\xb6\x52\x30\x37\xa2\x40\x4d\x46\x32\xec\xc1\x05\x73\x6a\xd7\x13\x22\x3d\xfd\xe6\xdb\x52\xd4\x91\x24\xfa\x1d\x14\x76\x97\xfc\x1c\xe7\x3a\xb6\x6b\x6d\x59\xac\x75\xec\x65\xb7\x55\x32\x9b\x82\x3e\x7c\x3e\x5c\xb6\x63\x30\x94\xf7\x0c\xe8\x10\x3d\x70\x80\xe7\xc1\x7c\x8e\x82\x79\xba\xf0\xe3\x3b\x15\xa4\x92\xc1\x16\x2f\x7d\x1b\x07\x28\x20\x72\x49\xb1\xb5\xd2\x5f\x16\x14\x30\x0e\x76\x15\x05\x08\xd2\x30\xe8\x07\x53\x72\x70\x03\xe7\x58\x92\xdb\xd0\x52\x1b\xaa\xce\xc2\x9a\x56\x30\x4a\xe1\x9c\x75\x00\xe4\xf5\x3d\x60\xbd\x0a\xdc\x31\x61\x28\xef\x47\xe7\xfd\x66\xdb\x59\xbd\xa7\x96\x9f\x05\x30\xe2\x4c\xa2\x34\x07\x03\xe9\x28\xa0\xff\x0d\x38\xbb\x11\x69\x9f\x14\x61\xa1\x9d\xee\xf7\x54\x98\x73\x56\xa7\x1f\x77\xa7\x4f\x4e\xc5\xf1\x1b\x54\xde\x8e\x3b\x7c\xb4\x3b\x63\x49\x90\xb5\x85\x6a\x4e\xa8\x47\x61\xd1\xa0\xf6\x61\x10\x46\xa3\xe4\x28\x2c\x6c\x03\xf1\x2a\xff\x4b\x0b\x46\xf1\xc4\x57\x20\xe5\xec\x48\xc4\x4d\xde\xc5\x0d\x7b\x53\xde\x9f\x83\xf6\x88\xe1\xed\xc0\xc0\x8d\x6a\x74\xc1\x83\x87\x9f\x37\x97\x97\x65\x1f\x6a\x09\x8a\xaf\x09\x8a\x12\x42\xe2\x58\x39\x95\x67\x44\xb5\x0a\x94\xa5\xe8\xc6\xd2\x16\xaa\xb0\x81\x1d\xe5\xbb\xda\x6e\x75\x10\xbf\x8b\x41\xa7\xa7\xad\x67\x23\x23\xb1\x5a\x8e\xfb\x49\x75\xdd\x76\xd7\x5a\x32\x89\x74\x2f\x75\xa6\x91\x4d\x9d\x75\xe8\x8e\x27\xcc\x55\x28\xdf\x1b\x5c\xf7\x45\xf3\x8c\x2c\x7e\xb4\x49\xe6\x21\x9f\x76\x80\x38\x57\x9d\x34\x77\x87\xa2\x04\x9f\x3c\x3d\x46\xa5\xc0\x5e\xb2\x64\x38\x9b\xdb\xca\xef\x2b\xda\x36\x2a\x69\xf4\x89\x2d\x82\x2a\x75\xa4\xca\xb1\x6d\x69\x7a\x95\xfe\x12\xc9\x47\x7d\xc9\x55\xe5\x89\x89\x3c\xde\x64\xad\x4f\xd9\x0d\x64\x33\x1c\xa0\x31\xe9\x78\x0f\x47\x27\x52\xea\xbc\xf5\xc0\x18\x0e\xb6\x0d\xc0\x8a\x4d\x95\xa7\x62\x23\x63\x6f\x19\x47\x05\x63\x2a\xe3\xb6\x6e\xa3\x67\xd3\x36\xf3\x78\xa4\x95\xaf\x62\xa5\xa5\x15\x5a\x67\x25\x56\xc9\xab\x62\xdb\xcd\xe6\xa9\x88\x2a\xa4\x14\xcb\x18\x0d\x63\x10\x3d\xfa\x15\x07\x60\x9d\x5a\x10\xff\xcd\x2d\xd8\x12\x32\xd8\xc8\x5a\x61\x96\xdc\xa7\x98\x55\x02\x88\x99\x26\x6b\xa0\xf4\x5f\xb3\x24\xbe\x9d\x04\xfe\x74\x31\xc1\x26\xd8\x02\x5d\x85\x80\xb7\xb7\x60\x34\xc0\x77\x60\xe7\x4e\x02\x50\x64\x41\xf1\xc0\x83\x0a\xe4\xb5\x32\x12\xa6\x75\xfc\x8a\x38\xc6\xf1\xab\x57\xde\x1e\xd8\xcb\x13\x2d\x8c\xdb\xfa\x35\xe3\xa9\x30\xb2\x22\xc8\x0d\xea\x32\x94\xa5\xbe\xaf\x94\xfd\xeb\xfe\x78\x74\x01\x32\x00\xf4\x27\xd8\x54\xdb\xba\x6b\xd4\x9d\x6c\xa9\x88\xa7\xab\xd1\xc7\x6b\x98\xb5\x26\x05\xe4\x23\x47\xfa\x9c\xbe\x3c\x33\xcf\xeb\xbe\xb4\xbe\xdc\x63\x73\xea\xd6\x0a\x7f\x9b\xcd\xb1\x8e\xc8\x0a\x9b\xb3\x8e\x63\x00\xbd\xde\x13\xb3\x23\x42\xed\xc8\xe3\x36\x81\xfb\x6f\xd9\x61\x4c\x60\x80\xd2\x2f\x25\x48\xc3\x0b\xad\x9c\x3c\x1d\xb4\xcb\x33\xf0\x1e\x03\x69\xd9\x9d\x5e\x44\x19\xc6\xb5\x91\x2d\x1b\x7e\xaa\xda\x88\x0f\x60\x05\x82\x75\xd7\xfb\x30\xc0\x80\x99\x9d\xda\x6c\x1d\x67\x4f\x19\x50\xb2\x82\x26\xe1\x7c\x42\xc2\x24\xab\xb7\xa0\x5d\x93\x99\xf7\xad\xa5\x4e\xf5\x36\x9c\xe8\xb9\x1e\x23\xf3\xa1\x39\xdd\xde\x76\xce\xa2\xf2\x5f\xca\xda\xe4\x86\x85\x38\xda\xff\x33\x25\x6e\x6e\x82\xa3\xcb\x47\xed\xc8\x17\x89\x00\xfa\x20\x19\xa9\x34\x60\xf1\x4e\x4b\x4b\xec\xd3\x92\xf2\x39\xb7\xf6\xd3\x50\x0c\x13\xeb\x3e\xf6\xf9\x33\xb7\x0b\xe7\x98\xf0\xf0\xb8\xb3\x96\x6d\xa6\xf3\x06\x67\xcb\x96\x13\x5f\x7e\x28\x5d\x4f\x0f\x28\x86\xc4\x77\xa3\xd1\x65\xbf\x37\xdc\x1d\x73\xda\x82\xe2\x5c\x1f\x87\x40\x1b\x96\x57\x34\x1f\x2b\x9d\x8e\x6d\x0c\x43\x0b\xb6\xb8\x1e\x9d\xa3\xb8\x3d\x46\x7d\x7e\x6f\x64\x79\x4f\x6b\xc5\xff\xaa\x1e\x6b\x37\xfb\x27\x1f\xef\xd2\x46\x95\x7a\x8b\x67\xaf\x82\x43\x81\xde\x46\xb8\x74\x24\x7d\x15\xa4\x65\x83\x15\x3d\x5d\xab\x18\x8a\x25\x66\x3a\x05\x5f\x30\x55\x16\xa5\xa4\xd2\xa5\x4c\x84\xc7\xbc\x56\xe7\xfe\xdb\x38\x38\x6a\x60\xb3\xa3\x73\xae\xae\xb5\x74\xaa\xbb\x08\x5e\x5c\xdd\x0e\xc6\xce\x8e\x33\x6c\x6f\x9b\x8c\xcc\xae\x54\x78\xff\x6c\x1e\x78\x42\xab\x2d\x4a\xef\xc1\x58\x2f\x19\x30\x0b\x83\x06\x39\x80\xe7\xeb\x08\x9d\x75\x53\x5f\x06\xfe\x65\xd2\x45\x0f\x5d\x82\xd6\xb6\xe2\xd4\x1f\xca\x02\x9f\xc3\x90\xfb\xd0\x8f\x15\x53\x6e\xe3\xd6\x63\x69\xe6\xb7\x45\xf0\x72\xd3\x1d\xf0\xba\xa2\x91\x42\xe7\x3a\x04\x3a\x50\xee\xd7\xc1\xb8\x0a\x81\x2c\x69\xff\x2e\xb0\x0c\x77\x8c\x14\x60\x3c\x31\xa6\xb0\x58\xf9\x21\xc5\x80\xc6\x09\x07\x4b\x4a\x89\x1f\x58\x59\x54\xc4\xba\xfc\x4c\x61\x0d\x25\x40\x72\x34\xea\x3a\xa3\xb2\x03\xb3\x4c\xcc\x42\xf4\x06\x47\x8f\x65\xb7\x60\x07\xda\xf6\xeb\x06\xef\xc0\x66\x6e\xcb\x5e\x49\x79\x1a\x4d\x70\x09\x3e\xa3\xf7\x4d\x67\xe3\x53\x98\xef\x6d\x80\xd3\x87\x65\x81\xc9\xa2\xce\xaa\x51\x15\xe7\x62\x00\x61\xf4\x50\x85\x87\xdb\x6c\xf1\xc7\x5a\xe2\x07\x33\xc3\x92\x5b\xc5\x86\xd9\x6f\xc2\xd5\xb6\x5b\xf1\xa4\x39\xda\xd1\xcf\xe6\x60\xc1\xcf\x94\x87\x99\x37\x07\xd1\x96\x2c\x4e\x68\xf6\x2a\x03\x8b\x19\xd3\x8f\x71\x0f\x61\x79\xb2\x76\x82\x2a\xf4\x90\x01\xb6\xb6\xee\xf1\x88\x0b\x45\x24\x1e\x7c\xa0\x6b\x16\x3d\x5c\x21\xee\x35\xa8\x81\xdc\xaf\xd6\x47\x75\x72\x5e\xee\xe9\xb8\xa2\x50\xbf\x02\x5b\x55\x56\x80\xf0\xb1\xb9\xce\xc6\xe5\xde\x64\xc9\x09\xf8\x9c\xe8\x82\xb0\x27\x89\xed\x30\x89\x69\x14\x52\x85\x06\x8c\x1e\xc7\xcc\x4a\x2c\xe3\xc0\x81\xdc\x38\xd8\x15\x50\xa7\x2e\xac\x80\x55\x7e\x54\x30\x79\xf0\x17\x8b\xe4\x52\x2e\x74\x21\xe3\xa9\x35\x2c\x38\x40\x13\x3a\x06\x3c\xf6\xa3\x30\x7f\x2c\xbd\x11\x5c\xb4\x13\xc3\x6b\x68\xdb\xb9\x26\x5b\xc8\xd0\xd8\xc7\xc1\xf8\x7b\xa0\x85\x2f\x13\xc0\x31\x7c\xdc\xda\xe4\xf2\x07\xfe\xc2\x39\x93\x18\x27\x9e\x5b\x61\xac\x74\x28\x2c\x8d\x08\x54\xc3\x91\x52\xf4\x81\x71\xb1\x0b\x8a\x2a\x21\xb9\x84\x1c\x87\xd5\xa3\x07\xb9\xe9\x24\xe9\x04\x66\xd0\x39\x7b\xc7\x89\x9f\x99\xe7\x74\x35\x4d\xfc\x28\xc8\xa6\x41\x0b\x19\x39\x8c\x56\x0c\x12\xda\x43\x7e\xff\x9a\x75\xde\xbe\xb5\xd3\xd6\x02\x52\x21\x3c\x84\x4c\xbb\x66\xd0\x6e\x39\xea\x69\x37\xda\xa2\xbe\x71\x08\x76\x62\x7a\x48\xde\x6e\x29\x87\x3a\xbf\x8d\x27\x02\x77\xc4\xcb\xfe\xc5\x58\xfc\xfb\x68\x30\xdc\xe4\x4e\xb4\x7e\x00\x9b\x60\xe2\xcc\xc7\x68\x1a\x2c\x08\xbb\x8a\x7d\xa9\x39\x35\x76\x1f\xa4\xfe\x30\x47\x8f\x59\x7c\xd2\xaa\x48\x48\x2c\x4b\xf2\xc2\x9e\x38\xec\xd6\x6d\x67\xad\xa7\xf8\x85\x67\xa5\xd6\xa3\x5c\x24\x44\xe5\x62\x30\xa0\x28\x91\xfa\x62\xa4\xca\x0c\x48\x5a\xd6\x22\x9a\x8b\xea\xcd\xd3\xa9\xd3\x54\xaa\x80\x0b\x22\x95\xea\x7b\x44\x7a\x26\x9e\x7d\xcc\xdc\xbb\xba\xea\xfd\xd4\x2a\xd5\xfa\x50\x08\x25\x89\x10\x77\xa0\x2d\x5e\x79\xf5\x47\x6a\x8a\xef\xca\x63\x8e\x56\x65\x7a\xe7\x71\x75\xd6\x67\x4b\xc5\xb0\xfa\x5f\x70\x40\x8f\xf1\x4d\x0e\xed\x6e\xbb\x27\xee\x6a\xd0\x40\xb1\x0b\xc4\x26\x35\x6b\xf8\x17\x0d\x04\xee\xc2\x3b\x39\xa9\xe1\x3c\x1b\x44\x96\x95\xbf\x7f\x08\xeb\x23\xbe\x87\x49\xfc\x9c\xcf\x92\xa3\x54\xd2\xbc\xd6\xb7\x63\x60\x9a\x8f\x14\x8f\xf6\x88\xe5\xf3\x98\xa7\x60\xe4\x36\x21\x70\xec\x95\x26\xb5\x8c\x84\xf2\xcf\xbf\xa8\x47\x44\xaf\xea\xe1\xff\x33\xfe\x7d\x19\x7f\xed\x1e\xb8\xb6\xe6\xa7\xcf\xcf\x28\x0f\xb8\x73\x1a\xa4\x56\x22\x90\x17\x1b\x7f\x6b\x39\x2e\x6b\x44\x08\xaf\x0d\x6a\xe6\xb0\x7f\x3d\x6e\xd9\x18\x01\x9d\xc0\xa6\x7e\xfa\x5c\x3a\x2e\x7b\x0a\xd1\xc1\x33\x2e\xc8\x0e\x3d\xfd\xbf\x07\xe1\xb1\xd3\xbe\x6e\x15\x29\xbc\xce\x7a\x99\xa2\x39\xbe\xf5\xe1\xff\xb3\xfc\xdf\x88\xe5\x1b\x13\x05\xb9\xa1\x62\x80\x05\x09\x60\x25\x75\xb5\xa5\x55\x92\xcc\xc9\xf4\x68\x73\x5e\xa5\x7a\xa4\xf8\xe8\xb3\xc8\x0a\xe6\xe1\x85\xa9\x56\x1d\xe5\xcb\x7c\xd6\x0c\x19\x64\x9b\x2d\x24\x9c\xa5\x9c\x9c\xe5\x1c\x92\xa0\x85\x16\xba\x66\xa2\x0e\xeb\xbe\x65\x3b\x24\xa3\xdc\xd1\x4c\x7e\x80\xe5\x07\xa8\x7e\x9e\xac\xe1\x28\xdc\xd2\x31\x5a\x37\x82\xc6\x1c\x3c\xf8\x57\xe9\x44\xb0\xb8\xf1\x5e\x3e\xa4\x6c\x12\xc6\xf3\xa4\x35\x18\xa2\x8f\x5e\x1e\xd9\x82\x79\x8f\x00\x90\x47\xac\x46\x94\xc9\x53\x56\x23\xc6\x6a\xbd\x47\xb4\xec\x89\x7f\x77\x47\xfc\xd6\x6b\x3b\x0f\x90\x45\xbb\x4f\x2c\x86\x64\xd1\x54\xf9\xf4\x11\x34\x6b\xc5\x8e\x1b\xaa\xce\xda\x10\x48\x7b\x03\x7f\x94\x0c\x91\xb2\x36\x54\x5b\x6f\x47\x37\xd1\x06\xbc\xaf\x00\xe0\xb8\x8c\xd7\xb1\x41\x5c\x23\x50\x29\x81\x30\x0d\xa4\x4f\x31\x3b\xa1\xe0\x70\xdc\x42\x42\x26\xf5\x87\x46\x2a\x3f\xe6\x1a\x8c\xf8\x90\x11\xac\xb9\x97\x03\xcb\x99\xdf\x21\x25\x5a\xa9\x2b\x14\x00\xec\xde\x60\x52\xd9\x5c\xe6\xe2\x10\xdc\x91\xd4\x4e\xdf\xd8\xde\x9e\xd2\x4a\x24\x26\x3c\xd1\x1e\x16\x17\x56\xb3\xa2\x12\xc7\xd2\x25\x4a\x28\x27\x9a\x2b\x86\x16\x37\xf4\x29\xf6\x70\xd7\xf9\x55\xa5\xd2\x2a\x2e\x65\xfc\x19\xcc\x9a\x64\x68\xb6\xca\x43\x27\x57\xb4\xcd\xae\x76\xc4\x09\xea\x72\x0b\x26\x18\x55\x97\x27\x50\xcb\x31\xd8\x8a\x4e\x6e\x7f\x0d\xa6\x79\xcb\xa0\x42\x89\x29\x6c\x47\xca\xa7\xc2\x8c\xdd\x96\xb7\x05\x2d\x7c\xf1\xef\xd7\xa3\xe1\x77\x82\x17\xb6\xf3\xae\xf3\xd8\xfb\xec\xf5\x39\x97\x56\x25\xcd\x5f\x96\xf5\xa3\x90\x32\x8e\x3e\x76\x2b\x9f\x96\x0f\x8a\xf7\xcf\xc9\x2b\x6a\x38\x4e\x6d\x9c\x76\xf1\xb1\x75\x22\x4c\xc9\x27\x0d\xa7\x5c\x8e\x34\x7a\xea\x98\x89\xd1\xe3\x6e\xc6\xa5\xa2\x55\xd5\xa9\x7b\x94\x29\x64\x3e\xe5\xaa\x3e\x26\x48\xdf\x7d\x6b\xd2\xf9\x9a\x95\xd5\xf4\x30\x13\xcd\xb3\x92\xf5\xdc\x38\x6b\x61\x17\x1d\xaa\x08\xfb\x74\x6a\x0e\x0d\xec\xec\x62\xca\xae\x92\x28\x5b\x50\x3d\x8f\x8e\xdb\xe2\xe8\x35\xfc\xff\x9b\x86\xa5\xd8\xd7\x85\xc5\xb9\xa1\x71\x52\x36\x62\xad\x9d\x12\xf4\xad\x00\x77\xbd\x36\xf6\x80\x5f\x63\x53\x07\x2e\xe5\x79\xf2\x7e\x94\x62\xd7\x0c\x24\xa5\x53\x37\x5e\x47\x91\xfe\xaa\xae\x3e\x93\x3e\xec\xaf\x2c\x8f\xea\x42\xad\x50\x1c\x55\x12\xd9\x29\x80\xe9\xe0\xa5\x1e\xb0\xa0\xe7\x4e\x2f\x93\x24\x45\x61\x14\x4e\x0a\x62\x3d\x03\xb0\x75\xd2\x31\xd5\x2e\xe1\x28\x53\x34\xd5\xa9\x7c\x89\xaa\xc8\x41\x25\x36\x7c\xb4\xd3\x65\x04\x2a\xd6\x12\x91\x85\xb7\xa9\x42\x07\xd5\xe3\x68\x02\xf3\x58\x67\x81\xd2\x57\x83\xbf\x68\xcf\x38\x68\xad\xf9\x94\x44\x98\xed\x51\x20\xdf\xb8\x64\x2a\xa8\xda\x44\xe1\x27\xf6\xd8\x77\xc5\xf7\x5c\xb5\xb8\x2d\xfb\x4a\x39\x35\x42\xe5\x1f\x91\x17\x1e\xcf\x46\xe5\xd1\x82\xac\xf0\x19\x98\xce\xf4\x11\x57\x49\xbb\xa5\x0e\xa1\xcd\x7d\x20\x6b\x2e\xab\x83\xd5\x2c\xa0\x48\x98\x7b\x53\x87\x44\x1e\x3c\xb4\xf1\x24\x56\x95\x65\xc0\x62\x28\xd8\x47\x19\x14\xdc\x1b\x9d\xb3\xa9\xe3\xdb\xf9\x1a\x24\x68\x65\xd5\x91\x1d\xcd\x0b\x8d\x4a\x2c\x48\x8a\x8e\x7f\x59\x78\x8f\xa5\xa2\x61\x5f\x65\xce\x25\x8a\x41\x35\xf8\xc8\xe1\xb9\x56\x0a\x52\xa7\x83\xd5\x1a\x6b\x26\xc2\x27\xf8\x9f\x25\xcf\x45\xe0\x62\x29\xb7\x0c\xeb\x3c\xae\x73\x95\xa9\x64\x45\xbd\x2c\xf3\x98\x13\xe9\xe1\x5f\x6b\x0e\x87\x64\xdf\x10\x08\x1c\x6f\xb1\x87\xdd\x36\x0a\x39\x37\xc5\x82\x03\x8d\x9d\x0b\xab\xc2\x8e\xc9\xc2\xaa\x9c\xde\x62\x8a\xaa\x16\xf9\x10\x9e\x08\x3d\x58\x1e\xb6\x33\x78\x57\xe1\x5d\xdb\xdd\x76\x3c\x3a\xf6\xca\x7e\x85\x8a\xc3\xcb\x52\x09\x3a\x20\x3c\x9e\x7d\xa3\x82\xc1\x29\xa7\xc0\x4b\xee\x63\x9a\x4b\xb6\x56\x75\x60\xb9\x99\xab\x60\x41\xb9\xb6\x80\x11\xe1\xbf\x15\xbd\xba\x07\x96\xc8\x53\x19\x42\xf6\x4e\x58\x02\x81\x3e\xb7\x18\x69\xa3\xc4\x6a\x9d\x2a\x6e\xd6\x53\x62\x9d\x1b\xd9\xe6\xde\xde\x06\x43\x63\x96\x7b\x39\xb5\x74\x5f\xc3\x54\x88\x71\xa8\xfa\x5f\xcc\xd2\x32\xa3\xa3\x49\x2b\x2d\x3b\xdc\x9b\x50\x9c\xca\xd3\xf9\x9d\xab\xe9\xf7\x60\x17\x74\x29\x50\xcf\xe4\x04\xef\xa6\x61\xd5\xf3\x1e\xc5\x7c\x31\xb1\xcd\xe4\xb5\x95\xd2\x41\x65\xb0\xcc\xb3\x30\x1a\x3b\xac\x6e\x57\x0e\xd3\xe9\x50\x71\x7d\x9d\xc1\x2a\xab\xca\xdd\x72\x35\x6c\x58\x92\xbc\xf5\xc1\x04\xfd\xe8\x7a\xba\x9c\xcc\x07\xa2\x70\x89\xc5\x69\x4d\x0b\x55\x5e\xbb\x7c\x9b\xc0\x14\xe3\xd1\x49\x35\x4f\xdc\x7b\x3d\xb6\xf0\xaa\x0d\x35\xa6\x39\xb7\x0f\x39\x8a\x29\x2f\xcd\x5c\x10\x73\xfa\x58\x4f\x2a\xd3\xb4\xf7\x9b\xb1\x47\xa5\xd0\xfe\x1f\x65\x93\xce\x39\x85\xa1\x51\x97\x38\x37\xb3\xd1\x67\x49\x0a\xd9\xca\x61\x76\x73\x8a\x72\x95\xc8\x0f\x7e\x0a\xeb\xcb\x49\x5d\x8c\xc3\xd5\x3a\xe2\x32\x72\x5a\x67\x6c\xec\x17\xbd\x9e\x05\xc5\x2a\xb9\x93\x24\x76\x03\x6c\xcb\xfc\x8f\x2a\x86\xc8\xcf\x75\x1d\x7c\xa3\x71\x61\x0d\xf8\x42\x41\x37\xaa\xab\x2d\x74\x1b\xae\xf7\xee\xcf\x88\x3e\x8f\x5f\xa0\x58\xe0\x9b\x20\xe2\x20\xcb\xd4\x0d\x2c\xfa\x6b\x55\x8e\x52\x5e\x04\xa0\x2f\x43\x89\xc2\xbb\xd8\x54\xab\x6c\x38\xb7\x05\xe0\x47\x59\xee\x63\x09\x30\xe9\xbd\x55\xe5\xfe\x11\x5a\xbf\x26\xb7\x59\xd7\x46\x58\x03\x06\xa7\xb8\xb0\x55\xd1\xae\xa6\x90\x71\xab\x94\x38\xfd\x68\x6e\x8a\xa5\x6b\xd2\xe0\x6e\x1a\xf9\xf6\x2d\x0d\x0e\xcc\x5f\x8a\xd6\x71\xf7\xd5\x57\xad\x96\xba\x3f\xe3\xe5\xab\xee\xab\x63\xaf\x03\xff\x7d\xf5\x7b\xe8\xe0\xcd\x96\xe8\xe6\x5d\xdd\x4f\x59\x7d\x2d\x65\xf7\xcf\x0a\x2c\x90\xf1\xe7\x96\x13\x72\x43\xc2\xa6\x1c\xc4\x3e\xa2\x93\xd9\x6c\xad\xe2\x9d\x5b\x6d\xe1\x3e\xa8\x2b\xd8\x85\x7d\x79\x68\xc6\xab\x9b\x13\xfa\x63\x1d\x9b\x46\xc9\xd8\xe7\xfd\x73\xf6\x8a\x6f\x2c\xec\xbc\x1f\x81\x14\x27\xe7\x35\x76\xb8\x36\x85\x39\x6d\x35\x9c\x0b\xe9\xf9\x29\xce\xf6\xa0\x53\xa4\x6d\xfb\x69\x36\x10\x55\xbb\x4c\x46\x59\xd2\x47\x86\x12\xe7\x4e\x61\x8c\x4c\xb4\x48\xc3\x40\x22\x46\xd1\x0c\x28\xea\x51\x74\x17\x5a\x39\x74\x1f\xd2\x2a\x0a\xa7\x61\x2e\xb0\x3e\x4e\x1a\xce\x82\xe6\x7e\x98\xa7\x0a\xe5\xbb\x13\x2d\xb3\xa3\xbd\x50\xd1\xe6\x49\x68\x21\x6f\xa1\x4c\xbb\xf0\x80\x32\x82\xd1\xea\xa7\x12\x83\x09\xc5\xe9\x7d\xcd\x3a\xc8\xd7\x04\x19\xbe\x15\x00\xcd\xa4\xbb\x20\x53\x17\xd3\x58\x01\x37\x74\x47\x02\xeb\x2c\xeb\xd5\x0c\x63\x05\x80\x7d\x51\x1e\x19\xe9\x6a\xee\xbb\xee\xce\x05\xc7\xcb\x1c\xa5\x16\x80\xdd\x8a\x54\xde\xad\x97\x8b\x54\x23\x0d\x50\x92\x4e\x19\x31\xb7\x8c\xe0\x25\x17\x5a\xeb\xa8\xe5\x86\x9b\x52\x7d\x76\x9b\xbb\xf7\x9c\x74\x5b\x49\x77\x1b\x79\xea\x2e\xb4\x57\x8d\xd1\x8c\xc5\x65\x02\xf4\x2b\xc9\xcf\x44\xb4\xab\x6a\x8a\xe4\x5c\x52\x34\x96\xc9\x0b\xac\x68\xbf\xbc\x3d\x28\x0e\x63\x51\x77\xa5\xb9\x6d\xa4\x75\x38\x3e\xa9\xec\x1f\xfb\xd2\x9a\x47\x62\xd3\xb3\xe1\xcc\xa6\xf0\xe5\xba\x1b\x08\x9e\x01\xb1\x36\x6d\x1c\x6f\x16\x1b\xeb\x74\xb3\x49\x1d\x53\x2f\x61\x15\xd5\x84\x56\x75\x55\xe4\x6a\x9a\x6f\x0e\x4c\x8c\x2c\x5d\xc4\x58\x8f\x50\xce\xd5\x93\x6e\x35\xe0\x51\xef\xb2\x7f\x7d\xd6\x6f\x2d\xbb\xc5\xfe\xda\xfb\xdd\x02\xe9\xed\x53\x8d\xf2\x49\x38\xda\x06\x58\xb8\x3c\x6d\x67\x9b\x6a\x87\xdb\x3c\x9f\x38\x61\x63\x97\x7d\x75\x8b\xb6\xed\x7b\x02\x9e\x6d\x5a\x53\xf1\xc1\x73\xaa\x9c\xa5\xab\x4a\xdb\xa2\xf8\xe8\x29\xd4\xce\x67\xd2\xec\x4a\xa0\xab\xd6\xed\xf4\x67\x42\x02\xf4\x6f\xa2\xdd\x6d\x65\x0d\x6c\x6e\xee\xb9\xfb\xff\x07\xb5\xbc\x8d\x7c\x65\x57\x3d\xaf\x04\xe6\xd3\x4a\xe8\x3f\xa3\xc2\xb7\x99\x3d\x3e\xab\x5a\x56\xc9\xcd\xaa\x15\xb3\x6a\xda\xf9\x4d\x54\xb3\x3d\x64\xe9\x81\xca\x59\x05\x12\x50\xf6\xd1\xb3\xaa\x65\xcf\xa9\x14\x55\x8b\xa9\xa2\x5a\xb4\xe3\x9e\xd6\x29\x46\x9d\xce\x2c\x4d\x56\xca\x49\x45\x09\x5f\x8a\x91\xd2\xfa\x39\x4c\x69\x16\xe0\x85\x70\x9c\xef\xba\x02\x21\xb9\x4a\x43\x62\x0f\xe4\x18\xdc\xa7\x96\x00\x0e\xe6\x28\x7d\x59\x05\xe7\x4c\x22\x90\xbf\x93\x7c\x01\xec\xda\xba\xb7\xcc\x4d\x34\x54\x48\x52\x77\x71\x5a\x65\x29\x46\xbe\x8c\x2b\x98\xb2\xbf\xcc\xee\x9c\xdf\x91\x17\x6d\x06\xff\x89\xd1\xdf\xa6\xaf\x5d\x2b\x86\xa3\x80\x0e\xfa\xf3\x2f\x76\x61\xc7\xea\x32\x84\xf6\x25\x59\xf6\x64\x0e\xae\x51\x58\xc1\x1a\xe5\xc8\x16\xc4\xbe\x2a\x5f\xf1\x69\x66\x63\x16\x2f\xdb\x9b\xcc\x54\x82\x88\x5e\xbb\x90\x29\xaa\xe5\x37\xf6\xb0\x33\xa7\x38\x92\x5c\x6a\x09\x88\x66\xbd\x13\xeb\xba\xd5\x89\xbc\x20\xa1\x6b\xee\x5d\x13\x8b\x46\x21\x72\xb3\xb2\x81\x99\xe4\x8c\x62\x38\x67\x56\x17\x1c\xb5\xb3\xe8\xaa\x1b\xa6\x98\xf0\x17\x5d\x2e\xb1\xa8\xeb\xee\x59\xfe\x50\xca\x4b\x82\x2f\x2c\xb1\x7a\x5a\xde\x2e\x1d\x28\x8e\x4b\x06\x84\x3b\xb3\x55\x55\x07\x96\x3e\x00\x00\xa3\x05\xac\x2d\x69\xe9\x2a\x8a\x5e\x95\xc8\xfe\x14\x83\x88\xc5\x7b\x6a\xb8\x13\x3a\x84\x14\xd3\x75\xde\x49\xe6\x73\x7d\x7f\x2c\x90\x6e\xa6\xaf\x88\x45\x2a\x5a\x49\xf9\x2d\xb7\xc2\x81\x54\x28\xef\x64\xea\xe6\x09\x3f\xcf\xfd\xe5\x0a\xbd\xae\x77\xc1\x24\x88\x67\x56\x00\x8c\x99\xe5\x96\x5d\x62\xeb\x6b\xba\xd3\x06\xb1\x35\x67\x2e\x90\x17\xd3\x29\x6d\xd4\x94\x83\x6d\xa7\x53\xf9\x45\xa8\x67\xb2\xe3\x86\x4f\x32\xd0\xdd\x60\xf9\x19\xef\x7b\xa6\xfb\x2b\x7c\xa1\x7b\xee\x74\xf4\xa2\x51\xf1\x31\xf7\xd8\x66\xf2\x9e\x5b\x7c\x08\x72\x8e\xeb\x67\x8b\x3f\x3a\xbb\x66\x2e\x42\xc7\x6a\x9c\xba\xad\x8d\x58\x30\x05\x87\x5d\x9c\x56\xb0\x10\x44\x2f\xf8\xce\x4c\xe4\x8f\xa7\xf5\xbb\xb5\x8e\xc3\x2f\x93\x65\x88\x97\xe3\x51\x4d\xcd\xac\x65\x66\xe4\x35\x8a\x97\x50\xcb\x0e\xcf\xfb\x95\xf8\x38\xb8\xb0\x97\x53\x59\x27\x51\x9e\xc2\x93\x3b\xac\xa2\x48\x1f\x9e\x4b\xf8\x54\xd5\xdf\x37\x97\xf6\x04\x7c\x5d\xb1\xbc\xf3\x77\xc1\xd8\x28\x56\x09\x6e\x34\x21\x28\x5f\xda\x43\x85\x35\x30\xf7\x3b\x5c\x86\x91\x9f\xea\xf3\x14\x75\x75\xd3\x3d\xf6\x86\xd7\xb0\x33\x2e\x53\xed\x72\xce\x16\x9f\x87\x51\xce\xe9\x7d\x18\xb2\xa8\x5a\xe0\xe7\xd4\xf3\x2d\xde\xb4\x64\x53\x40\xa7\x83\xf7\xc8\xab\x44\x64\x0c\x70\x0a\xd5\xfd\xa3\xd4\x1f\x4f\x97\xef\x48\x8f\xdd\xc3\xe7\x07\xa7\x05\x1f\xf2\x82\x64\xad\x0a\x50\xb3\xcf\x49\x4d\xd4\x19\x1e\x81\xae\x12\x92\xc0\x78\x37\xcb\x84\xe4\x9b\xba\x6a\xf7\xba\x90\xeb\xa2\xb8\x26\x99\x27\xd3\xbc\x10\x7d\x56\x17\xec\x46\xa7\x9c\xce\x17\x8c\x7b\xc4\x96\xff\x48\xf7\x01\x3a\x6f\x81\xd9\xf4\x3f\x8c\x9f\x7b\xe0\xb7\xd6\x4d\x84\x6a\x26\xdf\x58\x33\xf1\xda\x58\x85\x16\x36\x60\x19\xcc\x76\x82\xca\x86\x39\xd5\x00\xb8\x62\x6a\x58\xa4\x52\xde\xc7\x57\x91\x62\x23\x47\x3a\x2e\xbf\xa1\x61\xca\xe7\xe5\x7c\x3f\xa9\x89\x47\x70\x7f\x24\x0b\x30\x9f\x74\xed\xc8\xd1\x9a\x49\x5b\xdf\x68\xd0\xbd\x3d\x75\x61\x67\xa5\xdc\x51\x34\x1c\xb1\x5e\x2c\xec\x06\x38\xfc\x15\x97\xab\xc1\x78\xba\xe8\xc1\x44\xda\x25\x20\xb1\x88\x85\x01\xab\x4f\xd9\xf8\xcd\x45\xe0\xa7\x51\x48\x05\xe0\xc2\x65\x50\xee\x5d\x73\x12\x9a\x84\x92\x69\x6e\x7a\xb2\x39\xcf\xae\xc8\x9e\x82\x3d\x66\xc5\x70\x56\xb3\xb9\xe7\x00\x70\xa4\x20\xd4\x2a\x6b\x8e\xef\x1b\x45\x78\xba\x96\x99\x81\x16\x9f\xb4\x57\xa1\x94\x9d\x02\x65\x07\xbb\xb6\x8b\xf9\xbd\xa5\x60\x5a\xce\xee\x92\x7f\x9c\x03\xda\x0c\x86\x85\x9a\x72\x99\x87\xf1\x05\x85\xd4\x12\x89\x2f\xee\xda\x3d\x37\x90\xc2\xd6\x20\x6c\x8d\xb6\x6d\xe9\x60\x1e\xcb\xe0\x72\xc0\xa9\x65\x93\x53\xac\x66\x16\xac\x7c\xcc\x1e\xa3\x72\x05\x0f\xec\xd7\xc0\xa3\x64\x8a\xbe\x30\xd5\x10\x4c\x12\xd0\x3f\x66\x41\xf0\x8f\xb2\x2b\x2b\xc8\x04\x6c\xf9\x4c\x4d\x9b\xaf\x6f\xc6\xd5\xc9\x07\xdd\xca\xb0\xdc\x62\xbc\x47\x61\x07\x64\xa0\x45\x1d\x51\x97\x00\xa7\x81\x27\x81\x7c\x74\x6c\x00\x9c\xb5\x4c\x1a\xce\x33\x92\xb6\x13\x3c\x22\xf1\x6b\x33\x89\x3b\x1f\xc9\x8c\x13\xf1\xcf\xff\xcc\xe8\xf3\x33\xff\xdd\x55\x73\xff\x65\x6f\x2a\x6a\x14\xc8\x65\x73\x45\x93\x3a\x4a\x79\x59\x49\x21\x12\x89\xdf\xd4\x23\xa7\x57\x17\xf6\xac\xaa\xec\x52\x3f\xd2\x56\x33\x4a\xf2\xe9\x5b\x17\xc3\x2d\x05\xfb\xf4\xad\xab\x60\xdb\xe8\x7f\xfa\xd6\xd2\x67\xde\x58\x81\x80\x6c\x3d\xef\x16\xc6\x02\x46\xeb\x48\x55\x00\xe0\x50\x02\xae\xce\x91\xb1\x15\xb1\xf4\x53\x4a\x6e\xc0\x6a\x79\x58\x2c\x87\x13\xe9\x38\xc5\x2e\xc4\x4b\x1a\xe1\xbb\x9c\x8b\x40\x93\x6d\x1b\xce\xe7\x01\x06\x5f\x61\x9c\x8a\x8a\xb6\xa2\x88\x51\xfd\xc6\xb4\xc8\x0e\x3a\x49\xc8\x70\xbd\x58\x1c\x46\x6d\x0b\x81\xb3\x65\x65\x7b\x53\x89\xcc\xea\xb3\x76\xe3\x16\x76\xae\xb0\x7a\x59\x19\x1d\x5f\x79\x22\x60\x50\xa6\x92\x80\x14\xed\x94\xb3\x56\xb3\x45\x72\xaf\xb6\xde\xd8\x58\xa7\x6f\xf5\x5d\x3c\x03\xbe\x16\xbb\xb0\xdd\x4b\xe7\x8e\xec\xfa\xcb\xab\x6d\xb4\x18\x8e\x3e\xb6\x3c\xd1\xd9\xeb\x34\xc6\x75\xb2\xd9\x95\x22\x24\x56\xf0\x9e\x93\xfa\x6e\xd7\x1e\x02\x11\xf9\xd9\x77\xca\x6b\xdb\x4a\x35\x45\xa6\xd4\x9c\x3e\x1c\x74\xde\x50\xb7\xfb\x55\xc9\x3a\xb2\xd0\x18\x3c\x9e\x06\x33\x52\x52\x13\xab\x24\xf4\x2d\x86\x16\xc2\xb4\x25\x0e\x96\xae\xf1\x25\x1f\x92\x4d\xaf\x2a\x99\xce\xf6\x8b\xa4\x80\xb8\x67\xb0\x60\xf7\x66\x8a\x59\x42\xc9\x69\x78\x59\x90\x24\xa6\x4f\xe1\x4a\x85\x2b\x6a\xe5\x19\x3f\xe1\xab\xbd\xb9\xcc\x46\x3d\x54\xf1\xee\xe1\x54\x0c\x86\x45\xc4\xdd\x8c\xb6\x3b\x90\x0c\x07\x3a\xca\x84\x1b\xeb\xf6\x05\x39\x8f\xcc\x5c\x7e\x90\xdb\xac\x0b\x4b\x03\x11\x1f\x30\x36\xbd\x5d\x4a\xfc\x40\x7a\x5a\xb2\x05\x9a\x76\x6d\xdd\x00\x56\x3e\x1c\xe1\x3d\xd7\xda\x67\xf9\xa7\xc1\x07\x0a\xce\xec\x9f\x5b\x95\xec\xcf\x46\x43\xd0\x37\x6e\xfa\x9c\x59\xa2\xcb\xab\x5b\x5f\xd4\xd4\x39\xaf\xf0\xa1\xa5\x6e\xb5\x83\x03\x88\x29\xed\x56\x78\x36\x79\x9a\xef\x41\x7a\x19\xe3\x90\xf3\x5c\x7e\xe3\x3d\xfe\x3b\x86\x44\x1f\xb7\xec\xe8\x48\x14\xe5\x95\xe3\xec\xdd\x85\x52\xd1\xaf\x8b\x4f\x32\x3e\xa7\x71\x22\x81\x75\x00\xb2\xe5\xed\x4d\x80\x51\x3c\x74\x39\x0d\xdc\xf0\x0b\x59\x4b\x1e\x78\x06\x9e\xe6\xa4\xc1\xdd\x1a\x8c\xef\xe8\x81\x05\x1f\x32\x0f\x8c\x22\xdc\xc3\x89\x1f\x66\x13\xa0\x6c\x60\xe6\x28\x55\x83\xb4\xc5\x87\x82\xf2\x0e\x1b\xa0\xb9\x29\x5d\x81\xbc\xcd\x79\x2f\x11\x63\x1e\x25\x7e\xfe\xaf\x19\xd8\x31\x2d\x79\x74\x79\x2a\x9a\xff\xf5\xe5\x0f\xf3\xf9\x2b\xeb\xe7\x75\x73\xbf\xeb\x66\xb7\x39\xd5\x8b\x4b\x28\x4f\xde\x49\x0b\x00\x45\x44\x95\xfc\xe4\xc5\xa2\x07\x48\x7c\x48\xc9\xc4\x0a\x50\x9d\xc0\xce\x04\x77\xb6\x73\xde\xe6\xd6\x49\x1c\x7c\xfc\x0c\x3d\xc7\x28\x8f\xf1\x3e\xdf\xf8\xb9\xf6\xe7\x5f\xad\xfd\x39\x7e\xfa\xfd\xb1\x16\x70\xd0\xee\x0c\xfd\xe1\x3e\x3b\xb1\x69\xb8\x83\xf7\xc1\x09\xbc\xd5\xe5\xa6\x28\x4d\xc2\xa9\x98\xdd\xff\x71\x43\x71\x4d\x5a\xd3\xde\x75\x0d\x9d\x1a\x06\x4f\x94\xe4\x2c\xa3\x2b\xcb\x39\x33\x9c\xe6\xc0\xc0\x97\x97\xb4\xab\x12\x06\x3b\xef\x81\xea\xfc\x10\x60\xdb\x3c\xdc\xd4\xa0\xb1\x2e\x9b\xa3\x28\x75\xba\xfe\xc3\x5c\x2f\xc7\xb5\x46\x30\x73\x45\xd5\x97\x26\x55\x48\x2e\x8b\xae\xfa\xac\x3a\x45\x02\x5c\xd1\xb7\xc4\xdc\x26\x49\x14\xf8\xb1\x51\x9b\x1c\x1b\x97\xeb\xcd\xf4\x86\x3f\xb5\xd8\x2a\x6c\xa2\x81\x8f\xaa\x31\x01\x0a\x7f\x31\x29\xc3\xf0\x87\x4c\x27\xfa\x05\xe7\x61\x1f\x9e\x58\x03\x92\x90\x1d\x5c\x38\x73\xd0\xce\x5b\x33\xe8\xc9\xa9\xec\x6d\xd2\xc4\xdb\x32\xf5\x0b\x94\x53\x96\x33\x17\x3b\xb2\xda\x4b\xab\xab\xb5\xe3\x25\x36\xfa\x37\xcf\xeb\x96\x6f\xad\xd4\x17\x0d\x3d\xa2\xd7\xd2\x15\x82\xf6\xfc\x9f\x21\xbb\x61\x0b\xe6\x30\xbe\x28\x64\x79\x4c\xb2\x56\xc5\x8d\x36\x3b\xd6\xd6\x2f\xb3\x6a\x4b\x8d\xb7\x92\x43\x70\x05\x9c\xbd\x45\xba\x09\x0e\x61\xba\xa4\x47\x75\x29\x59\x45\xee\xd3\x6c\x13\x0e\x65\x39\x9a\xc0\x54\x81\xc5\xbd\x95\x5e\xa6\xb5\x37\x8b\xa4\x2c\x5d\x9b\x8c\xd4\x3f\xbf\xc8\x7e\xa1\xda\x55\x68\x1a\xae\x92\x8c\x6e\xb4\x6d\x1f\xb0\x07\x14\xe2\x44\x47\x13\x96\x6d\x07\xb4\x03\xff\x33\x06\x1b\x5e\x60\x55\x3a\x82\x2c\x02\x67\xfb\x9d\xa2\x35\x75\x8a\x2b\x0a\x53\x95\xf7\xd3\xf9\x00\x4d\x25\x24\xcb\x7f\xb0\x6f\xf7\x2a\x5e\x3f\xed\xa4\x04\x55\x9d\xa1\xea\x3d\xb4\x52\x86\xf6\xba\x8b\x7a\xaf\x49\x17\x32\xf2\x51\xd3\xee\x8d\xed\x8c\xfc\x32\xb6\xff\x30\xe8\x7f\x54\xf3\xb0\xfd\x69\xbd\xeb\x4d\x55\xe8\xe8\xe8\xd4\xf8\x74\x5d\xd7\x40\xc1\x59\x8b\x3f\x2f\x5e\x1f\x65\x5b\x6f\x26\x2b\xf9\xf4\xf4\x10\xba\x04\x80\x05\xce\x22\x6a\x48\xfd\xfe\x00\x2f\xd4\x61\x49\xfc\x86\xbd\x3c\x05\x57\xb1\xef\xba\x7a\x5e\xae\x62\x1d\x8f\x3f\x1b\x5b\x29\xb1\x91\x27\xe3\x22\xb8\xaf\x7f\x87\x4c\xc4\xda\xbe\x67\x60\x22\x95\x89\x87\x4f\xc0\x45\x6a\x66\xfd\x48\x2e\xf2\xbe\x8f\xb3\xde\x85\x8b\xa0\x21\xdc\xa5\x33\x2b\x2c\x8e\x16\xda\x21\xed\xfa\x35\xab\xa7\xf0\x9e\x7e\xa9\xf8\xc0\x3a\x86\xab\xe5\x48\x0e\x3e\x1e\xc6\x98\x34\x47\xc2\x41\x37\xd7\x55\xac\xe7\x63\x14\xed\x20\x27\x43\xaa\xbe\xbb\x02\x4f\xf3\x39\x7b\xc7\xff\x76\x8c\xce\x66\x4a\x35\x8c\x6e\xfb\xed\x46\xe2\x07\x68\x5e\x79\x33\xd2\xd6\x2b\x8f\x6c\xac\x1a\x0c\x2f\x46\x0a\xb5\x19\xab\x6c\x84\x5a\x9a\x92\x9f\x0e\xae\xeb\x67\x16\x40\x1b\x75\x39\x13\xbb\xb9\xa7\x11\x17\x4b\xf1\xff\x0d\xb7\x1e\xe6\xb6\x50\x1e\x9d\x1f\xa1\xf3\x3c\xa2\x20\xbe\xcb\x17\x6e\xdd\xc9\xad\x41\x53\x05\x1f\x66\x75\xf8\x54\xd9\x35\xee\x44\x3e\x15\x8f\xb5\x68\x7d\x6e\x16\x87\x84\x58\x75\x05\x4d\xbb\x2a\xe7\x9e\x3c\x75\x13\x5f\x5d\x56\xde\x15\x61\xaa\x7c\x62\x90\xbb\x95\x62\x4d\x5f\x2c\x42\x80\x2d\x00\x69\x92\x85\x7f\x25\x96\x81\xff\xca\xad\x39\xee\xbe\x12\x1d\xd1\x5a\xdd\xd1\xcb\xc9\xed\x43\x1e\x64\xad\xe9\x22\xeb\xaa\xb4\xdc\x60\x36\xe1\xc6\xf4\xca\x3b\x39\x89\xd7\xcb\x00\x91\xed\x6b\x51\x6e\x04\x84\xb6\xa5\x99\xe7\x89\x97\x78\x15\x9b\xba\x9c\x5b\x66\xfe\x4e\x52\x74\xac\xb7\x65\xfc\x62\x26\xe7\xcb\x9e\x43\xf3\x14\xfa\xb8\x0d\xc0\xcc\x32\x63\xa8\xac\x62\xd3\x99\x7e\xb8\xcb\x7d\x49\x86\x45\xb9\x18\xc9\x02\x1b\x2f\xd9\xb0\x63\xf7\xc2\x72\xad\xd7\x16\xc2\x96\xf0\x4a\x5e\x71\x5c\x19\x7a\x17\x16\x62\xef\xba\x85\x52\x4d\xdb\xa7\x61\xad\xce\xc2\x65\x3c\xb4\x43\xa4\xcc\x2a\x26\x86\xf0\xb2\x3e\x95\x43\xef\x73\xae\x65\xb2\x9d\x0b\x93\xdc\x1a\xe4\x58\x05\xa7\xbd\x03\x14\xbb\x6e\xa5\x86\x1d\xb8\x20\x3b\x69\x88\x09\x5a\x3c\x30\xfa\x44\xb5\x8b\xd5\xef\x25\x33\x5c\xbf\x71\xcd\x7e\x7e\xec\xc4\x4f\xb0\xc8\xdd\x70\x35\xa6\xa2\x5a\x75\x67\x0b\x8d\x6c\x88\xd3\xa9\x3d\x0c\xb2\x4f\x17\x3e\xd8\xc8\x1f\xa0\x1b\x96\x27\x14\x00\x21\xbd\xdf\x54\x31\x15\x0b\x38\x71\x4c\x0c\x7a\xc2\x43\x60\x7e\x33\x0e\x4b\xcb\x53\x3f\xce\x7c\xaa\x3b\xd0\x15\x83\xbc\x99\x89\x70\x89\xc5\xa0\x28\x1c\x13\xdf\x7f\x89\x45\x10\xcf\x32\x19\xe2\x86\x67\x6f\x1c\x38\x6d\xd5\x5b\xc5\x01\x13\x60\xe8\x51\x80\x65\xa3\xe8\x30\x6c\x9f\x28\xe8\x20\xf2\x1f\x26\x36\x81\xff\x9a\xdc\xb6\x16\xb9\x0c\x56\x56\x77\xfa\xde\x4f\x28\x9a\x47\xe8\x10\xce\xfc\xaf\x9e\xd6\xe3\x7f\x18\x0d\xce\x4b\xa7\x7b\xb7\x77\xf7\xd8\x55\x85\x4a\x6e\xf1\x5e\xf9\x01\xa9\x8a\xa6\x41\xe3\x77\xbf\xab\x90\x1e\x18\x4d\x70\xd7\xc5\xaf\xf8\xbc\x41\x4f\x5a\xf1\x96\x15\xb4\xdb\x16\xbf\x59\x40\x7e\x44\x77\x0e\x91\x72\x25\x8f\x07\x5d\xed\x12\xaf\x8b\xda\x71\x81\x1c\x14\xe8\xac\xc3\x33\xbc\x44\x1d\xb7\x0f\x56\x47\xc1\x09\xb3\x75\x14\xa8\xaa\x6d\xf2\x7c\x10\xeb\xa1\x9b\x9b\xd2\x3b\x99\x3f\x0f\x44\xcb\x5a\x00\x7c\x9e\x01\x4a\xff\xd3\xeb\xe3\x6f\x7f\xef\x95\xce\xa2\x80\xbf\xfb\xb3\xcf\x61\x96\xa4\x0f\x13\x0c\xd9\x9d\x20\x16\xb4\x8e\x5f\x7f\xf3\x87\x3f\xb4\x2d\xb8\x22\x75\xfe\xee\x77\xaa\x11\xcd\x89\xde\xa8\x39\xb5\xcc\xa7\xf2\x1e\x67\xda\xf4\xd3\xb7\xef\x08\x9d\xae\xc7\x2d\x8d\x08\xa6\xf0\x88\xf9\x8e\xa9\xa3\x8e\x31\xca\x4d\x63\x4e\xc8\xb0\x95\xbb\x7f\x6a\x4f\x11\x4f\xb9\x6a\xca\x2f\x71\x09\xc9\xc0\x2e\x43\xa1\x88\x0c\x03\x29\x57\x91\x4f\x17\xbb\xe0\x0d\x32\xfa\x0c\xca\x8a\x71\xe6\xda\xf0\x14\xe9\xcc\x27\xc6\x8b\x00\x9a\xfa\x18\xdf\x8a\x35\x3f\x66\x81\x8b\x4f\x44\x6c\x99\x0e\x69\xf2\xad\xcb\x8f\x70\xb8\x8c\x02\xdd\xa0\x0f\x1f\x94\xcb\x54\xf6\x28\x83\x52\x81\x62\xab\x12\x11\x4a\x27\x6f\x86\x06\x0b\xa8\x3c\x21\x72\x6f\x95\xd2\x06\x80\xef\x87\x71\x29\x61\xa0\xea\x64\x9d\xf5\x1f\x4c\x98\xaa\x0f\x8b\x66\x2d\xdc\x04\x08\xd7\x7e\xad\x3f\xe1\x16\x16\xa5\xd4\x36\x31\xdf\x70\x1b\x35\x6f\xad\x44\x96\xca\x5d\x6a\x9e\xb0\xe8\xbe\x74\x6c\xc7\xc2\x70\x7b\x44\xef\xcb\x18\xd1\xba\x68\x7a\x12\x66\x1b\xa8\xdb\xb5\x72\x67\x85\x69\xb9\x70\xdb\x49\xdb\x55\x29\x00\x45\x1d\xd7\x59\x20\xf2\x23\x2d\x88\xe0\x77\x37\x90\x7a\xb3\x5e\x8e\x00\x56\xca\x79\x8c\x59\xe7\x12\xea\x9e\x9b\x00\x52\xdc\x0b\xeb\x0c\xdd\xe0\x4d\x99\x59\x4f\x8b\x81\x09\x3b\x46\xc8\x5b\x85\x47\x0f\x0a\xd6\xaf\x0c\xaa\x47\xd3\x33\xb3\x8d\x83\x9d\x3a\x17\xaa\x43\x15\xf5\x8f\xae\x04\xbb\x17\x69\x87\xb8\x81\xf4\xce\x56\xdb\x5f\xcb\x12\x94\x1c\xfe\x4e\x3c\x90\xc3\xd3\x81\x67\x70\x80\xbe\x73\x47\x5c\x99\x06\xde\x9e\x9a\x70\x7c\x6a\xee\x7c\x3f\xed\x16\x55\x65\x92\x95\xd7\x14\xa0\xa1\xf3\xc9\x5c\xc3\xa8\xdc\x1b\x05\x2f\x14\x23\x54\x4c\x12\xbb\xce\x25\xe8\x36\x4c\xd4\xb6\x13\x77\xd9\xa4\x5a\x5e\xa4\x87\xbe\xd1\xb5\x63\x10\x10\x16\xe9\xd8\x8f\x1d\x6d\xd0\x8c\x2b\x4f\x37\xf9\x0a\xf9\x10\x55\x9d\x2c\xa7\xeb\x62\xf5\x1a\xbb\x25\xb9\xb6\x1b\xc6\xd9\xf9\x20\x66\x0f\x55\x86\x87\x9e\x97\x0c\x73\xde\x0a\x55\x4b\x70\x0f\x2e\x38\x10\xc6\xcd\x54\x20\xdf\x45\x6f\x70\x4d\x81\xa0\x03\x60\xec\xcd\xb1\x9a\x55\xc7\x3a\x9b\xc7\x84\x02\x23\xac\x40\x6b\xa3\xc1\x4e\xc4\x8b\xee\x8b\xfd\xc0\xf8\xc6\x19\x59\xc7\x42\x16\x84\x46\xab\x68\x31\xec\xb3\x51\x96\x05\x61\x46\x73\xf2\x2f\x0e\x89\x3a\xf9\x1f\x80\x46\x88\xf0\xdd\xb4\x00\x00"),
 		},
 		"/idempotent/matcher-functions.sql": &vfsgen۰CompressedFileInfo{
 			name:             "matcher-functions.sql",
@@ -79,10 +79,16 @@ var MigrationFiles = func() http.FileSystem {
 			name:    "dev",
 			modTime: time.Time{},
 		},
-		"/versions/dev/.gitignore": &vfsgen۰FileInfo{
-			name:    ".gitignore",
+		"/versions/dev/0.1.0-beta.2.dev": &vfsgen۰DirInfo{
+			name:    "0.1.0-beta.2.dev",
 			modTime: time.Time{},
-			content: []byte(""),
+		},
+		"/versions/dev/0.1.0-beta.2.dev/1-nullable_values.sql": &vfsgen۰CompressedFileInfo{
+			name:             "1-nullable_values.sql",
+			modTime:          time.Time{},
+			uncompressedSize: 651,
+
+			compressedContent: []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\x03\x5d\x92\xc9\x6e\xe3\x30\x0c\x86\xef\x7e\x0a\x1e\x12\xa4\x03\x34\x7e\x81\x9c\x1c\x5b\x6d\x0d\x28\x56\xe0\x28\x9d\xde\x02\x8d\xcd\x4c\x84\xda\x92\x20\x29\x5d\xde\xbe\x8c\x9d\x6d\x86\x27\x89\xcb\xcf\x4f\xa4\xe6\xf3\x57\xd5\x1d\x31\x40\x88\xd6\x63\x0b\x2a\x40\xb5\xe5\x1c\x7a\xe5\xdf\x01\xbf\x5c\xa7\x1b\x1d\xe1\xaf\x72\x01\xec\x1e\x82\x53\x3e\x20\x04\xf4\x1a\x43\x0a\x52\xf7\x18\x1a\xd5\x61\xb1\x84\xd6\x62\x48\xe6\x73\x63\x23\xa8\x2e\xa2\x87\x78\x40\x68\x6c\x77\xec\xcd\x50\x7a\xf8\x76\xe8\xa3\xfa\xd3\x51\xaf\x4f\x1d\x0f\x14\xeb\x9d\xc7\x10\xb4\x35\x80\xe6\x14\x68\x1f\xa9\xc8\x92\xfe\x3b\xa2\x23\x2d\x52\xd0\x1e\x2a\x21\x47\xa4\xc6\x9a\x10\xbd\xd2\x86\x3a\x98\xf6\xac\x6f\x0c\x36\x44\x0e\xad\xb7\xee\x8c\xfe\x31\x3e\x68\x6f\x07\x86\x3e\x4d\x0a\x01\x93\x49\x52\xb0\x9c\x67\x35\x4b\x80\xcc\x43\xcd\x72\x51\x17\x8b\x64\xc9\x9e\xcb\x6a\xf0\x3d\x89\x9a\xfc\xe7\xcb\xc9\x36\x8c\xb3\x5c\xc2\xc0\xbc\x33\xaa\xc7\x6b\xe4\xa9\x16\x2b\xd8\xe4\x2f\x6c\x95\xed\xf2\x4c\x66\x5c\x3c\xa7\x3d\x46\xaf\x9b\x21\x85\x0b\xb1\xbe\xe6\xde\x1a\x5c\x8c\xbd\xb1\x7c\x2b\xd9\x09\xb0\x57\xf1\x61\x96\x71\xc9\x6a\x90\xd9\x92\xb3\x8b\x68\x41\xa2\xe9\xb4\x84\x31\x94\x0b\xbe\x5d\x55\xe3\xbb\xa0\xa8\xc5\xfa\x3a\x93\xd9\x23\xf8\xf4\x06\xf8\x6b\x91\xdc\x9a\xe4\x6c\x2d\x4b\x51\xc1\xef\x17\x56\xc1\x1e\x55\x3c\x7a\xca\xb2\x71\x17\x8e\xce\x59\x1f\x69\xd9\x92\x42\xff\xa0\xd5\x59\xb9\x61\x27\xf5\x32\x67\x30\x3b\x0f\x92\x96\x77\xd9\x15\xd5\x0c\xdd\x60\x9a\x4e\xe9\xc7\xa8\xef\x7b\x92\xd9\x1d\xfc\x7f\x60\x77\x5c\x55\x31\x5e\xe8\x30\x0c\x6a\x91\xd0\x29\x99\x4c\x16\xc9\x0f\x67\x8a\x8f\x65\x8b\x02\x00\x00"),
 		},
 	}
 	fs["/"].(*vfsgen۰DirInfo).entries = []os.FileInfo{
@@ -104,7 +110,10 @@ var MigrationFiles = func() http.FileSystem {
 		fs["/versions/dev"].(os.FileInfo),
 	}
 	fs["/versions/dev"].(*vfsgen۰DirInfo).entries = []os.FileInfo{
-		fs["/versions/dev/.gitignore"].(os.FileInfo),
+		fs["/versions/dev/0.1.0-beta.2.dev"].(os.FileInfo),
+	}
+	fs["/versions/dev/0.1.0-beta.2.dev"].(*vfsgen۰DirInfo).entries = []os.FileInfo{
+		fs["/versions/dev/0.1.0-beta.2.dev/1-nullable_values.sql"].(os.FileInfo),
 	}
 
 	return fs
@@ -0,0 +1,19 @@
+--Values stored as NULL mark explicit gaps of sparse series. TimescaleDB does
+--not alter the columns of hypertables with compression enabled, those keep
+--their NOT NULL constraint and the connector drops NULL values for them.
+DO $$
+DECLARE
+    r RECORD;
+BEGIN
+    FOR r IN
+        SELECT table_name
+        FROM SCHEMA_CATALOG.metric
+    LOOP
+        BEGIN
+            EXECUTE format('ALTER TABLE SCHEMA_DATA.%I ALTER COLUMN value DROP NOT NULL', r.table_name);
+        EXCEPTION WHEN feature_not_supported THEN
+            RAISE NOTICE 'values of compressed table %.% stay NOT NULL', 'SCHEMA_DATA', r.table_name;
+        END;
+    END LOOP;
+END
+$$;
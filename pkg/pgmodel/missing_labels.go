@@ -0,0 +1,45 @@
+// This file and its contents are licensed under the Apache License 2.0.
+// Please see the included NOTICE for copyright information and
+// LICENSE for a copy of the license.
+
+package pgmodel
+
+import (
+	"context"
+
+	"github.com/prometheus/prometheus/pkg/labels"
+)
+
+// missingLabelNameSQL returns one of the names no label of the catalog has.
+const missingLabelNameSQL = "SELECT name FROM unnest($1::text[]) name WHERE NOT EXISTS (SELECT 1 FROM " + catalogSchema + ".label l WHERE l.key = name) LIMIT 1"
+
+// requiredLabelNames returns the names of the labels a series must have to
+// be matched. Matchers that match the empty value also match series without
+// the label, e.g. job="" or job!="api", and the metric name is left out since
+// every series has one.
+func requiredLabelNames(matchers []*labels.Matcher) []string {
+	var names []string
+	for _, m := range matchers {
+		if m.Name == MetricNameLabelName || m.Matches("") {
+			continue
+		}
+		names = append(names, m.Name)
+	}
+	return names
+}
+
+// hasMissingLabelName returns true if a matcher requires a label no series
+// has, in which case the query matches no series.
+func (q *pgxQuerier) hasMissingLabelName(ctx context.Context, matchers []*labels.Matcher) (bool, error) {
+	names := requiredLabelNames(matchers)
+	if len(names) == 0 {
+		return false, nil
+	}
+	rows, err := q.queryWithRetry(ctx, missingLabelNameSQL, names)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	missing := rows.Next()
+	return missing, rows.Err()
+}
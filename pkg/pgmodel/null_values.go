@@ -0,0 +1,101 @@
+// This file and its contents are licensed under the Apache License 2.0.
+// Please see the included NOTICE for copyright information and
+// LICENSE for a copy of the license.
+
+package pgmodel
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgtype"
+	"github.com/prometheus/prometheus/pkg/value"
+)
+
+// nullValues are the bit patterns of the sample values stored as NULL, which
+// the reads skip like missing samples. Matching bits rather than values lets
+// a specific NaN be a sentinel.
+type nullValues map[uint64]bool
+
+// newNullValues returns the sentinels for the values. Stale markers are never
+// sentinels, PromQL needs them to end series.
+func newNullValues(vs []float64) nullValues {
+	if len(vs) == 0 {
+		return nil
+	}
+	n := make(nullValues, len(vs))
+	for _, v := range vs {
+		if !value.IsStaleNaN(v) {
+			n[math.Float64bits(v)] = true
+		}
+	}
+	return n
+}
+
+// isNull returns true if v is stored as NULL.
+func (n nullValues) isNull(v float64) bool {
+	return n[math.Float64bits(v)]
+}
+
+// values returns the insert argument for the values. It is vals itself unless
+// one of them is a sentinel, in which case an array with NULL elements in
+// place of the sentinels is returned.
+func (n nullValues) values(vals []float64) interface{} {
+	if len(n) == 0 {
+		return vals
+	}
+	hasNull := false
+	for _, v := range vals {
+		if n.isNull(v) {
+			hasNull = true
+			break
+		}
+	}
+	if !hasNull {
+		return vals
+	}
+
+	elements := make([]pgtype.Float8, len(vals))
+	for i, v := range vals {
+		if n.isNull(v) {
+			elements[i] = pgtype.Float8{Status: pgtype.Null}
+			continue
+		}
+		elements[i] = pgtype.Float8{Float: v, Status: pgtype.Present}
+	}
+	return pgtype.Float8Array{
+		Elements:   elements,
+		Dimensions: []pgtype.ArrayDimension{{Length: int32(len(vals)), LowerBound: 1}},
+		Status:     pgtype.Present,
+	}
+}
+
+// withoutNulls returns the rows without the ones whose value is stored as
+// NULL, and the number of rows dropped.
+func (n nullValues) withoutNulls(rows sampleRows) (sampleRows, int64) {
+	kept := sampleRows{
+		times:  make([]time.Time, 0, len(rows.times)),
+		vals:   make([]float64, 0, len(rows.vals)),
+		series: make([]int64, 0, len(rows.series)),
+	}
+	for i, v := range rows.vals {
+		if n.isNull(v) {
+			continue
+		}
+		kept.times = append(kept.times, rows.times[i])
+		kept.vals = append(kept.vals, v)
+		kept.series = append(kept.series, rows.series[i])
+	}
+	return kept, int64(len(rows.vals) - len(kept.vals))
+}
+
+// isNotNullViolation returns true if err is a NOT NULL constraint violation,
+// as for the value column of data tables that were compressed before values
+// could be NULL.
+func isNotNullViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgerrcode.NotNullViolation
+}
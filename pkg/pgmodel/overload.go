@@ -0,0 +1,76 @@
+// This file and its contents are licensed under the Apache License 2.0.
+// Please see the included NOTICE for copyright information and
+// LICENSE for a copy of the license.
+
+package pgmodel
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ErrOverloaded is returned by Overloaded while the ingestor can not keep up
+// with the writes it receives.
+var ErrOverloaded = fmt.Errorf("ingestion overloaded")
+
+// OverloadPolicy decides when the ingestor reports that it is overloaded, so
+// that clients slow down rather than have their writes time out. A zero
+// threshold is not checked.
+type OverloadPolicy struct {
+	// MaxQueueDepth is the number of write requests queued for insertion
+	// above which the ingestor is overloaded.
+	MaxQueueDepth int
+	// MaxPoolSaturation is the fraction of the connections inserting
+	// samples that are busy above which the ingestor is overloaded, e.g.
+	// 0.9. Connections used by reads are not counted, even if reads share
+	// the pool.
+	MaxPoolSaturation float64
+	// RetryAfter is how long clients are asked to wait before retrying.
+	RetryAfter time.Duration
+}
+
+// OverloadReporter reports whether new writes should be retried later.
+type OverloadReporter interface {
+	// Overloaded returns an error wrapping ErrOverloaded while the ingestor
+	// is overloaded, along with how long to wait before retrying.
+	Overloaded() (time.Duration, error)
+}
+
+// Overloaded implements OverloadReporter. Inserters that can not report
+// overload are never overloaded.
+func (i *DBIngestor) Overloaded() (time.Duration, error) {
+	if r, ok := i.db.(OverloadReporter); ok {
+		return r.Overloaded()
+	}
+	return 0, nil
+}
+
+// Overloaded implements OverloadReporter, checking the depth of the insert
+// queues and the share of busy copiers, each inserting with a connection of
+// its own, against the thresholds of the overload policy.
+func (p *pgxInserter) Overloaded() (time.Duration, error) {
+	if max := p.overload.MaxQueueDepth; max > 0 {
+		if depth := p.queueDepth(); depth > max {
+			return p.overload.RetryAfter, fmt.Errorf("%w: %d requests queued, more than %d", ErrOverloaded, depth, max)
+		}
+	}
+	if max := p.overload.MaxPoolSaturation; max > 0 && p.numCopiers > 0 {
+		busy := atomic.LoadInt64(&p.copierOptions.busyCopiers)
+		if saturation := float64(busy) / float64(p.numCopiers); saturation > max {
+			return p.overload.RetryAfter, fmt.Errorf("%w: %.0f%% of insert connections in use, more than %.0f%%", ErrOverloaded, saturation*100, max*100)
+		}
+	}
+	return 0, nil
+}
+
+// queueDepth returns the number of requests waiting in the queues of the
+// metrics and of the copiers.
+func (p *pgxInserter) queueDepth() int {
+	depth := len(p.toCopiers)
+	p.inserters.Range(func(key, value interface{}) bool {
+		depth += len(value.(chan insertDataRequest))
+		return true
+	})
+	return depth
+}
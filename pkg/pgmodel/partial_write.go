@@ -0,0 +1,45 @@
+// This file and its contents are licensed under the Apache License 2.0.
+// Please see the included NOTICE for copyright information and
+// LICENSE for a copy of the license.
+
+package pgmodel
+
+import (
+	"fmt"
+
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/timescale/timescale-prometheus/pkg/prompb"
+)
+
+// PartialWriteError is returned by Ingest in partial write mode for write
+// requests of which some series can not be stored. The other series of the
+// request are inserted.
+type PartialWriteError struct {
+	// Inserted is the number of samples inserted.
+	Inserted uint64
+	// FailedSamples is the number of samples of the rejected series.
+	FailedSamples int
+	// FailedFingerprints are the fingerprints of the rejected series, see
+	// FingerprintFor.
+	FailedFingerprints []uint64
+	// Err is the error of the first series rejected.
+	Err error
+}
+
+func (e *PartialWriteError) Error() string {
+	return fmt.Sprintf("partial write: %d series with %d samples rejected, first: %v", len(e.FailedFingerprints), e.FailedSamples, e.Err)
+}
+
+func (e *PartialWriteError) Unwrap() error {
+	return e.Err
+}
+
+// reject records a series left out of the write.
+func (e *PartialWriteError) reject(t *prompb.TimeSeries) {
+	ls := make(labels.Labels, len(t.Labels))
+	for i, l := range t.Labels {
+		ls[i] = labels.Label{Name: l.Name, Value: l.Value}
+	}
+	e.FailedFingerprints = append(e.FailedFingerprints, FingerprintFor(ls))
+	e.FailedSamples += len(t.Samples)
+}
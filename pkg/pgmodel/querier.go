@@ -5,6 +5,8 @@
 package pgmodel
 
 import (
+	"context"
+
 	"github.com/prometheus/prometheus/pkg/labels"
 	"github.com/prometheus/prometheus/promql/parser"
 	"github.com/prometheus/prometheus/storage"
@@ -13,20 +15,32 @@ import (
 
 // Reader reads the data based on the provided read request.
 type Reader interface {
-	Read(*prompb.ReadRequest) (*prompb.ReadResponse, error)
+	Read(context.Context, *prompb.ReadRequest) (*prompb.ReadResponse, error)
 }
 
 // Querier queries the data using the provided query data and returns the
 // matching timeseries.
 type Querier interface {
-	Query(*prompb.Query) ([]*prompb.TimeSeries, error)
-	Select(mint int64, maxt int64, sortSeries bool, hints *storage.SelectHints, path []parser.Node, ms ...*labels.Matcher) (storage.SeriesSet, parser.Node, storage.Warnings, error)
-	LabelNames() ([]string, error)
-	LabelValues(labelName string) ([]string, error)
+	// Query and Select run their database queries in ctx, see
+	// ReaderCfg.PropagateCancel.
+	Query(ctx context.Context, query *prompb.Query) ([]*prompb.TimeSeries, error)
+	Select(ctx context.Context, mint int64, maxt int64, sortSeries bool, hints *storage.SelectHints, path []parser.Node, ms ...*labels.Matcher) (storage.SeriesSet, parser.Node, storage.Warnings, error)
+	// LabelNames and LabelValues only consider the series selected by the
+	// matchers, or all series if there are none. Like Select, their queries
+	// run in ctx, bounded by the maximum query duration.
+	LabelNames(ctx context.Context, matchers ...*labels.Matcher) ([]string, error)
+	LabelValues(ctx context.Context, labelName string, matchers ...*labels.Matcher) ([]string, error)
 	NumCachedLabels() int
 	LabelsCacheCapacity() int
 }
 
+// TenantScoper constrains the matchers of a read to the series of the tenant
+// of its context, see Tenancy. Queriers without it read the series of all
+// tenants.
+type TenantScoper interface {
+	ScopeToTenant(ctx context.Context, matchers []*labels.Matcher) ([]*labels.Matcher, error)
+}
+
 //HealthChecker allows checking for proper operations.
 type HealthChecker interface {
 	HealthCheck() error
@@ -47,7 +61,7 @@ func (r *DBReader) GetQuerier() QueryHealthChecker {
 	return r.db
 }
 
-func (r *DBReader) Read(req *prompb.ReadRequest) (*prompb.ReadResponse, error) {
+func (r *DBReader) Read(ctx context.Context, req *prompb.ReadRequest) (*prompb.ReadResponse, error) {
 	if req == nil {
 		return nil, nil
 	}
@@ -57,7 +71,7 @@ func (r *DBReader) Read(req *prompb.ReadRequest) (*prompb.ReadResponse, error) {
 	}
 
 	for i, q := range req.Queries {
-		tts, err := r.db.Query(q)
+		tts, err := r.db.Query(ctx, q)
 		if err != nil {
 			return nil, err
 		}
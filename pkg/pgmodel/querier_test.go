@@ -4,6 +4,7 @@
 package pgmodel
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"testing"
@@ -24,19 +25,19 @@ type mockQuerier struct {
 
 var _ Querier = (*mockQuerier)(nil)
 
-func (q *mockQuerier) Select(mint int64, maxt int64, sortSeries bool, hints *storage.SelectHints, path []parser.Node, ms ...*labels.Matcher) (storage.SeriesSet, parser.Node, storage.Warnings, error) {
+func (q *mockQuerier) Select(ctx context.Context, mint int64, maxt int64, sortSeries bool, hints *storage.SelectHints, path []parser.Node, ms ...*labels.Matcher) (storage.SeriesSet, parser.Node, storage.Warnings, error) {
 	return nil, nil, nil, nil
 }
 
-func (q *mockQuerier) Query(*prompb.Query) ([]*prompb.TimeSeries, error) {
+func (q *mockQuerier) Query(context.Context, *prompb.Query) ([]*prompb.TimeSeries, error) {
 	return q.tts, q.err
 }
 
-func (q *mockQuerier) LabelNames() ([]string, error) {
+func (q *mockQuerier) LabelNames(context.Context, ...*labels.Matcher) ([]string, error) {
 	return q.labelNames, q.labelNamesErr
 }
 
-func (q *mockQuerier) LabelValues(string) ([]string, error) {
+func (q *mockQuerier) LabelValues(context.Context, string, ...*labels.Matcher) ([]string, error) {
 	return nil, nil
 }
 
@@ -136,7 +137,7 @@ func TestDBReaderRead(t *testing.T) {
 
 			r := DBReader{mq}
 
-			res, err := r.Read(c.req)
+			res, err := r.Read(context.Background(), c.req)
 
 			if err != nil {
 				if c.err == nil || err != c.err {
@@ -5,18 +5,21 @@
 package pgmodel
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"sort"
 	"strings"
 	"time"
 
+	"github.com/jackc/pgtype"
 	"github.com/jackc/pgx/v4"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/pkg/labels"
 	"github.com/prometheus/prometheus/pkg/timestamp"
 	"github.com/prometheus/prometheus/promql/parser"
 	"github.com/prometheus/prometheus/storage"
+	"github.com/timescale/timescale-prometheus/pkg/log"
 	"github.com/timescale/timescale-prometheus/pkg/prompb"
 )
 
@@ -55,6 +58,41 @@ const (
 	AND time >= '%[4]s'
 	AND time <= '%[5]s'
 	GROUP BY s.id`
+
+	// The bucketed variants roll the samples of each series up into a time
+	// grid before aggregating them into arrays.
+	timeseriesByMetricBucketedSQLFormat = `
+	FROM (
+		SELECT series_id, %[6]s AS time, %[7]s AS value
+		FROM %[1]s
+		WHERE time >= '%[4]s'
+		AND time <= '%[5]s'
+		GROUP BY series_id, 2
+	) m
+	INNER JOIN %[2]s s
+	ON m.series_id = s.id
+	WHERE %[3]s
+	GROUP BY s.id`
+
+	timeseriesBySeriesIDsBucketedSQLFormat = `SELECT s.labels, array_agg(m.time ORDER BY time), array_agg(m.value ORDER BY time)
+	FROM (
+		SELECT series_id, %[6]s AS time, %[7]s AS value
+		FROM %[1]s
+		WHERE series_id IN (%[3]s)
+		AND time >= '%[4]s'
+		AND time <= '%[5]s'
+		GROUP BY series_id, 2
+	) m
+	INNER JOIN %[2]s s
+	ON m.series_id = s.id
+	GROUP BY s.id`
+
+	// lastValueAgg keeps the latest sample of every bucket.
+	lastValueAgg = "last(value, time)"
+
+	// metricSeriesClause selects every series of the series view of a
+	// metric, which only holds the series with the metric's name.
+	metricSeriesClause = "TRUE"
 )
 
 var (
@@ -62,7 +100,7 @@ var (
 	maxTime = timestamp.FromTime(time.Unix(math.MaxInt64/1000-62135596801, 999999999).UTC())
 )
 
-func buildSubQueries(matchers []*labels.Matcher) (string, []string, []interface{}, error) {
+func buildSubQueries(matchers []*labels.Matcher, caseInsensitive caseInsensitiveLabels) (string, []string, []interface{}, error) {
 	var err error
 	metric := ""
 	metricMatcherCount := 0
@@ -88,25 +126,25 @@ func buildSubQueries(matchers []*labels.Matcher) (string, []string, []interface{
 			if matchesEmpty {
 				sq = subQueryEQMatchEmpty
 			}
-			err = cb.addClause(sq, m.Name, m.Value)
+			err = cb.addClause(caseInsensitive.subQuery(m, sq), m.Name, m.Value)
 		case labels.MatchNotEqual:
 			sq := subQueryNEQ
 			if matchesEmpty {
 				sq = subQueryNEQMatchEmpty
 			}
-			err = cb.addClause(sq, m.Name, m.Value)
+			err = cb.addClause(caseInsensitive.subQuery(m, sq), m.Name, m.Value)
 		case labels.MatchRegexp:
 			sq := subQueryRE
 			if matchesEmpty {
 				sq = subQueryREMatchEmpty
 			}
-			err = cb.addClause(sq, m.Name, anchorValue(m.Value))
+			err = cb.addClause(caseInsensitive.subQuery(m, sq), m.Name, anchorValue(m.Value))
 		case labels.MatchNotRegexp:
 			sq := subQueryNRE
 			if matchesEmpty {
 				sq = subQueryNREMatchEmpty
 			}
-			err = cb.addClause(sq, m.Name, anchorValue(m.Value))
+			err = cb.addClause(caseInsensitive.subQuery(m, sq), m.Name, anchorValue(m.Value))
 		}
 
 		if err != nil {
@@ -172,52 +210,126 @@ func (c *clauseBuilder) build() ([]string, []interface{}) {
 	return c.clauses, c.args
 }
 
-func buildSeriesSet(rows []pgx.Rows, sortSeries bool, querier *pgxQuerier) (storage.SeriesSet, storage.Warnings, error) {
-	return &pgxSeriesSet{
-		rows:    rows,
-		querier: querier,
-	}, nil, nil
+// buildSeriesSet returns the set of the series of the rows. If sortSeries is
+// set, the series are sorted by their labels, otherwise they are returned in
+// the order they are read. The labels of the series are fetched in the query
+// context of ctx.
+func buildSeriesSet(ctx context.Context, rows []pgx.Rows, sortSeries bool, hints *storage.SelectHints, matchers []*labels.Matcher, querier *pgxQuerier) (storage.SeriesSet, storage.Warnings, error) {
+	labelFilter, err := querier.caseInsensitive.filter(matchers)
+	if err != nil {
+		return nil, nil, err
+	}
+	ss := &pgxSeriesSet{
+		ctx:            ctx,
+		rows:           rows,
+		querier:        querier,
+		valuePrecision: querier.valuePrecision,
+		enricher:       querier.newLabelEnricher(),
+		fill:           querier.newGapFill(hints),
+		emptyLabels:    querier.emptyLabels,
+		namePrefix:     querier.metricNamePrefix,
+		valueFilter:    querier.valueFilter,
+		staleGap:       querier.staleGap,
+		labelFilter:    labelFilter,
+		// elements failing to decode are reported by the series' iterators
+		failOnDecodeErrors: querier.failOnDecodeErrors,
+		monotonicCounters:  querier.monotonicCounters,
+		rewrites:           querier.labelRewrites,
+		ignoreExtraColumns: querier.ignoreExtraColumns,
+		infiniteTimestamps: querier.infiniteTimestamps,
+	}
+	var set storage.SeriesSet = ss
+	if querier.mergeRewrittenSeries && len(querier.labelRewrites) > 0 {
+		set = newMergedSeriesSet(set)
+	}
+	if sortSeries {
+		set = newSortedSeriesSet(set)
+	}
+	return set, nil, nil
 }
 
-func buildTimeSeries(rows pgx.Rows, q *pgxQuerier) ([]*prompb.TimeSeries, error) {
+func buildTimeSeries(ctx context.Context, rows pgx.Rows, q *pgxQuerier, labelFilter []*labels.Matcher) ([]*prompb.TimeSeries, error) {
 	results := make([]*prompb.TimeSeries, 0)
+	enricher := q.newLabelEnricher()
 
+	numRows := 0
 	for rows.Next() {
+		numRows++
 		var (
 			labelIDs   []int64
 			timestamps []time.Time
-			values     []float64
+			// values stored as NULL are not samples
+			values pgtype.Float8Array
 		)
-		err := rows.Scan(&labelIDs, &timestamps, &values)
+		err := scanSeriesRow(rows, q.ignoreExtraColumns, &labelIDs, &timestamps, &values)
 
 		if err != nil {
 			return nil, err
 		}
 
-		if len(timestamps) != len(values) {
+		if len(timestamps) != len(values.Elements) {
 			return nil, fmt.Errorf("query returned a mismatch in timestamps and values")
 		}
 
-		promLabels, err := q.getPrompbLabelsForIds(labelIDs)
+		promLabels, err := q.getPrompbLabelsForIds(ctx, labelIDs)
 		if err != nil {
 			return nil, err
 		}
+		promLabels, err = enricher.enrichPrompb(promLabels)
+		if err != nil {
+			return nil, err
+		}
+		promLabels = q.labelRewrites.applyPrompb(promLabels)
+
+		if len(promLabels) == 0 {
+			log.Warn("msg", "series without labels read", "policy", q.emptyLabels)
+			switch q.emptyLabels {
+			case DropEmptyLabels:
+				continue
+			case LabelEmptyLabels:
+				promLabels = []prompb.Label{{Name: UnlabeledSeriesLabel.Name, Value: UnlabeledSeriesLabel.Value}}
+			}
+		}
 
 		sort.Slice(promLabels, func(i, j int) bool {
 			return promLabels[i].Name < promLabels[j].Name
 		})
+		if !matchesLabels(labelFilter, func(name string) string { return prompbLabelValue(promLabels, name) }) {
+			continue
+		}
 
 		result := &prompb.TimeSeries{
 			Labels:  promLabels,
 			Samples: make([]prompb.Sample, 0, len(timestamps)),
 		}
 
+		sigFigs, monotonic := 0, false
+		for i := range promLabels {
+			if promLabels[i].Name == MetricNameLabelName {
+				sigFigs = q.valuePrecision[promLabels[i].Value]
+				monotonic = q.monotonicCounters[promLabels[i].Value]
+				promLabels[i].Value = q.metricNamePrefix + promLabels[i].Value
+				break
+			}
+		}
 		for i := range timestamps {
+			if values.Elements[i].Status != pgtype.Present {
+				continue
+			}
 			result.Samples = append(result.Samples, prompb.Sample{
 				Timestamp: toMilis(timestamps[i]),
-				Value:     values[i],
+				Value:     values.Elements[i].Float,
 			})
 		}
+		if monotonic {
+			mergeCounterResets(result.Samples)
+		}
+		for i := range result.Samples {
+			result.Samples[i].Value = roundSignificant(result.Samples[i].Value, sigFigs)
+		}
+		if !q.valueFilter.matchesSamples(result.Samples) {
+			continue
+		}
 
 		results = append(results, result)
 	}
@@ -225,7 +337,11 @@ func buildTimeSeries(rows pgx.Rows, q *pgxQuerier) ([]*prompb.TimeSeries, error)
 	if rows.Err() != nil {
 		return nil, rows.Err()
 	}
+	readRowsPerQuery.Observe(float64(numRows))
 
+	if q.mergeRewrittenSeries && len(q.labelRewrites) > 0 {
+		results = mergeTimeSeries(results)
+	}
 	return results, nil
 }
 
@@ -238,9 +354,21 @@ func buildTimeseriesBySeriesIDQuery(filter metricTimeRangeFilter, series []Serie
 	for _, sID := range series {
 		s = append(s, fmt.Sprintf("%d", sID))
 	}
+	if filter.bucket != nil {
+		return fmt.Sprintf(
+			timeseriesBySeriesIDsBucketedSQLFormat,
+			filter.dataTable(),
+			pgx.Identifier{dataSeriesSchema, filter.metric}.Sanitize(),
+			strings.Join(s, ","),
+			filter.startTime,
+			filter.endTime,
+			filter.bucket.timeSQL(),
+			filter.bucket.agg,
+		)
+	}
 	return fmt.Sprintf(
 		timeseriesBySeriesIDsSQLFormat,
-		pgx.Identifier{dataSchema, filter.metric}.Sanitize(),
+		filter.dataTable(),
 		pgx.Identifier{dataSeriesSchema, filter.metric}.Sanitize(),
 		strings.Join(s, ","),
 		filter.startTime,
@@ -248,16 +376,37 @@ func buildTimeseriesBySeriesIDQuery(filter metricTimeRangeFilter, series []Serie
 	)
 }
 
+// buildUnionQuery returns the UNION ALL of the selections of several metric
+// tables. Series ids are unique across metrics, so every series is still
+// returned by a single row.
+func buildUnionQuery(queries []string) string {
+	return "(" + strings.Join(queries, ")\nUNION ALL\n(") + ")"
+}
+
 func buildTimeseriesByLabelClausesQuery(filter metricTimeRangeFilter, cases []string, values []interface{},
 	hints *storage.SelectHints, path []parser.Node) (string, []interface{}, parser.Node, error) {
-	restOfQuery := fmt.Sprintf(
-		timeseriesByMetricSQLFormat,
-		pgx.Identifier{dataSchema, filter.metric}.Sanitize(),
-		pgx.Identifier{dataSeriesSchema, filter.metric}.Sanitize(),
-		strings.Join(cases, " AND "),
-		filter.startTime,
-		filter.endTime,
-	)
+	var restOfQuery string
+	if filter.bucket != nil {
+		restOfQuery = fmt.Sprintf(
+			timeseriesByMetricBucketedSQLFormat,
+			filter.dataTable(),
+			pgx.Identifier{dataSeriesSchema, filter.metric}.Sanitize(),
+			strings.Join(cases, " AND "),
+			filter.startTime,
+			filter.endTime,
+			filter.bucket.timeSQL(),
+			filter.bucket.agg,
+		)
+	} else {
+		restOfQuery = fmt.Sprintf(
+			timeseriesByMetricSQLFormat,
+			filter.dataTable(),
+			pgx.Identifier{dataSeriesSchema, filter.metric}.Sanitize(),
+			strings.Join(cases, " AND "),
+			filter.startTime,
+			filter.endTime,
+		)
+	}
 
 	qf, node, err := getQueryFinalizer(restOfQuery, values, hints, path)
 	if err != nil {
@@ -296,6 +445,20 @@ func (t *queryFinalizer) Finalize() (string, []interface{}, error) {
 	return fillInParameters(fullQuery, t.restOfQueryParams, newParams...)
 }
 
+// pushdownAggregates maps the PromQL range functions computed in the database
+// to the extension aggregates implementing them. The aggregates handle counter
+// resets like PromQL does, queries run without the extension fall back to
+// evaluating the raw samples.
+var pushdownAggregates = map[string]string{
+	"delta": "prom_delta",
+	"rate":  "prom_rate",
+}
+
+// staleMarkerFilter excludes Prometheus staleness markers from the pushed down
+// aggregates, as PromQL drops them from range vectors. In SQL the markers
+// compare equal to any other NaN, so is_stale_marker matches their bit pattern.
+const staleMarkerFilter = " FILTER (WHERE NOT " + promSchema + ".is_stale_marker(value))"
+
 /* The path is the list of ancestors (direct parent last) returned node is the most-ancestral node processed by the pushdown */
 func getQueryFinalizer(otherClauses string, values []interface{}, hints *storage.SelectHints, path []parser.Node) (*queryFinalizer, parser.Node, error) {
 	if ExtensionIsInstalled && path != nil && hints != nil && len(path) >= 2 && !hasSubquery(path) {
@@ -304,7 +467,7 @@ func getQueryFinalizer(otherClauses string, values []interface{}, hints *storage
 		node := path[len(path)-2]
 		switch n := node.(type) {
 		case *parser.Call:
-			if n.Func.Name == "delta" {
+			if agg, ok := pushdownAggregates[n.Func.Name]; ok {
 				topNode = node
 				queryStart := hints.Start + hints.Range
 				queryEnd := hints.End
@@ -321,7 +484,7 @@ func getQueryFinalizer(otherClauses string, values []interface{}, hints *storage
 				qf := queryFinalizer{
 					timeClause:        "ARRAY(SELECT generate_series($%d::timestamptz, $%d::timestamptz, $%d))",
 					timeParams:        []interface{}{model.Time(queryStart).Time(), model.Time(queryEnd).Time(), stepDuration},
-					valueClause:       "prom_delta($%d, $%d,$%d, $%d, time, value ORDER BY time ASC)",
+					valueClause:       agg + "($%d, $%d,$%d, $%d, time, value ORDER BY time ASC)" + staleMarkerFilter,
 					valueParams:       []interface{}{model.Time(hints.Start).Time(), model.Time(queryEnd).Time(), int64(stepDuration.Milliseconds()), int64(rangeDuration.Milliseconds())},
 					restOfQuery:       otherClauses,
 					restOfQueryParams: values,
@@ -369,27 +532,108 @@ func getSeriesPerMetric(rows pgx.Rows) ([]string, [][]SeriesID, error) {
 }
 
 // anchorValue adds anchors to values in regexps since PromQL docs
-// states that "Regex-matches are fully anchored."
+// states that "Regex-matches are fully anchored." Like Prometheus, the
+// value is wrapped in a group so anchors also apply to every alternative.
 func anchorValue(str string) string {
-	l := len(str)
-
-	if l == 0 {
+	if len(str) == 0 {
 		return "^$"
 	}
 
-	if str[0] == '^' && str[l-1] == '$' {
-		return str
+	return fmt.Sprintf("^(?:%s)$", str)
+}
+
+// timeBucket describes the time grid samples are rolled up into. Every sample
+// is assigned to the grid point at or before it, or to the nearest grid point
+// if nearest is set.
+type timeBucket struct {
+	// width of a bucket in milliseconds
+	width int64
+	// origin is a Unix timestamp in milliseconds that lies on the grid
+	origin int64
+	// nearest snaps samples to the nearest grid point. Samples exactly half
+	// a bucket past a grid point go to the next one.
+	nearest bool
+	// agg is the SQL aggregate combining the samples of a bucket
+	agg string
+}
+
+// newStepAlignment returns a timeBucket matching the evaluation steps of a
+// query, or nil if the query has no step. The engine moves the start of the
+// hints back from the first step by the range of the selector, or by
+// queryLookbackDelta, so the grid goes through the start plus that amount. The
+// offset of the selector moves the start and the steps alike. Subqueries are
+// evaluated at steps of their own, so their samples are not aligned.
+func newStepAlignment(hints *storage.SelectHints, path []parser.Node) *timeBucket {
+	if hints == nil || hints.Step <= 0 {
+		return nil
+	}
+	for _, n := range path {
+		if _, ok := n.(*parser.SubqueryExpr); ok {
+			return nil
+		}
+	}
+	origin := hints.Start + queryLookbackDelta.Milliseconds()
+	if hints.Range > 0 {
+		origin = hints.Start + hints.Range
 	}
+	return &timeBucket{
+		width:   hints.Step,
+		origin:  origin,
+		nearest: true,
+		agg:     lastValueAgg,
+	}
+}
+
+// readHintsAggregates maps the functions of remote read hints to the SQL
+// aggregates downsampling a bucket. Staleness markers are left out unless a
+// bucket contains nothing else, in which case its latest marker is returned.
+var readHintsAggregates = map[string]string{
+	"avg":  staleAwareAggregate("avg"),
+	"min":  staleAwareAggregate("min"),
+	"max":  staleAwareAggregate("max"),
+	"last": lastValueAgg,
+}
 
-	if str[0] == '^' {
-		return fmt.Sprintf("%s$", str)
+func staleAwareAggregate(agg string) string {
+	return fmt.Sprintf("COALESCE(%s(value)%s, %s)", agg, staleMarkerFilter, lastValueAgg)
+}
+
+// newReadHintsBucket returns a timeBucket downsampling the samples of a remote
+// read query to the step of its hints, or nil if the hints have no step or a
+// function that is not supported.
+func newReadHintsBucket(hints *prompb.ReadHints) *timeBucket {
+	if hints == nil || hints.StepMs <= 0 {
+		return nil
+	}
+	agg, ok := readHintsAggregates[hints.Func]
+	if !ok {
+		return nil
+	}
+	return &timeBucket{
+		width:  hints.StepMs,
+		origin: hints.StartMs,
+		agg:    agg,
 	}
+}
 
-	if str[l-1] == '$' {
-		return fmt.Sprintf("^%s", str)
+// newGapFill returns the gap interpolation for a query, or nil if it is
+// disabled or the samples are not aligned to the query's steps.
+func (q *pgxQuerier) newGapFill(hints *storage.SelectHints) *gapFill {
+	if !q.alignToStep || q.interpolationMaxGap <= 0 || hints == nil || hints.Step <= 0 {
+		return nil
 	}
+	return &gapFill{step: hints.Step, maxGap: q.interpolationMaxGap}
+}
 
-	return fmt.Sprintf("^%s$", str)
+// timeSQL returns the SQL expression of the grid point a sample is assigned
+// to. Snapping to the nearest grid point shifts the buckets back by half a
+// bucket and labels them by their middle.
+func (b *timeBucket) timeSQL() string {
+	if !b.nearest {
+		return fmt.Sprintf("time_bucket(INTERVAL '%d milliseconds', time, TIMESTAMPTZ '%s')", b.width, toRFC3339Nano(b.origin))
+	}
+	half := b.width / 2
+	return fmt.Sprintf("time_bucket(INTERVAL '%d milliseconds', time, TIMESTAMPTZ '%s') + INTERVAL '%d milliseconds'", b.width, toRFC3339Nano(b.origin-half), half)
 }
 
 func toMilis(t time.Time) int64 {
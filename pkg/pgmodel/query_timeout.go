@@ -0,0 +1,98 @@
+// This file and its contents are licensed under the Apache License 2.0.
+// Please see the included NOTICE for copyright information and
+// LICENSE for a copy of the license.
+
+package pgmodel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// ErrQueryTimeout is returned for reads that did not finish within the
+// maximum query duration or the deadline of their request.
+var ErrQueryTimeout = fmt.Errorf("query timed out")
+
+// queryContext returns the context the database queries of a request with
+// context ctx run in, bounded by the maximum query duration. cancel must be
+// called once the queries are done.
+func (q *pgxQuerier) queryContext(ctx context.Context) (queryCtx context.Context, cancel context.CancelFunc) {
+	if !q.propagateCancel || ctx == nil {
+		ctx = context.Background()
+	}
+	if q.maxQueryDuration <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, q.maxQueryDuration)
+}
+
+// timeoutError returns ErrQueryTimeout if err was caused by the deadline of
+// ctx, and err otherwise.
+func timeoutError(ctx context.Context, err error) error {
+	if err == nil || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	return fmt.Errorf("%w: %v", ErrQueryTimeout, err)
+}
+
+// queryRows wraps the rows of the queries of a read sharing a query context,
+// calling cancel once all of them are closed. Errors caused by the context's
+// deadline are reported as ErrQueryTimeout.
+func queryRows(ctx context.Context, rows []pgx.Rows, cancel context.CancelFunc) []pgx.Rows {
+	if len(rows) == 0 {
+		cancel()
+		return rows
+	}
+	release := &queryRelease{open: len(rows), cancel: cancel}
+	wrapped := make([]pgx.Rows, len(rows))
+	for i, r := range rows {
+		wrapped[i] = &timeoutRows{Rows: r, ctx: ctx, release: release}
+	}
+	return wrapped
+}
+
+// queryRelease cancels a query context once the last of the rows read in it
+// is closed.
+type queryRelease struct {
+	lock   sync.Mutex
+	open   int
+	cancel context.CancelFunc
+}
+
+func (r *queryRelease) closed() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.open--; r.open == 0 {
+		r.cancel()
+	}
+}
+
+// timeoutRows are rows read in a query context bounded by a deadline.
+type timeoutRows struct {
+	pgx.Rows
+	ctx     context.Context
+	release *queryRelease
+	once    sync.Once
+}
+
+// Close closes the rows, releasing the query context once all rows sharing
+// it are closed.
+func (r *timeoutRows) Close() {
+	r.Rows.Close()
+	r.once.Do(r.release.closed)
+}
+
+// Err returns the error of the rows, ErrQueryTimeout if the deadline
+// passed while they were read.
+func (r *timeoutRows) Err() error {
+	return timeoutError(r.ctx, r.Rows.Err())
+}
+
+// Scan reads the values of the current row.
+func (r *timeoutRows) Scan(dest ...interface{}) error {
+	return timeoutError(r.ctx, r.Rows.Scan(dest...))
+}
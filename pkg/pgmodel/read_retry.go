@@ -0,0 +1,84 @@
+// This file and its contents are licensed under the Apache License 2.0.
+// Please see the included NOTICE for copyright information and
+// LICENSE for a copy of the license.
+
+package pgmodel
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v4"
+	"github.com/timescale/timescale-prometheus/pkg/log"
+)
+
+// DefaultSchemaChangeRetryDelay is the default delay before a read failing
+// because of a concurrent schema change is retried.
+const DefaultSchemaChangeRetryDelay = 100 * time.Millisecond
+
+// schemaChangeRetryDelay returns the retry delay of the querier for the
+// configured one, zero if reads are not retried.
+func schemaChangeRetryDelay(delay time.Duration) time.Duration {
+	switch {
+	case delay == 0:
+		return DefaultSchemaChangeRetryDelay
+	case delay < 0:
+		return 0
+	}
+	return delay
+}
+
+// waitForMigrationsSQL blocks until no migration holds the migration lock.
+// The shared lock is released right away, it only orders the read after
+// migrations in progress.
+const waitForMigrationsSQL = "SELECT pg_advisory_xact_lock_shared($1)"
+
+// isSchemaChangeError returns true for errors of queries whose tables were
+// altered or replaced while the query was planned, e.g. by a migration.
+func isSchemaChangeError(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	switch pgErr.Code {
+	case pgerrcode.UndefinedTable, pgerrcode.UndefinedColumn:
+		return true
+	case pgerrcode.FeatureNotSupported:
+		return strings.Contains(pgErr.Message, "cached plan must not change result type")
+	}
+	return false
+}
+
+// waitForMigrations waits for running migrations to finish if the querier is
+// configured to.
+func (q *pgxQuerier) waitForMigrations(ctx context.Context) error {
+	if !q.waitForMigrationLock {
+		return nil
+	}
+	rows, err := q.conn.Query(ctx, waitForMigrationsSQL, migrationLockID)
+	if err != nil {
+		return err
+	}
+	rows.Close()
+	return rows.Err()
+}
+
+// queryWithRetry runs a read query, retrying it once after
+// schemaChangeRetryDelay if it fails because of a concurrent schema change.
+// Only errors returned by Query itself are retried, not errors of reading the
+// rows.
+func (q *pgxQuerier) queryWithRetry(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	rows, err := q.conn.Query(ctx, sql, args...)
+	if err == nil || q.schemaChangeRetryDelay <= 0 || !isSchemaChangeError(err) {
+		return rows, err
+	}
+
+	log.Warn("msg", "read failed due to a schema change, retrying", "err", err)
+	readRetries.Inc()
+	time.Sleep(q.schemaChangeRetryDelay)
+	return q.conn.Query(ctx, sql, args...)
+}
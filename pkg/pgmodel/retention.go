@@ -0,0 +1,93 @@
+// This file and its contents are licensed under the Apache License 2.0.
+// Please see the included NOTICE for copyright information and
+// LICENSE for a copy of the license.
+
+package pgmodel
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const (
+	setDefaultRetentionSQL  = "SELECT " + promSchema + ".set_default_retention_period($1::interval)"
+	setMetricRetentionSQL   = "SELECT " + promSchema + ".set_metric_retention_period($1, $2::interval)"
+	resetMetricRetentionSQL = "SELECT " + promSchema + ".reset_metric_retention_period($1)"
+	getMetricRetentionSQL   = "SELECT EXTRACT(epoch FROM " + catalogSchema + ".get_metric_retention_period($1))"
+)
+
+// Retention manages the retention periods of metrics. The data older than
+// the retention period of its metric is dropped by the prom_api.drop_chunks
+// procedure, which must be run regularly. Metrics without a period of their
+// own use the default one.
+//
+// All changes are idempotent, setting a period again just overwrites it.
+type Retention struct {
+	conn pgxConn
+}
+
+// NewRetention returns a Retention managing the periods in the database.
+func NewRetention(db *pgxpool.Pool) *Retention {
+	return &Retention{conn: &pgxConnImpl{conn: db}}
+}
+
+// SetDefault sets the retention period of the metrics without one of their
+// own, existing and new.
+func (r *Retention) SetDefault(ctx context.Context, period time.Duration) error {
+	if err := validateRetention(period); err != nil {
+		return err
+	}
+	_, err := r.conn.Exec(ctx, setDefaultRetentionSQL, period)
+	return err
+}
+
+// Set sets the retention period of the metric, overriding the default. The
+// metric is created if it does not exist yet, so its period can be set before
+// any of its data is written.
+func (r *Retention) Set(ctx context.Context, metric string, period time.Duration) error {
+	if err := validateRetention(period); err != nil {
+		return err
+	}
+	_, err := r.conn.Exec(ctx, setMetricRetentionSQL, metric, period)
+	return err
+}
+
+// Remove removes the retention period of the metric, which then uses the
+// default one again.
+func (r *Retention) Remove(ctx context.Context, metric string) error {
+	_, err := r.conn.Exec(ctx, resetMetricRetentionSQL, metric)
+	return err
+}
+
+// Get returns the retention period applying to the metric, which is the
+// default one for metrics without their own or that do not exist. Months
+// count as 30 days.
+func (r *Retention) Get(ctx context.Context, metric string) (time.Duration, error) {
+	rows, err := r.conn.Query(ctx, getMetricRetentionSQL, metric)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return 0, err
+		}
+		return 0, fmt.Errorf("no retention period returned for metric %s", metric)
+	}
+	var seconds float64
+	if err := rows.Scan(&seconds); err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+func validateRetention(period time.Duration) error {
+	if period <= 0 {
+		return fmt.Errorf("invalid retention period %v, it must be positive", period)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+// This file and its contents are licensed under the Apache License 2.0.
+// Please see the included NOTICE for copyright information and
+// LICENSE for a copy of the license.
+
+package pgmodel
+
+import (
+	"context"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// execer is the part of a connection that runs statements.
+type execer interface {
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+}
+
+// SetRoleAfterConnect returns a hook for pgxpool.Config.AfterConnect that
+// switches new connections to the role, so that a connector connecting as a
+// privileged user, e.g. to run migrations, ingests and reads with a
+// restricted one. The connecting user must be a member of the role.
+func SetRoleAfterConnect(role string) func(context.Context, *pgx.Conn) error {
+	return func(ctx context.Context, conn *pgx.Conn) error {
+		return setRole(ctx, conn, role)
+	}
+}
+
+func setRole(ctx context.Context, conn execer, role string) error {
+	_, err := conn.Exec(ctx, "SET ROLE "+pgx.Identifier{role}.Sanitize())
+	return err
+}
@@ -0,0 +1,89 @@
+// This file and its contents are licensed under the Apache License 2.0.
+// Please see the included NOTICE for copyright information and
+// LICENSE for a copy of the license.
+
+package pgmodel
+
+import (
+	"sync"
+)
+
+const (
+	DefaultSeriesCacheSize = 100000
+)
+
+// seriesIDCache caches the ids of the series written, so that the series
+// recurring in every scrape are only looked up in the database once. It is
+// shared by the inserters of all metrics and holds at most max series, a full
+// cache evicts an arbitrary series for every new one.
+type seriesIDCache struct {
+	lock sync.Mutex
+	ids  map[string]SeriesID
+	max  int
+}
+
+var _ SeriesCache = (*seriesIDCache)(nil)
+
+func newSeriesIDCache(max uint64) *seriesIDCache {
+	if max == 0 {
+		max = DefaultSeriesCacheSize
+	}
+	return &seriesIDCache{ids: make(map[string]SeriesID), max: int(max)}
+}
+
+// GetSeries returns the id of the series, or ErrEntryNotFound if it is not
+// cached.
+func (c *seriesIDCache) GetSeries(lset Labels) (SeriesID, error) {
+	c.lock.Lock()
+	id, ok := c.ids[lset.String()]
+	c.lock.Unlock()
+
+	if !ok {
+		seriesCacheMisses.Inc()
+		return 0, ErrEntryNotFound
+	}
+	seriesCacheHits.Inc()
+	return id, nil
+}
+
+// SetSeries caches the id of the series. Only ids of series committed to the
+// database may be cached.
+func (c *seriesIDCache) SetSeries(lset Labels, id SeriesID) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	key := lset.String()
+	if _, ok := c.ids[key]; !ok && len(c.ids) >= c.max {
+		for evicted := range c.ids {
+			delete(c.ids, evicted)
+			break
+		}
+	}
+	c.ids[key] = id
+	seriesCacheElements.Set(float64(len(c.ids)))
+	return nil
+}
+
+// invalidate removes the series of the samples from the cache, so that their
+// ids are looked up again on their next write.
+func (c *seriesIDCache) invalidate(sampleInfos []samplesInfo) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, si := range sampleInfos {
+		if si.labels != nil {
+			delete(c.ids, si.labels.String())
+		}
+	}
+	seriesCacheElements.Set(float64(len(c.ids)))
+}
+
+func (c *seriesIDCache) NumElements() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.ids)
+}
+
+func (c *seriesIDCache) Capacity() int {
+	return c.max
+}
@@ -0,0 +1,168 @@
+// This file and its contents are licensed under the Apache License 2.0.
+// Please see the included NOTICE for copyright information and
+// LICENSE for a copy of the license.
+
+package pgmodel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/prometheus/pkg/labels"
+)
+
+const (
+	seriesCatalogPageSQL = "SELECT id, labels FROM " + catalogSchema + ".series WHERE id > $1 ORDER BY id LIMIT $2"
+	// seriesCatalogScopedPageSQLFormat reads a page of the series matching
+	// the clauses of the tenant scope.
+	seriesCatalogScopedPageSQLFormat = "SELECT id, labels FROM " + catalogSchema + ".series WHERE %s AND id > $%d ORDER BY id LIMIT $%d"
+)
+
+// seriesCatalogPageSize is the number of series read by every query of a
+// series catalog export.
+var seriesCatalogPageSize = 1000
+
+// SeriesCatalogEntry is a series of the catalog as written by
+// ExportSeriesCatalog.
+type SeriesCatalogEntry struct {
+	// Fingerprint is the fingerprint Prometheus identifies the series
+	// with, as 16 hex digits.
+	Fingerprint string        `json:"fingerprint"`
+	ID          SeriesID      `json:"id"`
+	Labels      labels.Labels `json:"labels"`
+}
+
+// SeriesCatalogExporter exports the catalog of all stored series.
+type SeriesCatalogExporter interface {
+	ExportSeriesCatalog(ctx context.Context, w io.Writer) error
+}
+
+// ExportSeriesCatalog writes every series of the catalog to w as a JSON
+// SeriesCatalogEntry per line, ordered by id. With tenancy, only the series
+// of the tenant of ctx are written.
+func (r *DBReader) ExportSeriesCatalog(ctx context.Context, w io.Writer) error {
+	e, ok := r.db.(SeriesCatalogExporter)
+	if !ok {
+		return fmt.Errorf("querier does not support exporting the series catalog")
+	}
+	return e.ExportSeriesCatalog(ctx, w)
+}
+
+// ExportSeriesCatalog implements SeriesCatalogExporter. The series table is
+// read in pages keyed by the last id read, so only a page of series is held
+// in memory and series created during the export do not shift the pages.
+// The labels of every page are fetched at once.
+func (q *pgxQuerier) ExportSeriesCatalog(ctx context.Context, w io.Writer) error {
+	sqlQuery, args, err := q.seriesCatalogPageQuery(ctx)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	lastID := SeriesID(0)
+	for {
+		ids, labelIDs, err := q.readSeriesCatalogPage(ctx, sqlQuery, args, lastID)
+		if err != nil {
+			return err
+		}
+		labelMap, err := q.getSeriesCatalogLabels(ctx, labelIDs)
+		if err != nil {
+			return err
+		}
+		for i := range ids {
+			lls := make(labels.Labels, 0, len(labelIDs[i]))
+			for _, id := range labelIDs[i] {
+				l, ok := labelMap[id]
+				if !ok {
+					return fmt.Errorf("%w: missing label with id %d of series %d", errInvalidData, id, ids[i])
+				}
+				lls = append(lls, l)
+			}
+			sort.Sort(lls)
+			entry := SeriesCatalogEntry{
+				Fingerprint: fmt.Sprintf("%016x", FingerprintFor(lls)),
+				ID:          ids[i],
+				Labels:      lls,
+			}
+			if err = enc.Encode(entry); err != nil {
+				return err
+			}
+		}
+		if len(ids) < seriesCatalogPageSize {
+			return nil
+		}
+		lastID = ids[len(ids)-1]
+	}
+}
+
+// seriesCatalogPageQuery returns the query of a page of the series catalog
+// and its arguments before the last id read and the page size. With tenancy,
+// the query is constrained to the series of the tenant of ctx like every
+// read.
+func (q *pgxQuerier) seriesCatalogPageQuery(ctx context.Context) (string, []interface{}, error) {
+	matchers, err := q.tenancy.scope(ctx, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(matchers) == 0 {
+		return seriesCatalogPageSQL, nil, nil
+	}
+	_, cases, values, err := buildSubQueries(matchers, q.caseInsensitive)
+	if err != nil {
+		return "", nil, err
+	}
+	return fmt.Sprintf(seriesCatalogScopedPageSQLFormat, strings.Join(cases, " AND "), len(values)+1, len(values)+2), values, nil
+}
+
+// getSeriesCatalogLabels returns the labels of the series of a page keyed
+// by their id.
+func (q *pgxQuerier) getSeriesCatalogLabels(ctx context.Context, labelIDs [][]int64) (map[int64]labels.Label, error) {
+	seen := make(map[int64]bool)
+	ids := make([]int64, 0)
+	for _, lls := range labelIDs {
+		for _, id := range lls {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return q.getLabelMapForIds(ctx, ids)
+}
+
+// readSeriesCatalogPage returns the ids and label ids of the series of the
+// page following the series with id lastID, read with the page query and its
+// arguments in the query context of ctx.
+func (q *pgxQuerier) readSeriesCatalogPage(ctx context.Context, sqlQuery string, args []interface{}, lastID SeriesID) ([]SeriesID, [][]int64, error) {
+	queryCtx, cancel := q.queryContext(ctx)
+	defer cancel()
+
+	pageArgs := make([]interface{}, 0, len(args)+2)
+	pageArgs = append(append(pageArgs, args...), int64(lastID), seriesCatalogPageSize)
+	rows, err := q.conn.Query(queryCtx, sqlQuery, pageArgs...)
+	if err != nil {
+		return nil, nil, timeoutError(queryCtx, err)
+	}
+	defer rows.Close()
+
+	ids := make([]SeriesID, 0, seriesCatalogPageSize)
+	labelIDs := make([][]int64, 0, seriesCatalogPageSize)
+	for rows.Next() {
+		var (
+			id  SeriesID
+			lls []int64
+		)
+		if err = rows.Scan(&id, &lls); err != nil {
+			return nil, nil, timeoutError(queryCtx, err)
+		}
+		ids = append(ids, id)
+		labelIDs = append(labelIDs, lls)
+	}
+	return ids, labelIDs, timeoutError(queryCtx, rows.Err())
+}
@@ -1,13 +1,17 @@
 package pgmodel
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"sort"
+	"strconv"
+	"time"
 
 	"github.com/jackc/pgtype"
 	"github.com/jackc/pgx/v4"
 	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/value"
 	"github.com/prometheus/prometheus/storage"
 	"github.com/prometheus/prometheus/tsdb/chunkenc"
 	"github.com/timescale/timescale-prometheus/pkg/log"
@@ -21,14 +25,131 @@ const (
 
 var (
 	errInvalidData = fmt.Errorf("invalid row data")
+	// ErrUnexpectedColumns is returned for series rows whose columns are not
+	// the label ids, times and values of a series, e.g. after a change of the
+	// schema the connector does not know.
+	ErrUnexpectedColumns = fmt.Errorf("unexpected columns in series rows")
 )
 
+// SampleDecodeError is the error of a series iterator that stopped at an
+// element whose time or value failed to decode. It wraps errInvalidData.
+type SampleDecodeError struct {
+	// Index is the position of the element in the series.
+	Index int
+	// Time and Value are set if the time, or the value, failed to decode.
+	Time  bool
+	Value bool
+}
+
+func (e *SampleDecodeError) Error() string {
+	var what string
+	switch {
+	case e.Time && e.Value:
+		what = "time and value"
+	case e.Time:
+		what = "time"
+	default:
+		what = "value"
+	}
+	return fmt.Sprintf("%s: %s of sample %d of the series failed to decode", errInvalidData, what, e.Index)
+}
+
+func (e *SampleDecodeError) Unwrap() error {
+	return errInvalidData
+}
+
+// seriesColumns is the number of columns of series rows.
+const seriesColumns = 3
+
+// scanSeriesRow scans the label ids, times and values of the current row. Rows
+// with other columns fail with ErrUnexpectedColumns, naming the columns
+// returned, unless ignoreExtra is set and the columns expected are followed
+// by extra ones, which are then skipped.
+func scanSeriesRow(rows pgx.Rows, ignoreExtra bool, labelIDs, times, values interface{}) error {
+	fds := rows.FieldDescriptions()
+	switch {
+	case len(fds) == seriesColumns:
+		return rows.Scan(labelIDs, times, values)
+	case len(fds) > seriesColumns && ignoreExtra:
+		// nil destinations are skipped
+		dest := make([]interface{}, len(fds))
+		dest[0], dest[1], dest[2] = labelIDs, times, values
+		return rows.Scan(dest...)
+	}
+
+	names := make([]string, len(fds))
+	for i, fd := range fds {
+		names[i] = string(fd.Name)
+	}
+	return fmt.Errorf("%w: got %d columns %v, wanted %d: label ids, times and values",
+		ErrUnexpectedColumns, len(fds), names, seriesColumns)
+}
+
+// seriesBatchSize is the number of rows a pgxSeriesSet reads at once, fetching
+// the labels of their series in a single query.
+const seriesBatchSize = 1000
+
 // pgxSeriesSet implements storage.SeriesSet.
 type pgxSeriesSet struct {
+	// ctx is the context of the request, the labels are fetched in its
+	// query context.
+	ctx     context.Context
 	rowIdx  int
 	rows    []pgx.Rows
 	err     error
 	querier labelQuerier
+	// valuePrecision maps metric names to the number of significant
+	// figures their values are rounded to.
+	valuePrecision map[string]int
+	// enricher, if set, adds labels from an external table.
+	enricher *labelEnricher
+	// fill, if set, interpolates the gaps of the returned series.
+	fill *gapFill
+	// emptyLabels decides what happens to series without labels.
+	emptyLabels EmptyLabelsPolicy
+	// namePrefix is prepended to the metric names of the series.
+	namePrefix string
+	// valueFilter, if set, drops series whose values do not pass it.
+	valueFilter *ValueFilter
+	// staleGap is the distance in milliseconds between two samples above
+	// which the series is marked stale in between, zero disables it.
+	staleGap int64
+	// labelFilter drops the series whose labels do not pass all of its
+	// matchers, see caseInsensitiveLabels.filter.
+	labelFilter []*labels.Matcher
+	// failOnDecodeErrors ends the iteration of series with elements that
+	// failed to decode, reporting them by the iterator's Err.
+	failOnDecodeErrors bool
+	// monotonicCounters are the names of the metrics whose counter resets
+	// are merged.
+	monotonicCounters map[string]bool
+	// rewrites rewrite the label values of the series.
+	rewrites labelRewrites
+	// ignoreExtraColumns skips the columns of rows after the values.
+	ignoreExtraColumns bool
+	// infiniteTimestamps decides what happens to samples at infinite times.
+	infiniteTimestamps InfiniteTimestampPolicy
+	// current is the series of the current row if it was read by Next,
+	// which is needed to skip series without labels or failing the value
+	// filter.
+	current *pgxSeries
+	scanned bool
+	// buffered holds the current batch of rows, read from the rows at
+	// rowsIdx so the labels of the whole batch are fetched at once, and
+	// labelMap their labels by id. numRows counts the rows read from the
+	// rows at rowsIdx so far.
+	buffered []timescaleRow
+	labelMap map[int64]labels.Label
+	rowsIdx  int
+	numRows  int
+}
+
+// timescaleRow is a row of series data read from the database.
+type timescaleRow struct {
+	labelIds []int64
+	times    pgtype.TimestamptzArray
+	values   pgtype.Float8Array
+	err      error
 }
 
 // pgxSeriesSet must implement storage.SeriesSet
@@ -36,55 +157,193 @@ var _ storage.SeriesSet = (*pgxSeriesSet)(nil)
 
 // Next forwards the internal cursor to next storage.Series
 func (p *pgxSeriesSet) Next() bool {
-	if p.rowIdx >= len(p.rows) {
+	if !p.next() {
 		return false
 	}
-	for !p.rows[p.rowIdx].Next() {
-		if p.err == nil {
-			p.err = p.rows[p.rowIdx].Err()
+	readSeries.Inc()
+	return true
+}
+
+func (p *pgxSeriesSet) next() bool {
+	p.current, p.scanned = nil, false
+	for p.nextRow() {
+		if p.emptyLabels != DropEmptyLabels && p.valueFilter == nil && p.labelFilter == nil && p.infiniteTimestamps != DropInfiniteTimestamps {
+			return true
+		}
+		p.current, p.scanned = p.scan(), true
+		if p.current == nil {
+			return true
+		}
+		if len(p.current.labels) == 0 && p.emptyLabels == DropEmptyLabels {
+			continue
+		}
+		if p.current.dropInfinite && countInfinite(p.current.times) == len(p.current.times.Elements) {
+			continue
 		}
-		p.rows[p.rowIdx].Close()
+		if !matchesLabels(p.labelFilter, p.current.labels.Get) {
+			continue
+		}
+		if p.valueFilter.matchesIterator(p.current.Iterator()) {
+			return true
+		}
+	}
+	return false
+}
+
+// nextRow advances to the next row, reading the next batch of rows once the
+// current one is used up.
+func (p *pgxSeriesSet) nextRow() bool {
+	if p.rowIdx+1 < len(p.buffered) {
 		p.rowIdx++
-		if p.rowIdx >= len(p.rows) {
-			return false
+		return true
+	}
+	return p.loadBatch()
+}
+
+// loadBatch reads the next batch of at most seriesBatchSize rows and fetches
+// the labels of all their series in a single query, rather than one query per
+// series. Only a batch of rows is held in memory at any time. All rows are
+// closed once they are read or reading them failed.
+func (p *pgxSeriesSet) loadBatch() bool {
+	p.buffered, p.labelMap, p.rowIdx = p.buffered[:0], nil, 0
+
+	ids := make([]int64, 0)
+	seen := make(map[int64]bool)
+	for len(p.buffered) < seriesBatchSize && p.rowsIdx < len(p.rows) {
+		rows := p.rows[p.rowsIdx]
+		if !rows.Next() {
+			err := rows.Err()
+			rows.Close()
+			readRowsPerQuery.Observe(float64(p.numRows))
+			p.rowsIdx, p.numRows = p.rowsIdx+1, 0
+			if err != nil {
+				p.closeRows()
+				p.err, p.buffered = err, nil
+				return false
+			}
+			continue
 		}
+		p.numRows++
+		var row timescaleRow
+		row.err = scanSeriesRow(rows, p.ignoreExtraColumns, &row.labelIds, &row.times, &row.values)
+		p.buffered = append(p.buffered, row)
+		for _, id := range row.labelIds {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	if len(p.buffered) == 0 {
+		return false
 	}
+	if len(ids) == 0 {
+		return true
+	}
+	labelMap, err := p.querier.getLabelMapForIds(p.ctx, ids)
+	if err != nil {
+		// the set ends rather than returning the series without labels,
+		// so that the result is not mistaken for a complete one
+		p.closeRows()
+		p.err, p.buffered = fmt.Errorf("fetching the labels of series: %w", err), nil
+		return false
+	}
+	p.labelMap = labelMap
 	return true
 }
 
+// closeRows closes the rows not read yet.
+func (p *pgxSeriesSet) closeRows() {
+	for _, rows := range p.rows[p.rowsIdx:] {
+		rows.Close()
+	}
+	p.rowsIdx = len(p.rows)
+}
+
 // At returns the current storage.Series. It expects to get rows to contain
 // four arrays in binary format which it attempts to deserialize into specific types.
 // It also expects that the first two and second two arrays are the same length.
 func (p *pgxSeriesSet) At() storage.Series {
-	if p.rowIdx >= len(p.rows) {
+	s := p.current
+	if !p.scanned {
+		s = p.scan()
+	}
+	if s == nil {
+		return nil
+	}
+	return s
+}
+
+// scan reads the series of the current row, returning nil on error.
+func (p *pgxSeriesSet) scan() *pgxSeries {
+	if p.rowIdx < 0 || p.rowIdx >= len(p.buffered) {
 		return nil
 	}
 
 	// Setting invalid data until we confirm that all data is valid.
 	p.err = errInvalidData
 
-	ps := &pgxSeries{}
-	var labelIds []int64
-	if err := p.rows[p.rowIdx].Scan(&labelIds, &ps.times, &ps.values); err != nil {
-		log.Error("err", err)
+	row := p.buffered[p.rowIdx]
+	if row.err != nil {
+		log.Error("err", row.err)
+		p.err = fmt.Errorf("%w: %v", errInvalidData, row.err)
 		return nil
 	}
 
-	if len(ps.times.Elements) != len(ps.values.Elements) {
+	if len(row.times.Elements) != len(row.values.Elements) {
 		return nil
 	}
+	ps := &pgxSeries{times: row.times, values: row.values}
 
 	// this should pretty much always be non-empty due to __name__, but it
 	// costs little to check here
-	if len(labelIds) != 0 {
-		lls, err := p.querier.getLabelsForIds(labelIds)
+	if len(row.labelIds) != 0 {
+		lls := make(labels.Labels, 0, len(row.labelIds))
+		for _, id := range row.labelIds {
+			l, ok := p.labelMap[id]
+			if !ok {
+				log.Error("msg", "missing labels of series", "id", id)
+				p.err = fmt.Errorf("%w: missing label with id %d", errInvalidData, id)
+				return nil
+			}
+			lls = append(lls, l)
+		}
+		sort.Sort(lls)
+		lls, err := p.enricher.enrich(lls)
 		if err != nil {
 			log.Error("err", err)
 			return nil
 		}
-		sort.Sort(lls)
+		ps.sigFigs = p.valuePrecision[lls.Get(MetricNameLabelName)]
+		ps.monotonic = p.monotonicCounters[lls.Get(MetricNameLabelName)]
+		lls = p.rewrites.apply(lls)
+		if p.namePrefix != "" {
+			for i := range lls {
+				if lls[i].Name == MetricNameLabelName {
+					lls[i].Value = p.namePrefix + lls[i].Value
+				}
+			}
+		}
 		ps.labels = lls
 	}
+	if len(ps.labels) == 0 {
+		log.Warn("msg", "series without labels read", "policy", p.emptyLabels)
+		if p.emptyLabels == LabelEmptyLabels {
+			ps.labels = labels.Labels{UnlabeledSeriesLabel}
+		}
+	}
+	if n := countInfinite(ps.times); n > 0 {
+		switch p.infiniteTimestamps {
+		case WarnInfiniteTimestamps:
+			log.Warn("msg", "samples at infinite timestamps read", "series", ps.labels, "count", n)
+		case DropInfiniteTimestamps:
+			ps.dropInfinite = true
+		}
+	}
+	ps.fill = p.fill
+	ps.staleGap = p.staleGap
+	ps.failOnDecodeErrors = p.failOnDecodeErrors
 
 	p.err = nil
 	return ps
@@ -98,11 +357,80 @@ func (p *pgxSeriesSet) Err() error {
 	return nil
 }
 
+// sortedSeriesSet returns the series of another set sorted by their labels,
+// as required of the sets of Select calls asking for sorted series, e.g. by
+// merging queriers. Sorting needs the labels of every series, so the whole
+// set is read on the first call of Next.
+type sortedSeriesSet struct {
+	set    storage.SeriesSet
+	series []storage.Series
+	idx    int
+	loaded bool
+	err    error
+}
+
+func newSortedSeriesSet(set storage.SeriesSet) *sortedSeriesSet {
+	return &sortedSeriesSet{set: set, idx: -1}
+}
+
+// Next implements storage.SeriesSet.
+func (s *sortedSeriesSet) Next() bool {
+	if !s.loaded {
+		s.load()
+	}
+	if s.err != nil || s.idx+1 >= len(s.series) {
+		return false
+	}
+	s.idx++
+	return true
+}
+
+// At implements storage.SeriesSet.
+func (s *sortedSeriesSet) At() storage.Series {
+	if s.idx < 0 || s.idx >= len(s.series) {
+		return nil
+	}
+	return s.series[s.idx]
+}
+
+// Err implements storage.SeriesSet.
+func (s *sortedSeriesSet) Err() error {
+	return s.err
+}
+
+func (s *sortedSeriesSet) load() {
+	s.loaded = true
+	for s.set.Next() {
+		series := s.set.At()
+		if series == nil {
+			// invalid series are reported by Err
+			break
+		}
+		s.series = append(s.series, series)
+	}
+	if s.err = s.set.Err(); s.err != nil {
+		s.series = nil
+		return
+	}
+	sort.SliceStable(s.series, func(i, j int) bool {
+		return labels.Compare(s.series[i].Labels(), s.series[j].Labels()) < 0
+	})
+}
+
 // pgxSeries implements storage.Series.
 type pgxSeries struct {
-	labels labels.Labels
-	times  pgtype.TimestamptzArray
-	values pgtype.Float8Array
+	labels  labels.Labels
+	times   pgtype.TimestamptzArray
+	values  pgtype.Float8Array
+	sigFigs int
+	fill    *gapFill
+	// staleGap is in milliseconds
+	staleGap           int64
+	failOnDecodeErrors bool
+	// monotonic merges the counter resets of the series
+	monotonic bool
+	// dropInfinite skips the samples at infinite times
+	dropInfinite bool
 }
 
 // Labels returns the label names and values for the series.
@@ -112,7 +440,69 @@ func (p *pgxSeries) Labels() labels.Labels {
 
 // Iterator returns a chunkenc.Iterator for iterating over series data.
 func (p *pgxSeries) Iterator() chunkenc.Iterator {
-	return newIterator(p.times, p.values)
+	times, values := p.times, p.values
+	if p.fill != nil {
+		times, values = p.fill.interpolate(times, values)
+	}
+	it := newIterator(times, values, p.staleGap)
+	it.sigFigs = p.sigFigs
+	it.failOnDecodeErrors = p.failOnDecodeErrors
+	it.dropInfinite = p.dropInfinite
+	if p.monotonic {
+		it.offsets = counterOffsets(values)
+	}
+	return it
+}
+
+// gapFill linearly interpolates the missing points of series aligned to a
+// step grid.
+type gapFill struct {
+	// step is the distance between grid points in milliseconds
+	step int64
+	// maxGap is the widest distance in milliseconds between two samples
+	// that is interpolated, wider gaps are left as they are
+	maxGap int64
+}
+
+// interpolate returns the samples with a point added at every missing step
+// between two samples at most maxGap apart. The added values lie on the line
+// between the two samples. Gaps next to NaN values, including stale markers,
+// are not filled.
+func (f *gapFill) interpolate(times pgtype.TimestamptzArray, values pgtype.Float8Array) (pgtype.TimestamptzArray, pgtype.Float8Array) {
+	filledTimes := make([]pgtype.Timestamptz, 0, len(times.Elements))
+	filledValues := make([]pgtype.Float8, 0, len(values.Elements))
+
+	prev := -1
+	for i := range times.Elements {
+		t, v := times.Elements[i], values.Elements[i]
+		present := t.Status == pgtype.Present && v.Status == pgtype.Present && t.InfinityModifier == pgtype.None
+		if present && prev >= 0 {
+			start, end := toMilis(times.Elements[prev].Time), toMilis(t.Time)
+			startVal, endVal := values.Elements[prev].Float, v.Float
+			if end-start <= f.maxGap && !math.IsNaN(startVal) && !math.IsNaN(endVal) {
+				for ts := start + f.step; ts < end; ts += f.step {
+					frac := float64(ts-start) / float64(end-start)
+					filledTimes = append(filledTimes, pgtype.Timestamptz{
+						Time:   time.Unix(0, ts*int64(time.Millisecond)),
+						Status: pgtype.Present,
+					})
+					filledValues = append(filledValues, pgtype.Float8{
+						Float:  startVal + (endVal-startVal)*frac,
+						Status: pgtype.Present,
+					})
+				}
+			}
+		}
+		if present {
+			prev = i
+		}
+		filledTimes = append(filledTimes, t)
+		filledValues = append(filledValues, v)
+	}
+
+	times.Elements = filledTimes
+	values.Elements = filledValues
+	return times, values
 }
 
 // pgxSeriesIterator implements storage.SeriesIterator.
@@ -121,24 +511,78 @@ type pgxSeriesIterator struct {
 	totalSamples int
 	times        pgtype.TimestamptzArray
 	values       pgtype.Float8Array
+	// sigFigs is the number of significant figures values are rounded to,
+	// zero returns exact values.
+	sigFigs int
+	// staleGap is the distance in milliseconds between two samples above
+	// which a staleness marker is returned right after the first of them,
+	// so PromQL does not connect samples across an outage. Zero disables it.
+	staleGap int64
+	// prevTs is the timestamp of the previous sample if hasPrev, samples
+	// at infinity are not tracked.
+	prevTs  int64
+	hasPrev bool
+	// stale is set while the iterator is on a staleness marker inserted
+	// before the sample at cur, staleTs is the marker's timestamp.
+	stale   bool
+	staleTs int64
+	// failOnDecodeErrors ends the iteration at the first element that
+	// failed to decode, otherwise such elements are skipped like NULLs.
+	failOnDecodeErrors bool
+	err                error
+	// offsets, if set, are added to the values at the same index to merge
+	// counter resets, see counterOffsets. They are computed for the whole
+	// series up front, so the values do not depend on where Seek lands.
+	offsets []float64
+	// dropInfinite skips the samples at infinite times like NULLs.
+	dropInfinite bool
 }
 
-// newIterator returns an iterator over the samples. It expects times and values to be the same length.
-func newIterator(times pgtype.TimestamptzArray, values pgtype.Float8Array) *pgxSeriesIterator {
+// newIterator returns an iterator over the samples. It expects times and
+// values to be the same length. The series is marked stale between samples
+// more than staleGap milliseconds apart.
+func newIterator(times pgtype.TimestamptzArray, values pgtype.Float8Array, staleGap int64) *pgxSeriesIterator {
 	return &pgxSeriesIterator{
 		cur:          -1,
 		totalSamples: len(times.Elements),
 		times:        times,
 		values:       values,
+		staleGap:     staleGap,
 	}
 }
 
-// Seek implements storage.SeriesIterator.
+// Seek implements storage.SeriesIterator. The times are sorted, so the first
+// sample at or after t is found by binary search. The search always starts
+// from the first sample, so seeking backwards works too.
+//
+// If there is no sample at or after t, Seek returns false and leaves the
+// iterator exhausted: cur is totalSamples, At returns 0, 0 and Next returns
+// false, until a later Seek finds a sample again. A Seek stopped by an
+// element failing to decode returns false too, with the error in Err.
 func (p *pgxSeriesIterator) Seek(t int64) bool {
-	p.cur = -1
+	p.cur, p.hasPrev, p.stale = -1, false, false
+
+	// elements without a time are skipped by comparing the next time after
+	// them, which keeps the search predicate monotonic
+	idx := sort.Search(p.totalSamples, func(i int) bool {
+		ts, ok := p.nextTs(i)
+		return !ok || ts >= t
+	})
+
+	// a staleness marker may follow the last sample before idx
+	if p.staleGap > 0 {
+		for i := idx - 1; i >= 0; i-- {
+			if p.present(i) {
+				p.cur = i
+				p.markGap()
+				break
+			}
+		}
+	}
+	p.cur = idx - 1
 
 	for p.Next() {
-		if p.getTs() >= t {
+		if ts, _ := p.At(); ts >= t {
 			return true
 		}
 	}
@@ -146,9 +590,34 @@ func (p *pgxSeriesIterator) Seek(t int64) bool {
 	return false
 }
 
+// nextTs returns the timestamp of the first element from i on that has a
+// time, ok is false if there is none.
+func (p *pgxSeriesIterator) nextTs(i int) (ts int64, ok bool) {
+	for ; i < p.totalSamples; i++ {
+		if p.times.Elements[i].Status == pgtype.Present {
+			return p.tsAt(i), true
+		}
+	}
+	return 0, false
+}
+
+// present returns true if the element at i has both a time and a value.
+func (p *pgxSeriesIterator) present(i int) bool {
+	if p.dropInfinite && p.times.Elements[i].InfinityModifier != pgtype.None {
+		return false
+	}
+	return p.times.Elements[i].Status == pgtype.Present &&
+		p.values.Elements[i].Status == pgtype.Present
+}
+
 // getTs returns a Unix timestamp in milliseconds.
 func (p *pgxSeriesIterator) getTs() int64 {
-	v := p.times.Elements[p.cur]
+	return p.tsAt(p.cur)
+}
+
+// tsAt returns the Unix timestamp in milliseconds of the element at i.
+func (p *pgxSeriesIterator) tsAt(i int) int64 {
+	v := p.times.Elements[i]
 
 	switch v.InfinityModifier {
 	case pgtype.NegativeInfinity:
@@ -161,7 +630,24 @@ func (p *pgxSeriesIterator) getTs() int64 {
 }
 
 func (p *pgxSeriesIterator) getVal() float64 {
-	return p.values.Elements[p.cur].Float
+	v := p.values.Elements[p.cur].Float
+	if p.offsets != nil && !math.IsNaN(v) {
+		v += p.offsets[p.cur]
+	}
+	return roundSignificant(v, p.sigFigs)
+}
+
+// roundSignificant rounds v to sigFigs significant figures. Non-positive
+// sigFigs, NaN and infinite values return v unchanged.
+func roundSignificant(v float64, sigFigs int) float64 {
+	if sigFigs <= 0 || v == 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+		return v
+	}
+	rounded, err := strconv.ParseFloat(strconv.FormatFloat(v, 'g', sigFigs, 64), 64)
+	if err != nil {
+		return v
+	}
+	return rounded
 }
 
 // At returns a Unix timestamp in milliseconds and value of the sample.
@@ -169,24 +655,77 @@ func (p *pgxSeriesIterator) At() (t int64, v float64) {
 	if p.cur >= p.totalSamples || p.cur < 0 {
 		return 0, 0
 	}
+	if p.stale {
+		return p.staleTs, math.Float64frombits(value.StaleNaN)
+	}
 	return p.getTs(), p.getVal()
 }
 
 // Next implements storage.SeriesIterator.
 func (p *pgxSeriesIterator) Next() bool {
+	if p.err != nil {
+		return false
+	}
+	if p.stale {
+		// the sample following the marker
+		p.stale = false
+		return true
+	}
 	for {
 		p.cur++
 		if p.cur >= p.totalSamples {
+			// exhausted, cur stays past the last element
+			p.cur = p.totalSamples
 			return false
 		}
-		if p.times.Elements[p.cur].Status == pgtype.Present &&
-			p.values.Elements[p.cur].Status == pgtype.Present {
+		if p.present(p.cur) {
+			p.markGap()
 			return true
 		}
+		if p.failOnDecodeErrors {
+			if err := p.decodeError(p.cur); err != nil {
+				p.err = err
+				return false
+			}
+		}
+	}
+}
+
+// decodeError returns the error of the element at i if its time or value
+// failed to decode, i.e. has any status but present or NULL. NULL elements
+// decode fine, they are just not samples.
+func (p *pgxSeriesIterator) decodeError(i int) error {
+	badTime := !decoded(p.times.Elements[i].Status)
+	badValue := !decoded(p.values.Elements[i].Status)
+	if !badTime && !badValue {
+		return nil
 	}
+	return &SampleDecodeError{Index: i, Time: badTime, Value: badValue}
+}
+
+func decoded(s pgtype.Status) bool {
+	return s == pgtype.Present || s == pgtype.Null
+}
+
+// markGap positions the iterator on a staleness marker if the sample at cur
+// is more than staleGap after the previous one.
+func (p *pgxSeriesIterator) markGap() {
+	if p.staleGap <= 0 {
+		return
+	}
+	if p.times.Elements[p.cur].InfinityModifier != pgtype.None {
+		p.hasPrev = false
+		return
+	}
+	ts := p.getTs()
+	if p.hasPrev && ts-p.prevTs > p.staleGap {
+		p.stale, p.staleTs = true, p.prevTs+1
+	}
+	// a stale sample already ends the series, it needs no marker
+	p.prevTs, p.hasPrev = ts, !value.IsStaleNaN(p.values.Elements[p.cur].Float)
 }
 
 // Err implements storage.SeriesIterator.
 func (p *pgxSeriesIterator) Err() error {
-	return nil
+	return p.err
 }
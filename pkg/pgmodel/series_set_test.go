@@ -1,11 +1,13 @@
 package pgmodel
 
 import (
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"math"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -14,6 +16,9 @@ import (
 	"github.com/jackc/pgtype"
 	"github.com/jackc/pgx/v4"
 	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/timescale/timescale-prometheus/pkg/prompb"
 )
 
 type mockPgxRows struct {
@@ -41,7 +46,7 @@ func (m *mockPgxRows) CommandTag() pgconn.CommandTag {
 }
 
 func (m *mockPgxRows) FieldDescriptions() []pgproto3.FieldDescription {
-	panic("not implemented")
+	return []pgproto3.FieldDescription{{Name: []byte("labels")}, {Name: []byte("time_array")}, {Name: []byte("value_array")}}
 }
 
 // Next prepares the next row for reading. It returns true if there is another
@@ -379,6 +384,38 @@ func (m mapQuerier) getLabelsForIds(ids []int64) (labels.Labels, error) {
 	return lls, nil
 }
 
+func (m mapQuerier) getLabelMapForIds(_ context.Context, ids []int64) (map[int64]labels.Label, error) {
+	lls, err := m.getLabelsForIds(ids)
+	if err != nil {
+		return nil, err
+	}
+	labelMap := make(map[int64]labels.Label, len(ids))
+	for i, id := range ids {
+		labelMap[id] = lls[i]
+	}
+	return labelMap, nil
+}
+
+// countingQuerier records the ids of every labels lookup.
+type countingQuerier struct {
+	mapQuerier
+	lookups [][]int64
+}
+
+func (c *countingQuerier) getLabelMapForIds(ctx context.Context, ids []int64) (map[int64]labels.Label, error) {
+	c.lookups = append(c.lookups, ids)
+	return c.mapQuerier.getLabelMapForIds(ctx, ids)
+}
+
+// failingQuerier fails every labels lookup.
+type failingQuerier struct {
+	err error
+}
+
+func (f failingQuerier) getLabelMapForIds(_ context.Context, ids []int64) (map[int64]labels.Label, error) {
+	return nil, f.err
+}
+
 func genRows(count int) [][][]byte {
 	result := make([][][]byte, count)
 
@@ -426,3 +463,1543 @@ func genSeries(labels []int64, ts []pgtype.Timestamptz, vs []pgtype.Float8) seri
 		values:     vs,
 	}
 }
+
+func TestPgxSeriesSetValuePrecision(t *testing.T) {
+	staleNaN := math.Float64frombits(0x7ff0000000000002)
+	labelMapping := map[int64]struct {
+		k string
+		v string
+	}{
+		1: {k: MetricNameLabelName, v: "rounded"},
+		2: {k: MetricNameLabelName, v: "exact"},
+		3: {k: "job", v: "test"},
+	}
+	values := []float64{3.14159, 123456, -0.00098765, 1, 0, math.NaN(), staleNaN, math.Inf(1), math.Inf(-1)}
+
+	testCases := []struct {
+		name     string
+		labels   []int64
+		expected []float64
+	}{
+		{
+			name:     "configured metric is rounded",
+			labels:   []int64{1, 3},
+			expected: []float64{3.14, 123000, -0.000988, 1, 0, math.NaN(), staleNaN, math.Inf(1), math.Inf(-1)},
+		},
+		{
+			name:     "other metric is exact",
+			labels:   []int64{2, 3},
+			expected: values,
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			ts := make([]pgtype.Timestamptz, len(values))
+			vs := make([]pgtype.Float8, len(values))
+			for i, v := range values {
+				ts[i] = pgtype.Timestamptz{Time: time.Unix(int64(i), 0)}
+				vs[i] = pgtype.Float8{Float: v}
+			}
+			p := pgxSeriesSet{
+				rows:           genPgxRows([][]seriesSetRow{{genSeries(c.labels, ts, vs)}}, nil),
+				querier:        mapQuerier{labelMapping},
+				valuePrecision: map[string]int{"rounded": 3},
+			}
+
+			if !p.Next() {
+				t.Fatal("unexpected end of series set")
+			}
+			s := p.At()
+			if p.Err() != nil {
+				t.Fatalf("unexpected error: %s", p.Err())
+			}
+
+			iter := s.Iterator()
+			for i, wanted := range c.expected {
+				if !iter.Next() {
+					t.Fatal("unexpected end of series iterator")
+				}
+				_, got := iter.At()
+				if math.IsNaN(wanted) {
+					if math.Float64bits(got) != math.Float64bits(wanted) {
+						t.Errorf("unexpected NaN value %d: got %x, wanted %x", i, math.Float64bits(got), math.Float64bits(wanted))
+					}
+					continue
+				}
+				if got != wanted {
+					t.Errorf("unexpected value %d: got %v, wanted %v", i, got, wanted)
+				}
+			}
+		})
+	}
+}
+
+func TestPgxSeriesSetInterpolation(t *testing.T) {
+	labelMapping := map[int64]struct {
+		k string
+		v string
+	}{
+		1: {k: MetricNameLabelName, v: "interpolated"},
+	}
+	// a one step gap, a gap wider than the maximum and a gap next to a NaN
+	times := []int64{0, 2000, 10000, 13000}
+	values := []float64{0, 10, 20, math.NaN()}
+
+	expectedTimes := []int64{0, 1000, 2000, 10000, 13000}
+	expectedValues := []float64{0, 5, 10, 20, math.NaN()}
+
+	ts := make([]pgtype.Timestamptz, len(times))
+	vs := make([]pgtype.Float8, len(values))
+	for i := range times {
+		ts[i] = pgtype.Timestamptz{Time: time.Unix(0, times[i]*int64(time.Millisecond))}
+		vs[i] = pgtype.Float8{Float: values[i]}
+	}
+	p := pgxSeriesSet{
+		rows:    genPgxRows([][]seriesSetRow{{genSeries([]int64{1}, ts, vs)}}, nil),
+		querier: mapQuerier{labelMapping},
+		fill:    &gapFill{step: 1000, maxGap: 3000},
+	}
+
+	if !p.Next() {
+		t.Fatal("unexpected end of series set")
+	}
+	s := p.At()
+	if p.Err() != nil {
+		t.Fatalf("unexpected error: %s", p.Err())
+	}
+
+	iter := s.Iterator()
+	for i := range expectedTimes {
+		if !iter.Next() {
+			t.Fatalf("unexpected end of series iterator at sample %d", i)
+		}
+		gotT, gotV := iter.At()
+		if gotT != expectedTimes[i] {
+			t.Errorf("unexpected timestamp %d: got %d, wanted %d", i, gotT, expectedTimes[i])
+		}
+		if math.IsNaN(expectedValues[i]) {
+			if !math.IsNaN(gotV) {
+				t.Errorf("unexpected value %d: got %v, wanted NaN", i, gotV)
+			}
+			continue
+		}
+		if gotV != expectedValues[i] {
+			t.Errorf("unexpected value %d: got %v, wanted %v", i, gotV, expectedValues[i])
+		}
+	}
+	if iter.Next() {
+		t.Error("unexpected extra samples")
+	}
+}
+
+func TestPgxSeriesSetEmptyLabels(t *testing.T) {
+	labelMapping := map[int64]struct {
+		k string
+		v string
+	}{
+		1: {k: MetricNameLabelName, v: "labeled"},
+	}
+	unlabeled := labels.Labels{UnlabeledSeriesLabel}
+	labeled := labels.Labels{{Name: MetricNameLabelName, Value: "labeled"}}
+
+	testCases := []struct {
+		policy   EmptyLabelsPolicy
+		expected []labels.Labels
+	}{
+		{
+			policy:   KeepEmptyLabels,
+			expected: []labels.Labels{nil, labeled, nil},
+		},
+		{
+			policy:   DropEmptyLabels,
+			expected: []labels.Labels{labeled},
+		},
+		{
+			policy:   LabelEmptyLabels,
+			expected: []labels.Labels{unlabeled, labeled, unlabeled},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.policy.String(), func(t *testing.T) {
+			rows := []seriesSetRow{
+				genSeries([]int64{}, []pgtype.Timestamptz{{Time: time.Unix(1, 0)}}, []pgtype.Float8{{Float: 1}}),
+				genSeries([]int64{1}, []pgtype.Timestamptz{{Time: time.Unix(2, 0)}}, []pgtype.Float8{{Float: 2}}),
+				genSeries([]int64{}, []pgtype.Timestamptz{{Time: time.Unix(3, 0)}}, []pgtype.Float8{{Float: 3}}),
+			}
+			p := pgxSeriesSet{
+				rows:        genPgxRows([][]seriesSetRow{rows}, nil),
+				querier:     mapQuerier{labelMapping},
+				emptyLabels: c.policy,
+			}
+
+			got := make([]labels.Labels, 0, len(c.expected))
+			for p.Next() {
+				s := p.At()
+				if p.Err() != nil {
+					t.Fatalf("unexpected error: %s", p.Err())
+				}
+				got = append(got, s.Labels())
+			}
+
+			if len(got) != len(c.expected) {
+				t.Fatalf("unexpected number of series: got %v, wanted %v", got, c.expected)
+			}
+			for i := range got {
+				if labels.Compare(got[i], c.expected[i]) != 0 {
+					t.Errorf("unexpected labels for series %d: got %v, wanted %v", i, got[i], c.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPgxSeriesSetLabelsFetchedOnce(t *testing.T) {
+	labelMapping := map[int64]struct {
+		k string
+		v string
+	}{
+		1: {MetricNameLabelName, "foo"},
+		2: {"b", "2"},
+		3: {"a", "3"},
+	}
+	ts := []pgtype.Timestamptz{{Time: time.Unix(1, 0), Status: pgtype.Present}}
+	vs := []pgtype.Float8{{Float: 1, Status: pgtype.Present}}
+
+	querier := &countingQuerier{mapQuerier: mapQuerier{labelMapping}}
+	p := pgxSeriesSet{
+		rows: genPgxRows([][]seriesSetRow{
+			{genSeries([]int64{1, 2}, ts, vs), genSeries([]int64{1, 3}, ts, vs)},
+			{genSeries([]int64{3, 2, 1}, ts, vs)},
+		}, nil),
+		querier: querier,
+	}
+
+	expected := []labels.Labels{
+		labels.FromStrings(MetricNameLabelName, "foo", "b", "2"),
+		labels.FromStrings(MetricNameLabelName, "foo", "a", "3"),
+		labels.FromStrings(MetricNameLabelName, "foo", "a", "3", "b", "2"),
+	}
+	got := make([]labels.Labels, 0, len(expected))
+	for p.Next() {
+		s := p.At()
+		if s == nil {
+			t.Fatalf("unexpected error: %v", p.Err())
+		}
+		got = append(got, s.Labels())
+	}
+
+	if p.Err() != nil {
+		t.Fatalf("unexpected error: %v", p.Err())
+	}
+	// labels.FromStrings sorts the labels, so this also checks the order
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected labels: got %v, wanted %v", got, expected)
+	}
+	if !reflect.DeepEqual(querier.lookups, [][]int64{{1, 2, 3}}) {
+		t.Errorf("unexpected label lookups: got %v, wanted a single lookup of all ids", querier.lookups)
+	}
+}
+
+func TestPgxSeriesSetStreamsRows(t *testing.T) {
+	labelMapping := map[int64]struct {
+		k string
+		v string
+	}{
+		1: {MetricNameLabelName, "foo"},
+	}
+	ts := []pgtype.Timestamptz{{Time: time.Unix(1, 0), Status: pgtype.Present}}
+	vs := []pgtype.Float8{{Float: 1, Status: pgtype.Present}}
+
+	// the first rows span more than a batch
+	input := make([][]seriesSetRow, 2)
+	for i := 0; i < seriesBatchSize+10; i++ {
+		input[0] = append(input[0], genSeries([]int64{1}, ts, vs))
+	}
+	input[1] = append(input[1], genSeries([]int64{1}, ts, vs))
+	rows := genPgxRows(input, nil)
+
+	querier := &countingQuerier{mapQuerier: mapQuerier{labelMapping}}
+	p := pgxSeriesSet{rows: rows, querier: querier}
+
+	if !p.Next() {
+		t.Fatalf("unexpected end of series set: %v", p.Err())
+	}
+	first, second := rows[0].(*mockPgxRows), rows[1].(*mockPgxRows)
+	if first.idx != seriesBatchSize-1 || first.closeCalled {
+		t.Errorf("expected a single batch of rows to be read, read %d rows", first.idx+1)
+	}
+	if second.firstRowRead {
+		t.Errorf("rows read before they are needed")
+	}
+
+	count := 1
+	for p.Next() {
+		if p.At() == nil {
+			t.Fatalf("unexpected error: %v", p.Err())
+		}
+		count++
+	}
+	if p.Err() != nil {
+		t.Fatalf("unexpected error: %v", p.Err())
+	}
+	if count != seriesBatchSize+11 {
+		t.Errorf("unexpected number of series: got %d, wanted %d", count, seriesBatchSize+11)
+	}
+	if !first.closeCalled || !second.closeCalled {
+		t.Errorf("rows not closed")
+	}
+	if len(querier.lookups) != 2 {
+		t.Errorf("unexpected number of label lookups: got %d, wanted one per batch", len(querier.lookups))
+	}
+}
+
+func TestPgxSeriesSetScanError(t *testing.T) {
+	scanErr := fmt.Errorf("scan failed")
+	rows := genPgxRows([][]seriesSetRow{{{}}, {{}}}, scanErr)
+	p := pgxSeriesSet{rows: rows, querier: mapQuerier{}}
+
+	if !p.Next() {
+		t.Fatal("unexpected end of series set")
+	}
+	if p.At() != nil {
+		t.Errorf("expected no series for a row failing to scan")
+	}
+	if err := p.Err(); !errors.Is(err, errInvalidData) || !strings.Contains(err.Error(), scanErr.Error()) {
+		t.Errorf("unexpected error: got %v, wanted the scan error", err)
+	}
+}
+
+// encodedPgxRows returns rows encoded in a wire format, decoding them on Scan
+// like pgx does.
+type encodedPgxRows struct {
+	mockPgxRows
+	format  int16
+	encoded [][][]byte
+}
+
+func (m *encodedPgxRows) Next() bool {
+	if m.firstRowRead {
+		m.idx++
+	}
+	m.firstRowRead = true
+
+	return m.idx < len(m.encoded)
+}
+
+func (m *encodedPgxRows) Scan(dest ...interface{}) error {
+	ci := pgtype.NewConnInfo()
+	var ids pgtype.Int8Array
+	for i, d := range []interface{}{&ids, dest[1], dest[2]} {
+		var err error
+		if m.format == pgx.TextFormatCode {
+			err = d.(pgtype.TextDecoder).DecodeText(ci, m.encoded[m.idx][i])
+		} else {
+			err = d.(pgtype.BinaryDecoder).DecodeBinary(ci, m.encoded[m.idx][i])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return ids.AssignTo(dest[0])
+}
+
+func TestPgxSeriesSetWireFormats(t *testing.T) {
+	labelMapping := map[int64]struct {
+		k string
+		v string
+	}{
+		1: {MetricNameLabelName, "foo"},
+	}
+	times := []time.Time{time.Unix(1, 0), time.Unix(2, 500000000)}
+	values := []float64{1.5, -2}
+
+	var ids pgtype.Int8Array
+	var ts pgtype.TimestamptzArray
+	var vs pgtype.Float8Array
+	for _, err := range []error{ids.Set([]int64{1}), ts.Set(times), vs.Set(values)} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, format := range []int16{pgx.TextFormatCode, pgx.BinaryFormatCode} {
+		ci := pgtype.NewConnInfo()
+		row := make([][]byte, 0, 3)
+		for _, col := range []interface{}{&ids, &ts, &vs} {
+			var buf []byte
+			var err error
+			if format == pgx.TextFormatCode {
+				buf, err = col.(pgtype.TextEncoder).EncodeText(ci, nil)
+			} else {
+				buf, err = col.(pgtype.BinaryEncoder).EncodeBinary(ci, nil)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			row = append(row, buf)
+		}
+
+		p := pgxSeriesSet{
+			rows:    []pgx.Rows{&encodedPgxRows{format: format, encoded: [][][]byte{row}}},
+			querier: mapQuerier{labelMapping},
+		}
+		if !p.Next() {
+			t.Fatalf("format %d: unexpected end of series set", format)
+		}
+		s := p.At()
+		if s == nil {
+			t.Fatalf("format %d: unexpected error: %v", format, p.Err())
+		}
+		if expected := labels.FromStrings(MetricNameLabelName, "foo"); !reflect.DeepEqual(s.Labels(), expected) {
+			t.Errorf("format %d: unexpected labels: got %v, wanted %v", format, s.Labels(), expected)
+		}
+
+		iter := s.Iterator()
+		for i := range times {
+			if !iter.Next() {
+				t.Fatalf("format %d: unexpected end of series iterator", format)
+			}
+			gotTs, gotVs := iter.At()
+			if wanted := toMilis(times[i]); gotTs != wanted || gotVs != values[i] {
+				t.Errorf("format %d: unexpected sample: got (%d, %v), wanted (%d, %v)", format, gotTs, gotVs, wanted, values[i])
+			}
+		}
+		if iter.Next() {
+			t.Errorf("format %d: unexpected sample after end", format)
+		}
+	}
+}
+
+func TestPgxSeriesSetMetricNamePrefix(t *testing.T) {
+	labelMapping := map[int64]struct {
+		k string
+		v string
+	}{
+		1: {MetricNameLabelName, "foo"},
+		2: {"job", "api"},
+	}
+	ts := []pgtype.Timestamptz{{Time: time.Unix(1, 0)}}
+	vs := []pgtype.Float8{{Float: 1.23456}}
+
+	p := pgxSeriesSet{
+		rows:           genPgxRows([][]seriesSetRow{{genSeries([]int64{1, 2}, ts, vs)}}, nil),
+		querier:        mapQuerier{labelMapping},
+		valuePrecision: map[string]int{"foo": 2},
+		namePrefix:     "cluster_a:",
+	}
+
+	if !p.Next() {
+		t.Fatal("unexpected end of series set")
+	}
+	s := p.At()
+	if s == nil {
+		t.Fatalf("unexpected error: %v", p.Err())
+	}
+	expected := labels.FromStrings(MetricNameLabelName, "cluster_a:foo", "job", "api")
+	if !reflect.DeepEqual(s.Labels(), expected) {
+		t.Errorf("unexpected labels: got %v, wanted %v", s.Labels(), expected)
+	}
+
+	// settings of the metric still apply under its stored name
+	iter := s.Iterator()
+	if !iter.Next() {
+		t.Fatal("unexpected end of series iterator")
+	}
+	if _, v := iter.At(); v != 1.2 {
+		t.Errorf("unexpected value: got %v, wanted 1.2", v)
+	}
+}
+
+func TestPgxSeriesSetValueFilter(t *testing.T) {
+	labelMapping := map[int64]struct {
+		k string
+		v string
+	}{
+		1: {MetricNameLabelName, "foo"},
+		2: {"job", "a"},
+		3: {"job", "b"},
+		4: {"job", "c"},
+	}
+	genRows := func() []pgx.Rows {
+		series := func(job int64, values ...float64) seriesSetRow {
+			ts := make([]pgtype.Timestamptz, len(values))
+			vs := make([]pgtype.Float8, len(values))
+			for i, v := range values {
+				ts[i] = pgtype.Timestamptz{Time: time.Unix(int64(i), 0)}
+				vs[i] = pgtype.Float8{Float: v}
+			}
+			return genSeries([]int64{1, job}, ts, vs)
+		}
+		return genPgxRows([][]seriesSetRow{{
+			series(2, 1, 5, 2),
+			// the staleness marker is ignored
+			series(3, 3, 3, math.Float64frombits(0x7ff0000000000002)),
+			series(4, 10, 0),
+		}}, nil)
+	}
+
+	testCases := []struct {
+		filter string
+		jobs   []string
+	}{
+		{filter: "", jobs: []string{"a", "b", "c"}},
+		{filter: "last>2", jobs: []string{"b"}},
+		{filter: "last==0", jobs: []string{"c"}},
+		{filter: "max>=5", jobs: []string{"a", "c"}},
+		{filter: "min<1", jobs: []string{"c"}},
+		{filter: "avg<3", jobs: []string{"a"}},
+		{filter: "max>100", jobs: []string{}},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.filter, func(t *testing.T) {
+			filter, err := ParseValueFilter(c.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := pgxSeriesSet{
+				rows:        genRows(),
+				querier:     mapQuerier{labelMapping},
+				valueFilter: filter,
+			}
+
+			jobs := make([]string, 0)
+			for p.Next() {
+				s := p.At()
+				if s == nil {
+					t.Fatalf("unexpected error: %v", p.Err())
+				}
+				jobs = append(jobs, s.Labels().Get("job"))
+			}
+			if !reflect.DeepEqual(jobs, c.jobs) {
+				t.Errorf("unexpected series: got %v, wanted %v", jobs, c.jobs)
+			}
+		})
+	}
+}
+
+func TestParseValueFilter(t *testing.T) {
+	testCases := []struct {
+		expr     string
+		expected *ValueFilter
+		invalid  bool
+	}{
+		{expr: "", expected: nil},
+		{expr: "last>0.9", expected: &ValueFilter{Aggregate: LastValue, Op: ">", Threshold: 0.9}},
+		{expr: " max >= 100 ", expected: &ValueFilter{Aggregate: MaxValue, Op: ">=", Threshold: 100}},
+		{expr: "avg!=-1e3", expected: &ValueFilter{Aggregate: AvgValue, Op: "!=", Threshold: -1000}},
+		{expr: "sum>1", invalid: true},
+		{expr: "last=1", invalid: true},
+		{expr: "last>high", invalid: true},
+		{expr: "last", invalid: true},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.expr, func(t *testing.T) {
+			filter, err := ParseValueFilter(c.expr)
+			if c.invalid {
+				if err == nil {
+					t.Fatalf("expected an error, got filter %v", filter)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(filter, c.expected) {
+				t.Errorf("unexpected filter: got %v, wanted %v", filter, c.expected)
+			}
+		})
+	}
+}
+
+func TestPgxSeriesIteratorStaleGap(t *testing.T) {
+	staleNaN := math.Float64frombits(0x7ff0000000000002)
+	type sample struct {
+		t int64
+		v float64
+	}
+	genArrays := func(samples []sample, negInf bool) (pgtype.TimestamptzArray, pgtype.Float8Array) {
+		times := pgtype.TimestamptzArray{}
+		values := pgtype.Float8Array{}
+		if negInf {
+			times.Elements = append(times.Elements, pgtype.Timestamptz{InfinityModifier: pgtype.NegativeInfinity, Status: pgtype.Present})
+			values.Elements = append(values.Elements, pgtype.Float8{Float: 0, Status: pgtype.Present})
+		}
+		for _, s := range samples {
+			times.Elements = append(times.Elements, pgtype.Timestamptz{Time: time.Unix(0, s.t*int64(time.Millisecond)), Status: pgtype.Present})
+			values.Elements = append(values.Elements, pgtype.Float8{Float: s.v, Status: pgtype.Present})
+		}
+		return times, values
+	}
+	input := []sample{{1000, 1}, {2000, 2}, {10000, 3}, {11000, 4}}
+
+	testCases := []struct {
+		name     string
+		staleGap int64
+		input    []sample
+		negInf   bool
+		expected []sample
+	}{
+		{
+			name:     "gap larger than threshold",
+			staleGap: 5000,
+			input:    input,
+			expected: []sample{{1000, 1}, {2000, 2}, {2001, staleNaN}, {10000, 3}, {11000, 4}},
+		},
+		{
+			name:     "gap smaller than threshold",
+			staleGap: 10000,
+			input:    input,
+			expected: input,
+		},
+		{
+			name:     "disabled",
+			input:    input,
+			expected: input,
+		},
+		{
+			name:     "already stale",
+			staleGap: 5000,
+			input:    []sample{{1000, 1}, {2000, staleNaN}, {10000, 3}},
+			expected: []sample{{1000, 1}, {2000, staleNaN}, {10000, 3}},
+		},
+		{
+			name:     "after infinity",
+			staleGap: 5000,
+			input:    []sample{{1000, 1}, {2000, 2}},
+			negInf:   true,
+			expected: []sample{{math.MinInt64, 0}, {1000, 1}, {2000, 2}},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			times, values := genArrays(c.input, c.negInf)
+			iter := newIterator(times, values, c.staleGap)
+			got := make([]sample, 0)
+			for iter.Next() {
+				ts, v := iter.At()
+				got = append(got, sample{ts, v})
+			}
+			if len(got) != len(c.expected) {
+				t.Fatalf("unexpected samples: got %v, wanted %v", got, c.expected)
+			}
+			for i := range got {
+				if got[i].t != c.expected[i].t || math.Float64bits(got[i].v) != math.Float64bits(c.expected[i].v) {
+					t.Errorf("unexpected sample %d: got %v, wanted %v", i, got[i], c.expected[i])
+				}
+			}
+		})
+	}
+
+	t.Run("seek", func(t *testing.T) {
+		times, values := genArrays(input, false)
+		iter := newIterator(times, values, 5000)
+		if !iter.Seek(2001) {
+			t.Fatal("unexpected end of series iterator")
+		}
+		if ts, v := iter.At(); ts != 2001 || math.Float64bits(v) != math.Float64bits(staleNaN) {
+			t.Errorf("unexpected sample: got %d %v, wanted the staleness marker at 2001", ts, v)
+		}
+		if !iter.Seek(2002) {
+			t.Fatal("unexpected end of series iterator")
+		}
+		if ts, v := iter.At(); ts != 10000 || v != 3 {
+			t.Errorf("unexpected sample: got %d %v, wanted 10000 3", ts, v)
+		}
+	})
+}
+
+// seekLinear is the reference Seek, scanning all samples from the start.
+func seekLinear(p *pgxSeriesIterator, t int64) bool {
+	p.cur, p.hasPrev, p.stale = -1, false, false
+	for p.Next() {
+		if ts, _ := p.At(); ts >= t {
+			return true
+		}
+	}
+	return false
+}
+
+func TestPgxSeriesIteratorSeek(t *testing.T) {
+	present := func(ms int64) pgtype.Timestamptz {
+		return pgtype.Timestamptz{Time: time.Unix(0, ms*int64(time.Millisecond)), Status: pgtype.Present}
+	}
+	times := pgtype.TimestamptzArray{Elements: []pgtype.Timestamptz{
+		{InfinityModifier: pgtype.NegativeInfinity, Status: pgtype.Present},
+		{Status: pgtype.Null},
+		present(1000),
+		present(2000),
+		{Status: pgtype.Null},
+		{Status: pgtype.Null},
+		present(3000),
+		present(4000),
+		present(10000),
+		present(11000),
+		{Status: pgtype.Null},
+		{InfinityModifier: pgtype.Infinity, Status: pgtype.Present},
+	}}
+	values := pgtype.Float8Array{Elements: make([]pgtype.Float8, len(times.Elements))}
+	for i := range values.Elements {
+		values.Elements[i] = pgtype.Float8{Float: float64(i), Status: pgtype.Present}
+	}
+	// a sample with a time but no value is skipped too
+	values.Elements[3].Status = pgtype.Null
+
+	seeks := []int64{math.MinInt64, -5, 0, 999, 1000, 1001, 2000, 2001, 2999, 3000, 4000, 4001, 9999, 10000, 11000, 11001, math.MaxInt64 - 1, math.MaxInt64}
+	for _, staleGap := range []int64{0, 5000} {
+		for _, target := range seeks {
+			// seeking backwards must work too, so the same iterator is used
+			// for every target in both directions
+			iter := newIterator(times, values, staleGap)
+			reference := newIterator(times, values, staleGap)
+			for _, seek := range []int64{target, math.MaxInt64, target} {
+				found := iter.Seek(seek)
+				wanted := seekLinear(reference, seek)
+				if found != wanted {
+					t.Fatalf("unexpected result seeking %d with stale gap %d: got %v, wanted %v", seek, staleGap, found, wanted)
+				}
+				for {
+					gotTs, gotV := iter.At()
+					wantedTs, wantedV := reference.At()
+					if gotTs != wantedTs || math.Float64bits(gotV) != math.Float64bits(wantedV) {
+						t.Fatalf("unexpected sample after seeking %d with stale gap %d: got %d %v, wanted %d %v", seek, staleGap, gotTs, gotV, wantedTs, wantedV)
+					}
+					next := iter.Next()
+					if next != reference.Next() {
+						t.Fatalf("unexpected end of samples after seeking %d with stale gap %d", seek, staleGap)
+					}
+					if !next {
+						break
+					}
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkPgxSeriesIteratorSeek(b *testing.B) {
+	const numSamples = 10000
+	times := pgtype.TimestamptzArray{Elements: make([]pgtype.Timestamptz, numSamples)}
+	values := pgtype.Float8Array{Elements: make([]pgtype.Float8, numSamples)}
+	for i := 0; i < numSamples; i++ {
+		times.Elements[i] = pgtype.Timestamptz{Time: time.Unix(int64(i)*15, 0), Status: pgtype.Present}
+		values.Elements[i] = pgtype.Float8{Float: float64(i), Status: pgtype.Present}
+	}
+	// seek to every step of a range query over the whole series
+	seeks := make([]int64, 0, numSamples/10)
+	for i := 0; i < numSamples; i += 10 {
+		seeks = append(seeks, int64(i)*15000+1)
+	}
+
+	b.Run("binary search", func(b *testing.B) {
+		iter := newIterator(times, values, 0)
+		for n := 0; n < b.N; n++ {
+			for _, t := range seeks {
+				iter.Seek(t)
+			}
+		}
+	})
+	b.Run("linear scan", func(b *testing.B) {
+		iter := newIterator(times, values, 0)
+		for n := 0; n < b.N; n++ {
+			for _, t := range seeks {
+				seekLinear(iter, t)
+			}
+		}
+	})
+}
+
+func TestPgxSeriesIteratorSeekPastEnd(t *testing.T) {
+	times := pgtype.TimestamptzArray{}
+	values := pgtype.Float8Array{}
+	for i := int64(1); i <= 3; i++ {
+		times.Elements = append(times.Elements, pgtype.Timestamptz{Time: time.Unix(i, 0), Status: pgtype.Present})
+		values.Elements = append(values.Elements, pgtype.Float8{Float: float64(i), Status: pgtype.Present})
+	}
+	// trailing elements without a sample are past the end too
+	times.Elements = append(times.Elements, pgtype.Timestamptz{Status: pgtype.Null})
+	values.Elements = append(values.Elements, pgtype.Float8{Status: pgtype.Null})
+
+	for _, staleGap := range []int64{0, 500} {
+		iter := newIterator(times, values, staleGap)
+		if iter.Seek(3001) {
+			t.Fatalf("unexpected sample found past the end with stale gap %d", staleGap)
+		}
+		// the iterator is exhausted, however often Next is called
+		for i := 0; i < 3; i++ {
+			if iter.cur != iter.totalSamples {
+				t.Errorf("unexpected position with stale gap %d: got %d, wanted %d", staleGap, iter.cur, iter.totalSamples)
+			}
+			if ts, v := iter.At(); ts != 0 || v != 0 {
+				t.Errorf("unexpected sample with stale gap %d: got %d %v, wanted 0 0", staleGap, ts, v)
+			}
+			if iter.Next() {
+				t.Fatalf("unexpected next sample with stale gap %d", staleGap)
+			}
+		}
+		if iter.Err() != nil {
+			t.Errorf("unexpected error with stale gap %d: %v", staleGap, iter.Err())
+		}
+
+		// a later Seek resumes from the sample found
+		if !iter.Seek(2000) {
+			t.Fatalf("sample not found after seeking past the end with stale gap %d", staleGap)
+		}
+		got := make([]int64, 0)
+		for ts, _ := iter.At(); ; ts, _ = iter.At() {
+			got = append(got, ts)
+			if !iter.Next() {
+				break
+			}
+		}
+		// the samples are 1s apart, so the stale gap adds a marker before
+		// every one of them but the first
+		expected := []int64{2000, 3000}
+		if staleGap > 0 {
+			expected = []int64{2000, 2001, 3000}
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("unexpected samples with stale gap %d: got %v, wanted %v", staleGap, got, expected)
+		}
+	}
+}
+
+func TestPgxSeriesIteratorDecodeErrors(t *testing.T) {
+	times := pgtype.TimestamptzArray{}
+	values := pgtype.Float8Array{}
+	for i := int64(1); i <= 4; i++ {
+		times.Elements = append(times.Elements, pgtype.Timestamptz{Time: time.Unix(i, 0), Status: pgtype.Present})
+		values.Elements = append(values.Elements, pgtype.Float8{Float: float64(i), Status: pgtype.Present})
+	}
+	// a NULL value is not a decode error, an undefined one is
+	values.Elements[1].Status = pgtype.Null
+	values.Elements[2].Status = pgtype.Undefined
+
+	testCases := []struct {
+		name     string
+		fail     bool
+		expected []float64
+	}{
+		{
+			name:     "fail on decode errors",
+			fail:     true,
+			expected: []float64{1},
+		},
+		{
+			name:     "skip decode errors",
+			fail:     false,
+			expected: []float64{1, 4},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			series := &pgxSeries{times: times, values: values, failOnDecodeErrors: c.fail}
+			iter := series.Iterator()
+			var got []float64
+			for iter.Next() {
+				_, v := iter.At()
+				got = append(got, v)
+			}
+			if !reflect.DeepEqual(got, c.expected) {
+				t.Errorf("unexpected values: got %v, wanted %v", got, c.expected)
+			}
+
+			err := iter.Err()
+			if c.fail && !errors.Is(err, errInvalidData) {
+				t.Errorf("expected decode error, got %v", err)
+			}
+			if !c.fail && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if c.fail && iter.Next() {
+				t.Errorf("iteration continued after decode error")
+			}
+		})
+	}
+}
+
+func TestPgxSeriesIteratorDecodeErrorType(t *testing.T) {
+	testCases := []struct {
+		name        string
+		timeStatus  pgtype.Status
+		valueStatus pgtype.Status
+		expected    SampleDecodeError
+	}{
+		{
+			name:        "undefined time",
+			timeStatus:  pgtype.Undefined,
+			valueStatus: pgtype.Present,
+			expected:    SampleDecodeError{Index: 1, Time: true},
+		},
+		{
+			name:        "unexpected value status",
+			timeStatus:  pgtype.Present,
+			valueStatus: pgtype.Status(42),
+			expected:    SampleDecodeError{Index: 1, Value: true},
+		},
+		{
+			name:        "both undefined",
+			timeStatus:  pgtype.Undefined,
+			valueStatus: pgtype.Undefined,
+			expected:    SampleDecodeError{Index: 1, Time: true, Value: true},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			times := pgtype.TimestamptzArray{Elements: []pgtype.Timestamptz{
+				{Time: time.Unix(1, 0), Status: pgtype.Present},
+				{Time: time.Unix(2, 0), Status: c.timeStatus},
+				{Time: time.Unix(3, 0), Status: pgtype.Present},
+			}}
+			values := pgtype.Float8Array{Elements: []pgtype.Float8{
+				{Float: 1, Status: pgtype.Present},
+				{Float: 2, Status: c.valueStatus},
+				{Float: 3, Status: pgtype.Present},
+			}}
+			series := &pgxSeries{times: times, values: values, failOnDecodeErrors: true}
+			iter := series.Iterator()
+			for iter.Next() {
+			}
+
+			var decodeErr *SampleDecodeError
+			if err := iter.Err(); !errors.As(err, &decodeErr) || !errors.Is(err, errInvalidData) {
+				t.Fatalf("expected sample decode error, got %v", err)
+			}
+			if *decodeErr != c.expected {
+				t.Errorf("unexpected error: got %+v, wanted %+v", *decodeErr, c.expected)
+			}
+		})
+	}
+}
+
+func TestPgxSeriesIteratorCounterResets(t *testing.T) {
+	staleNaN := math.Float64frombits(0x7ff0000000000002)
+	times := pgtype.TimestamptzArray{}
+	values := pgtype.Float8Array{}
+	// the counter resets after 5 and again after 4, the stale marker and
+	// the NULL value in between are not counter values
+	input := []float64{1, 3, 5, 2, 4, staleNaN, 0, 3, 7}
+	for i, v := range input {
+		times.Elements = append(times.Elements, pgtype.Timestamptz{Time: time.Unix(int64(i+1), 0), Status: pgtype.Present})
+		values.Elements = append(values.Elements, pgtype.Float8{Float: v, Status: pgtype.Present})
+	}
+	values.Elements[7].Status = pgtype.Null
+
+	testCases := []struct {
+		name      string
+		monotonic bool
+		seek      int64
+		expected  []float64
+	}{
+		{
+			name:      "monotonic",
+			monotonic: true,
+			expected:  []float64{1, 3, 5, 7, 9, staleNaN, 9, 16},
+		},
+		{
+			name:      "monotonic after seek",
+			monotonic: true,
+			seek:      5000,
+			expected:  []float64{9, staleNaN, 9, 16},
+		},
+		{
+			name:     "disabled",
+			expected: []float64{1, 3, 5, 2, 4, staleNaN, 0, 7},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			series := &pgxSeries{times: times, values: values, monotonic: c.monotonic}
+			iter := series.Iterator()
+			var got []float64
+			ok := iter.Next()
+			if c.seek != 0 {
+				ok = iter.Seek(c.seek)
+			}
+			for ; ok; ok = iter.Next() {
+				_, v := iter.At()
+				got = append(got, v)
+			}
+			if len(got) != len(c.expected) {
+				t.Fatalf("unexpected values: got %v, wanted %v", got, c.expected)
+			}
+			for i := range got {
+				if math.Float64bits(got[i]) != math.Float64bits(c.expected[i]) {
+					t.Errorf("unexpected value %d: got %v, wanted %v", i, got[i], c.expected[i])
+				}
+			}
+		})
+	}
+
+	samples := []prompb.Sample{{Timestamp: 1, Value: 2}, {Timestamp: 2, Value: 6}, {Timestamp: 3, Value: 1}, {Timestamp: 4, Value: 3}}
+	mergeCounterResets(samples)
+	expected := []prompb.Sample{{Timestamp: 1, Value: 2}, {Timestamp: 2, Value: 6}, {Timestamp: 3, Value: 7}, {Timestamp: 4, Value: 9}}
+	if !reflect.DeepEqual(samples, expected) {
+		t.Errorf("unexpected samples: got %v, wanted %v", samples, expected)
+	}
+}
+
+func TestPgxSeriesIteratorNullValues(t *testing.T) {
+	times := pgtype.TimestamptzArray{}
+	values := pgtype.Float8Array{}
+	for i := int64(1); i <= 6; i++ {
+		times.Elements = append(times.Elements, pgtype.Timestamptz{Time: time.Unix(i, 0), Status: pgtype.Present})
+		values.Elements = append(values.Elements, pgtype.Float8{Float: float64(i), Status: pgtype.Present})
+	}
+	// values stored as NULL, including the first and the last one
+	for _, i := range []int{0, 2, 3, 5} {
+		values.Elements[i] = pgtype.Float8{Status: pgtype.Null}
+	}
+
+	testCases := []struct {
+		name     string
+		seek     int64
+		expected []float64
+	}{
+		{
+			name:     "iterate",
+			expected: []float64{2, 5},
+		},
+		{
+			name:     "seek onto NULL",
+			seek:     3000,
+			expected: []float64{5},
+		},
+		{
+			name: "seek past last present",
+			seek: 6000,
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			series := &pgxSeries{times: times, values: values, failOnDecodeErrors: true}
+			iter := series.Iterator()
+			var got []float64
+			ok := iter.Next()
+			if c.seek != 0 {
+				ok = iter.Seek(c.seek)
+			}
+			for ; ok; ok = iter.Next() {
+				_, v := iter.At()
+				got = append(got, v)
+			}
+			if !reflect.DeepEqual(got, c.expected) {
+				t.Errorf("unexpected values: got %v, wanted %v", got, c.expected)
+			}
+			if err := iter.Err(); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestPgxSeriesSetLabelRewrites(t *testing.T) {
+	labelMapping := map[int64]struct {
+		k string
+		v string
+	}{
+		1: {MetricNameLabelName, "foo"},
+		2: {"pod", "api-7d9f8b-x2k4p"},
+		3: {"pod", "api-7d9f8b-q8z1m"},
+		4: {"pod", "db"},
+		5: {"job", "canary"},
+	}
+	rewrites, err := ParseLabelRewrites("pod=~(.+)-[a-z0-9]+-[a-z0-9]{5}->$1; job=~canary->")
+	if err != nil {
+		t.Fatal(err)
+	}
+	genRows := func() []pgx.Rows {
+		series := func(ids []int64, times ...int64) seriesSetRow {
+			ts := make([]pgtype.Timestamptz, len(times))
+			vs := make([]pgtype.Float8, len(times))
+			for i, tm := range times {
+				ts[i] = pgtype.Timestamptz{Time: time.Unix(tm, 0)}
+				vs[i] = pgtype.Float8{Float: float64(ids[1]*100 + tm)}
+			}
+			return genSeries(ids, ts, vs)
+		}
+		return genPgxRows([][]seriesSetRow{{
+			series([]int64{1, 2}, 1, 2),
+			series([]int64{1, 4}, 1),
+			series([]int64{1, 3, 5}, 2, 3),
+		}}, nil)
+	}
+	type sample struct {
+		t int64
+		v float64
+	}
+	read := func(t *testing.T, set storage.SeriesSet) ([]labels.Labels, [][]sample) {
+		var (
+			lbls    []labels.Labels
+			samples [][]sample
+		)
+		for set.Next() {
+			s := set.At()
+			if s == nil {
+				t.Fatalf("unexpected error: %v", set.Err())
+			}
+			lbls = append(lbls, s.Labels())
+			var ss []sample
+			it := s.Iterator()
+			for it.Next() {
+				ts, v := it.At()
+				ss = append(ss, sample{ts, v})
+			}
+			samples = append(samples, ss)
+		}
+		if err := set.Err(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return lbls, samples
+	}
+
+	t.Run("rewrite", func(t *testing.T) {
+		p := &pgxSeriesSet{rows: genRows(), querier: mapQuerier{labelMapping}, rewrites: rewrites}
+		lbls, samples := read(t, p)
+
+		// the rewritten job is empty and dropped
+		expectedLabels := []labels.Labels{
+			labels.FromStrings(MetricNameLabelName, "foo", "pod", "api"),
+			labels.FromStrings(MetricNameLabelName, "foo", "pod", "db"),
+			labels.FromStrings(MetricNameLabelName, "foo", "pod", "api"),
+		}
+		if !reflect.DeepEqual(lbls, expectedLabels) {
+			t.Errorf("unexpected labels: got %v, wanted %v", lbls, expectedLabels)
+		}
+		expectedSamples := [][]sample{
+			{{1000, 201}, {2000, 202}},
+			{{1000, 401}},
+			{{2000, 302}, {3000, 303}},
+		}
+		if !reflect.DeepEqual(samples, expectedSamples) {
+			t.Errorf("unexpected samples: got %v, wanted %v", samples, expectedSamples)
+		}
+	})
+
+	t.Run("merge", func(t *testing.T) {
+		p := &pgxSeriesSet{rows: genRows(), querier: mapQuerier{labelMapping}, rewrites: rewrites}
+		lbls, samples := read(t, newMergedSeriesSet(p))
+
+		expectedLabels := []labels.Labels{
+			labels.FromStrings(MetricNameLabelName, "foo", "pod", "api"),
+			labels.FromStrings(MetricNameLabelName, "foo", "pod", "db"),
+		}
+		if !reflect.DeepEqual(lbls, expectedLabels) {
+			t.Errorf("unexpected labels: got %v, wanted %v", lbls, expectedLabels)
+		}
+		// at 2s, the sample of the series read first is kept
+		expectedSamples := [][]sample{
+			{{1000, 201}, {2000, 202}, {3000, 303}},
+			{{1000, 401}},
+		}
+		if !reflect.DeepEqual(samples, expectedSamples) {
+			t.Errorf("unexpected samples: got %v, wanted %v", samples, expectedSamples)
+		}
+	})
+}
+
+func TestPgxSeriesSetInfiniteTimestamps(t *testing.T) {
+	labelMapping := map[int64]struct {
+		k string
+		v string
+	}{
+		1: {MetricNameLabelName, "foo"},
+		2: {"job", "a"},
+		3: {"job", "b"},
+	}
+	genRows := func() []pgx.Rows {
+		return genPgxRows([][]seriesSetRow{{
+			genSeries([]int64{1, 2},
+				[]pgtype.Timestamptz{{InfinityModifier: pgtype.NegativeInfinity}, {Time: time.Unix(1, 0)}, {InfinityModifier: pgtype.Infinity}},
+				[]pgtype.Float8{{Float: 1}, {Float: 2}, {Float: 3}}),
+			genSeries([]int64{1, 3},
+				[]pgtype.Timestamptz{{InfinityModifier: pgtype.NegativeInfinity}, {InfinityModifier: pgtype.Infinity}},
+				[]pgtype.Float8{{Float: 4}, {Float: 5}}),
+		}}, nil)
+	}
+	type sample struct {
+		t int64
+		v float64
+	}
+
+	testCases := []struct {
+		name     string
+		policy   InfiniteTimestampPolicy
+		expected map[string][]sample
+	}{
+		{
+			name:   "keep",
+			policy: KeepInfiniteTimestamps,
+			expected: map[string][]sample{
+				"a": {{math.MinInt64, 1}, {1000, 2}, {math.MaxInt64, 3}},
+				"b": {{math.MinInt64, 4}, {math.MaxInt64, 5}},
+			},
+		},
+		{
+			name:   "warn",
+			policy: WarnInfiniteTimestamps,
+			expected: map[string][]sample{
+				"a": {{math.MinInt64, 1}, {1000, 2}, {math.MaxInt64, 3}},
+				"b": {{math.MinInt64, 4}, {math.MaxInt64, 5}},
+			},
+		},
+		{
+			name:   "drop",
+			policy: DropInfiniteTimestamps,
+			// the series with only infinite timestamps is left out
+			expected: map[string][]sample{
+				"a": {{1000, 2}},
+			},
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			p := &pgxSeriesSet{rows: genRows(), querier: mapQuerier{labelMapping}, infiniteTimestamps: c.policy}
+
+			got := make(map[string][]sample)
+			for p.Next() {
+				s := p.At()
+				if s == nil {
+					t.Fatalf("unexpected error: %v", p.Err())
+				}
+				var ss []sample
+				it := s.Iterator()
+				for it.Next() {
+					ts, v := it.At()
+					ss = append(ss, sample{ts, v})
+				}
+				got[s.Labels().Get("job")] = ss
+			}
+			if err := p.Err(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, c.expected) {
+				t.Errorf("unexpected samples: got %v, wanted %v", got, c.expected)
+			}
+		})
+	}
+
+	t.Run("bounded seek", func(t *testing.T) {
+		p := &pgxSeriesSet{rows: genRows(), querier: mapQuerier{labelMapping}, infiniteTimestamps: DropInfiniteTimestamps}
+		if !p.Next() {
+			t.Fatalf("expected a series, err: %v", p.Err())
+		}
+		mint, maxt := int64(0), int64(2000)
+		it := p.At().Iterator()
+		for ok := it.Seek(mint); ok; ok = it.Next() {
+			if ts, _ := it.At(); ts < mint || ts > maxt {
+				t.Errorf("sample outside of [%d, %d] returned at %d", mint, maxt, ts)
+			}
+		}
+	})
+}
+
+func TestParseInfiniteTimestampPolicy(t *testing.T) {
+	for _, name := range []string{"keep", "warn", "drop"} {
+		policy, err := ParseInfiniteTimestampPolicy(name)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if policy.String() != name {
+			t.Errorf("unexpected policy: got %s, wanted %s", policy, name)
+		}
+	}
+	if _, err := ParseInfiniteTimestampPolicy("clamp"); err == nil {
+		t.Errorf("expected an error for an unknown policy")
+	}
+}
+
+func TestSortedSeriesSet(t *testing.T) {
+	labelMapping := map[int64]struct {
+		k string
+		v string
+	}{
+		1: {MetricNameLabelName, "foo"},
+		2: {"job", "b"},
+		3: {"job", "a"},
+		4: {MetricNameLabelName, "bar"},
+	}
+	genRows := func() []pgx.Rows {
+		ts := []pgtype.Timestamptz{{Time: time.Unix(1, 0)}}
+		vs := []pgtype.Float8{{Float: 1}}
+		return genPgxRows([][]seriesSetRow{
+			{genSeries([]int64{1, 2}, ts, vs), genSeries([]int64{4, 3}, ts, vs)},
+			{genSeries([]int64{1, 3}, ts, vs)},
+		}, nil)
+	}
+
+	testCases := []struct {
+		name     string
+		sort     bool
+		querier  labelQuerier
+		expected []labels.Labels
+		wantErr  bool
+	}{
+		{
+			name:    "unsorted",
+			querier: mapQuerier{labelMapping},
+			expected: []labels.Labels{
+				labels.FromStrings(MetricNameLabelName, "foo", "job", "b"),
+				labels.FromStrings(MetricNameLabelName, "bar", "job", "a"),
+				labels.FromStrings(MetricNameLabelName, "foo", "job", "a"),
+			},
+		},
+		{
+			name:    "sorted",
+			sort:    true,
+			querier: mapQuerier{labelMapping},
+			expected: []labels.Labels{
+				labels.FromStrings(MetricNameLabelName, "bar", "job", "a"),
+				labels.FromStrings(MetricNameLabelName, "foo", "job", "a"),
+				labels.FromStrings(MetricNameLabelName, "foo", "job", "b"),
+			},
+		},
+		{
+			name:    "sorted error",
+			sort:    true,
+			querier: failingQuerier{fmt.Errorf("connection reset")},
+			wantErr: true,
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			var set storage.SeriesSet = &pgxSeriesSet{rows: genRows(), querier: c.querier}
+			if c.sort {
+				set = newSortedSeriesSet(set)
+			}
+
+			var lbls []labels.Labels
+			for set.Next() {
+				s := set.At()
+				if s == nil {
+					break
+				}
+				lbls = append(lbls, s.Labels())
+			}
+			if c.wantErr {
+				if set.Err() == nil {
+					t.Fatalf("expected an error")
+				}
+				if len(lbls) != 0 {
+					t.Errorf("unexpected series read: %v", lbls)
+				}
+				return
+			}
+			if err := set.Err(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(lbls, c.expected) {
+				t.Errorf("unexpected labels: got %v, wanted %v", lbls, c.expected)
+			}
+		})
+	}
+}
+
+func TestMergeTimeSeries(t *testing.T) {
+	rewrites, err := ParseLabelRewrites("pod=~(.+)-[a-z0-9]{5}->$1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	series := func(pod string, samples ...prompb.Sample) *prompb.TimeSeries {
+		return &prompb.TimeSeries{
+			Labels: labelRewrites(rewrites).applyPrompb([]prompb.Label{
+				{Name: MetricNameLabelName, Value: "foo"},
+				{Name: "pod", Value: pod},
+			}),
+			Samples: samples,
+		}
+	}
+
+	merged := mergeTimeSeries([]*prompb.TimeSeries{
+		series("api-x2k4p", prompb.Sample{Timestamp: 3, Value: 1}, prompb.Sample{Timestamp: 4, Value: 1}),
+		series("db"),
+		series("api-q8z1m", prompb.Sample{Timestamp: 1, Value: 2}, prompb.Sample{Timestamp: 4, Value: 2}),
+	})
+
+	expected := []*prompb.TimeSeries{
+		{
+			Labels:  []prompb.Label{{Name: MetricNameLabelName, Value: "foo"}, {Name: "pod", Value: "api"}},
+			Samples: []prompb.Sample{{Timestamp: 1, Value: 2}, {Timestamp: 3, Value: 1}, {Timestamp: 4, Value: 1}},
+		},
+		{
+			Labels: []prompb.Label{{Name: MetricNameLabelName, Value: "foo"}, {Name: "pod", Value: "db"}},
+		},
+	}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Errorf("unexpected series: got %v, wanted %v", merged, expected)
+	}
+}
+
+func TestParseLabelRewrites(t *testing.T) {
+	testCases := []struct {
+		expr    string
+		label   []string
+		invalid bool
+	}{
+		{expr: ""},
+		{expr: "pod=~(.+)-[a-z0-9]{5}->$1", label: []string{"pod"}},
+		{expr: "pod=~(.+)-[a-z0-9]{5}->$1; instance=~(.*):\\d+->$1 ;", label: []string{"pod", "instance"}},
+		{expr: "pod=(.+)->$1", invalid: true},
+		{expr: "pod=~(.+)", invalid: true},
+		{expr: "=~(.+)->$1", invalid: true},
+		{expr: "pod=~(.+->$1", invalid: true},
+		{expr: "__name__=~(.+)_total->$1", invalid: true},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.expr, func(t *testing.T) {
+			rewrites, err := ParseLabelRewrites(c.expr)
+			if c.invalid {
+				if err == nil {
+					t.Fatalf("expected an error, got rewrites %v", rewrites)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, rw := range rewrites {
+				names = append(names, rw.Label)
+			}
+			if !reflect.DeepEqual(names, c.label) {
+				t.Errorf("unexpected labels: got %v, wanted %v", names, c.label)
+			}
+		})
+	}
+}
+
+func TestStreamChunkedSeries(t *testing.T) {
+	labelMapping := map[int64]struct {
+		k string
+		v string
+	}{
+		1: {MetricNameLabelName, "foo"},
+		2: {"job", "a"},
+		3: {"job", "b"},
+	}
+	genRows := func() []pgx.Rows {
+		series := func(ids []int64, numSamples int) seriesSetRow {
+			ts := make([]pgtype.Timestamptz, numSamples)
+			vs := make([]pgtype.Float8, numSamples)
+			for i := range ts {
+				ts[i] = pgtype.Timestamptz{Time: time.Unix(int64(i+1), 0)}
+				vs[i] = pgtype.Float8{Float: float64(i)}
+			}
+			return genSeries(ids, ts, vs)
+		}
+		return genPgxRows([][]seriesSetRow{{
+			series([]int64{1, 2}, 250),
+			series([]int64{1, 3}, 1),
+		}}, nil)
+	}
+	stream := func(t *testing.T, maxFrameBytes int) []*prompb.ChunkedReadResponse {
+		var frames []*prompb.ChunkedReadResponse
+		p := &pgxSeriesSet{rows: genRows(), querier: mapQuerier{labelMapping}}
+		err := streamChunkedSeries(p, 7, maxFrameBytes, func(frame *prompb.ChunkedReadResponse) error {
+			frames = append(frames, frame)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return frames
+	}
+	seriesA := []prompb.Label{{Name: MetricNameLabelName, Value: "foo"}, {Name: "job", Value: "a"}}
+	seriesB := []prompb.Label{{Name: MetricNameLabelName, Value: "foo"}, {Name: "job", Value: "b"}}
+
+	t.Run("single frame", func(t *testing.T) {
+		frames := stream(t, maxChunkedFrameBytes)
+		if len(frames) != 1 || frames[0].QueryIndex != 7 || len(frames[0].ChunkedSeries) != 2 {
+			t.Fatalf("unexpected frames: %v", frames)
+		}
+		a, b := frames[0].ChunkedSeries[0], frames[0].ChunkedSeries[1]
+		if !reflect.DeepEqual(a.Labels, seriesA) || !reflect.DeepEqual(b.Labels, seriesB) {
+			t.Errorf("unexpected labels: got %v and %v", a.Labels, b.Labels)
+		}
+
+		// chunks of 120 samples, decoding to the samples of the series
+		expectedChunks := [][2]int64{{1000, 120000}, {121000, 240000}, {241000, 250000}}
+		if len(a.Chunks) != len(expectedChunks) {
+			t.Fatalf("unexpected number of chunks: got %d, wanted %d", len(a.Chunks), len(expectedChunks))
+		}
+		i := 0
+		for j, c := range a.Chunks {
+			if c.Type != prompb.Chunk_XOR || c.MinTimeMs != expectedChunks[j][0] || c.MaxTimeMs != expectedChunks[j][1] {
+				t.Errorf("unexpected chunk %d: got %v %d-%d, wanted %v", j, c.Type, c.MinTimeMs, c.MaxTimeMs, expectedChunks[j])
+			}
+			chk, err := chunkenc.FromData(chunkenc.EncXOR, c.Data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			it := chk.Iterator(nil)
+			for it.Next() {
+				ts, v := it.At()
+				if ts != int64(i+1)*1000 || v != float64(i) {
+					t.Errorf("unexpected sample %d: got %d %v", i, ts, v)
+				}
+				i++
+			}
+			if err := it.Err(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if i != 250 {
+			t.Errorf("unexpected number of samples: got %d, wanted 250", i)
+		}
+		if len(b.Chunks) != 1 || b.Chunks[0].MinTimeMs != 1000 || b.Chunks[0].MaxTimeMs != 1000 {
+			t.Errorf("unexpected chunks: %v", b.Chunks)
+		}
+	})
+
+	t.Run("frame per chunk", func(t *testing.T) {
+		frames := stream(t, 1)
+		expectedLabels := [][]prompb.Label{seriesA, seriesA, seriesA, seriesB}
+		if len(frames) != len(expectedLabels) {
+			t.Fatalf("unexpected number of frames: got %d, wanted %d", len(frames), len(expectedLabels))
+		}
+		for i, frame := range frames {
+			if frame.QueryIndex != 7 || len(frame.ChunkedSeries) != 1 || len(frame.ChunkedSeries[0].Chunks) != 1 {
+				t.Fatalf("unexpected frame %d: %v", i, frame)
+			}
+			if !reflect.DeepEqual(frame.ChunkedSeries[0].Labels, expectedLabels[i]) {
+				t.Errorf("unexpected labels of frame %d: got %v, wanted %v", i, frame.ChunkedSeries[0].Labels, expectedLabels[i])
+			}
+		}
+	})
+}
+
+func TestPgxSeriesSetLabelsUnavailable(t *testing.T) {
+	labelsErr := fmt.Errorf("labels table unavailable")
+	rows := genPgxRows([][]seriesSetRow{
+		{
+			genSeries([]int64{1}, []pgtype.Timestamptz{{Time: time.Unix(1, 0)}}, []pgtype.Float8{{Float: 1}}),
+			genSeries([]int64{2}, []pgtype.Timestamptz{{Time: time.Unix(1, 0)}}, []pgtype.Float8{{Float: 2}}),
+		},
+		{
+			genSeries([]int64{3}, []pgtype.Timestamptz{{Time: time.Unix(1, 0)}}, []pgtype.Float8{{Float: 3}}),
+		},
+	}, nil)
+	p := &pgxSeriesSet{rows: rows, querier: failingQuerier{labelsErr}}
+
+	// no series are returned without their labels
+	if p.Next() {
+		t.Fatalf("unexpected series: %v", p.At())
+	}
+	if !errors.Is(p.Err(), labelsErr) {
+		t.Fatalf("unexpected error: got %v, wanted %v", p.Err(), labelsErr)
+	}
+	for i, r := range rows {
+		if !r.(*mockPgxRows).closeCalled {
+			t.Errorf("rows %d not closed", i)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// This file and its contents are licensed under the Apache License 2.0.
+// Please see the included NOTICE for copyright information and
+// LICENSE for a copy of the license.
+
+package pgmodel
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+)
+
+// Shutdowner is implemented by the ingestors that buffer data in memory,
+// which would be lost if the process exited without inserting it.
+type Shutdowner interface {
+	// Shutdown inserts the data buffered and releases the connections to
+	// the database. It returns the error of the context if the inserts do
+	// not complete before it is done, and otherwise the first error of the
+	// inserts no request waits for.
+	Shutdown(ctx context.Context) error
+}
+
+// Shutdown implements Shutdowner. Inserters that can not shut down are
+// closed.
+func (i *DBIngestor) Shutdown(ctx context.Context) error {
+	i.stopReplaying()
+	if s, ok := i.db.(Shutdowner); ok {
+		return s.Shutdown(ctx)
+	}
+	i.db.Close()
+	return nil
+}
+
+// Shutdown implements Shutdowner. It closes the inserter, waits for the
+// pending batches and the copies of the batches already taken to be inserted
+// and then closes the connection. Requests acked synchronously report their
+// errors to their callers, only the errors of asynchronously acked requests
+// are returned.
+func (p *pgxInserter) Shutdown(ctx context.Context) error {
+	atomic.StoreInt32(&p.shuttingDown, 1)
+	done := make(chan struct{})
+	go func() {
+		p.Close()
+		p.copiers.Wait()
+		p.asyncInserts.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return fmt.Errorf("flushing pending inserts: %w", ctx.Err())
+	}
+
+	p.conn.Close()
+	p.flushErrMtx.Lock()
+	defer p.flushErrMtx.Unlock()
+	return p.flushErr
+}
+
+// recordFlushErr keeps the first error of the asynchronously acked inserts
+// completing after Shutdown was called.
+func (p *pgxInserter) recordFlushErr(err error) {
+	if atomic.LoadInt32(&p.shuttingDown) == 0 {
+		return
+	}
+	p.flushErrMtx.Lock()
+	defer p.flushErrMtx.Unlock()
+	if p.flushErr == nil {
+		p.flushErr = err
+	}
+}
@@ -14,7 +14,6 @@ import (
 	"time"
 
 	"github.com/jackc/pgconn"
-	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/prometheus/common/model"
 	"github.com/timescale/timescale-prometheus/pkg/clockcache"
@@ -28,11 +27,114 @@ const (
 )
 
 type Cfg struct {
-	AsyncAcks       bool
-	ReportInterval  int
+	AsyncAcks      bool
+	ReportInterval int
+	// SeriesCacheSize is the maximum number of series ids cached on the
+	// write path, DefaultSeriesCacheSize if zero.
 	SeriesCacheSize uint64
+	// ConflictTarget lists the columns of the unique constraint samples are
+	// deduplicated on. If empty, conflicts on any constraint are ignored.
+	// With series_id and time, connectors writing the same samples into one
+	// database, e.g. active-active in several regions, store each sample
+	// once; the samples skipped are counted as duplicates.
+	ConflictTarget []string
+	// SeriesInsertConcurrency is the maximum number of batches used in
+	// parallel to create the new series of a metric. Values below 2 create
+	// all series in a single batch.
+	SeriesInsertConcurrency int
+	// InsertRetryPolicy controls retries of sample inserts failing with
+	// transient errors. If nil, failed inserts are not retried.
+	InsertRetryPolicy *RetryPolicy
+	// DuplicatePolicy decides how samples of a series sharing a timestamp
+	// within one insert are handled. By default all of them are inserted.
+	DuplicatePolicy DuplicatePolicy
+	// RejectOutOfOrder drops samples older than a sample of the same series
+	// received earlier in the same insert. Such samples are always counted.
+	RejectOutOfOrder bool
+	// IngestStages are applied in order to every write request before it is
+	// inserted.
+	IngestStages []IngestStage
+	// InsertFormat is the wire format samples are inserted in. It only
+	// applies to the statements inserting samples, and samples holding
+	// staleness markers are always inserted in binary.
+	InsertFormat WireFormat
+	// MaxBatchSize is the number of series of a metric batched into one
+	// insert. If not positive, batches of up to 2000 series are sent.
+	MaxBatchSize int
+	// MaxBatchAge is how long a metric's batch waits for more data before it
+	// is sent incomplete. If zero, a batch is sent as soon as no more data of
+	// the metric is queued.
+	MaxBatchAge time.Duration
+	// InsertTimeBucket, if positive, splits every batch into one insert per
+	// time range of this width, so that with the chunk interval of the data
+	// tables each insert writes into a single chunk.
+	InsertTimeBucket time.Duration
+	// EmptyInsertPolicy decides what happens when no row of a non-empty
+	// batch is inserted. By default the samples are counted as duplicates.
+	EmptyInsertPolicy EmptyInsertPolicy
+	// NumCopiers is the number of routines inserting samples in parallel,
+	// each on a connection of its own from the pool. If not positive, it is
+	// derived from GOMAXPROCS and ConnectionsPerProc.
+	NumCopiers int
+	// NullValues are sample values stored as NULL rather than as values,
+	// to mark explicit gaps of sparse series. Reads skip NULL samples like
+	// missing ones. Values are matched by their bits, so a specific NaN can
+	// be given; stale markers are always stored as they are.
+	NullValues []float64
+	// MaxLabelsSize is the maximum combined size in bytes of the label names
+	// and values of a series. Write requests with a larger series fail with
+	// ErrSeriesTooLarge before anything is inserted. Zero means no limit
+	// besides the 64KiB of the series' canonical string.
+	MaxLabelsSize int
+	// MaxLabelValueLength is the maximum length in bytes of a label value.
+	// Write requests with a longer value fail with ErrInvalidLabel before
+	// anything is inserted. Zero means no limit.
+	MaxLabelValueLength int
+	// TimestampRangePolicy decides what happens to samples with a timestamp
+	// a TIMESTAMPTZ can not hold. By default the insert fails with
+	// ErrTimestampOutOfRange before anything is inserted.
+	TimestampRangePolicy TimestampRangePolicy
+	// MetricChunkIntervals maps metric names to the chunk interval of their
+	// data tables, set when the connector creates the metric. Other metrics
+	// use the default chunk interval, see ChunkIntervals.
+	MetricChunkIntervals map[string]time.Duration
+	// Tenancy, if set, stores the tenant of every series written as a
+	// label.
+	Tenancy *Tenancy
+	// OverloadPolicy decides when the ingestor reports that it can not keep
+	// up with its writes. By default it never does.
+	OverloadPolicy OverloadPolicy
+	// InsertBuffer, if set, keeps the write requests that fail because the
+	// database is unavailable, acking them to the client. It is replayed
+	// every InsertBufferReplayInterval, by InsertBufferReplayConcurrency
+	// routines keeping the buffered samples of every series in order. Once
+	// the database is back, new requests are inserted directly, so their
+	// samples may be inserted before older buffered ones.
+	InsertBuffer                  *InsertBuffer
+	InsertBufferReplayInterval    time.Duration
+	InsertBufferReplayConcurrency int
+	// PartialWrites rejects the series of a write request that can not be
+	// stored, e.g. with invalid labels, and inserts the others. Ingest then
+	// returns a PartialWriteError. By default the whole request fails.
+	PartialWrites bool
+	// SortRows sorts the rows of every insert by series and time, so that
+	// the samples of a series are written next to each other in time
+	// order. Samples of a series at the same time keep their order.
+	SortRows bool
+	// DryRun validates and batches the samples like for an insert, but
+	// sends nothing to the database: metric tables and series are only
+	// taken from the caches, never created, and the inserts of the samples
+	// are not executed. The insert statements built are returned by
+	// DryRunInserts instead.
+	DryRun bool
+	// InsertTarget is the schema and the columns samples are inserted
+	// into. The zero value inserts into the standard data tables.
+	InsertTarget InsertTarget
 }
 
+// sampleColumns are the columns of a metric's data table.
+var sampleColumns = map[string]bool{"time": true, "value": true, "series_id": true}
+
 // NewPgxIngestorWithMetricCache returns a new Ingestor that uses connection pool and a metrics cache
 // for caching metric table names.
 func NewPgxIngestorWithMetricCache(c *pgxpool.Pool, cache MetricCache, cfg *Cfg) (*DBIngestor, error) {
@@ -46,7 +148,22 @@ func NewPgxIngestorWithMetricCache(c *pgxpool.Pool, cache MetricCache, cfg *Cfg)
 		return nil, err
 	}
 
-	return &DBIngestor{db: pi}, nil
+	ingestor := &DBIngestor{db: pi, stages: cfg.IngestStages, maxLabelsSize: cfg.MaxLabelsSize, maxLabelValueLength: cfg.MaxLabelValueLength, tenancy: cfg.Tenancy, partialWrites: cfg.PartialWrites}
+	if cfg.InsertBuffer != nil {
+		interval := cfg.InsertBufferReplayInterval
+		if interval <= 0 {
+			interval = DefaultInsertBufferReplayInterval
+		}
+		ingestor.buffer = cfg.InsertBuffer
+		ingestor.replayConcurrency = cfg.InsertBufferReplayConcurrency
+		if ingestor.replayConcurrency <= 0 {
+			ingestor.replayConcurrency = DefaultInsertBufferReplayConcurrency
+		}
+		ingestor.stopReplay = make(chan struct{})
+		ingestor.replayDone = make(chan struct{})
+		go ingestor.replayBuffer(interval)
+	}
+	return ingestor, nil
 }
 
 // NewPgxIngestor returns a new Ingestor that write to PostgreSQL using PGX
@@ -58,6 +175,11 @@ func NewPgxIngestor(c *pgxpool.Pool) (*DBIngestor, error) {
 var ConnectionsPerProc = 5
 
 func newPgxInserter(conn pgxConn, cache MetricCache, cfg *Cfg) (*pgxInserter, error) {
+	stmt, err := newInsertStatement(cfg.InsertTarget, cfg.ConflictTarget)
+	if err != nil {
+		return nil, err
+	}
+
 	cmc := make(chan struct{}, 1)
 
 	maxProcs := runtime.GOMAXPROCS(-1)
@@ -69,10 +191,32 @@ func newPgxInserter(conn pgxConn, cache MetricCache, cfg *Cfg) (*pgxInserter, er
 	}
 
 	// we leave one connection per-core for other usages
-	numCopiers := maxProcs*ConnectionsPerProc - maxProcs
+	numCopiers := cfg.NumCopiers
+	if numCopiers <= 0 {
+		numCopiers = maxProcs*ConnectionsPerProc - maxProcs
+	}
+	seriesCache := newSeriesIDCache(cfg.SeriesCacheSize)
 	toCopiers := make(chan copyRequest, numCopiers)
-	for i := 0; i < numCopiers; i++ {
-		go runInserter(conn, toCopiers)
+	opts := &copierOptions{
+		metricTableNames:       cache,
+		completeMetricCreation: cmc,
+		toCopiers:              toCopiers,
+		seriesCache:            seriesCache,
+		chunkIntervals:         cfg.MetricChunkIntervals,
+		seriesConcurrency:      cfg.SeriesInsertConcurrency,
+		maxBatchSize:           cfg.MaxBatchSize,
+		maxBatchAge:            cfg.MaxBatchAge,
+		stmt:                   stmt,
+		retryPolicy:            cfg.InsertRetryPolicy,
+		timeBucket:             cfg.InsertTimeBucket,
+		emptyInsert:            cfg.EmptyInsertPolicy,
+		nulls:                  newNullValues(cfg.NullValues),
+		insertFormat:           cfg.InsertFormat,
+		sortRows:               cfg.SortRows,
+		dryRun:                 cfg.DryRun,
+	}
+	if opts.maxBatchSize <= 0 {
+		opts.maxBatchSize = flushSize
 	}
 
 	inserter := &pgxInserter{
@@ -81,6 +225,25 @@ func newPgxInserter(conn pgxConn, cache MetricCache, cfg *Cfg) (*pgxInserter, er
 		completeMetricCreation: cmc,
 		asyncAcks:              cfg.AsyncAcks,
 		toCopiers:              toCopiers,
+		duplicatePolicy:        cfg.DuplicatePolicy,
+		rejectOutOfOrder:       cfg.RejectOutOfOrder,
+		timestampRange:         cfg.TimestampRangePolicy,
+		seriesCache:            seriesCache,
+		overload:               cfg.OverloadPolicy,
+		copierOptions:          opts,
+		numCopiers:             numCopiers,
+	}
+	if cfg.DryRun {
+		inserter.dryRun = &dryRun{}
+		inserter.conn = dryRunConn{dryRun: inserter.dryRun}
+	}
+	copierConn := inserter.conn
+	for i := 0; i < numCopiers; i++ {
+		inserter.copiers.Add(1)
+		go func() {
+			defer inserter.copiers.Done()
+			runInserter(copierConn, toCopiers, opts)
+		}()
 	}
 	if cfg.AsyncAcks && cfg.ReportInterval > 0 {
 		inserter.insertedDatapoints = new(int64)
@@ -95,10 +258,12 @@ func newPgxInserter(conn pgxConn, cache MetricCache, cfg *Cfg) (*pgxInserter, er
 		}()
 	}
 	//on startup run a completeMetricCreation to recover any potentially
-	//incomplete metric
-	err := inserter.CompleteMetricCreation()
-	if err != nil {
-		return nil, err
+	//incomplete metric, unless nothing is written
+	if !cfg.DryRun {
+		err = inserter.CompleteMetricCreation()
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	go inserter.runCompleteMetricCreationWorker()
@@ -114,9 +279,39 @@ type pgxInserter struct {
 	asyncAcks              bool
 	insertedDatapoints     *int64
 	toCopiers              chan copyRequest
+	duplicatePolicy        DuplicatePolicy
+	rejectOutOfOrder       bool
+	timestampRange         TimestampRangePolicy
+	// seriesCache is shared by the insert routines of all metrics
+	seriesCache *seriesIDCache
+	overload    OverloadPolicy
+	// copierOptions are passed to the insert routines and the copiers
+	copierOptions *copierOptions
+	// numCopiers is the number of copiers, the connections inserting
+	// samples
+	numCopiers int
+	// dryRun is nil unless the inserter runs dry
+	dryRun *dryRun
+	// routines tracks the running per-metric insert routines, which may
+	// still flush batches after their input is closed.
+	routines sync.WaitGroup
+	// copiers tracks the running copiers, which insert the batches left
+	// after toCopiers is closed.
+	copiers sync.WaitGroup
+	// asyncInserts tracks the asynchronously acked inserts still running.
+	asyncInserts sync.WaitGroup
+	closeOnce    sync.Once
+	// shuttingDown is set once Shutdown is called, the errors of the
+	// asynchronously acked inserts are then kept in flushErr.
+	shuttingDown int32
+	flushErrMtx  sync.Mutex
+	flushErr     error
 }
 
 func (p *pgxInserter) CompleteMetricCreation() error {
+	if p.dryRun != nil {
+		return nil
+	}
 	_, err := p.conn.Exec(
 		context.Background(),
 		finalizeMetricCreation,
@@ -133,20 +328,29 @@ func (p *pgxInserter) runCompleteMetricCreationWorker() {
 	}
 }
 
+// Close stops the insert routines once they have passed their pending batches
+// on to the copiers, which insert them in the background. Use Shutdown to
+// wait for the inserts.
 func (p *pgxInserter) Close() {
-	close(p.completeMetricCreation)
-	p.inserters.Range(func(key, value interface{}) bool {
-		close(value.(chan insertDataRequest))
-		return true
+	p.closeOnce.Do(func() {
+		p.inserters.Range(func(key, value interface{}) bool {
+			close(value.(chan insertDataRequest))
+			return true
+		})
+		p.routines.Wait()
+		close(p.completeMetricCreation)
+		close(p.toCopiers)
 	})
-	close(p.toCopiers)
 }
 
-func (p *pgxInserter) InsertNewData(rows map[string][]samplesInfo) (uint64, error) {
-	return p.InsertData(rows)
+func (p *pgxInserter) InsertNewData(ctx context.Context, rows map[string][]samplesInfo) (uint64, DroppedSamples, error) {
+	return p.InsertData(ctx, rows)
 }
 
 type insertDataRequest struct {
+	// ctx is the context of the request the data belongs to. Requests
+	// canceled before their data is batched are dropped.
+	ctx      context.Context
 	metric   string
 	data     []samplesInfo
 	finished *sync.WaitGroup
@@ -158,28 +362,117 @@ type insertDataTask struct {
 	errChan  chan error
 }
 
-func (p *pgxInserter) InsertData(rows map[string][]samplesInfo) (uint64, error) {
-	var numRows uint64
+// DroppedSamples counts the samples of an insert that were dropped before
+// reaching the database, by the reason they were dropped.
+type DroppedSamples struct {
+	// Duplicates are the samples collapsed by the DuplicatePolicy.
+	Duplicates uint64
+	// OutOfOrder are the samples dropped by RejectOutOfOrder.
+	OutOfOrder uint64
+	// OutOfRange are the samples dropped by the DropOutOfRange policy.
+	OutOfRange uint64
+}
+
+// Total returns the number of samples dropped for any reason.
+func (d DroppedSamples) Total() uint64 {
+	return d.Duplicates + d.OutOfOrder + d.OutOfRange
+}
+
+// InsertData inserts the samples of rows, returning the number of samples
+// inserted and, separately, the samples dropped by the duplicate,
+// out-of-order and timestamp range checks. Without async acks it waits for
+// the insert to complete, returning ctx.Err() as soon as ctx is canceled.
+// Samples of a canceled request that were not yet batched are not sent to
+// the database. Samples already batched may be inserted together with those
+// of other requests. Metrics without samples are skipped, so rows without
+// any sample are not sent to the database at all.
+func (p *pgxInserter) InsertData(ctx context.Context, rows map[string][]samplesInfo) (uint64, DroppedSamples, error) {
+	var (
+		numRows uint64
+		dropped DroppedSamples
+	)
+	// Duplicates are collapsed before any data is sent, so a rejected
+	// request inserts nothing.
+	for metricName, data := range rows {
+		if outOfRange := checkTimestampRange(data, p.timestampRange); outOfRange > 0 {
+			outOfRangeSamples.Add(float64(outOfRange))
+			if p.timestampRange == FailOutOfRange {
+				return 0, DroppedSamples{}, fmt.Errorf("%w: %d samples of metric %s", ErrTimestampOutOfRange, outOfRange, metricName)
+			}
+			if p.timestampRange == DropOutOfRange {
+				dropped.OutOfRange += uint64(outOfRange)
+			}
+			log.Warn("msg", "samples with timestamps out of range", "metric", metricName, "count", outOfRange, "policy", p.timestampRange)
+		}
+		if outOfOrder := checkOutOfOrder(data, p.rejectOutOfOrder); outOfOrder > 0 {
+			outOfOrderSamples.Add(float64(outOfOrder))
+			if p.rejectOutOfOrder {
+				dropped.OutOfOrder += uint64(outOfOrder)
+				log.Warn("msg", "dropping out-of-order samples", "metric", metricName, "count", outOfOrder)
+			}
+		}
+		deduped, duplicates, err := dedupSamples(data, p.duplicatePolicy)
+		if err != nil {
+			return 0, DroppedSamples{}, err
+		}
+		if duplicates > 0 {
+			duplicateSamples.Add(float64(duplicates))
+			dropped.Duplicates += uint64(duplicates)
+		}
+
+		// only the samples left after the checks are counted as inserted
+		metricRows := 0
+		for _, si := range deduped {
+			metricRows += len(si.samples)
+		}
+		if metricRows == 0 {
+			delete(rows, metricName)
+			continue
+		}
+		numRows += uint64(metricRows)
+		rows[metricName] = deduped
+	}
+	if len(rows) == 0 {
+		return 0, dropped, nil
+	}
+
+	if p.asyncAcks {
+		// the data is acked before it is inserted, so it must outlive the
+		// request
+		ctx = context.Background()
+	}
+
 	workFinished := &sync.WaitGroup{}
 	workFinished.Add(len(rows))
 	errChan := make(chan error, 1)
 	for metricName, data := range rows {
-		for _, si := range data {
-			numRows += uint64(len(si.samples))
+		if err := p.insertMetricData(ctx, metricName, data, workFinished, errChan); err != nil {
+			return 0, dropped, err
 		}
-		p.insertMetricData(metricName, data, workFinished, errChan)
 	}
 
 	var err error
 	if !p.asyncAcks {
-		workFinished.Wait()
+		done := make(chan struct{})
+		go func() {
+			workFinished.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			// errChan is left open, the handlers may still report to it
+			return 0, dropped, ctx.Err()
+		}
 		select {
 		case err = <-errChan:
 		default:
 		}
 		close(errChan)
 	} else {
+		p.asyncInserts.Add(1)
 		go func() {
+			defer p.asyncInserts.Done()
 			workFinished.Wait()
 			select {
 			case err = <-errChan:
@@ -188,18 +481,24 @@ func (p *pgxInserter) InsertData(rows map[string][]samplesInfo) (uint64, error)
 			close(errChan)
 			if err != nil {
 				log.Error("msg", fmt.Sprintf("error on async send, dropping %d datapoints", numRows), "error", err)
+				p.recordFlushErr(err)
 			} else if p.insertedDatapoints != nil {
 				atomic.AddInt64(p.insertedDatapoints, int64(numRows))
 			}
 		}()
 	}
 
-	return numRows, err
+	return numRows, dropped, err
 }
 
-func (p *pgxInserter) insertMetricData(metric string, data []samplesInfo, finished *sync.WaitGroup, errChan chan error) {
+func (p *pgxInserter) insertMetricData(ctx context.Context, metric string, data []samplesInfo, finished *sync.WaitGroup, errChan chan error) error {
 	inserter := p.getMetricInserter(metric, errChan)
-	inserter <- insertDataRequest{metric: metric, data: data, finished: finished, errChan: errChan}
+	select {
+	case inserter <- insertDataRequest{ctx: ctx, metric: metric, data: data, finished: finished, errChan: errChan}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 func (p *pgxInserter) createMetricTable(metric string) (string, error) {
@@ -262,19 +561,32 @@ func (p *pgxInserter) getMetricInserter(metric string, errChan chan error) chan
 		actual, old := p.inserters.LoadOrStore(metric, c)
 		inserter = actual
 		if !old {
-			go runInserterRoutine(p.conn, c, metric, p.completeMetricCreation, errChan, p.metricTableNames, p.toCopiers)
+			p.routines.Add(1)
+			go func() {
+				defer p.routines.Done()
+				runInserterRoutine(p.conn, c, metric, errChan, p.copierOptions)
+			}()
 		}
 	}
 	return inserter.(chan insertDataRequest)
 }
 
 type insertHandler struct {
-	conn            pgxConn
-	input           chan insertDataRequest
-	pending         *pendingBuffer
-	seriesCache     map[string]SeriesID
-	metricTableName string
-	toCopiers       chan copyRequest
+	conn              pgxConn
+	input             chan insertDataRequest
+	pending           *pendingBuffer
+	seriesCache       *seriesIDCache
+	metricTableName   string
+	toCopiers         chan copyRequest
+	seriesConcurrency int
+	maxBatchSize      int
+	maxBatchAge       time.Duration
+	// pendingSince is when the first request of the pending batch was
+	// received.
+	pendingSince time.Time
+	// dryRun leaves the series not in the cache without an id rather than
+	// creating them
+	dryRun bool
 }
 
 type pendingBuffer struct {
@@ -300,6 +612,41 @@ type copyRequest struct {
 	table string
 }
 
+// copierOptions are the settings of the per-metric insert routines, which
+// batch the samples of their metric, and of the copiers, which insert the
+// batches. They are built once by newPgxInserter.
+type copierOptions struct {
+	// busyCopiers is the number of copiers inserting a batch. It is the
+	// first word of the struct for atomic access on 32-bit systems.
+	busyCopiers      int64
+	metricTableNames MetricCache
+	// completeMetricCreation is signaled when a metric may have been created
+	completeMetricCreation chan struct{}
+	toCopiers              chan copyRequest
+	seriesCache            *seriesIDCache
+	chunkIntervals         map[string]time.Duration
+	seriesConcurrency      int
+	maxBatchSize           int
+	maxBatchAge            time.Duration
+
+	stmt        insertStatement
+	retryPolicy *RetryPolicy
+	// timeBucket splits every batch into one insert per time range of its
+	// width, if positive
+	timeBucket time.Duration
+	// emptyInsert decides whether a batch of which no row was inserted is
+	// an error
+	emptyInsert EmptyInsertPolicy
+	// nulls are the values stored as NULL
+	nulls nullValues
+	// insertFormat is the wire format of the sample inserts
+	insertFormat WireFormat
+	// sortRows sorts the rows of every insert by series and time
+	sortRows bool
+	// dryRun skips the creation of metric tables and series
+	dryRun bool
+}
+
 func runInserterRoutineFailure(input chan insertDataRequest, err error) {
 	for idr := range input {
 		select {
@@ -310,9 +657,12 @@ func runInserterRoutineFailure(input chan insertDataRequest, err error) {
 	}
 }
 
-func runInserterRoutine(conn pgxConn, input chan insertDataRequest, metricName string, completeMetricCreationSignal chan struct{}, errChan chan error, metricTableNames MetricCache, toCopiers chan copyRequest) {
-	tableName, err := metricTableNames.Get(metricName)
-	if err == ErrEntryNotFound {
+func runInserterRoutine(conn pgxConn, input chan insertDataRequest, metricName string, errChan chan error, opts *copierOptions) {
+	tableName, err := opts.metricTableNames.Get(metricName)
+	if err == ErrEntryNotFound && opts.dryRun {
+		//the table is not created, assume it would be named after the metric
+		tableName, err = metricName, nil
+	} else if err == ErrEntryNotFound {
 		var possiblyNew bool
 		tableName, possiblyNew, err = getMetricTableName(conn, metricName)
 		if err != nil {
@@ -326,12 +676,21 @@ func runInserterRoutine(conn pgxConn, input chan insertDataRequest, metricName s
 		}
 
 		//ignone error since this is just an optimization
-		_ = metricTableNames.Set(metricName, tableName)
+		_ = opts.metricTableNames.Set(metricName, tableName)
+
+		chunkInterval := opts.chunkIntervals[metricName]
+		if possiblyNew && chunkInterval > 0 {
+			//a new table has no chunks yet, so all of them get the interval
+			err = setMetricChunkInterval(context.Background(), conn, metricName, chunkInterval)
+			if err != nil {
+				log.Warn("msg", "error setting the chunk interval of a new metric", "metric", metricName, "err", err)
+			}
+		}
 
 		if possiblyNew {
 			//pass a signal if there is space
 			select {
-			case completeMetricCreationSignal <- struct{}{}:
+			case opts.completeMetricCreation <- struct{}{}:
 			default:
 			}
 		}
@@ -348,12 +707,16 @@ func runInserterRoutine(conn pgxConn, input chan insertDataRequest, metricName s
 	}
 
 	handler := insertHandler{
-		conn:            conn,
-		input:           input,
-		pending:         pendingBuffers.Get().(*pendingBuffer),
-		seriesCache:     make(map[string]SeriesID),
-		metricTableName: tableName,
-		toCopiers:       toCopiers,
+		conn:              conn,
+		input:             input,
+		pending:           pendingBuffers.Get().(*pendingBuffer),
+		seriesCache:       opts.seriesCache,
+		metricTableName:   tableName,
+		toCopiers:         opts.toCopiers,
+		seriesConcurrency: opts.seriesConcurrency,
+		maxBatchSize:      opts.maxBatchSize,
+		maxBatchAge:       opts.maxBatchAge,
+		dryRun:            opts.dryRun,
 	}
 
 	for {
@@ -367,15 +730,47 @@ func runInserterRoutine(conn pgxConn, input chan insertDataRequest, metricName s
 
 	hotReceive:
 		for handler.nonblockingHandleReq() {
-			if len(handler.pending.batch.sampleInfos) >= flushSize {
+			if handler.batchFull() {
 				break hotReceive
 			}
 		}
 
+		if !handler.waitForBatch() {
+			// the input was closed, send what is left before exiting
+			handler.flush()
+			return
+		}
 		handler.flush()
 	}
 }
 
+func (h *insertHandler) batchFull() bool {
+	return len(h.pending.batch.sampleInfos) >= h.maxBatchSize
+}
+
+// waitForBatch receives requests until the pending batch is full or
+// maxBatchAge has passed since its first request. It returns false if the
+// input was closed.
+func (h *insertHandler) waitForBatch() bool {
+	if h.maxBatchAge <= 0 {
+		return true
+	}
+	timer := time.NewTimer(time.Until(h.pendingSince.Add(h.maxBatchAge)))
+	defer timer.Stop()
+	for h.hasPendingReqs() && !h.batchFull() {
+		select {
+		case req, ok := <-h.input:
+			if !ok {
+				return false
+			}
+			h.handleReq(req)
+		case <-timer.C:
+			return true
+		}
+	}
+	return true
+}
+
 func (h *insertHandler) hasPendingReqs() bool {
 	return len(h.pending.batch.sampleInfos) > 0
 }
@@ -393,7 +788,10 @@ func (h *insertHandler) blockingHandleReq() bool {
 
 func (h *insertHandler) nonblockingHandleReq() bool {
 	select {
-	case req := <-h.input:
+	case req, ok := <-h.input:
+		if !ok {
+			return false
+		}
 		h.handleReq(req)
 		return true
 	default:
@@ -402,8 +800,19 @@ func (h *insertHandler) nonblockingHandleReq() bool {
 }
 
 func (h *insertHandler) handleReq(req insertDataRequest) bool {
+	if err := req.ctx.Err(); err != nil {
+		select {
+		case req.errChan <- err:
+		default:
+		}
+		req.finished.Done()
+		return false
+	}
 	h.fillKnowSeriesIds(req.data)
-	needsFlush := h.pending.addReq(req)
+	if !h.hasPendingReqs() {
+		h.pendingSince = time.Now()
+	}
+	needsFlush := h.pending.addReq(req, h.maxBatchSize)
 	if needsFlush {
 		h.flushPending()
 		return true
@@ -416,8 +825,8 @@ func (h *insertHandler) fillKnowSeriesIds(sampleInfos []samplesInfo) (numMissing
 		if series.seriesID > -1 {
 			continue
 		}
-		id, ok := h.seriesCache[series.labels.String()]
-		if ok {
+		id, err := h.seriesCache.GetSeries(*series.labels)
+		if err == nil {
 			sampleInfos[i].seriesID = id
 			series.labels = nil
 		} else {
@@ -445,15 +854,40 @@ func (h *insertHandler) flushPending() {
 	h.pending = pendingBuffers.Get().(*pendingBuffer)
 }
 
-func runInserter(conn pgxConn, in chan copyRequest) {
+// buildOnConflictClause returns the ON CONFLICT clause of the sample insert
+// for the given conflict target columns, renamed to the names in columns.
+func buildOnConflictClause(target []string, columns map[string]string) (string, error) {
+	if len(target) == 0 {
+		return "ON CONFLICT DO NOTHING", nil
+	}
+	seen := make(map[string]bool, len(target))
+	names := make([]string, 0, len(target))
+	for _, col := range target {
+		if !sampleColumns[col] {
+			return "", fmt.Errorf("invalid conflict target column %q", col)
+		}
+		if seen[col] {
+			return "", fmt.Errorf("duplicate conflict target column %q", col)
+		}
+		seen[col] = true
+		names = append(names, columns[col])
+	}
+	return fmt.Sprintf("ON CONFLICT (%s) DO NOTHING", strings.Join(names, ", ")), nil
+}
+
+func runInserter(conn pgxConn, in chan copyRequest, opts *copierOptions) {
 	for {
 		req, ok := <-in
 		if !ok {
 			return
 		}
-		err := doInsert(conn, req)
+		atomic.AddInt64(&opts.busyCopiers, 1)
+		err := doInsert(conn, req, opts)
+		atomic.AddInt64(&opts.busyCopiers, -1)
 		if err != nil {
-			err = insertErrorFallback(conn, req, err)
+			// the series may be the cause, e.g. if they were deleted by
+			// the retention job since they were cached
+			opts.seriesCache.invalidate(req.data.batch.sampleInfos)
 		}
 
 		req.data.reportResults(err)
@@ -463,14 +897,14 @@ func runInserter(conn pgxConn, in chan copyRequest) {
 
 // certain errors are recoverable, handle those we can
 //   1. if the table is compressed, decompress and retry the insertion
-func insertErrorFallback(conn pgxConn, req copyRequest, err error) error {
+func insertErrorFallback(conn pgxConn, req copyRequest, queryString string, rows sampleRows, opts *copierOptions, err error) (int64, error) {
 	err = tryRecovery(conn, req, err)
 	if err != nil {
 		log.Warn("msg", fmt.Sprintf("time out while processing error for %s", req.table), "error", err.Error())
-		return err
+		return 0, err
 	}
 
-	return doInsert(conn, req)
+	return insertRows(conn, req.table, queryString, rows, opts.nulls, opts.insertFormat)
 }
 
 // we can currently recover from two error:
@@ -503,7 +937,11 @@ func tryRecovery(conn pgxConn, req copyRequest, err error) error {
 	return err
 }
 
-func doInsert(conn pgxConn, req copyRequest) (err error) {
+// doInsert inserts the samples of req, with one insert per time range of
+// width opts.timeBucket if it is positive. Every insert is retried and
+// recovered from errors on its own, so the inserts of earlier time ranges,
+// already committed, are not sent again when a later one fails.
+func doInsert(conn pgxConn, req copyRequest, opts *copierOptions) (err error) {
 	numRows := 0
 	for i := range req.data.batch.sampleInfos {
 		numRows += len(req.data.batch.sampleInfos[i].samples)
@@ -520,21 +958,55 @@ func doInsert(conn pgxConn, req copyRequest) (err error) {
 	if len(times) != numRows {
 		panic("invalid insert request")
 	}
-	queryString := fmt.Sprintf("INSERT INTO %s(time, value, series_id) SELECT * FROM unnest($1::TIMESTAMPTZ[], $2::DOUBLE PRECISION[], $3::BIGINT[]) a ON CONFLICT DO NOTHING", pgx.Identifier{dataSchema, req.table}.Sanitize())
-	var ct pgconn.CommandTag
-	ct, err = conn.Exec(context.Background(), queryString, times, vals, series)
-	if err != nil {
-		return
+	if opts.sortRows {
+		sortBySeriesAndTime(sampleRows{times, vals, series})
+	}
+	queryString := opts.stmt.sql(req.table)
+	var inserted int64
+	for _, rows := range groupByTimeBucket(sampleRows{times, vals, series}, opts.timeBucket) {
+		var n int64
+		n, err = insertWithRetry(conn, req.table, queryString, rows, opts.retryPolicy, opts.nulls, opts.insertFormat)
+		if err != nil {
+			n, err = insertErrorFallback(conn, req, queryString, rows, opts, err)
+		}
+		if err != nil {
+			return
+		}
+		inserted += n
 	}
 
-	if int64(numRows) != ct.RowsAffected() {
-		log.Warn("msg", "duplicate data in sample", "table", req.table, "duplicate_count", int64(numRows)-ct.RowsAffected(), "row_count", numRows)
-		duplicateSamples.Add(float64(int64(numRows) - ct.RowsAffected()))
+	if err = opts.emptyInsert.check(req.table, int64(numRows), inserted); err != nil {
+		return
+	}
+	if int64(numRows) != inserted {
+		log.Warn("msg", "duplicate data in sample", "table", req.table, "duplicate_count", int64(numRows)-inserted, "row_count", numRows)
+		duplicateSamples.Add(float64(int64(numRows) - inserted))
 		duplicateWrites.Inc()
 	}
 	return nil
 }
 
+// insertRows inserts the rows with the insert statement, sent in the wire
+// format. It returns the number of rows inserted. The statement skips the rows
+// conflicting with stored ones, so the rows not inserted are duplicates and
+// sending them again would not insert them either.
+func insertRows(conn pgxConn, table string, queryString string, rows sampleRows, nulls nullValues, format WireFormat) (int64, error) {
+	vals := nulls.values(rows.vals)
+	ct, err := conn.Exec(context.Background(), queryString, format.insertArgs(rows.vals, rows.times, vals, rows.series)...)
+	// the gaps read the same whether they are stored as NULL or not at all,
+	// so the dropped rows are not reported as duplicates
+	var dropped int64
+	if len(nulls) > 0 && isNotNullViolation(err) {
+		log.Warn("msg", "data table does not take NULL values, dropping them", "table", table)
+		rows, dropped = nulls.withoutNulls(rows)
+		ct, err = conn.Exec(context.Background(), queryString, format.insertArgs(rows.vals, rows.times, rows.vals, rows.series)...)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return ct.RowsAffected() + dropped, nil
+}
+
 func decompressChunks(conn pgxConn, pending *pendingBuffer, table string) error {
 	minTime := model.Time(pending.batch.minSeen).Time()
 
@@ -587,10 +1059,15 @@ func (pending *pendingBuffer) reportResults(err error) {
 	pending.batch.ResetPosition()
 }
 
+// setSeriesIds sets the seriesID of every element of sampleInfos whose series
+// is not yet known, creating the series if needed. sampleInfos may contain the
+// same series several times: each series is looked up only once and all of its
+// elements get the same id. On error no ids are set and the series cache is
+// left unchanged.
 func (h *insertHandler) setSeriesIds(sampleInfos []samplesInfo) (string, error) {
 	numMissingSeries := h.fillKnowSeriesIds(sampleInfos)
 
-	if numMissingSeries == 0 {
+	if numMissingSeries == 0 || h.dryRun {
 		return "", nil
 	}
 
@@ -602,10 +1079,9 @@ func (h *insertHandler) setSeriesIds(sampleInfos []samplesInfo) (string, error)
 	}
 	var lastSeenLabel *Labels
 
-	batch := h.conn.NewBatch()
-	numSQLFunctionCalls := 0
 	// Sort and remove duplicates. The sort is needed to remove duplicates. Each series is inserted
-	// in a different transaction, thus deadlocks are not an issue.
+	// in a different transaction, thus deadlocks are not an issue, even when the series are split
+	// across concurrent batches.
 	sort.Slice(seriesToInsert, func(i, j int) bool {
 		return seriesToInsert[i].labels.Compare(seriesToInsert[j].labels) < 0
 	})
@@ -618,53 +1094,148 @@ func (h *insertHandler) setSeriesIds(sampleInfos []samplesInfo) (string, error)
 			continue
 		}
 
-		batch.Queue("BEGIN;")
-		batch.Queue(getSeriesIDForLabelSQL, curr.labels.metricName, curr.labels.names, curr.labels.values)
-		batch.Queue("COMMIT;")
-		numSQLFunctionCalls++
 		batchSeries = append(batchSeries, []*samplesInfo{curr})
 
 		lastSeenLabel = curr.labels
 	}
 
-	br, err := h.conn.SendBatch(context.Background(), batch)
-	if err != nil {
-		return "", err
+	numBatches := h.seriesConcurrency
+	if numBatches < 1 {
+		numBatches = 1
 	}
-	defer br.Close()
+	if numBatches > len(batchSeries) {
+		numBatches = len(batchSeries)
+	}
+
+	// The ids are only applied once all batches succeeded, so a failure
+	// leaves the series cache and the samples untouched.
+	ids := make([]SeriesID, len(batchSeries))
+	var tableName string
+	if numBatches == 1 {
+		var err error
+		tableName, err = h.getSeriesIds(context.Background(), 0, batchSeries, ids)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var (
+			wg         sync.WaitGroup
+			errOnce    sync.Once
+			firstErr   error
+			tableNames = make([]string, numBatches)
+		)
+		for b := 0; b < numBatches; b++ {
+			start := b * len(batchSeries) / numBatches
+			end := (b + 1) * len(batchSeries) / numBatches
+			wg.Add(1)
+			go func(b, start, end int) {
+				defer wg.Done()
+				var err error
+				tableNames[b], err = h.getSeriesIds(ctx, b, batchSeries[start:end], ids[start:end])
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = err
+						cancel()
+					})
+				}
+			}(b, start, end)
+		}
+		wg.Wait()
+
+		if firstErr != nil {
+			return "", firstErr
+		}
+		tableName = tableNames[0]
+	}
+
+	for i, series := range batchSeries {
+		_ = h.seriesCache.SetSeries(*series[0].labels, ids[i])
+		for _, lsi := range series {
+			lsi.seriesID = ids[i]
+		}
+	}
+
+	return tableName, nil
+}
+
+// SeriesInsertError is returned when looking up or creating series fails. It
+// identifies the series affected so that callers can log or retry just those.
+type SeriesInsertError struct {
+	// Batch is the index of the concurrent batch that failed.
+	Batch int
+	// SQL is the statement that failed. It is empty if the batch could not
+	// be sent at all.
+	SQL string
+	// Series are the labels of the series the failed statement was sent for,
+	// or of every series in the batch if the batch could not be sent.
+	Series []*Labels
+	Err    error
+}
+
+func (e *SeriesInsertError) Error() string {
+	if e.SQL == "" {
+		return fmt.Sprintf("error sending series batch %d: %v", e.Batch, e.Err)
+	}
+	return fmt.Sprintf("error inserting series in batch %d: %q: %v", e.Batch, e.SQL, e.Err)
+}
+
+func (e *SeriesInsertError) Unwrap() error {
+	return e.Err
+}
 
-	if numSQLFunctionCalls != len(batchSeries) {
-		return "", fmt.Errorf("unexpected difference in numQueries and batchSeries")
+// getSeriesIds creates the given series in a single batch, storing their ids
+// in the corresponding elements of ids. Errors are returned as
+// *SeriesInsertError.
+func (h *insertHandler) getSeriesIds(ctx context.Context, batchIdx int, batchSeries [][]*samplesInfo, ids []SeriesID) (string, error) {
+	batch := h.conn.NewBatch()
+	for _, series := range batchSeries {
+		labels := series[0].labels
+		batch.Queue("BEGIN;")
+		batch.Queue(getSeriesIDForLabelSQL, labels.metricName, labels.names, labels.values)
+		batch.Queue("COMMIT;")
 	}
 
+	br, err := h.conn.SendBatch(ctx, batch)
+	if err != nil {
+		all := make([]*Labels, len(batchSeries))
+		for i, series := range batchSeries {
+			all[i] = series[0].labels
+		}
+		return "", &SeriesInsertError{Batch: batchIdx, Series: all, Err: err}
+	}
+	defer br.Close()
+
 	var tableName string
-	for i := 0; i < numSQLFunctionCalls; i++ {
+	for i, series := range batchSeries {
+		seriesErr := func(sql string, err error) error {
+			return &SeriesInsertError{Batch: batchIdx, SQL: sql, Series: []*Labels{series[0].labels}, Err: err}
+		}
 		_, err = br.Exec()
 		if err != nil {
-			return "", err
+			return "", seriesErr("BEGIN;", err)
 		}
 		row := br.QueryRow()
 
 		var id SeriesID
 		err = row.Scan(&tableName, &id)
 		if err != nil {
-			return "", err
-		}
-		h.seriesCache[batchSeries[i][0].labels.String()] = id
-		for _, lsi := range batchSeries[i] {
-			lsi.seriesID = id
+			return "", seriesErr(getSeriesIDForLabelSQL, err)
 		}
+		ids[i] = id
 		_, err = br.Exec()
 		if err != nil {
-			return "", err
+			return "", seriesErr("COMMIT;", err)
 		}
 	}
 
 	return tableName, nil
 }
 
-func (p *pendingBuffer) addReq(req insertDataRequest) bool {
+func (p *pendingBuffer) addReq(req insertDataRequest, maxSize int) bool {
 	p.needsResponse = append(p.needsResponse, insertDataTask{finished: req.finished, errChan: req.errChan})
 	p.batch.sampleInfos = append(p.batch.sampleInfos, req.data...)
-	return len(p.batch.sampleInfos) > flushSize
+	return len(p.batch.sampleInfos) >= maxSize
 }
@@ -7,35 +7,210 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgconn"
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/pkg/labels"
 	"github.com/prometheus/prometheus/promql/parser"
 	"github.com/prometheus/prometheus/storage"
 	"github.com/timescale/timescale-prometheus/pkg/clockcache"
 	"github.com/timescale/timescale-prometheus/pkg/log"
 	"github.com/timescale/timescale-prometheus/pkg/prompb"
+	"github.com/timescale/timescale-prometheus/pkg/util"
+)
+
+var (
+	// ErrUnboundedQuery is returned for queries that would select every series.
+	ErrUnboundedQuery = fmt.Errorf("query must contain at least one selective label matcher")
+
+	// ErrTooManyMetrics is returned for queries matching more metrics than
+	// allowed.
+	ErrTooManyMetrics = fmt.Errorf("query matches too many metrics")
+
+	// matchAll is used in place of an empty matcher set for allowed unbounded queries.
+	matchAll = labels.MustNewMatcher(labels.MatchRegexp, MetricNameLabelName, ".*")
 )
 
 const (
 	getMetricsTableSQL = "SELECT table_name FROM " + catalogSchema + ".get_metric_table_name_if_exists($1)"
 	getLabelNamesSQL   = "SELECT distinct key from " + catalogSchema + ".label"
 	getLabelValuesSQL  = "SELECT value from " + catalogSchema + ".label WHERE key = $1"
+
+	// The by matchers variants only consider the labels of the series
+	// selected by the matcher clauses.
+	getLabelNamesByMatchersSQLFormat = `SELECT DISTINCT l.key
+	FROM ` + catalogSchema + `.label l
+	WHERE l.id IN (SELECT unnest(s.labels) FROM ` + catalogSchema + `.series s WHERE %s)`
+	getLabelValuesByMatchersSQLFormat = `SELECT DISTINCT l.value
+	FROM ` + catalogSchema + `.label l
+	WHERE l.key = $%d
+	AND l.id IN (SELECT unnest(s.labels) FROM ` + catalogSchema + `.series s WHERE %s)`
+
+	// Data older than the boundary may have been dropped by the retention job.
+	getRetentionBoundarySQL = "SELECT now() - " + catalogSchema + ".get_metric_retention_period($1)"
 )
 
-// NewPgxReaderWithMetricCache returns a new DBReader that reads from PostgreSQL using PGX
-// and caches metric table names using the supplied cacher.
-func NewPgxReaderWithMetricCache(c *pgxpool.Pool, cache MetricCache, labelsCacheSize uint64) *DBReader {
+// ReaderCfg holds the configuration of the read path.
+type ReaderCfg struct {
+	LabelsCacheSize uint64
+	// AlignToStep snaps the samples returned for step queries onto the
+	// query's step grid so all series share the same time points.
+	AlignToStep bool
+	// AllowUnbounded permits queries whose matchers select every series. By
+	// default they fail with ErrUnboundedQuery.
+	AllowUnbounded bool
+	// WarnOnRetention adds a warning to queries of a single metric whose time
+	// range ends before the metric's retention boundary.
+	WarnOnRetention bool
+	// ValuePrecision maps metric names to the number of significant figures
+	// their values are rounded to when read. Values of other metrics are
+	// returned exactly.
+	ValuePrecision map[string]int
+	// LabelEnrichment, if set, adds labels from an external table to the
+	// returned series.
+	LabelEnrichment *LabelEnrichment
+	// InterpolationMaxGap enables linear interpolation of the steps missing
+	// between two samples at most this far apart. It requires AlignToStep.
+	InterpolationMaxGap time.Duration
+	// EmptyLabelsPolicy decides what happens to series read without any
+	// labels. By default they are returned as they are.
+	EmptyLabelsPolicy EmptyLabelsPolicy
+	// MaxMetricsPerQuery is the maximum number of metrics a query may match,
+	// zero means no limit. Queries over the limit fail with
+	// ErrTooManyMetrics, or only log a warning if MaxMetricsWarnOnly is set.
+	MaxMetricsPerQuery int
+	MaxMetricsWarnOnly bool
+	// QueryFormat is the wire format query results are read in.
+	QueryFormat WireFormat
+	// MetricNamePrefix is prepended to the metric name of every series read,
+	// e.g. to namespace the metrics federated into another Prometheus.
+	// Queries still select metrics by their stored names.
+	MetricNamePrefix string
+	// SchemaChangeRetryDelay is the delay before a read failing because of a
+	// concurrent schema change is retried once. DefaultSchemaChangeRetryDelay
+	// is used if it is zero, a negative delay disables the retry.
+	SchemaChangeRetryDelay time.Duration
+	// WaitForMigrations makes reads wait for running migrations to finish.
+	WaitForMigrations bool
+	// ValueFilter, if set, drops the series read whose values do not pass
+	// it.
+	ValueFilter *ValueFilter
+	// StaleGap marks series stale between two samples further apart than
+	// it, so PromQL does not connect them. Zero disables it.
+	StaleGap time.Duration
+	// IgnoreCancel runs the data queries of a request detached from its
+	// context. By default they run in it, so that pgx sends PostgreSQL a
+	// cancel request for running queries when the request is canceled. pgx
+	// closes the canceled connection.
+	IgnoreCancel bool
+	// CaseInsensitiveLabels are the names of the labels whose values are
+	// matched case-insensitively, e.g. host names. It does not apply to the
+	// metric name.
+	CaseInsensitiveLabels []string
+	// SkipDecodeErrors skips the samples that failed to decode. By default
+	// they end the iteration of their series and are reported by the
+	// iterator's Err.
+	SkipDecodeErrors bool
+	// MaxQueryDuration bounds the time the database queries of a read may
+	// take, including reading their results. Reads taking longer fail with
+	// ErrQueryTimeout. Zero means no limit besides the request's deadline.
+	MaxQueryDuration time.Duration
+	// MonotonicCounters are the names of counter metrics whose resets, e.g.
+	// on restarts of the exporting process, are merged on read: values
+	// after a reset are offset by the last value before it, so the series
+	// read never decreases.
+	MonotonicCounters []string
+	// LabelRewrites rewrite the values of labels of the series read, e.g.
+	// to collapse per-pod suffixes. Queries still match the stored values.
+	LabelRewrites []LabelRewrite
+	// MergeRewrittenSeries merges the series read that have the same labels
+	// after the LabelRewrites, rather than returning them separately. It
+	// reads all series of a query before returning the first.
+	MergeRewrittenSeries bool
+	// Tenancy, if set, constrains every read to the series of the tenant of
+	// its request.
+	Tenancy *Tenancy
+	// IgnoreExtraColumns skips the columns of series rows after the label
+	// ids, times and values, e.g. columns added by a newer schema. Without
+	// it, such rows fail with ErrUnexpectedColumns.
+	IgnoreExtraColumns bool
+	// LabelQueryRetries is the number of times a failed query of the labels
+	// of the series read is retried, with a backoff starting at 100ms.
+	// Reads whose labels cannot be fetched fail rather than return series
+	// without labels.
+	LabelQueryRetries int
+	// InfiniteTimestampPolicy decides what happens to samples read at an
+	// infinite time. By default they are returned as they are.
+	InfiniteTimestampPolicy InfiniteTimestampPolicy
+	// CheckLabelNames looks up the names of the labels the matchers of a
+	// read require before querying any series. Reads requiring a label no
+	// series has return no series without querying the data tables, at the
+	// cost of an extra query for the other reads.
+	CheckLabelNames bool
+	// ContinuousAggregates are read instead of the data tables of their
+	// metrics by reads with a step and a range, or a lookback delta for
+	// selectors without a range, of at least their resolution, the coarsest
+	// such aggregate of a metric first.
+	ContinuousAggregates []ContinuousAggregate
+	// UnionMetricTables reads the series of queries matching several
+	// metrics with a single query, the UNION ALL of the selections of
+	// every metric table, rather than with a query per table.
+	UnionMetricTables bool
+}
+
+// NewPgxReaderWithCfg returns a new DBReader that reads from PostgreSQL using PGX,
+// caches metric table names using the supplied cacher and applies the supplied config.
+func NewPgxReaderWithCfg(c *pgxpool.Pool, cache MetricCache, cfg *ReaderCfg) *DBReader {
 	pi := &pgxQuerier{
 		conn: &pgxConnImpl{
-			conn: c,
+			conn:   c,
+			format: cfg.QueryFormat,
 		},
 		metricTableNames: cache,
-		labels:           clockcache.WithMax(labelsCacheSize),
+		labels:           clockcache.WithMax(cfg.LabelsCacheSize),
+		alignToStep:      cfg.AlignToStep,
+		allowUnbounded:   cfg.AllowUnbounded,
+		warnOnRetention:  cfg.WarnOnRetention,
+		valuePrecision:   cfg.ValuePrecision,
+		labelEnrichment:  cfg.LabelEnrichment,
+		// in milliseconds, like the query hints
+		interpolationMaxGap: int64(cfg.InterpolationMaxGap / time.Millisecond),
+		emptyLabels:         cfg.EmptyLabelsPolicy,
+		maxMetrics:          cfg.MaxMetricsPerQuery,
+		maxMetricsWarnOnly:  cfg.MaxMetricsWarnOnly,
+		metricNamePrefix:    cfg.MetricNamePrefix,
+		// reads are retried at most once
+		schemaChangeRetryDelay: schemaChangeRetryDelay(cfg.SchemaChangeRetryDelay),
+		waitForMigrationLock:   cfg.WaitForMigrations,
+		valueFilter:            cfg.ValueFilter,
+		staleGap:               int64(cfg.StaleGap / time.Millisecond),
+		propagateCancel:        !cfg.IgnoreCancel,
+		caseInsensitive:        newCaseInsensitiveLabels(cfg.CaseInsensitiveLabels),
+		failOnDecodeErrors:     !cfg.SkipDecodeErrors,
+		maxQueryDuration:       cfg.MaxQueryDuration,
+		labelRewrites:          cfg.LabelRewrites,
+		mergeRewrittenSeries:   cfg.MergeRewrittenSeries,
+		tenancy:                cfg.Tenancy,
+		ignoreExtraColumns:     cfg.IgnoreExtraColumns,
+		labelQueryRetries:      cfg.LabelQueryRetries,
+		infiniteTimestamps:     cfg.InfiniteTimestampPolicy,
+		checkLabelNames:        cfg.CheckLabelNames,
+		continuousAggregates:   newContinuousAggregates(cfg.ContinuousAggregates),
+		// a name and a value per cached label at most
+		labelStrings:      newStringInterner(2 * int(cfg.LabelsCacheSize)),
+		unionMetricTables: cfg.UnionMetricTables,
+	}
+	if len(cfg.MonotonicCounters) > 0 {
+		pi.monotonicCounters = make(map[string]bool, len(cfg.MonotonicCounters))
+		for _, name := range cfg.MonotonicCounters {
+			pi.monotonicCounters[name] = true
+		}
 	}
 
 	return &DBReader{
@@ -43,6 +218,12 @@ func NewPgxReaderWithMetricCache(c *pgxpool.Pool, cache MetricCache, labelsCache
 	}
 }
 
+// NewPgxReaderWithMetricCache returns a new DBReader that reads from PostgreSQL using PGX
+// and caches metric table names using the supplied cacher.
+func NewPgxReaderWithMetricCache(c *pgxpool.Pool, cache MetricCache, labelsCacheSize uint64) *DBReader {
+	return NewPgxReaderWithCfg(c, cache, &ReaderCfg{LabelsCacheSize: labelsCacheSize})
+}
+
 // NewPgxReader returns a new DBReader that reads that from PostgreSQL using PGX.
 func NewPgxReader(c *pgxpool.Pool, readHist prometheus.ObserverVec, labelsCacheSize uint64) *DBReader {
 	cache := &MetricNameCache{clockcache.WithMax(DefaultMetricCacheSize)}
@@ -53,29 +234,69 @@ type metricTimeRangeFilter struct {
 	metric    string
 	startTime string
 	endTime   string
+	// bucket, if set, rolls the samples up into a time grid.
+	bucket *timeBucket
+	// aggregate, if set, is read instead of the metric's data table.
+	aggregate *ContinuousAggregate
+}
+
+// dataTable returns the table the samples are read from.
+func (f metricTimeRangeFilter) dataTable() string {
+	if f.aggregate != nil {
+		return pgx.Identifier{f.aggregate.Schema, f.aggregate.View}.Sanitize()
+	}
+	return pgx.Identifier{dataSchema, f.metric}.Sanitize()
 }
 
 type pgxQuerier struct {
 	conn             pgxConn
 	metricTableNames MetricCache
 	// contains [int64]labels.Label
-	labels *clockcache.Cache
+	labels          *clockcache.Cache
+	alignToStep     bool
+	allowUnbounded  bool
+	warnOnRetention bool
+	valuePrecision  map[string]int
+	labelEnrichment *LabelEnrichment
+	// interpolationMaxGap is in milliseconds, zero disables interpolation
+	interpolationMaxGap int64
+	emptyLabels         EmptyLabelsPolicy
+	maxMetrics          int
+	maxMetricsWarnOnly  bool
+	metricNamePrefix    string
+	// schemaChangeRetryDelay is zero if reads are not retried
+	schemaChangeRetryDelay time.Duration
+	waitForMigrationLock   bool
+	valueFilter            *ValueFilter
+	// staleGap is in milliseconds
+	staleGap        int64
+	propagateCancel bool
+	caseInsensitive caseInsensitiveLabels
+	// failOnDecodeErrors is passed on to the iterators of the series read
+	failOnDecodeErrors bool
+	// maxQueryDuration is zero if queries are not bounded
+	maxQueryDuration time.Duration
+	// monotonicCounters are the metrics whose counter resets are merged
+	monotonicCounters map[string]bool
+	// mergeRewrittenSeries only applies if there are labelRewrites
+	labelRewrites        labelRewrites
+	mergeRewrittenSeries bool
+	// tenancy is nil if tenancy is disabled
+	tenancy            *Tenancy
+	ignoreExtraColumns bool
+	labelQueryRetries  int
+	infiniteTimestamps InfiniteTimestampPolicy
+	checkLabelNames    bool
+	// continuousAggregates is nil if no aggregate is configured
+	continuousAggregates continuousAggregates
+	// labelStrings interns the names and values of the labels fetched, it
+	// is nil if they are not interned
+	labelStrings      *stringInterner
+	unionMetricTables bool
 }
 
 var _ Querier = (*pgxQuerier)(nil)
 
-// HealthCheck implements the healtchecker interface
-func (q *pgxQuerier) HealthCheck() error {
-	rows, err := q.conn.Query(context.Background(), "SELECT")
-
-	if err != nil {
-		return err
-	}
-
-	rows.Close()
-	return nil
-}
-
 func (q *pgxQuerier) NumCachedLabels() int {
 	return q.labels.Len()
 }
@@ -85,22 +306,84 @@ func (q *pgxQuerier) LabelsCacheCapacity() int {
 }
 
 // entry point from our own version of the prometheus engine
-func (q *pgxQuerier) Select(mint int64, maxt int64, sortSeries bool, hints *storage.SelectHints, path []parser.Node, ms ...*labels.Matcher) (storage.SeriesSet, parser.Node, storage.Warnings, error) {
-	rows, topNode, err := q.getResultRows(mint, maxt, hints, path, ms)
+func (q *pgxQuerier) Select(ctx context.Context, mint int64, maxt int64, sortSeries bool, hints *storage.SelectHints, path []parser.Node, ms ...*labels.Matcher) (storage.SeriesSet, parser.Node, storage.Warnings, error) {
+	var bucket *timeBucket
+	if q.alignToStep {
+		bucket = newStepAlignment(hints, path)
+	}
+	rows, topNode, err := q.getResultRows(ctx, mint, maxt, hints, path, ms, bucket)
 
 	if err != nil {
 		return nil, nil, nil, err
 	}
 
-	ss, warn, err := buildSeriesSet(rows, sortSeries, q)
-	return ss, topNode, warn, err
+	ss, warn, err := buildSeriesSet(ctx, rows, sortSeries, hints, ms, q)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if q.warnOnRetention {
+		if w := q.checkRetention(ctx, maxt, ms); w != nil {
+			warn = append(warn, w)
+		}
+	}
+	return ss, topNode, warn, nil
+}
+
+// checkRetention returns a warning if the queried metric's data ending at
+// maxt may have been dropped by retention. Only queries of a single metric
+// are checked. The boundary is queried in the query context of ctx.
+func (q *pgxQuerier) checkRetention(ctx context.Context, maxt int64, ms []*labels.Matcher) error {
+	metric := ""
+	for _, m := range ms {
+		if m.Name != MetricNameLabelName {
+			continue
+		}
+		if m.Type != labels.MatchEqual || metric != "" {
+			return nil
+		}
+		metric = m.Value
+	}
+	if metric == "" {
+		return nil
+	}
+
+	queryCtx, cancel := q.queryContext(ctx)
+	defer cancel()
+
+	rows, err := q.conn.Query(queryCtx, getRetentionBoundarySQL, metric)
+	if err != nil {
+		log.Warn("msg", "error fetching retention period", "metric", metric, "err", timeoutError(queryCtx, err))
+		return nil
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil
+	}
+	var boundary time.Time
+	if err := rows.Scan(&boundary); err != nil {
+		log.Warn("msg", "error fetching retention period", "metric", metric, "err", timeoutError(queryCtx, err))
+		return nil
+	}
+
+	end := model.Time(maxt).Time()
+	if end.Before(boundary) {
+		return fmt.Errorf("query range ends at %s, before the retention boundary %s of metric %s: its data may have been dropped by retention",
+			end.UTC().Format(time.RFC3339), boundary.UTC().Format(time.RFC3339), metric)
+	}
+	return nil
 }
 
 // entry point from remote-storage queries
-func (q *pgxQuerier) Query(query *prompb.Query) ([]*prompb.TimeSeries, error) {
+func (q *pgxQuerier) Query(ctx context.Context, query *prompb.Query) ([]*prompb.TimeSeries, error) {
 	if query == nil {
 		return []*prompb.TimeSeries{}, nil
 	}
+	start := time.Now()
+	defer func() {
+		readDuration.Observe(time.Since(start).Seconds())
+	}()
 
 	matchers, err := FromLabelMatchers(query.Matchers)
 
@@ -108,7 +391,8 @@ func (q *pgxQuerier) Query(query *prompb.Query) ([]*prompb.TimeSeries, error) {
 		return nil, err
 	}
 
-	rows, _, err := q.getResultRows(query.StartTimestampMs, query.EndTimestampMs, nil, nil, matchers)
+	// Samples are downsampled in the database if the hints ask for it.
+	rows, _, err := q.getResultRows(ctx, query.StartTimestampMs, query.EndTimestampMs, nil, nil, matchers, newReadHintsBucket(query.Hints))
 
 	if err != nil {
 		return nil, err
@@ -120,10 +404,15 @@ func (q *pgxQuerier) Query(query *prompb.Query) ([]*prompb.TimeSeries, error) {
 		}
 	}()
 
+	labelFilter, err := q.caseInsensitive.filter(matchers)
+	if err != nil {
+		return nil, err
+	}
+
 	results := make([]*prompb.TimeSeries, 0, len(rows))
 
 	for _, r := range rows {
-		ts, err := buildTimeSeries(r, q)
+		ts, err := buildTimeSeries(ctx, r, q, labelFilter)
 
 		if err != nil {
 			return nil, err
@@ -131,64 +420,82 @@ func (q *pgxQuerier) Query(query *prompb.Query) ([]*prompb.TimeSeries, error) {
 
 		results = append(results, ts...)
 	}
+	readSeries.Add(float64(len(results)))
 
 	return results, nil
 }
 
-func (q *pgxQuerier) LabelNames() ([]string, error) {
-	rows, err := q.conn.Query(context.Background(), getLabelNamesSQL)
+// ScopeToTenant implements TenantScoper.
+func (q *pgxQuerier) ScopeToTenant(ctx context.Context, matchers []*labels.Matcher) ([]*labels.Matcher, error) {
+	return q.tenancy.scope(ctx, matchers)
+}
+
+func (q *pgxQuerier) LabelNames(ctx context.Context, matchers ...*labels.Matcher) ([]string, error) {
+	if len(matchers) == 0 {
+		return q.queryStrings(ctx, getLabelNamesSQL)
+	}
+
+	_, cases, values, err := buildSubQueries(matchers, q.caseInsensitive)
 	if err != nil {
 		return nil, err
 	}
+	sqlQuery := fmt.Sprintf(getLabelNamesByMatchersSQLFormat, strings.Join(cases, " AND "))
+	return q.queryStrings(ctx, sqlQuery, values...)
+}
 
-	defer rows.Close()
-
-	labelNames := make([]string, 0)
-
-	for rows.Next() {
-		var labelName string
-		if err := rows.Scan(&labelName); err != nil {
-			return nil, err
-		}
-
-		labelNames = append(labelNames, labelName)
+func (q *pgxQuerier) LabelValues(ctx context.Context, labelName string, matchers ...*labels.Matcher) ([]string, error) {
+	if len(matchers) == 0 {
+		return q.queryStrings(ctx, getLabelValuesSQL, labelName)
 	}
 
-	sort.Strings(labelNames)
-	return labelNames, nil
+	_, cases, values, err := buildSubQueries(matchers, q.caseInsensitive)
+	if err != nil {
+		return nil, err
+	}
+	values = append(values, labelName)
+	sqlQuery := fmt.Sprintf(getLabelValuesByMatchersSQLFormat, len(values), strings.Join(cases, " AND "))
+	return q.queryStrings(ctx, sqlQuery, values...)
 }
 
-func (q *pgxQuerier) LabelValues(labelName string) ([]string, error) {
-	rows, err := q.conn.Query(context.Background(), getLabelValuesSQL, labelName)
+// queryStrings returns the sorted results of a query of a single text column,
+// run in the query context of ctx.
+func (q *pgxQuerier) queryStrings(ctx context.Context, sqlQuery string, args ...interface{}) ([]string, error) {
+	queryCtx, cancel := q.queryContext(ctx)
+	defer cancel()
+
+	rows, err := q.conn.Query(queryCtx, sqlQuery, args...)
 	if err != nil {
-		return nil, err
+		return nil, timeoutError(queryCtx, err)
 	}
 
 	defer rows.Close()
 
-	labelValues := make([]string, 0)
+	results := make([]string, 0)
 
 	for rows.Next() {
-		var value string
-		if err := rows.Scan(&value); err != nil {
-			return nil, err
+		var result string
+		if err := rows.Scan(&result); err != nil {
+			return nil, timeoutError(queryCtx, err)
 		}
 
-		labelValues = append(labelValues, value)
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, timeoutError(queryCtx, err)
 	}
 
-	sort.Strings(labelValues)
-	return labelValues, nil
+	sort.Strings(results)
+	return results, nil
 }
 
 const GetLabelsSQL = "SELECT (labels_info($1::int[])).*"
 
 type labelQuerier interface {
-	getLabelsForIds(ids []int64) (lls labels.Labels, err error)
+	getLabelMapForIds(ctx context.Context, ids []int64) (map[int64]labels.Label, error)
 }
 
-func (q *pgxQuerier) getPrompbLabelsForIds(ids []int64) (lls []prompb.Label, err error) {
-	ll, err := q.getLabelsForIds(ids)
+func (q *pgxQuerier) getPrompbLabelsForIds(ctx context.Context, ids []int64) (lls []prompb.Label, err error) {
+	ll, err := q.getLabelsForIds(ctx, ids)
 	if err != nil {
 		return
 	}
@@ -199,36 +506,88 @@ func (q *pgxQuerier) getPrompbLabelsForIds(ids []int64) (lls []prompb.Label, err
 	return
 }
 
-func (q *pgxQuerier) getLabelsForIds(ids []int64) (lls labels.Labels, err error) {
-	keys := make([]interface{}, len(ids))
-	values := make([]interface{}, len(ids))
+func (q *pgxQuerier) getLabelsForIds(ctx context.Context, ids []int64) (lls labels.Labels, err error) {
+	_, values, err := q.lookupLabelsWithRetry(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	lls = make([]labels.Label, 0, len(values))
+	for i := range values {
+		lls = append(lls, values[i].(labels.Label))
+	}
+
+	return
+}
+
+// getLabelMapForIds returns the labels of the ids keyed by their id.
+func (q *pgxQuerier) getLabelMapForIds(ctx context.Context, ids []int64) (map[int64]labels.Label, error) {
+	keys, values, err := q.lookupLabelsWithRetry(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	labelMap := make(map[int64]labels.Label, len(values))
+	for i := range values {
+		labelMap[keys[i].(int64)] = values[i].(labels.Label)
+	}
+
+	return labelMap, nil
+}
+
+// labelQueryRetryBackoff is the delay before the first retry of a failed query
+// of labels, it doubles with every further retry.
+var labelQueryRetryBackoff = 100 * time.Millisecond
+
+// lookupLabelsWithRetry is lookupLabels, retried labelQueryRetries times if it
+// fails. All attempts run in the query context of ctx, so they end with the
+// request or at the maximum query duration.
+func (q *pgxQuerier) lookupLabelsWithRetry(ctx context.Context, ids []int64) (keys []interface{}, values []interface{}, err error) {
+	queryCtx, cancel := q.queryContext(ctx)
+	defer cancel()
+
+	if q.labelQueryRetries <= 0 {
+		keys, values, err = q.lookupLabels(queryCtx, ids)
+		return keys, values, timeoutError(queryCtx, err)
+	}
+	err = util.RetryWithBackoff("fetching labels", q.labelQueryRetries, labelQueryRetryBackoff, func() (err error) {
+		keys, values, err = q.lookupLabels(queryCtx, ids)
+		return err
+	})
+	return keys, values, timeoutError(queryCtx, err)
+}
+
+// lookupLabels returns the labels of the ids from the cache, fetching the
+// missing ones from the database. values[i] is the label of id keys[i].
+func (q *pgxQuerier) lookupLabels(ctx context.Context, ids []int64) (keys []interface{}, values []interface{}, err error) {
+	labelLookups.Inc()
+	keys = make([]interface{}, len(ids))
+	values = make([]interface{}, len(ids))
 	for i := range ids {
 		keys[i] = ids[i]
 	}
 	numHits := q.labels.GetValues(keys, values)
+	labelsCacheHits.Add(float64(numHits))
+	labelsCacheMisses.Add(float64(len(ids) - numHits))
 
 	if numHits < len(ids) {
 		var numFetches int
-		numFetches, err = q.fetchMissingLabels(keys[numHits:], ids[numHits:], values[numHits:])
+		numFetches, err = q.fetchMissingLabels(ctx, keys[numHits:], ids[numHits:], values[numHits:])
 		if err != nil {
-			return
+			return nil, nil, err
 		}
-		values = values[:numHits+numFetches]
+		keys, values = keys[:numHits+numFetches], values[:numHits+numFetches]
 	}
 
-	lls = make([]labels.Label, 0, len(values))
-	for i := range values {
-		lls = append(lls, values[i].(labels.Label))
-	}
-
-	return
+	return keys, values, nil
 }
 
-func (q *pgxQuerier) fetchMissingLabels(misses []interface{}, missedIds []int64, newLabels []interface{}) (numNewLabels int, err error) {
+func (q *pgxQuerier) fetchMissingLabels(ctx context.Context, misses []interface{}, missedIds []int64, newLabels []interface{}) (numNewLabels int, err error) {
 	for i := range misses {
 		missedIds[i] = misses[i].(int64)
 	}
-	rows, err := q.conn.Query(context.Background(), GetLabelsSQL, missedIds)
+	labelQueries.Inc()
+	rows, err := q.conn.Query(ctx, GetLabelsSQL, missedIds)
 	if err != nil {
 		return 0, err
 	}
@@ -257,7 +616,7 @@ func (q *pgxQuerier) fetchMissingLabels(misses []interface{}, missedIds []int64,
 		newLabels = newLabels[:len(keys)]
 		for i := range newLabels {
 			misses[i] = ids[i]
-			newLabels[i] = labels.Label{Name: keys[i], Value: vals[i]}
+			newLabels[i] = labels.Label{Name: q.labelStrings.intern(keys[i]), Value: q.labelStrings.intern(vals[i])}
 		}
 
 		numInserted := q.labels.InsertBatch(misses, newLabels)
@@ -268,9 +627,35 @@ func (q *pgxQuerier) fetchMissingLabels(misses []interface{}, missedIds []int64,
 	return numNewLabels, nil
 }
 
-func (q *pgxQuerier) getResultRows(startTimestamp int64, endTimestamp int64, hints *storage.SelectHints, path []parser.Node, matchers []*labels.Matcher) ([]pgx.Rows, parser.Node, error) {
+// getResultRows queries the samples of the series matching the matchers. If
+// bucket is set, the samples are rolled up into its time grid. The queries
+// are bounded by the maximum query duration until all rows are closed.
+func (q *pgxQuerier) getResultRows(ctx context.Context, startTimestamp int64, endTimestamp int64, hints *storage.SelectHints, path []parser.Node, matchers []*labels.Matcher, bucket *timeBucket) ([]pgx.Rows, parser.Node, error) {
+	queryCtx, cancel := q.queryContext(ctx)
+	rows, topNode, err := q.queryResultRows(queryCtx, startTimestamp, endTimestamp, hints, path, matchers, bucket)
+	if err != nil {
+		cancel()
+		return nil, nil, timeoutError(queryCtx, err)
+	}
+	return queryRows(queryCtx, rows, cancel), topNode, nil
+}
+
+func (q *pgxQuerier) queryResultRows(ctx context.Context, startTimestamp int64, endTimestamp int64, hints *storage.SelectHints, path []parser.Node, matchers []*labels.Matcher, bucket *timeBucket) ([]pgx.Rows, parser.Node, error) {
+	if isUnbounded(matchers) {
+		if !q.allowUnbounded {
+			return nil, nil, ErrUnboundedQuery
+		}
+		if len(matchers) == 0 {
+			matchers = []*labels.Matcher{matchAll}
+		}
+	}
+	// only after the check, the tenant does not bound a query
+	matchers, err := q.tenancy.scope(ctx, matchers)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	metric, cases, values, err := buildSubQueries(matchers)
+	metric, cases, values, err := buildSubQueries(matchers, q.caseInsensitive)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -279,14 +664,34 @@ func (q *pgxQuerier) getResultRows(startTimestamp int64, endTimestamp int64, hin
 		metric:    metric,
 		startTime: toRFC3339Nano(startTimestamp),
 		endTime:   toRFC3339Nano(endTimestamp),
+		bucket:    bucket,
 	}
 
+	if err = q.waitForMigrations(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	if q.checkLabelNames {
+		missing, err := q.hasMissingLabelName(ctx, matchers)
+		if err != nil || missing {
+			return nil, nil, err
+		}
+	}
+
+	step, window := queryStep(hints, bucket), queryWindow(hints, bucket)
 	if metric != "" {
-		return q.querySingleMetric(metric, filter, cases, values, hints, path)
+		filter.aggregate = q.continuousAggregates.forStep(metric, step, window)
+		if len(matchers) == 1 {
+			// Selecting by the metric name alone is the most common query.
+			// Its series are read from the metric's views without looking
+			// up the label id of the name.
+			cases, values = []string{metricSeriesClause}, nil
+		}
+		return q.querySingleMetric(ctx, metric, filter, cases, values, hints, path)
 	}
 
 	sqlQuery := buildMetricNameSeriesIDQuery(cases)
-	rows, err := q.conn.Query(context.Background(), sqlQuery, values...)
+	rows, err := q.queryWithRetry(ctx, sqlQuery, values...)
 
 	if err != nil {
 		return nil, nil, err
@@ -299,7 +704,12 @@ func (q *pgxQuerier) getResultRows(startTimestamp int64, endTimestamp int64, hin
 		return nil, nil, err
 	}
 
+	if err = q.checkMetricCount(len(metrics)); err != nil {
+		return nil, nil, err
+	}
+
 	results := make([]pgx.Rows, 0, len(metrics))
+	var unionQueries []string
 
 	for i, metric := range metrics {
 		tableName, err := q.getMetricTableName(metric)
@@ -312,8 +722,13 @@ func (q *pgxQuerier) getResultRows(startTimestamp int64, endTimestamp int64, hin
 			return nil, nil, err
 		}
 		filter.metric = tableName
+		filter.aggregate = q.continuousAggregates.forStep(metric, step, window)
 		sqlQuery = buildTimeseriesBySeriesIDQuery(filter, series[i])
-		rows, err = q.conn.Query(context.Background(), sqlQuery)
+		if q.unionMetricTables {
+			unionQueries = append(unionQueries, sqlQuery)
+			continue
+		}
+		rows, err = q.queryWithRetry(ctx, sqlQuery)
 
 		if err != nil {
 			return nil, nil, err
@@ -322,10 +737,50 @@ func (q *pgxQuerier) getResultRows(startTimestamp int64, endTimestamp int64, hin
 		results = append(results, rows)
 	}
 
+	if len(unionQueries) > 0 {
+		rows, err = q.queryWithRetry(ctx, buildUnionQuery(unionQueries))
+		if err != nil {
+			return nil, nil, err
+		}
+		results = append(results, rows)
+	}
+
 	return results, nil, nil
 }
 
-func (q *pgxQuerier) querySingleMetric(metric string, filter metricTimeRangeFilter, cases []string, values []interface{}, hints *storage.SelectHints, path []parser.Node) ([]pgx.Rows, parser.Node, error) {
+// checkMetricCount enforces the maximum number of metrics matched by a query.
+func (q *pgxQuerier) checkMetricCount(numMetrics int) error {
+	if q.maxMetrics <= 0 || numMetrics <= q.maxMetrics {
+		return nil
+	}
+	if q.maxMetricsWarnOnly {
+		log.Warn("msg", "query matches more metrics than the maximum", "metrics", numMetrics, "max", q.maxMetrics)
+		return nil
+	}
+	return fmt.Errorf("%w: %d metrics, the maximum is %d", ErrTooManyMetrics, numMetrics, q.maxMetrics)
+}
+
+// isUnbounded returns true if none of the matchers narrows down the series
+// selected, e.g. an empty matcher set or a lone `__name__=""` matcher.
+// Matchers of any value of a label, like `__name__!=""` or `__name__=~".+"`,
+// do not narrow it down either.
+func isUnbounded(matchers []*labels.Matcher) bool {
+	for _, m := range matchers {
+		switch {
+		case m.Matches("") && (m.Value == "" || m.Value == ".*"):
+			// every series
+		case (m.Type == labels.MatchNotEqual || m.Type == labels.MatchNotRegexp) && m.Value == "":
+			// every series with the label
+		case m.Type == labels.MatchRegexp && m.Value == ".+":
+			// every series with the label
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func (q *pgxQuerier) querySingleMetric(ctx context.Context, metric string, filter metricTimeRangeFilter, cases []string, values []interface{}, hints *storage.SelectHints, path []parser.Node) ([]pgx.Rows, parser.Node, error) {
 	tableName, err := q.getMetricTableName(metric)
 	if err != nil {
 		// If the metric table is missing, there are no results for this query.
@@ -341,7 +796,7 @@ func (q *pgxQuerier) querySingleMetric(metric string, filter metricTimeRangeFilt
 	if err != nil {
 		return nil, nil, err
 	}
-	rows, err := q.conn.Query(context.Background(), sqlQuery, values...)
+	rows, err := q.queryWithRetry(ctx, sqlQuery, values...)
 
 	if err != nil {
 		// If we are getting undefined table error, it means the query
@@ -4,22 +4,38 @@
 package pgmodel
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"reflect"
+	"runtime"
 	"sort"
 	"strings"
 	"sync"
 	"testing"
 	"time"
+	"unsafe"
 
 	"github.com/jackc/pgconn"
+	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgproto3/v2"
+	"github.com/jackc/pgtype"
 	"github.com/jackc/pgx/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/timestamp"
+	"github.com/prometheus/prometheus/pkg/value"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/storage"
 	"github.com/timescale/timescale-prometheus/pkg/clockcache"
 	"github.com/timescale/timescale-prometheus/pkg/prompb"
+	"github.com/timescale/timescale-prometheus/pkg/promql"
 )
 
 // rowResults represents a collection of a multi-column row result
@@ -32,20 +48,29 @@ type mockPGXConn struct {
 	ExecSQLs          []string
 	ExecArgs          [][]interface{}
 	ExecErr           error
+	PingErr           error
 	QuerySQLs         []string
 	QueryArgs         [][]interface{}
+	QueryCtxErrs      []error               // Context error at the time of every query.
+	QueryDelay        map[int]time.Duration // Mapping query call to its duration, ended early by the context.
 	QueryResults      []rowResults
 	QueryResultsIndex int
 	QueryNoRows       bool
 	QueryErr          map[int]error // Mapping query call to error response.
 	CopyFromTableName []string
+	InsertSQLs        []string
+	InsertArgs        [][]interface{}
 	Times             []time.Time
 	Vals              []float64
+	NullVals          []int // Indices into Vals of the values inserted as NULL.
 	Series            []int64
 	CopyFromResult    int64
 	CopyFromError     error
+	CopyFromErrors    []error // Sequence of insert errors, takes precedence over CopyFromError.
+	InsertAffected    []int64 // Sequence of rows affected by successful inserts, zero once exhausted.
 	CopyFromRowsRows  [][]interface{}
 	Batch             []*mockBatch
+	UniqueSamples     map[string]bool // Emulates the unique index on (series_id, time) if set, keyed by both.
 }
 
 func (m *mockPGXConn) Close() {
@@ -68,16 +93,61 @@ func (m *mockPGXConn) Exec(ctx context.Context, sql string, arguments ...interfa
 		}
 		tableName := sql[len("INSERT INTO "):end]
 		m.CopyFromTableName = append(m.CopyFromTableName, tableName)
+		m.InsertSQLs = append(m.InsertSQLs, sql)
+		m.InsertArgs = append(m.InsertArgs, arguments)
 
+		err := m.CopyFromError
+		if len(m.CopyFromErrors) > 0 {
+			err = m.CopyFromErrors[0]
+			m.CopyFromErrors = m.CopyFromErrors[1:]
+		}
+		// Like the database, a failed insert stores no rows.
+		if err != nil {
+			return pgconn.CommandTag([]byte{}), err
+		}
+
+		// parameters sent as text come after the option selecting the
+		// simple protocol
+		if _, ok := arguments[0].(pgx.QuerySimpleProtocol); ok {
+			arguments = arguments[1:]
+		}
 		times := arguments[0].([]time.Time)
-		vals := arguments[1].([]float64)
 		series := arguments[2].([]int64)
+		switch vals := arguments[1].(type) {
+		case []float64:
+			m.Vals = append(m.Vals, vals...)
+		case pgtype.Float8Array:
+			for _, v := range vals.Elements {
+				if v.Status == pgtype.Null {
+					m.NullVals = append(m.NullVals, len(m.Vals))
+				}
+				m.Vals = append(m.Vals, v.Float)
+			}
+		default:
+			panic(fmt.Sprintf("invalid values: %v", vals))
+		}
 
 		m.Times = append(m.Times, times...)
-		m.Vals = append(m.Vals, vals...)
 		m.Series = append(m.Series, series...)
 
-		return pgconn.CommandTag([]byte{}), m.CopyFromError
+		if m.UniqueSamples != nil {
+			affected := 0
+			for i := range times {
+				key := fmt.Sprintf("%d/%d", series[i], times[i].UnixNano())
+				if !m.UniqueSamples[key] {
+					m.UniqueSamples[key] = true
+					affected++
+				}
+			}
+			return pgconn.CommandTag(fmt.Sprintf("INSERT 0 %d", affected)), nil
+		}
+
+		if len(m.InsertAffected) > 0 {
+			affected := m.InsertAffected[0]
+			m.InsertAffected = m.InsertAffected[1:]
+			return pgconn.CommandTag(fmt.Sprintf("INSERT 0 %d", affected)), nil
+		}
+		return pgconn.CommandTag([]byte{}), nil
 	} else {
 		m.ExecSQLs = append(m.ExecSQLs, sql)
 		m.ExecArgs = append(m.ExecArgs, arguments)
@@ -93,6 +163,14 @@ func (m *mockPGXConn) Query(ctx context.Context, sql string, args ...interface{}
 	}()
 	m.QuerySQLs = append(m.QuerySQLs, sql)
 	m.QueryArgs = append(m.QueryArgs, args)
+	m.QueryCtxErrs = append(m.QueryCtxErrs, ctx.Err())
+	if delay, ok := m.QueryDelay[m.QueryResultsIndex]; ok {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
 	if len(m.QueryResults) <= m.QueryResultsIndex {
 		return &mockRows{results: nil, noNext: m.QueryNoRows}, m.QueryErr[m.QueryResultsIndex]
 
@@ -109,6 +187,10 @@ func (m *mockPGXConn) CopyFromRows(rows [][]interface{}) pgx.CopyFromSource {
 	return pgx.CopyFromRows(rows)
 }
 
+func (m *mockPGXConn) Ping(ctx context.Context) error {
+	return m.PingErr
+}
+
 func (m *mockPGXConn) NewBatch() pgxBatch {
 	return &mockBatch{}
 }
@@ -217,8 +299,17 @@ func (m *mockRows) CommandTag() pgconn.CommandTag {
 	panic("not implemented")
 }
 
+// FieldDescriptions returns a description named after the position of every
+// column of the current row.
 func (m *mockRows) FieldDescriptions() []pgproto3.FieldDescription {
-	panic("not implemented")
+	if m.idx >= len(m.results) {
+		return nil
+	}
+	fds := make([]pgproto3.FieldDescription, len(m.results[m.idx]))
+	for i := range fds {
+		fds[i].Name = []byte(fmt.Sprintf("column%d", i+1))
+	}
+	return fds
 }
 
 // Next prepares the next row for reading. It returns true if there is another
@@ -242,6 +333,18 @@ func (m *mockRows) Scan(dest ...interface{}) error {
 	}
 
 	for i := range dest {
+		if dest[i] == nil {
+			continue
+		}
+		if d, ok := dest[i].(*pgtype.Float8Array); ok {
+			if s, ok := m.results[m.idx][i].([]float64); ok {
+				*d = pgtype.Float8Array{Status: pgtype.Present}
+				for _, v := range s {
+					d.Elements = append(d.Elements, pgtype.Float8{Float: v, Status: pgtype.Present})
+				}
+				continue
+			}
+		}
 		if scanner, ok := dest[i].(sql.Scanner); ok {
 			err := scanner.Scan(m.results[m.idx][i])
 			if err != nil {
@@ -279,6 +382,10 @@ func (m *mockRows) Scan(dest ...interface{}) error {
 				*d = s
 			}
 		case float64:
+			if d, ok := dest[i].(*float64); ok {
+				*d = s
+				continue
+			}
 			if _, ok := dest[i].(float64); !ok {
 				return fmt.Errorf("wrong value type float64")
 			}
@@ -322,6 +429,12 @@ func (m *mockRows) Scan(dest ...interface{}) error {
 			dv := reflect.ValueOf(dest[i])
 			dvp := reflect.Indirect(dv)
 			dvp.SetString(m.results[m.idx][i].(string))
+		case bool:
+			if d, ok := dest[i].(*bool); ok {
+				*d = s
+				continue
+			}
+			return fmt.Errorf("wrong value type bool")
 		}
 	}
 
@@ -380,6 +493,8 @@ func TestPGXInserterInsertSeries(t *testing.T) {
 	testCases := []struct {
 		name         string
 		series       []*labels.Labels
+		concurrency  int
+		numBatches   int
 		queryResults []rowResults
 		queryErr     map[int]error
 	}{
@@ -407,6 +522,34 @@ func TestPGXInserterInsertSeries(t *testing.T) {
 			queryResults: createSeriesResults(2),
 			queryErr:     map[int]error{0: fmt.Errorf("some query error")},
 		},
+		{
+			name:         "Concurrent batches",
+			series:       createSeries(5),
+			concurrency:  2,
+			numBatches:   2,
+			queryResults: createSeriesResults(3),
+		},
+		{
+			name:         "Concurrent batches, duplicate series",
+			series:       append(createSeries(4), createSeries(3)...),
+			concurrency:  3,
+			numBatches:   3,
+			queryResults: createSeriesResults(2),
+		},
+		{
+			name:         "Concurrency above series count",
+			series:       createSeries(2),
+			concurrency:  10,
+			numBatches:   2,
+			queryResults: createSeriesResults(1),
+		},
+		{
+			name:         "Concurrent batches, query err",
+			series:       createSeries(4),
+			concurrency:  2,
+			queryResults: createSeriesResults(2),
+			queryErr:     map[int]error{1: fmt.Errorf("some query error")},
+		},
 	}
 
 	for _, c := range testCases {
@@ -416,7 +559,7 @@ func TestPGXInserterInsertSeries(t *testing.T) {
 				QueryResults: c.queryResults,
 			}
 
-			inserter := insertHandler{conn: mock, seriesCache: make(map[string]SeriesID)}
+			inserter := insertHandler{conn: mock, seriesCache: newSeriesIDCache(0), seriesConcurrency: c.concurrency}
 
 			lsi := make([]samplesInfo, 0)
 			for _, ser := range c.series {
@@ -432,10 +575,23 @@ func TestPGXInserterInsertSeries(t *testing.T) {
 				switch {
 				case len(c.queryErr) > 0:
 					for _, qErr := range c.queryErr {
-						if err != qErr {
+						if !errors.Is(err, qErr) {
 							t.Errorf("unexpected query error:\ngot\n%s\nwanted\n%s", err, qErr)
 						}
 					}
+					var seriesErr *SeriesInsertError
+					if !errors.As(err, &seriesErr) || len(seriesErr.Series) == 0 {
+						t.Errorf("expected a series insert error with the affected series, got %#v", err)
+					}
+					// A failed insert must not leave partial results behind.
+					if inserter.seriesCache.NumElements() != 0 {
+						t.Errorf("series cache modified on error: %d series cached", inserter.seriesCache.NumElements())
+					}
+					for _, si := range lsi {
+						if si.seriesID >= 0 {
+							t.Errorf("series id set on error: %d", si.seriesID)
+						}
+					}
 					return
 				default:
 					t.Errorf("unexpected error: %v", err)
@@ -451,10 +607,262 @@ func TestPGXInserterInsertSeries(t *testing.T) {
 			if c.queryErr != nil {
 				t.Errorf("expected query error:\ngot\n%v\nwanted\n%v", err, c.queryErr)
 			}
+
+			if c.numBatches > 0 {
+				checkSeriesBatches(t, mock.Batch, lsi, c.numBatches)
+			}
 		})
 	}
 }
 
+func TestPGXInserterInsertSeriesDuplicates(t *testing.T) {
+	series := createSeries(3)
+	// ids are handed out in the sorted order of the unique series
+	order := []int{2, 0, 1, 0, 2, 2}
+	expected := []SeriesID{3, 1, 2, 1, 3, 3}
+
+	mock := &mockPGXConn{QueryResults: createSeriesResults(3)}
+	inserter := insertHandler{conn: mock, seriesCache: newSeriesIDCache(0)}
+
+	lsi := make([]samplesInfo, 0, len(order))
+	for _, i := range order {
+		ls, err := LabelsFromSlice(*series[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		lsi = append(lsi, samplesInfo{labels: ls, seriesID: -1})
+	}
+
+	if _, err := inserter.setSeriesIds(lsi); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, si := range lsi {
+		if si.seriesID != expected[i] {
+			t.Errorf("unexpected id for element %d: got %d, wanted %d", i, si.seriesID, expected[i])
+		}
+	}
+	if inserter.seriesCache.NumElements() != len(series) {
+		t.Errorf("unexpected number of cached series: got %d, wanted %d", inserter.seriesCache.NumElements(), len(series))
+	}
+
+	// every series is queried once, in sorted order
+	queried := make([]string, 0, len(series))
+	for _, b := range mock.Batch {
+		for _, item := range b.items {
+			if item.query == getSeriesIDForLabelSQL {
+				queried = append(queried, item.arguments[0].(string))
+			}
+		}
+	}
+	if !reflect.DeepEqual(queried, []string{"metric_1", "metric_2", "metric_3"}) {
+		t.Errorf("unexpected series queried: got %v", queried)
+	}
+}
+
+func TestPGXInserterInsertSeriesCached(t *testing.T) {
+	series := createSeries(3)
+	mock := &mockPGXConn{QueryResults: createSeriesResults(3)}
+	inserter := insertHandler{conn: mock, seriesCache: newSeriesIDCache(0)}
+
+	newSamplesInfos := func() []samplesInfo {
+		lsi := make([]samplesInfo, 0, len(series))
+		for _, s := range series {
+			ls, err := LabelsFromSlice(*s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lsi = append(lsi, samplesInfo{labels: ls, seriesID: -1})
+		}
+		return lsi
+	}
+
+	first := newSamplesInfos()
+	if _, err := inserter.setSeriesIds(first); err != nil {
+		t.Fatal(err)
+	}
+	numBatches := len(mock.Batch)
+
+	// series already known are resolved from the cache without a query
+	second := newSamplesInfos()
+	if _, err := inserter.setSeriesIds(second); err != nil {
+		t.Fatal(err)
+	}
+	if len(mock.Batch) != numBatches {
+		t.Errorf("known series were queried again: got %d batches, wanted %d", len(mock.Batch), numBatches)
+	}
+	for i := range second {
+		if second[i].seriesID != first[i].seriesID {
+			t.Errorf("unexpected id for series %d: got %d, wanted %d", i, second[i].seriesID, first[i].seriesID)
+		}
+	}
+}
+
+func TestSeriesIDCache(t *testing.T) {
+	series := createSeries(3)
+	lsi := make([]samplesInfo, 0, len(series))
+	for _, s := range series {
+		ls, err := LabelsFromSlice(*s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lsi = append(lsi, samplesInfo{labels: ls, seriesID: -1})
+	}
+	cache := newSeriesIDCache(2)
+	hits, misses := testutil.ToFloat64(seriesCacheHits), testutil.ToFloat64(seriesCacheMisses)
+
+	if _, err := cache.GetSeries(*lsi[0].labels); err != ErrEntryNotFound {
+		t.Fatalf("unexpected error: got %v, wanted %v", err, ErrEntryNotFound)
+	}
+	for i, si := range lsi {
+		if err := cache.SetSeries(*si.labels, SeriesID(i+1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the cache is bounded, one of the series was evicted
+	if cache.NumElements() != 2 || testutil.ToFloat64(seriesCacheElements) != 2 {
+		t.Fatalf("unexpected number of cached series: got %d, wanted 2", cache.NumElements())
+	}
+	found := 0
+	for i, si := range lsi {
+		id, err := cache.GetSeries(*si.labels)
+		if err == ErrEntryNotFound {
+			continue
+		}
+		found++
+		if id != SeriesID(i+1) {
+			t.Errorf("unexpected id for series %d: got %d, wanted %d", i, id, i+1)
+		}
+	}
+	if found != 2 {
+		t.Errorf("unexpected number of series found: got %d, wanted 2", found)
+	}
+	if delta := testutil.ToFloat64(seriesCacheHits) - hits; delta != 2 {
+		t.Errorf("unexpected number of cache hits: got %v, wanted 2", delta)
+	}
+	if delta := testutil.ToFloat64(seriesCacheMisses) - misses; delta != 2 {
+		t.Errorf("unexpected number of cache misses: got %v, wanted 2", delta)
+	}
+
+	cache.invalidate(lsi)
+	if cache.NumElements() != 0 {
+		t.Errorf("series cached after invalidation: %d", cache.NumElements())
+	}
+}
+
+func TestPGXInserterSeriesCacheInvalidatedOnError(t *testing.T) {
+	ls, err := LabelsFromSlice(labels.Labels{{Name: MetricNameLabelName, Value: "foo"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := newSeriesIDCache(0)
+	if err = cache.SetSeries(*ls, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	insertErr := fmt.Errorf("insert failed")
+	mock := &mockPGXConn{CopyFromError: insertErr}
+	in := make(chan copyRequest)
+	defer close(in)
+	go runInserter(mock, in, &copierOptions{
+		stmt:        insertStatement{schema: dataSchema, columns: "time, value, series_id", onConflict: "ON CONFLICT DO NOTHING"},
+		emptyInsert: IgnoreEmptyInserts,
+		seriesCache: cache,
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	errChan := make(chan error, 1)
+	pending := &pendingBuffer{
+		needsResponse: []insertDataTask{{finished: &wg, errChan: errChan}},
+		batch:         NewSampleInfoIterator(),
+	}
+	pending.batch.sampleInfos = []samplesInfo{{labels: ls, seriesID: 1, samples: []prompb.Sample{{Timestamp: 1, Value: 1}}}}
+	in <- copyRequest{pending, "metricTableName_0"}
+	wg.Wait()
+
+	if err = <-errChan; err != insertErr {
+		t.Errorf("unexpected error: got %v, wanted %v", err, insertErr)
+	}
+	// the series is looked up again on its next write
+	if _, err = cache.GetSeries(*ls); err != ErrEntryNotFound {
+		t.Errorf("series still cached after a failed insert: %v", err)
+	}
+}
+
+func TestPGXInserterInsertSeriesError(t *testing.T) {
+	series := createSeries(3)
+	// only the first two series get a result, scanning the third fails
+	mock := &mockPGXConn{QueryResults: createSeriesResults(2)}
+	inserter := insertHandler{conn: mock, seriesCache: newSeriesIDCache(0)}
+
+	lsi := make([]samplesInfo, 0, len(series))
+	for _, s := range series {
+		ls, err := LabelsFromSlice(*s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lsi = append(lsi, samplesInfo{labels: ls, seriesID: -1})
+	}
+
+	_, err := inserter.setSeriesIds(lsi)
+	var seriesErr *SeriesInsertError
+	if !errors.As(err, &seriesErr) {
+		t.Fatalf("expected a series insert error, got %v", err)
+	}
+	if seriesErr.Batch != 0 {
+		t.Errorf("unexpected batch: got %d, wanted 0", seriesErr.Batch)
+	}
+	if seriesErr.SQL != getSeriesIDForLabelSQL {
+		t.Errorf("unexpected SQL: got %q, wanted %q", seriesErr.SQL, getSeriesIDForLabelSQL)
+	}
+	if len(seriesErr.Series) != 1 || seriesErr.Series[0].metricName != "metric_3" {
+		t.Errorf("unexpected series: got %v", seriesErr.Series)
+	}
+	if seriesErr.Err == nil {
+		t.Error("missing underlying error")
+	}
+}
+
+// checkSeriesBatches checks that the unique series are split across the
+// expected number of batches, each holding a sorted run of series.
+func checkSeriesBatches(t *testing.T, batches []*mockBatch, lsi []samplesInfo, numBatches int) {
+	if len(batches) != numBatches {
+		t.Fatalf("unexpected number of batches: got %d, wanted %d", len(batches), numBatches)
+	}
+
+	unique := make(map[string]bool)
+	for _, si := range lsi {
+		unique[si.labels.String()] = true
+	}
+
+	got := make([][]string, 0, len(batches))
+	for _, b := range batches {
+		names := make([]string, 0, len(b.items)/3)
+		for _, item := range b.items {
+			if item.query == getSeriesIDForLabelSQL {
+				names = append(names, item.arguments[0].(string))
+			}
+		}
+		if !sort.StringsAreSorted(names) {
+			t.Errorf("series in batch are not sorted: %v", names)
+		}
+		got = append(got, names)
+	}
+	sort.Slice(got, func(i, j int) bool { return got[i][0] < got[j][0] })
+
+	all := make([]string, 0, len(unique))
+	for _, names := range got {
+		all = append(all, names...)
+	}
+	if len(all) != len(unique) {
+		t.Errorf("unexpected number of series inserted: got %d, wanted %d", len(all), len(unique))
+	}
+	if !sort.StringsAreSorted(all) {
+		t.Errorf("batches do not hold consecutive series: %v", got)
+	}
+}
+
 func createRows(x int) map[string][]samplesInfo {
 	return createRowsByMetric(x, 1)
 }
@@ -475,862 +883,5094 @@ func createRowsByMetric(x int, metricCount int) map[string][]samplesInfo {
 	for i < x {
 		metricIndex := i % metricCount
 
-		ret[metrics[metricIndex]] = append(ret[metrics[metricIndex]], samplesInfo{samples: []prompb.Sample{{}}})
+		lset, _, err := labelProtosToLabels([]prompb.Label{
+			{Name: MetricNameLabelName, Value: metrics[metricIndex]},
+			{Name: "row", Value: fmt.Sprint(i)},
+		})
+		if err != nil {
+			panic(err)
+		}
+		ret[metrics[metricIndex]] = append(ret[metrics[metricIndex]], samplesInfo{labels: lset, samples: []prompb.Sample{{}}})
 		i++
 	}
 	return ret
 }
 
-func TestPGXInserterInsertData(t *testing.T) {
+func TestLabelProtosToLabelsOrder(t *testing.T) {
+	canonical := []prompb.Label{
+		{Name: MetricNameLabelName, Value: "order_test"},
+		{Name: "instance", Value: "localhost:9090"},
+		{Name: "job", Value: "prometheus"},
+		{Name: "zone", Value: "eu-1"},
+	}
+	orders := [][]int{
+		{3, 2, 1, 0},
+		{2, 0, 3, 1},
+		{1, 3, 0, 2},
+		{0, 1, 2, 3},
+	}
+
+	var first *Labels
+	for _, order := range orders {
+		shuffled := make([]prompb.Label, len(order))
+		for i, idx := range order {
+			shuffled[i] = canonical[idx]
+		}
+		str, err := getStr(append([]prompb.Label(nil), shuffled...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		lset, metric, err := labelProtosToLabels(shuffled)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if metric != "order_test" {
+			t.Errorf("unexpected metric name for order %v: got %q", order, metric)
+		}
+		if str != lset.str {
+			t.Errorf("unexpected canonical string for order %v", order)
+		}
+		if first == nil {
+			first = lset
+			for i, l := range canonical {
+				if lset.names[i] != l.Name || lset.values[i] != l.Value {
+					t.Fatalf("labels not in canonical order: got %v=%v, wanted %v", lset.names, lset.values, canonical)
+				}
+			}
+			continue
+		}
+		if lset != first || lset.str != first.str {
+			t.Errorf("labels in order %v differ from the first order", order)
+		}
+	}
+}
+
+func TestSetRole(t *testing.T) {
 	testCases := []struct {
-		name           string
-		rows           map[string][]samplesInfo
-		queryNoRows    bool
-		queryErr       map[int]error
-		copyFromResult int64
-		copyFromErr    error
-		metricsGetErr  error
-		metricsSetErr  error
+		name        string
+		role        string
+		execErr     error
+		expectedSQL string
 	}{
 		{
-			name: "Zero data",
-		},
-		{
-			name: "One data",
-			rows: createRows(1),
-		},
-		{
-			name: "Two data",
-			rows: createRows(2),
+			name:        "role",
+			role:        "prom_writer",
+			expectedSQL: `SET ROLE "prom_writer"`,
 		},
 		{
-			name:     "Create table error",
-			rows:     createRows(5),
-			queryErr: map[int]error{0: fmt.Errorf("create table error")},
+			name:        "quoted role",
+			role:        `prom "reader"`,
+			expectedSQL: `SET ROLE "prom ""reader"""`,
 		},
 		{
-			name:        "Copy from error",
-			rows:        createRows(5),
-			copyFromErr: fmt.Errorf("some error"),
+			name:        "missing role",
+			role:        "prom_writer",
+			execErr:     &pgconn.PgError{Code: pgerrcode.InvalidParameterValue},
+			expectedSQL: `SET ROLE "prom_writer"`,
 		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			mock := &mockPGXConn{ExecErr: c.execErr}
+			err := setRole(context.Background(), mock, c.role)
+			if err != c.execErr {
+				t.Errorf("unexpected error: got %v, wanted %v", err, c.execErr)
+			}
+			if !reflect.DeepEqual(mock.ExecSQLs, []string{c.expectedSQL}) {
+				t.Errorf("unexpected statements: got %v, wanted %v", mock.ExecSQLs, []string{c.expectedSQL})
+			}
+		})
+	}
+}
+
+func TestSetCompressionPolicy(t *testing.T) {
+	table := `"prom_data"."metricTableName_0"`
+	enable := fmt.Sprintf(enableCompressionSQL, table)
+	testCases := []struct {
+		name          string
+		compressAfter time.Duration
+		enabled       bool
+		noTable       bool
+		expectedErr   error
+		expectedSQLs  []string
+		expectedArgs  [][]interface{}
+	}{
 		{
-			name:           "Not all data inserted",
-			rows:           createRows(5),
-			copyFromResult: 4,
+			name:          "enable compression",
+			compressAfter: time.Hour,
+			expectedSQLs:  []string{enable, removeCompressionPolicySQL, addCompressionPolicySQL},
+			expectedArgs:  [][]interface{}{nil, {table}, {table, time.Hour}},
 		},
 		{
-			name:        "Can't find/create table in DB",
-			rows:        createRows(5),
-			queryNoRows: true,
+			name:          "compression already enabled",
+			compressAfter: 24 * time.Hour,
+			enabled:       true,
+			expectedSQLs:  []string{removeCompressionPolicySQL, addCompressionPolicySQL},
+			expectedArgs:  [][]interface{}{{table}, {table, 24 * time.Hour}},
 		},
 		{
-			name:          "Metrics get error",
-			rows:          createRows(1),
-			metricsGetErr: fmt.Errorf("some metrics error"),
+			name:         "remove policy",
+			enabled:      true,
+			expectedSQLs: []string{removeCompressionPolicySQL},
+			expectedArgs: [][]interface{}{{table}},
 		},
 		{
-			name:          "Metrics set error",
-			rows:          createRows(1),
-			metricsSetErr: fmt.Errorf("some metrics error"),
+			name:          "missing metric",
+			compressAfter: time.Hour,
+			noTable:       true,
+			expectedErr:   errMissingTableName,
 		},
 	}
-	for _, co := range testCases {
-		c := co
+	for _, c := range testCases {
 		t.Run(c.name, func(t *testing.T) {
 			mock := &mockPGXConn{
-				QueryNoRows:    c.queryNoRows,
-				QueryErr:       c.queryErr,
-				CopyFromResult: c.copyFromResult,
-				CopyFromError:  c.copyFromErr,
+				QueryResults: []rowResults{
+					{{"metricTableName_0"}},
+					{{c.enabled}},
+				},
+				QueryErr: map[int]error{},
 			}
-
-			//The database will be queried for metricNames
-			results := make([]rowResults, 0, len(c.rows))
-			for metricName := range c.rows {
-				//create metric name
-				results = append(results, rowResults{{metricName, true}})
-				//send batch
-				results = append(results, rowResults{{}})
+			if c.noTable {
+				mock.QueryResults = []rowResults{{}}
 			}
-			mock.QueryResults = results
 
-			metricCache := map[string]string{"metric_1": "metricTableName_1"}
-			mockMetrics := &mockMetricCache{
-				metricCache:  metricCache,
-				getMetricErr: c.metricsGetErr,
-				setMetricErr: c.metricsSetErr,
+			err := setCompressionPolicy(mock, "metric_0", c.compressAfter)
+			if err != c.expectedErr {
+				t.Fatalf("unexpected error: got %v, wanted %v", err, c.expectedErr)
 			}
-			inserter, err := newPgxInserter(mock, mockMetrics, &Cfg{})
-			if err != nil {
-				t.Fatal(err)
+			if !reflect.DeepEqual(mock.ExecSQLs, c.expectedSQLs) {
+				t.Errorf("unexpected statements: got %v, wanted %v", mock.ExecSQLs, c.expectedSQLs)
 			}
+			if len(c.expectedArgs) > 0 && !reflect.DeepEqual(mock.ExecArgs, c.expectedArgs) {
+				t.Errorf("unexpected arguments: got %v, wanted %v", mock.ExecArgs, c.expectedArgs)
+			}
+		})
+	}
+}
 
-			_, err = inserter.InsertData(c.rows)
+func TestRetention(t *testing.T) {
+	ctx := context.Background()
 
-			if err != nil {
-				var expErr error
+	t.Run("set", func(t *testing.T) {
+		mock := &mockPGXConn{}
+		r := &Retention{conn: mock}
+		if err := r.SetDefault(ctx, 90*24*time.Hour); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Set(ctx, "foo", 7*24*time.Hour); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Remove(ctx, "foo"); err != nil {
+			t.Fatal(err)
+		}
 
-				switch {
-				case c.metricsGetErr != nil:
-					expErr = c.metricsGetErr
-				case c.metricsSetErr != nil:
-					expErr = c.metricsSetErr
-				case c.copyFromErr != nil:
-					expErr = c.copyFromErr
-				case c.queryErr != nil:
-					for _, qErr := range c.queryErr {
-						expErr = qErr
-					}
-				case c.queryNoRows:
-					expErr = errMissingTableName
-				}
+		expectedSQLs := []string{setDefaultRetentionSQL, setMetricRetentionSQL, resetMetricRetentionSQL}
+		if !reflect.DeepEqual(mock.ExecSQLs, expectedSQLs) {
+			t.Errorf("unexpected statements: got %v, wanted %v", mock.ExecSQLs, expectedSQLs)
+		}
+		expectedArgs := [][]interface{}{{90 * 24 * time.Hour}, {"foo", 7 * 24 * time.Hour}, {"foo"}}
+		if !reflect.DeepEqual(mock.ExecArgs, expectedArgs) {
+			t.Errorf("unexpected arguments: got %v, wanted %v", mock.ExecArgs, expectedArgs)
+		}
+	})
 
-				if err != expErr {
-					t.Errorf("unexpected error:\ngot\n%s\nwanted\n%s", err, expErr)
-				}
-
-				return
+	t.Run("invalid period", func(t *testing.T) {
+		mock := &mockPGXConn{}
+		r := &Retention{conn: mock}
+		for _, period := range []time.Duration{0, -time.Hour} {
+			if err := r.SetDefault(ctx, period); err == nil {
+				t.Errorf("expected an error for default period %v", period)
 			}
-
-			if c.copyFromErr != nil {
-				t.Errorf("expected error:\ngot\nnil\nwanted\n%s", c.copyFromErr)
+			if err := r.Set(ctx, "foo", period); err == nil {
+				t.Errorf("expected an error for period %v", period)
 			}
+		}
+		if len(mock.ExecSQLs) != 0 {
+			t.Errorf("unexpected statements: %v", mock.ExecSQLs)
+		}
+	})
 
-			if len(c.rows) == 0 {
-				return
-			}
+	t.Run("get", func(t *testing.T) {
+		mock := &mockPGXConn{
+			QueryResults: []rowResults{{{float64(7 * 24 * 3600)}}},
+			QueryErr:     map[int]error{},
+		}
+		r := &Retention{conn: mock}
+		period, err := r.Get(ctx, "foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if period != 7*24*time.Hour {
+			t.Errorf("unexpected period: got %v, wanted %v", period, 7*24*time.Hour)
+		}
+		if !reflect.DeepEqual(mock.QueryArgs, [][]interface{}{{"foo"}}) {
+			t.Errorf("unexpected arguments: got %v", mock.QueryArgs)
+		}
+	})
+}
 
-			if len(mock.CopyFromTableName) != len(c.rows) {
-				t.Errorf("number of table names differs from input: got %d want %d\n", len(mock.CopyFromTableName), len(c.rows))
+func TestChunkIntervals(t *testing.T) {
+	ctx := context.Background()
+	mock := &mockPGXConn{}
+	c := &ChunkIntervals{conn: mock}
+
+	if err := c.SetDefault(ctx, 12*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set(ctx, "foo", 2*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Reset(ctx, "foo"); err != nil {
+		t.Fatal(err)
+	}
+	for _, interval := range []time.Duration{0, -time.Hour} {
+		if err := c.SetDefault(ctx, interval); err == nil {
+			t.Errorf("expected an error for default interval %v", interval)
+		}
+		if err := c.Set(ctx, "foo", interval); err == nil {
+			t.Errorf("expected an error for interval %v", interval)
+		}
+	}
+
+	expectedSQLs := []string{setDefaultChunkIntervalSQL, setMetricChunkIntervalSQL, resetMetricChunkIntervalSQL}
+	if !reflect.DeepEqual(mock.ExecSQLs, expectedSQLs) {
+		t.Errorf("unexpected statements: got %v, wanted %v", mock.ExecSQLs, expectedSQLs)
+	}
+	expectedArgs := [][]interface{}{{12 * time.Hour}, {"foo", 2 * time.Hour}, {"foo"}}
+	if !reflect.DeepEqual(mock.ExecArgs, expectedArgs) {
+		t.Errorf("unexpected arguments: got %v, wanted %v", mock.ExecArgs, expectedArgs)
+	}
+}
+
+func TestPGXInserterChunkInterval(t *testing.T) {
+	testCases := []struct {
+		name         string
+		metric       string
+		possiblyNew  bool
+		expectedArgs [][]interface{}
+	}{
+		{
+			name:         "new metric",
+			metric:       "metric_0",
+			possiblyNew:  true,
+			expectedArgs: [][]interface{}{{"metric_0", 2 * time.Hour}},
+		},
+		{
+			name:   "existing metric",
+			metric: "metric_0",
+		},
+		{
+			name:        "new metric without interval",
+			metric:      "metric_1",
+			possiblyNew: true,
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			mock := &mockPGXConn{
+				QueryResults: []rowResults{{{"metricTableName", c.possiblyNew}}, {{}}},
+			}
+			mockMetrics := &mockMetricCache{
+				metricCache: make(map[string]string),
+			}
+			cfg := &Cfg{MetricChunkIntervals: map[string]time.Duration{"metric_0": 2 * time.Hour}}
+			inserter, err := newPgxInserter(mock, mockMetrics, cfg)
+			if err != nil {
+				t.Fatal(err)
 			}
 
-			tNames := make([]pgx.Identifier, 0, len(c.rows))
-			for tableName := range c.rows {
-				realTableName, err := mockMetrics.Get(tableName)
-				if err != nil {
-					t.Fatalf("error when fetching metric table name: %s", err)
-				}
-				tNames = append(tNames, pgx.Identifier{dataSchema, realTableName})
+			rows := createRowsWithDuplicates(t)
+			rows[c.metric] = rows["metric_0"]
+			if c.metric != "metric_0" {
+				delete(rows, "metric_0")
+			}
+			if _, _, err = inserter.InsertData(context.Background(), rows); err != nil {
+				t.Fatal(err)
 			}
 
-			// Sorting because range over a map gives random iteration order.
-			sort.Slice(tNames, func(i, j int) bool { return tNames[i][1] < tNames[j][1] })
-			sort.Slice(mock.CopyFromTableName, func(i, j int) bool { return mock.CopyFromTableName[i][1] < mock.CopyFromTableName[j][1] })
-			expectedNames := make([]string, len(tNames))
-			for i := range tNames {
-				expectedNames[i] = tNames[i].Sanitize()
+			var args [][]interface{}
+			for i, sql := range mock.ExecSQLs {
+				if sql == setMetricChunkIntervalSQL {
+					args = append(args, mock.ExecArgs[i])
+				}
 			}
-			if !reflect.DeepEqual(mock.CopyFromTableName, expectedNames) {
-				t.Errorf("unexpected copy table:\ngot\n%s\nwanted\n%s", mock.CopyFromTableName, tNames)
+			if !reflect.DeepEqual(args, c.expectedArgs) {
+				t.Errorf("unexpected chunk intervals set: got %v, wanted %v", args, c.expectedArgs)
 			}
 		})
 	}
 }
 
-func TestPGXQuerierQuery(t *testing.T) {
+func TestPGXInserterOverloaded(t *testing.T) {
 	testCases := []struct {
-		name         string
-		query        *prompb.Query
-		result       []*prompb.TimeSeries
-		err          error
-		sqlQueries   []string // XXX whitespace in these is significant
-		sqlArgs      [][]interface{}
-		queryResults []rowResults
-		queryErr     map[int]error
+		name          string
+		policy        OverloadPolicy
+		metricQueued  int
+		copiersQueued int
+		busyCopiers   int64
+		overloaded    bool
 	}{
 		{
-			name: "Error metric name value",
-			query: &prompb.Query{
-				StartTimestampMs: 1000,
-				EndTimestampMs:   2000,
-				Matchers: []*prompb.LabelMatcher{
-					{Type: prompb.LabelMatcher_NEQ, Name: MetricNameLabelName, Value: "bar"},
-				},
-			},
-			sqlQueries: []string{`SELECT m.metric_name, array_agg(s.id)
-	FROM _prom_catalog.series s
-	INNER JOIN _prom_catalog.metric m
-	ON (m.id = s.metric_id)
-	WHERE NOT labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $1 and l.value = $2)
-	GROUP BY m.metric_name
-	ORDER BY m.metric_name`},
-			sqlArgs: [][]interface{}{{MetricNameLabelName, "bar"}},
-			queryResults: []rowResults{
-				{{1, []int64{}}},
-			},
-			err: fmt.Errorf("wrong value type int"),
+			name:         "no thresholds",
+			metricQueued: 10,
+			busyCopiers:  10,
 		},
 		{
-			name: "Error first query",
-			query: &prompb.Query{
-				StartTimestampMs: 1000,
-				EndTimestampMs:   2000,
-				Matchers: []*prompb.LabelMatcher{
-					{Type: prompb.LabelMatcher_NEQ, Name: MetricNameLabelName, Value: "bar"},
-				},
-			},
-			sqlQueries: []string{`SELECT m.metric_name, array_agg(s.id)
-	FROM _prom_catalog.series s
-	INNER JOIN _prom_catalog.metric m
-	ON (m.id = s.metric_id)
-	WHERE NOT labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $1 and l.value = $2)
-	GROUP BY m.metric_name
-	ORDER BY m.metric_name`},
-			sqlArgs: [][]interface{}{{"__name__", "bar"}},
-			queryResults: []rowResults{
-				{{"{}", []time.Time{}, []float64{}}},
-			},
-			queryErr: map[int]error{0: fmt.Errorf("some error")},
+			name:          "queue below threshold",
+			policy:        OverloadPolicy{MaxQueueDepth: 5},
+			metricQueued:  3,
+			copiersQueued: 2,
 		},
 		{
-			name: "Error second query",
-			query: &prompb.Query{
-				StartTimestampMs: 1000,
-				EndTimestampMs:   2000,
-				Matchers: []*prompb.LabelMatcher{
-					{Type: prompb.LabelMatcher_NEQ, Name: "foo", Value: "bar"},
-				},
-			},
-			sqlQueries: []string{`SELECT m.metric_name, array_agg(s.id)
-	FROM _prom_catalog.series s
-	INNER JOIN _prom_catalog.metric m
-	ON (m.id = s.metric_id)
-	WHERE NOT labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $1 and l.value = $2)
-	GROUP BY m.metric_name
-	ORDER BY m.metric_name`,
-				`SELECT table_name FROM _prom_catalog.get_metric_table_name_if_exists($1)`},
-			sqlArgs: [][]interface{}{
-				{"foo", "bar"},
-				{"foo"},
-			},
-			queryResults: []rowResults{
-				{{`foo`, []int64{1}}},
-			},
-			queryErr: map[int]error{1: fmt.Errorf("some error")},
+			name:          "queue above threshold",
+			policy:        OverloadPolicy{MaxQueueDepth: 5, RetryAfter: time.Second},
+			metricQueued:  4,
+			copiersQueued: 2,
+			overloaded:    true,
 		},
 		{
-			name: "Error third query",
-			query: &prompb.Query{
-				StartTimestampMs: 1000,
-				EndTimestampMs:   2000,
-				Matchers: []*prompb.LabelMatcher{
-					{Type: prompb.LabelMatcher_NEQ, Name: "foo", Value: "bar"},
-				},
-			},
-			sqlQueries: []string{`SELECT m.metric_name, array_agg(s.id)
-	FROM _prom_catalog.series s
-	INNER JOIN _prom_catalog.metric m
-	ON (m.id = s.metric_id)
-	WHERE NOT labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $1 and l.value = $2)
-	GROUP BY m.metric_name
-	ORDER BY m.metric_name`,
-				`SELECT table_name FROM _prom_catalog.get_metric_table_name_if_exists($1)`,
-				`SELECT s.labels, array_agg(m.time ORDER BY time), array_agg(m.value ORDER BY time)
-	FROM "prom_data"."foo" m
-	INNER JOIN "prom_data_series"."foo" s
-	ON m.series_id = s.id
-	WHERE m.series_id IN (1)
-	AND time >= '1970-01-01T00:00:01Z'
-	AND time <= '1970-01-01T00:00:02Z'
-	GROUP BY s.id`},
-			sqlArgs: [][]interface{}{
-				{"foo", "bar"},
-				{"foo"},
-				nil,
-			},
-			queryResults: []rowResults{
-				{{`foo`, []int64{1}}},
-				{{"foo"}},
-			},
-			queryErr: map[int]error{2: fmt.Errorf("some error")},
+			name:        "pool below threshold",
+			policy:      OverloadPolicy{MaxPoolSaturation: 0.9},
+			busyCopiers: 9,
 		},
 		{
-			name: "Error scan values",
-			query: &prompb.Query{
-				StartTimestampMs: 1000,
-				EndTimestampMs:   2000,
-				Matchers: []*prompb.LabelMatcher{
-					{Type: prompb.LabelMatcher_NEQ, Name: MetricNameLabelName, Value: "bar"},
-				},
-			},
-			sqlQueries: []string{`SELECT m.metric_name, array_agg(s.id)
-	FROM _prom_catalog.series s
-	INNER JOIN _prom_catalog.metric m
-	ON (m.id = s.metric_id)
-	WHERE NOT labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $1 and l.value = $2)
-	GROUP BY m.metric_name
-	ORDER BY m.metric_name`},
-			sqlArgs: [][]interface{}{{"__name__", "bar"}},
-			queryResults: []rowResults{
-				{{0}},
-			},
-			err: fmt.Errorf("scanning error, missing results for scanning: got 1 wanted 2"),
+			name:        "pool above threshold",
+			policy:      OverloadPolicy{MaxPoolSaturation: 0.9, RetryAfter: time.Second},
+			busyCopiers: 10,
+			overloaded:  true,
 		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			inserter := &pgxInserter{
+				conn:          &mockPGXConn{},
+				toCopiers:     make(chan copyRequest, 10),
+				overload:      c.policy,
+				copierOptions: &copierOptions{busyCopiers: c.busyCopiers},
+				numCopiers:    10,
+			}
+			queue := make(chan insertDataRequest, 10)
+			inserter.inserters.Store("metric_0", queue)
+			for i := 0; i < c.metricQueued; i++ {
+				queue <- insertDataRequest{}
+			}
+			for i := 0; i < c.copiersQueued; i++ {
+				inserter.toCopiers <- copyRequest{}
+			}
+
+			retryAfter, err := (&DBIngestor{db: inserter}).Overloaded()
+			if !c.overloaded {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrOverloaded) {
+				t.Fatalf("unexpected error: got %v, wanted %v", err, ErrOverloaded)
+			}
+			if retryAfter != c.policy.RetryAfter {
+				t.Errorf("unexpected retry after: got %v, wanted %v", retryAfter, c.policy.RetryAfter)
+			}
+		})
+	}
+}
+
+func TestPGXQuerierTenancy(t *testing.T) {
+	query := func(matchers ...*prompb.LabelMatcher) *prompb.Query {
+		return &prompb.Query{StartTimestampMs: 1000, EndTimestampMs: 2000, Matchers: matchers}
+	}
+	metricMatcher := &prompb.LabelMatcher{Type: prompb.LabelMatcher_EQ, Name: MetricNameLabelName, Value: "bar"}
+
+	testCases := []struct {
+		name          string
+		ctxTenant     string
+		defaultTenant string
+		query         *prompb.Query
+		args          []interface{}
+		err           error
+	}{
 		{
-			name:   "Empty query",
-			result: []*prompb.TimeSeries{},
+			name:      "tenant of request",
+			ctxTenant: "a",
+			query:     query(metricMatcher),
+			args:      []interface{}{MetricNameLabelName, "bar", "tenant", "a"},
 		},
 		{
-			name: "Simple query, no result",
-			query: &prompb.Query{
-				StartTimestampMs: 1000,
-				EndTimestampMs:   2000,
-				Matchers: []*prompb.LabelMatcher{
-					{Type: prompb.LabelMatcher_EQ, Name: "foo", Value: "bar"},
-				},
-			},
-			sqlQueries: []string{`SELECT m.metric_name, array_agg(s.id)
-	FROM _prom_catalog.series s
-	INNER JOIN _prom_catalog.metric m
-	ON (m.id = s.metric_id)
-	WHERE labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $1 and l.value = $2)
-	GROUP BY m.metric_name
-	ORDER BY m.metric_name`},
-			sqlArgs:      [][]interface{}{{"foo", "bar"}},
-			result:       []*prompb.TimeSeries{},
-			queryResults: []rowResults{},
+			name:      "tenant matcher of query",
+			ctxTenant: "a",
+			query:     query(metricMatcher, &prompb.LabelMatcher{Type: prompb.LabelMatcher_EQ, Name: "tenant", Value: "b"}),
+			args:      []interface{}{MetricNameLabelName, "bar", "tenant", "b", "tenant", "a"},
 		},
 		{
-			name: "Simple query, metric doesn't exist",
-			query: &prompb.Query{
-				StartTimestampMs: 1000,
-				EndTimestampMs:   2000,
-				Matchers: []*prompb.LabelMatcher{
-					{Type: prompb.LabelMatcher_EQ, Name: MetricNameLabelName, Value: "bar"},
-				},
-			},
-			sqlQueries: []string{`SELECT table_name FROM _prom_catalog.get_metric_table_name_if_exists($1)`},
-			sqlArgs: [][]interface{}{
-				{"bar"},
-			},
-			result:       []*prompb.TimeSeries{},
-			queryResults: []rowResults{},
+			name:          "default tenant",
+			defaultTenant: "d",
+			query:         query(metricMatcher),
+			args:          []interface{}{MetricNameLabelName, "bar", "tenant", "d"},
 		},
 		{
-			name: "Simple query, exclude matcher",
-			query: &prompb.Query{
-				StartTimestampMs: 1000,
-				EndTimestampMs:   2000,
-				Matchers: []*prompb.LabelMatcher{
-					{Type: prompb.LabelMatcher_NEQ, Name: MetricNameLabelName, Value: "bar"},
-				},
-			},
-			sqlQueries: []string{`SELECT m.metric_name, array_agg(s.id)
+			name:  "missing tenant",
+			query: query(metricMatcher),
+			err:   ErrMissingTenant,
+		},
+		{
+			name:      "tenant does not bound query",
+			ctxTenant: "a",
+			query:     query(),
+			err:       ErrUnboundedQuery,
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			mock := &mockPGXConn{
+				QueryResults: []rowResults{{}},
+			}
+			mockMetrics := &mockMetricCache{
+				metricCache: map[string]string{"bar": "bar"},
+			}
+			querier := pgxQuerier{
+				conn:             mock,
+				metricTableNames: mockMetrics,
+				labels:           clockcache.WithMax(0),
+				tenancy:          &Tenancy{Label: "tenant", DefaultTenant: c.defaultTenant},
+			}
+
+			ctx := context.Background()
+			if c.ctxTenant != "" {
+				ctx = WithTenant(ctx, c.ctxTenant)
+			}
+			_, err := querier.Query(ctx, c.query)
+			if !errors.Is(err, c.err) {
+				t.Fatalf("unexpected error: got %v, wanted %v", err, c.err)
+			}
+			if c.err != nil {
+				if len(mock.QuerySQLs) != 0 {
+					t.Errorf("unexpected queries: %v", mock.QuerySQLs)
+				}
+				return
+			}
+			if len(mock.QueryArgs) == 0 || !reflect.DeepEqual(mock.QueryArgs[0], c.args) {
+				t.Errorf("unexpected query arguments: got %v, wanted %v", mock.QueryArgs, c.args)
+			}
+		})
+	}
+}
+
+func TestPGXQuerierMetricNameOnly(t *testing.T) {
+	metricMatcher := &prompb.LabelMatcher{Type: prompb.LabelMatcher_EQ, Name: MetricNameLabelName, Value: "bar"}
+	labelSubQuery := "labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $1 and l.value = $2)"
+
+	testCases := []struct {
+		name     string
+		matchers []*prompb.LabelMatcher
+		clause   string
+		args     []interface{}
+	}{
+		{
+			name:     "metric name only",
+			matchers: []*prompb.LabelMatcher{metricMatcher},
+			clause:   "WHERE " + metricSeriesClause + "\n",
+		},
+		{
+			name:     "metric name and label",
+			matchers: []*prompb.LabelMatcher{metricMatcher, {Type: prompb.LabelMatcher_EQ, Name: "job", Value: "api"}},
+			clause:   "WHERE " + labelSubQuery + " AND ",
+			args:     []interface{}{MetricNameLabelName, "bar", "job", "api"},
+		},
+		{
+			name:     "metric name and negative label",
+			matchers: []*prompb.LabelMatcher{metricMatcher, {Type: prompb.LabelMatcher_NEQ, Name: "job", Value: "api"}},
+			clause:   "WHERE " + labelSubQuery + " AND ",
+			args:     []interface{}{MetricNameLabelName, "bar", "job", "api"},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			mock := &mockPGXConn{
+				QueryResults: []rowResults{{}},
+			}
+			mockMetrics := &mockMetricCache{
+				metricCache: map[string]string{"bar": "bar"},
+			}
+			querier := pgxQuerier{conn: mock, metricTableNames: mockMetrics, labels: clockcache.WithMax(0)}
+
+			_, err := querier.Query(context.Background(), &prompb.Query{StartTimestampMs: 1000, EndTimestampMs: 2000, Matchers: c.matchers})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(mock.QuerySQLs) != 1 || !strings.Contains(mock.QuerySQLs[0], `INNER JOIN "prom_data_series"."bar" s`) {
+				t.Fatalf("unexpected queries: %v", mock.QuerySQLs)
+			}
+			if !strings.Contains(mock.QuerySQLs[0], c.clause) {
+				t.Errorf("unexpected query:\n%s\nwanted it to contain\n%s", mock.QuerySQLs[0], c.clause)
+			}
+			if !reflect.DeepEqual(mock.QueryArgs[0], c.args) {
+				t.Errorf("unexpected query arguments: got %v, wanted %v", mock.QueryArgs[0], c.args)
+			}
+		})
+	}
+}
+
+func TestPGXQuerierCheckLabelNames(t *testing.T) {
+	metricMatcher := &prompb.LabelMatcher{Type: prompb.LabelMatcher_EQ, Name: MetricNameLabelName, Value: "bar"}
+
+	testCases := []struct {
+		name     string
+		matchers []*prompb.LabelMatcher
+		missing  bool
+		// checked are the names looked up, none if nil
+		checked []string
+	}{
+		{
+			name:     "missing label",
+			matchers: []*prompb.LabelMatcher{metricMatcher, {Type: prompb.LabelMatcher_EQ, Name: "nonexistent", Value: "x"}},
+			missing:  true,
+			checked:  []string{"nonexistent"},
+		},
+		{
+			name:     "missing label by regex",
+			matchers: []*prompb.LabelMatcher{metricMatcher, {Type: prompb.LabelMatcher_RE, Name: "nonexistent", Value: "x.+"}},
+			missing:  true,
+			checked:  []string{"nonexistent"},
+		},
+		{
+			name:     "existing label",
+			matchers: []*prompb.LabelMatcher{metricMatcher, {Type: prompb.LabelMatcher_EQ, Name: "job", Value: "api"}},
+			checked:  []string{"job"},
+		},
+		{
+			name:     "metric name only",
+			matchers: []*prompb.LabelMatcher{metricMatcher},
+		},
+		{
+			name:     "matcher of the empty value",
+			matchers: []*prompb.LabelMatcher{metricMatcher, {Type: prompb.LabelMatcher_NEQ, Name: "nonexistent", Value: "x"}},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			results := []rowResults{{}}
+			if c.checked != nil {
+				check := rowResults{}
+				if c.missing {
+					check = rowResults{{c.checked[0]}}
+				}
+				results = []rowResults{check, {}}
+			}
+			mock := &mockPGXConn{QueryResults: results}
+			mockMetrics := &mockMetricCache{
+				metricCache: map[string]string{"bar": "bar"},
+			}
+			querier := pgxQuerier{conn: mock, metricTableNames: mockMetrics, labels: clockcache.WithMax(0), checkLabelNames: true}
+
+			ts, err := querier.Query(context.Background(), &prompb.Query{StartTimestampMs: 1000, EndTimestampMs: 2000, Matchers: c.matchers})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ts) != 0 {
+				t.Errorf("unexpected series: %v", ts)
+			}
+
+			queries := mock.QuerySQLs
+			if c.checked != nil {
+				if len(queries) == 0 || queries[0] != missingLabelNameSQL {
+					t.Fatalf("label names not checked: %v", queries)
+				}
+				if !reflect.DeepEqual(mock.QueryArgs[0], []interface{}{c.checked}) {
+					t.Errorf("unexpected label names checked: got %v, wanted %v", mock.QueryArgs[0], c.checked)
+				}
+				queries = queries[1:]
+			}
+			if c.missing {
+				if len(queries) != 0 {
+					t.Errorf("series queried despite a missing label: %v", queries)
+				}
+				return
+			}
+			if len(queries) != 1 || !strings.Contains(queries[0], `INNER JOIN "prom_data_series"."bar" s`) {
+				t.Errorf("unexpected queries: %v", queries)
+			}
+		})
+	}
+}
+
+func TestScanSeriesRow(t *testing.T) {
+	testCases := []struct {
+		name        string
+		row         []interface{}
+		ignoreExtra bool
+		err         error
+	}{
+		{
+			name: "expected columns",
+			row:  []interface{}{[]int64{1}, []time.Time{time.Unix(0, 0)}, []float64{1}},
+		},
+		{
+			name: "extra column",
+			row:  []interface{}{[]int64{1}, []time.Time{time.Unix(0, 0)}, []float64{1}, "extra"},
+			err:  ErrUnexpectedColumns,
+		},
+		{
+			name:        "extra column ignored",
+			row:         []interface{}{[]int64{1}, []time.Time{time.Unix(0, 0)}, []float64{1}, "extra"},
+			ignoreExtra: true,
+		},
+		{
+			name: "missing column",
+			row:  []interface{}{[]int64{1}, []time.Time{time.Unix(0, 0)}},
+			err:  ErrUnexpectedColumns,
+		},
+		{
+			name:        "missing column with extra columns ignored",
+			row:         []interface{}{[]int64{1}, []time.Time{time.Unix(0, 0)}},
+			ignoreExtra: true,
+			err:         ErrUnexpectedColumns,
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			rows := &mockRows{results: rowResults{c.row}}
+			var (
+				labelIDs   []int64
+				timestamps []time.Time
+				values     pgtype.Float8Array
+			)
+			err := scanSeriesRow(rows, c.ignoreExtra, &labelIDs, &timestamps, &values)
+			if !errors.Is(err, c.err) {
+				t.Fatalf("unexpected error: got %v, wanted %v", err, c.err)
+			}
+			if err != nil {
+				if !strings.Contains(err.Error(), fmt.Sprintf("got %d columns", len(c.row))) {
+					t.Errorf("error does not name the number of columns: %v", err)
+				}
+				return
+			}
+			if !reflect.DeepEqual(labelIDs, []int64{1}) || len(timestamps) != 1 || len(values.Elements) != 1 || values.Elements[0].Float != 1 {
+				t.Errorf("unexpected row scanned: %v %v %v", labelIDs, timestamps, values.Elements)
+			}
+		})
+	}
+}
+
+func TestPGXQuerierLabelQueryRetries(t *testing.T) {
+	defer func(backoff time.Duration) { labelQueryRetryBackoff = backoff }(labelQueryRetryBackoff)
+	labelQueryRetryBackoff = time.Millisecond
+
+	labelsErr := fmt.Errorf("labels table unavailable")
+	testCases := []struct {
+		name     string
+		retries  int
+		failures int
+		err      error
+	}{
+		{
+			name: "no failure",
+		},
+		{
+			name:     "no retries",
+			failures: 1,
+			err:      labelsErr,
+		},
+		{
+			name:     "retried",
+			retries:  2,
+			failures: 2,
+		},
+		{
+			name:     "retries exhausted",
+			retries:  1,
+			failures: 2,
+			err:      labelsErr,
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			queryErr := make(map[int]error)
+			var results []rowResults
+			for i := 0; i < c.failures; i++ {
+				queryErr[i] = labelsErr
+				results = append(results, rowResults{})
+			}
+			results = append(results, rowResults{{[]int64{1}, []string{"__name__"}, []string{"foo"}}})
+			mock := &mockPGXConn{
+				QueryResults: results,
+				QueryErr:     queryErr,
+			}
+			querier := pgxQuerier{conn: mock, labels: clockcache.WithMax(10), labelQueryRetries: c.retries}
+
+			labelMap, err := querier.getLabelMapForIds(context.Background(), []int64{1})
+			if !errors.Is(err, c.err) {
+				t.Fatalf("unexpected error: got %v, wanted %v", err, c.err)
+			}
+			if err != nil {
+				return
+			}
+			expected := map[int64]labels.Label{1: {Name: MetricNameLabelName, Value: "foo"}}
+			if !reflect.DeepEqual(labelMap, expected) {
+				t.Errorf("unexpected labels: got %v, wanted %v", labelMap, expected)
+			}
+			if len(mock.QuerySQLs) != c.failures+1 {
+				t.Errorf("unexpected number of label queries: got %d, wanted %d", len(mock.QuerySQLs), c.failures+1)
+			}
+		})
+	}
+}
+
+func TestFingerprintFor(t *testing.T) {
+	testCases := []struct {
+		name     string
+		labels   labels.Labels
+		expected uint64
+	}{
+		{
+			name:     "empty",
+			labels:   labels.Labels{},
+			expected: 0xef46db3751d8e999,
+		},
+		{
+			name:     "metric name only",
+			labels:   labels.FromStrings(MetricNameLabelName, "up"),
+			expected: 0x23d4c2cb13387598,
+		},
+		{
+			name:     "target labels",
+			labels:   labels.FromStrings(MetricNameLabelName, "up", "instance", "localhost:9090", "job", "prometheus"),
+			expected: 0xf28c8afcd224e55f,
+		},
+		{
+			name:     "unsorted",
+			labels:   labels.Labels{{Name: "method", Value: "GET"}, {Name: MetricNameLabelName, Value: "http_requests_total"}, {Name: "code", Value: "200"}},
+			expected: 0x083c707a08f265f3,
+		},
+		{
+			name:     "longer than the hash buffer",
+			labels:   labels.FromStrings(MetricNameLabelName, "big", "value", strings.Repeat("x", 2000)),
+			expected: 0x994d8c8b1dcba087,
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := FingerprintFor(c.labels); got != c.expected {
+				t.Errorf("unexpected fingerprint: got %#x, wanted %#x", got, c.expected)
+			}
+			sorted := append(labels.Labels(nil), c.labels...)
+			sort.Sort(sorted)
+			if got := FingerprintFor(c.labels); got != sorted.Hash() {
+				t.Errorf("fingerprint differs from Prometheus: got %#x, wanted %#x", got, sorted.Hash())
+			}
+
+			lset, err := LabelsFromSlice(c.labels)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := lset.Fingerprint(); got != c.expected {
+				t.Errorf("unexpected fingerprint of Labels: got %#x, wanted %#x", got, c.expected)
+			}
+		})
+	}
+}
+
+// createRowsWithDuplicates returns rows of two series of metric_0, with
+// the first series split across two samplesInfos and sending two samples for
+// timestamps 1 and 3.
+func createRowsWithDuplicates(t *testing.T) map[string][]samplesInfo {
+	series := func(id SeriesID, instance string, samples ...prompb.Sample) samplesInfo {
+		lset, _, err := labelProtosToLabels([]prompb.Label{
+			{Name: MetricNameLabelName, Value: "metric_0"},
+			{Name: "instance", Value: instance},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return samplesInfo{labels: lset, seriesID: id, samples: samples}
+	}
+	return map[string][]samplesInfo{
+		"metric_0": {
+			series(1, "a", prompb.Sample{Timestamp: 3, Value: 1}, prompb.Sample{Timestamp: 1, Value: 2}, prompb.Sample{Timestamp: 3, Value: 3}),
+			series(2, "b", prompb.Sample{Timestamp: 1, Value: 6}),
+			series(1, "a", prompb.Sample{Timestamp: 1, Value: 4}, prompb.Sample{Timestamp: 2, Value: 5}),
+		},
+	}
+}
+
+func TestPGXInserterInsertData(t *testing.T) {
+	testCases := []struct {
+		name           string
+		rows           map[string][]samplesInfo
+		queryNoRows    bool
+		queryErr       map[int]error
+		copyFromResult int64
+		copyFromErr    error
+		metricsGetErr  error
+		metricsSetErr  error
+	}{
+		{
+			name: "Zero data",
+		},
+		{
+			name: "One data",
+			rows: createRows(1),
+		},
+		{
+			name: "Two data",
+			rows: createRows(2),
+		},
+		{
+			name:     "Create table error",
+			rows:     createRows(5),
+			queryErr: map[int]error{0: fmt.Errorf("create table error")},
+		},
+		{
+			name:        "Copy from error",
+			rows:        createRows(5),
+			copyFromErr: fmt.Errorf("some error"),
+		},
+		{
+			name:           "Not all data inserted",
+			rows:           createRows(5),
+			copyFromResult: 4,
+		},
+		{
+			name:        "Can't find/create table in DB",
+			rows:        createRows(5),
+			queryNoRows: true,
+		},
+		{
+			name:          "Metrics get error",
+			rows:          createRows(1),
+			metricsGetErr: fmt.Errorf("some metrics error"),
+		},
+		{
+			name:          "Metrics set error",
+			rows:          createRows(1),
+			metricsSetErr: fmt.Errorf("some metrics error"),
+		},
+	}
+	for _, co := range testCases {
+		c := co
+		t.Run(c.name, func(t *testing.T) {
+			mock := &mockPGXConn{
+				QueryNoRows:    c.queryNoRows,
+				QueryErr:       c.queryErr,
+				CopyFromResult: c.copyFromResult,
+				CopyFromError:  c.copyFromErr,
+			}
+
+			//The database will be queried for metricNames
+			results := make([]rowResults, 0, len(c.rows))
+			for metricName := range c.rows {
+				//create metric name
+				results = append(results, rowResults{{metricName, true}})
+				//send batch
+				results = append(results, rowResults{{}})
+			}
+			mock.QueryResults = results
+
+			metricCache := map[string]string{"metric_1": "metricTableName_1"}
+			mockMetrics := &mockMetricCache{
+				metricCache:  metricCache,
+				getMetricErr: c.metricsGetErr,
+				setMetricErr: c.metricsSetErr,
+			}
+			inserter, err := newPgxInserter(mock, mockMetrics, &Cfg{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, _, err = inserter.InsertData(context.Background(), c.rows)
+
+			if err != nil {
+				var expErr error
+
+				switch {
+				case c.metricsGetErr != nil:
+					expErr = c.metricsGetErr
+				case c.metricsSetErr != nil:
+					expErr = c.metricsSetErr
+				case c.copyFromErr != nil:
+					expErr = c.copyFromErr
+				case c.queryErr != nil:
+					for _, qErr := range c.queryErr {
+						expErr = qErr
+					}
+				case c.queryNoRows:
+					expErr = errMissingTableName
+				}
+
+				if err != expErr {
+					t.Errorf("unexpected error:\ngot\n%s\nwanted\n%s", err, expErr)
+				}
+
+				return
+			}
+
+			if c.copyFromErr != nil {
+				t.Errorf("expected error:\ngot\nnil\nwanted\n%s", c.copyFromErr)
+			}
+
+			if len(c.rows) == 0 {
+				return
+			}
+
+			if len(mock.CopyFromTableName) != len(c.rows) {
+				t.Errorf("number of table names differs from input: got %d want %d\n", len(mock.CopyFromTableName), len(c.rows))
+			}
+
+			tNames := make([]pgx.Identifier, 0, len(c.rows))
+			for tableName := range c.rows {
+				realTableName, err := mockMetrics.Get(tableName)
+				if err != nil {
+					t.Fatalf("error when fetching metric table name: %s", err)
+				}
+				tNames = append(tNames, pgx.Identifier{dataSchema, realTableName})
+			}
+
+			// Sorting because range over a map gives random iteration order.
+			sort.Slice(tNames, func(i, j int) bool { return tNames[i][1] < tNames[j][1] })
+			sort.Slice(mock.CopyFromTableName, func(i, j int) bool { return mock.CopyFromTableName[i][1] < mock.CopyFromTableName[j][1] })
+			expectedNames := make([]string, len(tNames))
+			for i := range tNames {
+				expectedNames[i] = tNames[i].Sanitize()
+			}
+			if !reflect.DeepEqual(mock.CopyFromTableName, expectedNames) {
+				t.Errorf("unexpected copy table:\ngot\n%s\nwanted\n%s", mock.CopyFromTableName, tNames)
+			}
+		})
+	}
+}
+
+func TestPGXInserterEmptyInserts(t *testing.T) {
+	lset, _, err := labelProtosToLabels([]prompb.Label{{Name: MetricNameLabelName, Value: "metric_0"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		name string
+		rows map[string][]samplesInfo
+	}{
+		{name: "nil rows"},
+		{name: "no metrics", rows: map[string][]samplesInfo{}},
+		{name: "metric without series", rows: map[string][]samplesInfo{"metric_0": {}}},
+		{name: "series without samples", rows: map[string][]samplesInfo{"metric_0": {{labels: lset, seriesID: -1}}}},
+	}
+	for _, co := range testCases {
+		c := co
+		t.Run(c.name, func(t *testing.T) {
+			mock := &mockPGXConn{}
+			inserter, err := newPgxInserter(mock, &mockMetricCache{metricCache: map[string]string{}}, &Cfg{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer inserter.Close()
+
+			count, _, err := inserter.InsertData(context.Background(), c.rows)
+			if err != nil || count != 0 {
+				t.Fatalf("unexpected result: count %d, err %v", count, err)
+			}
+			assertNoDBCalls(t, mock)
+		})
+	}
+
+	t.Run("series ids", func(t *testing.T) {
+		mock := &mockPGXConn{}
+		handler := insertHandler{conn: mock, seriesCache: newSeriesIDCache(0)}
+		if _, err := handler.setSeriesIds(nil); err != nil {
+			t.Fatal(err)
+		}
+		assertNoDBCalls(t, mock)
+	})
+}
+
+func assertNoDBCalls(t *testing.T, mock *mockPGXConn) {
+	t.Helper()
+	if len(mock.QuerySQLs) != 0 || len(mock.ExecSQLs) != 0 || len(mock.InsertSQLs) != 0 || len(mock.CopyFromTableName) != 0 || len(mock.Batch) != 0 {
+		t.Errorf("unexpected database calls: queries %v, execs %v, inserts %v, copies %v, batches %d",
+			mock.QuerySQLs, mock.ExecSQLs, mock.InsertSQLs, mock.CopyFromTableName, len(mock.Batch))
+	}
+}
+
+func TestPGXInserterDryRun(t *testing.T) {
+	mock := &mockPGXConn{}
+	mockMetrics := &mockMetricCache{
+		metricCache: map[string]string{"metric_0": "metricTableName_0", "metric_1": "metricTableName_1"},
+	}
+	inserter, err := newPgxInserter(mock, mockMetrics, &Cfg{DryRun: true, InsertTimeBucket: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inserter.Close()
+
+	hour := time.Hour.Milliseconds()
+	rows := createRowsByMetric(3, 2)
+	rows["metric_0"][0].seriesID = 1
+	rows["metric_0"][0].samples = []prompb.Sample{{Timestamp: hour + 1, Value: 1}, {Timestamp: 10, Value: 2}}
+	rows["metric_0"][1].seriesID = 2
+	rows["metric_0"][1].samples = []prompb.Sample{{Timestamp: 20, Value: 3}}
+	rows["metric_1"][0].seriesID = 3
+	rows["metric_1"][0].samples = []prompb.Sample{{Timestamp: 30, Value: 4}}
+
+	count, _, err := inserter.InsertData(context.Background(), rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 4 {
+		t.Errorf("unexpected count: got %d, wanted 4", count)
+	}
+	// the samples are batched and handed to the copiers like for the
+	// database, but not inserted
+	if len(mock.InsertSQLs) != 0 {
+		t.Errorf("unexpected inserts executed: %v", mock.InsertSQLs)
+	}
+
+	ts := func(ms ...int64) []time.Time {
+		times := make([]time.Time, len(ms))
+		for i := range ms {
+			times[i] = time.Unix(0, ms[i]*int64(time.Millisecond))
+		}
+		return times
+	}
+	sql := func(table string) string {
+		return `INSERT INTO "prom_data"."` + table + `"(time, value, series_id) SELECT * FROM unnest($1::TIMESTAMPTZ[], $2::DOUBLE PRECISION[], $3::BIGINT[]) a ON CONFLICT DO NOTHING`
+	}
+	expected := []DryRunInsert{
+		{
+			SQL:  sql("metricTableName_0"),
+			Args: []interface{}{ts(10, 20), []float64{2, 3}, []int64{1, 2}},
+			Rows: 2,
+		},
+		{
+			SQL:  sql("metricTableName_0"),
+			Args: []interface{}{ts(hour + 1), []float64{1}, []int64{1}},
+			Rows: 1,
+		},
+		{
+			SQL:  sql("metricTableName_1"),
+			Args: []interface{}{ts(30), []float64{4}, []int64{3}},
+			Rows: 1,
+		},
+	}
+	ingestor := &DBIngestor{db: inserter}
+	inserts := ingestor.DryRunInserts()
+	// the metrics are batched and copied concurrently
+	sort.SliceStable(inserts, func(i, j int) bool { return inserts[i].SQL < inserts[j].SQL })
+	if !reflect.DeepEqual(inserts, expected) {
+		t.Errorf("unexpected inserts:\ngot\n%v\nwanted\n%v", inserts, expected)
+	}
+	if inserts = inserter.DryRunInserts(); len(inserts) != 0 {
+		t.Errorf("unexpected inserts after they were returned: %v", inserts)
+	}
+	assertNoDBCalls(t, mock)
+}
+
+func TestPGXInserterDryRunCreatesNothing(t *testing.T) {
+	mock := &mockPGXConn{}
+	mockMetrics := &mockMetricCache{metricCache: map[string]string{}}
+	inserter, err := newPgxInserter(mock, mockMetrics, &Cfg{DryRun: true, MetricChunkIntervals: map[string]time.Duration{"metric_0": time.Hour}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inserter.Close()
+
+	rows := createRowsByMetric(1, 1)
+	rows["metric_0"][0].seriesID = -1
+	rows["metric_0"][0].samples = []prompb.Sample{{Timestamp: 10, Value: 1}}
+
+	count, _, err := inserter.InsertData(context.Background(), rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("unexpected count: got %d, wanted 1", count)
+	}
+	if err = inserter.CompleteMetricCreation(); err != nil {
+		t.Fatal(err)
+	}
+
+	// neither the metric table nor the series are created
+	expected := []DryRunInsert{{
+		SQL:  `INSERT INTO "prom_data"."metric_0"(time, value, series_id) SELECT * FROM unnest($1::TIMESTAMPTZ[], $2::DOUBLE PRECISION[], $3::BIGINT[]) a ON CONFLICT DO NOTHING`,
+		Args: []interface{}{[]time.Time{time.Unix(0, 10*int64(time.Millisecond))}, []float64{1}, []int64{-1}},
+		Rows: 1,
+	}}
+	if inserts := inserter.DryRunInserts(); !reflect.DeepEqual(inserts, expected) {
+		t.Errorf("unexpected inserts:\ngot\n%v\nwanted\n%v", inserts, expected)
+	}
+	if _, err := mockMetrics.Get("metric_0"); err != ErrEntryNotFound {
+		t.Errorf("unexpected metric cache entry: %v", err)
+	}
+	assertNoDBCalls(t, mock)
+}
+
+func TestPGXInserterShutdown(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  error
+	}{
+		{name: "pending data inserted"},
+		{name: "insert error returned", err: fmt.Errorf("some error")},
+	}
+	for _, co := range testCases {
+		c := co
+		t.Run(c.name, func(t *testing.T) {
+			mock := &mockPGXConn{CopyFromError: c.err}
+			mockMetrics := &mockMetricCache{
+				metricCache: map[string]string{"metric_0": "metricTableName_0"},
+			}
+			// the batch is only flushed by the shutdown
+			inserter, err := newPgxInserter(mock, mockMetrics, &Cfg{AsyncAcks: true, MaxBatchAge: time.Hour})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rows := createRows(3)
+			if _, _, err = inserter.InsertData(context.Background(), rows); err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			err = inserter.Shutdown(ctx)
+			if !errors.Is(err, c.err) {
+				t.Fatalf("unexpected error: got %v, wanted %v", err, c.err)
+			}
+
+			mock.insertLock.Lock()
+			defer mock.insertLock.Unlock()
+			if c.err == nil && len(mock.Times) != 3 {
+				t.Errorf("unexpected number of samples inserted: got %d, wanted 3", len(mock.Times))
+			}
+			if len(mock.InsertSQLs) == 0 {
+				t.Errorf("pending data was not inserted")
+			}
+		})
+	}
+}
+
+func TestPGXInserterInsertDataCanceled(t *testing.T) {
+	mock := &mockPGXConn{}
+	mockMetrics := &mockMetricCache{
+		metricCache: map[string]string{"metric_0": "metricTableName_0"},
+	}
+	inserter, err := newPgxInserter(mock, mockMetrics, &Cfg{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	inserted, _, err := inserter.InsertData(ctx, createRows(5))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("unexpected error: got %v, wanted %v", err, context.Canceled)
+	}
+	if inserted != 0 {
+		t.Errorf("unexpected inserted count: got %d, wanted 0", inserted)
+	}
+
+	// Requests of a metric are handled in order, so once a later request
+	// completes the canceled one has been handled too. Only the later one
+	// may reach the database.
+	if _, _, err = inserter.InsertData(context.Background(), createRows(1)); err != nil {
+		t.Fatal(err)
+	}
+	mock.insertLock.Lock()
+	defer mock.insertLock.Unlock()
+	if len(mock.InsertSQLs) != 1 {
+		t.Errorf("canceled data was inserted: %v", mock.InsertSQLs)
+	}
+}
+
+func TestPGXInserterConcurrentRequests(t *testing.T) {
+	const numRequests = 20
+	mock := &mockPGXConn{}
+	mockMetrics := &mockMetricCache{
+		metricCache: map[string]string{"metric_0": "metricTableName_0"},
+	}
+	inserter, err := newPgxInserter(mock, mockMetrics, &Cfg{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every request writes its own series, with values identifying the request
+	var wg sync.WaitGroup
+	errs := make(chan error, numRequests)
+	for r := 0; r < numRequests; r++ {
+		wg.Add(1)
+		go func(r int) {
+			defer wg.Done()
+			rows := createRows(1)
+			rows["metric_0"][0].seriesID = SeriesID(r)
+			rows["metric_0"][0].samples = []prompb.Sample{{Timestamp: int64(r), Value: float64(r)}}
+			inserted, _, err := inserter.InsertData(context.Background(), rows)
+			if err == nil && inserted != 1 {
+				err = fmt.Errorf("request %d: unexpected inserted count %d", r, inserted)
+			}
+			errs <- err
+		}(r)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	mock.insertLock.Lock()
+	defer mock.insertLock.Unlock()
+	if len(mock.Series) != numRequests {
+		t.Fatalf("unexpected number of samples inserted: got %d, wanted %d", len(mock.Series), numRequests)
+	}
+	seen := make(map[int64]bool, numRequests)
+	for i, id := range mock.Series {
+		if mock.Vals[i] != float64(id) || toMilis(mock.Times[i]) != id {
+			t.Errorf("sample of request %d mixed with another request: time %v, value %v", id, mock.Times[i], mock.Vals[i])
+		}
+		if seen[id] {
+			t.Errorf("sample of request %d inserted twice", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestPGXInserterNumCopiers(t *testing.T) {
+	maxProcs := runtime.GOMAXPROCS(-1)
+	testCases := []struct {
+		name       string
+		numCopiers int
+		expected   int
+	}{
+		{
+			name:       "configured",
+			numCopiers: 3,
+			expected:   3,
+		},
+		{
+			name:     "default",
+			expected: maxProcs*ConnectionsPerProc - maxProcs,
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			inserter, err := newPgxInserter(&mockPGXConn{}, &mockMetricCache{}, &Cfg{NumCopiers: c.numCopiers})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer inserter.Close()
+
+			if cap(inserter.toCopiers) != c.expected {
+				t.Errorf("unexpected number of copiers: got %d, wanted %d", cap(inserter.toCopiers), c.expected)
+			}
+		})
+	}
+}
+
+func TestPGXInserterBatching(t *testing.T) {
+	testCases := []struct {
+		name        string
+		cfg         Cfg
+		requests    int
+		wantInserts int
+	}{
+		{
+			name:        "batched until max age",
+			cfg:         Cfg{MaxBatchAge: 200 * time.Millisecond},
+			requests:    5,
+			wantInserts: 1,
+		},
+		{
+			name:        "sent when full before max age",
+			cfg:         Cfg{MaxBatchSize: 2, MaxBatchAge: time.Hour},
+			requests:    4,
+			wantInserts: 2,
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			mock := &mockPGXConn{}
+			mockMetrics := &mockMetricCache{
+				metricCache: map[string]string{"metric_0": "metricTableName_0"},
+			}
+			inserter, err := newPgxInserter(mock, mockMetrics, &c.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var wg sync.WaitGroup
+			errs := make(chan error, c.requests)
+			for r := 0; r < c.requests; r++ {
+				wg.Add(1)
+				go func(r int) {
+					defer wg.Done()
+					rows := createRows(1)
+					rows["metric_0"][0].seriesID = SeriesID(r)
+					_, _, err := inserter.InsertData(context.Background(), rows)
+					errs <- err
+				}(r)
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			mock.insertLock.Lock()
+			defer mock.insertLock.Unlock()
+			if len(mock.InsertSQLs) != c.wantInserts {
+				t.Errorf("unexpected number of inserts: got %d, wanted %d", len(mock.InsertSQLs), c.wantInserts)
+			}
+			if len(mock.Series) != c.requests {
+				t.Errorf("unexpected number of samples inserted: got %d, wanted %d", len(mock.Series), c.requests)
+			}
+		})
+	}
+}
+
+func TestPGXInserterDuplicatePolicy(t *testing.T) {
+	type row struct {
+		series int64
+		ts     int64
+		val    float64
+	}
+	testCases := []struct {
+		name     string
+		policy   DuplicatePolicy
+		expected []row
+		err      error
+	}{
+		{
+			name:     "Keep all",
+			policy:   KeepDuplicates,
+			expected: []row{{1, 1, 2}, {1, 1, 4}, {1, 2, 5}, {1, 3, 1}, {1, 3, 3}, {2, 1, 6}},
+		},
+		{
+			name:     "Keep last",
+			policy:   KeepLastDuplicate,
+			expected: []row{{1, 1, 4}, {1, 2, 5}, {1, 3, 3}, {2, 1, 6}},
+		},
+		{
+			name:     "Keep first",
+			policy:   KeepFirstDuplicate,
+			expected: []row{{1, 1, 2}, {1, 2, 5}, {1, 3, 1}, {2, 1, 6}},
+		},
+		{
+			name:   "Error on conflict",
+			policy: RejectDuplicates,
+			err:    ErrDuplicateSample,
+		},
+	}
+	for _, co := range testCases {
+		c := co
+		t.Run(c.name, func(t *testing.T) {
+			mock := &mockPGXConn{
+				QueryResults: []rowResults{{{"metric_0", true}}, {{}}},
+			}
+			mockMetrics := &mockMetricCache{
+				metricCache: make(map[string]string),
+			}
+			inserter, err := newPgxInserter(mock, mockMetrics, &Cfg{DuplicatePolicy: c.policy})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			inserted, dropped, err := inserter.InsertData(context.Background(), createRowsWithDuplicates(t))
+			if c.err != nil {
+				if !errors.Is(err, c.err) {
+					t.Fatalf("unexpected error:\ngot\n%v\nwanted\n%s", err, c.err)
+				}
+				if len(mock.InsertSQLs) != 0 {
+					t.Errorf("samples inserted despite duplicates: %v", mock.InsertSQLs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			// the collapsed duplicates are reported apart from the rows
+			// inserted
+			if inserted != uint64(len(c.expected)) {
+				t.Errorf("unexpected number of rows reported: got %d, wanted %d", inserted, len(c.expected))
+			}
+			if expected := (DroppedSamples{Duplicates: 6 - uint64(len(c.expected))}); dropped != expected {
+				t.Errorf("unexpected dropped samples: got %+v, wanted %+v", dropped, expected)
+			}
+
+			got := make([]row, len(mock.Vals))
+			for i := range mock.Vals {
+				got[i] = row{mock.Series[i], mock.Times[i].UnixNano() / 1e6, mock.Vals[i]}
+			}
+			sort.Slice(got, func(i, j int) bool {
+				if got[i].series != got[j].series {
+					return got[i].series < got[j].series
+				}
+				if got[i].ts != got[j].ts {
+					return got[i].ts < got[j].ts
+				}
+				return got[i].val < got[j].val
+			})
+			if !reflect.DeepEqual(got, c.expected) {
+				t.Errorf("unexpected rows inserted:\ngot\n%v\nwanted\n%v", got, c.expected)
+			}
+		})
+	}
+}
+
+func TestPGXInserterOutOfOrder(t *testing.T) {
+	type row struct {
+		series int64
+		ts     int64
+		val    float64
+	}
+	testCases := []struct {
+		name     string
+		reject   bool
+		expected []row
+	}{
+		{
+			name:     "Detect only",
+			expected: []row{{1, 5, 1}, {1, 3, 2}, {1, 6, 3}, {1, 4, 4}, {1, 2, 5}, {1, 7, 6}, {2, 1, 7}, {2, 0, 8}},
+		},
+		{
+			name:     "Reject",
+			reject:   true,
+			expected: []row{{1, 5, 1}, {1, 6, 3}, {1, 7, 6}, {2, 1, 7}},
+		},
+	}
+	for _, co := range testCases {
+		c := co
+		t.Run(c.name, func(t *testing.T) {
+			mock := &mockPGXConn{
+				QueryResults: []rowResults{{{"metric_0", true}}, {{}}},
+			}
+			mockMetrics := &mockMetricCache{
+				metricCache: make(map[string]string),
+			}
+			inserter, err := newPgxInserter(mock, mockMetrics, &Cfg{RejectOutOfOrder: c.reject})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rows := createRows(3)
+			data := rows["metric_0"]
+			// the third row is a second batch of samples for the first series
+			data[2].labels = data[0].labels
+			data[0].seriesID, data[1].seriesID, data[2].seriesID = 1, 2, 1
+			data[0].samples = []prompb.Sample{{Timestamp: 5, Value: 1}, {Timestamp: 3, Value: 2}, {Timestamp: 6, Value: 3}, {Timestamp: 4, Value: 4}}
+			data[1].samples = []prompb.Sample{{Timestamp: 1, Value: 7}, {Timestamp: 0, Value: 8}}
+			data[2].samples = []prompb.Sample{{Timestamp: 2, Value: 5}, {Timestamp: 7, Value: 6}}
+
+			before := testutil.ToFloat64(outOfOrderSamples)
+			inserted, dropped, err := inserter.InsertData(context.Background(), rows)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if inserted != uint64(len(c.expected)) {
+				t.Errorf("unexpected number of rows reported: got %d, wanted %d", inserted, len(c.expected))
+			}
+			if expected := (DroppedSamples{OutOfOrder: 8 - uint64(len(c.expected))}); dropped != expected {
+				t.Errorf("unexpected dropped samples: got %+v, wanted %+v", dropped, expected)
+			}
+			if got := testutil.ToFloat64(outOfOrderSamples) - before; got != 4 {
+				t.Errorf("unexpected number of out-of-order samples counted: got %v, wanted 4", got)
+			}
+
+			got := make([]row, len(mock.Vals))
+			for i := range mock.Vals {
+				got[i] = row{mock.Series[i], mock.Times[i].UnixNano() / 1e6, mock.Vals[i]}
+			}
+			sort.SliceStable(got, func(i, j int) bool { return got[i].series < got[j].series })
+			if !reflect.DeepEqual(got, c.expected) {
+				t.Errorf("unexpected rows inserted:\ngot\n%v\nwanted\n%v", got, c.expected)
+			}
+		})
+	}
+}
+
+func TestPGXInserterInsertRetry(t *testing.T) {
+	serializationFailure := &pgconn.PgError{Code: "40001", Message: "could not serialize access"}
+	uniqueViolation := &pgconn.PgError{Code: "23505", Message: "duplicate key value"}
+	policy := &RetryPolicy{
+		MaxRetries:     2,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     2 * time.Millisecond,
+		RetryableCodes: map[string]bool{"40001": true},
+	}
+
+	testCases := []struct {
+		name         string
+		policy       *RetryPolicy
+		insertErrors []error
+		attempts     int
+		err          error
+	}{
+		{
+			name:     "No error",
+			policy:   policy,
+			attempts: 1,
+		},
+		{
+			name:         "No policy",
+			insertErrors: []error{serializationFailure},
+			attempts:     1,
+			err:          serializationFailure,
+		},
+		{
+			name:         "Transient error, recovered",
+			policy:       policy,
+			insertErrors: []error{serializationFailure, serializationFailure},
+			attempts:     3,
+		},
+		{
+			name:         "Transient error, retries exhausted",
+			policy:       policy,
+			insertErrors: []error{serializationFailure, serializationFailure, serializationFailure},
+			attempts:     3,
+			err:          serializationFailure,
+		},
+		{
+			name:         "Non-retryable error fails fast",
+			policy:       policy,
+			insertErrors: []error{uniqueViolation},
+			attempts:     1,
+			err:          uniqueViolation,
+		},
+		{
+			name:         "Non-postgres error fails fast",
+			policy:       policy,
+			insertErrors: []error{fmt.Errorf("some error")},
+			attempts:     1,
+			err:          fmt.Errorf("some error"),
+		},
+	}
+	for _, co := range testCases {
+		c := co
+		t.Run(c.name, func(t *testing.T) {
+			mock := &mockPGXConn{
+				QueryResults:   []rowResults{{{"metric_0", true}}, {{}}},
+				CopyFromErrors: c.insertErrors,
+			}
+			mockMetrics := &mockMetricCache{
+				metricCache: make(map[string]string),
+			}
+			inserter, err := newPgxInserter(mock, mockMetrics, &Cfg{InsertRetryPolicy: c.policy})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rows := createRows(1)
+			rows["metric_0"][0].samples = []prompb.Sample{{Timestamp: 1, Value: 1}, {Timestamp: 2, Value: 2}}
+			inserted, _, err := inserter.InsertData(context.Background(), rows)
+			if c.err != nil {
+				if err == nil || err.Error() != c.err.Error() {
+					t.Fatalf("unexpected error:\ngot\n%v\nwanted\n%s", err, c.err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if len(mock.InsertSQLs) != c.attempts {
+				t.Errorf("unexpected number of insert attempts: got %d, wanted %d", len(mock.InsertSQLs), c.attempts)
+			}
+			if inserted != 2 {
+				t.Errorf("unexpected number of rows reported: got %d, wanted 2", inserted)
+			}
+
+			expectedStored := 2
+			if c.err != nil {
+				expectedStored = 0
+			}
+			if len(mock.Vals) != expectedStored {
+				t.Errorf("unexpected number of rows stored: got %d, wanted %d", len(mock.Vals), expectedStored)
+			}
+		})
+	}
+}
+
+func TestPGXInserterEmptyInsert(t *testing.T) {
+	testCases := []struct {
+		name     string
+		policy   EmptyInsertPolicy
+		affected []int64
+		err      error
+		counted  float64
+	}{
+		{
+			name:     "ignored",
+			policy:   IgnoreEmptyInserts,
+			affected: []int64{0},
+		},
+		{
+			name:     "warning",
+			policy:   WarnEmptyInserts,
+			affected: []int64{0},
+			counted:  1,
+		},
+		{
+			name:     "error",
+			policy:   FailEmptyInserts,
+			affected: []int64{0},
+			err:      ErrNothingInserted,
+			counted:  1,
+		},
+		{
+			name:     "error, partial insert",
+			policy:   FailEmptyInserts,
+			affected: []int64{1},
+		},
+	}
+	for _, co := range testCases {
+		c := co
+		t.Run(c.name, func(t *testing.T) {
+			mock := &mockPGXConn{
+				InsertAffected: c.affected,
+			}
+			mockMetrics := &mockMetricCache{
+				metricCache: map[string]string{"metric_0": "metricTableName_0"},
+			}
+			inserter, err := newPgxInserter(mock, mockMetrics, &Cfg{EmptyInsertPolicy: c.policy})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			countedBefore := testutil.ToFloat64(emptyInserts)
+			rows := createRows(1)
+			rows["metric_0"][0].samples = []prompb.Sample{{Timestamp: 1, Value: 1}, {Timestamp: 2, Value: 2}}
+			_, _, err = inserter.InsertData(context.Background(), rows)
+			if !errors.Is(err, c.err) {
+				t.Fatalf("unexpected error: got %v, wanted %v", err, c.err)
+			}
+			if got := testutil.ToFloat64(emptyInserts) - countedBefore; got != c.counted {
+				t.Errorf("unexpected number of empty inserts counted: got %v, wanted %v", got, c.counted)
+			}
+		})
+	}
+}
+
+func TestParseEmptyInsertPolicy(t *testing.T) {
+	for name, policy := range emptyInsertPolicies {
+		got, err := ParseEmptyInsertPolicy(name)
+		if err != nil || got != policy || got.String() != name {
+			t.Errorf("unexpected policy for %q: got %v, %v", name, got, err)
+		}
+	}
+	if _, err := ParseEmptyInsertPolicy("drop"); err == nil {
+		t.Errorf("expected an error for an invalid policy")
+	}
+}
+
+func TestPGXInserterActiveActive(t *testing.T) {
+	rows := func() map[string][]samplesInfo {
+		series := func(id SeriesID, instance string) samplesInfo {
+			lset, _, err := labelProtosToLabels([]prompb.Label{
+				{Name: MetricNameLabelName, Value: "metric_0"},
+				{Name: "instance", Value: instance},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return samplesInfo{labels: lset, seriesID: id, samples: []prompb.Sample{{Timestamp: 1, Value: 1}, {Timestamp: 2, Value: 2}}}
+		}
+		return map[string][]samplesInfo{"metric_0": {series(1, "a"), series(2, "b")}}
+	}
+
+	// both writers share the database, each with an inserter of its own
+	mock := &mockPGXConn{UniqueSamples: make(map[string]bool)}
+	mockMetrics := &mockMetricCache{
+		metricCache: map[string]string{"metric_0": "metricTableName_0"},
+	}
+	duplicatesBefore := testutil.ToFloat64(duplicateSamples)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for w := range errs {
+		inserter, err := newPgxInserter(mock, mockMetrics, &Cfg{ConflictTarget: []string{"series_id", "time"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			_, _, errs[w] = inserter.InsertData(context.Background(), rows())
+		}(w)
+	}
+	wg.Wait()
+
+	for w, err := range errs {
+		if err != nil {
+			t.Errorf("unexpected error of writer %d: %v", w, err)
+		}
+	}
+	if len(mock.Times) != 8 {
+		t.Errorf("unexpected number of samples sent: got %d, wanted 8", len(mock.Times))
+	}
+	if len(mock.UniqueSamples) != 4 {
+		t.Errorf("unexpected number of samples stored: got %d, wanted 4", len(mock.UniqueSamples))
+	}
+	if got := testutil.ToFloat64(duplicateSamples) - duplicatesBefore; got != 4 {
+		t.Errorf("unexpected number of duplicates counted: got %v, wanted 4", got)
+	}
+	for _, sql := range mock.InsertSQLs {
+		if !strings.HasSuffix(sql, "ON CONFLICT (series_id, time) DO NOTHING") {
+			t.Errorf("unexpected insert: %s", sql)
+		}
+	}
+}
+
+func TestPGXInserterTimestampRange(t *testing.T) {
+	samples := func() []prompb.Sample {
+		return []prompb.Sample{
+			{Timestamp: minPostgresTimestamp - 1, Value: 1},
+			{Timestamp: -1000, Value: 2},
+			{Timestamp: 1, Value: 3},
+			{Timestamp: maxPostgresTimestamp + 1, Value: 4},
+		}
+	}
+	testCases := []struct {
+		name     string
+		policy   TimestampRangePolicy
+		err      error
+		expected []int64
+	}{
+		{
+			name:   "error",
+			policy: FailOutOfRange,
+			err:    ErrTimestampOutOfRange,
+		},
+		{
+			name:     "drop",
+			policy:   DropOutOfRange,
+			expected: []int64{-1000, 1},
+		},
+		{
+			name:     "clamp",
+			policy:   ClampOutOfRange,
+			expected: []int64{minPostgresTimestamp, -1000, 1, maxPostgresTimestamp},
+		},
+	}
+	for _, co := range testCases {
+		c := co
+		t.Run(c.name, func(t *testing.T) {
+			mock := &mockPGXConn{}
+			mockMetrics := &mockMetricCache{
+				metricCache: map[string]string{"metric_0": "metricTableName_0"},
+			}
+			inserter, err := newPgxInserter(mock, mockMetrics, &Cfg{TimestampRangePolicy: c.policy})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			countedBefore := testutil.ToFloat64(outOfRangeSamples)
+			rows := createRows(1)
+			rows["metric_0"][0].samples = samples()
+			count, dropped, err := inserter.InsertData(context.Background(), rows)
+			if !errors.Is(err, c.err) {
+				t.Fatalf("unexpected error: got %v, wanted %v", err, c.err)
+			}
+			if got := testutil.ToFloat64(outOfRangeSamples) - countedBefore; got != 2 {
+				t.Errorf("unexpected number of samples out of range: got %v, wanted 2", got)
+			}
+			if count != uint64(len(c.expected)) {
+				t.Errorf("unexpected number of rows reported: got %d, wanted %d", count, len(c.expected))
+			}
+			expectedDropped := DroppedSamples{}
+			if c.policy == DropOutOfRange {
+				expectedDropped.OutOfRange = 2
+			}
+			if dropped != expectedDropped {
+				t.Errorf("unexpected dropped samples: got %+v, wanted %+v", dropped, expectedDropped)
+			}
+
+			var inserted []int64
+			for _, ts := range mock.Times {
+				inserted = append(inserted, ts.Unix()*1000+int64(ts.Nanosecond())/int64(time.Millisecond))
+			}
+			if !reflect.DeepEqual(inserted, c.expected) {
+				t.Errorf("unexpected timestamps inserted: got %v, wanted %v", inserted, c.expected)
+			}
+		})
+	}
+}
+
+func TestParseTimestampRangePolicy(t *testing.T) {
+	for name, policy := range timestampRangePolicies {
+		got, err := ParseTimestampRangePolicy(name)
+		if err != nil || got != policy || got.String() != name {
+			t.Errorf("unexpected policy for %q: got %v, %v", name, got, err)
+		}
+	}
+	if _, err := ParseTimestampRangePolicy("ignore"); err == nil {
+		t.Errorf("expected an error for an invalid policy")
+	}
+}
+
+func TestPGXInserterNullValues(t *testing.T) {
+	staleNaN := math.Float64frombits(value.StaleNaN)
+	samples := []prompb.Sample{
+		{Timestamp: 1, Value: 1},
+		{Timestamp: 2, Value: -1},
+		{Timestamp: 3, Value: math.NaN()},
+		{Timestamp: 4, Value: staleNaN},
+		{Timestamp: 5, Value: 2},
+	}
+	testCases := []struct {
+		name       string
+		nullValues []float64
+		errs       []error
+		stored     int
+		expected   []int
+	}{
+		{
+			name:   "no null values",
+			stored: 5,
+		},
+		{
+			name:       "no sentinel in batch",
+			nullValues: []float64{-2},
+			stored:     5,
+		},
+		{
+			name:       "sentinels",
+			nullValues: []float64{-1, math.NaN()},
+			stored:     5,
+			expected:   []int{1, 2},
+		},
+		{
+			name:       "stale markers are kept",
+			nullValues: []float64{staleNaN},
+			stored:     5,
+		},
+		{
+			name:       "table without NULL values",
+			nullValues: []float64{-1, math.NaN()},
+			errs:       []error{&pgconn.PgError{Code: pgerrcode.NotNullViolation}},
+			stored:     3,
+		},
+	}
+	for _, co := range testCases {
+		c := co
+		t.Run(c.name, func(t *testing.T) {
+			mock := &mockPGXConn{
+				CopyFromErrors: c.errs,
+			}
+			mockMetrics := &mockMetricCache{
+				metricCache: map[string]string{"metric_0": "metricTableName_0"},
+			}
+			inserter, err := newPgxInserter(mock, mockMetrics, &Cfg{NullValues: c.nullValues})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rows := createRows(1)
+			rows["metric_0"][0].samples = samples
+			if _, _, err = inserter.InsertData(context.Background(), rows); err != nil {
+				t.Fatal(err)
+			}
+			if len(mock.Vals) != c.stored {
+				t.Fatalf("unexpected number of values inserted: got %d, wanted %d", len(mock.Vals), c.stored)
+			}
+			if !reflect.DeepEqual(mock.NullVals, c.expected) {
+				t.Errorf("unexpected NULL values: got %v, wanted %v", mock.NullVals, c.expected)
+			}
+		})
+	}
+}
+
+func TestPGXInserterDuplicateRows(t *testing.T) {
+	testCases := []struct {
+		name       string
+		affected   []int64
+		duplicates float64
+	}{
+		{
+			name:     "Fully inserted",
+			affected: []int64{5},
+		},
+		{
+			name:       "Duplicates skipped",
+			affected:   []int64{3},
+			duplicates: 2,
+		},
+		{
+			name:       "All duplicates",
+			affected:   []int64{0},
+			duplicates: 5,
+		},
+	}
+	for _, co := range testCases {
+		c := co
+		t.Run(c.name, func(t *testing.T) {
+			mock := &mockPGXConn{
+				InsertAffected: c.affected,
+			}
+			mockMetrics := &mockMetricCache{
+				metricCache: map[string]string{"metric_0": "metricTableName_0"},
+			}
+			inserter, err := newPgxInserter(mock, mockMetrics, &Cfg{InsertRetryPolicy: DefaultRetryPolicy(3)})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			duplicatesBefore := testutil.ToFloat64(duplicateSamples)
+			rows := createRows(1)
+			rows["metric_0"][0].samples = make([]prompb.Sample, 5)
+			for i := range rows["metric_0"][0].samples {
+				rows["metric_0"][0].samples[i] = prompb.Sample{Timestamp: int64(i), Value: float64(i)}
+			}
+			inserted, _, err := inserter.InsertData(context.Background(), rows)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if inserted != 5 {
+				t.Errorf("unexpected number of rows reported: got %d, wanted 5", inserted)
+			}
+
+			// the duplicates conflict with the stored rows, so the batch is
+			// never sent again
+			if len(mock.InsertSQLs) != 1 {
+				t.Errorf("unexpected number of insert attempts: got %d, wanted 1", len(mock.InsertSQLs))
+			}
+			if got := testutil.ToFloat64(duplicateSamples) - duplicatesBefore; got != c.duplicates {
+				t.Errorf("unexpected number of duplicates counted: got %v, wanted %v", got, c.duplicates)
+			}
+		})
+	}
+}
+
+func TestGroupByTimeBucket(t *testing.T) {
+	ms := func(ts ...int64) []time.Time {
+		times := make([]time.Time, len(ts))
+		for i := range ts {
+			times[i] = time.Unix(0, ts[i]*int64(time.Millisecond))
+		}
+		return times
+	}
+	rows := sampleRows{
+		times:  ms(2500, 100, 1200, -100, 999, 1000, 2999),
+		vals:   []float64{0, 1, 2, 3, 4, 5, 6},
+		series: []int64{1, 2, 1, 2, 1, 2, 1},
+	}
+
+	testCases := []struct {
+		name     string
+		width    time.Duration
+		expected []sampleRows
+	}{
+		{
+			name:     "disabled",
+			expected: []sampleRows{rows},
+		},
+		{
+			name:  "one second buckets",
+			width: time.Second,
+			expected: []sampleRows{
+				{times: ms(-100), vals: []float64{3}, series: []int64{2}},
+				{times: ms(100, 999), vals: []float64{1, 4}, series: []int64{2, 1}},
+				{times: ms(1200, 1000), vals: []float64{2, 5}, series: []int64{1, 2}},
+				{times: ms(2500, 2999), vals: []float64{0, 6}, series: []int64{1, 1}},
+			},
+		},
+		{
+			name:  "one bucket",
+			width: time.Hour,
+			expected: []sampleRows{
+				{times: ms(-100), vals: []float64{3}, series: []int64{2}},
+				{times: ms(2500, 100, 1200, 999, 1000, 2999), vals: []float64{0, 1, 2, 4, 5, 6}, series: []int64{1, 2, 1, 1, 2, 1}},
+			},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			groups := groupByTimeBucket(rows, c.width)
+			if !reflect.DeepEqual(groups, c.expected) {
+				t.Errorf("unexpected groups:\ngot\n%v\nwanted\n%v", groups, c.expected)
+			}
+		})
+	}
+
+	t.Run("times out of the nanosecond range", func(t *testing.T) {
+		// the times clamped into the range of a TIMESTAMPTZ
+		day := 24 * time.Hour.Milliseconds()
+		bounds := sampleRows{
+			times:  []time.Time{timestamp.Time(maxPostgresTimestamp), timestamp.Time(minPostgresTimestamp), timestamp.Time(maxPostgresTimestamp - day), timestamp.Time(0)},
+			vals:   []float64{0, 1, 2, 3},
+			series: []int64{1, 1, 1, 1},
+		}
+		expected := []sampleRows{
+			{times: bounds.times[1:2], vals: []float64{1}, series: []int64{1}},
+			{times: bounds.times[3:4], vals: []float64{3}, series: []int64{1}},
+			{times: bounds.times[2:3], vals: []float64{2}, series: []int64{1}},
+			{times: bounds.times[0:1], vals: []float64{0}, series: []int64{1}},
+		}
+		groups := groupByTimeBucket(bounds, time.Hour)
+		if !reflect.DeepEqual(groups, expected) {
+			t.Errorf("unexpected groups:\ngot\n%v\nwanted\n%v", groups, expected)
+		}
+	})
+}
+
+func TestPGXInserterInsertTimeBucket(t *testing.T) {
+	mock := &mockPGXConn{}
+	mockMetrics := &mockMetricCache{
+		metricCache: map[string]string{"metric_0": "metricTableName_0"},
+	}
+	inserter, err := newPgxInserter(mock, mockMetrics, &Cfg{InsertTimeBucket: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hour := time.Hour.Milliseconds()
+	rows := createRows(2)
+	rows["metric_0"][0].seriesID = 1
+	rows["metric_0"][0].samples = []prompb.Sample{{Timestamp: 2*hour + 1, Value: 1}, {Timestamp: 10, Value: 2}}
+	rows["metric_0"][1].seriesID = 2
+	rows["metric_0"][1].samples = []prompb.Sample{{Timestamp: hour - 1, Value: 3}, {Timestamp: 2 * hour, Value: 4}}
+	if _, _, err = inserter.InsertData(context.Background(), rows); err != nil {
+		t.Fatal(err)
+	}
+
+	// one insert per hour, earliest first
+	expected := [][]int64{{10, hour - 1}, {2*hour + 1, 2 * hour}}
+	mock.insertLock.Lock()
+	defer mock.insertLock.Unlock()
+	if len(mock.InsertArgs) != len(expected) {
+		t.Fatalf("unexpected number of inserts: got %d, wanted %d", len(mock.InsertArgs), len(expected))
+	}
+	for i, args := range mock.InsertArgs {
+		times := args[0].([]time.Time)
+		got := make([]int64, len(times))
+		for j := range times {
+			got[j] = toMilis(times[j])
+		}
+		if !reflect.DeepEqual(got, expected[i]) {
+			t.Errorf("unexpected times of insert %d: got %v, wanted %v", i, got, expected[i])
+		}
+	}
+}
+
+func TestPGXInserterInsertTimeBucketRetry(t *testing.T) {
+	serializationFailure := &pgconn.PgError{Code: "40001", Message: "could not serialize access"}
+	mock := &mockPGXConn{
+		// the second time bucket fails once
+		CopyFromErrors: []error{nil, serializationFailure},
+		UniqueSamples:  make(map[string]bool),
+	}
+	mockMetrics := &mockMetricCache{
+		metricCache: map[string]string{"metric_0": "metricTableName_0"},
+	}
+	policy := &RetryPolicy{
+		MaxRetries:     1,
+		InitialBackoff: time.Millisecond,
+		RetryableCodes: map[string]bool{"40001": true},
+	}
+	inserter, err := newPgxInserter(mock, mockMetrics, &Cfg{InsertTimeBucket: time.Hour, InsertRetryPolicy: policy})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hour := time.Hour.Milliseconds()
+	rows := createRows(1)
+	rows["metric_0"][0].samples = []prompb.Sample{{Timestamp: 10, Value: 1}, {Timestamp: hour + 10, Value: 2}}
+	if _, _, err = inserter.InsertData(context.Background(), rows); err != nil {
+		t.Fatal(err)
+	}
+
+	// the committed first time bucket is not sent again
+	expected := [][]int64{{10}, {hour + 10}, {hour + 10}}
+	mock.insertLock.Lock()
+	defer mock.insertLock.Unlock()
+	if len(mock.InsertArgs) != len(expected) {
+		t.Fatalf("unexpected number of inserts: got %d, wanted %d", len(mock.InsertArgs), len(expected))
+	}
+	for i, args := range mock.InsertArgs {
+		times := args[0].([]time.Time)
+		got := make([]int64, len(times))
+		for j := range times {
+			got[j] = toMilis(times[j])
+		}
+		if !reflect.DeepEqual(got, expected[i]) {
+			t.Errorf("unexpected times of insert %d: got %v, wanted %v", i, got, expected[i])
+		}
+	}
+}
+
+func TestPGXInserterSortRows(t *testing.T) {
+	mock := &mockPGXConn{}
+	mockMetrics := &mockMetricCache{
+		metricCache: map[string]string{"metric_0": "metricTableName_0"},
+	}
+	inserter, err := newPgxInserter(mock, mockMetrics, &Cfg{SortRows: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows := createRows(2)
+	rows["metric_0"][0].seriesID = 2
+	rows["metric_0"][0].samples = []prompb.Sample{{Timestamp: 3, Value: 0}, {Timestamp: 1, Value: 1}, {Timestamp: 3, Value: 2}}
+	rows["metric_0"][1].seriesID = 1
+	rows["metric_0"][1].samples = []prompb.Sample{{Timestamp: 2, Value: 3}, {Timestamp: 2, Value: 4}}
+	count, _, err := inserter.InsertData(context.Background(), rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 5 {
+		t.Errorf("unexpected count: got %d, wanted 5", count)
+	}
+
+	// samples at the same time keep the order they were received in
+	expectedTimes := []int64{2, 2, 1, 3, 3}
+	expectedVals := []float64{3, 4, 1, 0, 2}
+	expectedSeries := []int64{1, 1, 2, 2, 2}
+	mock.insertLock.Lock()
+	defer mock.insertLock.Unlock()
+	if len(mock.InsertArgs) != 1 {
+		t.Fatalf("unexpected number of inserts: got %d, wanted 1", len(mock.InsertArgs))
+	}
+	args := mock.InsertArgs[0]
+	times := args[0].([]time.Time)
+	gotTimes := make([]int64, len(times))
+	for i := range times {
+		gotTimes[i] = toMilis(times[i])
+	}
+	if !reflect.DeepEqual(gotTimes, expectedTimes) {
+		t.Errorf("unexpected times: got %v, wanted %v", gotTimes, expectedTimes)
+	}
+	if !reflect.DeepEqual(args[1], expectedVals) {
+		t.Errorf("unexpected values: got %v, wanted %v", args[1], expectedVals)
+	}
+	if !reflect.DeepEqual(args[2], expectedSeries) {
+		t.Errorf("unexpected series: got %v, wanted %v", args[2], expectedSeries)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := &RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	expected := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	}
+	for attempt, e := range expected {
+		if got := p.backoff(attempt); got != e {
+			t.Errorf("unexpected backoff for attempt %d: got %s, wanted %s", attempt, got, e)
+		}
+	}
+}
+
+func TestPGXInserterConflictTarget(t *testing.T) {
+	testCases := []struct {
+		name           string
+		conflictTarget []string
+		expectedSQL    string
+		err            error
+	}{
+		{
+			name:        "Default target",
+			expectedSQL: `INSERT INTO "prom_data"."metric_0"(time, value, series_id) SELECT * FROM unnest($1::TIMESTAMPTZ[], $2::DOUBLE PRECISION[], $3::BIGINT[]) a ON CONFLICT DO NOTHING`,
+		},
+		{
+			name:           "Series and time target",
+			conflictTarget: []string{"series_id", "time"},
+			expectedSQL:    `INSERT INTO "prom_data"."metric_0"(time, value, series_id) SELECT * FROM unnest($1::TIMESTAMPTZ[], $2::DOUBLE PRECISION[], $3::BIGINT[]) a ON CONFLICT (series_id, time) DO NOTHING`,
+		},
+		{
+			name:           "Unknown column",
+			conflictTarget: []string{"series_id", "time", "flags"},
+			err:            fmt.Errorf(`invalid conflict target column "flags"`),
+		},
+		{
+			name:           "Duplicate column",
+			conflictTarget: []string{"time", "time"},
+			err:            fmt.Errorf(`duplicate conflict target column "time"`),
+		},
+	}
+	for _, co := range testCases {
+		c := co
+		t.Run(c.name, func(t *testing.T) {
+			mock := &mockPGXConn{
+				QueryResults: []rowResults{{{"metric_0", true}}, {{}}},
+			}
+			mockMetrics := &mockMetricCache{
+				metricCache: make(map[string]string),
+			}
+			inserter, err := newPgxInserter(mock, mockMetrics, &Cfg{ConflictTarget: c.conflictTarget})
+			if c.err != nil {
+				if err == nil || err.Error() != c.err.Error() {
+					t.Fatalf("unexpected error:\ngot\n%v\nwanted\n%s", err, c.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, _, err = inserter.InsertData(context.Background(), createRows(1)); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(mock.InsertSQLs, []string{c.expectedSQL}) {
+				t.Errorf("unexpected insert sql:\ngot\n%v\nwanted\n%v", mock.InsertSQLs, c.expectedSQL)
+			}
+		})
+	}
+}
+
+func TestPGXInserterInsertTarget(t *testing.T) {
+	testCases := []struct {
+		name           string
+		target         InsertTarget
+		conflictTarget []string
+		expectedTable  string
+		expectedSQL    string
+		err            error
+	}{
+		{
+			name:          "Zero value",
+			expectedTable: `"prom_data"."metric_0"`,
+			expectedSQL:   `INSERT INTO "prom_data"."metric_0"(time, value, series_id) SELECT * FROM unnest($1::TIMESTAMPTZ[], $2::DOUBLE PRECISION[], $3::BIGINT[]) a ON CONFLICT DO NOTHING`,
+		},
+		{
+			name:           "Explicit schema and columns",
+			target:         InsertTarget{Schema: "custom_data", TimeColumn: "ts", ValueColumn: "val", SeriesIDColumn: "sid"},
+			conflictTarget: []string{"series_id", "time"},
+			expectedTable:  `"custom_data"."metric_0"`,
+			expectedSQL:    `INSERT INTO "custom_data"."metric_0"(ts, val, sid) SELECT * FROM unnest($1::TIMESTAMPTZ[], $2::DOUBLE PRECISION[], $3::BIGINT[]) a ON CONFLICT (sid, ts) DO NOTHING`,
+		},
+		{
+			name:          "Explicit value column",
+			target:        InsertTarget{ValueColumn: "val"},
+			expectedTable: `"prom_data"."metric_0"`,
+			expectedSQL:   `INSERT INTO "prom_data"."metric_0"(time, val, series_id) SELECT * FROM unnest($1::TIMESTAMPTZ[], $2::DOUBLE PRECISION[], $3::BIGINT[]) a ON CONFLICT DO NOTHING`,
+		},
+		{
+			name:   "Invalid column",
+			target: InsertTarget{TimeColumn: "ts; DROP TABLE foo"},
+			err:    fmt.Errorf(`invalid time column name "ts; DROP TABLE foo"`),
+		},
+	}
+	for _, co := range testCases {
+		c := co
+		t.Run(c.name, func(t *testing.T) {
+			mock := &mockPGXConn{
+				QueryResults: []rowResults{{{"metric_0", true}}, {{}}},
+			}
+			mockMetrics := &mockMetricCache{
+				metricCache: make(map[string]string),
+			}
+			inserter, err := newPgxInserter(mock, mockMetrics, &Cfg{InsertTarget: c.target, ConflictTarget: c.conflictTarget})
+			if c.err != nil {
+				if err == nil || err.Error() != c.err.Error() {
+					t.Fatalf("unexpected error:\ngot\n%v\nwanted\n%s", err, c.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, _, err = inserter.InsertData(context.Background(), createRows(1)); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(mock.CopyFromTableName, []string{c.expectedTable}) {
+				t.Errorf("unexpected insert table:\ngot\n%v\nwanted\n%v", mock.CopyFromTableName, c.expectedTable)
+			}
+			if !reflect.DeepEqual(mock.InsertSQLs, []string{c.expectedSQL}) {
+				t.Errorf("unexpected insert sql:\ngot\n%v\nwanted\n%v", mock.InsertSQLs, c.expectedSQL)
+			}
+		})
+	}
+}
+
+func TestPGXQuerierQuery(t *testing.T) {
+	testCases := []struct {
+		name           string
+		query          *prompb.Query
+		allowUnbounded bool
+		result         []*prompb.TimeSeries
+		err            error
+		sqlQueries     []string // XXX whitespace in these is significant
+		sqlArgs        [][]interface{}
+		queryResults   []rowResults
+		queryErr       map[int]error
+	}{
+		{
+			name: "Error metric name value",
+			query: &prompb.Query{
+				StartTimestampMs: 1000,
+				EndTimestampMs:   2000,
+				Matchers: []*prompb.LabelMatcher{
+					{Type: prompb.LabelMatcher_NEQ, Name: MetricNameLabelName, Value: "bar"},
+				},
+			},
+			sqlQueries: []string{`SELECT m.metric_name, array_agg(s.id)
+	FROM _prom_catalog.series s
+	INNER JOIN _prom_catalog.metric m
+	ON (m.id = s.metric_id)
+	WHERE NOT labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $1 and l.value = $2)
+	GROUP BY m.metric_name
+	ORDER BY m.metric_name`},
+			sqlArgs: [][]interface{}{{MetricNameLabelName, "bar"}},
+			queryResults: []rowResults{
+				{{1, []int64{}}},
+			},
+			err: fmt.Errorf("wrong value type int"),
+		},
+		{
+			name: "Error first query",
+			query: &prompb.Query{
+				StartTimestampMs: 1000,
+				EndTimestampMs:   2000,
+				Matchers: []*prompb.LabelMatcher{
+					{Type: prompb.LabelMatcher_NEQ, Name: MetricNameLabelName, Value: "bar"},
+				},
+			},
+			sqlQueries: []string{`SELECT m.metric_name, array_agg(s.id)
+	FROM _prom_catalog.series s
+	INNER JOIN _prom_catalog.metric m
+	ON (m.id = s.metric_id)
+	WHERE NOT labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $1 and l.value = $2)
+	GROUP BY m.metric_name
+	ORDER BY m.metric_name`},
+			sqlArgs: [][]interface{}{{"__name__", "bar"}},
+			queryResults: []rowResults{
+				{{"{}", []time.Time{}, []float64{}}},
+			},
+			queryErr: map[int]error{0: fmt.Errorf("some error")},
+		},
+		{
+			name: "Error second query",
+			query: &prompb.Query{
+				StartTimestampMs: 1000,
+				EndTimestampMs:   2000,
+				Matchers: []*prompb.LabelMatcher{
+					{Type: prompb.LabelMatcher_NEQ, Name: "foo", Value: "bar"},
+				},
+			},
+			sqlQueries: []string{`SELECT m.metric_name, array_agg(s.id)
+	FROM _prom_catalog.series s
+	INNER JOIN _prom_catalog.metric m
+	ON (m.id = s.metric_id)
+	WHERE NOT labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $1 and l.value = $2)
+	GROUP BY m.metric_name
+	ORDER BY m.metric_name`,
+				`SELECT table_name FROM _prom_catalog.get_metric_table_name_if_exists($1)`},
+			sqlArgs: [][]interface{}{
+				{"foo", "bar"},
+				{"foo"},
+			},
+			queryResults: []rowResults{
+				{{`foo`, []int64{1}}},
+			},
+			queryErr: map[int]error{1: fmt.Errorf("some error")},
+		},
+		{
+			name: "Error third query",
+			query: &prompb.Query{
+				StartTimestampMs: 1000,
+				EndTimestampMs:   2000,
+				Matchers: []*prompb.LabelMatcher{
+					{Type: prompb.LabelMatcher_NEQ, Name: "foo", Value: "bar"},
+				},
+			},
+			sqlQueries: []string{`SELECT m.metric_name, array_agg(s.id)
+	FROM _prom_catalog.series s
+	INNER JOIN _prom_catalog.metric m
+	ON (m.id = s.metric_id)
+	WHERE NOT labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $1 and l.value = $2)
+	GROUP BY m.metric_name
+	ORDER BY m.metric_name`,
+				`SELECT table_name FROM _prom_catalog.get_metric_table_name_if_exists($1)`,
+				`SELECT s.labels, array_agg(m.time ORDER BY time), array_agg(m.value ORDER BY time)
+	FROM "prom_data"."foo" m
+	INNER JOIN "prom_data_series"."foo" s
+	ON m.series_id = s.id
+	WHERE m.series_id IN (1)
+	AND time >= '1970-01-01T00:00:01Z'
+	AND time <= '1970-01-01T00:00:02Z'
+	GROUP BY s.id`},
+			sqlArgs: [][]interface{}{
+				{"foo", "bar"},
+				{"foo"},
+				nil,
+			},
+			queryResults: []rowResults{
+				{{`foo`, []int64{1}}},
+				{{"foo"}},
+			},
+			queryErr: map[int]error{2: fmt.Errorf("some error")},
+		},
+		{
+			name: "Error scan values",
+			query: &prompb.Query{
+				StartTimestampMs: 1000,
+				EndTimestampMs:   2000,
+				Matchers: []*prompb.LabelMatcher{
+					{Type: prompb.LabelMatcher_NEQ, Name: MetricNameLabelName, Value: "bar"},
+				},
+			},
+			sqlQueries: []string{`SELECT m.metric_name, array_agg(s.id)
+	FROM _prom_catalog.series s
+	INNER JOIN _prom_catalog.metric m
+	ON (m.id = s.metric_id)
+	WHERE NOT labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $1 and l.value = $2)
+	GROUP BY m.metric_name
+	ORDER BY m.metric_name`},
+			sqlArgs: [][]interface{}{{"__name__", "bar"}},
+			queryResults: []rowResults{
+				{{0}},
+			},
+			err: fmt.Errorf("scanning error, missing results for scanning: got 1 wanted 2"),
+		},
+		{
+			name:   "Empty query",
+			result: []*prompb.TimeSeries{},
+		},
+		{
+			name: "Simple query, no result",
+			query: &prompb.Query{
+				StartTimestampMs: 1000,
+				EndTimestampMs:   2000,
+				Matchers: []*prompb.LabelMatcher{
+					{Type: prompb.LabelMatcher_EQ, Name: "foo", Value: "bar"},
+				},
+			},
+			sqlQueries: []string{`SELECT m.metric_name, array_agg(s.id)
+	FROM _prom_catalog.series s
+	INNER JOIN _prom_catalog.metric m
+	ON (m.id = s.metric_id)
+	WHERE labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $1 and l.value = $2)
+	GROUP BY m.metric_name
+	ORDER BY m.metric_name`},
+			sqlArgs:      [][]interface{}{{"foo", "bar"}},
+			result:       []*prompb.TimeSeries{},
+			queryResults: []rowResults{},
+		},
+		{
+			name: "Simple query, metric doesn't exist",
+			query: &prompb.Query{
+				StartTimestampMs: 1000,
+				EndTimestampMs:   2000,
+				Matchers: []*prompb.LabelMatcher{
+					{Type: prompb.LabelMatcher_EQ, Name: MetricNameLabelName, Value: "bar"},
+				},
+			},
+			sqlQueries: []string{`SELECT table_name FROM _prom_catalog.get_metric_table_name_if_exists($1)`},
+			sqlArgs: [][]interface{}{
+				{"bar"},
+			},
+			result:       []*prompb.TimeSeries{},
+			queryResults: []rowResults{},
+		},
+		{
+			name: "Simple query, exclude matcher",
+			query: &prompb.Query{
+				StartTimestampMs: 1000,
+				EndTimestampMs:   2000,
+				Matchers: []*prompb.LabelMatcher{
+					{Type: prompb.LabelMatcher_NEQ, Name: MetricNameLabelName, Value: "bar"},
+				},
+			},
+			sqlQueries: []string{`SELECT m.metric_name, array_agg(s.id)
+	FROM _prom_catalog.series s
+	INNER JOIN _prom_catalog.metric m
+	ON (m.id = s.metric_id)
+	WHERE NOT labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $1 and l.value = $2)
+	GROUP BY m.metric_name
+	ORDER BY m.metric_name`,
+				`SELECT table_name FROM _prom_catalog.get_metric_table_name_if_exists($1)`,
+				`SELECT s.labels, array_agg(m.time ORDER BY time), array_agg(m.value ORDER BY time)
+	FROM "prom_data"."foo" m
+	INNER JOIN "prom_data_series"."foo" s
+	ON m.series_id = s.id
+	WHERE m.series_id IN (1)
+	AND time >= '1970-01-01T00:00:01Z'
+	AND time <= '1970-01-01T00:00:02Z'
+	GROUP BY s.id`,
+				"SELECT (labels_info($1::int[])).*"},
+			sqlArgs: [][]interface{}{
+				{"__name__", "bar"},
+				{"foo"},
+				nil,
+				{[]int64{1}},
+			},
+			result: []*prompb.TimeSeries{
+				{
+					Labels:  []prompb.Label{{Name: MetricNameLabelName, Value: "foo"}},
+					Samples: []prompb.Sample{{Timestamp: toMilis(time.Unix(0, 0)), Value: 1}},
+				},
+			},
+			queryResults: []rowResults{
+				{{`foo`, []int64{1}}},
+				{{"foo"}},
+				{{[]int64{1}, []time.Time{time.Unix(0, 0)}, []float64{1}}},
+				{{[]int64{1}, []string{"__name__"}, []string{"foo"}}},
+			},
+		},
+		{
+			name: "Simple query, metric name matcher",
+			query: &prompb.Query{
+				StartTimestampMs: 1000,
+				EndTimestampMs:   2000,
+				Matchers: []*prompb.LabelMatcher{
+					{Type: prompb.LabelMatcher_EQ, Name: MetricNameLabelName, Value: "bar"},
+				},
+			},
+			sqlQueries: []string{`SELECT table_name FROM _prom_catalog.get_metric_table_name_if_exists($1)`,
+				`SELECT s.labels, array_agg(m.time ORDER BY time) as time_array, array_agg(m.value ORDER BY time)
+	FROM "prom_data"."bar" m
+	INNER JOIN "prom_data_series"."bar" s
+	ON m.series_id = s.id
+	WHERE TRUE
+	AND time >= '1970-01-01T00:00:01Z'
+	AND time <= '1970-01-01T00:00:02Z'
+	GROUP BY s.id`,
+				"SELECT (labels_info($1::int[])).*"},
+			sqlArgs: [][]interface{}{
+				{"bar"},
+				nil,
+				{[]int64{2}},
+			},
+			result: []*prompb.TimeSeries{
+				{
+					Labels:  []prompb.Label{{Name: MetricNameLabelName, Value: "bar"}},
+					Samples: []prompb.Sample{{Timestamp: toMilis(time.Unix(0, 0)), Value: 1}},
+				},
+			},
+			queryResults: []rowResults{
+				{{"bar"}},
+				{{[]int64{2}, []time.Time{time.Unix(0, 0)}, []float64{1}}},
+				{{[]int64{2}, []string{"__name__"}, []string{"bar"}}},
+			},
+		},
+		{
+			name: "Empty matcher set, rejected",
+			query: &prompb.Query{
+				StartTimestampMs: 1000,
+				EndTimestampMs:   2000,
+			},
+			err: ErrUnboundedQuery,
+		},
+		{
+			name: "Empty metric name matcher, rejected",
+			query: &prompb.Query{
+				StartTimestampMs: 1000,
+				EndTimestampMs:   2000,
+				Matchers: []*prompb.LabelMatcher{
+					{Type: prompb.LabelMatcher_RE, Name: MetricNameLabelName, Value: ""},
+				},
+			},
+			err: ErrUnboundedQuery,
+		},
+		{
+			name: "Any metric name matcher, rejected",
+			query: &prompb.Query{
+				StartTimestampMs: 1000,
+				EndTimestampMs:   2000,
+				Matchers: []*prompb.LabelMatcher{
+					{Type: prompb.LabelMatcher_NEQ, Name: MetricNameLabelName, Value: ""},
+				},
+			},
+			err: ErrUnboundedQuery,
+		},
+		{
+			name: "Non-empty metric name matcher, rejected",
+			query: &prompb.Query{
+				StartTimestampMs: 1000,
+				EndTimestampMs:   2000,
+				Matchers: []*prompb.LabelMatcher{
+					{Type: prompb.LabelMatcher_RE, Name: MetricNameLabelName, Value: ".+"},
+				},
+			},
+			err: ErrUnboundedQuery,
+		},
+		{
+			name: "Empty matcher set, allowed",
+			query: &prompb.Query{
+				StartTimestampMs: 1000,
+				EndTimestampMs:   2000,
+			},
+			allowUnbounded: true,
+			sqlQueries: []string{`SELECT m.metric_name, array_agg(s.id)
+	FROM _prom_catalog.series s
+	INNER JOIN _prom_catalog.metric m
+	ON (m.id = s.metric_id)
+	WHERE NOT labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $1 and l.value !~ $2)
+	GROUP BY m.metric_name
+	ORDER BY m.metric_name`,
+				`SELECT table_name FROM _prom_catalog.get_metric_table_name_if_exists($1)`,
+				`SELECT s.labels, array_agg(m.time ORDER BY time), array_agg(m.value ORDER BY time)
+	FROM "prom_data"."foo" m
+	INNER JOIN "prom_data_series"."foo" s
+	ON m.series_id = s.id
+	WHERE m.series_id IN (1)
+	AND time >= '1970-01-01T00:00:01Z'
+	AND time <= '1970-01-01T00:00:02Z'
+	GROUP BY s.id`,
+				"SELECT (labels_info($1::int[])).*"},
+			sqlArgs: [][]interface{}{
+				{"__name__", "^(?:.*)$"},
+				{"foo"},
+				nil,
+				{[]int64{3}},
+			},
+			result: []*prompb.TimeSeries{
+				{
+					Labels:  []prompb.Label{{Name: MetricNameLabelName, Value: "foo"}},
+					Samples: []prompb.Sample{{Timestamp: toMilis(time.Unix(0, 0)), Value: 1}},
+				},
+			},
+			queryResults: []rowResults{
+				{{"foo", []int64{1}}},
+				{{"foo"}},
+				{{[]int64{3}, []time.Time{time.Unix(0, 0)}, []float64{1}}},
+				{{[]int64{3}, []string{"__name__"}, []string{"foo"}}},
+			},
+		},
+		{
+			name: "Simple query, empty metric name matcher",
+			query: &prompb.Query{
+				StartTimestampMs: 1000,
+				EndTimestampMs:   2000,
+				Matchers: []*prompb.LabelMatcher{
+					{Type: prompb.LabelMatcher_RE, Name: MetricNameLabelName, Value: ""},
+				},
+			},
+			allowUnbounded: true,
+			sqlQueries: []string{`SELECT m.metric_name, array_agg(s.id)
+	FROM _prom_catalog.series s
+	INNER JOIN _prom_catalog.metric m
+	ON (m.id = s.metric_id)
+	WHERE NOT labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $1 and l.value !~ $2)
+	GROUP BY m.metric_name
+	ORDER BY m.metric_name`,
+				`SELECT table_name FROM _prom_catalog.get_metric_table_name_if_exists($1)`,
+				`SELECT s.labels, array_agg(m.time ORDER BY time), array_agg(m.value ORDER BY time)
+	FROM "prom_data"."foo" m
+	INNER JOIN "prom_data_series"."foo" s
+	ON m.series_id = s.id
+	WHERE m.series_id IN (1)
+	AND time >= '1970-01-01T00:00:01Z'
+	AND time <= '1970-01-01T00:00:02Z'
+	GROUP BY s.id`,
+				`SELECT table_name FROM _prom_catalog.get_metric_table_name_if_exists($1)`,
+				`SELECT s.labels, array_agg(m.time ORDER BY time), array_agg(m.value ORDER BY time)
+	FROM "prom_data"."bar" m
+	INNER JOIN "prom_data_series"."bar" s
+	ON m.series_id = s.id
+	WHERE m.series_id IN (1)
+	AND time >= '1970-01-01T00:00:01Z'
+	AND time <= '1970-01-01T00:00:02Z'
+	GROUP BY s.id`,
+				"SELECT (labels_info($1::int[])).*",
+				"SELECT (labels_info($1::int[])).*"},
+			sqlArgs: [][]interface{}{
+				{"__name__", "^$"},
+				{"foo"},
+				nil,
+				{"bar"},
+				nil,
+				{[]int64{3}},
+				{[]int64{4}},
+			},
+			result: []*prompb.TimeSeries{
+				{
+					Labels:  []prompb.Label{{Name: MetricNameLabelName, Value: "foo"}},
+					Samples: []prompb.Sample{{Timestamp: toMilis(time.Unix(0, 0)), Value: 1}},
+				},
+				{
+					Labels:  []prompb.Label{{Name: MetricNameLabelName, Value: "bar"}},
+					Samples: []prompb.Sample{{Timestamp: toMilis(time.Unix(0, 0)), Value: 1}},
+				},
+			},
+			queryResults: []rowResults{
+				{{"foo", []int64{1}}, {"bar", []int64{1}}},
+				{{"foo"}},
+				{{[]int64{3}, []time.Time{time.Unix(0, 0)}, []float64{1}}},
+				{{"bar"}},
+				{{[]int64{4}, []time.Time{time.Unix(0, 0)}, []float64{1}}},
+				{{[]int64{3}, []string{"__name__"}, []string{"foo"}}},
+				{{[]int64{4}, []string{"__name__"}, []string{"bar"}}},
+			},
+		},
+		{
+			name: "Simple query, double metric name matcher",
+			query: &prompb.Query{
+				StartTimestampMs: 1000,
+				EndTimestampMs:   2000,
+				Matchers: []*prompb.LabelMatcher{
+					{Type: prompb.LabelMatcher_EQ, Name: MetricNameLabelName, Value: "foo"},
+					{Type: prompb.LabelMatcher_EQ, Name: MetricNameLabelName, Value: "bar"},
+				},
+			},
+			sqlQueries: []string{`SELECT m.metric_name, array_agg(s.id)
+	FROM _prom_catalog.series s
+	INNER JOIN _prom_catalog.metric m
+	ON (m.id = s.metric_id)
+	WHERE labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $1 and l.value = $2) AND labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $3 and l.value = $4)
+	GROUP BY m.metric_name
+	ORDER BY m.metric_name`,
+				`SELECT table_name FROM _prom_catalog.get_metric_table_name_if_exists($1)`,
+				`SELECT s.labels, array_agg(m.time ORDER BY time), array_agg(m.value ORDER BY time)
+	FROM "prom_data"."foo" m
+	INNER JOIN "prom_data_series"."foo" s
+	ON m.series_id = s.id
+	WHERE m.series_id IN (1)
+	AND time >= '1970-01-01T00:00:01Z'
+	AND time <= '1970-01-01T00:00:02Z'
+	GROUP BY s.id`,
+				`SELECT table_name FROM _prom_catalog.get_metric_table_name_if_exists($1)`,
+				`SELECT s.labels, array_agg(m.time ORDER BY time), array_agg(m.value ORDER BY time)
+	FROM "prom_data"."bar" m
+	INNER JOIN "prom_data_series"."bar" s
+	ON m.series_id = s.id
+	WHERE m.series_id IN (1)
+	AND time >= '1970-01-01T00:00:01Z'
+	AND time <= '1970-01-01T00:00:02Z'
+	GROUP BY s.id`,
+				"SELECT (labels_info($1::int[])).*",
+				"SELECT (labels_info($1::int[])).*"},
+			sqlArgs: [][]interface{}{
+				{"__name__", "foo", "__name__", "bar"},
+				{"foo"},
+				nil,
+				{"bar"},
+				nil,
+				{[]int64{5}},
+				{[]int64{6}},
+			},
+			result: []*prompb.TimeSeries{
+				{
+					Labels:  []prompb.Label{{Name: MetricNameLabelName, Value: "foo"}},
+					Samples: []prompb.Sample{{Timestamp: toMilis(time.Unix(0, 0)), Value: 1}},
+				},
+				{
+					Labels:  []prompb.Label{{Name: MetricNameLabelName, Value: "bar"}},
+					Samples: []prompb.Sample{{Timestamp: toMilis(time.Unix(0, 0)), Value: 1}},
+				},
+			},
+			queryResults: []rowResults{
+				{{"foo", []int64{1}}, {"bar", []int64{1}}},
+				{{"foo"}},
+				{{[]int64{5}, []time.Time{time.Unix(0, 0)}, []float64{1}}},
+				{{"bar"}},
+				{{[]int64{6}, []time.Time{time.Unix(0, 0)}, []float64{1}}},
+				{{[]int64{5}, []string{"__name__"}, []string{"foo"}}},
+				{{[]int64{6}, []string{"__name__"}, []string{"bar"}}},
+			},
+		},
+		{
+			name: "Simple query, no metric name matcher",
+			query: &prompb.Query{
+				StartTimestampMs: 1000,
+				EndTimestampMs:   2000,
+				Matchers: []*prompb.LabelMatcher{
+					{Type: prompb.LabelMatcher_EQ, Name: "foo", Value: "bar"},
+				},
+			},
+			sqlQueries: []string{`SELECT m.metric_name, array_agg(s.id)
+	FROM _prom_catalog.series s
+	INNER JOIN _prom_catalog.metric m
+	ON (m.id = s.metric_id)
+	WHERE labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $1 and l.value = $2)
+	GROUP BY m.metric_name
+	ORDER BY m.metric_name`,
+				`SELECT table_name FROM _prom_catalog.get_metric_table_name_if_exists($1)`,
+				`SELECT s.labels, array_agg(m.time ORDER BY time), array_agg(m.value ORDER BY time)
+	FROM "prom_data"."metric" m
+	INNER JOIN "prom_data_series"."metric" s
+	ON m.series_id = s.id
+	WHERE m.series_id IN (1,99,98)
+	AND time >= '1970-01-01T00:00:01Z'
+	AND time <= '1970-01-01T00:00:02Z'
+	GROUP BY s.id`,
+				"SELECT (labels_info($1::int[])).*"},
+			sqlArgs: [][]interface{}{
+				{"foo", "bar"},
+				{"metric"},
+				nil,
+				{[]int64{7}},
+			},
+			result: []*prompb.TimeSeries{
+				{
+					Labels:  []prompb.Label{{Name: "foo", Value: "bar"}},
+					Samples: []prompb.Sample{{Timestamp: toMilis(time.Unix(0, 0)), Value: 1}},
+				},
+			},
+			queryResults: []rowResults{
+				{{"metric", []int64{1, 99, 98}}},
+				{{"metric"}},
+				{{[]int64{7}, []time.Time{time.Unix(0, 0)}, []float64{1}}},
+				{{[]int64{7}, []string{"foo"}, []string{"bar"}}},
+			},
+		},
+		{
+			name: "Complex query, multiple matchers",
+			query: &prompb.Query{
+				StartTimestampMs: 1000,
+				EndTimestampMs:   2000,
+				Matchers: []*prompb.LabelMatcher{
+					{Type: prompb.LabelMatcher_EQ, Name: "foo", Value: "bar"},
+					{Type: prompb.LabelMatcher_NEQ, Name: "foo1", Value: "bar1"},
+					{Type: prompb.LabelMatcher_RE, Name: "foo2", Value: "^bar2"},
+					{Type: prompb.LabelMatcher_NRE, Name: "foo3", Value: "bar3$"},
+				},
+			},
+			sqlQueries: []string{`SELECT m.metric_name, array_agg(s.id)
+	FROM _prom_catalog.series s
+	INNER JOIN _prom_catalog.metric m
+	ON (m.id = s.metric_id)
+	WHERE labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $1 and l.value = $2) AND NOT labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $3 and l.value = $4) AND labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $5 and l.value ~ $6) AND NOT labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $7 and l.value ~ $8)
+	GROUP BY m.metric_name
+	ORDER BY m.metric_name`,
+				`SELECT table_name FROM _prom_catalog.get_metric_table_name_if_exists($1)`,
+				`SELECT s.labels, array_agg(m.time ORDER BY time), array_agg(m.value ORDER BY time)
+	FROM "prom_data"."metric" m
+	INNER JOIN "prom_data_series"."metric" s
+	ON m.series_id = s.id
+	WHERE m.series_id IN (1,4,5)
+	AND time >= '1970-01-01T00:00:01Z'
+	AND time <= '1970-01-01T00:00:02Z'
+	GROUP BY s.id`,
+				"SELECT (labels_info($1::int[])).*"},
+			sqlArgs: [][]interface{}{
+				{"foo", "bar", "foo1", "bar1", "foo2", "^(?:^bar2)$", "foo3", "^(?:bar3$)$"},
+				{"metric"},
+				nil,
+				{[]int64{9, 8}},
+			},
+			result: []*prompb.TimeSeries{
+				{
+					Labels: []prompb.Label{
+						{Name: "foo", Value: "bar"},
+						{Name: "foo2", Value: "bar2"},
+					},
+					Samples: []prompb.Sample{{Timestamp: toMilis(time.Unix(0, 0)), Value: 1}},
+				},
+			},
+			queryResults: []rowResults{
+				{{"metric", []int64{1, 4, 5}}},
+				{{"metric"}},
+				{{[]int64{8, 9}, []time.Time{time.Unix(0, 0)}, []float64{1}}},
+				{{[]int64{8, 9}, []string{"foo", "foo2"}, []string{"bar", "bar2"}}},
+			},
+		},
+		{
+			name: "Complex query, empty equal matchers",
+			query: &prompb.Query{
+				StartTimestampMs: 1000,
+				EndTimestampMs:   2000,
+				Matchers: []*prompb.LabelMatcher{
+					{Type: prompb.LabelMatcher_EQ, Name: "foo", Value: ""},
+					{Type: prompb.LabelMatcher_NEQ, Name: "foo1", Value: "bar1"},
+					{Type: prompb.LabelMatcher_RE, Name: "foo2", Value: "^bar2$"},
+					{Type: prompb.LabelMatcher_NRE, Name: "foo3", Value: "bar3"},
+				},
+			},
+			sqlQueries: []string{`SELECT m.metric_name, array_agg(s.id)
+	FROM _prom_catalog.series s
+	INNER JOIN _prom_catalog.metric m
+	ON (m.id = s.metric_id)
+	WHERE NOT labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $1 and l.value != $2) AND NOT labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $3 and l.value = $4) AND labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $5 and l.value ~ $6) AND NOT labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $7 and l.value ~ $8)
+	GROUP BY m.metric_name
+	ORDER BY m.metric_name`,
+				`SELECT table_name FROM _prom_catalog.get_metric_table_name_if_exists($1)`,
+				`SELECT s.labels, array_agg(m.time ORDER BY time), array_agg(m.value ORDER BY time)
+	FROM "prom_data"."metric" m
+	INNER JOIN "prom_data_series"."metric" s
+	ON m.series_id = s.id
+	WHERE m.series_id IN (1,2)
+	AND time >= '1970-01-01T00:00:01Z'
+	AND time <= '1970-01-01T00:00:02Z'
+	GROUP BY s.id`,
+				"SELECT (labels_info($1::int[])).*"},
+			sqlArgs: [][]interface{}{
+				{"foo", "", "foo1", "bar1", "foo2", "^(?:^bar2$)$", "foo3", "^(?:bar3)$"},
+				{"metric"},
+				nil,
+				{[]int64{10}},
+			},
+			result: []*prompb.TimeSeries{
+				{
+					Labels: []prompb.Label{
+						{Name: "foo2", Value: "bar2"},
+					},
+					Samples: []prompb.Sample{{Timestamp: toMilis(time.Unix(0, 0)), Value: 1}},
+				},
+			},
+			queryResults: []rowResults{
+				{{"metric", []int64{1, 2}}},
+				{{"metric"}},
+				{{[]int64{10}, []time.Time{time.Unix(0, 0)}, []float64{1}}},
+				{{[]int64{10}, []string{"foo2"}, []string{"bar2"}}},
+			},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			mock := &mockPGXConn{
+				QueryErr:     c.queryErr,
+				QueryResults: c.queryResults,
+			}
+			metricCache := map[string]string{"metric_1": "metricTableName_1"}
+			mockMetrics := &mockMetricCache{
+				metricCache: metricCache,
+				//getMetricErr: c.metricsGetErr,
+				//setMetricErr: c.metricsSetErr,
+			}
+			querier := pgxQuerier{conn: mock, metricTableNames: mockMetrics, labels: clockcache.WithMax(0), allowUnbounded: c.allowUnbounded}
+
+			result, err := querier.Query(context.Background(), c.query)
+
+			if err != nil {
+				switch {
+				case len(c.queryErr) > 0:
+					for _, qErr := range c.queryErr {
+						if err != qErr {
+							t.Errorf("unexpected error:\ngot\n%s\nwanted\n%s", err, qErr)
+						}
+					}
+				case c.err != nil:
+					if err.Error() != c.err.Error() {
+						t.Errorf("unexpected error:\ngot\n%#v\nwanted\n%#v", err, c.err)
+					}
+				default:
+					t.Errorf("unexpected error for query:\ngot\n%s\nwanted nil", err)
+				}
+			}
+
+			if !reflect.DeepEqual(result, c.result) {
+				t.Errorf("unexpected result:\ngot\n%v\nwanted\n%v", result, c.result)
+			}
+
+			if len(c.sqlQueries) != 0 {
+				if !reflect.DeepEqual(c.sqlQueries, mock.QuerySQLs) {
+					gotten := make(map[string]struct{}, len(c.sqlQueries))
+					for _, g := range mock.QuerySQLs {
+						gotten[g] = struct{}{}
+						t.Logf("got query:\n\t%s", g)
+					}
+
+					expected := make(map[string]struct{}, len(mock.QuerySQLs))
+					for _, e := range c.sqlQueries {
+						expected[e] = struct{}{}
+						t.Logf("expected query:\n\t%s", e)
+					}
+
+					missingOrExtra := false
+					for i, g := range mock.QuerySQLs {
+						if _, found := expected[g]; !found {
+							t.Errorf("extra SQL query %d:\n\t%s", i, g)
+							missingOrExtra = true
+						}
+					}
+
+					for i, e := range c.sqlQueries {
+						if _, found := gotten[e]; !found {
+							t.Errorf("missing SQL query %d:\n\t%s", i, e)
+							missingOrExtra = true
+						}
+					}
+
+					if !missingOrExtra {
+						t.Errorf("mis-ordered sql queries:\ngot\n%#v\nwanted\n%#v\n", mock.QuerySQLs, c.sqlQueries)
+					}
+				}
+			} else if len(mock.QuerySQLs) > 0 {
+				t.Errorf("incorrect sql queries:\ngot\n%#v\nwanted none", mock.QuerySQLs)
+			}
+			if len(c.sqlArgs) != 0 {
+				if !reflect.DeepEqual(c.sqlArgs, mock.QueryArgs) {
+					t.Errorf("incorrect sql arguments:\ngot\n%#v\nwanted\n%#v\n", mock.QueryArgs, c.sqlArgs)
+				}
+			} else if len(mock.QueryArgs) > 0 {
+				t.Errorf("incorrect sql arguments:\ngot\n%#v\nwanted none", mock.QueryArgs)
+			}
+		})
+	}
+}
+
+func TestBuildTimeseriesByLabelClausesQueryPushdown(t *testing.T) {
+	defer func(installed bool) { ExtensionIsInstalled = installed }(ExtensionIsInstalled)
+
+	hints := &storage.SelectHints{Start: 0, End: 600000, Step: 60000, Range: 300000}
+	filter := metricTimeRangeFilter{
+		metric:    "metric",
+		startTime: toRFC3339Nano(hints.Start),
+		endTime:   toRFC3339Nano(hints.End),
+	}
+	testCases := []struct {
+		name      string
+		query     string
+		extension bool
+		// aggregate is the pushed down aggregate, empty for raw samples
+		aggregate string
+	}{
+		{
+			name:      "rate",
+			query:     "rate(metric[5m])",
+			extension: true,
+			aggregate: "prom_rate(",
+		},
+		{
+			name:      "delta",
+			query:     "delta(metric[5m])",
+			extension: true,
+			aggregate: "prom_delta(",
+		},
+		{
+			name:  "rate without extension",
+			query: "rate(metric[5m])",
+		},
+		{
+			name:      "function without pushdown",
+			query:     "irate(metric[5m])",
+			extension: true,
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			ExtensionIsInstalled = c.extension
+			expr, err := parser.ParseExpr(c.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var path []parser.Node
+			parser.Inspect(expr, func(node parser.Node, p []parser.Node) error {
+				if _, ok := node.(*parser.VectorSelector); ok {
+					path = append([]parser.Node{}, p...)
+				}
+				return nil
+			})
+
+			query, _, topNode, err := buildTimeseriesByLabelClausesQuery(filter, []string{"TRUE"}, nil, hints, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if c.aggregate == "" {
+				if topNode != nil {
+					t.Errorf("unexpected pushdown of %v", topNode)
+				}
+				if !strings.Contains(query, "array_agg(m.value ORDER BY time)") {
+					t.Errorf("expected raw samples, got query:\n%s", query)
+				}
+				return
+			}
+			if topNode != expr {
+				t.Errorf("unexpected top node: got %v, wanted %v", topNode, expr)
+			}
+			if !strings.Contains(query, c.aggregate) {
+				t.Errorf("expected %s aggregate, got query:\n%s", c.aggregate, query)
+			}
+			if !strings.Contains(query, "FILTER (WHERE NOT prom_api.is_stale_marker(value))") {
+				t.Errorf("expected staleness markers to be excluded, got query:\n%s", query)
+			}
+		})
+	}
+}
+
+func TestPGXQuerierSelectAlignToStep(t *testing.T) {
+	hints := &storage.SelectHints{Start: 1500, End: 5000, Step: 1000}
+	testCases := []struct {
+		name         string
+		matchers     []*labels.Matcher
+		alignToStep  bool
+		hints        *storage.SelectHints
+		sqlQueries   []string // XXX whitespace in these is significant
+		queryResults []rowResults
+	}{
+		{
+			name:        "Alignment disabled",
+			matchers:    []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, MetricNameLabelName, "bar")},
+			alignToStep: false,
+			hints:       hints,
+			sqlQueries: []string{`SELECT table_name FROM _prom_catalog.get_metric_table_name_if_exists($1)`,
+				`SELECT s.labels, array_agg(m.time ORDER BY time) as time_array, array_agg(m.value ORDER BY time)
+	FROM "prom_data"."bar" m
+	INNER JOIN "prom_data_series"."bar" s
+	ON m.series_id = s.id
+	WHERE TRUE
+	AND time >= '1970-01-01T00:00:01Z'
+	AND time <= '1970-01-01T00:00:05Z'
+	GROUP BY s.id`},
+			queryResults: []rowResults{{{"bar"}}, {}},
+		},
+		{
+			name:        "Instant query is not aligned",
+			matchers:    []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, MetricNameLabelName, "bar")},
+			alignToStep: true,
+			hints:       &storage.SelectHints{Start: 1500, End: 5000},
+			sqlQueries: []string{`SELECT table_name FROM _prom_catalog.get_metric_table_name_if_exists($1)`,
+				`SELECT s.labels, array_agg(m.time ORDER BY time) as time_array, array_agg(m.value ORDER BY time)
+	FROM "prom_data"."bar" m
+	INNER JOIN "prom_data_series"."bar" s
+	ON m.series_id = s.id
+	WHERE TRUE
+	AND time >= '1970-01-01T00:00:01Z'
+	AND time <= '1970-01-01T00:00:05Z'
+	GROUP BY s.id`},
+			queryResults: []rowResults{{{"bar"}}, {}},
+		},
+		{
+			name:        "Single metric",
+			matchers:    []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, MetricNameLabelName, "bar")},
+			alignToStep: true,
+			hints:       hints,
+			sqlQueries: []string{`SELECT table_name FROM _prom_catalog.get_metric_table_name_if_exists($1)`,
+				`SELECT s.labels, array_agg(m.time ORDER BY time) as time_array, array_agg(m.value ORDER BY time)
+	FROM (
+		SELECT series_id, time_bucket(INTERVAL '1000 milliseconds', time, TIMESTAMPTZ '1970-01-01T00:05:01Z') + INTERVAL '500 milliseconds' AS time, last(value, time) AS value
+		FROM "prom_data"."bar"
+		WHERE time >= '1970-01-01T00:00:01Z'
+		AND time <= '1970-01-01T00:00:05Z'
+		GROUP BY series_id, 2
+	) m
+	INNER JOIN "prom_data_series"."bar" s
+	ON m.series_id = s.id
+	WHERE TRUE
+	GROUP BY s.id`},
+			queryResults: []rowResults{{{"bar"}}, {}},
+		},
+		{
+			name:        "Multiple metrics",
+			matchers:    []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, "foo", "bar")},
+			alignToStep: true,
+			hints:       hints,
+			sqlQueries: []string{`SELECT m.metric_name, array_agg(s.id)
 	FROM _prom_catalog.series s
 	INNER JOIN _prom_catalog.metric m
 	ON (m.id = s.metric_id)
-	WHERE NOT labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $1 and l.value = $2)
+	WHERE labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $1 and l.value = $2)
 	GROUP BY m.metric_name
 	ORDER BY m.metric_name`,
 				`SELECT table_name FROM _prom_catalog.get_metric_table_name_if_exists($1)`,
 				`SELECT s.labels, array_agg(m.time ORDER BY time), array_agg(m.value ORDER BY time)
-	FROM "prom_data"."foo" m
-	INNER JOIN "prom_data_series"."foo" s
+	FROM (
+		SELECT series_id, time_bucket(INTERVAL '1000 milliseconds', time, TIMESTAMPTZ '1970-01-01T00:05:01Z') + INTERVAL '500 milliseconds' AS time, last(value, time) AS value
+		FROM "prom_data"."bar"
+		WHERE series_id IN (1,2)
+		AND time >= '1970-01-01T00:00:01Z'
+		AND time <= '1970-01-01T00:00:05Z'
+		GROUP BY series_id, 2
+	) m
+	INNER JOIN "prom_data_series"."bar" s
 	ON m.series_id = s.id
-	WHERE m.series_id IN (1)
-	AND time >= '1970-01-01T00:00:01Z'
-	AND time <= '1970-01-01T00:00:02Z'
-	GROUP BY s.id`,
-				"SELECT (labels_info($1::int[])).*"},
-			sqlArgs: [][]interface{}{
-				{"__name__", "bar"},
-				{"foo"},
-				nil,
-				{[]int64{1}},
+	GROUP BY s.id`},
+			queryResults: []rowResults{{{"bar", []int64{1, 2}}}, {{"bar"}}, {}},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			mock := &mockPGXConn{
+				QueryResults: c.queryResults,
+			}
+			mockMetrics := &mockMetricCache{
+				metricCache: make(map[string]string),
+			}
+			querier := pgxQuerier{conn: mock, metricTableNames: mockMetrics, labels: clockcache.WithMax(0), alignToStep: c.alignToStep}
+
+			_, _, _, err := querier.Select(context.Background(), 1000, 5000, false, c.hints, nil, c.matchers...)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if !reflect.DeepEqual(c.sqlQueries, mock.QuerySQLs) {
+				t.Errorf("unexpected sql queries:\ngot\n%s\nwanted\n%s", strings.Join(mock.QuerySQLs, "\n"), strings.Join(c.sqlQueries, "\n"))
+			}
+		})
+	}
+}
+
+// hintsQueryable records the hints and paths of the selects of the PromQL
+// engine.
+type hintsQueryable struct {
+	hints []*storage.SelectHints
+	paths [][]parser.Node
+}
+
+func (q *hintsQueryable) Querier(context.Context, int64, int64) (promql.Querier, error) {
+	return q, nil
+}
+
+func (q *hintsQueryable) LabelValues(string, ...*labels.Matcher) ([]string, storage.Warnings, error) {
+	return nil, nil, nil
+}
+
+func (q *hintsQueryable) LabelNames(...*labels.Matcher) ([]string, storage.Warnings, error) {
+	return nil, nil, nil
+}
+
+func (q *hintsQueryable) Close() error {
+	return nil
+}
+
+func (q *hintsQueryable) Select(_ bool, hints *storage.SelectHints, path []parser.Node, _ ...*labels.Matcher) (storage.SeriesSet, parser.Node, storage.Warnings, error) {
+	q.hints = append(q.hints, hints)
+	q.paths = append(q.paths, path)
+	return storage.EmptySeriesSet(), nil, nil, nil
+}
+
+func TestStepAlignmentEngineHints(t *testing.T) {
+	start := time.Unix(1000, 0)
+	// neither the lookback delta, the range nor the offset is a multiple
+	// of the step
+	step := 14 * time.Second
+	end := start.Add(10 * step)
+
+	testCases := []struct {
+		name      string
+		query     string
+		offset    time.Duration
+		unaligned bool
+	}{
+		{
+			name:  "vector selector",
+			query: "foo",
+		},
+		{
+			name:  "range selector",
+			query: "rate(foo[1m])",
+		},
+		{
+			name:   "offset",
+			query:  "foo offset 1m",
+			offset: time.Minute,
+		},
+		{
+			name:   "range selector with offset",
+			query:  "rate(foo[1m] offset 1m)",
+			offset: time.Minute,
+		},
+		{
+			name:      "subquery",
+			query:     "max_over_time(foo[1m:5s])",
+			unaligned: true,
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			q := &hintsQueryable{}
+			engine := promql.NewEngine(promql.EngineOpts{MaxSamples: math.MaxInt32, Timeout: time.Minute})
+			query, err := engine.NewRangeQuery(q, c.query, start, end, step)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res := query.Exec(context.Background()); res.Err != nil {
+				t.Fatal(res.Err)
+			}
+			if len(q.hints) != 1 {
+				t.Fatalf("unexpected selects: %v", q.hints)
+			}
+
+			bucket := newStepAlignment(q.hints[0], q.paths[0])
+			if c.unaligned {
+				if bucket != nil {
+					t.Errorf("samples aligned to the steps of the outer query: %+v", bucket)
+				}
+				return
+			}
+			if bucket == nil {
+				t.Fatal("samples not aligned")
+			}
+			for ts := start; !ts.After(end); ts = ts.Add(step) {
+				if d := toMilis(ts.Add(-c.offset)) - bucket.origin; d%bucket.width != 0 {
+					t.Errorf("step %v off the grid by %dms: %+v", ts, d%bucket.width, bucket)
+				}
+			}
+		})
+	}
+}
+
+func TestParseContinuousAggregates(t *testing.T) {
+	testCases := []struct {
+		expr     string
+		expected []ContinuousAggregate
+		invalid  bool
+	}{
+		{expr: ""},
+		{
+			expr:     "cpu:1h=cpu_1h",
+			expected: []ContinuousAggregate{{Metric: "cpu", Resolution: time.Hour, Schema: dataSchema, View: "cpu_1h"}},
+		},
+		{
+			expr: "job:cpu:rate5m:5m=rollups.cpu_5m, cpu:1d=cpu_1d",
+			expected: []ContinuousAggregate{
+				{Metric: "job:cpu:rate5m", Resolution: 5 * time.Minute, Schema: "rollups", View: "cpu_5m"},
+				{Metric: "cpu", Resolution: 24 * time.Hour, Schema: dataSchema, View: "cpu_1d"},
 			},
-			result: []*prompb.TimeSeries{
-				{
-					Labels:  []prompb.Label{{Name: MetricNameLabelName, Value: "foo"}},
-					Samples: []prompb.Sample{{Timestamp: toMilis(time.Unix(0, 0)), Value: 1}},
+		},
+		{expr: "cpu=cpu_1h", invalid: true},
+		{expr: "cpu:1h", invalid: true},
+		{expr: "cpu:1h=", invalid: true},
+		{expr: ":1h=cpu_1h", invalid: true},
+		{expr: "cpu:0s=cpu_1h", invalid: true},
+		{expr: "cpu:hour=cpu_1h", invalid: true},
+		{expr: "cpu:1h=rollups.", invalid: true},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.expr, func(t *testing.T) {
+			aggs, err := ParseContinuousAggregates(c.expr)
+			if c.invalid {
+				if err == nil {
+					t.Fatalf("expected an error, got aggregates %v", aggs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(aggs, c.expected) {
+				t.Errorf("unexpected aggregates: got %v, wanted %v", aggs, c.expected)
+			}
+		})
+	}
+}
+
+func TestPGXQuerierSelectContinuousAggregates(t *testing.T) {
+	aggs := []ContinuousAggregate{
+		{Metric: "bar", Resolution: 5 * time.Minute, Schema: dataSchema, View: "bar_5m"},
+		{Metric: "bar", Resolution: time.Hour, Schema: "rollups", View: "bar_1h"},
+	}
+	metricMatcher := labels.MustNewMatcher(labels.MatchEqual, MetricNameLabelName, "bar")
+	testCases := []struct {
+		name         string
+		matchers     []*labels.Matcher
+		step         time.Duration
+		rng          time.Duration
+		queryResults []rowResults
+		table        string
+	}{
+		{
+			name:         "No step",
+			matchers:     []*labels.Matcher{metricMatcher},
+			queryResults: []rowResults{{{"bar"}}, {}},
+			table:        `"prom_data"."bar"`,
+		},
+		{
+			name:         "Step finer than the aggregates",
+			matchers:     []*labels.Matcher{metricMatcher},
+			step:         time.Minute,
+			queryResults: []rowResults{{{"bar"}}, {}},
+			table:        `"prom_data"."bar"`,
+		},
+		{
+			name:         "Step between the aggregates",
+			matchers:     []*labels.Matcher{metricMatcher},
+			step:         10 * time.Minute,
+			queryResults: []rowResults{{{"bar"}}, {}},
+			table:        `"prom_data"."bar_5m"`,
+		},
+		{
+			name:         "Step and range coarser than the aggregates",
+			matchers:     []*labels.Matcher{metricMatcher},
+			step:         24 * time.Hour,
+			rng:          24 * time.Hour,
+			queryResults: []rowResults{{{"bar"}}, {}},
+			table:        `"rollups"."bar_1h"`,
+		},
+		{
+			name:         "Range between the aggregates",
+			matchers:     []*labels.Matcher{metricMatcher},
+			step:         24 * time.Hour,
+			rng:          30 * time.Minute,
+			queryResults: []rowResults{{{"bar"}}, {}},
+			table:        `"prom_data"."bar_5m"`,
+		},
+		{
+			name:         "Range finer than the aggregates",
+			matchers:     []*labels.Matcher{metricMatcher},
+			step:         24 * time.Hour,
+			rng:          time.Minute,
+			queryResults: []rowResults{{{"bar"}}, {}},
+			table:        `"prom_data"."bar"`,
+		},
+		{
+			name:         "No range, lookback delta between the aggregates",
+			matchers:     []*labels.Matcher{metricMatcher},
+			step:         24 * time.Hour,
+			queryResults: []rowResults{{{"bar"}}, {}},
+			table:        `"prom_data"."bar_5m"`,
+		},
+		{
+			name:         "Multiple metrics",
+			matchers:     []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, "foo", "bar")},
+			step:         time.Hour,
+			rng:          time.Hour,
+			queryResults: []rowResults{{{"bar", []int64{1, 2}}}, {{"bar"}}, {}},
+			table:        `"rollups"."bar_1h"`,
+		},
+		{
+			name:         "Metric without aggregates",
+			matchers:     []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, MetricNameLabelName, "baz")},
+			step:         time.Hour,
+			queryResults: []rowResults{{{"baz"}}, {}},
+			table:        `"prom_data"."baz"`,
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			mock := &mockPGXConn{
+				QueryResults: c.queryResults,
+			}
+			mockMetrics := &mockMetricCache{
+				metricCache: make(map[string]string),
+			}
+			querier := pgxQuerier{conn: mock, metricTableNames: mockMetrics, labels: clockcache.WithMax(0), continuousAggregates: newContinuousAggregates(aggs)}
+
+			hints := &storage.SelectHints{Start: 1000, End: 5000, Step: c.step.Milliseconds(), Range: c.rng.Milliseconds()}
+			_, _, _, err := querier.Select(context.Background(), 1000, 5000, false, hints, nil, c.matchers...)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			dataQuery := mock.QuerySQLs[len(mock.QuerySQLs)-1]
+			if !strings.Contains(dataQuery, "FROM "+c.table+" m") {
+				t.Errorf("unexpected data query, wanted it to read from %s:\n%s", c.table, dataQuery)
+			}
+			if !strings.Contains(dataQuery, `INNER JOIN "prom_data_series"."`) {
+				t.Errorf("unexpected data query, wanted it to join the series view:\n%s", dataQuery)
+			}
+		})
+	}
+}
+
+func TestPGXQuerierSelectUnionMetricTables(t *testing.T) {
+	seriesQuery := func(metric string, ids string) string {
+		return fmt.Sprintf(`SELECT s.labels, array_agg(m.time ORDER BY time), array_agg(m.value ORDER BY time)
+	FROM "prom_data"."%[1]s" m
+	INNER JOIN "prom_data_series"."%[1]s" s
+	ON m.series_id = s.id
+	WHERE m.series_id IN (%[2]s)
+	AND time >= '1970-01-01T00:00:01Z'
+	AND time <= '1970-01-01T00:00:05Z'
+	GROUP BY s.id`, metric, ids)
+	}
+	testCases := []struct {
+		name         string
+		union        bool
+		sqlQueries   []string
+		queryResults []rowResults
+	}{
+		{
+			name:         "Query per table",
+			sqlQueries:   []string{seriesQuery("bar", "1,2"), seriesQuery("baz", "3")},
+			queryResults: []rowResults{{{"bar", []int64{1, 2}}, {"baz", []int64{3}}}, {{[]int64{1, 2}, []time.Time{time.Unix(1, 0)}, []float64{1}}}, {{[]int64{1, 3}, []time.Time{time.Unix(2, 0)}, []float64{2}}}},
+		},
+		{
+			name:         "Union of the tables",
+			union:        true,
+			sqlQueries:   []string{"(" + seriesQuery("bar", "1,2") + ")\nUNION ALL\n(" + seriesQuery("baz", "3") + ")"},
+			queryResults: []rowResults{{{"bar", []int64{1, 2}}, {"baz", []int64{3}}}, {{[]int64{1, 3}, []time.Time{time.Unix(2, 0)}, []float64{2}}, {[]int64{1, 2}, []time.Time{time.Unix(1, 0)}, []float64{1}}}},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			mock := &mockPGXConn{
+				QueryResults: c.queryResults,
+			}
+			mockMetrics := &mockMetricCache{
+				metricCache: map[string]string{"bar": "bar", "baz": "baz"},
+			}
+			querier := pgxQuerier{conn: mock, metricTableNames: mockMetrics, labels: clockcache.WithMax(10), unionMetricTables: c.union}
+			querier.labels.InsertBatch(
+				[]interface{}{int64(1), int64(2), int64(3)},
+				[]interface{}{
+					labels.Label{Name: "foo", Value: "x"},
+					labels.Label{Name: MetricNameLabelName, Value: "bar"},
+					labels.Label{Name: MetricNameLabelName, Value: "baz"},
+				},
+			)
+
+			matchers := []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, "foo", "x")}
+			ss, _, _, err := querier.Select(context.Background(), 1000, 5000, true, nil, nil, matchers...)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			// the series of both tables are returned, sorted
+			var got []labels.Labels
+			for ss.Next() {
+				got = append(got, ss.At().Labels())
+			}
+			if err = ss.Err(); err != nil {
+				t.Fatal(err)
+			}
+			expected := []labels.Labels{
+				labels.FromStrings(MetricNameLabelName, "bar", "foo", "x"),
+				labels.FromStrings(MetricNameLabelName, "baz", "foo", "x"),
+			}
+			if !reflect.DeepEqual(got, expected) {
+				t.Errorf("unexpected series: got %v, wanted %v", got, expected)
+			}
+
+			mock.queryLock.Lock()
+			defer mock.queryLock.Unlock()
+			if !reflect.DeepEqual(mock.QuerySQLs[1:len(c.sqlQueries)+1], c.sqlQueries) {
+				t.Errorf("unexpected sql queries:\ngot\n%s\nwanted\n%s", strings.Join(mock.QuerySQLs, "\n"), strings.Join(c.sqlQueries, "\n"))
+			}
+		})
+	}
+}
+
+func TestPGXQuerierSelectRetentionWarning(t *testing.T) {
+	boundary := time.Unix(1000, 0)
+	testCases := []struct {
+		name            string
+		matchers        []*labels.Matcher
+		maxt            int64
+		warnOnRetention bool
+		sqlQueries      []string
+		queryResults    []rowResults
+		warnings        int
+	}{
+		{
+			name:         "Disabled",
+			matchers:     []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, MetricNameLabelName, "bar")},
+			maxt:         500000,
+			queryResults: []rowResults{{{"bar"}}, {}},
+			sqlQueries:   []string{getMetricsTableSQL},
+		},
+		{
+			name:            "Range before retention boundary",
+			matchers:        []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, MetricNameLabelName, "bar")},
+			maxt:            500000,
+			warnOnRetention: true,
+			queryResults:    []rowResults{{{"bar"}}, {}, {{boundary}}},
+			sqlQueries:      []string{getMetricsTableSQL, getRetentionBoundarySQL},
+			warnings:        1,
+		},
+		{
+			name:            "Range after retention boundary",
+			matchers:        []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, MetricNameLabelName, "bar")},
+			maxt:            1500000,
+			warnOnRetention: true,
+			queryResults:    []rowResults{{{"bar"}}, {}, {{boundary}}},
+			sqlQueries:      []string{getMetricsTableSQL, getRetentionBoundarySQL},
+		},
+		{
+			name:            "Multiple metrics are not checked",
+			matchers:        []*labels.Matcher{labels.MustNewMatcher(labels.MatchRegexp, MetricNameLabelName, "ba.*")},
+			maxt:            500000,
+			warnOnRetention: true,
+			queryResults:    []rowResults{{}},
+			sqlQueries:      []string{},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			mock := &mockPGXConn{
+				QueryResults: c.queryResults,
+			}
+			mockMetrics := &mockMetricCache{
+				metricCache: make(map[string]string),
+			}
+			querier := pgxQuerier{conn: mock, metricTableNames: mockMetrics, labels: clockcache.WithMax(0), warnOnRetention: c.warnOnRetention}
+
+			_, _, warnings, err := querier.Select(context.Background(), 0, c.maxt, false, nil, nil, c.matchers...)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if len(warnings) != c.warnings {
+				t.Errorf("unexpected warnings: got %v, wanted %d", warnings, c.warnings)
+			}
+
+			// Only check the catalog queries, the data queries are covered elsewhere.
+			catalogQueries := make([]string, 0)
+			for _, q := range mock.QuerySQLs {
+				if q == getMetricsTableSQL || q == getRetentionBoundarySQL {
+					catalogQueries = append(catalogQueries, q)
+				}
+			}
+			if !reflect.DeepEqual(catalogQueries, c.sqlQueries) {
+				t.Errorf("unexpected catalog queries:\ngot\n%v\nwanted\n%v", catalogQueries, c.sqlQueries)
+			}
+		})
+	}
+}
+
+func TestPGXQuerierRetentionQueryContext(t *testing.T) {
+	mock := &mockPGXConn{
+		QueryResults: []rowResults{{{time.Unix(1000, 0)}}},
+	}
+	querier := pgxQuerier{conn: mock, propagateCancel: true}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	querier.checkRetention(ctx, 500000, []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, MetricNameLabelName, "bar")})
+	if len(mock.QueryCtxErrs) != 1 || mock.QueryCtxErrs[0] != context.Canceled {
+		t.Errorf("retention query not canceled with the request: %v", mock.QueryCtxErrs)
+	}
+}
+
+func TestPGXQuerierExportSeriesCatalog(t *testing.T) {
+	defer func(size int) { seriesCatalogPageSize = size }(seriesCatalogPageSize)
+	seriesCatalogPageSize = 2
+
+	mock := &mockPGXConn{
+		QueryResults: []rowResults{
+			{{int64(1), []int64{1, 2}}, {int64(2), []int64{1, 3}}},
+			{{[]int64{1, 2, 3}, []string{"__name__", "instance", "instance"}, []string{"foo", "a", "b"}}},
+			{{int64(3), []int64{1, 4}}, {int64(5), []int64{1, 5}}},
+			{{[]int64{4, 5}, []string{"instance", "instance"}, []string{"c", "d"}}},
+			{{int64(8), []int64{1, 6}}},
+			{{[]int64{6}, []string{"instance"}, []string{"e"}}},
+		},
+	}
+	querier := pgxQuerier{conn: mock, labels: clockcache.WithMax(10)}
+	reader := DBReader{db: &querier}
+
+	var buf bytes.Buffer
+	if err := reader.ExportSeriesCatalog(context.Background(), &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	dec := json.NewDecoder(&buf)
+	var entries []SeriesCatalogEntry
+	for dec.More() {
+		var entry SeriesCatalogEntry
+		if err := dec.Decode(&entry); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+	expected := []struct {
+		id       SeriesID
+		instance string
+	}{{1, "a"}, {2, "b"}, {3, "c"}, {5, "d"}, {8, "e"}}
+	if len(entries) != len(expected) {
+		t.Fatalf("unexpected number of series exported: got %d, wanted %d", len(entries), len(expected))
+	}
+	for i, e := range expected {
+		lls := labels.FromStrings(MetricNameLabelName, "foo", "instance", e.instance)
+		want := SeriesCatalogEntry{
+			Fingerprint: fmt.Sprintf("%016x", lls.Hash()),
+			ID:          e.id,
+			Labels:      lls,
+		}
+		if !reflect.DeepEqual(entries[i], want) {
+			t.Errorf("unexpected entry %d:\ngot\n%+v\nwanted\n%+v", i, entries[i], want)
+		}
+	}
+
+	// every page starts after the last series of the previous one, and
+	// fetches the labels not cached yet in a single query
+	var (
+		pageArgs    [][]interface{}
+		labelsQuery []interface{}
+	)
+	for i, sql := range mock.QuerySQLs {
+		switch sql {
+		case seriesCatalogPageSQL:
+			pageArgs = append(pageArgs, mock.QueryArgs[i])
+		case GetLabelsSQL:
+			labelsQuery = append(labelsQuery, mock.QueryArgs[i]...)
+		}
+	}
+	expectedLabels := []interface{}{[]int64{1, 2, 3}, []int64{4, 5}, []int64{6}}
+	if !reflect.DeepEqual(labelsQuery, expectedLabels) {
+		t.Errorf("unexpected label queries:\ngot\n%v\nwanted\n%v", labelsQuery, expectedLabels)
+	}
+	expectedArgs := [][]interface{}{{int64(0), 2}, {int64(2), 2}, {int64(5), 2}}
+	if !reflect.DeepEqual(pageArgs, expectedArgs) {
+		t.Errorf("unexpected pages:\ngot\n%v\nwanted\n%v", pageArgs, expectedArgs)
+	}
+}
+
+func TestPGXQuerierExportSeriesCatalogTenancy(t *testing.T) {
+	tenancy := &Tenancy{Label: "tenant"}
+	scopedSQL := fmt.Sprintf(seriesCatalogScopedPageSQLFormat, fmt.Sprintf(subQueryEQ, 1, 2), 3, 4)
+	for _, tenant := range []string{"a", "b"} {
+		t.Run(tenant, func(t *testing.T) {
+			mock := &mockPGXConn{
+				QueryResults: []rowResults{
+					{{int64(1), []int64{1, 2}}},
+					{{[]int64{1, 2}, []string{"__name__", "tenant"}, []string{"foo", tenant}}},
 				},
+			}
+			querier := pgxQuerier{conn: mock, labels: clockcache.WithMax(10), tenancy: tenancy}
+
+			var buf bytes.Buffer
+			if err := querier.ExportSeriesCatalog(WithTenant(context.Background(), tenant), &buf); err != nil {
+				t.Fatal(err)
+			}
+			var entry SeriesCatalogEntry
+			if err := json.NewDecoder(&buf).Decode(&entry); err != nil {
+				t.Fatal(err)
+			}
+			if got := entry.Labels.Get("tenant"); got != tenant {
+				t.Errorf("unexpected tenant exported: got %s, wanted %s", got, tenant)
+			}
+
+			// the pages only read the series of the tenant
+			if len(mock.QuerySQLs) == 0 || mock.QuerySQLs[0] != scopedSQL {
+				t.Fatalf("unexpected page query:\ngot\n%v\nwanted\n%s", mock.QuerySQLs, scopedSQL)
+			}
+			expectedArgs := []interface{}{"tenant", tenant, int64(0), seriesCatalogPageSize}
+			if !reflect.DeepEqual(mock.QueryArgs[0], expectedArgs) {
+				t.Errorf("unexpected page args: got %v, wanted %v", mock.QueryArgs[0], expectedArgs)
+			}
+		})
+	}
+
+	t.Run("missing tenant", func(t *testing.T) {
+		mock := &mockPGXConn{}
+		querier := pgxQuerier{conn: mock, labels: clockcache.WithMax(10), tenancy: tenancy}
+
+		err := querier.ExportSeriesCatalog(context.Background(), &bytes.Buffer{})
+		if !errors.Is(err, ErrMissingTenant) {
+			t.Fatalf("unexpected error: got %v, wanted %v", err, ErrMissingTenant)
+		}
+		if len(mock.QuerySQLs) != 0 {
+			t.Errorf("series read without a tenant: %v", mock.QuerySQLs)
+		}
+	})
+}
+
+func TestPGXQuerierLabelsCache(t *testing.T) {
+	mock := &mockPGXConn{
+		QueryResults: []rowResults{
+			{{[]int64{1, 2}, []string{"k1", "k2"}, []string{"v1", "v2"}}},
+		},
+	}
+	querier := pgxQuerier{conn: mock, labels: clockcache.WithMax(10)}
+
+	hits, misses := testutil.ToFloat64(labelsCacheHits), testutil.ToFloat64(labelsCacheMisses)
+	expected := labels.Labels{{Name: "k1", Value: "v1"}, {Name: "k2", Value: "v2"}}
+	for i := 0; i < 2; i++ {
+		lls, err := querier.getLabelsForIds(context.Background(), []int64{1, 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Sort(lls)
+		if !reflect.DeepEqual(lls, expected) {
+			t.Errorf("unexpected labels: got %v, wanted %v", lls, expected)
+		}
+	}
+
+	// the missing ids are fetched in a single query, then served from the cache
+	if len(mock.QuerySQLs) != 1 || mock.QuerySQLs[0] != GetLabelsSQL {
+		t.Errorf("unexpected queries: %v", mock.QuerySQLs)
+	}
+	if got := testutil.ToFloat64(labelsCacheMisses) - misses; got != 2 {
+		t.Errorf("unexpected number of misses: got %v, wanted 2", got)
+	}
+	if got := testutil.ToFloat64(labelsCacheHits) - hits; got != 2 {
+		t.Errorf("unexpected number of hits: got %v, wanted 2", got)
+	}
+}
+
+func TestPGXQuerierLabelStrings(t *testing.T) {
+	// every string of the results has a backing array of its own, like the
+	// strings scanned from the database
+	fresh := func(s string) string {
+		return string([]byte(s))
+	}
+	mock := &mockPGXConn{
+		QueryResults: []rowResults{
+			{{[]int64{1, 2, 3}, []string{fresh("instance"), fresh("instance"), fresh("host")}, []string{fresh("host1"), fresh("host2"), fresh("host1")}}},
+		},
+	}
+	querier := pgxQuerier{conn: mock, labels: clockcache.WithMax(10), labelStrings: newStringInterner(10)}
+
+	lls, err := querier.getLabelsForIds(context.Background(), []int64{1, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Sort(lls)
+	expected := labels.Labels{{Name: "host", Value: "host1"}, {Name: "instance", Value: "host1"}, {Name: "instance", Value: "host2"}}
+	if !reflect.DeepEqual(lls, expected) {
+		t.Fatalf("unexpected labels: got %v, wanted %v", lls, expected)
+	}
+
+	data := func(s string) uintptr {
+		return (*reflect.StringHeader)(unsafe.Pointer(&s)).Data
+	}
+	if data(lls[1].Name) != data(lls[2].Name) {
+		t.Errorf("label names are not shared")
+	}
+	if data(lls[0].Value) != data(lls[1].Value) {
+		t.Errorf("label values are not shared")
+	}
+}
+
+func TestStringInternerMax(t *testing.T) {
+	data := func(s string) uintptr {
+		return (*reflect.StringHeader)(unsafe.Pointer(&s)).Data
+	}
+	interner := newStringInterner(1)
+	a := interner.intern(string([]byte("a")))
+	if got := interner.intern(string([]byte("a"))); got != a || data(got) != data(a) {
+		t.Errorf("unexpected interned string %q", got)
+	}
+
+	// a full interner evicts the strings not read since the last eviction
+	b := interner.intern(string([]byte("b")))
+	if got := interner.intern(string([]byte("b"))); got != "b" || data(got) != data(b) {
+		t.Errorf("unexpected interned string %q", got)
+	}
+	if _, ok := interner.strings.Get("a"); ok {
+		t.Error("string not evicted from a full interner")
+	}
+	if n := interner.strings.Len(); n != 1 {
+		t.Errorf("unexpected number of interned strings: got %d, wanted 1", n)
+	}
+
+	var none *stringInterner
+	if got := none.intern(b); got != b {
+		t.Errorf("unexpected string %q", got)
+	}
+}
+
+// BenchmarkPGXQuerierGetLabelsForIds fetches the labels of a result set whose
+// label values repeat a lot, reporting the bytes the labels keep alive.
+func BenchmarkPGXQuerierGetLabelsForIds(b *testing.B) {
+	const numLabels = 10000
+	names := []string{"instance", "job", "region", "pod", "zone"}
+	ids := make([]int64, numLabels)
+	for i := range ids {
+		ids[i] = int64(i + 1)
+	}
+	results := func() []rowResults {
+		keys := make([]string, numLabels)
+		vals := make([]string, numLabels)
+		for i := range keys {
+			keys[i] = string([]byte(names[i%len(names)]))
+			vals[i] = fmt.Sprintf("host%d", i%20)
+		}
+		return []rowResults{{{ids, keys, vals}}}
+	}
+
+	for _, interned := range []bool{false, true} {
+		name := "not interned"
+		if interned {
+			name = "interned"
+		}
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			var retained uint64
+			var stats runtime.MemStats
+			for n := 0; n < b.N; n++ {
+				b.StopTimer()
+				runtime.GC()
+				runtime.ReadMemStats(&stats)
+				before := stats.HeapAlloc
+				mock := &mockPGXConn{QueryResults: results()}
+				querier := &pgxQuerier{conn: mock, labels: clockcache.WithMax(numLabels)}
+				if interned {
+					querier.labelStrings = newStringInterner(2 * numLabels)
+				}
+				b.StartTimer()
+
+				lls, err := querier.getLabelsForIds(context.Background(), ids)
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				b.StopTimer()
+				// only the labels and the cache hold on to the strings
+				querier.conn, mock = nil, nil
+				runtime.GC()
+				runtime.ReadMemStats(&stats)
+				if stats.HeapAlloc > before {
+					retained += stats.HeapAlloc - before
+				}
+				runtime.KeepAlive(lls)
+				runtime.KeepAlive(querier)
+				b.StartTimer()
+			}
+			b.ReportMetric(float64(retained)/float64(b.N), "retained-B/op")
+		})
+	}
+}
+
+func TestPGXQuerierMaxMetricsPerQuery(t *testing.T) {
+	testCases := []struct {
+		name         string
+		maxMetrics   int
+		warnOnly     bool
+		expectedErr  error
+		tableLookups int
+	}{
+		{
+			name:         "No limit",
+			tableLookups: 3,
+		},
+		{
+			name:         "Under the limit",
+			maxMetrics:   3,
+			tableLookups: 3,
+		},
+		{
+			name:        "Over the limit",
+			maxMetrics:  2,
+			expectedErr: ErrTooManyMetrics,
+		},
+		{
+			name:         "Over the limit, warn only",
+			maxMetrics:   2,
+			warnOnly:     true,
+			tableLookups: 3,
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			mock := &mockPGXConn{
+				QueryResults: []rowResults{
+					{{"metric_1", []int64{1}}, {"metric_2", []int64{2}}, {"metric_3", []int64{3}}},
+				},
+			}
+			mockMetrics := &mockMetricCache{
+				metricCache: make(map[string]string),
+			}
+			querier := pgxQuerier{conn: mock, metricTableNames: mockMetrics, labels: clockcache.WithMax(0), maxMetrics: c.maxMetrics, maxMetricsWarnOnly: c.warnOnly}
+
+			matcher := labels.MustNewMatcher(labels.MatchRegexp, MetricNameLabelName, "metric_.*")
+			_, _, _, err := querier.Select(context.Background(), 0, 1000, false, nil, nil, matcher)
+			if !errors.Is(err, c.expectedErr) {
+				t.Fatalf("unexpected error: got %v, wanted %v", err, c.expectedErr)
+			}
+
+			// The metrics are only looked up once the limit is checked.
+			lookups := 0
+			for _, q := range mock.QuerySQLs {
+				if q == getMetricsTableSQL {
+					lookups++
+				}
+			}
+			if lookups != c.tableLookups {
+				t.Errorf("unexpected number of metric table lookups: got %d, wanted %d", lookups, c.tableLookups)
+			}
+		})
+	}
+}
+
+func TestPgxQuerierLabelsNames(t *testing.T) {
+	testLabelMethods(t, func(querier *pgxQuerier) ([]string, error) {
+		return querier.LabelNames(context.Background())
+	})
+}
+
+func TestPgxQuerierLabelsValues(t *testing.T) {
+	testLabelMethods(t, func(querier *pgxQuerier) ([]string, error) {
+		return querier.LabelValues(context.Background(), "m")
+	})
+}
+
+func testLabelMethods(t *testing.T, f func(*pgxQuerier) ([]string, error)) {
+	testCases := []struct {
+		name         string
+		expectedRes  []string
+		errorOnQuery bool
+		errorOnScan  bool
+	}{
+		{
+			name:         "Error on query",
+			errorOnQuery: true,
+		}, {
+			name:        "Error on scanning values",
+			errorOnScan: true,
+			expectedRes: []string{"a"},
+		}, {
+			name:        "Empty result, is ok",
+			expectedRes: []string{},
+		}, {
+			name:        "Result should be sorted",
+			expectedRes: []string{"b", "a"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			queryResults := toRowResults(tc.expectedRes, tc.errorOnScan)
+			queryErr := make(map[int]error)
+			if tc.errorOnQuery {
+				queryErr[0] = fmt.Errorf("some error")
+			}
+			mock := &mockPGXConn{
+				QueryErr:     queryErr,
+				QueryResults: queryResults,
+			}
+			querier := pgxQuerier{conn: mock}
+			res, err := f(&querier)
+			if tc.errorOnQuery && err == nil {
+				t.Error("unexpected lack of error")
+				return
+			} else if tc.errorOnScan && err == nil {
+				t.Error("unexpected lack of error")
+				return
+			} else if err != nil && !tc.errorOnQuery && !tc.errorOnScan {
+				t.Errorf("unexpected error: %v", err)
+				return
+			} else if tc.errorOnQuery || tc.errorOnScan {
+				return
+			}
+			outputIsSorted := sort.SliceIsSorted(res, func(i, j int) bool {
+				return res[i] < res[j]
+			})
+			if !outputIsSorted {
+				t.Error("returned label names are not sorted")
+			}
+			sort.Strings(tc.expectedRes)
+			if !reflect.DeepEqual(tc.expectedRes, res) {
+				t.Errorf("expected: %v, got: %v", tc.expectedRes, res)
+			}
+		})
+	}
+}
+func toRowResults(labelNames []string, convertImproperly bool) []rowResults {
+	toReturn := make([]rowResults, 1)
+	toReturn[0] = make(rowResults, len(labelNames))
+	for i, labelName := range labelNames {
+		if convertImproperly {
+			toReturn[0][i] = []interface{}{1}
+		} else {
+			toReturn[0][i] = []interface{}{labelName}
+		}
+	}
+	return toReturn
+}
+
+func TestPGXQuerierLabelEnrichment(t *testing.T) {
+	enrichment := &LabelEnrichment{
+		Table:        "meta.owners",
+		KeyColumn:    "service",
+		KeyLabel:     "service",
+		LabelColumns: []string{"team", "owner"},
+	}
+	enrichSQL := `SELECT ARRAY[coalesce("team"::text, ''), coalesce("owner"::text, '')] FROM "meta"."owners" WHERE "service"::text = $1 LIMIT 2`
+	if sql := enrichment.sql(); sql != enrichSQL {
+		t.Fatalf("unexpected enrichment sql:\ngot\n%s\nwanted\n%s", sql, enrichSQL)
+	}
+
+	mock := &mockPGXConn{
+		QueryResults: []rowResults{
+			{{"bar"}},
+			{
+				{[]int64{1, 2}, []time.Time{time.Unix(1, 0)}, []float64{1}},
+				{[]int64{1, 3}, []time.Time{time.Unix(2, 0)}, []float64{2}},
+				{[]int64{1, 2, 5}, []time.Time{time.Unix(3, 0)}, []float64{3}},
+				{[]int64{1, 6}, []time.Time{time.Unix(4, 0)}, []float64{4}},
 			},
-			queryResults: []rowResults{
-				{{`foo`, []int64{1}}},
-				{{"foo"}},
-				{{[]int64{1}, []time.Time{time.Unix(0, 0)}, []float64{1}}},
-				{{[]int64{1}, []string{"__name__"}, []string{"foo"}}},
-			},
+			{{[]int64{1, 2}, []string{"__name__", "service"}, []string{"bar", "api"}}},
+			// the owner of the api service
+			{{[]string{"ops", "alice"}}},
+			{{[]int64{3}, []string{"service"}, []string{"db"}}},
+			// the db service has two owners, so it is not enriched
+			{{[]string{"ops", "alice"}}, {[]string{"dev", "bob"}}},
+			{{[]int64{5}, []string{"team"}, []string{"dev"}}},
+			{{[]int64{6}, []string{"service"}, []string{"web"}}},
+			// the web service has no owner
+			{},
+		},
+	}
+	mockMetrics := &mockMetricCache{
+		metricCache: make(map[string]string),
+	}
+	querier := pgxQuerier{conn: mock, metricTableNames: mockMetrics, labels: clockcache.WithMax(10), labelEnrichment: enrichment}
+
+	result, err := querier.Query(context.Background(), &prompb.Query{
+		StartTimestampMs: 1000,
+		EndTimestampMs:   4000,
+		Matchers: []*prompb.LabelMatcher{
+			{Type: prompb.LabelMatcher_EQ, Name: MetricNameLabelName, Value: "bar"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := [][]prompb.Label{
+		{
+			{Name: MetricNameLabelName, Value: "bar"},
+			{Name: "owner", Value: "alice"},
+			{Name: "service", Value: "api"},
+			{Name: "team", Value: "ops"},
 		},
 		{
-			name: "Simple query, metric name matcher",
-			query: &prompb.Query{
-				StartTimestampMs: 1000,
-				EndTimestampMs:   2000,
-				Matchers: []*prompb.LabelMatcher{
-					{Type: prompb.LabelMatcher_EQ, Name: MetricNameLabelName, Value: "bar"},
-				},
-			},
-			sqlQueries: []string{`SELECT table_name FROM _prom_catalog.get_metric_table_name_if_exists($1)`,
-				`SELECT s.labels, array_agg(m.time ORDER BY time) as time_array, array_agg(m.value ORDER BY time)
-	FROM "prom_data"."bar" m
-	INNER JOIN "prom_data_series"."bar" s
-	ON m.series_id = s.id
-	WHERE labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $1 and l.value = $2)
-	AND time >= '1970-01-01T00:00:01Z'
-	AND time <= '1970-01-01T00:00:02Z'
-	GROUP BY s.id`,
-				"SELECT (labels_info($1::int[])).*"},
-			sqlArgs: [][]interface{}{
-				{"bar"},
-				{MetricNameLabelName, "bar"},
-				{[]int64{2}},
-			},
-			result: []*prompb.TimeSeries{
-				{
-					Labels:  []prompb.Label{{Name: MetricNameLabelName, Value: "bar"}},
-					Samples: []prompb.Sample{{Timestamp: toMilis(time.Unix(0, 0)), Value: 1}},
-				},
-			},
-			queryResults: []rowResults{
-				{{"bar"}},
-				{{[]int64{2}, []time.Time{time.Unix(0, 0)}, []float64{1}}},
-				{{[]int64{2}, []string{"__name__"}, []string{"bar"}}},
-			},
+			{Name: MetricNameLabelName, Value: "bar"},
+			{Name: "service", Value: "db"},
 		},
 		{
-			name: "Simple query, empty metric name matcher",
-			query: &prompb.Query{
-				StartTimestampMs: 1000,
-				EndTimestampMs:   2000,
-				Matchers: []*prompb.LabelMatcher{
-					{Type: prompb.LabelMatcher_RE, Name: MetricNameLabelName, Value: ""},
-				},
-			},
-			sqlQueries: []string{`SELECT m.metric_name, array_agg(s.id)
-	FROM _prom_catalog.series s
-	INNER JOIN _prom_catalog.metric m
-	ON (m.id = s.metric_id)
-	WHERE NOT labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $1 and l.value !~ $2)
-	GROUP BY m.metric_name
-	ORDER BY m.metric_name`,
-				`SELECT table_name FROM _prom_catalog.get_metric_table_name_if_exists($1)`,
-				`SELECT s.labels, array_agg(m.time ORDER BY time), array_agg(m.value ORDER BY time)
-	FROM "prom_data"."foo" m
-	INNER JOIN "prom_data_series"."foo" s
-	ON m.series_id = s.id
-	WHERE m.series_id IN (1)
-	AND time >= '1970-01-01T00:00:01Z'
-	AND time <= '1970-01-01T00:00:02Z'
-	GROUP BY s.id`,
-				`SELECT table_name FROM _prom_catalog.get_metric_table_name_if_exists($1)`,
-				`SELECT s.labels, array_agg(m.time ORDER BY time), array_agg(m.value ORDER BY time)
-	FROM "prom_data"."bar" m
-	INNER JOIN "prom_data_series"."bar" s
-	ON m.series_id = s.id
-	WHERE m.series_id IN (1)
-	AND time >= '1970-01-01T00:00:01Z'
-	AND time <= '1970-01-01T00:00:02Z'
-	GROUP BY s.id`,
-				"SELECT (labels_info($1::int[])).*",
-				"SELECT (labels_info($1::int[])).*"},
-			sqlArgs: [][]interface{}{
-				{"__name__", "^$"},
-				{"foo"},
-				nil,
-				{"bar"},
-				nil,
-				{[]int64{3}},
-				{[]int64{4}},
-			},
-			result: []*prompb.TimeSeries{
-				{
-					Labels:  []prompb.Label{{Name: MetricNameLabelName, Value: "foo"}},
-					Samples: []prompb.Sample{{Timestamp: toMilis(time.Unix(0, 0)), Value: 1}},
-				},
-				{
-					Labels:  []prompb.Label{{Name: MetricNameLabelName, Value: "bar"}},
-					Samples: []prompb.Sample{{Timestamp: toMilis(time.Unix(0, 0)), Value: 1}},
-				},
-			},
-			queryResults: []rowResults{
-				{{"foo", []int64{1}}, {"bar", []int64{1}}},
-				{{"foo"}},
-				{{[]int64{3}, []time.Time{time.Unix(0, 0)}, []float64{1}}},
-				{{"bar"}},
-				{{[]int64{4}, []time.Time{time.Unix(0, 0)}, []float64{1}}},
-				{{[]int64{3}, []string{"__name__"}, []string{"foo"}}},
-				{{[]int64{4}, []string{"__name__"}, []string{"bar"}}},
-			},
+			// existing labels are not overwritten
+			{Name: MetricNameLabelName, Value: "bar"},
+			{Name: "owner", Value: "alice"},
+			{Name: "service", Value: "api"},
+			{Name: "team", Value: "dev"},
 		},
 		{
-			name: "Simple query, double metric name matcher",
-			query: &prompb.Query{
-				StartTimestampMs: 1000,
-				EndTimestampMs:   2000,
-				Matchers: []*prompb.LabelMatcher{
-					{Type: prompb.LabelMatcher_EQ, Name: MetricNameLabelName, Value: "foo"},
-					{Type: prompb.LabelMatcher_EQ, Name: MetricNameLabelName, Value: "bar"},
-				},
-			},
-			sqlQueries: []string{`SELECT m.metric_name, array_agg(s.id)
-	FROM _prom_catalog.series s
-	INNER JOIN _prom_catalog.metric m
-	ON (m.id = s.metric_id)
-	WHERE labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $1 and l.value = $2) AND labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $3 and l.value = $4)
-	GROUP BY m.metric_name
-	ORDER BY m.metric_name`,
-				`SELECT table_name FROM _prom_catalog.get_metric_table_name_if_exists($1)`,
-				`SELECT s.labels, array_agg(m.time ORDER BY time), array_agg(m.value ORDER BY time)
-	FROM "prom_data"."foo" m
-	INNER JOIN "prom_data_series"."foo" s
-	ON m.series_id = s.id
-	WHERE m.series_id IN (1)
-	AND time >= '1970-01-01T00:00:01Z'
-	AND time <= '1970-01-01T00:00:02Z'
-	GROUP BY s.id`,
-				`SELECT table_name FROM _prom_catalog.get_metric_table_name_if_exists($1)`,
-				`SELECT s.labels, array_agg(m.time ORDER BY time), array_agg(m.value ORDER BY time)
-	FROM "prom_data"."bar" m
-	INNER JOIN "prom_data_series"."bar" s
-	ON m.series_id = s.id
-	WHERE m.series_id IN (1)
-	AND time >= '1970-01-01T00:00:01Z'
-	AND time <= '1970-01-01T00:00:02Z'
-	GROUP BY s.id`,
-				"SELECT (labels_info($1::int[])).*",
-				"SELECT (labels_info($1::int[])).*"},
-			sqlArgs: [][]interface{}{
-				{"__name__", "foo", "__name__", "bar"},
-				{"foo"},
-				nil,
-				{"bar"},
-				nil,
-				{[]int64{5}},
-				{[]int64{6}},
-			},
-			result: []*prompb.TimeSeries{
-				{
-					Labels:  []prompb.Label{{Name: MetricNameLabelName, Value: "foo"}},
-					Samples: []prompb.Sample{{Timestamp: toMilis(time.Unix(0, 0)), Value: 1}},
-				},
-				{
-					Labels:  []prompb.Label{{Name: MetricNameLabelName, Value: "bar"}},
-					Samples: []prompb.Sample{{Timestamp: toMilis(time.Unix(0, 0)), Value: 1}},
-				},
-			},
-			queryResults: []rowResults{
-				{{"foo", []int64{1}}, {"bar", []int64{1}}},
-				{{"foo"}},
-				{{[]int64{5}, []time.Time{time.Unix(0, 0)}, []float64{1}}},
-				{{"bar"}},
-				{{[]int64{6}, []time.Time{time.Unix(0, 0)}, []float64{1}}},
-				{{[]int64{5}, []string{"__name__"}, []string{"foo"}}},
-				{{[]int64{6}, []string{"__name__"}, []string{"bar"}}},
-			},
+			{Name: MetricNameLabelName, Value: "bar"},
+			{Name: "service", Value: "web"},
 		},
+	}
+	if len(result) != len(expected) {
+		t.Fatalf("unexpected number of series: got %d, wanted %d", len(result), len(expected))
+	}
+	for i, ts := range result {
+		if !reflect.DeepEqual(ts.Labels, expected[i]) {
+			t.Errorf("unexpected labels for series %d:\ngot\n%v\nwanted\n%v", i, ts.Labels, expected[i])
+		}
+	}
+
+	// the key of the api service is looked up only once
+	enrichQueries := 0
+	for _, q := range mock.QuerySQLs {
+		if q == enrichSQL {
+			enrichQueries++
+		}
+	}
+	if enrichQueries != 3 {
+		t.Errorf("unexpected number of enrichment queries: got %d, wanted 3", enrichQueries)
+	}
+}
+
+func TestWireFormatArgs(t *testing.T) {
+	args := []interface{}{"foo", 1}
+	testCases := []struct {
+		name      string
+		format    string
+		queryArgs []interface{}
+		execArgs  []interface{}
+	}{
 		{
-			name: "Simple query, no metric name matcher",
-			query: &prompb.Query{
-				StartTimestampMs: 1000,
-				EndTimestampMs:   2000,
-				Matchers: []*prompb.LabelMatcher{
-					{Type: prompb.LabelMatcher_EQ, Name: "foo", Value: "bar"},
-				},
-			},
-			sqlQueries: []string{`SELECT m.metric_name, array_agg(s.id)
-	FROM _prom_catalog.series s
-	INNER JOIN _prom_catalog.metric m
-	ON (m.id = s.metric_id)
-	WHERE labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $1 and l.value = $2)
-	GROUP BY m.metric_name
-	ORDER BY m.metric_name`,
-				`SELECT table_name FROM _prom_catalog.get_metric_table_name_if_exists($1)`,
-				`SELECT s.labels, array_agg(m.time ORDER BY time), array_agg(m.value ORDER BY time)
-	FROM "prom_data"."metric" m
-	INNER JOIN "prom_data_series"."metric" s
-	ON m.series_id = s.id
-	WHERE m.series_id IN (1,99,98)
-	AND time >= '1970-01-01T00:00:01Z'
-	AND time <= '1970-01-01T00:00:02Z'
-	GROUP BY s.id`,
-				"SELECT (labels_info($1::int[])).*"},
-			sqlArgs: [][]interface{}{
-				{"foo", "bar"},
-				{"metric"},
-				nil,
-				{[]int64{7}},
-			},
-			result: []*prompb.TimeSeries{
-				{
-					Labels:  []prompb.Label{{Name: "foo", Value: "bar"}},
-					Samples: []prompb.Sample{{Timestamp: toMilis(time.Unix(0, 0)), Value: 1}},
-				},
-			},
-			queryResults: []rowResults{
-				{{"metric", []int64{1, 99, 98}}},
-				{{"metric"}},
-				{{[]int64{7}, []time.Time{time.Unix(0, 0)}, []float64{1}}},
-				{{[]int64{7}, []string{"foo"}, []string{"bar"}}},
-			},
+			name:      "binary",
+			format:    "binary",
+			queryArgs: args,
+			execArgs:  args,
+		},
+		{
+			name:      "text",
+			format:    "text",
+			queryArgs: []interface{}{pgx.QueryResultFormats{pgx.TextFormatCode}, "foo", 1},
+			execArgs:  []interface{}{pgx.QuerySimpleProtocol(true), "foo", 1},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			format, err := ParseWireFormat(c.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if format.String() != c.format {
+				t.Errorf("unexpected format name: got %s, wanted %s", format, c.format)
+			}
+			if got := format.queryArgs(args); !reflect.DeepEqual(got, c.queryArgs) {
+				t.Errorf("unexpected query args: got %v, wanted %v", got, c.queryArgs)
+			}
+			if got := format.execArgs(args); !reflect.DeepEqual(got, c.execArgs) {
+				t.Errorf("unexpected exec args: got %v, wanted %v", got, c.execArgs)
+			}
+		})
+	}
+
+	if _, err := ParseWireFormat("json"); err == nil {
+		t.Error("expected an error for an invalid format")
+	}
+}
+
+func TestPGXInserterInsertFormat(t *testing.T) {
+	testCases := []struct {
+		name           string
+		format         WireFormat
+		values         []float64
+		simpleProtocol bool
+	}{
+		{
+			name:   "binary",
+			format: BinaryFormat,
+			values: []float64{1, 2},
+		},
+		{
+			name:           "text",
+			format:         TextFormat,
+			values:         []float64{1, 2},
+			simpleProtocol: true,
+		},
+		{
+			name:   "text with staleness marker",
+			format: TextFormat,
+			values: []float64{1, math.Float64frombits(value.StaleNaN)},
+		},
+	}
+	for _, co := range testCases {
+		c := co
+		t.Run(c.name, func(t *testing.T) {
+			mock := &mockPGXConn{}
+			mockMetrics := &mockMetricCache{
+				metricCache: map[string]string{"metric_0": "metricTableName_0"},
+			}
+			inserter, err := newPgxInserter(mock, mockMetrics, &Cfg{InsertFormat: c.format})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rows := createRows(1)
+			rows["metric_0"][0].samples = make([]prompb.Sample, len(c.values))
+			for i, v := range c.values {
+				rows["metric_0"][0].samples[i] = prompb.Sample{Timestamp: int64(i), Value: v}
+			}
+			if _, _, err = inserter.InsertData(context.Background(), rows); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if len(mock.InsertArgs) != 1 {
+				t.Fatalf("unexpected number of inserts: got %d, wanted 1", len(mock.InsertArgs))
+			}
+			_, simpleProtocol := mock.InsertArgs[0][0].(pgx.QuerySimpleProtocol)
+			if simpleProtocol != c.simpleProtocol {
+				t.Errorf("unexpected protocol: got simple %v, wanted %v", simpleProtocol, c.simpleProtocol)
+			}
+			for i, v := range c.values {
+				if math.Float64bits(mock.Vals[i]) != math.Float64bits(v) {
+					t.Errorf("unexpected value %d: got %x, wanted %x", i, math.Float64bits(mock.Vals[i]), math.Float64bits(v))
+				}
+			}
+		})
+	}
+}
+
+func TestPGXQuerierQueryReadHints(t *testing.T) {
+	testCases := []struct {
+		name     string
+		hints    *prompb.ReadHints
+		bucketed string
+	}{
+		{
+			name: "No hints",
 		},
 		{
-			name: "Complex query, multiple matchers",
-			query: &prompb.Query{
+			name:  "No step",
+			hints: &prompb.ReadHints{Func: "avg"},
+		},
+		{
+			name:  "Unsupported function",
+			hints: &prompb.ReadHints{StepMs: 60000, Func: "rate", StartMs: 1000},
+		},
+		{
+			name:     "Average",
+			hints:    &prompb.ReadHints{StepMs: 60000, Func: "avg", StartMs: 1000},
+			bucketed: "time_bucket(INTERVAL '60000 milliseconds', time, TIMESTAMPTZ '1970-01-01T00:00:01Z') AS time, COALESCE(avg(value) FILTER (WHERE NOT prom_api.is_stale_marker(value)), last(value, time)) AS value",
+		},
+		{
+			name:     "Maximum",
+			hints:    &prompb.ReadHints{StepMs: 30000, Func: "max", StartMs: 0},
+			bucketed: "time_bucket(INTERVAL '30000 milliseconds', time, TIMESTAMPTZ '1970-01-01T00:00:00Z') AS time, COALESCE(max(value) FILTER (WHERE NOT prom_api.is_stale_marker(value)), last(value, time)) AS value",
+		},
+		{
+			name:     "Last",
+			hints:    &prompb.ReadHints{StepMs: 30000, Func: "last", StartMs: 0},
+			bucketed: "time_bucket(INTERVAL '30000 milliseconds', time, TIMESTAMPTZ '1970-01-01T00:00:00Z') AS time, last(value, time) AS value",
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			mock := &mockPGXConn{
+				QueryResults: []rowResults{
+					{{[]int64{1}, []time.Time{time.Unix(0, 0), time.Unix(60, 0)}, []float64{1, 2}}},
+					{{[]int64{1}, []string{"__name__"}, []string{"foo"}}},
+				},
+			}
+			mockMetrics := &mockMetricCache{
+				metricCache: map[string]string{"foo": "foo"},
+			}
+			querier := pgxQuerier{conn: mock, metricTableNames: mockMetrics, labels: clockcache.WithMax(0)}
+
+			result, err := querier.Query(context.Background(), &prompb.Query{
 				StartTimestampMs: 1000,
-				EndTimestampMs:   2000,
+				EndTimestampMs:   120000,
 				Matchers: []*prompb.LabelMatcher{
-					{Type: prompb.LabelMatcher_EQ, Name: "foo", Value: "bar"},
-					{Type: prompb.LabelMatcher_NEQ, Name: "foo1", Value: "bar1"},
-					{Type: prompb.LabelMatcher_RE, Name: "foo2", Value: "^bar2"},
-					{Type: prompb.LabelMatcher_NRE, Name: "foo3", Value: "bar3$"},
-				},
-			},
-			sqlQueries: []string{`SELECT m.metric_name, array_agg(s.id)
-	FROM _prom_catalog.series s
-	INNER JOIN _prom_catalog.metric m
-	ON (m.id = s.metric_id)
-	WHERE labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $1 and l.value = $2) AND NOT labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $3 and l.value = $4) AND labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $5 and l.value ~ $6) AND NOT labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $7 and l.value ~ $8)
-	GROUP BY m.metric_name
-	ORDER BY m.metric_name`,
-				`SELECT table_name FROM _prom_catalog.get_metric_table_name_if_exists($1)`,
-				`SELECT s.labels, array_agg(m.time ORDER BY time), array_agg(m.value ORDER BY time)
-	FROM "prom_data"."metric" m
-	INNER JOIN "prom_data_series"."metric" s
-	ON m.series_id = s.id
-	WHERE m.series_id IN (1,4,5)
-	AND time >= '1970-01-01T00:00:01Z'
-	AND time <= '1970-01-01T00:00:02Z'
-	GROUP BY s.id`,
-				"SELECT (labels_info($1::int[])).*"},
-			sqlArgs: [][]interface{}{
-				{"foo", "bar", "foo1", "bar1", "foo2", "^bar2$", "foo3", "^bar3$"},
-				{"metric"},
-				nil,
-				{[]int64{9, 8}},
-			},
-			result: []*prompb.TimeSeries{
-				{
-					Labels: []prompb.Label{
-						{Name: "foo", Value: "bar"},
-						{Name: "foo2", Value: "bar2"},
-					},
-					Samples: []prompb.Sample{{Timestamp: toMilis(time.Unix(0, 0)), Value: 1}},
+					{Type: prompb.LabelMatcher_EQ, Name: MetricNameLabelName, Value: "foo"},
 				},
+				Hints: c.hints,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(mock.QuerySQLs) == 0 {
+				t.Fatal("no queries issued")
+			}
+			sql := mock.QuerySQLs[0]
+			if c.bucketed == "" && strings.Contains(sql, "time_bucket") {
+				t.Errorf("unexpected downsampling:\n%s", sql)
+			}
+			if c.bucketed != "" && !strings.Contains(sql, c.bucketed) {
+				t.Errorf("unexpected downsampling:\ngot\n%s\nwanted it to contain\n%s", sql, c.bucketed)
+			}
+
+			// the timestamps of the buckets are returned as they are
+			expected := []*prompb.TimeSeries{{
+				Labels:  []prompb.Label{{Name: MetricNameLabelName, Value: "foo"}},
+				Samples: []prompb.Sample{{Timestamp: 0, Value: 1}, {Timestamp: 60000, Value: 2}},
+			}}
+			if !reflect.DeepEqual(result, expected) {
+				t.Errorf("unexpected result: got %v, wanted %v", result, expected)
+			}
+		})
+	}
+}
+
+func TestBuildSubQueriesRegex(t *testing.T) {
+	testCases := []struct {
+		name    string
+		matcher *labels.Matcher
+		clause  string
+		value   string
+	}{
+		{
+			name:    "regex",
+			matcher: labels.MustNewMatcher(labels.MatchRegexp, "job", "api|db"),
+			clause:  "labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $1 and l.value ~ $2)",
+			value:   "^(?:api|db)$",
+		},
+		{
+			name:    "regex matching empty",
+			matcher: labels.MustNewMatcher(labels.MatchRegexp, "job", "api|"),
+			clause:  "NOT labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $1 and l.value !~ $2)",
+			value:   "^(?:api|)$",
+		},
+		{
+			name:    "empty regex",
+			matcher: labels.MustNewMatcher(labels.MatchRegexp, "job", ""),
+			clause:  "NOT labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $1 and l.value !~ $2)",
+			value:   "^$",
+		},
+		{
+			name:    "negated regex",
+			matcher: labels.MustNewMatcher(labels.MatchNotRegexp, "job", ".+"),
+			clause:  "NOT labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $1 and l.value ~ $2)",
+			value:   "^(?:.+)$",
+		},
+		{
+			name:    "negated regex matching empty",
+			matcher: labels.MustNewMatcher(labels.MatchNotRegexp, "job", "a.*"),
+			clause:  "NOT labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $1 and l.value ~ $2)",
+			value:   "^(?:a.*)$",
+		},
+		{
+			name:    "negated regex not matching empty",
+			matcher: labels.MustNewMatcher(labels.MatchNotRegexp, "job", ".*"),
+			clause:  "labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $1 and l.value !~ $2)",
+			value:   "^(?:.*)$",
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			_, clauses, values, err := buildSubQueries([]*labels.Matcher{c.matcher}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(clauses, []string{c.clause}) {
+				t.Errorf("unexpected clauses:\ngot\n%v\nwanted\n%v", clauses, c.clause)
+			}
+			if !reflect.DeepEqual(values, []interface{}{"job", c.value}) {
+				t.Errorf("unexpected values: got %v, wanted %v", values, []interface{}{"job", c.value})
+			}
+		})
+	}
+}
+
+func TestBuildSubQueriesCaseInsensitive(t *testing.T) {
+	caseInsensitive := newCaseInsensitiveLabels([]string{"host", MetricNameLabelName})
+	testCases := []struct {
+		name    string
+		matcher *labels.Matcher
+		clause  string
+	}{
+		{
+			name:    "equal",
+			matcher: labels.MustNewMatcher(labels.MatchEqual, "host", "Web-1"),
+			clause:  "labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $1 and lower(l.value) = lower($2))",
+		},
+		{
+			name:    "not equal",
+			matcher: labels.MustNewMatcher(labels.MatchNotEqual, "host", "Web-1"),
+			clause:  "NOT labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $1 and lower(l.value) = lower($2))",
+		},
+		{
+			name:    "regex",
+			matcher: labels.MustNewMatcher(labels.MatchRegexp, "host", "Web-.*"),
+			clause:  "labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $1 and l.value ~* $2)",
+		},
+		{
+			name:    "negated regex",
+			matcher: labels.MustNewMatcher(labels.MatchNotRegexp, "host", "Web-.*"),
+			clause:  "NOT labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $1 and l.value ~* $2)",
+		},
+		{
+			name:    "other label",
+			matcher: labels.MustNewMatcher(labels.MatchEqual, "job", "Api"),
+			clause:  "labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $1 and l.value = $2)",
+		},
+		{
+			name:    "metric name",
+			matcher: labels.MustNewMatcher(labels.MatchEqual, MetricNameLabelName, "Foo"),
+			clause:  "labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $1 and l.value = $2)",
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			_, clauses, _, err := buildSubQueries([]*labels.Matcher{c.matcher}, caseInsensitive)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(clauses, []string{c.clause}) {
+				t.Errorf("unexpected clauses:\ngot\n%v\nwanted\n%v", clauses, c.clause)
+			}
+		})
+	}
+}
+
+func TestPGXQuerierQueryCaseInsensitive(t *testing.T) {
+	mock := &mockPGXConn{
+		QueryResults: []rowResults{
+			{{"foo"}},
+			{
+				{[]int64{1, 2}, []time.Time{time.Unix(1, 0)}, []float64{1}},
+				{[]int64{1, 3}, []time.Time{time.Unix(1, 0)}, []float64{2}},
+				// not matching, e.g. lowercased differently by the database
+				{[]int64{1, 4}, []time.Time{time.Unix(1, 0)}, []float64{3}},
 			},
-			queryResults: []rowResults{
-				{{"metric", []int64{1, 4, 5}}},
-				{{"metric"}},
-				{{[]int64{8, 9}, []time.Time{time.Unix(0, 0)}, []float64{1}}},
-				{{[]int64{8, 9}, []string{"foo", "foo2"}, []string{"bar", "bar2"}}},
-			},
+			{{[]int64{1, 2}, []string{"__name__", "host"}, []string{"foo", "web-1"}}},
+			{{[]int64{3}, []string{"host"}, []string{"WEB-1"}}},
+			{{[]int64{4}, []string{"host"}, []string{"web-2"}}},
+		},
+	}
+	mockMetrics := &mockMetricCache{
+		metricCache: make(map[string]string),
+	}
+	querier := pgxQuerier{conn: mock, metricTableNames: mockMetrics, labels: clockcache.WithMax(0), caseInsensitive: newCaseInsensitiveLabels([]string{"host"})}
+
+	result, err := querier.Query(context.Background(), &prompb.Query{
+		StartTimestampMs: 1000,
+		EndTimestampMs:   2000,
+		Matchers: []*prompb.LabelMatcher{
+			{Type: prompb.LabelMatcher_EQ, Name: MetricNameLabelName, Value: "foo"},
+			{Type: prompb.LabelMatcher_EQ, Name: "host", Value: "Web-1"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(mock.QuerySQLs) < 2 || !strings.Contains(mock.QuerySQLs[1], "lower(l.value) = lower(") {
+		t.Errorf("expected a case-insensitive data query, got %v", mock.QuerySQLs)
+	}
+	expected := []*prompb.TimeSeries{
+		{
+			Labels:  []prompb.Label{{Name: MetricNameLabelName, Value: "foo"}, {Name: "host", Value: "web-1"}},
+			Samples: []prompb.Sample{{Timestamp: 1000, Value: 1}},
 		},
 		{
-			name: "Complex query, empty equal matchers",
-			query: &prompb.Query{
+			Labels:  []prompb.Label{{Name: MetricNameLabelName, Value: "foo"}, {Name: "host", Value: "WEB-1"}},
+			Samples: []prompb.Sample{{Timestamp: 1000, Value: 2}},
+		},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("unexpected result: got %v, wanted %v", result, expected)
+	}
+}
+
+func TestPGXQuerierQueryMetricNamePrefix(t *testing.T) {
+	mock := &mockPGXConn{
+		QueryResults: []rowResults{
+			{{"foo"}},
+			{{[]int64{1, 2}, []time.Time{time.Unix(1, 0)}, []float64{1}}},
+			{{[]int64{1, 2}, []string{"__name__", "job"}, []string{"foo", "api"}}},
+		},
+	}
+	mockMetrics := &mockMetricCache{
+		metricCache: make(map[string]string),
+	}
+	querier := pgxQuerier{conn: mock, metricTableNames: mockMetrics, labels: clockcache.WithMax(0), metricNamePrefix: "cluster_a:"}
+
+	result, err := querier.Query(context.Background(), &prompb.Query{
+		StartTimestampMs: 1000,
+		EndTimestampMs:   2000,
+		Matchers: []*prompb.LabelMatcher{
+			{Type: prompb.LabelMatcher_EQ, Name: MetricNameLabelName, Value: "foo"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the metric is selected by its stored name
+	if len(mock.QueryArgs) == 0 || !reflect.DeepEqual(mock.QueryArgs[0], []interface{}{"foo"}) {
+		t.Errorf("unexpected metric table lookup: %v", mock.QueryArgs)
+	}
+	expected := []*prompb.TimeSeries{{
+		Labels:  []prompb.Label{{Name: MetricNameLabelName, Value: "cluster_a:foo"}, {Name: "job", Value: "api"}},
+		Samples: []prompb.Sample{{Timestamp: 1000, Value: 1}},
+	}}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("unexpected result: got %v, wanted %v", result, expected)
+	}
+}
+
+func TestIsUnbounded(t *testing.T) {
+	testCases := []struct {
+		matchers  []*labels.Matcher
+		unbounded bool
+	}{
+		{unbounded: true},
+		{matchers: []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, MetricNameLabelName, "")}, unbounded: true},
+		{matchers: []*labels.Matcher{labels.MustNewMatcher(labels.MatchRegexp, MetricNameLabelName, ".*")}, unbounded: true},
+		{matchers: []*labels.Matcher{labels.MustNewMatcher(labels.MatchNotEqual, MetricNameLabelName, "")}, unbounded: true},
+		{matchers: []*labels.Matcher{labels.MustNewMatcher(labels.MatchNotRegexp, MetricNameLabelName, "")}, unbounded: true},
+		{matchers: []*labels.Matcher{labels.MustNewMatcher(labels.MatchRegexp, MetricNameLabelName, ".+")}, unbounded: true},
+		{matchers: []*labels.Matcher{labels.MustNewMatcher(labels.MatchRegexp, "job", ".+"), labels.MustNewMatcher(labels.MatchNotEqual, MetricNameLabelName, "")}, unbounded: true},
+		{matchers: []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, MetricNameLabelName, "foo")}},
+		{matchers: []*labels.Matcher{labels.MustNewMatcher(labels.MatchRegexp, MetricNameLabelName, "foo.+")}},
+		{matchers: []*labels.Matcher{labels.MustNewMatcher(labels.MatchNotEqual, MetricNameLabelName, "foo")}},
+		{matchers: []*labels.Matcher{labels.MustNewMatcher(labels.MatchNotEqual, MetricNameLabelName, ""), labels.MustNewMatcher(labels.MatchEqual, "job", "api")}},
+	}
+	for _, c := range testCases {
+		if got := isUnbounded(c.matchers); got != c.unbounded {
+			t.Errorf("unexpected result for %v: got %v, wanted %v", c.matchers, got, c.unbounded)
+		}
+	}
+}
+
+func TestReaderCfgDefaults(t *testing.T) {
+	// the zero value behaves like the default flags
+	r := NewPgxReaderWithCfg(nil, nil, &ReaderCfg{})
+	q := r.GetQuerier().(*pgxQuerier)
+	if q.allowUnbounded {
+		t.Errorf("unbounded queries allowed by default")
+	}
+	if !q.propagateCancel {
+		t.Errorf("cancel not propagated by default")
+	}
+	if !q.failOnDecodeErrors {
+		t.Errorf("decode errors skipped by default")
+	}
+	if q.schemaChangeRetryDelay != DefaultSchemaChangeRetryDelay {
+		t.Errorf("unexpected schema change retry delay: got %v, wanted %v", q.schemaChangeRetryDelay, DefaultSchemaChangeRetryDelay)
+	}
+
+	r = NewPgxReaderWithCfg(nil, nil, &ReaderCfg{SchemaChangeRetryDelay: -1})
+	if q := r.GetQuerier().(*pgxQuerier); q.schemaChangeRetryDelay != 0 {
+		t.Errorf("reads retried with a negative delay: %v", q.schemaChangeRetryDelay)
+	}
+}
+
+func TestPGXQuerierPropagateCancel(t *testing.T) {
+	for _, propagate := range []bool{true, false} {
+		t.Run(fmt.Sprintf("propagate=%v", propagate), func(t *testing.T) {
+			mock := &mockPGXConn{
+				QueryResults: []rowResults{
+					{{"foo"}},
+					{{[]int64{1, 2}, []time.Time{time.Unix(1, 0)}, []float64{1}}},
+					{{[]int64{1, 2}, []string{"__name__", "job"}, []string{"foo", "api"}}},
+				},
+			}
+			mockMetrics := &mockMetricCache{
+				metricCache: make(map[string]string),
+			}
+			querier := pgxQuerier{conn: mock, metricTableNames: mockMetrics, labels: clockcache.WithMax(0), propagateCancel: propagate}
+
+			// the request is canceled while its data query runs
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, err := querier.Query(ctx, &prompb.Query{
 				StartTimestampMs: 1000,
 				EndTimestampMs:   2000,
 				Matchers: []*prompb.LabelMatcher{
-					{Type: prompb.LabelMatcher_EQ, Name: "foo", Value: ""},
-					{Type: prompb.LabelMatcher_NEQ, Name: "foo1", Value: "bar1"},
-					{Type: prompb.LabelMatcher_RE, Name: "foo2", Value: "^bar2$"},
-					{Type: prompb.LabelMatcher_NRE, Name: "foo3", Value: "bar3"},
-				},
-			},
-			sqlQueries: []string{`SELECT m.metric_name, array_agg(s.id)
-	FROM _prom_catalog.series s
-	INNER JOIN _prom_catalog.metric m
-	ON (m.id = s.metric_id)
-	WHERE NOT labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $1 and l.value != $2) AND NOT labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $3 and l.value = $4) AND labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $5 and l.value ~ $6) AND NOT labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $7 and l.value ~ $8)
-	GROUP BY m.metric_name
-	ORDER BY m.metric_name`,
-				`SELECT table_name FROM _prom_catalog.get_metric_table_name_if_exists($1)`,
-				`SELECT s.labels, array_agg(m.time ORDER BY time), array_agg(m.value ORDER BY time)
-	FROM "prom_data"."metric" m
-	INNER JOIN "prom_data_series"."metric" s
-	ON m.series_id = s.id
-	WHERE m.series_id IN (1,2)
-	AND time >= '1970-01-01T00:00:01Z'
-	AND time <= '1970-01-01T00:00:02Z'
-	GROUP BY s.id`,
-				"SELECT (labels_info($1::int[])).*"},
-			sqlArgs: [][]interface{}{
-				{"foo", "", "foo1", "bar1", "foo2", "^bar2$", "foo3", "^bar3$"},
-				{"metric"},
-				nil,
-				{[]int64{10}},
-			},
-			result: []*prompb.TimeSeries{
-				{
-					Labels: []prompb.Label{
-						{Name: "foo2", Value: "bar2"},
-					},
-					Samples: []prompb.Sample{{Timestamp: toMilis(time.Unix(0, 0)), Value: 1}},
+					{Type: prompb.LabelMatcher_EQ, Name: MetricNameLabelName, Value: "foo"},
 				},
-			},
-			queryResults: []rowResults{
-				{{"metric", []int64{1, 2}}},
-				{{"metric"}},
-				{{[]int64{10}, []time.Time{time.Unix(0, 0)}, []float64{1}}},
-				{{[]int64{10}, []string{"foo2"}, []string{"bar2"}}},
-			},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(mock.QueryCtxErrs) < 2 {
+				t.Fatalf("expected a data query, got queries %v", mock.QuerySQLs)
+			}
+			queryErr := mock.QueryCtxErrs[1]
+			if propagate && queryErr != context.Canceled {
+				t.Errorf("data query not canceled with the request: %v", queryErr)
+			}
+			if !propagate && queryErr != nil {
+				t.Errorf("data query canceled although propagation is disabled: %v", queryErr)
+			}
+			if len(mock.QueryCtxErrs) < 3 {
+				t.Fatalf("expected a labels query, got queries %v", mock.QuerySQLs)
+			}
+			labelsErr := mock.QueryCtxErrs[2]
+			if propagate && labelsErr != context.Canceled {
+				t.Errorf("labels query not canceled with the request: %v", labelsErr)
+			}
+			if !propagate && labelsErr != nil {
+				t.Errorf("labels query canceled although propagation is disabled: %v", labelsErr)
+			}
+		})
+	}
+}
+
+func TestPGXQuerierHealthCheck(t *testing.T) {
+	testCases := []struct {
+		name    string
+		pingErr error
+		missing rowResults
+		status  HealthStatus
+	}{
+		{
+			name: "healthy",
+		},
+		{
+			name:    "cannot connect",
+			pingErr: fmt.Errorf("connection refused"),
+			status:  DBUnreachable,
+		},
+		{
+			name:    "schema not initialized",
+			missing: rowResults{{"_prom_catalog.series"}},
+			status:  SchemaMissing,
 		},
 	}
 
 	for _, c := range testCases {
 		t.Run(c.name, func(t *testing.T) {
 			mock := &mockPGXConn{
-				QueryErr:     c.queryErr,
-				QueryResults: c.queryResults,
-			}
-			metricCache := map[string]string{"metric_1": "metricTableName_1"}
-			mockMetrics := &mockMetricCache{
-				metricCache: metricCache,
-				//getMetricErr: c.metricsGetErr,
-				//setMetricErr: c.metricsSetErr,
+				PingErr:      c.pingErr,
+				QueryResults: []rowResults{c.missing},
 			}
-			querier := pgxQuerier{conn: mock, metricTableNames: mockMetrics, labels: clockcache.WithMax(0)}
+			querier := pgxQuerier{conn: mock, labels: clockcache.WithMax(0)}
 
-			result, err := querier.Query(c.query)
-
-			if err != nil {
-				switch {
-				case len(c.queryErr) > 0:
-					for _, qErr := range c.queryErr {
-						if err != qErr {
-							t.Errorf("unexpected error:\ngot\n%s\nwanted\n%s", err, qErr)
-						}
-					}
-				case c.err != nil:
-					if err.Error() != c.err.Error() {
-						t.Errorf("unexpected error:\ngot\n%#v\nwanted\n%#v", err, c.err)
-					}
-				default:
-					t.Errorf("unexpected error for query:\ngot\n%s\nwanted nil", err)
+			err := querier.HealthCheck()
+			if c.status == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			} else {
+				var healthErr *HealthError
+				if !errors.As(err, &healthErr) || healthErr.Status != c.status {
+					t.Fatalf("unexpected error: got %v, wanted status %v", err, c.status)
 				}
 			}
 
-			if !reflect.DeepEqual(result, c.result) {
-				t.Errorf("unexpected result:\ngot\n%v\nwanted\n%v", result, c.result)
+			if c.pingErr != nil {
+				if len(mock.QuerySQLs) != 0 {
+					t.Errorf("schema checked although the database is unreachable")
+				}
+				return
 			}
+			if len(mock.QuerySQLs) != 1 || mock.QuerySQLs[0] != missingSchemaTablesSQL {
+				t.Errorf("unexpected queries: %v", mock.QuerySQLs)
+			}
+			if !reflect.DeepEqual(mock.QueryArgs[0], []interface{}{schemaTables}) {
+				t.Errorf("unexpected tables checked: %v", mock.QueryArgs[0])
+			}
+		})
+	}
+}
 
-			if len(c.sqlQueries) != 0 {
-				if !reflect.DeepEqual(c.sqlQueries, mock.QuerySQLs) {
-					gotten := make(map[string]struct{}, len(c.sqlQueries))
-					for _, g := range mock.QuerySQLs {
-						gotten[g] = struct{}{}
-						t.Logf("got query:\n\t%s", g)
-					}
-
-					expected := make(map[string]struct{}, len(mock.QuerySQLs))
-					for _, e := range c.sqlQueries {
-						expected[e] = struct{}{}
-						t.Logf("expected query:\n\t%s", e)
-					}
-
-					missingOrExtra := false
-					for i, g := range mock.QuerySQLs {
-						if _, found := expected[g]; !found {
-							t.Errorf("extra SQL query %d:\n\t%s", i, g)
-							missingOrExtra = true
-						}
-					}
+func TestPGXQuerierMaxQueryDuration(t *testing.T) {
+	testCases := []struct {
+		name        string
+		maxDuration time.Duration
+		delay       time.Duration
+		labelsDelay time.Duration
+		err         error
+	}{
+		{
+			name:        "query within the maximum duration",
+			maxDuration: time.Minute,
+			delay:       time.Millisecond,
+		},
+		{
+			name:        "query exceeding the maximum duration",
+			maxDuration: 10 * time.Millisecond,
+			delay:       time.Minute,
+			err:         ErrQueryTimeout,
+		},
+		{
+			name:        "labels query exceeding the maximum duration",
+			maxDuration: 10 * time.Millisecond,
+			labelsDelay: time.Minute,
+			err:         ErrQueryTimeout,
+		},
+		{
+			name:  "no maximum duration",
+			delay: time.Millisecond,
+		},
+	}
 
-					for i, e := range c.sqlQueries {
-						if _, found := gotten[e]; !found {
-							t.Errorf("missing SQL query %d:\n\t%s", i, e)
-							missingOrExtra = true
-						}
-					}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			mock := &mockPGXConn{
+				QueryResults: []rowResults{
+					{{"foo"}},
+					{{[]int64{1, 2}, []time.Time{time.Unix(1, 0)}, []float64{1}}},
+					{{[]int64{1, 2}, []string{"__name__", "job"}, []string{"foo", "api"}}},
+				},
+				// the data query and the labels query
+				QueryDelay: map[int]time.Duration{1: c.delay, 2: c.labelsDelay},
+			}
+			mockMetrics := &mockMetricCache{
+				metricCache: make(map[string]string),
+			}
+			querier := pgxQuerier{conn: mock, metricTableNames: mockMetrics, labels: clockcache.WithMax(0), propagateCancel: true, maxQueryDuration: c.maxDuration}
 
-					if !missingOrExtra {
-						t.Errorf("mis-ordered sql queries:\ngot\n%#v\nwanted\n%#v\n", mock.QuerySQLs, c.sqlQueries)
-					}
-				}
-			} else if len(mock.QuerySQLs) > 0 {
-				t.Errorf("incorrect sql queries:\ngot\n%#v\nwanted none", mock.QuerySQLs)
+			start := time.Now()
+			result, err := querier.Query(context.Background(), &prompb.Query{
+				StartTimestampMs: 1000,
+				EndTimestampMs:   2000,
+				Matchers: []*prompb.LabelMatcher{
+					{Type: prompb.LabelMatcher_EQ, Name: MetricNameLabelName, Value: "foo"},
+				},
+			})
+			if !errors.Is(err, c.err) {
+				t.Fatalf("unexpected error: got %v, wanted %v", err, c.err)
 			}
-			if len(c.sqlArgs) != 0 {
-				if !reflect.DeepEqual(c.sqlArgs, mock.QueryArgs) {
-					t.Errorf("incorrect sql arguments:\ngot\n%#v\nwanted\n%#v\n", mock.QueryArgs, c.sqlArgs)
+			if c.err != nil {
+				if time.Since(start) > 30*time.Second {
+					t.Errorf("query not ended at the maximum duration")
 				}
-			} else if len(mock.QueryArgs) > 0 {
-				t.Errorf("incorrect sql arguments:\ngot\n%#v\nwanted none", mock.QueryArgs)
+				return
+			}
+			if len(result) != 1 {
+				t.Errorf("unexpected result: %v", result)
 			}
 		})
 	}
 }
 
-func TestPgxQuerierLabelsNames(t *testing.T) {
-	testLabelMethods(t, func(querier *pgxQuerier) ([]string, error) {
-		return querier.LabelNames()
+func TestPGXQuerierLabelsQueryContext(t *testing.T) {
+	t.Run("canceled request", func(t *testing.T) {
+		mock := &mockPGXConn{QueryResults: []rowResults{{{"job"}}}}
+		querier := pgxQuerier{conn: mock, propagateCancel: true}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := querier.LabelNames(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if len(mock.QueryCtxErrs) != 1 || mock.QueryCtxErrs[0] != context.Canceled {
+			t.Errorf("label query not canceled with the request: %v", mock.QueryCtxErrs)
+		}
+	})
+
+	t.Run("query exceeding the maximum duration", func(t *testing.T) {
+		mock := &mockPGXConn{
+			QueryResults: []rowResults{{{"api"}}},
+			QueryDelay:   map[int]time.Duration{0: time.Minute},
+		}
+		querier := pgxQuerier{conn: mock, propagateCancel: true, maxQueryDuration: 10 * time.Millisecond}
+
+		start := time.Now()
+		_, err := querier.LabelValues(context.Background(), "job")
+		if !errors.Is(err, ErrQueryTimeout) {
+			t.Fatalf("unexpected error: got %v, wanted %v", err, ErrQueryTimeout)
+		}
+		if time.Since(start) > 30*time.Second {
+			t.Errorf("query not ended at the maximum duration")
+		}
 	})
 }
 
-func TestPgxQuerierLabelsValues(t *testing.T) {
-	testLabelMethods(t, func(querier *pgxQuerier) ([]string, error) {
-		return querier.LabelValues("m")
+func TestQueryRowsCancelsOnLastClose(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	rows := queryRows(ctx, []pgx.Rows{&mockRows{}, &mockRows{}}, cancel)
+
+	rows[0].Close()
+	rows[0].Close()
+	if ctx.Err() != nil {
+		t.Fatalf("query context canceled while rows are open")
+	}
+	rows[1].Close()
+	if ctx.Err() != context.Canceled {
+		t.Errorf("query context not canceled after all rows are closed")
+	}
+}
+
+func TestPGXQuerierQueryMetrics(t *testing.T) {
+	mock := &mockPGXConn{
+		QueryResults: []rowResults{
+			{{"foo"}},
+			{
+				{[]int64{1, 2}, []time.Time{time.Unix(1, 0)}, []float64{1}},
+				{[]int64{1, 3}, []time.Time{time.Unix(1, 0)}, []float64{2}},
+			},
+			{{[]int64{1, 2}, []string{"__name__", "job"}, []string{"foo", "api"}}},
+			{{[]int64{3}, []string{"job"}, []string{"db"}}},
+		},
+	}
+	mockMetrics := &mockMetricCache{
+		metricCache: make(map[string]string),
+	}
+	querier := pgxQuerier{conn: mock, metricTableNames: mockMetrics, labels: clockcache.WithMax(10)}
+
+	histogram := func(h prometheus.Histogram) *dto.Histogram {
+		m := &dto.Metric{}
+		if err := h.Write(m); err != nil {
+			t.Fatal(err)
+		}
+		return m.GetHistogram()
+	}
+	seriesBefore := testutil.ToFloat64(readSeries)
+	lookupsBefore := testutil.ToFloat64(labelLookups)
+	labelQueriesBefore := testutil.ToFloat64(labelQueries)
+	durationsBefore := histogram(readDuration).GetSampleCount()
+	rowsBefore := histogram(readRowsPerQuery)
+
+	result, err := querier.Query(context.Background(), &prompb.Query{
+		StartTimestampMs: 1000,
+		EndTimestampMs:   2000,
+		Matchers: []*prompb.LabelMatcher{
+			{Type: prompb.LabelMatcher_EQ, Name: MetricNameLabelName, Value: "foo"},
+		},
 	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 2 {
+		t.Fatalf("unexpected number of series: got %d, wanted 2", len(result))
+	}
+
+	if got := testutil.ToFloat64(readSeries) - seriesBefore; got != 2 {
+		t.Errorf("unexpected number of series counted: got %v, wanted 2", got)
+	}
+	// the labels of each series are resolved, the shared __name__ label
+	// is only fetched once
+	if got := testutil.ToFloat64(labelLookups) - lookupsBefore; got != 2 {
+		t.Errorf("unexpected number of label lookups: got %v, wanted 2", got)
+	}
+	if got := testutil.ToFloat64(labelQueries) - labelQueriesBefore; got != 2 {
+		t.Errorf("unexpected number of label queries: got %v, wanted 2", got)
+	}
+	if got := histogram(readDuration).GetSampleCount() - durationsBefore; got != 1 {
+		t.Errorf("unexpected number of durations observed: got %d, wanted 1", got)
+	}
+	rowsAfter := histogram(readRowsPerQuery)
+	if got := rowsAfter.GetSampleCount() - rowsBefore.GetSampleCount(); got != 1 {
+		t.Errorf("unexpected number of data queries observed: got %d, wanted 1", got)
+	}
+	if got := rowsAfter.GetSampleSum() - rowsBefore.GetSampleSum(); got != 2 {
+		t.Errorf("unexpected number of rows observed: got %v, wanted 2", got)
+	}
 }
 
-func testLabelMethods(t *testing.T, f func(*pgxQuerier) ([]string, error)) {
+func TestPGXQuerierSchemaChangeRetry(t *testing.T) {
+	schemaChangeErr := &pgconn.PgError{Code: pgerrcode.FeatureNotSupported, Message: "cached plan must not change result type"}
 	testCases := []struct {
 		name         string
-		expectedRes  []string
-		errorOnQuery bool
-		errorOnScan  bool
+		queryErr     error
+		retryDelay   time.Duration
+		waitForLock  bool
+		expectedErr  error
+		dataQueries  int
+		lockQueries  int
+		retriesDelta float64
 	}{
 		{
-			name:         "Error on query",
-			errorOnQuery: true,
-		}, {
-			name:        "Error on scanning values",
-			errorOnScan: true,
-			expectedRes: []string{"a"},
-		}, {
-			name:        "Empty result, is ok",
-			expectedRes: []string{},
-		}, {
-			name:        "Result should be sorted",
-			expectedRes: []string{"b", "a"},
+			name:        "no error",
+			retryDelay:  time.Millisecond,
+			dataQueries: 1,
+		},
+		{
+			name:         "schema change, retried",
+			queryErr:     schemaChangeErr,
+			retryDelay:   time.Millisecond,
+			dataQueries:  2,
+			retriesDelta: 1,
+		},
+		{
+			name:        "schema change, retries disabled",
+			queryErr:    schemaChangeErr,
+			expectedErr: schemaChangeErr,
+			dataQueries: 1,
+		},
+		{
+			name:        "other error, not retried",
+			queryErr:    &pgconn.PgError{Code: pgerrcode.SyntaxError},
+			retryDelay:  time.Millisecond,
+			expectedErr: &pgconn.PgError{Code: pgerrcode.SyntaxError},
+			dataQueries: 1,
+		},
+		{
+			name:        "wait for migrations",
+			retryDelay:  time.Millisecond,
+			waitForLock: true,
+			dataQueries: 1,
+			lockQueries: 1,
 		},
 	}
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			queryResults := toRowResults(tc.expectedRes, tc.errorOnScan)
-			queryErr := make(map[int]error)
-			if tc.errorOnQuery {
-				queryErr[0] = fmt.Errorf("some error")
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			results := []rowResults{
+				{{[]int64{1}, []time.Time{time.Unix(1, 0)}, []float64{1}}},
+				{{[]int64{1}, []string{"__name__"}, []string{"foo"}}},
+			}
+			queryErr := map[int]error{}
+			if c.waitForLock {
+				results = append([]rowResults{{}}, results...)
+			}
+			if c.queryErr != nil {
+				// the first attempt fails, a retry gets the results
+				results = append([]rowResults{{}}, results...)
+				queryErr[0] = c.queryErr
 			}
 			mock := &mockPGXConn{
+				QueryResults: results,
 				QueryErr:     queryErr,
-				QueryResults: queryResults,
 			}
-			querier := pgxQuerier{conn: mock}
-			res, err := f(&querier)
-			if tc.errorOnQuery && err == nil {
-				t.Error("unexpected lack of error")
-				return
-			} else if tc.errorOnScan && err == nil {
-				t.Error("unexpected lack of error")
-				return
-			} else if err != nil && !tc.errorOnQuery && !tc.errorOnScan {
-				t.Errorf("unexpected error: %v", err)
-				return
-			} else if tc.errorOnQuery || tc.errorOnScan {
-				return
+			mockMetrics := &mockMetricCache{
+				metricCache: map[string]string{"foo": "foo"},
 			}
-			outputIsSorted := sort.SliceIsSorted(res, func(i, j int) bool {
-				return res[i] < res[j]
+			querier := pgxQuerier{
+				conn:                   mock,
+				metricTableNames:       mockMetrics,
+				labels:                 clockcache.WithMax(0),
+				schemaChangeRetryDelay: c.retryDelay,
+				waitForMigrationLock:   c.waitForLock,
+			}
+
+			retries := testutil.ToFloat64(readRetries)
+			result, err := querier.Query(context.Background(), &prompb.Query{
+				StartTimestampMs: 1000,
+				EndTimestampMs:   2000,
+				Matchers: []*prompb.LabelMatcher{
+					{Type: prompb.LabelMatcher_EQ, Name: MetricNameLabelName, Value: "foo"},
+				},
 			})
-			if !outputIsSorted {
-				t.Error("returned label names are not sorted")
+			if !reflect.DeepEqual(err, c.expectedErr) {
+				t.Fatalf("unexpected error: got %v, wanted %v", err, c.expectedErr)
 			}
-			sort.Strings(tc.expectedRes)
-			if !reflect.DeepEqual(tc.expectedRes, res) {
-				t.Errorf("expected: %v, got: %v", tc.expectedRes, res)
+			if err == nil && len(result) != 1 {
+				t.Errorf("unexpected result: %v", result)
+			}
+
+			dataQueries, lockQueries := 0, 0
+			for i, sql := range mock.QuerySQLs {
+				switch {
+				case strings.HasPrefix(sql, "SELECT s.labels"):
+					dataQueries++
+				case sql == waitForMigrationsSQL:
+					lockQueries++
+					if !reflect.DeepEqual(mock.QueryArgs[i], []interface{}{migrationLockID}) {
+						t.Errorf("unexpected lock args: %v", mock.QueryArgs[i])
+					}
+				}
+			}
+			if dataQueries != c.dataQueries {
+				t.Errorf("unexpected number of data queries: got %d, wanted %d", dataQueries, c.dataQueries)
+			}
+			if lockQueries != c.lockQueries {
+				t.Errorf("unexpected number of lock queries: got %d, wanted %d", lockQueries, c.lockQueries)
+			}
+			if got := testutil.ToFloat64(readRetries) - retries; got != c.retriesDelta {
+				t.Errorf("unexpected number of retries: got %v, wanted %v", got, c.retriesDelta)
 			}
 		})
 	}
 }
-func toRowResults(labelNames []string, convertImproperly bool) []rowResults {
-	toReturn := make([]rowResults, 1)
-	toReturn[0] = make(rowResults, len(labelNames))
-	for i, labelName := range labelNames {
-		if convertImproperly {
-			toReturn[0][i] = []interface{}{1}
-		} else {
-			toReturn[0][i] = []interface{}{labelName}
-		}
+
+func TestPgxQuerierLabelsWithMatchers(t *testing.T) {
+	matchers := []*labels.Matcher{
+		labels.MustNewMatcher(labels.MatchEqual, MetricNameLabelName, "foo"),
+		labels.MustNewMatcher(labels.MatchRegexp, "job", "api|db"),
+	}
+	seriesClauses := "labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $1 and l.value = $2) AND " +
+		"labels && (SELECT COALESCE(array_agg(l.id), array[]::int[]) FROM _prom_catalog.label l WHERE l.key = $3 and l.value ~ $4)"
+
+	testCases := []struct {
+		name         string
+		call         func(*pgxQuerier) ([]string, error)
+		queryResults []rowResults
+		expectedSQL  string
+		expectedArgs []interface{}
+		expectedRes  []string
+	}{
+		{
+			name: "label names",
+			call: func(q *pgxQuerier) ([]string, error) {
+				return q.LabelNames(context.Background(), matchers...)
+			},
+			queryResults: []rowResults{{{"job"}, {"__name__"}}},
+			expectedSQL: `SELECT DISTINCT l.key
+	FROM _prom_catalog.label l
+	WHERE l.id IN (SELECT unnest(s.labels) FROM _prom_catalog.series s WHERE ` + seriesClauses + `)`,
+			expectedArgs: []interface{}{"__name__", "foo", "job", "^(?:api|db)$"},
+			expectedRes:  []string{"__name__", "job"},
+		},
+		{
+			name: "label values",
+			call: func(q *pgxQuerier) ([]string, error) {
+				return q.LabelValues(context.Background(), "job", matchers...)
+			},
+			queryResults: []rowResults{{{"db"}, {"api"}}},
+			expectedSQL: `SELECT DISTINCT l.value
+	FROM _prom_catalog.label l
+	WHERE l.key = $5
+	AND l.id IN (SELECT unnest(s.labels) FROM _prom_catalog.series s WHERE ` + seriesClauses + `)`,
+			expectedArgs: []interface{}{"__name__", "foo", "job", "^(?:api|db)$", "job"},
+			expectedRes:  []string{"api", "db"},
+		},
+		{
+			name: "no series selected",
+			call: func(q *pgxQuerier) ([]string, error) {
+				return q.LabelNames(context.Background(), matchers...)
+			},
+			queryResults: []rowResults{{}},
+			expectedSQL: `SELECT DISTINCT l.key
+	FROM _prom_catalog.label l
+	WHERE l.id IN (SELECT unnest(s.labels) FROM _prom_catalog.series s WHERE ` + seriesClauses + `)`,
+			expectedArgs: []interface{}{"__name__", "foo", "job", "^(?:api|db)$"},
+			expectedRes:  []string{},
+		},
+		{
+			name: "no matchers",
+			call: func(q *pgxQuerier) ([]string, error) {
+				return q.LabelValues(context.Background(), "job")
+			},
+			queryResults: []rowResults{{{"db"}, {"api"}}},
+			expectedSQL:  getLabelValuesSQL,
+			expectedArgs: []interface{}{"job"},
+			expectedRes:  []string{"api", "db"},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			mock := &mockPGXConn{QueryResults: c.queryResults}
+			querier := &pgxQuerier{conn: mock}
+
+			res, err := c.call(querier)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(res, c.expectedRes) {
+				t.Errorf("unexpected result: got %v, wanted %v", res, c.expectedRes)
+			}
+			if len(mock.QuerySQLs) != 1 || mock.QuerySQLs[0] != c.expectedSQL {
+				t.Errorf("unexpected sql:\ngot\n%v\nwanted\n%s", mock.QuerySQLs, c.expectedSQL)
+			}
+			if len(mock.QueryArgs) != 1 || !reflect.DeepEqual(mock.QueryArgs[0], c.expectedArgs) {
+				t.Errorf("unexpected args: got %v, wanted %v", mock.QueryArgs, c.expectedArgs)
+			}
+		})
 	}
-	return toReturn
 }
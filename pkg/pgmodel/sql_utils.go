@@ -45,11 +45,15 @@ type pgxConn interface {
 	CopyFromRows(rows [][]interface{}) pgx.CopyFromSource
 	NewBatch() pgxBatch
 	SendBatch(ctx context.Context, b pgxBatch) (pgx.BatchResults, error)
+	// Ping checks that a connection to the database can be made.
+	Ping(ctx context.Context) error
 }
 
 type pgxConnImpl struct {
 	conn     *pgxpool.Pool
 	readHist prometheus.ObserverVec
+	// format is the wire format of query results.
+	format WireFormat
 }
 
 func (p *pgxConnImpl) getConn() *pgxpool.Pool {
@@ -77,7 +81,7 @@ func (p *pgxConnImpl) Query(ctx context.Context, sql string, args ...interface{}
 		}(time.Now(), p.readHist, sql[0:6])
 	}
 
-	return conn.Query(ctx, sql, args...)
+	return conn.Query(ctx, sql, p.format.queryArgs(args)...)
 }
 
 func (p *pgxConnImpl) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
@@ -100,6 +104,16 @@ func (p *pgxConnImpl) SendBatch(ctx context.Context, b pgxBatch) (pgx.BatchResul
 	return conn.SendBatch(ctx, b.(*pgx.Batch)), nil
 }
 
+// Ping acquires a connection from the pool and pings the database on it.
+func (p *pgxConnImpl) Ping(ctx context.Context) error {
+	conn, err := p.getConn().Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	return conn.Conn().Ping(ctx)
+}
+
 // SampleInfoIterator is an iterator over a collection of sampleInfos that returns
 // data in the format expected for the data table row.
 type SampleInfoIterator struct {
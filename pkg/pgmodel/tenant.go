@@ -0,0 +1,114 @@
+// This file and its contents are licensed under the Apache License 2.0.
+// Please see the included NOTICE for copyright information and
+// LICENSE for a copy of the license.
+
+package pgmodel
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/timescale/timescale-prometheus/pkg/prompb"
+)
+
+// ErrMissingTenant is returned for requests without a tenant when tenancy is
+// enabled and there is no default tenant.
+var ErrMissingTenant = fmt.Errorf("tenant missing")
+
+// Tenancy isolates the data of tenants sharing a connector. The tenant of a
+// series is stored as the value of Label, so the series of different tenants
+// never share an id, and every read is constrained to the series of the
+// tenant of its request.
+//
+// The tenant of a request is set on its context with WithTenant, e.g. from an
+// HTTP header. Requests without one are requests of DefaultTenant, and the
+// tenant labels sent by clients are replaced, so that leaving out the tenant
+// does not allow writing the data of any tenant. Requests left without a
+// tenant fail with ErrMissingTenant.
+type Tenancy struct {
+	Label         string
+	DefaultTenant string
+	// HonorSeriesLabels keeps the tenant label of the series written
+	// without a tenant, falling back to DefaultTenant for the series
+	// without it. Only for clients trusted to write the data of any
+	// tenant.
+	HonorSeriesLabels bool
+}
+
+type tenantKey struct{}
+
+// WithTenant returns a context for the requests of the tenant.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant set on the context, or "" if there is
+// none.
+func TenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+// labelSeries sets the tenant label of the series written in ctx, replacing
+// the labels set by clients so that they cannot write the data of another
+// tenant, unless HonorSeriesLabels is set. It is a no-op on a nil Tenancy.
+func (t *Tenancy) labelSeries(ctx context.Context, tts []prompb.TimeSeries) error {
+	if t == nil {
+		return nil
+	}
+	tenant := TenantFromContext(ctx)
+	for i := range tts {
+		ts := &tts[i]
+		seriesTenant := tenant
+		if seriesTenant == "" && t.HonorSeriesLabels {
+			seriesTenant = prompbLabelValue(ts.Labels, t.Label)
+		}
+		if seriesTenant == "" {
+			seriesTenant = t.DefaultTenant
+		}
+		if seriesTenant == "" {
+			return fmt.Errorf("%w: series without a %s label", ErrMissingTenant, t.Label)
+		}
+		ts.Labels = setPrompbLabel(ts.Labels, t.Label, seriesTenant)
+	}
+	return nil
+}
+
+// scope returns the matchers constrained to the series of the tenant of the
+// read in ctx. The matchers passed are not modified. It is a no-op on a nil
+// Tenancy.
+func (t *Tenancy) scope(ctx context.Context, ms []*labels.Matcher) ([]*labels.Matcher, error) {
+	if t == nil {
+		return ms, nil
+	}
+	tenant := TenantFromContext(ctx)
+	if tenant == "" {
+		tenant = t.DefaultTenant
+	}
+	if tenant == "" {
+		return nil, ErrMissingTenant
+	}
+	scoped := make([]*labels.Matcher, len(ms), len(ms)+1)
+	copy(scoped, ms)
+	return append(scoped, labels.MustNewMatcher(labels.MatchEqual, t.Label, tenant)), nil
+}
+
+// setPrompbLabel sets the value of the label, replacing every label of the
+// name set by the client. The labels of clients need not be sorted, so all of
+// them are searched; the new label is added at its place by name if they are,
+// like the labels sent by Prometheus, so that the series keep their order.
+func setPrompbLabel(lls []prompb.Label, name, value string) []prompb.Label {
+	kept := lls[:0]
+	for _, l := range lls {
+		if l.Name != name {
+			kept = append(kept, l)
+		}
+	}
+	i := sort.Search(len(kept), func(i int) bool { return kept[i].Name >= name })
+	kept = append(kept, prompb.Label{})
+	copy(kept[i+1:], kept[i:])
+	kept[i] = prompb.Label{Name: name, Value: value}
+	return kept
+}
@@ -0,0 +1,93 @@
+// This file and its contents are licensed under the Apache License 2.0.
+// Please see the included NOTICE for copyright information and
+// LICENSE for a copy of the license.
+
+package pgmodel
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	// minPostgresTimestamp is the earliest Unix timestamp in milliseconds a
+	// TIMESTAMPTZ holds, 4714-11-24 BC. PostgreSQL defines it relative to its
+	// own time zero.
+	minPostgresTimestamp = -211813488000000 - PostgresUnixEpoch
+	// maxPostgresTimestamp is the latest Unix timestamp in milliseconds pgx
+	// encodes, in microseconds since the Unix epoch, without overflow. It is
+	// in 294247 AD, before the end of 294276 AD a TIMESTAMPTZ holds.
+	maxPostgresTimestamp = math.MaxInt64 / 1000
+)
+
+// ErrTimestampOutOfRange is returned under FailOutOfRange for inserts of
+// samples whose timestamp a TIMESTAMPTZ can not hold.
+var ErrTimestampOutOfRange = fmt.Errorf("sample timestamp out of range")
+
+// TimestampRangePolicy decides what happens to samples whose timestamp is
+// outside of the range of a TIMESTAMPTZ.
+type TimestampRangePolicy int
+
+const (
+	// FailOutOfRange fails the whole insert, before anything is inserted.
+	FailOutOfRange TimestampRangePolicy = iota
+	// DropOutOfRange drops the samples and inserts the others.
+	DropOutOfRange
+	// ClampOutOfRange moves the samples to the nearest representable time.
+	ClampOutOfRange
+)
+
+var timestampRangePolicies = map[string]TimestampRangePolicy{
+	"error": FailOutOfRange,
+	"drop":  DropOutOfRange,
+	"clamp": ClampOutOfRange,
+}
+
+// ParseTimestampRangePolicy returns the policy with the given name, one of
+// error, drop or clamp.
+func ParseTimestampRangePolicy(name string) (TimestampRangePolicy, error) {
+	policy, ok := timestampRangePolicies[name]
+	if !ok {
+		return FailOutOfRange, fmt.Errorf("invalid timestamp range policy %q", name)
+	}
+	return policy, nil
+}
+
+func (p TimestampRangePolicy) String() string {
+	for name, policy := range timestampRangePolicies {
+		if policy == p {
+			return name
+		}
+	}
+	return fmt.Sprintf("TimestampRangePolicy(%d)", int(p))
+}
+
+// checkTimestampRange counts the samples of a metric whose timestamp a
+// TIMESTAMPTZ can not hold, dropping or clamping them according to the policy.
+// Under FailOutOfRange the samples are left as they are.
+func checkTimestampRange(data []samplesInfo, policy TimestampRangePolicy) int {
+	outOfRange := 0
+	for i := range data {
+		kept := data[i].samples[:0]
+		for _, s := range data[i].samples {
+			if s.Timestamp >= minPostgresTimestamp && s.Timestamp <= maxPostgresTimestamp {
+				kept = append(kept, s)
+				continue
+			}
+			outOfRange++
+			switch policy {
+			case DropOutOfRange:
+				continue
+			case ClampOutOfRange:
+				if s.Timestamp < minPostgresTimestamp {
+					s.Timestamp = minPostgresTimestamp
+				} else {
+					s.Timestamp = maxPostgresTimestamp
+				}
+			}
+			kept = append(kept, s)
+		}
+		data[i].samples = kept
+	}
+	return outOfRange
+}
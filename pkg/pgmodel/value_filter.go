@@ -0,0 +1,167 @@
+// This file and its contents are licensed under the Apache License 2.0.
+// Please see the included NOTICE for copyright information and
+// LICENSE for a copy of the license.
+
+package pgmodel
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/timescale/timescale-prometheus/pkg/prompb"
+)
+
+// ValueAggregate is the aggregate of a series' values a ValueFilter compares.
+type ValueAggregate int
+
+const (
+	// LastValue is the latest value of the series.
+	LastValue ValueAggregate = iota
+	// MaxValue is the largest value of the series.
+	MaxValue
+	// MinValue is the smallest value of the series.
+	MinValue
+	// AvgValue is the mean of the values of the series.
+	AvgValue
+)
+
+var valueAggregates = map[string]ValueAggregate{
+	"last": LastValue,
+	"max":  MaxValue,
+	"min":  MinValue,
+	"avg":  AvgValue,
+}
+
+func (a ValueAggregate) String() string {
+	for name, aggregate := range valueAggregates {
+		if aggregate == a {
+			return name
+		}
+	}
+	return fmt.Sprintf("ValueAggregate(%d)", int(a))
+}
+
+var valueFilterOps = map[string]func(v, threshold float64) bool{
+	"==": func(v, threshold float64) bool { return v == threshold },
+	"!=": func(v, threshold float64) bool { return v != threshold },
+	">":  func(v, threshold float64) bool { return v > threshold },
+	">=": func(v, threshold float64) bool { return v >= threshold },
+	"<":  func(v, threshold float64) bool { return v < threshold },
+	"<=": func(v, threshold float64) bool { return v <= threshold },
+}
+
+var valueFilterRegexp = regexp.MustCompile(`^\s*(\w+)\s*(==|!=|>=|<=|>|<)\s*(\S+)\s*$`)
+
+// ValueFilter drops the series read whose values do not satisfy a predicate,
+// e.g. only returning series whose last value exceeds a threshold. It is
+// applied to the samples returned for the query range, after rounding and
+// gap filling. NaN values, including staleness markers, are ignored, so
+// series without any other value are always dropped.
+type ValueFilter struct {
+	Aggregate ValueAggregate
+	// Op is the comparison of the aggregate with the threshold, one of ==,
+	// !=, >, >=, < and <=.
+	Op        string
+	Threshold float64
+}
+
+// ParseValueFilter parses a filter of the form <aggregate><op><threshold>,
+// e.g. "last>0.9" or "max>=100". The aggregate is one of last, max, min and
+// avg. An empty expression returns a nil filter, which keeps all series.
+func ParseValueFilter(expr string) (*ValueFilter, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	parts := valueFilterRegexp.FindStringSubmatch(expr)
+	if parts == nil {
+		return nil, fmt.Errorf("invalid value filter %q, expected <aggregate><op><threshold>", expr)
+	}
+	aggregate, ok := valueAggregates[parts[1]]
+	if !ok {
+		return nil, fmt.Errorf("invalid value filter aggregate %q", parts[1])
+	}
+	threshold, err := strconv.ParseFloat(parts[3], 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value filter threshold %q: %w", parts[3], err)
+	}
+	return &ValueFilter{Aggregate: aggregate, Op: parts[2], Threshold: threshold}, nil
+}
+
+func (f *ValueFilter) String() string {
+	return fmt.Sprintf("%s%s%g", f.Aggregate, f.Op, f.Threshold)
+}
+
+// matchesIterator reports whether the samples of it pass the filter, a nil
+// filter passes all series.
+func (f *ValueFilter) matchesIterator(it chunkenc.Iterator) bool {
+	if f == nil {
+		return true
+	}
+	agg := valueAgg{}
+	for it.Next() {
+		_, v := it.At()
+		agg.add(v)
+	}
+	return f.matches(agg)
+}
+
+// matchesSamples reports whether the samples pass the filter, a nil filter
+// passes all series.
+func (f *ValueFilter) matchesSamples(samples []prompb.Sample) bool {
+	if f == nil {
+		return true
+	}
+	agg := valueAgg{}
+	for _, s := range samples {
+		agg.add(s.Value)
+	}
+	return f.matches(agg)
+}
+
+func (f *ValueFilter) matches(agg valueAgg) bool {
+	if agg.count == 0 {
+		return false
+	}
+	op, ok := valueFilterOps[f.Op]
+	if !ok {
+		return false
+	}
+	var v float64
+	switch f.Aggregate {
+	case LastValue:
+		v = agg.last
+	case MaxValue:
+		v = agg.max
+	case MinValue:
+		v = agg.min
+	case AvgValue:
+		v = agg.sum / float64(agg.count)
+	default:
+		return false
+	}
+	return op(v, f.Threshold)
+}
+
+// valueAgg accumulates the aggregates of the non-NaN values of a series.
+type valueAgg struct {
+	count               int
+	last, min, max, sum float64
+}
+
+func (a *valueAgg) add(v float64) {
+	if math.IsNaN(v) {
+		return
+	}
+	if a.count == 0 || v < a.min {
+		a.min = v
+	}
+	if a.count == 0 || v > a.max {
+		a.max = v
+	}
+	a.count++
+	a.last = v
+	a.sum += v
+}
@@ -0,0 +1,84 @@
+// This file and its contents are licensed under the Apache License 2.0.
+// Please see the included NOTICE for copyright information and
+// LICENSE for a copy of the license.
+
+package pgmodel
+
+import (
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/prometheus/prometheus/pkg/value"
+)
+
+// WireFormat selects the PostgreSQL protocol format data is exchanged in.
+// Binary is faster for sample data, text is more tolerant of unusual types
+// and intermediaries. Text does not preserve the payload of NaN values, so
+// staleness markers are read as plain NaN, and samples holding them are
+// always inserted in binary.
+type WireFormat int
+
+const (
+	// BinaryFormat exchanges data in the binary format, the pgx default.
+	BinaryFormat WireFormat = iota
+	// TextFormat exchanges data in the text format.
+	TextFormat
+)
+
+var wireFormats = map[string]WireFormat{
+	"binary": BinaryFormat,
+	"text":   TextFormat,
+}
+
+// ParseWireFormat returns the format with the given name, either binary or
+// text.
+func ParseWireFormat(name string) (WireFormat, error) {
+	format, ok := wireFormats[name]
+	if !ok {
+		return BinaryFormat, fmt.Errorf("invalid wire format %q", name)
+	}
+	return format, nil
+}
+
+func (f WireFormat) String() string {
+	for name, format := range wireFormats {
+		if format == f {
+			return name
+		}
+	}
+	return fmt.Sprintf("WireFormat(%d)", int(f))
+}
+
+// queryArgs prepends the pgx option requesting results in the format to the
+// arguments of a query.
+func (f WireFormat) queryArgs(args []interface{}) []interface{} {
+	if f != TextFormat {
+		return args
+	}
+	return append([]interface{}{pgx.QueryResultFormats{pgx.TextFormatCode}}, args...)
+}
+
+// execArgs prepends the pgx option sending the parameters of a statement in
+// the format to its arguments. Parameters are only sent as text by the simple
+// protocol.
+func (f WireFormat) execArgs(args []interface{}) []interface{} {
+	if f != TextFormat {
+		return args
+	}
+	return append([]interface{}{pgx.QuerySimpleProtocol(true)}, args...)
+}
+
+// insertArgs returns the arguments of a statement inserting samples with the
+// values vals in the format. Statements inserting staleness markers are sent
+// in binary, as text would turn them into plain NaN.
+func (f WireFormat) insertArgs(vals []float64, args ...interface{}) []interface{} {
+	if f != TextFormat {
+		return args
+	}
+	for _, v := range vals {
+		if value.IsStaleNaN(v) {
+			return args
+		}
+	}
+	return f.execArgs(args)
+}
@@ -51,12 +51,14 @@ type Queryable interface {
 
 // Querier provides querying access over time series data of a fixed time range.
 type Querier interface {
-	// LabelValues returns all potential values for a label name.
+	// LabelValues returns all potential values for a label name, of the
+	// series selected by the matchers if there are any.
 	// It is not safe to use the strings beyond the lifefime of the querier.
-	LabelValues(name string) ([]string, storage.Warnings, error)
+	LabelValues(name string, matchers ...*labels.Matcher) ([]string, storage.Warnings, error)
 
-	// LabelNames returns all the unique label names present in the block in sorted order.
-	LabelNames() ([]string, storage.Warnings, error)
+	// LabelNames returns all the unique label names present in the block in sorted order,
+	// of the series selected by the matchers if there are any.
+	LabelNames(matchers ...*labels.Matcher) ([]string, storage.Warnings, error)
 
 	// Close releases the resources of the Querier.
 	Close() error
@@ -20,6 +20,7 @@ import (
 	"math"
 	"os"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -82,6 +83,50 @@ func (t *QuerierWrapper) Select(b bool, sh *storage.SelectHints, _ []parser.Node
 	return ss, nil, w, err
 }
 
+// LabelValues returns the values of the label name, of the series selected
+// by the matchers if there are any.
+func (t *QuerierWrapper) LabelValues(name string, matchers ...*labels.Matcher) ([]string, storage.Warnings, error) {
+	if len(matchers) == 0 {
+		return t.Querier.LabelValues(name)
+	}
+	return t.selectLabels(matchers, func(l labels.Label) (string, bool) { return l.Value, l.Name == name })
+}
+
+// LabelNames returns the label names, of the series selected by the matchers
+// if there are any.
+func (t *QuerierWrapper) LabelNames(matchers ...*labels.Matcher) ([]string, storage.Warnings, error) {
+	if len(matchers) == 0 {
+		return t.Querier.LabelNames()
+	}
+	return t.selectLabels(matchers, func(l labels.Label) (string, bool) { return l.Name, true })
+}
+
+// selectLabels returns the sorted distinct strings f picks from the labels of
+// the series selected by the matchers.
+func (t *QuerierWrapper) selectLabels(matchers []*labels.Matcher, f func(labels.Label) (string, bool)) ([]string, storage.Warnings, error) {
+	ss, w, err := t.Querier.Select(false, nil, matchers...)
+	if err != nil {
+		return nil, w, err
+	}
+	seen := make(map[string]struct{})
+	for ss.Next() {
+		for _, l := range ss.At().Labels() {
+			if s, ok := f(l); ok {
+				seen[s] = struct{}{}
+			}
+		}
+	}
+	if err = ss.Err(); err != nil {
+		return nil, w, err
+	}
+	res := make([]string, 0, len(seen))
+	for s := range seen {
+		res = append(res, s)
+	}
+	sort.Strings(res)
+	return res, w, nil
+}
+
 func (db *TestStorage) Querier(ctx context.Context, mint, maxt int64) (Querier, error) {
 	q, err := db.DB.Querier(ctx, mint, maxt)
 	if err != nil {
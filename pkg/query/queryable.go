@@ -31,20 +31,37 @@ func newQuerier(ctx context.Context, q pgmodel.Querier, mint, maxt int64) (*quer
 	return &querier{ctx, mint, maxt, q}, nil
 }
 
-func (q querier) LabelValues(name string) ([]string, storage.Warnings, error) {
-	lVals, err := q.pgQuerier.LabelValues(name)
+func (q querier) LabelValues(name string, matchers ...*labels.Matcher) ([]string, storage.Warnings, error) {
+	matchers, err := q.scopeToTenant(matchers)
+	if err != nil {
+		return nil, nil, err
+	}
+	lVals, err := q.pgQuerier.LabelValues(q.ctx, name, matchers...)
 	return lVals, nil, err
 }
 
-func (q querier) LabelNames() ([]string, storage.Warnings, error) {
-	lNames, err := q.pgQuerier.LabelNames()
+func (q querier) LabelNames(matchers ...*labels.Matcher) ([]string, storage.Warnings, error) {
+	matchers, err := q.scopeToTenant(matchers)
+	if err != nil {
+		return nil, nil, err
+	}
+	lNames, err := q.pgQuerier.LabelNames(q.ctx, matchers...)
 	return lNames, nil, err
 }
 
+// scopeToTenant constrains the matchers to the tenant of the querier's
+// context. Select is constrained by the pgmodel querier itself.
+func (q querier) scopeToTenant(matchers []*labels.Matcher) ([]*labels.Matcher, error) {
+	if s, ok := q.pgQuerier.(pgmodel.TenantScoper); ok {
+		return s.ScopeToTenant(q.ctx, matchers)
+	}
+	return matchers, nil
+}
+
 func (q querier) Close() error {
 	return nil
 }
 
 func (q querier) Select(sortSeries bool, hints *storage.SelectHints, path []parser.Node, matchers ...*labels.Matcher) (storage.SeriesSet, parser.Node, storage.Warnings, error) {
-	return q.pgQuerier.Select(q.mint, q.maxt, sortSeries, hints, path, matchers...)
+	return q.pgQuerier.Select(q.ctx, q.mint, q.maxt, sortSeries, hints, path, matchers...)
 }
@@ -0,0 +1,39 @@
+// This file and its contents are licensed under the Apache License 2.0.
+// Please see the included NOTICE for copyright information and
+// LICENSE for a copy of the license.
+
+package util
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/timescale/timescale-prometheus/pkg/log"
+)
+
+// maxRetryBackoff caps the delay between two attempts of RetryWithBackoff.
+const maxRetryBackoff = 30 * time.Second
+
+// sleep is replaced in tests to not wait out the backoff.
+var sleep = time.Sleep
+
+// RetryWithBackoff calls f until it succeeds or it failed retries+1 times,
+// returning the last error. The delay before the first retry is backoff and
+// doubles with every further retry, up to 30s. Every failed attempt is logged
+// with desc, passwords in the error are masked.
+func RetryWithBackoff(desc string, retries int, backoff time.Duration, f func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil {
+			return nil
+		}
+		if attempt > retries {
+			return fmt.Errorf("%s failed after %d attempts: %w", desc, attempt, err)
+		}
+		log.Warn("msg", desc+" failed, retrying", "attempt", attempt, "backoff", backoff, "err", MaskPassword(err.Error()))
+		sleep(backoff)
+		if backoff *= 2; backoff > maxRetryBackoff {
+			backoff = maxRetryBackoff
+		}
+	}
+}
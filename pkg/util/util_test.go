@@ -4,6 +4,8 @@
 package util
 
 import (
+	"fmt"
+	"reflect"
 	"testing"
 	"time"
 
@@ -79,3 +81,72 @@ func TestMaskPassword(t *testing.T) {
 		}
 	}
 }
+
+func TestRetryWithBackoff(t *testing.T) {
+	testCases := []struct {
+		name     string
+		failures int
+		retries  int
+		backoffs []time.Duration
+		err      bool
+	}{
+		{
+			name:     "connects right away",
+			retries:  3,
+			backoffs: []time.Duration{},
+		},
+		{
+			name:     "connects after failures",
+			failures: 3,
+			retries:  3,
+			backoffs: []time.Duration{time.Second, 2 * time.Second, 4 * time.Second},
+		},
+		{
+			name:     "gives up",
+			failures: 3,
+			retries:  2,
+			backoffs: []time.Duration{time.Second, 2 * time.Second},
+			err:      true,
+		},
+		{
+			name:     "backoff is capped",
+			failures: 7,
+			retries:  10,
+			backoffs: []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 30 * time.Second, 30 * time.Second},
+		},
+		{
+			name:     "no retries",
+			failures: 1,
+			backoffs: []time.Duration{},
+			err:      true,
+		},
+	}
+
+	defer func() { sleep = time.Sleep }()
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			backoffs := make([]time.Duration, 0)
+			sleep = func(d time.Duration) { backoffs = append(backoffs, d) }
+
+			attempts := 0
+			dial := func() error {
+				attempts++
+				if attempts <= c.failures {
+					return fmt.Errorf("connection refused, password='secret'")
+				}
+				return nil
+			}
+
+			err := RetryWithBackoff("connecting", c.retries, time.Second, dial)
+			if c.err != (err != nil) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(backoffs, c.backoffs) {
+				t.Errorf("unexpected backoffs: got %v, wanted %v", backoffs, c.backoffs)
+			}
+			if wanted := len(c.backoffs) + 1; attempts != wanted {
+				t.Errorf("unexpected number of attempts: got %d, wanted %d", attempts, wanted)
+			}
+		})
+	}
+}